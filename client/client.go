@@ -0,0 +1,781 @@
+// Code generated by client/internal/generate from the OpenAPI specifications. DO NOT EDIT.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SecureWorkflowReponse is the SecureWorkflowReponse schema of openapi.yml
+type SecureWorkflowReponse struct {
+	OriginalInput               string       `json:"OriginalInput,omitempty"`
+	FinalOutput                 string       `json:"FinalOutput,omitempty"`
+	Diff                        string       `json:"Diff,omitempty"`
+	IsChanged                   bool         `json:"IsChanged,omitempty"`
+	HasErrors                   bool         `json:"HasErrors,omitempty"`
+	AlreadyHasPermissions       bool         `json:"AlreadyHasPermissions,omitempty"`
+	AddedMaintainedActions      bool         `json:"AddedMaintainedActions,omitempty"`
+	PinnedActions               bool         `json:"PinnedActions,omitempty"`
+	AddedHardenRunner           bool         `json:"AddedHardenRunner,omitempty"`
+	AddedPermissions            bool         `json:"AddedPermissions,omitempty"`
+	ReplacedRunnerLabels        bool         `json:"ReplacedRunnerLabels,omitempty"`
+	RemovedUnnecessaryTokens    bool         `json:"RemovedUnnecessaryTokens,omitempty"`
+	AddedForkPullRequestGuards  bool         `json:"AddedForkPullRequestGuards,omitempty"`
+	FixedDispatchInputs         bool         `json:"FixedDispatchInputs,omitempty"`
+	AddedShellDefaults          bool         `json:"AddedShellDefaults,omitempty"`
+	RewroteDeprecatedCommands   bool         `json:"RewroteDeprecatedCommands,omitempty"`
+	AddedRepositoryGuards       bool         `json:"AddedRepositoryGuards,omitempty"`
+	PinnedRunTools              bool         `json:"PinnedRunTools,omitempty"`
+	AddedBuildProvenance        bool         `json:"AddedBuildProvenance,omitempty"`
+	AddedCosignSigning          bool         `json:"AddedCosignSigning,omitempty"`
+	AddedSBOM                   bool         `json:"AddedSBOM,omitempty"`
+	FixedVulnerableActions      bool         `json:"FixedVulnerableActions,omitempty"`
+	FixedTyposquattedActions    bool         `json:"FixedTyposquattedActions,omitempty"`
+	FixedSecretBuildArgs        bool         `json:"FixedSecretBuildArgs,omitempty"`
+	SanitizedUntrustedEnvWrites bool         `json:"SanitizedUntrustedEnvWrites,omitempty"`
+	HasPolicyViolations         bool         `json:"HasPolicyViolations,omitempty"`
+	FailedStrictChecks          bool         `json:"FailedStrictChecks,omitempty"`
+	IncorrectYaml               bool         `json:"IncorrectYaml,omitempty"`
+	WorkflowFetchError          bool         `json:"WorkflowFetchError,omitempty"`
+	JobErrors                   []JobError   `json:"JobErrors,omitempty"`
+	MissingActions              []string     `json:"MissingActions,omitempty"`
+	UsingSecureRepoPAT          bool         `json:"UsingSecureRepoPAT,omitempty"`
+	Findings                    []Finding    `json:"Findings,omitempty"`
+	Report                      *Report      `json:"Report,omitempty"`
+	Score                       *ScoreChange `json:"Score,omitempty"`
+}
+
+// ScoreChange is the ScoreChange schema of openapi.yml
+type ScoreChange struct {
+	Before *Score  `json:"Before,omitempty"`
+	After  *Score  `json:"After,omitempty"`
+	Delta  float64 `json:"Delta,omitempty"`
+}
+
+// Score is the Score schema of openapi.yml
+type Score struct {
+	// The average of the scores of the checks, weighted by their risk
+	Score  float64      `json:"Score,omitempty"`
+	Checks []CheckScore `json:"Checks,omitempty"`
+}
+
+// CheckScore is the CheckScore schema of openapi.yml
+type CheckScore struct {
+	Name   string  `json:"Name,omitempty"`
+	Score  float64 `json:"Score,omitempty"`
+	Reason string  `json:"Reason,omitempty"`
+}
+
+// JobError is the JobError schema of openapi.yml
+type JobError struct {
+	JobName string   `json:"JobName,omitempty"`
+	Errors  []string `json:"Errors,omitempty"`
+}
+
+// Finding is the Finding schema of openapi.yml
+type Finding struct {
+	RuleID     string `json:"RuleID,omitempty"`
+	Message    string `json:"Message,omitempty"`
+	JobName    string `json:"JobName,omitempty"`
+	Action     string `json:"Action,omitempty"`
+	Line       int    `json:"Line,omitempty"`
+	Column     int    `json:"Column,omitempty"`
+	Suggestion string `json:"Suggestion,omitempty"`
+	Fixed      bool   `json:"Fixed,omitempty"`
+}
+
+// Report is the Report schema of openapi.yml
+type Report struct {
+	Modules  []Module `json:"Modules,omitempty"`
+	Statuses []Status `json:"Statuses,omitempty"`
+}
+
+// Status is the Status schema of openapi.yml
+type Status struct {
+	Module string   `json:"Module,omitempty"`
+	Status string   `json:"Status,omitempty"`
+	Errors []string `json:"Errors,omitempty"`
+}
+
+// Module is the Module schema of openapi.yml
+type Module struct {
+	Name    string    `json:"Name,omitempty"`
+	Changes []Change  `json:"Changes,omitempty"`
+	Skipped []Skipped `json:"Skipped,omitempty"`
+	Errors  []string  `json:"Errors,omitempty"`
+}
+
+// Change is the Change schema of openapi.yml
+type Change struct {
+	File       string `json:"File,omitempty"`
+	Line       int    `json:"Line,omitempty"`
+	Kind       string `json:"Kind,omitempty"`
+	Before     string `json:"Before,omitempty"`
+	After      string `json:"After,omitempty"`
+	Confidence string `json:"Confidence,omitempty"`
+	Revert     *Edit  `json:"Revert,omitempty"`
+}
+
+// Edit is the Edit schema of openapi.yml
+type Edit struct {
+	Line   int    `json:"Line,omitempty"`
+	Kind   string `json:"Kind,omitempty"`
+	Before string `json:"Before,omitempty"`
+	After  string `json:"After,omitempty"`
+}
+
+// Skipped is the Skipped schema of openapi.yml
+type Skipped struct {
+	File   string `json:"File,omitempty"`
+	Line   int    `json:"Line,omitempty"`
+	Item   string `json:"Item,omitempty"`
+	Reason string `json:"Reason,omitempty"`
+}
+
+// SecureDockerfileResponse is the SecureDockerfileResponse schema of openapi.yml
+type SecureDockerfileResponse struct {
+	OriginalInput        string `json:"OriginalInput,omitempty"`
+	FinalOutput          string `json:"FinalOutput,omitempty"`
+	Diff                 string `json:"Diff,omitempty"`
+	IsChanged            bool   `json:"IsChanged,omitempty"`
+	DockerfileFetchError bool   `json:"DockerfileFetchError,omitempty"`
+	AddedNonRootUser     bool   `json:"AddedNonRootUser,omitempty"`
+}
+
+// SecureCompositeActionResponse is the SecureCompositeActionResponse schema of openapi.yml
+type SecureCompositeActionResponse struct {
+	OriginalInput             string    `json:"OriginalInput,omitempty"`
+	FinalOutput               string    `json:"FinalOutput,omitempty"`
+	Diff                      string    `json:"Diff,omitempty"`
+	IsChanged                 bool      `json:"IsChanged,omitempty"`
+	PinnedActions             bool      `json:"PinnedActions,omitempty"`
+	RewroteDeprecatedCommands bool      `json:"RewroteDeprecatedCommands,omitempty"`
+	HasErrors                 bool      `json:"HasErrors,omitempty"`
+	IncorrectYaml             bool      `json:"IncorrectYaml,omitempty"`
+	ActionFetchError          bool      `json:"ActionFetchError,omitempty"`
+	Findings                  []Finding `json:"Findings,omitempty"`
+}
+
+// UpdateDependabotConfigRequest is the UpdateDependabotConfigRequest schema of openapi.yml
+type UpdateDependabotConfigRequest struct {
+	Ecosystems  []Ecosystem `json:"Ecosystems,omitempty"`
+	Content     string      `json:"Content,omitempty"`
+	Subtractive bool        `json:"Subtractive,omitempty"`
+}
+
+// Ecosystem is the Ecosystem schema of openapi.yml
+type Ecosystem struct {
+	PackageEcosystem string           `json:"PackageEcosystem,omitempty"`
+	Directory        string           `json:"Directory,omitempty"`
+	Directories      []string         `json:"Directories,omitempty"`
+	Interval         string           `json:"Interval,omitempty"`
+	CoolDown         *CoolDown        `json:"CoolDown,omitempty"`
+	Groups           map[string]Group `json:"Groups,omitempty"`
+}
+
+// UpdateDependabotConfigResponse is the UpdateDependabotConfigResponse schema of openapi.yml
+type UpdateDependabotConfigResponse struct {
+	OriginalInput        string `json:"OriginalInput,omitempty"`
+	FinalOutput          string `json:"FinalOutput,omitempty"`
+	IsChanged            bool   `json:"IsChanged,omitempty"`
+	ConfigfileFetchError bool   `json:"ConfigfileFetchError,omitempty"`
+}
+
+// UpdateCodeownersRequest is the UpdateCodeownersRequest schema of openapi.yml
+type UpdateCodeownersRequest struct {
+	Owners   []string `json:"Owners,omitempty"`
+	Patterns []string `json:"Patterns,omitempty"`
+	Content  string   `json:"Content,omitempty"`
+}
+
+// UpdateCodeownersResponse is the UpdateCodeownersResponse schema of openapi.yml
+type UpdateCodeownersResponse struct {
+	OriginalInput        string `json:"OriginalInput,omitempty"`
+	FinalOutput          string `json:"FinalOutput,omitempty"`
+	IsChanged            bool   `json:"IsChanged,omitempty"`
+	CodeownersFetchError bool   `json:"CodeownersFetchError,omitempty"`
+}
+
+// SecureRepoRequest is the SecureRepoRequest schema of openapi.yml
+type SecureRepoRequest struct {
+	Files         map[string]string `json:"Files,omitempty"`
+	FileList      []File            `json:"FileList,omitempty"`
+	Archive       []byte            `json:"Archive,omitempty"`
+	ArchiveFormat string            `json:"ArchiveFormat,omitempty"`
+}
+
+// File is the File schema of openapi.yml
+type File struct {
+	Path    string `json:"Path,omitempty"`
+	Content string `json:"Content,omitempty"`
+}
+
+// SecureRepoResponse is the SecureRepoResponse schema of openapi.yml
+type SecureRepoResponse struct {
+	Files              map[string]string `json:"Files,omitempty"`
+	Diffs              map[string]string `json:"Diffs,omitempty"`
+	Archive            []byte            `json:"Archive,omitempty"`
+	Report             []FileReport      `json:"Report,omitempty"`
+	IsChanged          bool              `json:"IsChanged,omitempty"`
+	HasErrors          bool              `json:"HasErrors,omitempty"`
+	FailedStrictChecks bool              `json:"FailedStrictChecks,omitempty"`
+	MissingActions     []string          `json:"MissingActions,omitempty"`
+	Score              *ScoreChange      `json:"Score,omitempty"`
+}
+
+// FileReport is the FileReport schema of openapi.yml
+type FileReport struct {
+	Path               string       `json:"Path,omitempty"`
+	FileType           string       `json:"FileType,omitempty"`
+	IsChanged          bool         `json:"IsChanged,omitempty"`
+	IsNew              bool         `json:"IsNew,omitempty"`
+	HasErrors          bool         `json:"HasErrors,omitempty"`
+	FailedStrictChecks bool         `json:"FailedStrictChecks,omitempty"`
+	Error              string       `json:"Error,omitempty"`
+	Findings           []Finding    `json:"Findings,omitempty"`
+	Score              *ScoreChange `json:"Score,omitempty"`
+}
+
+// RepoPermissionsRequest is the RepoPermissionsRequest schema of openapi.yml
+type RepoPermissionsRequest struct {
+	Workflows map[string]string `json:"Workflows,omitempty"`
+}
+
+// RepoPermissionsResponse is the RepoPermissionsResponse schema of openapi.yml
+type RepoPermissionsResponse struct {
+	Changes        []WorkflowPermissionsChange `json:"Changes,omitempty"`
+	Summary        *RepoPermissionsSummary     `json:"Summary,omitempty"`
+	MissingActions []string                    `json:"MissingActions,omitempty"`
+}
+
+// WorkflowPermissionsChange is the WorkflowPermissionsChange schema of openapi.yml
+type WorkflowPermissionsChange struct {
+	Path                  string     `json:"Path,omitempty"`
+	OriginalInput         string     `json:"OriginalInput,omitempty"`
+	FinalOutput           string     `json:"FinalOutput,omitempty"`
+	IsChanged             bool       `json:"IsChanged,omitempty"`
+	AddedPermissions      bool       `json:"AddedPermissions,omitempty"`
+	AlreadyHasPermissions bool       `json:"AlreadyHasPermissions,omitempty"`
+	HasErrors             bool       `json:"HasErrors,omitempty"`
+	IncorrectYaml         bool       `json:"IncorrectYaml,omitempty"`
+	JobErrors             []JobError `json:"JobErrors,omitempty"`
+}
+
+// RepoPermissionsSummary is the RepoPermissionsSummary schema of openapi.yml
+type RepoPermissionsSummary struct {
+	TotalWorkflows          int `json:"TotalWorkflows,omitempty"`
+	ChangedWorkflows        int `json:"ChangedWorkflows,omitempty"`
+	AlreadyHavePermissions  int `json:"AlreadyHavePermissions,omitempty"`
+	WorkflowsWithErrors     int `json:"WorkflowsWithErrors,omitempty"`
+	WorkflowsIncorrectYaml  int `json:"WorkflowsIncorrectYaml,omitempty"`
+	MissingActionsWorkflows int `json:"MissingActionsWorkflows,omitempty"`
+}
+
+// ReusableWorkflowsRequest is the ReusableWorkflowsRequest schema of openapi.yml
+type ReusableWorkflowsRequest struct {
+	Workflows map[string]string `json:"Workflows,omitempty"`
+}
+
+// ReusableWorkflowsResponse is the ReusableWorkflowsResponse schema of openapi.yml
+type ReusableWorkflowsResponse struct {
+	ReusableWorkflows []ReusableWorkflow       `json:"ReusableWorkflows,omitempty"`
+	Callers           []ReusableWorkflowCaller `json:"Callers,omitempty"`
+	MissingActions    []string                 `json:"MissingActions,omitempty"`
+}
+
+// ReusableWorkflow is the ReusableWorkflow schema of openapi.yml
+type ReusableWorkflow struct {
+	Path    string   `json:"Path,omitempty"`
+	Content string   `json:"Content,omitempty"`
+	Inputs  []string `json:"Inputs,omitempty"`
+	Secrets []string `json:"Secrets,omitempty"`
+	Callers []string `json:"Callers,omitempty"`
+}
+
+// ReusableWorkflowCaller is the ReusableWorkflowCaller schema of openapi.yml
+type ReusableWorkflowCaller struct {
+	Path          string `json:"Path,omitempty"`
+	OriginalInput string `json:"OriginalInput,omitempty"`
+	FinalOutput   string `json:"FinalOutput,omitempty"`
+	IsChanged     bool   `json:"IsChanged,omitempty"`
+}
+
+// WebhookResponse is the WebhookResponse schema of openapi.yml
+type WebhookResponse struct {
+	Event   string `json:"Event,omitempty"`
+	Action  string `json:"Action,omitempty"`
+	Ignored bool   `json:"Ignored,omitempty"`
+	// The check run with the findings of a pull request event
+	CheckRunURL  string             `json:"CheckRunURL,omitempty"`
+	Repositories []RepositoryResult `json:"Repositories,omitempty"`
+}
+
+// RepositoryResult is the RepositoryResult schema of openapi.yml
+type RepositoryResult struct {
+	Repository     string `json:"Repository,omitempty"`
+	IsChanged      bool   `json:"IsChanged,omitempty"`
+	PullRequestURL string `json:"PullRequestURL,omitempty"`
+	Error          string `json:"Error,omitempty"`
+}
+
+// CampaignRequest is the CampaignRequest schema of openapi.yml
+type CampaignRequest struct {
+	// The id of the campaign to resume, which starts a new campaign if it is empty
+	ID string `json:"ID,omitempty"`
+	// Query parameters of /secure-repo, with dryRun=true to only evaluate the repositories
+	Params map[string]string `json:"Params,omitempty"`
+	// Full names of the repositories of the campaign, which are all repositories of the installation by default
+	Repositories []string `json:"Repositories,omitempty"`
+	// Number of repositories remediated by the request, 10 by default and at most 50
+	BatchSize int `json:"BatchSize,omitempty"`
+	// Number of shards a new campaign is split into, 1 by default and at most 100
+	Shards int `json:"Shards,omitempty"`
+	// Index of the shard whose next batch is run, from 0
+	Shard int `json:"Shard,omitempty"`
+}
+
+// Campaign is the Campaign schema of openapi.yml
+type Campaign struct {
+	ID           string            `json:"ID,omitempty"`
+	Status       string            `json:"Status,omitempty"`
+	Params       map[string]string `json:"Params,omitempty"`
+	Repositories []string          `json:"Repositories,omitempty"`
+	// Number of repositories that were remediated, in the order of Repositories
+	Processed    int `json:"Processed,omitempty"`
+	Changed      int `json:"Changed,omitempty"`
+	PullRequests int `json:"PullRequests,omitempty"`
+	Failed       int `json:"Failed,omitempty"`
+	// Progress of the shards, whose totals are Processed, Changed, PullRequests and Failed
+	Shards    []CampaignShard    `json:"Shards,omitempty"`
+	CreatedAt time.Time          `json:"CreatedAt,omitempty"`
+	UpdatedAt time.Time          `json:"UpdatedAt,omitempty"`
+	Results   []RepositoryResult `json:"Results,omitempty"`
+}
+
+// CampaignShard is the CampaignShard schema of openapi.yml
+type CampaignShard struct {
+	Index int `json:"Index,omitempty"`
+	// Index of the first repository of the shard in Repositories
+	Start int `json:"Start,omitempty"`
+	// Index after the last repository of the shard in Repositories
+	End int `json:"End,omitempty"`
+	// Index of the next repository, from which the shard is resumed
+	Next         int `json:"Next,omitempty"`
+	Changed      int `json:"Changed,omitempty"`
+	PullRequests int `json:"PullRequests,omitempty"`
+	Failed       int `json:"Failed,omitempty"`
+	// Id of the lease of the request that ran the shard last
+	LeaseID string `json:"LeaseID,omitempty"`
+	// Time until which another request for the shard fails with 409
+	LeaseExpiresAt time.Time `json:"LeaseExpiresAt,omitempty"`
+	UpdatedAt      time.Time `json:"UpdatedAt,omitempty"`
+}
+
+// ProjectResult is the ProjectResult schema of openapi.yml
+type ProjectResult struct {
+	Project         string `json:"Project,omitempty"`
+	IsChanged       bool   `json:"IsChanged,omitempty"`
+	MergeRequestURL string `json:"MergeRequestURL,omitempty"`
+	Error           string `json:"Error,omitempty"`
+}
+
+// CoolDown is the CoolDown schema of openapi.yml
+type CoolDown struct {
+	DefaultDays     int      `json:"DefaultDays,omitempty"`
+	SemverMajorDays int      `json:"SemverMajorDays,omitempty"`
+	SemverMinorDays int      `json:"SemverMinorDays,omitempty"`
+	SemverPatchDays int      `json:"SemverPatchDays,omitempty"`
+	Include         []string `json:"Include,omitempty"`
+	Exclude         []string `json:"Exclude,omitempty"`
+}
+
+// Group is the Group schema of openapi.yml
+type Group struct {
+	AppliesTo       string   `json:"AppliesTo,omitempty"`
+	Patterns        []string `json:"Patterns,omitempty"`
+	ExcludePatterns []string `json:"ExcludePatterns,omitempty"`
+	DependencyType  string   `json:"DependencyType,omitempty"`
+	UpdateTypes     []string `json:"UpdateTypes,omitempty"`
+	GroupBy         string   `json:"GroupBy,omitempty"`
+}
+
+// SecureWorkflowRequest is the SecureWorkflowRequest schema of openapi-v2.yml
+type SecureWorkflowRequest struct {
+	Params    map[string]string `json:"Params,omitempty"`
+	Workflows []File            `json:"Workflows,omitempty"`
+}
+
+// Summary is the Summary schema of openapi-v2.yml
+type Summary struct {
+	Files              int `json:"Files,omitempty"`
+	ChangedFiles       int `json:"ChangedFiles,omitempty"`
+	SafeChanges        int `json:"SafeChanges,omitempty"`
+	NeedsReviewChanges int `json:"NeedsReviewChanges,omitempty"`
+	Findings           int `json:"Findings,omitempty"`
+	FixedFindings      int `json:"FixedFindings,omitempty"`
+}
+
+// WorkflowResult is the WorkflowResult schema of openapi-v2.yml
+type WorkflowResult struct {
+	Path string `json:"Path,omitempty"`
+	// The remediated workflow, if it was changed and output is not diff
+	Output             string       `json:"Output,omitempty"`
+	Diff               string       `json:"Diff,omitempty"`
+	IsChanged          bool         `json:"IsChanged,omitempty"`
+	HasErrors          bool         `json:"HasErrors,omitempty"`
+	FailedStrictChecks bool         `json:"FailedStrictChecks,omitempty"`
+	Error              string       `json:"Error,omitempty"`
+	Findings           []Finding    `json:"Findings,omitempty"`
+	JobErrors          []JobError   `json:"JobErrors,omitempty"`
+	MissingActions     []string     `json:"MissingActions,omitempty"`
+	Modules            []Module     `json:"Modules,omitempty"`
+	Statuses           []Status     `json:"Statuses,omitempty"`
+	Score              *ScoreChange `json:"Score,omitempty"`
+}
+
+// SecureWorkflowResponse is the SecureWorkflowResponse schema of openapi-v2.yml
+type SecureWorkflowResponse struct {
+	APIVersion         string           `json:"APIVersion,omitempty"`
+	Results            []WorkflowResult `json:"Results,omitempty"`
+	IsChanged          bool             `json:"IsChanged,omitempty"`
+	HasErrors          bool             `json:"HasErrors,omitempty"`
+	FailedStrictChecks bool             `json:"FailedStrictChecks,omitempty"`
+	Score              *ScoreChange     `json:"Score,omitempty"`
+	Summary            *Summary         `json:"Summary,omitempty"`
+}
+
+// FileResult is the FileResult schema of openapi-v2.yml
+type FileResult struct {
+	Path     string `json:"Path,omitempty"`
+	FileType string `json:"FileType,omitempty"`
+	// The remediated file, if it was changed and output is not diff
+	Output             string       `json:"Output,omitempty"`
+	Diff               string       `json:"Diff,omitempty"`
+	IsChanged          bool         `json:"IsChanged,omitempty"`
+	IsNew              bool         `json:"IsNew,omitempty"`
+	HasErrors          bool         `json:"HasErrors,omitempty"`
+	FailedStrictChecks bool         `json:"FailedStrictChecks,omitempty"`
+	Error              string       `json:"Error,omitempty"`
+	Findings           []Finding    `json:"Findings,omitempty"`
+	Modules            []Module     `json:"Modules,omitempty"`
+	Statuses           []Status     `json:"Statuses,omitempty"`
+	Score              *ScoreChange `json:"Score,omitempty"`
+}
+
+// SecureRepoResponseV2 is the SecureRepoResponse schema of openapi-v2.yml
+type SecureRepoResponseV2 struct {
+	APIVersion         string       `json:"APIVersion,omitempty"`
+	Files              []FileResult `json:"Files,omitempty"`
+	Archive            []byte       `json:"Archive,omitempty"`
+	IsChanged          bool         `json:"IsChanged,omitempty"`
+	HasErrors          bool         `json:"HasErrors,omitempty"`
+	FailedStrictChecks bool         `json:"FailedStrictChecks,omitempty"`
+	MissingActions     []string     `json:"MissingActions,omitempty"`
+	Score              *ScoreChange `json:"Score,omitempty"`
+	Summary            *Summary     `json:"Summary,omitempty"`
+}
+
+// SubmitRequest is the SubmitRequest schema of openapi-v2.yml
+type SubmitRequest struct {
+	Params    map[string]string `json:"Params,omitempty"`
+	Workflows []File            `json:"Workflows,omitempty"`
+	// The https URL that is posted the status of the job when it completes
+	CallbackURL string `json:"CallbackURL,omitempty"`
+}
+
+// Job is the Job schema of openapi-v2.yml
+type Job struct {
+	ID          string `json:"ID,omitempty"`
+	Status      string `json:"Status,omitempty"`
+	CallbackURL string `json:"CallbackURL,omitempty"`
+	Total       int    `json:"Total,omitempty"`
+	// The number of tasks with a result
+	Completed int `json:"Completed,omitempty"`
+	// The number of tasks whose result is an error after all attempts
+	Failed    int       `json:"Failed,omitempty"`
+	CreatedAt time.Time `json:"CreatedAt,omitempty"`
+}
+
+// JobStatus is the JobStatus schema of openapi-v2.yml
+type JobStatus struct {
+	ID          string           `json:"ID,omitempty"`
+	Status      string           `json:"Status,omitempty"`
+	CallbackURL string           `json:"CallbackURL,omitempty"`
+	Total       int              `json:"Total,omitempty"`
+	Completed   int              `json:"Completed,omitempty"`
+	Failed      int              `json:"Failed,omitempty"`
+	CreatedAt   time.Time        `json:"CreatedAt,omitempty"`
+	Results     []WorkflowResult `json:"Results,omitempty"`
+	Summary     *Summary         `json:"Summary,omitempty"`
+}
+
+// DescriptionRequest is the DescriptionRequest schema of openapi-v2.yml. The response of /v2/secure-repo, with the
+// repository and the kind of the request
+type DescriptionRequest struct {
+	Kind               string       `json:"Kind,omitempty"`
+	Repository         string       `json:"Repository,omitempty"`
+	APIVersion         string       `json:"APIVersion,omitempty"`
+	Files              []FileResult `json:"Files,omitempty"`
+	Archive            []byte       `json:"Archive,omitempty"`
+	IsChanged          bool         `json:"IsChanged,omitempty"`
+	HasErrors          bool         `json:"HasErrors,omitempty"`
+	FailedStrictChecks bool         `json:"FailedStrictChecks,omitempty"`
+	MissingActions     []string     `json:"MissingActions,omitempty"`
+	Score              *ScoreChange `json:"Score,omitempty"`
+	Summary            *Summary     `json:"Summary,omitempty"`
+}
+
+// DescriptionResponse is the DescriptionResponse schema of openapi-v2.yml
+type DescriptionResponse struct {
+	Description string `json:"Description,omitempty"`
+}
+
+// SecureWorkflow calls POST /secure-workflow of the v1 stage, to run the enabled remediations on a workflow.
+// Remediations that are off by default are enabled with their query parameter set to true, e.g. addShellDefaults,
+// checkUnmaintainedActions or fixVulnerableActions. The body is the workflow, if owner is not passed. The params are
+// the query parameters, which include the options of the remediations
+func (c *Client) SecureWorkflow(ctx context.Context, params map[string]string, body string) (*SecureWorkflowReponse, error) {
+	response := &SecureWorkflowReponse{}
+	if err := c.do(ctx, http.MethodPost, "/secure-workflow", params, nil, "text/plain", strings.NewReader(body), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SecureCompositeAction calls POST /secure-composite-action of the v1 stage, to pin actions and rewrite deprecated
+// commands in a composite action. The body is the action.yml, if owner is not passed. The params are the query
+// parameters, which include the options of the remediations
+func (c *Client) SecureCompositeAction(ctx context.Context, params map[string]string, body string) (*SecureCompositeActionResponse, error) {
+	response := &SecureCompositeActionResponse{}
+	if err := c.do(ctx, http.MethodPost, "/secure-composite-action", params, nil, "text/plain", strings.NewReader(body), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SecureDockerfile calls POST /secure-dockerfile of the v1 stage, to pin the base images of a Dockerfile. The body is
+// the Dockerfile, if owner is not passed. The params are the query parameters, which include the options of the
+// remediations
+func (c *Client) SecureDockerfile(ctx context.Context, params map[string]string, body string) (*SecureDockerfileResponse, error) {
+	response := &SecureDockerfileResponse{}
+	if err := c.do(ctx, http.MethodPost, "/secure-dockerfile", params, nil, "text/plain", strings.NewReader(body), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// UpdateDependabotConfig calls POST /update-dependabot-config of the v1 stage, to add update configurations for the
+// ecosystems to a dependabot config. The params are the query parameters, which include the options of the remediations
+func (c *Client) UpdateDependabotConfig(ctx context.Context, params map[string]string, request UpdateDependabotConfigRequest) (*UpdateDependabotConfigResponse, error) {
+	content, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response := &UpdateDependabotConfigResponse{}
+	if err := c.do(ctx, http.MethodPost, "/update-dependabot-config", params, nil, "application/json", bytes.NewReader(content), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// UpdateCodeowners calls POST /update-codeowners of the v1 stage, to add owners for the workflows and other security
+// sensitive files to CODEOWNERS. The params are the query parameters, which include the options of the remediations
+func (c *Client) UpdateCodeowners(ctx context.Context, params map[string]string, request UpdateCodeownersRequest) (*UpdateCodeownersResponse, error) {
+	content, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response := &UpdateCodeownersResponse{}
+	if err := c.do(ctx, http.MethodPost, "/update-codeowners", params, nil, "application/json", bytes.NewReader(content), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SecureRepo calls POST /secure-repo of the v1 stage, to run the remediations on all files of a repository. The query
+// parameters of /secure-workflow are passed on to the remediation of each workflow. The params are the query
+// parameters, which include the options of the remediations
+func (c *Client) SecureRepo(ctx context.Context, params map[string]string, request SecureRepoRequest) (*SecureRepoResponse, error) {
+	content, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response := &SecureRepoResponse{}
+	if err := c.do(ctx, http.MethodPost, "/secure-repo", params, nil, "application/json", bytes.NewReader(content), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// RepoPermissions calls POST /repo-permissions of the v1 stage, to add minimal permissions to all workflows of a
+// repository. The params are the query parameters, which include the options of the remediations
+func (c *Client) RepoPermissions(ctx context.Context, params map[string]string, request RepoPermissionsRequest) (*RepoPermissionsResponse, error) {
+	content, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response := &RepoPermissionsResponse{}
+	if err := c.do(ctx, http.MethodPost, "/repo-permissions", params, nil, "application/json", bytes.NewReader(content), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ExtractReusableWorkflows calls POST /extract-reusable-workflows of the v1 stage, to move the jobs of near-identical
+// workflows to hardened reusable workflows. The params are the query parameters, which include the options of the
+// remediations
+func (c *Client) ExtractReusableWorkflows(ctx context.Context, params map[string]string, request ReusableWorkflowsRequest) (*ReusableWorkflowsResponse, error) {
+	content, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response := &ReusableWorkflowsResponse{}
+	if err := c.do(ctx, http.MethodPost, "/extract-reusable-workflows", params, nil, "application/json", bytes.NewReader(content), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GithubAppWebhook calls POST /github-app-webhook of the v1 stage, to handle a webhook event of the GitHub App. The
+// params are the query parameters, which include the options of the remediations
+func (c *Client) GithubAppWebhook(ctx context.Context, xGitHubEvent string, xHubSignature256 string, params map[string]string, request json.RawMessage) (*WebhookResponse, error) {
+	response := &WebhookResponse{}
+	if err := c.do(ctx, http.MethodPost, "/github-app-webhook", params, map[string]string{"X-GitHub-Event": xGitHubEvent, "X-Hub-Signature-256": xHubSignature256}, "application/json", bytes.NewReader(request), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GitlabMergeRequest calls POST /gitlab-merge-request of the v1 stage, to remediate a GitLab project and open a merge
+// request with the changes. The params are the query parameters, which include the options of the remediations
+func (c *Client) GitlabMergeRequest(ctx context.Context, privateToken string, project string, params map[string]string) (*ProjectResult, error) {
+	params = withValues(params, "project", project)
+	response := &ProjectResult{}
+	if err := c.do(ctx, http.MethodPost, "/gitlab-merge-request", params, map[string]string{"Private-Token": privateToken}, "", nil, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// RunCampaign calls POST /campaigns of the v1 stage, to start or resume a campaign that rolls out the remediations to
+// the repositories of an organization. A campaign lists the repositories of the installation of the token when it
+// starts, or takes the Repositories of the request, and each request remediates the next batch of them, opening a pull
+// request for each repository with changes. The campaign is resumed by posting its ID, with a new token if the previous
+// one expired, until its status is completed. A campaign split into Shards is resumed by a request for each shard,
+// which can run concurrently. The params are the query parameters, which include the options of the remediations
+func (c *Client) RunCampaign(ctx context.Context, xGitHubToken string, params map[string]string, request CampaignRequest) (*Campaign, error) {
+	content, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response := &Campaign{}
+	if err := c.do(ctx, http.MethodPost, "/campaigns", params, map[string]string{"X-GitHub-Token": xGitHubToken}, "application/json", bytes.NewReader(content), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetCampaign calls GET /campaigns of the v1 stage, to get the progress of a campaign with the results of all processed
+// repositories. The params are the query parameters, which include the options of the remediations
+func (c *Client) GetCampaign(ctx context.Context, id string, params map[string]string) (*Campaign, error) {
+	params = withValues(params, "id", id)
+	response := &Campaign{}
+	if err := c.do(ctx, http.MethodGet, "/campaigns", params, nil, "", nil, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// BitbucketPullRequest calls POST /bitbucket-pull-request of the v1 stage, to remediate a Bitbucket Cloud repository
+// and open a pull request with the changes. The params are the query parameters, which include the options of the
+// remediations
+func (c *Client) BitbucketPullRequest(ctx context.Context, xBitbucketToken string, repository string, params map[string]string) (*RepositoryResult, error) {
+	params = withValues(params, "repository", repository)
+	response := &RepositoryResult{}
+	if err := c.do(ctx, http.MethodPost, "/bitbucket-pull-request", params, map[string]string{"X-Bitbucket-Token": xBitbucketToken}, "", nil, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SecureWorkflowV2 calls POST /secure-workflow of the v2 stage, to run the enabled remediations on a batch of
+// workflows. The query parameters and the parameters of the request are the query parameters of /v1/secure-workflow,
+// and the parameters of the request take precedence. The errors of a workflow are returned in its result. The params
+// are the query parameters, which include the options of the remediations
+func (c *Client) SecureWorkflowV2(ctx context.Context, params map[string]string, request SecureWorkflowRequest) (*SecureWorkflowResponse, error) {
+	content, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response := &SecureWorkflowResponse{}
+	if err := c.stage("v2").do(ctx, http.MethodPost, "/secure-workflow", params, nil, "application/json", bytes.NewReader(content), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SecureRepoV2 calls POST /secure-repo of the v2 stage, to run the remediations on all files of a repository. The
+// request and the query parameters are the ones of /v1/secure-repo. The params are the query parameters, which include
+// the options of the remediations
+func (c *Client) SecureRepoV2(ctx context.Context, params map[string]string, request SecureRepoRequest) (*SecureRepoResponseV2, error) {
+	content, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response := &SecureRepoResponseV2{}
+	if err := c.stage("v2").do(ctx, http.MethodPost, "/secure-repo", params, nil, "application/json", bytes.NewReader(content), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SubmitJob calls POST /jobs of the v2 stage, to submit a batch of workflows to be secured asynchronously. For batches
+// that take longer than a request, such as the workflows of an organization or of a monorepo. Each workflow is secured
+// as a task of the job, with the query parameters and the parameters of /v2/secure-workflow, and a task that fails is
+// retried before its error is returned in its result. The status of the job is polled with GET, and is posted to
+// CallbackURL when the job completes, signed like the notifications with the X-StepSecurity-Signature-256 header. The
+// params are the query parameters, which include the options of the remediations
+func (c *Client) SubmitJob(ctx context.Context, params map[string]string, request SubmitRequest) (*Job, error) {
+	content, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response := &Job{}
+	if err := c.stage("v2").do(ctx, http.MethodPost, "/jobs", params, nil, "application/json", bytes.NewReader(content), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetJob calls GET /jobs of the v2 stage, to get the status of a job with the results of the tasks that completed. The
+// params are the query parameters, which include the options of the remediations
+func (c *Client) GetJob(ctx context.Context, id string, params map[string]string) (*JobStatus, error) {
+	params = withValues(params, "id", id)
+	response := &JobStatus{}
+	if err := c.stage("v2").do(ctx, http.MethodGet, "/jobs", params, nil, "", nil, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// PullRequestDescription calls POST /pull-request-description of the v2 stage, to generate the description of a pull
+// request with the changes of /v2/secure-repo. The description has a section for each kind of fix with the files it
+// changed, the counts of the changes and findings, and the change of the score with computeScore=true. It is generated
+// with the template of the tenant of the request, if the tenant has one, or with the default template. The params are
+// the query parameters, which include the options of the remediations
+func (c *Client) PullRequestDescription(ctx context.Context, params map[string]string, request DescriptionRequest) (*DescriptionResponse, error) {
+	content, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response := &DescriptionResponse{}
+	if err := c.stage("v2").do(ctx, http.MethodPost, "/pull-request-description", params, nil, "application/json", bytes.NewReader(content), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
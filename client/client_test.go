@@ -0,0 +1,189 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/client/internal/codegen"
+	"github.com/step-security/secure-repo/remediation/apiv2"
+	"github.com/step-security/secure-repo/remediation/bitbucket"
+	"github.com/step-security/secure-repo/remediation/codeowners"
+	"github.com/step-security/secure-repo/remediation/compositeaction"
+	"github.com/step-security/secure-repo/remediation/dependabot"
+	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/githubapp"
+	"github.com/step-security/secure-repo/remediation/gitlab"
+	"github.com/step-security/secure-repo/remediation/jobs"
+	"github.com/step-security/secure-repo/remediation/prbody"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/score"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+	"github.com/step-security/secure-repo/remediation/workflow"
+	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSecureWorkflow(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/secure-workflow" || r.URL.Query().Get("addHardenRunner") != "false" || string(body) != "on: push\n" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("unexpected request"))
+			return
+		}
+		w.Write([]byte(`{"FinalOutput": "on: push\npermissions: read-all\n", "AddedPermissions": true, "Findings": [{"RuleID": "missing-permissions"}]}`))
+	}))
+	defer server.Close()
+
+	c := New(server.URL + "/v1/")
+	response, err := c.SecureWorkflow(context.Background(), map[string]string{"addHardenRunner": "false"}, "on: push\n")
+	if err != nil {
+		t.Fatalf("SecureWorkflow() unexpected error = %v", err)
+	}
+	if response.FinalOutput != "on: push\npermissions: read-all\n" || !response.AddedPermissions || len(response.Findings) != 1 {
+		t.Errorf("SecureWorkflow() = %+v", response)
+	}
+
+	_, err = c.SecureWorkflow(context.Background(), nil, "on: push\n")
+	if err == nil || err.Error() != "/secure-workflow returned 500: unexpected request" {
+		t.Errorf("SecureWorkflow() error = %v, want error with the response", err)
+	}
+}
+
+func TestSecureRepo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path != "/secure-repo" || r.Header.Get("Content-Type") != "application/json" || !strings.Contains(string(body), `"Path":"Dockerfile"`) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"Files": {"Dockerfile": "FROM alpine@sha256:abc\n"}, "IsChanged": true}`))
+	}))
+	defer server.Close()
+
+	request := SecureRepoRequest{FileList: []File{{Path: "Dockerfile", Content: "FROM alpine\n"}}}
+	response, err := New(server.URL).SecureRepo(context.Background(), nil, request)
+	if err != nil {
+		t.Fatalf("SecureRepo() unexpected error = %v", err)
+	}
+	if !response.IsChanged || response.Files["Dockerfile"] != "FROM alpine@sha256:abc\n" {
+		t.Errorf("SecureRepo() = %+v", response)
+	}
+}
+
+func TestSecureWorkflowV2(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path != "/v2/secure-workflow" || !strings.Contains(string(body), `"Path":".github/workflows/ci.yml"`) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"APIVersion": "v2", "Results": [{"Path": ".github/workflows/ci.yml", "IsChanged": true}], "Summary": {"Files": 1, "SafeChanges": 2}}`))
+	}))
+	defer server.Close()
+
+	request := SecureWorkflowRequest{Workflows: []File{{Path: ".github/workflows/ci.yml", Content: "on: push\n"}}}
+	response, err := New(server.URL+"/v1").SecureWorkflowV2(context.Background(), nil, request)
+	if err != nil {
+		t.Fatalf("SecureWorkflowV2() unexpected error = %v", err)
+	}
+	if len(response.Results) != 1 || !response.Results[0].IsChanged || response.Summary.SafeChanges != 2 {
+		t.Errorf("SecureWorkflowV2() = %+v", response)
+	}
+}
+
+func TestGenerated(t *testing.T) {
+	source, err := codegen.Generate("../openapi/openapi.yml", "../openapi/openapi-v2.yml")
+	if err != nil {
+		t.Fatalf("Generate() unexpected error = %v", err)
+	}
+	generated, err := ioutil.ReadFile("client.go")
+	if err != nil {
+		t.Fatalf("unable to read client.go: %v", err)
+	}
+	if !bytes.Equal(source, generated) {
+		t.Errorf("client.go is not generated from the specification, run go generate ./client")
+	}
+}
+
+// jsonFields returns the names of the fields of the type in its JSON, with the fields of embedded structs
+func jsonFields(goType reflect.Type) []string {
+	var fields []string
+	for i := 0; i < goType.NumField(); i++ {
+		field := goType.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(field.Type)...)
+			continue
+		}
+		// unexported fields are not in the JSON
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" && !strings.HasPrefix(tag, ",") {
+			name = strings.Split(tag, ",")[0]
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// TestSchemas checks that the schemas in the OpenAPI specification have the same properties as the JSON of the Go types
+func TestSchemas(t *testing.T) {
+	specifications := map[string][]interface{}{
+		"../openapi/openapi.yml": {permissions.SecureWorkflowReponse{}, permissions.JobError{}, findings.Finding{}, report.Report{}, report.Module{}, report.Change{}, report.Edit{}, report.Skipped{},
+			docker.SecureDockerfileResponse{}, compositeaction.SecureCompositeActionResponse{}, dependabot.UpdateDependabotConfigRequest{}, dependabot.Ecosystem{},
+			dependabot.UpdateDependabotConfigResponse{}, codeowners.UpdateCodeownersRequest{}, codeowners.UpdateCodeownersResponse{}, securerepo.SecureRepoRequest{},
+			securerepo.File{}, securerepo.SecureRepoResponse{}, securerepo.FileReport{}, workflow.RepoPermissionsRequest{}, workflow.RepoPermissionsResponse{},
+			workflow.WorkflowPermissionsChange{}, workflow.RepoPermissionsSummary{}, workflow.ReusableWorkflowsRequest{},
+			workflow.ReusableWorkflowsResponse{}, workflow.ReusableWorkflow{}, workflow.ReusableWorkflowCaller{}, githubapp.WebhookResponse{}, githubapp.RepositoryResult{}, githubapp.CampaignRequest{}, githubapp.Campaign{}, githubapp.CampaignShard{},
+			gitlab.ProjectResult{}, bitbucket.RepositoryResult{}, score.ScoreChange{}, score.Score{}, score.CheckScore{}},
+		"../openapi/openapi-v2.yml": {apiv2.SecureWorkflowRequest{}, apiv2.Summary{}, apiv2.WorkflowResult{}, apiv2.SecureWorkflowResponse{},
+			apiv2.FileResult{}, apiv2.SecureRepoResponse{}, jobs.SubmitRequest{}, jobs.Job{}, jobs.JobStatus{},
+			prbody.DescriptionRequest{}, prbody.DescriptionResponse{}},
+	}
+	for file, types := range specifications {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("unable to read specification: %v", err)
+		}
+		var spec struct {
+			Components struct {
+				Schemas map[string]struct {
+					Properties map[string]interface{}
+				}
+			}
+		}
+		if err := yaml.Unmarshal(content, &spec); err != nil {
+			t.Fatalf("unable to parse specification: %v", err)
+		}
+
+		for _, value := range types {
+			goType := reflect.TypeOf(value)
+			schema, found := spec.Components.Schemas[goType.Name()]
+			if !found {
+				t.Errorf("schema %s is missing from %s", goType.Name(), file)
+				continue
+			}
+
+			var properties []string
+			fields := jsonFields(goType)
+			for property := range schema.Properties {
+				properties = append(properties, property)
+			}
+			sort.Strings(fields)
+			sort.Strings(properties)
+			if !reflect.DeepEqual(fields, properties) {
+				t.Errorf("schema %s of %s has properties %v, want %v", goType.Name(), file, properties, fields)
+			}
+		}
+	}
+}
@@ -0,0 +1,457 @@
+// Package codegen generates the Go client of the HTTP API from its OpenAPI specifications. The schemas are generated as
+// types, and the operations as methods of the Client, which sends the requests with its do method.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Header is the first line of the generated file
+const Header = "// Code generated by client/internal/generate from the OpenAPI specifications. DO NOT EDIT."
+
+// lineLength is the length the comments are wrapped at
+const lineLength = 120
+
+type specification struct {
+	file    string
+	Info    struct{ Version string }
+	Servers []struct{ URL string }
+	Paths   orderedMap
+	// Components are the parameters, responses and schemas referenced by the operations
+	Components struct {
+		Parameters map[string]*parameter
+		Responses  map[string]*response
+		Schemas    orderedMap
+	}
+	// names are the names of the types of the schemas
+	names map[string]string
+}
+
+type operation struct {
+	OperationID string `yaml:"operationId"`
+	Summary     string
+	Description string
+	Parameters  []*parameter
+	RequestBody *struct {
+		Description string
+		Content     map[string]*mediaType
+	} `yaml:"requestBody"`
+	Responses map[string]*response
+}
+
+type parameter struct {
+	Ref      string `yaml:"$ref"`
+	Name     string
+	In       string
+	Required bool
+}
+
+type response struct {
+	Ref     string `yaml:"$ref"`
+	Content map[string]*mediaType
+}
+
+type mediaType struct {
+	Schema *schema
+}
+
+type schema struct {
+	Ref                  string `yaml:"$ref"`
+	Type                 string
+	Format               string
+	Description          string
+	Properties           orderedMap
+	Items                *schema
+	AdditionalProperties *schema `yaml:"additionalProperties"`
+}
+
+// orderedMap is a mapping of the specification, with its keys in the order of the specification
+type orderedMap struct {
+	keys   []string
+	values map[string]*yaml.Node
+}
+
+func (m *orderedMap) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping", node.Line)
+	}
+	m.values = map[string]*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		m.keys = append(m.keys, node.Content[i].Value)
+		m.values[node.Content[i].Value] = node.Content[i+1]
+	}
+	return nil
+}
+
+func (m *orderedMap) schema(key string) (*schema, error) {
+	s := &schema{}
+	return s, m.values[key].Decode(s)
+}
+
+// generator generates the client of the specifications, which reference each other by their file name
+type generator struct {
+	specifications map[string]*specification
+	out            bytes.Buffer
+	imports        map[string]bool
+	// operations are the names of the methods of the operations written so far
+	operations map[string]bool
+	// stage is the stage of the first specification, whose operations are called with the URL of the client
+	stage string
+}
+
+// Generate returns the source of the client of the specifications. The types of the schemas and the methods of the
+// operations of a specification that have the name of one of a specification before it are suffixed with the major
+// version of their specification. The operations of the specifications with another stage than the first one call
+// that stage of the API.
+func Generate(paths ...string) ([]byte, error) {
+	g := &generator{specifications: map[string]*specification{}, imports: map[string]bool{"context": true, "net/http": true},
+		operations: map[string]bool{}}
+	var specifications []*specification
+	used := map[string]bool{}
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		spec := &specification{file: filepath.Base(path), names: map[string]string{}}
+		if err := yaml.Unmarshal(content, spec); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+		}
+		for _, name := range spec.Components.Schemas.keys {
+			typeName := name
+			if used[typeName] {
+				typeName += "V" + strings.Split(spec.Info.Version, ".")[0]
+			}
+			used[typeName] = true
+			spec.names[name] = typeName
+		}
+		g.specifications[spec.file] = spec
+		specifications = append(specifications, spec)
+	}
+	if len(specifications) > 0 {
+		g.stage = specifications[0].stage()
+	}
+
+	for _, spec := range specifications {
+		for _, name := range spec.Components.Schemas.keys {
+			if err := g.writeType(spec, name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, spec := range specifications {
+		for _, path := range spec.Paths.keys {
+			var methods orderedMap
+			if err := spec.Paths.values[path].Decode(&methods); err != nil {
+				return nil, err
+			}
+			for _, method := range methods.keys {
+				op := &operation{}
+				if err := methods.values[method].Decode(op); err != nil {
+					return nil, err
+				}
+				if err := g.writeOperation(spec, path, method, op); err != nil {
+					return nil, fmt.Errorf("%s %s: %v", strings.ToUpper(method), path, err)
+				}
+			}
+		}
+	}
+
+	var source bytes.Buffer
+	source.WriteString(Header + "\n\n")
+	source.WriteString("package client\n\nimport (\n")
+	for _, imp := range []string{"bytes", "context", "encoding/json", "net/http", "strings", "time"} {
+		if g.imports[imp] {
+			fmt.Fprintf(&source, "\t%q\n", imp)
+		}
+	}
+	source.WriteString(")\n")
+	source.Write(g.out.Bytes())
+	return format.Source(source.Bytes())
+}
+
+// stage returns the stage of the API of the specification, which is the path of its server, e.g. v1
+func (spec *specification) stage() string {
+	if len(spec.Servers) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(spec.Servers[0].URL, "{baseUrl}/")
+}
+
+// resolve returns the specification and the name of the component of the reference, e.g. openapi.yml#/components/schemas/File
+func (g *generator) resolve(spec *specification, ref string) (*specification, string, error) {
+	file, pointer := spec.file, ref
+	if i := strings.Index(ref, "#"); i > 0 {
+		file, pointer = ref[:i], ref[i:]
+	}
+	target, found := g.specifications[file]
+	if !found {
+		return nil, "", fmt.Errorf("reference %s to a specification that is not generated", ref)
+	}
+	parts := strings.Split(pointer, "/")
+	if len(parts) != 4 || parts[0] != "#" || parts[1] != "components" {
+		return nil, "", fmt.Errorf("unsupported reference %s", ref)
+	}
+	return target, parts[3], nil
+}
+
+// goType returns the Go type of the schema. Objects of other schemas are referenced by a pointer, so they can be left out.
+func (g *generator) goType(spec *specification, s *schema, pointer bool) (string, error) {
+	if s.Ref != "" {
+		target, name, err := g.resolve(spec, s.Ref)
+		if err != nil {
+			return "", err
+		}
+		typeName, found := target.names[name]
+		if !found {
+			return "", fmt.Errorf("schema %s is not defined", s.Ref)
+		}
+		if pointer {
+			return "*" + typeName, nil
+		}
+		return typeName, nil
+	}
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "byte":
+			return "[]byte", nil
+		case "date-time":
+			g.imports["time"] = true
+			return "time.Time", nil
+		}
+		return "string", nil
+	case "boolean":
+		return "bool", nil
+	case "integer":
+		return "int", nil
+	case "number":
+		return "float64", nil
+	case "array":
+		if s.Items == nil {
+			return "", fmt.Errorf("array without items")
+		}
+		item, err := g.goType(spec, s.Items, false)
+		return "[]" + item, err
+	case "object":
+		if s.AdditionalProperties != nil {
+			value, err := g.goType(spec, s.AdditionalProperties, false)
+			return "map[string]" + value, err
+		}
+		if len(s.Properties.keys) == 0 {
+			g.imports["encoding/json"] = true
+			return "json.RawMessage", nil
+		}
+	}
+	return "", fmt.Errorf("unsupported schema of type %q", s.Type)
+}
+
+func (g *generator) writeType(spec *specification, name string) error {
+	s, err := spec.Components.Schemas.schema(name)
+	if err != nil {
+		return err
+	}
+	typeName := spec.names[name]
+	g.writeComment("", fmt.Sprintf("%s is the %s schema of %s. %s", typeName, name, spec.file, s.Description))
+	fmt.Fprintf(&g.out, "type %s struct {\n", typeName)
+	for _, property := range s.Properties.keys {
+		propertySchema, err := s.Properties.schema(property)
+		if err != nil {
+			return err
+		}
+		fieldType, err := g.goType(spec, propertySchema, true)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %v", name, property, err)
+		}
+		if propertySchema.Description != "" {
+			g.writeComment("\t", propertySchema.Description)
+		}
+		fmt.Fprintf(&g.out, "\t%s %s `json:\"%s,omitempty\"`\n", exported(property), fieldType, property)
+	}
+	g.out.WriteString("}\n\n")
+	return nil
+}
+
+func (g *generator) writeOperation(spec *specification, path, method string, op *operation) error {
+	if op.OperationID == "" {
+		return fmt.Errorf("operation without an operationId")
+	}
+	name := exported(op.OperationID)
+	if g.operations[name] {
+		name += "V" + strings.Split(spec.Info.Version, ".")[0]
+	}
+	g.operations[name] = true
+	stage := spec.stage()
+	summary := op.Summary
+	if summary != "" {
+		summary = ", to " + strings.ToLower(summary[:1]) + summary[1:]
+	}
+	description := sentence(op.Description)
+	if op.RequestBody != nil && op.RequestBody.Description != "" {
+		description += " The body is " + sentence(strings.ToLower(op.RequestBody.Description[:1])+op.RequestBody.Description[1:])
+	}
+	g.writeComment("", fmt.Sprintf("%s calls %s %s of the %s stage%s. %s The params are the query parameters, which "+
+		"include the options of the remediations.", name, strings.ToUpper(method), path, stage, summary, description))
+
+	// the required parameters are arguments, the other query parameters are passed in params
+	args := []string{"ctx context.Context"}
+	var queryValues, headerValues []string
+	for _, p := range op.Parameters {
+		if p.Ref != "" {
+			target, paramName, err := g.resolve(spec, p.Ref)
+			if err != nil {
+				return err
+			}
+			if p = target.Components.Parameters[paramName]; p == nil {
+				return fmt.Errorf("parameter %s is not defined", paramName)
+			}
+		}
+		if !p.Required {
+			continue
+		}
+		arg := unexported(p.Name)
+		args = append(args, arg+" string")
+		switch p.In {
+		case "query":
+			queryValues = append(queryValues, fmt.Sprintf("%q, %s", p.Name, arg))
+		case "header":
+			headerValues = append(headerValues, fmt.Sprintf("%q: %s", p.Name, arg))
+		default:
+			return fmt.Errorf("unsupported parameter in %s", p.In)
+		}
+	}
+	args = append(args, "params map[string]string")
+
+	contentType, body := "\"\"", "nil"
+	var bodyCode string
+	if op.RequestBody != nil {
+		if media, found := op.RequestBody.Content["text/plain"]; found && media.Schema != nil && media.Schema.Type == "string" {
+			g.imports["strings"] = true
+			args = append(args, "body string")
+			contentType, body = "\"text/plain\"", "strings.NewReader(body)"
+		} else if media, found := op.RequestBody.Content["application/json"]; found && media.Schema != nil {
+			requestType, err := g.goType(spec, media.Schema, false)
+			if err != nil {
+				return err
+			}
+			args = append(args, "request "+requestType)
+			contentType = "\"application/json\""
+			if requestType == "json.RawMessage" {
+				// the raw body is sent as it is, e.g. a webhook event whose signature is computed on its bytes
+				g.imports["bytes"] = true
+				body = "bytes.NewReader(request)"
+			} else {
+				g.imports["bytes"] = true
+				g.imports["encoding/json"] = true
+				bodyCode = "\tcontent, err := json.Marshal(request)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n"
+				body = "bytes.NewReader(content)"
+			}
+		} else {
+			return fmt.Errorf("unsupported request body")
+		}
+	}
+
+	responseType := ""
+	for _, status := range []string{"200", "201", "202"} {
+		r := op.Responses[status]
+		if r == nil {
+			continue
+		}
+		if r.Ref != "" {
+			target, responseName, err := g.resolve(spec, r.Ref)
+			if err != nil {
+				return err
+			}
+			r = target.Components.Responses[responseName]
+		}
+		if media, found := r.Content["application/json"]; found && media.Schema != nil {
+			var err error
+			if responseType, err = g.goType(spec, media.Schema, false); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if responseType == "" {
+		return fmt.Errorf("no JSON response")
+	}
+
+	fmt.Fprintf(&g.out, "func (c *Client) %s(%s) (*%s, error) {\n", name, strings.Join(args, ", "), responseType)
+	g.out.WriteString(bodyCode)
+	if len(queryValues) > 0 {
+		fmt.Fprintf(&g.out, "\tparams = withValues(params, %s)\n", strings.Join(queryValues, ", "))
+	}
+	headers := "nil"
+	if len(headerValues) > 0 {
+		headers = "map[string]string{" + strings.Join(headerValues, ", ") + "}"
+	}
+	fmt.Fprintf(&g.out, "\tresponse := &%s{}\n", responseType)
+	client := "c"
+	if stage != g.stage {
+		client = fmt.Sprintf("c.stage(%q)", stage)
+	}
+	fmt.Fprintf(&g.out, "\tif err := %s.do(ctx, http.Method%s, %q, params, %s, %s, %s, response); err != nil {\n\t\treturn nil, err\n\t}\n",
+		client, exported(strings.ToLower(method)), path, headers, contentType, body)
+	g.out.WriteString("\treturn response, nil\n}\n\n")
+	return nil
+}
+
+// writeComment writes the text as a comment wrapped at lineLength, without a trailing period
+func (g *generator) writeComment(indent, text string) {
+	text = strings.TrimSuffix(strings.Join(strings.Fields(text), " "), ".")
+	line := indent + "//"
+	for _, word := range strings.Fields(text) {
+		if len(line)+1+len(word) > lineLength && line != indent+"//" {
+			g.out.WriteString(line + "\n")
+			line = indent + "//"
+		}
+		line += " " + word
+	}
+	g.out.WriteString(line + "\n")
+}
+
+// sentence returns the text ending with a period, or the empty text
+func sentence(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.HasSuffix(text, ".") {
+		return text
+	}
+	return text + "."
+}
+
+// exported returns the name with its first letter in upper case, without the characters that are not letters or digits
+func exported(name string) string {
+	name = identifier(name)
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// unexported returns the name with its first letter in lower case, e.g. xGitHubEvent for X-GitHub-Event
+func unexported(name string) string {
+	name = identifier(name)
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+func identifier(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9':
+			if upper {
+				r = []rune(strings.ToUpper(string(r)))[0]
+			}
+			b.WriteRune(r)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	return b.String()
+}
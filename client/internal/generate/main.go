@@ -0,0 +1,27 @@
+// Command generate writes the Go client generated from the OpenAPI specifications. It is run by go generate in the
+// directory of the client.
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+
+	"github.com/step-security/secure-repo/client/internal/codegen"
+)
+
+func main() {
+	output := flag.String("output", "client.go", "file the client is written to")
+	flag.Parse()
+	specifications := flag.Args()
+	if len(specifications) == 0 {
+		specifications = []string{"../openapi/openapi.yml", "../openapi/openapi-v2.yml"}
+	}
+	source, err := codegen.Generate(specifications...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*output, source, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,97 @@
+// Package client is a Go client for the HTTP API of secure-repo. The types and the methods of the operations in client.go
+// are generated from openapi/openapi.yml and openapi/openapi-v2.yml, and are generated again after the specifications
+// change with go generate ./client. The methods of version 2 are suffixed with V2 where version 1 has the same operation.
+package client
+
+//go:generate go run ./internal/generate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type Client struct {
+	// BaseURL is the URL of the API stage, e.g. https://example.execute-api.us-west-2.amazonaws.com/v1
+	BaseURL    string
+	HTTPClient *http.Client
+	// APIKey is sent in the x-api-key header, if it is set, for instances that require authentication
+	APIKey string
+}
+
+// New returns a client for the API at baseURL, which uses the default HTTP client
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// stage returns a copy of the client for another stage of the API, whose URL is the URL of the client with its last path
+// element replaced by the stage, e.g. https://example.com/v2 for https://example.com/v1
+func (c *Client) stage(name string) *Client {
+	stage := *c
+	if i := strings.LastIndex(c.BaseURL, "/"); i >= 0 {
+		stage.BaseURL = c.BaseURL[:i+1] + name
+	}
+	return &stage
+}
+
+// do sends the request to the route with the query parameters and the headers, and decodes the JSON response into
+// response. The SARIF logs returned with format=sarif are not decoded, since they are not the responses of the operations.
+func (c *Client) do(ctx context.Context, method, route string, params, headers map[string]string, contentType string, body io.Reader, response interface{}) error {
+	if params["format"] == "sarif" {
+		return fmt.Errorf("format sarif is not supported by the client")
+	}
+	query := url.Values{}
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	requestURL := c.BaseURL + route
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if c.APIKey != "" {
+		request.Header.Set("x-api-key", c.APIKey)
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	httpResponse, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return err
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", route, httpResponse.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	return json.Unmarshal(responseBody, response)
+}
+
+// withValues returns a copy of the query parameters with the values of the required parameters, passed as pairs of
+// their name and value
+func withValues(params map[string]string, pairs ...string) map[string]string {
+	values := make(map[string]string, len(params)+len(pairs)/2)
+	for key, value := range params {
+		values[key] = value
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		values[pairs[i]] = pairs[i+1]
+	}
+	return values
+}
@@ -0,0 +1,59 @@
+//go:build js && wasm
+
+// Command secure-repo-wasm is the WebAssembly build of the remediations that need neither the network nor the state of
+// the handler, for the dashboard to preview the fixes of unsaved workflows in the browser.
+//
+//	GOOS=js GOARCH=wasm go build -o secure-repo.wasm ./cmd/secure-repo-wasm
+//
+// It is loaded with the wasm_exec.js of the Go release it was built with, and sets the global function
+// secureWorkflowPreview(workflow, params), which takes the query parameters of /secure-workflow as an object, and
+// returns the JSON of the response, or an Error. The knowledge base of actions is embedded in the build.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"syscall/js"
+
+	knowledgebase "github.com/step-security/secure-repo/knowledge-base"
+	"github.com/step-security/secure-repo/remediation/preview"
+	"github.com/step-security/secure-repo/remediation/workflow/metadata"
+)
+
+func main() {
+	if err := metadata.SetKnowledgeBase(knowledgebase.Actions()); err != nil {
+		// the knowledge base is embedded, so it can always be read
+		panic(err)
+	}
+	js.Global().Set("secureWorkflowPreview", js.FuncOf(secureWorkflowPreview))
+	// the function is called by the page until it is closed
+	select {}
+}
+
+func secureWorkflowPreview(this js.Value, args []js.Value) interface{} {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return newError(fmt.Errorf("the workflow must be a string"))
+	}
+	params := map[string]string{}
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		keys := js.Global().Get("Object").Call("keys", args[1])
+		for i := 0; i < keys.Length(); i++ {
+			key := keys.Index(i).String()
+			// booleans and numbers are passed as the strings of the query parameters
+			params[key] = js.Global().Call("String", args[1].Get(key)).String()
+		}
+	}
+	response, err := preview.Preview(params, args[0].String())
+	if err != nil {
+		return newError(err)
+	}
+	output, err := json.Marshal(response)
+	if err != nil {
+		return newError(fmt.Errorf("unable to marshal response: %v", err))
+	}
+	return string(output)
+}
+
+func newError(err error) js.Value {
+	return js.Global().Get("Error").New(err.Error())
+}
@@ -0,0 +1,542 @@
+// Command secure-repo runs the remediations on a local checkout of a repository.
+//
+//	secure-repo fix --pin --permissions --harden-runner ./
+//
+// Changes are printed as a unified diff, or written to the files with --write.
+// The exit code is 1 if there are changes that were not written, or findings that were not fixed, and 2 on errors.
+// With --strict, it is also 1 if an action, image or job could not be remediated, so it can be used as a blocking check.
+//
+//	secure-repo filter --stdin-filename .github/workflows/ci.yml < ci.yml
+//
+// The filter command reads a file from stdin and writes the remediated file to stdout, with the findings on stderr,
+// for pre-commit hooks and format on save in editors.
+//
+//	secure-repo push --pin https://github.com/octo-org/app.git
+//
+// The push command clones the repository, and pushes the changes to a branch with a single commit on the default branch.
+//
+//	secure-repo lsp --kb knowledge-base/actions
+//
+// The lsp command is a language server of workflows on stdin and stdout, which reports the findings as diagnostics, and
+// offers pinning actions, setting permissions and adding Harden-Runner as quick fixes in editors.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/step-security/secure-repo/remediation/diff"
+	"github.com/step-security/secure-repo/remediation/githubapp"
+	"github.com/step-security/secure-repo/remediation/gitpush"
+	"github.com/step-security/secure-repo/remediation/lsp"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+	"github.com/step-security/secure-repo/remediation/workflow"
+	"github.com/step-security/secure-repo/remediation/workflow/metadata"
+)
+
+const (
+	exitOK       = 0
+	exitFindings = 1
+	exitError    = 2
+)
+
+// maxFileSize is the size of the largest file that is read, which leaves room for generated workflows
+const maxFileSize = 8 << 20
+
+// skippedDirectories are not searched for files to remediate
+var skippedDirectories = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// params are query parameters passed with --param name=value
+type params map[string]string
+
+func (p params) String() string {
+	var values []string
+	for key, value := range p {
+		values = append(values, key+"="+value)
+	}
+	return strings.Join(values, ",")
+}
+
+func (p params) Type() string {
+	return "name=value"
+}
+
+func (p params) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected name=value, got %s", value)
+	}
+	p[parts[0]] = parts[1]
+	return nil
+}
+
+// remediationFlags are the flags that select the remediations, shared by the commands
+type remediationFlags struct {
+	pinActions        *bool
+	addPermissions    *bool
+	addHardenRunner   *bool
+	strict            *bool
+	kbFolder          *string
+	queryStringParams params
+}
+
+func addRemediationFlags(flags *pflag.FlagSet) *remediationFlags {
+	f := &remediationFlags{queryStringParams: params{}}
+	f.pinActions = flags.Bool("pin", false, "pin actions to a full length commit SHA")
+	f.addPermissions = flags.Bool("permissions", false, "set minimum GITHUB_TOKEN permissions")
+	f.addHardenRunner = flags.Bool("harden-runner", false, "add the Harden-Runner action to each job")
+	f.strict = flags.Bool("strict", false, "fail if an action is not in the knowledge base or cannot be pinned, a uses has an expression, an image cannot be pinned or the permissions of a job cannot be set")
+	f.kbFolder = flags.String("kb", "", "path to the knowledge-base/actions folder, used to compute permissions instead of the knowledge base embedded in the binary")
+	flags.Var(f.queryStringParams, "param", "query parameter passed to the remediations as name=value, e.g. addShellDefaults=true (can be repeated)")
+	return f
+}
+
+// getParams returns the query parameters for the remediations, and reads the knowledge base from its folder if it was
+// passed, or from the knowledge base embedded in the binary
+func (f *remediationFlags) getParams() (map[string]string, error) {
+	if *f.kbFolder != "" {
+		os.Setenv("KBFolder", *f.kbFolder)
+	}
+	if err := metadata.IndexKnowledgeBase(); err != nil {
+		return nil, err
+	}
+
+	// the flags override the query parameters with the same name
+	queryStringParams := f.queryStringParams
+	if *f.pinActions || *f.addPermissions || *f.addHardenRunner {
+		queryStringParams["pinActions"] = fmt.Sprint(*f.pinActions)
+		queryStringParams["addPermissions"] = fmt.Sprint(*f.addPermissions)
+		queryStringParams["addHardenRunner"] = fmt.Sprint(*f.addHardenRunner)
+	}
+	if *f.strict {
+		queryStringParams["strict"] = "true"
+	}
+	// missing actions are stored by the hosted service only
+	queryStringParams["ignoreMissingKBs"] = "true"
+	return queryStringParams, nil
+}
+
+// repoFlags are the flags of the remediations of a whole repository, shared by fix and push
+type repoFlags struct {
+	updateDependabot   *bool
+	includeDockerfiles *bool
+	codeowners         *string
+}
+
+func addRepoFlags(flags *pflag.FlagSet) *repoFlags {
+	return &repoFlags{
+		updateDependabot:   flags.Bool("dependabot", false, "add or update the dependabot configuration"),
+		includeDockerfiles: flags.Bool("dockerfiles", false, "pin images in Dockerfiles to digests"),
+		codeowners:         flags.String("codeowners", "", "comma separated list of owners to add to CODEOWNERS"),
+	}
+}
+
+// setParams sets the query parameters of the flags
+func (f *repoFlags) setParams(queryStringParams map[string]string) {
+	queryStringParams["updateDependabotConfig"] = fmt.Sprint(*f.updateDependabot)
+	if *f.codeowners != "" {
+		queryStringParams["codeowners"] = *f.codeowners
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// remediationsHelp is the help of the commands about the remediation flags
+const remediationsHelp = "If none of --pin, --permissions and --harden-runner are set, all of them are applied."
+
+// run runs the command of the arguments, and returns its exit code. The errors of parsing the arguments are printed
+// with the usage of the command.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	// an interrupt cancels the requests to GitHub and the registries of the remediations
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	exitCode := exitOK
+	root := newRootCommand(&exitCode)
+	root.SetArgs(args)
+	root.SetIn(stdin)
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+	if err := root.ExecuteContext(ctx); err != nil {
+		return exitError
+	}
+	return exitCode
+}
+
+// newRootCommand returns the secure-repo command with its subcommands, which set the exit code when they run
+func newRootCommand(exitCode *int) *cobra.Command {
+	root := &cobra.Command{
+		Use:   "secure-repo",
+		Short: "Run the secure-repo remediations on a local checkout of a repository",
+		// a command is required
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return errors.New("missing command")
+		},
+		CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
+	}
+	root.AddCommand(newFixCommand(exitCode), newFilterCommand(exitCode), newPushCommand(exitCode), newLSPCommand(exitCode))
+	return root
+}
+
+// isCandidate returns true if the file could be remediated based on its path, so only these files are read
+func isCandidate(filePath string) bool {
+	name := filepath.Base(filePath)
+	return strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml") || name == "CODEOWNERS" ||
+		strings.Contains(strings.ToLower(name), "dockerfile")
+}
+
+// readFiles returns the content of the files in the directory that can be remediated, keyed by their slash separated path
+func readFiles(root string, includeDockerfiles bool) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if filePath != root && skippedDirectories[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Size() > maxFileSize {
+			return nil
+		}
+		relativePath, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		if !isCandidate(relativePath) {
+			return nil
+		}
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		fileType := securerepo.GetFileType(relativePath, string(content))
+		if fileType == "" || (fileType == securerepo.FileTypeDockerfile && !includeDockerfiles) {
+			return nil
+		}
+		files[relativePath] = string(content)
+		return nil
+	})
+	return files, err
+}
+
+// newFixCommand returns the fix command
+func newFixCommand(exitCode *int) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fix [flags] [directory]",
+		Short: "Remediate the files of a local checkout, and print the changes as a diff or write them",
+		Long:  "Remediate the files of a local checkout, and print the changes as a diff or write them.\n" + remediationsHelp,
+		Args:  cobra.MaximumNArgs(1),
+	}
+	remediations := addRemediationFlags(cmd.Flags())
+	repo := addRepoFlags(cmd.Flags())
+	write := cmd.Flags().Bool("write", false, "write the changes to the files instead of printing a diff")
+	format := cmd.Flags().String("format", "text", "output format: text prints a diff and the findings, sarif prints the findings as a SARIF log")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if *format != "text" && *format != "sarif" {
+			return fmt.Errorf("invalid format %s, expected text or sarif", *format)
+		}
+		root := "."
+		if len(args) == 1 {
+			root = args[0]
+		}
+		*exitCode = fix(cmd.Context(), root, remediations, repo, *write, *format, cmd.OutOrStdout(), cmd.ErrOrStderr())
+		return nil
+	}
+	return cmd
+}
+
+func fix(ctx context.Context, root string, remediations *remediationFlags, repo *repoFlags, write bool, format string, stdout, stderr io.Writer) int {
+
+	queryStringParams, err := remediations.getParams()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	repo.setParams(queryStringParams)
+	if format == "sarif" {
+		for _, param := range workflow.AnalyzerParams {
+			if _, found := queryStringParams[param]; !found {
+				queryStringParams[param] = "true"
+			}
+		}
+	}
+	files, err := readFiles(root, *repo.includeDockerfiles)
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to read %s: %v\n", root, err)
+		return exitError
+	}
+
+	response, err := securerepo.SecureRepo(ctx, queryStringParams, securerepo.SecureRepoRequest{Files: files}, nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to secure %s: %v\n", root, err)
+		return exitError
+	}
+
+	if format == "sarif" {
+		output, err := json.MarshalIndent(securerepo.GetSARIF(response.Report), "", "  ")
+		if err != nil {
+			fmt.Fprintf(stderr, "unable to write SARIF: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintln(stdout, string(output))
+	}
+
+	exitCode := exitOK
+	for _, fileReport := range response.Report {
+		if fileReport.Error != "" {
+			fmt.Fprintf(stderr, "%s: %s\n", fileReport.Path, fileReport.Error)
+		}
+		for _, finding := range fileReport.Findings {
+			if !finding.Fixed {
+				exitCode = exitFindings
+			}
+			if format == "sarif" || (finding.Fixed && write) {
+				continue
+			}
+			fmt.Fprintf(stderr, "%s:%d:%d: [%s] %s\n", fileReport.Path, finding.Line, finding.Column, finding.RuleID, finding.Message)
+		}
+		if !fileReport.IsChanged {
+			continue
+		}
+		if !write {
+			if format == "text" {
+				fmt.Fprint(stdout, diff.Unified(fileReport.Path, files[fileReport.Path], response.Files[fileReport.Path]))
+			}
+			exitCode = exitFindings
+			continue
+		}
+		filePath := filepath.Join(root, filepath.FromSlash(fileReport.Path))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			fmt.Fprintf(stderr, "unable to write %s: %v\n", fileReport.Path, err)
+			return exitError
+		}
+		if err := ioutil.WriteFile(filePath, []byte(response.Files[fileReport.Path]), 0644); err != nil {
+			fmt.Fprintf(stderr, "unable to write %s: %v\n", fileReport.Path, err)
+			return exitError
+		}
+		fmt.Fprintf(stdout, "updated %s\n", fileReport.Path)
+	}
+
+	if response.HasErrors {
+		return exitError
+	}
+	if response.FailedStrictChecks {
+		return exitFindings
+	}
+	return exitCode
+}
+
+// newFilterCommand returns the filter command
+func newFilterCommand(exitCode *int) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "filter [flags] < file",
+		Short: "Remediate the file read from stdin, and write it to stdout",
+		Long:  "Remediate the file read from stdin, and write it to stdout, with the findings that were not fixed on stderr.\n" + remediationsHelp,
+		Args:  cobra.NoArgs,
+	}
+	remediations := addRemediationFlags(cmd.Flags())
+	filePath := cmd.Flags().String("stdin-filename", ".github/workflows/workflow.yml", "path of the file in the repository, used to find the type of file")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		*exitCode = filter(cmd.Context(), remediations, *filePath, cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr())
+		return nil
+	}
+	return cmd
+}
+
+// filter remediates the file read from stdin, and writes it to stdout. The findings that were not fixed are written to stderr.
+// The path of the file decides how it is remediated. If the file cannot be remediated, or there is an error, the input is
+// written unchanged, so it is not lost when the output replaces the file.
+func filter(ctx context.Context, remediations *remediationFlags, filePath string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	input, err := ioutil.ReadAll(io.LimitReader(stdin, maxFileSize+1))
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to read stdin: %v\n", err)
+		return exitError
+	}
+	if len(input) > maxFileSize {
+		fmt.Fprintf(stderr, "input is larger than %d bytes\n", maxFileSize)
+		return exitError
+	}
+
+	queryStringParams, err := remediations.getParams()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	queryStringParams["updateDependabotConfig"] = "false"
+	relativePath := filepath.ToSlash(filepath.Clean(filePath))
+	request := securerepo.SecureRepoRequest{Files: map[string]string{relativePath: string(input)}}
+	response, err := securerepo.SecureRepo(ctx, queryStringParams, request, nil)
+	if err != nil {
+		stdout.Write(input)
+		fmt.Fprintf(stderr, "unable to secure %s: %v\n", relativePath, err)
+		return exitError
+	}
+
+	output, exitCode := string(input), exitOK
+	for _, fileReport := range response.Report {
+		if fileReport.Error != "" {
+			stdout.Write(input)
+			fmt.Fprintf(stderr, "%s: %s\n", fileReport.Path, fileReport.Error)
+			return exitError
+		}
+		for _, finding := range fileReport.Findings {
+			if !finding.Fixed {
+				exitCode = exitFindings
+				fmt.Fprintf(stderr, "%s:%d:%d: [%s] %s\n", fileReport.Path, finding.Line, finding.Column, finding.RuleID, finding.Message)
+			}
+		}
+		if fileReport.IsChanged {
+			output = response.Files[fileReport.Path]
+		}
+	}
+	fmt.Fprint(stdout, output)
+	if response.FailedStrictChecks {
+		return exitFindings
+	}
+	return exitCode
+}
+
+// newPushCommand returns the push command
+func newPushCommand(exitCode *int) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "push [flags] repository-url",
+		Short: "Clone a repository, remediate it, and push the changes to a branch",
+		Long:  "Clone a repository, remediate it, and push the changes to a branch with a single commit on the default branch.\n" + remediationsHelp,
+		Args:  cobra.ExactArgs(1),
+	}
+	flags := cmd.Flags()
+	remediations := addRemediationFlags(flags)
+	repo := addRepoFlags(flags)
+	opts := gitpush.Options{}
+	flags.StringVar(&opts.Branch, "branch", githubapp.RemediationBranch, "branch the changes are force pushed to")
+	flags.StringVar(&opts.BaseBranch, "base", "", "branch the changes are applied to, defaults to the default branch")
+	flags.StringVar(&opts.Message, "message", gitpush.DefaultMessage, "first line of the commit message")
+	flags.StringVar(&opts.Username, "username", gitpush.DefaultUsername, "username of the token, e.g. x-token-auth for Bitbucket")
+	flags.Int64Var(&opts.InstallationID, "installation-id", 0, "installation of the GitHub App used if there is no token, with GITHUB_APP_ID and GITHUB_APP_PRIVATE_KEY set")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		opts.URL = args[0]
+		*exitCode = push(cmd.Context(), remediations, repo, opts, cmd.OutOrStdout(), cmd.ErrOrStderr())
+		return nil
+	}
+	return cmd
+}
+
+// push clones the repository, runs the remediations on the clone, and commits and pushes the changes to a branch. The
+// clone and the push are authenticated with the token in GIT_TOKEN or PAT, or as an installation of the GitHub App.
+func push(ctx context.Context, remediations *remediationFlags, repo *repoFlags, opts gitpush.Options, stdout, stderr io.Writer) int {
+	opts.Token = os.Getenv("GIT_TOKEN")
+	if opts.Token == "" {
+		opts.Token = os.Getenv("PAT")
+	}
+
+	checkout, err := gitpush.Clone(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to clone %s: %v\n", opts.URL, err)
+		return exitError
+	}
+	defer checkout.Close()
+
+	queryStringParams, err := remediations.getParams()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	repo.setParams(queryStringParams)
+	files, err := readFiles(checkout.Dir, *repo.includeDockerfiles)
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to read %s: %v\n", opts.URL, err)
+		return exitError
+	}
+	response, err := securerepo.SecureRepo(ctx, queryStringParams, securerepo.SecureRepoRequest{Files: files}, nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to secure %s: %v\n", opts.URL, err)
+		return exitError
+	}
+	for _, fileReport := range response.Report {
+		if fileReport.Error != "" {
+			fmt.Fprintf(stderr, "%s: %s\n", fileReport.Path, fileReport.Error)
+		}
+	}
+	if response.HasErrors {
+		return exitError
+	}
+	// with --strict, the changes are not pushed if a part of the repository could not be remediated
+	if response.FailedStrictChecks {
+		for _, fileReport := range response.Report {
+			for _, finding := range fileReport.Findings {
+				if !finding.Fixed {
+					fmt.Fprintf(stderr, "%s:%d:%d: [%s] %s\n", fileReport.Path, finding.Line, finding.Column, finding.RuleID, finding.Message)
+				}
+			}
+		}
+		return exitFindings
+	}
+
+	if err := checkout.Apply(response.Files); err != nil {
+		fmt.Fprintf(stderr, "unable to apply the changes: %v\n", err)
+		return exitError
+	}
+	result, err := checkout.CommitAndPush(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to push the changes: %v\n", err)
+		return exitError
+	}
+	if !result.Pushed {
+		fmt.Fprintln(stdout, "no changes")
+		return exitOK
+	}
+	fmt.Fprintf(stdout, "pushed %s to %s\n", result.Commit, result.Branch)
+	return exitOK
+}
+
+// newLSPCommand returns the lsp command
+func newLSPCommand(exitCode *int) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lsp [flags]",
+		Short: "Run a language server of workflows on stdin and stdout",
+		Long:  "Run a language server of workflows on stdin and stdout.\nIf none of --pin, --permissions and --harden-runner are set, all of them are applied by the fix of all findings.",
+		Args:  cobra.NoArgs,
+	}
+	remediations := addRemediationFlags(cmd.Flags())
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		*exitCode = serveLSP(remediations, cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr())
+		return nil
+	}
+	return cmd
+}
+
+// serveLSP runs the language server on stdin and stdout until the editor exits it. The remediation flags select the
+// remediations of the fix of all findings, and the query parameters enable more checks.
+func serveLSP(remediations *remediationFlags, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	queryStringParams, err := remediations.getParams()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	if err := lsp.NewServer(queryStringParams).Serve(stdin, stdout); err != nil {
+		fmt.Fprintf(stderr, "language server stopped: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
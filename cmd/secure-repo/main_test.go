@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const buildWorkflow = `name: Build
+on: push
+
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v2
+`
+
+func setupRepo(t *testing.T) string {
+	root, err := ioutil.TempDir("", "secure-repo")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	files := map[string]string{
+		".github/workflows/build.yml":  buildWorkflow,
+		"node_modules/x/action.yml":    "runs:\n  using: composite\n  steps: []\n",
+		"README.md":                    "# readme\n",
+		".github/ISSUE_TEMPLATE/a.yml": "name: a\n",
+	}
+	for filePath, content := range files {
+		fullPath := filepath.Join(root, filepath.FromSlash(filePath))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("unable to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("unable to write file: %v", err)
+		}
+	}
+	return root
+}
+
+func TestReadFiles(t *testing.T) {
+	root := setupRepo(t)
+
+	files, err := readFiles(root, false)
+	if err != nil {
+		t.Fatalf("readFiles() unexpected error = %v", err)
+	}
+	if len(files) != 1 || files[".github/workflows/build.yml"] != buildWorkflow {
+		t.Errorf("readFiles() = %v, want only the workflow", files)
+	}
+}
+
+func TestFix(t *testing.T) {
+	root := setupRepo(t)
+
+	var stdout, stderr bytes.Buffer
+	exitCode := run([]string{"fix", "--permissions", root}, nil, &stdout, &stderr)
+	if exitCode != exitFindings {
+		t.Errorf("run() = %d, want %d, stderr: %s", exitCode, exitFindings, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "+++ b/.github/workflows/build.yml") || !strings.Contains(stdout.String(), "+  contents: read") {
+		t.Errorf("run() printed unexpected diff: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	exitCode = run([]string{"fix", "--permissions", "--write", root}, nil, &stdout, &stderr)
+	if exitCode != exitOK {
+		t.Errorf("run() = %d, want %d, stderr: %s", exitCode, exitOK, stderr.String())
+	}
+	if stdout.String() != "updated .github/workflows/build.yml\n" {
+		t.Errorf("run() printed %s", stdout.String())
+	}
+	content, err := ioutil.ReadFile(filepath.Join(root, ".github", "workflows", "build.yml"))
+	if err != nil {
+		t.Fatalf("unable to read workflow: %v", err)
+	}
+	if !strings.Contains(string(content), "contents: read") {
+		t.Errorf("workflow was not updated: %s", content)
+	}
+
+	// there is nothing left to fix
+	stdout.Reset()
+	exitCode = run([]string{"fix", "--permissions", root}, nil, &stdout, &stderr)
+	if exitCode != exitOK || stdout.Len() != 0 {
+		t.Errorf("run() = %d, printed %s, want no changes", exitCode, stdout.String())
+	}
+}
+
+func TestFixSARIF(t *testing.T) {
+	root := setupRepo(t)
+
+	var stdout, stderr bytes.Buffer
+	exitCode := run([]string{"fix", "--permissions", "--format", "sarif", root}, nil, &stdout, &stderr)
+	if exitCode != exitFindings {
+		t.Errorf("run() = %d, want %d, stderr: %s", exitCode, exitFindings, stderr.String())
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleID     string
+				Fixes      []interface{}
+				Properties map[string]interface{}
+			}
+		}
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &log); err != nil {
+		t.Fatalf("run() printed invalid SARIF: %v, %s", err, stdout.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("run() printed unexpected SARIF: %s", stdout.String())
+	}
+	fixed := map[string]bool{}
+	for _, result := range log.Runs[0].Results {
+		fixed[result.RuleID] = result.Properties["fixed"] == true
+		// fixed results have the change as a suggested fix
+		if fixed[result.RuleID] != (len(result.Fixes) == 1) {
+			t.Errorf("unexpected fixes for %s: %v", result.RuleID, result.Fixes)
+		}
+	}
+	// permissions are fixed, but the action is not pinned
+	if isFixed, ok := fixed["missing-permissions"]; !ok || !isFixed {
+		t.Errorf("expected fixed missing-permissions result: %s", stdout.String())
+	}
+	if isFixed, ok := fixed["unpinned-action"]; !ok || isFixed {
+		t.Errorf("expected unfixed unpinned-action result: %s", stdout.String())
+	}
+}
+
+func TestFilter(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	exitCode := run([]string{"filter", "--permissions", "--stdin-filename", ".github/workflows/build.yml"}, strings.NewReader(buildWorkflow), &stdout, &stderr)
+	if exitCode != exitOK {
+		t.Errorf("run() = %d, want %d, stderr: %s", exitCode, exitOK, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "  contents: read\n") {
+		t.Errorf("run() printed unexpected workflow: %s", stdout.String())
+	}
+
+	// files that cannot be remediated are written unchanged
+	const invalidWorkflow = "on: push\njobs: [\n"
+	stdout.Reset()
+	exitCode = run([]string{"filter", "--permissions"}, strings.NewReader(invalidWorkflow), &stdout, &stderr)
+	if exitCode != exitOK || stdout.String() != invalidWorkflow {
+		t.Errorf("run() = %d, printed %s, want the input", exitCode, stdout.String())
+	}
+}
+
+func TestFilterStrict(t *testing.T) {
+	const dynamicWorkflow = `name: Build
+on: push
+
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: ${{ matrix.action }}
+`
+	// the maintenance of the actions is checked with requests to GitHub
+	args := []string{"filter", "--permissions", "--param", "checkUnmaintainedActions=false"}
+	var stdout, stderr bytes.Buffer
+	if exitCode := run(args, strings.NewReader(dynamicWorkflow), &stdout, &stderr); exitCode != exitOK {
+		t.Errorf("run() = %d, want %d, stderr: %s", exitCode, exitOK, stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	exitCode := run(append(args, "--strict"), strings.NewReader(dynamicWorkflow), &stdout, &stderr)
+	if exitCode != exitFindings {
+		t.Errorf("run() = %d, want %d, stderr: %s", exitCode, exitFindings, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), ".github/workflows/workflow.yml:8:15: [dynamic-uses]") || !strings.Contains(stderr.String(), "[skipped-job]") {
+		t.Errorf("run() printed unexpected findings: %s", stderr.String())
+	}
+	if stdout.String() != dynamicWorkflow {
+		t.Errorf("run() printed unexpected workflow: %s", stdout.String())
+	}
+}
+
+func TestLSP(t *testing.T) {
+	var input strings.Builder
+	for _, message := range []string{`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"capabilities": {}}}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "shutdown"}`, `{"jsonrpc": "2.0", "method": "exit"}`} {
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(message), message)
+	}
+	var stdout, stderr bytes.Buffer
+	if exitCode := run([]string{"lsp"}, strings.NewReader(input.String()), &stdout, &stderr); exitCode != exitOK {
+		t.Errorf("run() = %d, want %d: %s", exitCode, exitOK, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"textDocumentSync":1`) || !strings.Contains(stdout.String(), `{"jsonrpc":"2.0","id":2,"result":null}`) {
+		t.Errorf("run() printed %s, want the responses to initialize and shutdown", stdout.String())
+	}
+
+	// the editor closed stdin without exiting the server
+	stdout.Reset()
+	if exitCode := run([]string{"lsp"}, strings.NewReader(""), &stdout, &stderr); exitCode != exitError {
+		t.Errorf("run() = %d, want %d", exitCode, exitError)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if exitCode := run([]string{"secure"}, nil, &stdout, &stderr); exitCode != exitError {
+		t.Errorf("run() = %d, want %d", exitCode, exitError)
+	}
+	if exitCode := run(nil, nil, &stdout, &stderr); exitCode != exitError {
+		t.Errorf("run() = %d, want %d", exitCode, exitError)
+	}
+	// the arguments of the commands are checked
+	if exitCode := run([]string{"push"}, nil, &stdout, &stderr); exitCode != exitError {
+		t.Errorf("run() = %d, want %d", exitCode, exitError)
+	}
+	if exitCode := run([]string{"fix", "--format", "xml"}, nil, &stdout, &stderr); exitCode != exitError {
+		t.Errorf("run() = %d, want %d", exitCode, exitError)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if exitCode := run([]string{"fix", "--help"}, nil, &stdout, &stderr); exitCode != exitOK {
+		t.Errorf("run() = %d, want %d", exitCode, exitOK)
+	}
+	if !strings.Contains(stdout.String(), "secure-repo fix [flags] [directory]") || !strings.Contains(stdout.String(), "--harden-runner") {
+		t.Errorf("run() printed unexpected help: %s", stdout.String())
+	}
+}
+
+func TestFixFlagsAfterDirectory(t *testing.T) {
+	root := setupRepo(t)
+
+	var stdout, stderr bytes.Buffer
+	if exitCode := run([]string{"fix", root, "--permissions", "--write"}, nil, &stdout, &stderr); exitCode != exitOK {
+		t.Errorf("run() = %d, want %d, stderr: %s", exitCode, exitOK, stderr.String())
+	}
+	if stdout.String() != "updated .github/workflows/build.yml\n" {
+		t.Errorf("run() printed %s", stdout.String())
+	}
+}
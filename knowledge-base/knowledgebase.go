@@ -0,0 +1,19 @@
+// Package knowledgebase embeds the knowledge base of the permissions of actions, so the binaries can be deployed on their
+// own, and the WebAssembly build, which has no file system, can read it. It is read from the KBFolder folder instead if
+// it is set, or refreshed from KB_REFRESH_URL, so it can be updated without a release.
+package knowledgebase
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed actions
+var files embed.FS
+
+// Actions returns the knowledge base of actions, which has a folder for each action, e.g. actions/checkout
+func Actions() fs.FS {
+	// actions is a folder of files, so it is always a valid sub folder
+	actions, _ := fs.Sub(files, "actions")
+	return actions
+}
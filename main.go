@@ -2,23 +2,153 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"path"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/apiv2"
+	"github.com/step-security/secure-repo/remediation/auth"
+	"github.com/step-security/secure-repo/remediation/awssession"
+	"github.com/step-security/secure-repo/remediation/bitbucket"
+	"github.com/step-security/secure-repo/remediation/cache"
+	"github.com/step-security/secure-repo/remediation/codeowners"
+	"github.com/step-security/secure-repo/remediation/compositeaction"
 	"github.com/step-security/secure-repo/remediation/dependabot"
+	"github.com/step-security/secure-repo/remediation/diff"
 	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/githubapp"
+	"github.com/step-security/secure-repo/remediation/gitlab"
+	"github.com/step-security/secure-repo/remediation/grpcapi"
+	"github.com/step-security/secure-repo/remediation/jobs"
+	"github.com/step-security/secure-repo/remediation/kbrefresh"
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/metrics"
+	"github.com/step-security/secure-repo/remediation/notify"
+	"github.com/step-security/secure-repo/remediation/prbody"
+	"github.com/step-security/secure-repo/remediation/profiling"
+	"github.com/step-security/secure-repo/remediation/report"
 	"github.com/step-security/secure-repo/remediation/secrets"
+	"github.com/step-security/secure-repo/remediation/securerepo"
 	"github.com/step-security/secure-repo/remediation/workflow"
+	"github.com/step-security/secure-repo/remediation/workflow/metadata"
 	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+	"github.com/step-security/secure-repo/remediation/workflow/policy"
 )
 
 type Handler struct {
+	// authenticator authenticates the requests and limits the requests of each tenant, if it is set
+	authenticator *auth.Authenticator
+	// jobs runs the batches of workflows submitted to /v2/jobs, if the queue and the table of the jobs are configured
+	jobs *jobs.Manager
+	// webhooks rejects the replayed deliveries of the GitHub App webhook, if it is set
+	webhooks *githubapp.WebhookVerifier
+}
+
+// routes are the routes of the API, which are the last segment of the path, after the stage
+var routes = []string{"secrets", "secure-workflow", "secure-dockerfile", "secure-composite-action", "update-dependabot-config",
+	"secure-repo", "github-app-webhook", "gitlab-merge-request", "bitbucket-pull-request", "repo-permissions", "extract-reusable-workflows", "update-codeowners", "metrics", "jobs", "campaigns",
+	"pull-request-description"}
+
+// getRoute returns the route of the path, which decides the handler and the authentication of the request and labels
+// its metrics. The route must be the last segment of the path, so a path such as /update-codeowners/secrets is the
+// secrets route only. The profiles are served under /debug/pprof, e.g. /debug/pprof/heap.
+func getRoute(rawPath string) string {
+	segments := strings.Split(strings.Trim(rawPath, "/"), "/")
+	last := segments[len(segments)-1]
+	for _, route := range routes {
+		if last == route {
+			return route
+		}
+	}
+	n := len(segments)
+	if n >= 2 && segments[n-2] == "debug" && segments[n-1] == "pprof" || n >= 3 && segments[n-3] == "debug" && segments[n-2] == "pprof" {
+		return "debug/pprof"
+	}
+	return "unknown"
+}
+
+// getAPIVersion returns the version of the API of the path, which is the stage it is served by, e.g. v2 for
+// /v2/secure-repo. The paths of the other stages are served by version 1.
+func getAPIVersion(rawPath string) string {
+	if strings.HasPrefix(rawPath, "/"+apiv2.Version+"/") {
+		return apiv2.Version
+	}
+	return "v1"
+}
+
+// requiresAuthentication returns false for the routes that authenticate requests themselves, which are the secrets with
+// the OIDC token of the workflow and the GitHub App webhook with its signature
+func requiresAuthentication(route string) bool {
+	return route != "secrets" && route != "github-app-webhook"
+}
+
+// getProfile returns the profile of the path, or the names of the profiles for /debug/pprof, if the profiles are enabled
+func getProfile(httpRequest *events.APIGatewayV2HTTPRequest) events.APIGatewayProxyResponse {
+	if !profiling.Enabled() {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNotFound}
+	}
+	name := path.Base(strings.TrimSuffix(httpRequest.RawPath, "/"))
+	if name == "pprof" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+			Body:       strings.Join(profiling.Profiles(), "\n") + "\n",
+		}
+	}
+	debug, _ := strconv.Atoi(httpRequest.QueryStringParameters["debug"])
+	var sb strings.Builder
+	if err := profiling.Write(&sb, name, debug); err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNotFound, Body: err.Error()}
+	}
+	if debug > 0 {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+			Body:       sb.String(),
+		}
+	}
+	// the profile is gzipped protobuf, which API Gateway returns from base64
+	return events.APIGatewayProxyResponse{
+		StatusCode:      http.StatusOK,
+		Headers:         map[string]string{"Content-Type": "application/octet-stream", "Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, name)},
+		Body:            base64.StdEncoding.EncodeToString([]byte(sb.String())),
+		IsBase64Encoded: true,
+	}
+}
+
+// isDiffOutput returns true if a unified diff is requested instead of the full content of the file
+func isDiffOutput(queryStringParams map[string]string) bool {
+	return queryStringParams["output"] == "diff"
+}
+
+// getDiffPath returns the path of the file in the diff, which is the path it was fetched from, if any
+func getDiffPath(queryStringParams map[string]string, defaultPath string) string {
+	if filePath := queryStringParams["path"]; filePath != "" {
+		return filePath
+	}
+	return defaultPath
+}
+
+// getRepository returns the owner and repo the files were fetched from, if any
+func getRepository(queryStringParams map[string]string) string {
+	if queryStringParams["owner"] == "" || queryStringParams["repo"] == "" {
+		return ""
+	}
+	return queryStringParams["owner"] + "/" + queryStringParams["repo"]
 }
 
 func (h Handler) Invoke(ctx context.Context, req []byte) ([]byte, error) {
@@ -29,12 +159,26 @@ func (h Handler) Invoke(ctx context.Context, req []byte) ([]byte, error) {
 
 	if err == nil && httpRequest.RawPath != "" {
 
-		sess := session.Must(session.NewSessionWithOptions(session.Options{
-			SharedConfigState: session.SharedConfigEnable,
-		}))
-
-		dynamoDbSvc := dynamodb.New(sess)
+		dynamoDbSvc := dynamoDBClient()
 		var response events.APIGatewayProxyResponse
+		route := getRoute(httpRequest.RawPath)
+		// the logs of the request have its id, so the logs of the remediations can be correlated with it
+		logger := logging.Logger().With("request_id", httpRequest.RequestContext.RequestID, "route", route)
+		if repository := getRepository(httpRequest.QueryStringParameters); repository != "" {
+			logger = logger.With("repository", repository)
+		}
+		start := time.Now()
+		defer func() {
+			duration := time.Since(start)
+			metrics.Requests.Inc(route, strconv.Itoa(response.StatusCode))
+			metrics.RequestDuration.Observe(duration.Seconds(), route)
+			attrs := []any{"method", httpRequest.RequestContext.HTTP.Method, "status", response.StatusCode, "duration_ms", duration.Milliseconds()}
+			if response.StatusCode >= http.StatusInternalServerError {
+				logger.Error("handled request", append(attrs, "error", response.Body)...)
+			} else {
+				logger.Info("handled request", attrs...)
+			}
+		}()
 
 		if httpRequest.RequestContext.HTTP.Method == "OPTIONS" {
 			response = events.APIGatewayProxyResponse{
@@ -44,7 +188,52 @@ func (h Handler) Invoke(ctx context.Context, req []byte) ([]byte, error) {
 			return returnValue, nil
 		}
 
-		if strings.Contains(httpRequest.RawPath, "/secrets") {
+		// tenant is the tenant of the request, if it is authenticated
+		var tenant *auth.Tenant
+		if h.authenticator != nil && requiresAuthentication(route) {
+			if tenant, err = h.authenticator.Authenticate(httpRequest.Headers); err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Body:       err.Error(),
+				}
+				if authError, ok := err.(*auth.Error); ok {
+					response.StatusCode = authError.StatusCode
+					if authError.RetryAfter > 0 {
+						response.Headers = map[string]string{"Retry-After": strconv.Itoa(authError.RetryAfter)}
+					}
+				}
+				returnValue, _ := json.Marshal(&response)
+				return returnValue, nil
+			}
+		}
+
+		// version 2 has its own schemas for the routes it changes, and serves the other routes as version 1
+		if getAPIVersion(httpRequest.RawPath) == apiv2.Version && apiv2.HasRoute(route) {
+			response = h.invokeV2(ctx, route, httpRequest, dynamoDbSvc, logger)
+			returnValue, _ := json.Marshal(&response)
+			return returnValue, nil
+		}
+
+		switch route {
+		case "metrics":
+			var sb strings.Builder
+			metrics.DefaultRegistry.Write(&sb)
+			response = events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Headers:    map[string]string{"Content-Type": metrics.ContentType},
+				Body:       sb.String(),
+			}
+			returnValue, _ := json.Marshal(&response)
+			return returnValue, nil
+
+		// the profiles are served when they are enabled, e.g. /debug/pprof/heap for go tool pprof, or
+		// /debug/pprof/heap?debug=1 for text
+		case "debug/pprof":
+			response = getProfile(httpRequest)
+			returnValue, _ := json.Marshal(&response)
+			return returnValue, nil
+
+		case "secrets":
 			if httpRequest.RequestContext.HTTP.Method == "GET" {
 				authHeader := httpRequest.Headers["authorization"]
 				githubWorkflowSecrets, err := secrets.GetSecrets(httpRequest.QueryStringParameters, authHeader, dynamoDbSvc, false)
@@ -103,17 +292,17 @@ func (h Handler) Invoke(ctx context.Context, req []byte) ([]byte, error) {
 					}
 				}
 			}
-		}
 
-		if strings.Contains(httpRequest.RawPath, "/secure-workflow") {
+		case "secure-workflow":
 
 			inputYaml := ""
 			queryStringParams := httpRequest.QueryStringParameters
 			// if owner is set, assuming that repo, path are also set
 			// get the workflow using API
 			if _, ok := queryStringParams["owner"]; ok {
-				inputYaml, err = workflow.GetGitHubWorkflowContents(httpRequest.QueryStringParameters)
+				inputYaml, err = workflow.GetGitHubWorkflowContents(ctx, httpRequest.QueryStringParameters)
 				if err != nil {
+					logger.Warn("unable to fetch workflow", "path", queryStringParams["path"], "error", err)
 					fixResponse := &permissions.SecureWorkflowReponse{WorkflowFetchError: true, HasErrors: true}
 					output, _ := json.Marshal(fixResponse)
 					response = events.APIGatewayProxyResponse{
@@ -128,7 +317,7 @@ func (h Handler) Invoke(ctx context.Context, req []byte) ([]byte, error) {
 				inputYaml = httpRequest.Body
 			}
 
-			fixResponse, err := workflow.SecureWorkflow(httpRequest.QueryStringParameters, inputYaml, dynamoDbSvc)
+			fixResponse, err := workflow.SecureWorkflowCached(ctx, cache.Default(), httpRequest.QueryStringParameters, inputYaml, dynamoDbSvc, workflow.Options{Logger: logger})
 
 			if err != nil {
 				response = events.APIGatewayProxyResponse{
@@ -136,6 +325,14 @@ func (h Handler) Invoke(ctx context.Context, req []byte) ([]byte, error) {
 					Body:       err.Error(),
 				}
 			} else {
+				if fixResponse.FinalOutput != fixResponse.OriginalInput {
+					notify.Notify(&notify.Notification{Event: notify.EventComputed, Repository: getRepository(queryStringParams),
+						Path: queryStringParams["path"], Report: fixResponse.Report})
+				}
+				if isDiffOutput(queryStringParams) {
+					fixResponse.Diff = diff.Unified(getDiffPath(queryStringParams, ".github/workflows/workflow.yml"), fixResponse.OriginalInput, fixResponse.FinalOutput)
+					fixResponse.OriginalInput, fixResponse.FinalOutput = "", ""
+				}
 
 				output, _ := json.Marshal(fixResponse)
 				response = events.APIGatewayProxyResponse{
@@ -144,16 +341,14 @@ func (h Handler) Invoke(ctx context.Context, req []byte) ([]byte, error) {
 				}
 			}
 
-		}
-
-		if strings.Contains(httpRequest.RawPath, "/secure-dockerfile") {
+		case "secure-dockerfile":
 
 			dockerFile := ""
 			queryStringParams := httpRequest.QueryStringParameters
 			// if owner is set, assuming that repo, path are also set
 			// get the dockerfile using API
 			if _, ok := queryStringParams["owner"]; ok {
-				dockerFile, err = workflow.GetGitHubWorkflowContents(httpRequest.QueryStringParameters)
+				dockerFile, err = workflow.GetGitHubWorkflowContents(ctx, httpRequest.QueryStringParameters)
 				if err != nil {
 					fixResponse := &docker.SecureDockerfileResponse{DockerfileFetchError: true}
 					output, _ := json.Marshal(fixResponse)
@@ -169,13 +364,22 @@ func (h Handler) Invoke(ctx context.Context, req []byte) ([]byte, error) {
 				dockerFile = httpRequest.Body
 			}
 
-			fixResponse, err := docker.SecureDockerFile(dockerFile)
+			dockerfileConfig := docker.DockerfileConfig{
+				AddNonRootUser: queryStringParams["addNonRootUser"] == "true",
+			}
+			fixResponse, err := cache.Do(cache.Default(), cache.Key("secure-dockerfile", dockerfileConfig, dockerFile), func() (*docker.SecureDockerfileResponse, error) {
+				return docker.SecureDockerFile(ctx, dockerFile, dockerfileConfig)
+			})
 			if err != nil {
 				response = events.APIGatewayProxyResponse{
 					StatusCode: http.StatusInternalServerError,
 					Body:       err.Error(),
 				}
 			} else {
+				if isDiffOutput(queryStringParams) {
+					fixResponse.Diff = diff.Unified(getDiffPath(queryStringParams, "Dockerfile"), fixResponse.OriginalInput, fixResponse.FinalOutput)
+					fixResponse.OriginalInput, fixResponse.FinalOutput = "", ""
+				}
 
 				output, _ := json.Marshal(fixResponse)
 				response = events.APIGatewayProxyResponse{
@@ -184,9 +388,52 @@ func (h Handler) Invoke(ctx context.Context, req []byte) ([]byte, error) {
 				}
 			}
 
-		}
+		case "secure-composite-action":
+
+			actionYaml := ""
+			queryStringParams := httpRequest.QueryStringParameters
+			// if owner is set, assuming that repo, path are also set
+			// get the action using API
+			if _, ok := queryStringParams["owner"]; ok {
+				actionYaml, err = workflow.GetGitHubWorkflowContents(ctx, httpRequest.QueryStringParameters)
+				if err != nil {
+					fixResponse := &compositeaction.SecureCompositeActionResponse{ActionFetchError: true, HasErrors: true}
+					output, _ := json.Marshal(fixResponse)
+					response = events.APIGatewayProxyResponse{
+						StatusCode: http.StatusOK,
+						Body:       string(output),
+					}
+					returnValue, _ := json.Marshal(&response)
+					return returnValue, nil
+				}
+			} else {
+				// if owner is not set, then action should be sent in the body
+				actionYaml = httpRequest.Body
+			}
+
+			actionParams := cache.WithoutParams(queryStringParams, workflow.RepositoryParams...)
+			fixResponse, err := cache.Do(cache.Default(), cache.Key("secure-composite-action", actionParams, actionYaml), func() (*compositeaction.SecureCompositeActionResponse, error) {
+				return compositeaction.SecureCompositeAction(actionParams, actionYaml)
+			})
+			if err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Body:       err.Error(),
+				}
+			} else {
+				if isDiffOutput(queryStringParams) {
+					fixResponse.Diff = diff.Unified(getDiffPath(queryStringParams, "action.yml"), fixResponse.OriginalInput, fixResponse.FinalOutput)
+					fixResponse.OriginalInput, fixResponse.FinalOutput = "", ""
+				}
+
+				output, _ := json.Marshal(fixResponse)
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusOK,
+					Body:       string(output),
+				}
+			}
 
-		if strings.Contains(httpRequest.RawPath, "/update-dependabot-config") {
+		case "update-dependabot-config":
 
 			updateDependabotConfigRequest := ""
 			updateDependabotConfigRequest = httpRequest.Body
@@ -206,6 +453,324 @@ func (h Handler) Invoke(ctx context.Context, req []byte) ([]byte, error) {
 				}
 			}
 
+		case "secure-repo":
+
+			var secureRepoRequest securerepo.SecureRepoRequest
+			err := json.Unmarshal([]byte(httpRequest.Body), &secureRepoRequest)
+			if err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusBadRequest,
+					Body:       err.Error(),
+				}
+				returnValue, _ := json.Marshal(&response)
+				return returnValue, nil
+			}
+
+			// format=sarif returns the findings as a SARIF log, with the analyzers enabled
+			isSARIF := httpRequest.QueryStringParameters["format"] == "sarif"
+			if isSARIF {
+				for _, param := range workflow.AnalyzerParams {
+					if _, found := httpRequest.QueryStringParameters[param]; !found {
+						httpRequest.QueryStringParameters[param] = "true"
+					}
+				}
+			}
+
+			fixResponse, err := securerepo.SecureRepo(ctx, httpRequest.QueryStringParameters, secureRepoRequest, dynamoDbSvc)
+			if err == nil && fixResponse.IsChanged {
+				notify.Notify(&notify.Notification{Event: notify.EventComputed, Repository: getRepository(httpRequest.QueryStringParameters),
+					Report: fixResponse.Report})
+			}
+			if err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Body:       err.Error(),
+				}
+			} else if isSARIF {
+				output, _ := json.Marshal(securerepo.GetSARIF(fixResponse.Report))
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusOK,
+					Body:       string(output),
+				}
+			} else {
+
+				output, _ := json.Marshal(fixResponse)
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusOK,
+					Body:       string(output),
+				}
+			}
+
+		case "github-app-webhook":
+
+			// the signature is computed with the webhook secret of the GitHub App
+			webhookSecret := os.Getenv(githubapp.WebhookSecretEnv)
+			signature := httpRequest.Headers["x-hub-signature-256"]
+			if webhookSecret == "" || github.ValidateSignature(signature, []byte(httpRequest.Body), []byte(webhookSecret)) != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusUnauthorized,
+					Body:       "invalid signature",
+				}
+				returnValue, _ := json.Marshal(&response)
+				return returnValue, nil
+			}
+
+			// a delivery that was already handled or an old event is rejected, so a replayed event does not open pull requests
+			eventType, deliveryID := httpRequest.Headers["x-github-event"], httpRequest.Headers["x-github-delivery"]
+			if h.webhooks != nil {
+				if err := h.webhooks.Verify(deliveryID, eventType, []byte(httpRequest.Body)); err != nil {
+					statusCode := http.StatusBadRequest
+					if err == githubapp.ErrDuplicateDelivery {
+						statusCode = http.StatusConflict
+					} else if err != githubapp.ErrStaleEvent && deliveryID != "" {
+						statusCode = http.StatusInternalServerError
+					}
+					logger.Warn("webhook delivery rejected", "delivery", deliveryID, "event", eventType, "error", err)
+					response = events.APIGatewayProxyResponse{
+						StatusCode: statusCode,
+						Body:       err.Error(),
+					}
+					returnValue, _ := json.Marshal(&response)
+					return returnValue, nil
+				}
+			}
+
+			webhookResponse, err := githubapp.HandleWebhook(ctx, eventType, []byte(httpRequest.Body), dynamoDbSvc)
+			if err != nil {
+				// the delivery is handled again if it is redelivered after an error
+				if h.webhooks != nil {
+					if forgetErr := h.webhooks.Forget([]byte(httpRequest.Body)); forgetErr != nil {
+						logger.Error("unable to forget webhook delivery", "delivery", deliveryID, "error", forgetErr)
+					}
+				}
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Body:       err.Error(),
+				}
+			} else {
+
+				output, _ := json.Marshal(webhookResponse)
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusOK,
+					Body:       string(output),
+				}
+			}
+
+		case "gitlab-merge-request":
+
+			// the project is remediated with the access token of the request, or the token of the instance
+			project := httpRequest.QueryStringParameters["project"]
+			client := gitlab.NewClient(httpRequest.Headers[strings.ToLower(gitlab.TokenHeader)])
+			if project == "" || client.Token == "" {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusBadRequest,
+					Body:       "project and a GitLab access token are required",
+				}
+				returnValue, _ := json.Marshal(&response)
+				return returnValue, nil
+			}
+
+			projectResult := gitlab.RemediateProject(ctx, client, project, dynamoDbSvc)
+			output, _ := json.Marshal(projectResult)
+			response = events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(output),
+			}
+
+		case "bitbucket-pull-request":
+
+			// the repository is remediated with the access token of the request, or the token of the instance
+			repository := httpRequest.QueryStringParameters["repository"]
+			client := bitbucket.NewClient(httpRequest.Headers[strings.ToLower(bitbucket.TokenHeader)])
+			if repository == "" || client.Token == "" {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusBadRequest,
+					Body:       "repository and a Bitbucket access token are required",
+				}
+				returnValue, _ := json.Marshal(&response)
+				return returnValue, nil
+			}
+
+			repositoryResult := bitbucket.RemediateRepository(ctx, client, repository, dynamoDbSvc)
+			output, _ := json.Marshal(repositoryResult)
+			response = events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       string(output),
+			}
+
+		case "campaigns":
+
+			// the campaigns are stored, so they can be resumed and polled, and the token is only used for the batch of the request
+			store, err := githubapp.NewCampaignStoreFromEnv(dynamoDbSvc)
+			if err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Body:       err.Error(),
+				}
+				returnValue, _ := json.Marshal(&response)
+				return returnValue, nil
+			}
+			if store == nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusNotImplemented,
+					Body:       "campaigns are not configured",
+				}
+				returnValue, _ := json.Marshal(&response)
+				return returnValue, nil
+			}
+
+			var campaign *githubapp.Campaign
+			if httpRequest.RequestContext.HTTP.Method == "GET" {
+				campaign, err = githubapp.GetCampaign(store, httpRequest.QueryStringParameters["id"])
+			} else {
+				var campaignRequest githubapp.CampaignRequest
+				token := httpRequest.Headers[strings.ToLower(githubapp.TokenHeader)]
+				if err := json.Unmarshal([]byte(httpRequest.Body), &campaignRequest); err != nil || token == "" {
+					response = events.APIGatewayProxyResponse{
+						StatusCode: http.StatusBadRequest,
+						Body:       "a campaign request and an installation token are required",
+					}
+					returnValue, _ := json.Marshal(&response)
+					return returnValue, nil
+				}
+				campaign, err = githubapp.RunCampaign(ctx, token, store, campaignRequest, dynamoDbSvc)
+			}
+			if errors.Is(err, githubapp.ErrShardRunning) {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusConflict,
+					Body:       err.Error(),
+				}
+			} else if err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Body:       err.Error(),
+				}
+			} else if campaign == nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusNotFound,
+					Body:       "campaign not found",
+				}
+			} else {
+
+				output, _ := json.Marshal(campaign)
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusOK,
+					Body:       string(output),
+				}
+			}
+
+		case "pull-request-description":
+
+			var descriptionRequest prbody.DescriptionRequest
+			if err := json.Unmarshal([]byte(httpRequest.Body), &descriptionRequest); err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusBadRequest,
+					Body:       err.Error(),
+				}
+				returnValue, _ := json.Marshal(&response)
+				return returnValue, nil
+			}
+			tenantID := ""
+			if tenant != nil {
+				tenantID = tenant.ID
+			}
+			templates, err := prbody.NewTemplateStoreFromEnv(dynamoDbSvc)
+			var descriptionResponse *prbody.DescriptionResponse
+			if err == nil {
+				descriptionResponse, err = prbody.GenerateForTenant(templates, tenantID, descriptionRequest)
+			}
+			if err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Body:       err.Error(),
+				}
+			} else {
+
+				output, _ := json.Marshal(descriptionResponse)
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusOK,
+					Body:       string(output),
+				}
+			}
+
+		case "repo-permissions":
+
+			var repoPermissionsRequest workflow.RepoPermissionsRequest
+			err := json.Unmarshal([]byte(httpRequest.Body), &repoPermissionsRequest)
+			if err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusBadRequest,
+					Body:       err.Error(),
+				}
+				returnValue, _ := json.Marshal(&response)
+				return returnValue, nil
+			}
+
+			fixResponse, err := workflow.SecureRepoPermissions(ctx, httpRequest.QueryStringParameters, repoPermissionsRequest, dynamoDbSvc)
+			if err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Body:       err.Error(),
+				}
+			} else {
+
+				output, _ := json.Marshal(fixResponse)
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusOK,
+					Body:       string(output),
+				}
+			}
+
+		case "extract-reusable-workflows":
+
+			var reusableWorkflowsRequest workflow.ReusableWorkflowsRequest
+			err := json.Unmarshal([]byte(httpRequest.Body), &reusableWorkflowsRequest)
+			if err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusBadRequest,
+					Body:       err.Error(),
+				}
+				returnValue, _ := json.Marshal(&response)
+				return returnValue, nil
+			}
+
+			fixResponse, err := workflow.ExtractReusableWorkflows(ctx, httpRequest.QueryStringParameters, reusableWorkflowsRequest, dynamoDbSvc)
+			if err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Body:       err.Error(),
+				}
+			} else {
+
+				output, _ := json.Marshal(fixResponse)
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusOK,
+					Body:       string(output),
+				}
+			}
+
+		case "update-codeowners":
+
+			fixResponse, err := codeowners.UpdateCodeowners(httpRequest.Body)
+			if err != nil {
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Body:       err.Error(),
+				}
+			} else {
+
+				output, _ := json.Marshal(fixResponse)
+				response = events.APIGatewayProxyResponse{
+					StatusCode: http.StatusOK,
+					Body:       string(output),
+				}
+			}
+
+		default:
+			response = events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "route not found",
+			}
 		}
 
 		returnValue, _ := json.Marshal(&response)
@@ -213,9 +778,162 @@ func (h Handler) Invoke(ctx context.Context, req []byte) ([]byte, error) {
 
 	}
 
+	// the tasks of the jobs are received from the queue of the jobs
+	sqsEvent := &events.SQSEvent{}
+	if jobs.IsEvent(req, sqsEvent) {
+		if h.jobs == nil {
+			return nil, fmt.Errorf("received SQSEvent but jobs are not configured")
+		}
+		return json.Marshal(h.jobs.HandleEvent(ctx, sqsEvent, dynamoDBClient()))
+	}
+
 	return nil, fmt.Errorf("request was neither APIGatewayV2HTTPRequest nor SQSEvent")
 }
 
+// invokeV2 serves the routes of version 2 of the API, which run the same remediations as version 1
+func (h Handler) invokeV2(ctx context.Context, route string, httpRequest *events.APIGatewayV2HTTPRequest, dynamoDbSvc *dynamodb.DynamoDB, logger *slog.Logger) events.APIGatewayProxyResponse {
+	queryStringParams := httpRequest.QueryStringParameters
+	var output interface{}
+	var err error
+	switch route {
+	case "secure-workflow":
+		var request apiv2.SecureWorkflowRequest
+		if err := json.Unmarshal([]byte(httpRequest.Body), &request); err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest, Body: err.Error()}
+		}
+		var fixResponse *apiv2.SecureWorkflowResponse
+		fixResponse, err = apiv2.SecureWorkflows(ctx, queryStringParams, request, dynamoDbSvc, logger)
+		if err == nil {
+			for _, result := range fixResponse.Results {
+				if result.IsChanged {
+					notify.Notify(&notify.Notification{Event: notify.EventComputed, Repository: getRepository(queryStringParams),
+						Path: result.Path, Report: &report.Report{Modules: result.Modules}})
+				}
+			}
+		}
+		output = fixResponse
+	case "secure-repo":
+		var request securerepo.SecureRepoRequest
+		if err := json.Unmarshal([]byte(httpRequest.Body), &request); err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest, Body: err.Error()}
+		}
+		var fixResponse *securerepo.SecureRepoResponse
+		fixResponse, err = securerepo.SecureRepo(ctx, queryStringParams, request, dynamoDbSvc)
+		if err == nil {
+			if fixResponse.IsChanged {
+				notify.Notify(&notify.Notification{Event: notify.EventComputed, Repository: getRepository(queryStringParams),
+					Report: fixResponse.Report})
+			}
+			output = apiv2.NewSecureRepoResponse(fixResponse)
+		}
+	case "jobs":
+		return h.invokeJobs(httpRequest)
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError, Body: err.Error()}
+	}
+	body, _ := json.Marshal(output)
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: string(body)}
+}
+
+// invokeJobs submits a job with POST, and returns the status of the job in the id query parameter with GET
+func (h Handler) invokeJobs(httpRequest *events.APIGatewayV2HTTPRequest) events.APIGatewayProxyResponse {
+	if h.jobs == nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNotImplemented, Body: "jobs are not configured"}
+	}
+	var output interface{}
+	statusCode := http.StatusOK
+	switch httpRequest.RequestContext.HTTP.Method {
+	case http.MethodPost:
+		var request jobs.SubmitRequest
+		if err := json.Unmarshal([]byte(httpRequest.Body), &request); err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest, Body: err.Error()}
+		}
+		job, err := h.jobs.Submit(httpRequest.QueryStringParameters, request)
+		if err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest, Body: err.Error()}
+		}
+		output, statusCode = job, http.StatusAccepted
+	case http.MethodGet:
+		status, err := h.jobs.Status(httpRequest.QueryStringParameters["id"])
+		if err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError, Body: err.Error()}
+		}
+		if status == nil {
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusNotFound, Body: "job not found"}
+		}
+		output = status
+	default:
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusMethodNotAllowed}
+	}
+	body, _ := json.Marshal(output)
+	return events.APIGatewayProxyResponse{StatusCode: statusCode, Body: string(body)}
+}
+
+// dynamoDBClient is the client of DynamoDB shared by the requests, which is created on first use
+var dynamoDBClient = sync.OnceValue(func() *dynamodb.DynamoDB {
+	return dynamodb.New(awssession.Default())
+})
+
 func main() {
-	lambda.StartHandler(Handler{})
+	// the authenticator is created once, so the rate limits are kept across the requests served by the instance
+	authenticator, err := auth.NewAuthenticatorFromEnv(dynamoDBClient())
+	if err != nil {
+		logging.Logger().Error("unable to configure authentication", "error", err)
+		os.Exit(1)
+	}
+	jobManager, err := jobs.NewManagerFromEnv(dynamoDBClient())
+	if err != nil {
+		logging.Logger().Error("unable to configure jobs", "error", err)
+		os.Exit(1)
+	}
+	webhookVerifier, err := githubapp.NewWebhookVerifierFromEnv(dynamoDBClient())
+	if err != nil {
+		logging.Logger().Error("unable to configure webhook verification", "error", err)
+		os.Exit(1)
+	}
+	// the policy is compiled when the function starts, so an invalid policy fails the deployment instead of the requests
+	if _, err := policy.DefaultEvaluator(); err != nil {
+		logging.Logger().Error("unable to configure the policy", "error", err)
+		os.Exit(1)
+	}
+	// the knowledge base is read once, instead of for each request, when it is first used, so the start of the function
+	// does not wait for it
+	metadata.IndexKnowledgeBaseLazily()
+	refresher, err := kbrefresh.NewRefresherFromEnv()
+	if err != nil {
+		logging.Logger().Error("unable to configure the refresh of the knowledge base", "error", err)
+		os.Exit(1)
+	}
+	if refresher != nil {
+		go refresher.Run(context.Background())
+	}
+	if err := serve(Handler{authenticator: authenticator, jobs: jobManager, webhooks: webhookVerifier}, lambda.StartHandler); err != nil {
+		logging.Logger().Error("unable to serve the gRPC API", "error", err)
+		os.Exit(1)
+	}
+}
+
+// serve serves the remediation APIs over gRPC on the address in GRPC_ADDR, e.g. when the image runs as a container for
+// the clients that use gRPC, and to Lambda with startLambda otherwise. The modes are exclusive: the Lambda runtime
+// freezes the function between invocations, so a gRPC server in the function would not answer, and a container has no
+// Lambda runtime to take invocations. It only returns if the API cannot be served.
+func serve(handler Handler, startLambda func(lambda.Handler)) error {
+	if addr := os.Getenv(grpcapi.AddrEnv); addr != "" {
+		return serveGRPC(addr, handler.authenticator)
+	}
+	startLambda(handler)
+	return nil
+}
+
+// serveGRPC serves the remediation APIs over gRPC on the address, with the same authentication and rate limits as the
+// HTTP API. It only returns if the API cannot be served.
+func serveGRPC(addr string, authenticator *auth.Authenticator) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %w", addr, err)
+	}
+	logging.Logger().Info("serving the gRPC API", "address", listener.Addr().String())
+	server := grpcapi.NewServer(&grpcapi.Server{Authenticator: authenticator, DynamoDB: dynamoDBClient()})
+	return server.Serve(listener)
 }
@@ -1,7 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
 	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/step-security/secure-repo/remediation/auth"
+	"github.com/step-security/secure-repo/remediation/grpcapi"
 )
 
 func TestInvokeHTTPRequest(t *testing.T) {
@@ -31,3 +39,75 @@ func TestInvokeHTTPRequest(t *testing.T) {
 	proxyResponse := &events.APIGatewayProxyResponse{}
 	err = json.Unmarshal(response, &proxyResponse)*/
 }
+
+func TestGetRoute(t *testing.T) {
+	tests := []struct {
+		rawPath string
+		want    string
+	}{
+		{"/v1/secure-workflow", "secure-workflow"},
+		{"/v1/secrets/", "secrets"},
+		{"/v1/github-app-webhook", "github-app-webhook"},
+		{"/v1/update-codeowners/secrets", "secrets"},
+		{"/v1/secrets/update-codeowners", "update-codeowners"},
+		{"/metrics/github-app-webhook", "github-app-webhook"},
+		{"/v1/secure-workflow-preview", "unknown"},
+		{"/debug/pprof", "debug/pprof"},
+		{"/debug/pprof/heap", "debug/pprof"},
+		{"/v1/debug/pprof/heap/profile", "unknown"},
+	}
+	for _, test := range tests {
+		if got := getRoute(test.rawPath); got != test.want {
+			t.Errorf("getRoute(%q) = %q, want %q", test.rawPath, got, test.want)
+		}
+	}
+}
+
+func TestInvokeRequiresAuthentication(t *testing.T) {
+	handler := Handler{authenticator: auth.NewAuthenticator(auth.StaticKeyStore{}, nil, 0)}
+	tests := []struct {
+		method  string
+		rawPath string
+		want    int
+	}{
+		// the routes that authenticate requests themselves are matched on the last segment only, so these paths are
+		// handled by the secrets and the webhook, which reject the empty body and the missing signature, instead of
+		// updating the CODEOWNERS or serving the metrics without an API key
+		{"POST", "/v1/update-codeowners/secrets", http.StatusInternalServerError},
+		{"GET", "/metrics/github-app-webhook", http.StatusUnauthorized},
+		{"POST", "/v1/secrets/update-codeowners", http.StatusUnauthorized},
+		{"GET", "/metrics", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		request := &events.APIGatewayV2HTTPRequest{RawPath: test.rawPath}
+		request.RequestContext.HTTP.Method = test.method
+		input, _ := json.Marshal(request)
+
+		output, err := handler.Invoke(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Invoke(%s %s) returned error: %v", test.method, test.rawPath, err)
+		}
+		response := events.APIGatewayProxyResponse{}
+		json.Unmarshal(output, &response)
+		if response.StatusCode != test.want {
+			t.Errorf("Invoke(%s %s) returned status %d, want %d", test.method, test.rawPath, response.StatusCode, test.want)
+		}
+	}
+}
+
+func TestServeExclusive(t *testing.T) {
+	started := false
+	startLambda := func(lambda.Handler) { started = true }
+
+	t.Setenv(grpcapi.AddrEnv, "")
+	if err := serve(Handler{}, startLambda); err != nil || !started {
+		t.Errorf("serve() = %v, started = %v, want the Lambda handler started", err, started)
+	}
+
+	// with GRPC_ADDR, only the gRPC API is served, so the error of its listener is returned instead of starting Lambda
+	started = false
+	t.Setenv(grpcapi.AddrEnv, "missing-port")
+	if err := serve(Handler{}, startLambda); err == nil || started {
+		t.Errorf("serve() = %v, started = %v, want an error and the Lambda handler not started", err, started)
+	}
+}
@@ -0,0 +1,17 @@
+#!/bin/bash -eu
+# Builds the fuzz targets of the workflow remediations for OSS-Fuzz. The workflows of the test files are the seed corpus
+# of each target.
+
+# the package, the fuzz target and the folder of its seed corpus in testfiles
+targets=(
+  "remediation/workflow/runnerlabel FuzzReplaceRunnerLabels runnerLabel"
+  "remediation/workflow/pin FuzzPinActions pinactions"
+  "remediation/workflow/permissions FuzzAddJobLevelPermissions joblevelperms"
+  "remediation/workflow/hardenrunner FuzzAddAction addaction"
+)
+
+for target in "${targets[@]}"; do
+  read -r package fuzzer corpus <<< "$target"
+  compile_native_go_fuzzer "github.com/step-security/secure-repo/$package" "$fuzzer" "$fuzzer"
+  zip -j "$OUT/${fuzzer}_seed_corpus.zip" testfiles/"$corpus"/input/*
+done
@@ -0,0 +1,107 @@
+package securerepo
+
+import (
+	"log/slog"
+	"strconv"
+
+	"github.com/step-security/secure-repo/remediation/workflow"
+	"github.com/step-security/secure-repo/remediation/workflow/hardenrunner"
+)
+
+// SecureWorkflowOptions configures the remediations of a workflow. The zero value runs the default remediations, which
+// pin actions, add Harden-Runner and add permissions.
+type SecureWorkflowOptions struct {
+	Pin          PinOptions
+	HardenRunner HardenRunnerOptions
+	Permissions  PermissionsOptions
+	// MaintainedActions maps actions to the maintained actions that replace them
+	MaintainedActions map[string]string
+	// RunnerLabels maps runner labels to the labels that replace them
+	RunnerLabels map[string]string
+	// ReplaceByMajorTag replaces actions with the major tag of their latest release, instead of its full version
+	ReplaceByMajorTag bool
+	// Params are query parameters of the API that enable the other remediations and checks, e.g. addShellDefaults or
+	// checkScriptInjection set to true. The options take precedence over the parameters they also set.
+	Params map[string]string
+	// DryRun runs all checks, and returns the findings and the report of the proposed changes without changing the workflow
+	DryRun bool
+	// Strict runs all checks, and fails the result if a remediation failed, or an action, image or job could not be
+	// remediated, which are added to the findings
+	Strict bool
+	// Logger is used for the logs of the remediations, instead of the logger of the logging package
+	Logger *slog.Logger
+}
+
+// PinOptions configures the pinning of actions and docker images to their commit SHA and digest
+type PinOptions struct {
+	// Skip leaves the actions and images unpinned
+	Skip bool
+	// ExemptedActions are patterns of actions that are not pinned, e.g. myorg/*
+	ExemptedActions []string
+	// Immutable pins actions that are published as immutable actions to their semantic version instead of a commit SHA
+	Immutable bool
+	// ActionCommits maps actions to the action and commit they are pinned to, instead of looking up the commit of the tag
+	ActionCommits map[string]string
+}
+
+// HardenRunnerOptions configures the Harden-Runner step added to the jobs
+type HardenRunnerOptions struct {
+	// Skip does not add Harden-Runner
+	Skip bool
+	// Step is the YAML of the step, e.g. with a custom egress policy. The default step audits the egress traffic.
+	Step string
+	// UpdateExisting updates the configuration of the Harden-Runner steps that are already in the jobs to Step
+	UpdateExisting bool
+	// RunnerLabels limits Harden-Runner to the jobs that run on one of the labels
+	RunnerLabels []string
+	// SkipContainerJobs does not add Harden-Runner to the jobs that run in a container
+	SkipContainerJobs bool
+}
+
+// PermissionsOptions configures the permissions added for the GITHUB_TOKEN
+type PermissionsOptions struct {
+	// Skip does not add permissions
+	Skip bool
+	// AddEmptyTopLevel adds empty permissions at the top of the workflow, instead of read permissions for contents
+	AddEmptyTopLevel bool
+	// SkipProjectComment does not add the comment that the permissions were added by secure-repo
+	SkipProjectComment bool
+}
+
+// DockerfileOptions configures the remediations of a Dockerfile
+type DockerfileOptions struct {
+	// ExemptedImages are images that are not pinned
+	ExemptedImages []string
+	// AddNonRootUser adds a user to the final stage, so the container does not run as root
+	AddNonRootUser bool
+}
+
+func (o HardenRunnerOptions) config() hardenrunner.HardenRunnerConfig {
+	return hardenrunner.HardenRunnerConfig{Config: o.Step, Subtractive: o.UpdateExisting, SkipHardenRunner: len(o.RunnerLabels) > 0, RunnerLabels: o.RunnerLabels}
+}
+
+// queryStringParams returns the query parameters of the options, which the remediations are configured with
+func (o SecureWorkflowOptions) queryStringParams() map[string]string {
+	params := make(map[string]string, len(o.Params)+10)
+	for key, value := range o.Params {
+		params[key] = value
+	}
+	params["pinActions"] = strconv.FormatBool(!o.Pin.Skip)
+	params["addHardenRunner"] = strconv.FormatBool(!o.HardenRunner.Skip)
+	params["skipHardenRunnerForContainers"] = strconv.FormatBool(o.HardenRunner.SkipContainerJobs)
+	params["addPermissions"] = strconv.FormatBool(!o.Permissions.Skip)
+	params["addEmptyTopLevelPermissions"] = strconv.FormatBool(o.Permissions.AddEmptyTopLevel)
+	params["addProjectComment"] = strconv.FormatBool(!o.Permissions.SkipProjectComment)
+	params["replaceActionByMajorTag"] = strconv.FormatBool(o.ReplaceByMajorTag)
+	params["dryRun"] = strconv.FormatBool(o.DryRun)
+	params["strict"] = strconv.FormatBool(o.Strict)
+	// the actions missing from the knowledge base are returned, instead of being stored for the hosted instance
+	params["ignoreMissingKBs"] = "true"
+	return params
+}
+
+// workflowOptions returns the options of workflow.SecureWorkflow
+func (o SecureWorkflowOptions) workflowOptions() workflow.Options {
+	return workflow.Options{ExemptedActions: o.Pin.ExemptedActions, PinToImmutable: o.Pin.Immutable, MaintainedActions: o.MaintainedActions,
+		ActionCommits: o.Pin.ActionCommits, RunnerLabels: o.RunnerLabels, HardenRunnerConfig: o.HardenRunner.config(), Logger: o.Logger}
+}
@@ -0,0 +1,228 @@
+// Package securerepo is the Go API of the remediations, for programs that embed them instead of calling the HTTP API.
+//
+// The package follows semantic versioning with the module: within a major version, the exported functions, option
+// structs and result types are not removed or changed incompatibly, and new options are added with zero values that
+// keep the current behavior. The packages under remediation are the implementation, and may change in any release.
+//
+// The knowledge base of the permissions of actions is read from the folder in the KBFolder environment variable, and the
+// commits of tags are looked up with the GitHub token in the PAT environment variable.
+package securerepo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/workflow"
+	"github.com/step-security/secure-repo/remediation/workflow/hardenrunner"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+)
+
+// Finding is an issue found in a file, which is Fixed if a remediation fixed it
+type Finding struct {
+	RuleID     string
+	Message    string
+	JobName    string
+	Action     string
+	Line       int
+	Column     int
+	Suggestion string
+	Fixed      bool
+}
+
+// Change is a line changed by a remediation. Kind is added, removed or modified. Confidence is safe for changes that
+// keep the behavior of the workflow, and needs-review for the rest. Revert is the edit that undoes the change.
+type Change struct {
+	File       string
+	Line       int
+	Kind       string
+	Before     string
+	After      string
+	Confidence string
+	Revert     Edit
+}
+
+// Edit is a line edit of the output of a remediation. Before is the line at Line, and After is the line that replaces it,
+// or is added before Line. The reverts of the changes of a module are applied in the reverse order of the changes.
+type Edit struct {
+	Line   int
+	Kind   string
+	Before string
+	After  string
+}
+
+// Skipped is an item, such as a job or an action, that a remediation did not change, along with the reason
+type Skipped struct {
+	File   string
+	Line   int
+	Item   string
+	Reason string
+}
+
+// Module has what a remediation changed, skipped and the errors it ran into
+type Module struct {
+	Name    string
+	Changes []Change
+	Skipped []Skipped
+	Errors  []string
+}
+
+// ModuleStatus is whether a remediation that ran changed the workflow, left it unchanged or failed, along with its
+// errors. The remediations after one that failed still run, and the changes it made are kept.
+type ModuleStatus struct {
+	Name   string
+	Status string
+	Errors []string
+}
+
+// WorkflowResult is the result of securing a workflow
+type WorkflowResult struct {
+	// Output is the remediated workflow, which is the input for a dry run
+	Output    string
+	IsChanged bool
+	HasErrors bool
+	// FailedStrictChecks is true with Strict if a remediation failed, or an action, image or job could not be remediated
+	FailedStrictChecks bool
+	// JobErrors has the reasons permissions were not added to each job
+	JobErrors map[string][]string
+	// MissingActions are the actions that are not in the knowledge base, so permissions were not added for them
+	MissingActions []string
+	Findings       []Finding
+	// Modules are the remediations that changed or skipped something, or ran into errors, in the order they ran
+	Modules []Module
+	// Statuses are the statuses of all the remediations that ran, in the order they ran: changed, unchanged or failed
+	Statuses []ModuleStatus
+}
+
+// DockerfileResult is the result of securing a Dockerfile
+type DockerfileResult struct {
+	Output    string
+	IsChanged bool
+}
+
+func newFindings(fileFindings []findings.Finding) []Finding {
+	var result []Finding
+	for _, finding := range fileFindings {
+		result = append(result, Finding(finding))
+	}
+	return result
+}
+
+func newModules(workflowReport *report.Report) []Module {
+	if workflowReport == nil {
+		return nil
+	}
+	var modules []Module
+	for _, m := range workflowReport.Modules {
+		module := Module{Name: m.Name, Errors: m.Errors}
+		for _, change := range m.Changes {
+			module.Changes = append(module.Changes, Change{File: change.File, Line: change.Line, Kind: change.Kind, Before: change.Before,
+				After: change.After, Confidence: change.Confidence, Revert: Edit(change.Revert)})
+		}
+		for _, skipped := range m.Skipped {
+			module.Skipped = append(module.Skipped, Skipped(skipped))
+		}
+		modules = append(modules, module)
+	}
+	return modules
+}
+
+func newStatuses(workflowReport *report.Report) []ModuleStatus {
+	if workflowReport == nil {
+		return nil
+	}
+	var statuses []ModuleStatus
+	for _, status := range workflowReport.Statuses {
+		statuses = append(statuses, ModuleStatus{Name: status.Module, Status: status.Status, Errors: status.Errors})
+	}
+	return statuses
+}
+
+// SecureWorkflow runs the remediations configured by the options on a workflow
+func SecureWorkflow(inputYaml string, opts SecureWorkflowOptions) (*WorkflowResult, error) {
+	return SecureWorkflowContext(context.Background(), inputYaml, opts)
+}
+
+// SecureWorkflowContext is SecureWorkflow with a context, which cancels the requests to GitHub and the registries, and
+// whose error is returned once it is done
+func SecureWorkflowContext(ctx context.Context, inputYaml string, opts SecureWorkflowOptions) (*WorkflowResult, error) {
+	// the actions missing from the knowledge base are not stored, so there is no DynamoDB client
+	response, err := workflow.SecureWorkflow(ctx, opts.queryStringParams(), inputYaml, nil, opts.workflowOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WorkflowResult{
+		Output:             response.FinalOutput,
+		IsChanged:          response.FinalOutput != inputYaml,
+		HasErrors:          response.HasErrors,
+		FailedStrictChecks: response.FailedStrictChecks,
+		MissingActions:     response.MissingActions,
+		Findings:           newFindings(response.Findings),
+		Modules:            newModules(response.Report),
+		Statuses:           newStatuses(response.Report),
+	}
+	for _, jobError := range response.JobErrors {
+		if result.JobErrors == nil {
+			result.JobErrors = make(map[string][]string)
+		}
+		result.JobErrors[jobError.JobName] = jobError.Errors
+	}
+	return result, nil
+}
+
+// IsRemediated returns whether each remediation configured by the options would leave the workflow unchanged, by the
+// name of its module, e.g. to skip the workflows that are already remediated instead of opening a pull request
+func IsRemediated(inputYaml string, opts SecureWorkflowOptions) (map[string]bool, error) {
+	return IsRemediatedContext(context.Background(), inputYaml, opts)
+}
+
+// IsRemediatedContext is IsRemediated with a context, which cancels the requests to GitHub and the registries
+func IsRemediatedContext(ctx context.Context, inputYaml string, opts SecureWorkflowOptions) (map[string]bool, error) {
+	return workflow.IsRemediated(ctx, opts.queryStringParams(), inputYaml, nil, opts.workflowOptions())
+}
+
+// PinActions pins the actions and docker images of a workflow or composite action to their commit SHA and digest. It
+// returns whether any were pinned. The ones that cannot be pinned are returned as the error, along with the workflow with
+// the others pinned. PinOptions.Skip is ignored.
+func PinActions(inputYaml string, opts PinOptions) (string, bool, error) {
+	return PinActionsContext(context.Background(), inputYaml, opts)
+}
+
+// PinActionsContext is PinActions with a context, which cancels the requests to GitHub and the registries
+func PinActionsContext(ctx context.Context, inputYaml string, opts PinOptions) (string, bool, error) {
+	output, pinnedActions, actionsErr := pin.PinActions(ctx, inputYaml, opts.ExemptedActions, opts.Immutable, opts.ActionCommits)
+	output, pinnedImages, err := pin.PinDocker(ctx, output)
+	return output, pinnedActions || pinnedImages, errors.Join(actionsErr, err)
+}
+
+// AddHardenRunner adds the Harden-Runner step to the jobs of a workflow, pinned unless pinning is skipped. It returns
+// whether any jobs were changed. HardenRunnerOptions.Skip is ignored.
+func AddHardenRunner(inputYaml string, opts HardenRunnerOptions, pinOpts PinOptions) (string, bool, error) {
+	return AddHardenRunnerContext(context.Background(), inputYaml, opts, pinOpts)
+}
+
+// AddHardenRunnerContext is AddHardenRunner with a context, which cancels the request of pinning Harden-Runner
+func AddHardenRunnerContext(ctx context.Context, inputYaml string, opts HardenRunnerOptions, pinOpts PinOptions) (string, bool, error) {
+	var pinner hardenrunner.Pinner
+	if !pinOpts.Skip && !pin.ActionExists(workflow.HardenRunnerActionPath, pinOpts.ExemptedActions) {
+		pinner = pin.ActionPinner{}
+	}
+	return hardenrunner.AddAction(ctx, inputYaml, opts.config(), pinner, pinOpts.Immutable, opts.SkipContainerJobs)
+}
+
+// SecureDockerfile pins the base images of a Dockerfile to their digest, and adds a non-root user if it is enabled
+func SecureDockerfile(inputDockerfile string, opts DockerfileOptions) (*DockerfileResult, error) {
+	return SecureDockerfileContext(context.Background(), inputDockerfile, opts)
+}
+
+// SecureDockerfileContext is SecureDockerfile with a context, which cancels the requests to the registries
+func SecureDockerfileContext(ctx context.Context, inputDockerfile string, opts DockerfileOptions) (*DockerfileResult, error) {
+	response, err := docker.SecureDockerFile(ctx, inputDockerfile, docker.DockerfileConfig{ExemptedImages: opts.ExemptedImages, AddNonRootUser: opts.AddNonRootUser})
+	if err != nil {
+		return nil, err
+	}
+	return &DockerfileResult{Output: response.FinalOutput, IsChanged: response.IsChanged}, nil
+}
@@ -0,0 +1,140 @@
+package securerepo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+const workflowInput = `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make build
+`
+
+func TestSecureWorkflow(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	opts := SecureWorkflowOptions{
+		Pin:          PinOptions{Skip: true},
+		HardenRunner: HardenRunnerOptions{Skip: true},
+		Permissions:  PermissionsOptions{SkipProjectComment: true},
+		Params:       map[string]string{"addShellDefaults": "true"},
+	}
+	result, err := SecureWorkflow(workflowInput, opts)
+	if err != nil {
+		t.Fatalf("SecureWorkflow() returned error: %v", err)
+	}
+	if !result.IsChanged || result.HasErrors {
+		t.Errorf("expected the workflow to be changed without errors, got %+v", result)
+	}
+	if !strings.Contains(result.Output, "permissions:\n  contents: read\n") || !strings.Contains(result.Output, "shell: bash") {
+		t.Errorf("expected permissions and shell defaults to be added, got\n%s", result.Output)
+	}
+	var names []string
+	for _, module := range result.Modules {
+		names = append(names, module.Name)
+	}
+	if strings.Join(names, ",") != "permissions,shelldefaults" {
+		t.Errorf("modules = %v, want permissions and shelldefaults", names)
+	}
+	for _, change := range result.Modules[1].Changes {
+		if change.Confidence != "needs-review" || change.Revert.Kind != "removed" || change.Revert.Before != change.After {
+			t.Errorf("expected the added shell defaults to need review and be reverted by removing them, got %+v", change)
+		}
+	}
+
+	opts.DryRun = true
+	result, err = SecureWorkflow(workflowInput, opts)
+	if err != nil {
+		t.Fatalf("SecureWorkflow() returned error: %v", err)
+	}
+	if result.IsChanged || result.Output != workflowInput || len(result.Modules) == 0 {
+		t.Errorf("expected the changes of a dry run to only be reported, got %+v", result)
+	}
+
+	// the workflow is remediated completely, so it passes the strict checks
+	opts.DryRun, opts.Strict = false, true
+	opts.Params["checkUnmaintainedActions"] = "false"
+	result, err = SecureWorkflow(workflowInput, opts)
+	if err != nil {
+		t.Fatalf("SecureWorkflow() returned error: %v", err)
+	}
+	if result.FailedStrictChecks {
+		t.Errorf("expected the strict checks to pass, got %+v", result.Findings)
+	}
+	result, err = SecureWorkflow(strings.Replace(workflowInput, "- run: make build", "- uses: ${{ matrix.action }}", 1), opts)
+	if err != nil {
+		t.Fatalf("SecureWorkflow() returned error: %v", err)
+	}
+	if !result.FailedStrictChecks {
+		t.Errorf("expected a uses with an expression to fail the strict checks, got %+v", result.Findings)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := SecureWorkflowContext(ctx, workflowInput, opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("SecureWorkflowContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestIsRemediated(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	opts := SecureWorkflowOptions{
+		Pin:          PinOptions{Skip: true},
+		HardenRunner: HardenRunnerOptions{Skip: true},
+		Permissions:  PermissionsOptions{SkipProjectComment: true},
+		Params:       map[string]string{"addShellDefaults": "true"},
+	}
+	remediated, err := IsRemediated(workflowInput, opts)
+	if err != nil {
+		t.Fatalf("IsRemediated() returned error: %v", err)
+	}
+	if len(remediated) != 2 || remediated["permissions"] || remediated["shelldefaults"] {
+		t.Errorf("IsRemediated() = %v, want permissions and shelldefaults not remediated", remediated)
+	}
+
+	// the output is remediated, so securing it again does not change it
+	result, err := SecureWorkflow(workflowInput, opts)
+	if err != nil {
+		t.Fatalf("SecureWorkflow() returned error: %v", err)
+	}
+	remediated, err = IsRemediated(result.Output, opts)
+	if err != nil {
+		t.Fatalf("IsRemediated() returned error: %v", err)
+	}
+	if len(remediated) != 2 || !remediated["permissions"] || !remediated["shelldefaults"] {
+		t.Errorf("IsRemediated() of the output = %v, want permissions and shelldefaults remediated", remediated)
+	}
+	again, err := SecureWorkflow(result.Output, opts)
+	if err != nil {
+		t.Fatalf("SecureWorkflow() returned error: %v", err)
+	}
+	if again.IsChanged || again.Output != result.Output {
+		t.Errorf("expected the output to be unchanged, got\n%s", again.Output)
+	}
+}
+
+func TestAddHardenRunner(t *testing.T) {
+	output, added, err := AddHardenRunner(workflowInput, HardenRunnerOptions{RunnerLabels: []string{"self-hosted"}}, PinOptions{Skip: true})
+	if err != nil {
+		t.Fatalf("AddHardenRunner() returned error: %v", err)
+	}
+	if added || output != workflowInput {
+		t.Errorf("expected jobs that do not run on the labels to be skipped, got\n%s", output)
+	}
+
+	output, added, err = AddHardenRunner(workflowInput, HardenRunnerOptions{}, PinOptions{Skip: true})
+	if err != nil {
+		t.Fatalf("AddHardenRunner() returned error: %v", err)
+	}
+	if !added || !strings.Contains(output, "uses: step-security/harden-runner@v2") {
+		t.Errorf("expected harden-runner to be added without pinning, got\n%s", output)
+	}
+}
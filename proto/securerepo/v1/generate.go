@@ -0,0 +1,5 @@
+// Package securerepov1 has the messages, the client and the server of the gRPC service of the remediation APIs, which
+// are generated from securerepo.proto
+package securerepov1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative securerepo/v1/securerepo.proto
@@ -0,0 +1,305 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: securerepo/v1/securerepo.proto
+
+// The remediation APIs of secure-repo, for clients that use gRPC instead of the HTTP API.
+// The messages mirror the JSON requests and responses of the HTTP API.
+
+package securerepov1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SecureRepoService_SecureWorkflow_FullMethodName      = "/securerepo.v1.SecureRepoService/SecureWorkflow"
+	SecureRepoService_PinActions_FullMethodName          = "/securerepo.v1.SecureRepoService/PinActions"
+	SecureRepoService_ReplaceRunnerLabels_FullMethodName = "/securerepo.v1.SecureRepoService/ReplaceRunnerLabels"
+	SecureRepoService_SecureRepo_FullMethodName          = "/securerepo.v1.SecureRepoService/SecureRepo"
+	SecureRepoService_SecureRepoArchive_FullMethodName   = "/securerepo.v1.SecureRepoService/SecureRepoArchive"
+)
+
+// SecureRepoServiceClient is the client API for SecureRepoService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SecureRepoServiceClient interface {
+	// SecureWorkflow runs the enabled remediations on a workflow, as the /secure-workflow route
+	SecureWorkflow(ctx context.Context, in *SecureWorkflowRequest, opts ...grpc.CallOption) (*SecureWorkflowResponse, error)
+	// PinActions pins the actions and docker images in a workflow or composite action to a commit SHA or digest
+	PinActions(ctx context.Context, in *PinActionsRequest, opts ...grpc.CallOption) (*PinActionsResponse, error)
+	// ReplaceRunnerLabels replaces the runner labels of the jobs in a workflow
+	ReplaceRunnerLabels(ctx context.Context, in *ReplaceRunnerLabelsRequest, opts ...grpc.CallOption) (*ReplaceRunnerLabelsResponse, error)
+	// SecureRepo runs the remediations on the files of a repository, as the /secure-repo route
+	SecureRepo(ctx context.Context, in *SecureRepoRequest, opts ...grpc.CallOption) (*SecureRepoResponse, error)
+	// SecureRepoArchive runs the remediations on a zip or tar.gz archive of a repository. The archive is streamed in
+	// chunks in both directions, so large repositories do not need to fit in a single message.
+	SecureRepoArchive(ctx context.Context, opts ...grpc.CallOption) (SecureRepoService_SecureRepoArchiveClient, error)
+}
+
+type secureRepoServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSecureRepoServiceClient(cc grpc.ClientConnInterface) SecureRepoServiceClient {
+	return &secureRepoServiceClient{cc}
+}
+
+func (c *secureRepoServiceClient) SecureWorkflow(ctx context.Context, in *SecureWorkflowRequest, opts ...grpc.CallOption) (*SecureWorkflowResponse, error) {
+	out := new(SecureWorkflowResponse)
+	err := c.cc.Invoke(ctx, SecureRepoService_SecureWorkflow_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secureRepoServiceClient) PinActions(ctx context.Context, in *PinActionsRequest, opts ...grpc.CallOption) (*PinActionsResponse, error) {
+	out := new(PinActionsResponse)
+	err := c.cc.Invoke(ctx, SecureRepoService_PinActions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secureRepoServiceClient) ReplaceRunnerLabels(ctx context.Context, in *ReplaceRunnerLabelsRequest, opts ...grpc.CallOption) (*ReplaceRunnerLabelsResponse, error) {
+	out := new(ReplaceRunnerLabelsResponse)
+	err := c.cc.Invoke(ctx, SecureRepoService_ReplaceRunnerLabels_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secureRepoServiceClient) SecureRepo(ctx context.Context, in *SecureRepoRequest, opts ...grpc.CallOption) (*SecureRepoResponse, error) {
+	out := new(SecureRepoResponse)
+	err := c.cc.Invoke(ctx, SecureRepoService_SecureRepo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secureRepoServiceClient) SecureRepoArchive(ctx context.Context, opts ...grpc.CallOption) (SecureRepoService_SecureRepoArchiveClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SecureRepoService_ServiceDesc.Streams[0], SecureRepoService_SecureRepoArchive_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &secureRepoServiceSecureRepoArchiveClient{stream}
+	return x, nil
+}
+
+type SecureRepoService_SecureRepoArchiveClient interface {
+	Send(*ArchiveChunk) error
+	Recv() (*SecureRepoArchiveResponse, error)
+	grpc.ClientStream
+}
+
+type secureRepoServiceSecureRepoArchiveClient struct {
+	grpc.ClientStream
+}
+
+func (x *secureRepoServiceSecureRepoArchiveClient) Send(m *ArchiveChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *secureRepoServiceSecureRepoArchiveClient) Recv() (*SecureRepoArchiveResponse, error) {
+	m := new(SecureRepoArchiveResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SecureRepoServiceServer is the server API for SecureRepoService service.
+// All implementations must embed UnimplementedSecureRepoServiceServer
+// for forward compatibility
+type SecureRepoServiceServer interface {
+	// SecureWorkflow runs the enabled remediations on a workflow, as the /secure-workflow route
+	SecureWorkflow(context.Context, *SecureWorkflowRequest) (*SecureWorkflowResponse, error)
+	// PinActions pins the actions and docker images in a workflow or composite action to a commit SHA or digest
+	PinActions(context.Context, *PinActionsRequest) (*PinActionsResponse, error)
+	// ReplaceRunnerLabels replaces the runner labels of the jobs in a workflow
+	ReplaceRunnerLabels(context.Context, *ReplaceRunnerLabelsRequest) (*ReplaceRunnerLabelsResponse, error)
+	// SecureRepo runs the remediations on the files of a repository, as the /secure-repo route
+	SecureRepo(context.Context, *SecureRepoRequest) (*SecureRepoResponse, error)
+	// SecureRepoArchive runs the remediations on a zip or tar.gz archive of a repository. The archive is streamed in
+	// chunks in both directions, so large repositories do not need to fit in a single message.
+	SecureRepoArchive(SecureRepoService_SecureRepoArchiveServer) error
+	mustEmbedUnimplementedSecureRepoServiceServer()
+}
+
+// UnimplementedSecureRepoServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedSecureRepoServiceServer struct {
+}
+
+func (UnimplementedSecureRepoServiceServer) SecureWorkflow(context.Context, *SecureWorkflowRequest) (*SecureWorkflowResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SecureWorkflow not implemented")
+}
+func (UnimplementedSecureRepoServiceServer) PinActions(context.Context, *PinActionsRequest) (*PinActionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PinActions not implemented")
+}
+func (UnimplementedSecureRepoServiceServer) ReplaceRunnerLabels(context.Context, *ReplaceRunnerLabelsRequest) (*ReplaceRunnerLabelsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplaceRunnerLabels not implemented")
+}
+func (UnimplementedSecureRepoServiceServer) SecureRepo(context.Context, *SecureRepoRequest) (*SecureRepoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SecureRepo not implemented")
+}
+func (UnimplementedSecureRepoServiceServer) SecureRepoArchive(SecureRepoService_SecureRepoArchiveServer) error {
+	return status.Errorf(codes.Unimplemented, "method SecureRepoArchive not implemented")
+}
+func (UnimplementedSecureRepoServiceServer) mustEmbedUnimplementedSecureRepoServiceServer() {}
+
+// UnsafeSecureRepoServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SecureRepoServiceServer will
+// result in compilation errors.
+type UnsafeSecureRepoServiceServer interface {
+	mustEmbedUnimplementedSecureRepoServiceServer()
+}
+
+func RegisterSecureRepoServiceServer(s grpc.ServiceRegistrar, srv SecureRepoServiceServer) {
+	s.RegisterService(&SecureRepoService_ServiceDesc, srv)
+}
+
+func _SecureRepoService_SecureWorkflow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecureWorkflowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecureRepoServiceServer).SecureWorkflow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecureRepoService_SecureWorkflow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecureRepoServiceServer).SecureWorkflow(ctx, req.(*SecureWorkflowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecureRepoService_PinActions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PinActionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecureRepoServiceServer).PinActions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecureRepoService_PinActions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecureRepoServiceServer).PinActions(ctx, req.(*PinActionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecureRepoService_ReplaceRunnerLabels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplaceRunnerLabelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecureRepoServiceServer).ReplaceRunnerLabels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecureRepoService_ReplaceRunnerLabels_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecureRepoServiceServer).ReplaceRunnerLabels(ctx, req.(*ReplaceRunnerLabelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecureRepoService_SecureRepo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecureRepoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecureRepoServiceServer).SecureRepo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecureRepoService_SecureRepo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecureRepoServiceServer).SecureRepo(ctx, req.(*SecureRepoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecureRepoService_SecureRepoArchive_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SecureRepoServiceServer).SecureRepoArchive(&secureRepoServiceSecureRepoArchiveServer{stream})
+}
+
+type SecureRepoService_SecureRepoArchiveServer interface {
+	Send(*SecureRepoArchiveResponse) error
+	Recv() (*ArchiveChunk, error)
+	grpc.ServerStream
+}
+
+type secureRepoServiceSecureRepoArchiveServer struct {
+	grpc.ServerStream
+}
+
+func (x *secureRepoServiceSecureRepoArchiveServer) Send(m *SecureRepoArchiveResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *secureRepoServiceSecureRepoArchiveServer) Recv() (*ArchiveChunk, error) {
+	m := new(ArchiveChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SecureRepoService_ServiceDesc is the grpc.ServiceDesc for SecureRepoService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SecureRepoService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "securerepo.v1.SecureRepoService",
+	HandlerType: (*SecureRepoServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SecureWorkflow",
+			Handler:    _SecureRepoService_SecureWorkflow_Handler,
+		},
+		{
+			MethodName: "PinActions",
+			Handler:    _SecureRepoService_PinActions_Handler,
+		},
+		{
+			MethodName: "ReplaceRunnerLabels",
+			Handler:    _SecureRepoService_ReplaceRunnerLabels_Handler,
+		},
+		{
+			MethodName: "SecureRepo",
+			Handler:    _SecureRepoService_SecureRepo_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SecureRepoArchive",
+			Handler:       _SecureRepoService_SecureRepoArchive_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "securerepo/v1/securerepo.proto",
+}
@@ -0,0 +1,266 @@
+// Package apiv2 has the schemas of version 2 of the HTTP API, which is served by the v2 stage. Version 2 takes a batch of
+// workflows in one request, and returns the changes of each remediation with the results. Version 1 keeps its schemas
+// for the existing integrations, such as the GitHub App and the dashboard, and both versions run the same remediations.
+package apiv2
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/cache"
+	"github.com/step-security/secure-repo/remediation/diff"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/score"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+	"github.com/step-security/secure-repo/remediation/workers"
+	"github.com/step-security/secure-repo/remediation/workflow"
+	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+)
+
+// Version is the stage of version 2 of the API
+const Version = "v2"
+
+// Routes are the routes that have the schemas of version 2. The other routes of the v2 stage are served as in version 1.
+var Routes = []string{"secure-workflow", "secure-repo", "jobs"}
+
+// HasRoute returns true if the route has the schemas of version 2
+func HasRoute(route string) bool {
+	for _, r := range Routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
+// SecureWorkflowRequest has the workflows to secure, and the parameters of the remediations, which are the query
+// parameters of /v1/secure-workflow. The parameters of the request take precedence over the query parameters.
+type SecureWorkflowRequest struct {
+	Params    map[string]string `json:",omitempty"`
+	Workflows []securerepo.File
+}
+
+// Summary counts the changes and findings of the results. The changes are the lines changed by the remediations of
+// workflows, by confidence.
+type Summary struct {
+	Files              int
+	ChangedFiles       int
+	SafeChanges        int
+	NeedsReviewChanges int
+	Findings           int
+	FixedFindings      int
+}
+
+// WorkflowResult is the result of securing a workflow. Output is the remediated workflow, which is only returned if it was
+// changed, and is replaced by Diff with output=diff.
+type WorkflowResult struct {
+	Path      string
+	Output    string `json:",omitempty"`
+	Diff      string `json:",omitempty"`
+	IsChanged bool
+	HasErrors bool
+	// FailedStrictChecks is true with strict=true if a module failed, or a construct of the workflow was not resolved
+	FailedStrictChecks bool
+	Error              string                 `json:",omitempty"`
+	Findings           []findings.Finding     `json:",omitempty"`
+	JobErrors          []permissions.JobError `json:",omitempty"`
+	MissingActions     []string               `json:",omitempty"`
+	Modules            []report.Module        `json:",omitempty"`
+	Statuses           []report.Status        `json:",omitempty"`
+	Score              *score.ScoreChange     `json:",omitempty"`
+}
+
+// SecureWorkflowResponse has the results of the workflows, in the order of the request
+type SecureWorkflowResponse struct {
+	APIVersion         string
+	Results            []WorkflowResult
+	IsChanged          bool
+	HasErrors          bool
+	FailedStrictChecks bool
+	Score              *score.ScoreChange `json:",omitempty"`
+	Summary            Summary
+}
+
+// FileResult is the result of securing a file of a repository. Output is the remediated file, which is only returned if it
+// was changed, and is replaced by Diff with output=diff or dryRun=true. Modules are only returned for workflows.
+type FileResult struct {
+	Path               string
+	FileType           string
+	Output             string `json:",omitempty"`
+	Diff               string `json:",omitempty"`
+	IsChanged          bool
+	IsNew              bool
+	HasErrors          bool
+	FailedStrictChecks bool
+	Error              string             `json:",omitempty"`
+	Findings           []findings.Finding `json:",omitempty"`
+	Modules            []report.Module    `json:",omitempty"`
+	Statuses           []report.Status    `json:",omitempty"`
+	Score              *score.ScoreChange `json:",omitempty"`
+}
+
+// SecureRepoResponse has the results of the files of a repository. The request is the request of /v1/secure-repo.
+type SecureRepoResponse struct {
+	APIVersion         string
+	Files              []FileResult
+	Archive            []byte `json:",omitempty"`
+	IsChanged          bool
+	HasErrors          bool
+	FailedStrictChecks bool
+	MissingActions     []string           `json:",omitempty"`
+	Score              *score.ScoreChange `json:",omitempty"`
+	Summary            Summary
+}
+
+// Add counts a file with its findings and the changes of its modules
+func (s *Summary) Add(isChanged bool, fileFindings []findings.Finding, modules []report.Module) {
+	s.Files++
+	if isChanged {
+		s.ChangedFiles++
+	}
+	for _, finding := range fileFindings {
+		s.Findings++
+		if finding.Fixed {
+			s.FixedFindings++
+		}
+	}
+	for _, module := range modules {
+		for _, change := range module.Changes {
+			if change.Confidence == report.ConfidenceSafe {
+				s.SafeChanges++
+			} else {
+				s.NeedsReviewChanges++
+			}
+		}
+	}
+}
+
+// Validate returns an error if the request has no workflows, or workflows without a unique path
+func (r SecureWorkflowRequest) Validate() error {
+	if len(r.Workflows) == 0 {
+		return fmt.Errorf("no workflows in the request")
+	}
+	seen := make(map[string]bool, len(r.Workflows))
+	for _, file := range r.Workflows {
+		filePath := strings.TrimPrefix(path.Clean(file.Path), "/")
+		if file.Path == "" || seen[filePath] {
+			return fmt.Errorf("invalid path %q, each workflow needs a unique path", file.Path)
+		}
+		seen[filePath] = true
+	}
+	return nil
+}
+
+// WorkflowParams returns the parameters of the remediations of a workflow, which are the query parameters overridden
+// by the parameters of the request, and the path of the workflow
+func WorkflowParams(queryStringParams, requestParams map[string]string, workflowPath string) map[string]string {
+	params := make(map[string]string, len(queryStringParams)+len(requestParams)+1)
+	for key, value := range queryStringParams {
+		params[key] = value
+	}
+	for key, value := range requestParams {
+		params[key] = value
+	}
+	params["path"] = workflowPath
+	return params
+}
+
+// SecureWorkflows runs the remediations of /v1/secure-workflow on each workflow of the request. The errors of a workflow
+// are returned in its result, so the other workflows are still secured.
+func SecureWorkflows(ctx context.Context, queryStringParams map[string]string, request SecureWorkflowRequest, svc dynamodbiface.DynamoDBAPI, logger *slog.Logger) (*SecureWorkflowResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	response := &SecureWorkflowResponse{APIVersion: Version}
+	var scores []*score.ScoreChange
+	// the workflows are remediated by a pool of workers, and the results are in the order of the workflows
+	results := workers.Map(request.Workflows, workers.Count(), func(file securerepo.File) WorkflowResult {
+		return SecureWorkflowFile(ctx, WorkflowParams(queryStringParams, request.Params, file.Path), file, svc, logger)
+	})
+	for _, result := range results {
+		response.IsChanged = response.IsChanged || result.IsChanged
+		response.HasErrors = response.HasErrors || result.HasErrors
+		response.FailedStrictChecks = response.FailedStrictChecks || result.FailedStrictChecks
+		response.Summary.Add(result.IsChanged, result.Findings, result.Modules)
+		response.Results = append(response.Results, result)
+		scores = append(scores, result.Score)
+	}
+	response.Score = score.Average(scores)
+	return response, nil
+}
+
+// SecureWorkflowFile runs the remediations of /v1/secure-workflow on a workflow with the parameters, which include its path.
+// The error of securing the workflow is returned in the result.
+func SecureWorkflowFile(ctx context.Context, params map[string]string, file securerepo.File, svc dynamodbiface.DynamoDBAPI, logger *slog.Logger) WorkflowResult {
+	result := WorkflowResult{Path: file.Path}
+	secureWorkflowReponse, err := workflow.SecureWorkflowCached(ctx, cache.Default(), params, file.Content, svc, workflow.Options{Logger: logger})
+	if err != nil {
+		result.HasErrors, result.Error = true, err.Error()
+		return result
+	}
+	result.IsChanged = secureWorkflowReponse.FinalOutput != file.Content
+	result.HasErrors = secureWorkflowReponse.HasErrors
+	result.FailedStrictChecks = secureWorkflowReponse.FailedStrictChecks
+	result.Findings = secureWorkflowReponse.Findings
+	result.JobErrors = secureWorkflowReponse.JobErrors
+	result.MissingActions = secureWorkflowReponse.MissingActions
+	result.Score = secureWorkflowReponse.Score
+	if secureWorkflowReponse.Report != nil {
+		result.Modules, result.Statuses = secureWorkflowReponse.Report.Modules, secureWorkflowReponse.Report.Statuses
+	}
+	if result.IsChanged && params["output"] == "diff" {
+		result.Diff = diff.Unified(file.Path, file.Content, secureWorkflowReponse.FinalOutput)
+	} else if result.IsChanged {
+		result.Output = secureWorkflowReponse.FinalOutput
+	}
+	return result
+}
+
+// SecureRepo runs the remediations of /v1/secure-repo on the files of a repository, and returns the results of the files
+// with the changes of the remediations of each workflow
+func SecureRepo(ctx context.Context, queryStringParams map[string]string, request securerepo.SecureRepoRequest, svc dynamodbiface.DynamoDBAPI) (*SecureRepoResponse, error) {
+	secureRepoResponse, err := securerepo.SecureRepo(ctx, queryStringParams, request, svc)
+	if err != nil {
+		return nil, err
+	}
+	return NewSecureRepoResponse(secureRepoResponse), nil
+}
+
+// NewSecureRepoResponse returns the version 2 response of the response of /v1/secure-repo
+func NewSecureRepoResponse(secureRepoResponse *securerepo.SecureRepoResponse) *SecureRepoResponse {
+	response := &SecureRepoResponse{
+		APIVersion:         Version,
+		Archive:            secureRepoResponse.Archive,
+		IsChanged:          secureRepoResponse.IsChanged,
+		HasErrors:          secureRepoResponse.HasErrors,
+		FailedStrictChecks: secureRepoResponse.FailedStrictChecks,
+		MissingActions:     secureRepoResponse.MissingActions,
+		Score:              secureRepoResponse.Score,
+	}
+	for _, fileReport := range secureRepoResponse.Report {
+		result := FileResult{
+			Path:               fileReport.Path,
+			FileType:           fileReport.FileType,
+			Output:             secureRepoResponse.Files[fileReport.Path],
+			Diff:               secureRepoResponse.Diffs[fileReport.Path],
+			IsChanged:          fileReport.IsChanged,
+			IsNew:              fileReport.IsNew,
+			HasErrors:          fileReport.HasErrors,
+			FailedStrictChecks: fileReport.FailedStrictChecks,
+			Error:              fileReport.Error,
+			Findings:           fileReport.Findings,
+			Modules:            fileReport.Modules(),
+			Statuses:           fileReport.Statuses(),
+			Score:              fileReport.Score,
+		}
+		response.Summary.Add(result.IsChanged, result.Findings, result.Modules)
+		response.Files = append(response.Files, result)
+	}
+	return response
+}
@@ -0,0 +1,115 @@
+package apiv2
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+)
+
+var queryParams = map[string]string{
+	"pinActions":        "false",
+	"addHardenRunner":   "false",
+	"addProjectComment": "false",
+}
+
+const workflowInput = `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "::set-output name=version::1.0"
+`
+
+func TestSecureWorkflows(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	request := SecureWorkflowRequest{
+		Params: map[string]string{"rewriteDeprecatedCommands": "true"},
+		Workflows: []securerepo.File{
+			{Path: ".github/workflows/ci.yml", Content: workflowInput},
+			{Path: ".github/workflows/invalid.yml", Content: "on: [push\n"},
+		},
+	}
+	response, err := SecureWorkflows(context.Background(), queryParams, request, nil, nil)
+	if err != nil {
+		t.Fatalf("SecureWorkflows() returned error: %v", err)
+	}
+	if response.APIVersion != Version || len(response.Results) != 2 || !response.IsChanged || !response.HasErrors {
+		t.Fatalf("unexpected response %+v", response)
+	}
+
+	result := response.Results[0]
+	if !result.IsChanged || !strings.Contains(result.Output, "$GITHUB_OUTPUT") || !strings.Contains(result.Output, "contents: read") {
+		t.Errorf("expected the deprecated command to be rewritten and permissions to be added, got\n%s", result.Output)
+	}
+	var names []string
+	for _, module := range result.Modules {
+		names = append(names, module.Name)
+		for _, change := range module.Changes {
+			if change.File != ".github/workflows/ci.yml" {
+				t.Errorf("expected the path of the workflow in the changes, got %q", change.File)
+			}
+		}
+	}
+	if strings.Join(names, ",") != "deprecatedcommands,permissions" {
+		t.Errorf("modules = %v, want deprecatedcommands and permissions", names)
+	}
+	if response.Summary.Files != 2 || response.Summary.ChangedFiles != 1 || response.Summary.SafeChanges != 1 || response.Summary.NeedsReviewChanges == 0 {
+		t.Errorf("unexpected summary %+v", response.Summary)
+	}
+	if !response.Results[1].HasErrors || response.Results[1].IsChanged || response.Results[1].Output != "" {
+		t.Errorf("expected the invalid workflow to have errors, got %+v", response.Results[1])
+	}
+
+	diffParams := map[string]string{"output": "diff"}
+	for key, value := range queryParams {
+		diffParams[key] = value
+	}
+	response, err = SecureWorkflows(context.Background(), diffParams, SecureWorkflowRequest{Workflows: request.Workflows[:1]}, nil, nil)
+	if err != nil {
+		t.Fatalf("SecureWorkflows() returned error: %v", err)
+	}
+	if result := response.Results[0]; result.Output != "" || !strings.HasPrefix(result.Diff, "--- a/.github/workflows/ci.yml") {
+		t.Errorf("expected a diff instead of the output, got %+v", result)
+	}
+
+	if _, err := SecureWorkflows(context.Background(), queryParams, SecureWorkflowRequest{}, nil, nil); err == nil {
+		t.Errorf("expected an error for a request without workflows")
+	}
+	duplicates := SecureWorkflowRequest{Workflows: []securerepo.File{{Path: "ci.yml"}, {Path: "./ci.yml"}}}
+	if _, err := SecureWorkflows(context.Background(), queryParams, duplicates, nil, nil); err == nil {
+		t.Errorf("expected an error for workflows with the same path")
+	}
+}
+
+func TestSecureRepo(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	request := securerepo.SecureRepoRequest{FileList: []securerepo.File{{Path: ".github/workflows/ci.yml", Content: workflowInput}}}
+	params := map[string]string{"updateDependabotConfig": "false"}
+	for key, value := range queryParams {
+		params[key] = value
+	}
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("SecureRepo() returned error: %v", err)
+	}
+	if response.APIVersion != Version || len(response.Files) != 1 || !response.IsChanged {
+		t.Fatalf("unexpected response %+v", response)
+	}
+	result := response.Files[0]
+	if result.FileType != securerepo.FileTypeWorkflow || !strings.Contains(result.Output, "contents: read") || len(result.Modules) != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if change := result.Modules[0].Changes[0]; change.File != ".github/workflows/ci.yml" || change.Confidence != report.ConfidenceNeedsReview {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if response.Summary.ChangedFiles != 1 || response.Summary.NeedsReviewChanges != len(result.Modules[0].Changes) {
+		t.Errorf("unexpected summary %+v", response.Summary)
+	}
+}
@@ -0,0 +1,236 @@
+package argo
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/multidoc"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleUnpinnedImage = "argo-unpinned-image"
+	RuleHostPath      = "argo-host-path"
+	RulePrivileged    = "argo-privileged-container"
+
+	apiGroupPrefix = "argoproj.io/"
+)
+
+// SecureWorkflowResponse is the result of the remediations of a file with Argo Workflows manifests
+type SecureWorkflowResponse struct {
+	OriginalInput string
+	FinalOutput   string
+	IsChanged     bool
+	PinnedImages  bool
+	Findings      []findings.Finding
+}
+
+// IsArgoPath returns true for the YAML files in the .argo and argo directories. The manifests are found by their
+// content, so other files are remediated too.
+func IsArgoPath(filePath string) bool {
+	name := path.Base(filePath)
+	if !strings.HasSuffix(name, ".yml") && !strings.HasSuffix(name, ".yaml") {
+		return false
+	}
+	for _, dir := range strings.Split(path.Dir(filePath), "/") {
+		if dir == ".argo" || dir == "argo" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsWorkflow returns true if a document of the YAML is a Workflow, WorkflowTemplate, ClusterWorkflowTemplate or
+// CronWorkflow
+func IsWorkflow(inputYaml string) bool {
+	documents, err := multidoc.Mappings(inputYaml)
+	if err != nil {
+		return false
+	}
+	for _, topNode := range documents {
+		if getWorkflowSpec(topNode) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// getWorkflowSpec returns the spec of the workflow of a manifest, which is in workflowSpec for a CronWorkflow, or nil if
+// the manifest is not a workflow
+func getWorkflowSpec(topNode *yaml.Node) *yaml.Node {
+	apiVersion, kind := document.MappingValue(topNode, "apiVersion"), document.MappingValue(topNode, "kind")
+	if apiVersion == nil || kind == nil || !strings.HasPrefix(apiVersion.Value, apiGroupPrefix) {
+		return nil
+	}
+	spec := document.MappingValue(topNode, "spec")
+	switch kind.Value {
+	case "Workflow", "WorkflowTemplate", "ClusterWorkflowTemplate":
+		return spec
+	case "CronWorkflow":
+		return document.MappingValue(spec, "workflowSpec")
+	}
+	return nil
+}
+
+// container is a container of a template, with the name of the template
+type container struct {
+	node     *yaml.Node
+	template string
+}
+
+// getContainers returns the containers of the templates of a workflow and of its template defaults: the container or
+// script of a template, the containers of a container set, and its init containers and sidecars
+func getContainers(spec *yaml.Node) []container {
+	templates := append([]*yaml.Node{document.MappingValue(spec, "templateDefaults")}, document.Sequence(document.MappingValue(spec, "templates"))...)
+	var containers []container
+	for _, template := range templates {
+		if template == nil {
+			continue
+		}
+		name := "templateDefaults"
+		if nameNode := document.MappingValue(template, "name"); nameNode != nil {
+			name = nameNode.Value
+		}
+		nodes := []*yaml.Node{document.MappingValue(template, "container"), document.MappingValue(template, "script")}
+		nodes = append(nodes, document.Sequence(document.MappingValue(document.MappingValue(template, "containerSet"), "containers"))...)
+		nodes = append(nodes, document.Sequence(document.MappingValue(template, "initContainers"))...)
+		nodes = append(nodes, document.Sequence(document.MappingValue(template, "sidecars"))...)
+		for _, node := range nodes {
+			if node != nil && node.Kind == yaml.MappingNode {
+				containers = append(containers, container{node: node, template: name})
+			}
+		}
+	}
+	return containers
+}
+
+// findImageEdits returns the edits that pin the images of the containers to their digest, and the findings of the
+// images set with parameters or expressions
+func findImageEdits(ctx context.Context, containers []container) ([]textedit.Replacement, []findings.Finding, error) {
+	var edits []textedit.Replacement
+	var imageFindings []findings.Finding
+	for _, c := range containers {
+		image := document.MappingValue(c.node, "image")
+		if image == nil || image.Kind != yaml.ScalarNode || image.Value == "" || strings.Contains(image.Value, "@") {
+			continue
+		}
+		if strings.Contains(image.Value, "{{") {
+			imageFindings = append(imageFindings, findings.Finding{
+				RuleID:     RuleUnpinnedImage,
+				Message:    fmt.Sprintf("Image %s is set with a parameter, so it cannot be pinned to a digest", image.Value),
+				JobName:    c.template,
+				Action:     image.Value,
+				Line:       image.Line,
+				Column:     image.Column,
+				Suggestion: "Set the default of the parameter to an image with its digest",
+			})
+			continue
+		}
+		pinned, err := docker.PinImage(ctx, image.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		edits = append(edits, textedit.Replacement{Line: image.Line, Column: image.Column, Old: image.Value, New: pinned})
+	}
+	return edits, imageFindings, nil
+}
+
+// findPrivilegedContainers returns findings for the containers that run privileged or may escalate their privileges
+func findPrivilegedContainers(containers []container) []findings.Finding {
+	var privilegedFindings []findings.Finding
+	for _, c := range containers {
+		securityContext := document.MappingValue(c.node, "securityContext")
+		for _, key := range []string{"privileged", "allowPrivilegeEscalation"} {
+			value := document.MappingValue(securityContext, key)
+			if value == nil || value.Value != "true" {
+				continue
+			}
+			privilegedFindings = append(privilegedFindings, findings.Finding{
+				RuleID:     RulePrivileged,
+				Message:    fmt.Sprintf("A container of template %s sets %s, so a compromised step can take over the node", c.template, key),
+				JobName:    c.template,
+				Action:     key,
+				Line:       value.Line,
+				Column:     value.Column,
+				Suggestion: fmt.Sprintf("Remove %s from the security context of the container", key),
+			})
+		}
+	}
+	return privilegedFindings
+}
+
+// findHostPaths returns findings for the hostPath volumes of a workflow and its templates, which give the steps access
+// to the files of the node
+func findHostPaths(spec *yaml.Node) []findings.Finding {
+	volumes := append([]*yaml.Node{}, document.Sequence(document.MappingValue(spec, "volumes"))...)
+	for _, template := range document.Sequence(document.MappingValue(spec, "templates")) {
+		volumes = append(volumes, document.Sequence(document.MappingValue(template, "volumes"))...)
+	}
+	var hostPathFindings []findings.Finding
+	for _, volume := range volumes {
+		hostPath := document.MappingValue(volume, "hostPath")
+		if hostPath == nil {
+			continue
+		}
+		hostPathValue := ""
+		if pathNode := document.MappingValue(hostPath, "path"); pathNode != nil {
+			hostPathValue = pathNode.Value
+		}
+		volumeName := ""
+		if nameNode := document.MappingValue(volume, "name"); nameNode != nil {
+			volumeName = nameNode.Value
+		}
+		hostPathFindings = append(hostPathFindings, findings.Finding{
+			RuleID:     RuleHostPath,
+			Message:    fmt.Sprintf("Volume %s mounts %s of the node, which gives the steps access to the files of the node and of other pods", volumeName, hostPathValue),
+			Action:     hostPathValue,
+			Line:       hostPath.Line,
+			Column:     hostPath.Column,
+			Suggestion: "Use an emptyDir volume, or an artifact to pass files between steps",
+		})
+	}
+	return hostPathFindings
+}
+
+// SecureWorkflow runs the remediations for a file with Argo Workflows manifests. The images of the containers of the
+// templates are pinned to their digest unless pinImages is false. Images set with parameters, privileged containers and
+// hostPath volumes are reported as findings.
+func SecureWorkflow(ctx context.Context, queryStringParams map[string]string, inputYaml string) (*SecureWorkflowResponse, error) {
+	response := &SecureWorkflowResponse{OriginalInput: inputYaml, FinalOutput: inputYaml}
+	documents, err := multidoc.Mappings(inputYaml)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+
+	var edits []textedit.Replacement
+	for _, topNode := range documents {
+		spec := getWorkflowSpec(topNode)
+		if spec == nil {
+			continue
+		}
+		containers := getContainers(spec)
+		if queryStringParams["pinImages"] != "false" {
+			imageEdits, imageFindings, err := findImageEdits(ctx, containers)
+			if err != nil {
+				return nil, err
+			}
+			edits = append(edits, imageEdits...)
+			response.PinnedImages = response.PinnedImages || len(imageEdits) > 0
+			response.Findings = append(response.Findings, imageFindings...)
+		}
+		response.Findings = append(response.Findings, findPrivilegedContainers(containers)...)
+		response.Findings = append(response.Findings, findHostPaths(spec)...)
+	}
+
+	if len(edits) > 0 {
+		response.FinalOutput = textedit.ApplyReplacements(inputYaml, edits)
+		response.IsChanged = true
+	}
+	return response, nil
+}
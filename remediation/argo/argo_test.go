@@ -0,0 +1,128 @@
+package argo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/internal/testutil"
+)
+
+func TestSecureWorkflow(t *testing.T) {
+	digest := testutil.MockRegistry(t, "library/alpine/manifests/3.19", "library/python/manifests/latest", "library/docker/manifests/24-dind")
+
+	input := `apiVersion: argoproj.io/v1alpha1
+kind: WorkflowTemplate
+metadata:
+  name: build
+spec:
+  entrypoint: main
+  volumes:
+    - name: docker-sock
+      hostPath:
+        path: /var/run/docker.sock
+  templates:
+    - name: main
+      container:
+        image: alpine:3.19
+        command: [sh, -c, "echo hello"]
+    - name: report
+      script:
+        image: python
+        source: print("done")
+    - name: custom
+      container:
+        image: "{{inputs.parameters.image}}"
+    - name: image
+      container:
+        image: docker:24-dind
+        securityContext:
+          privileged: true
+---
+apiVersion: argoproj.io/v1alpha1
+kind: CronWorkflow
+metadata:
+  name: nightly
+spec:
+  schedule: "0 0 * * *"
+  workflowSpec:
+    entrypoint: main
+    templates:
+      - name: main
+        container:
+          image: alpine:3.19@sha256:0000000000000000000000000000000000000000000000000000000000000000
+`
+	want := `apiVersion: argoproj.io/v1alpha1
+kind: WorkflowTemplate
+metadata:
+  name: build
+spec:
+  entrypoint: main
+  volumes:
+    - name: docker-sock
+      hostPath:
+        path: /var/run/docker.sock
+  templates:
+    - name: main
+      container:
+        image: alpine:3.19@` + digest + `
+        command: [sh, -c, "echo hello"]
+    - name: report
+      script:
+        image: python:latest@` + digest + `
+        source: print("done")
+    - name: custom
+      container:
+        image: "{{inputs.parameters.image}}"
+    - name: image
+      container:
+        image: docker:24-dind@` + digest + `
+        securityContext:
+          privileged: true
+---
+apiVersion: argoproj.io/v1alpha1
+kind: CronWorkflow
+metadata:
+  name: nightly
+spec:
+  schedule: "0 0 * * *"
+  workflowSpec:
+    entrypoint: main
+    templates:
+      - name: main
+        container:
+          image: alpine:3.19@sha256:0000000000000000000000000000000000000000000000000000000000000000
+`
+	response, err := SecureWorkflow(context.Background(), map[string]string{}, input)
+	if err != nil {
+		t.Fatalf("SecureWorkflow() returned error: %v", err)
+	}
+	if !response.IsChanged || !response.PinnedImages || response.FinalOutput != want {
+		t.Errorf("SecureWorkflow() = %+v,\n%s\nwant\n%s", response, response.FinalOutput, want)
+	}
+	var got []string
+	for _, finding := range response.Findings {
+		got = append(got, fmt.Sprintf("%s:%d:%s:%s", finding.RuleID, finding.Line, finding.JobName, finding.Action))
+	}
+	want = "[argo-unpinned-image:22:custom:{{inputs.parameters.image}} argo-privileged-container:27:image:privileged argo-host-path:10::/var/run/docker.sock]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("findings = %v, want %v", got, want)
+	}
+
+	response, err = SecureWorkflow(context.Background(), map[string]string{"pinImages": "false"}, input)
+	if err != nil || response.IsChanged || len(response.Findings) != 2 {
+		t.Errorf("expected images not to be pinned, got %+v, %v", response, err)
+	}
+}
+
+func TestIsWorkflow(t *testing.T) {
+	for input, want := range map[string]bool{
+		"apiVersion: argoproj.io/v1alpha1\nkind: Workflow\nspec:\n  entrypoint: main\n":    true,
+		"apiVersion: argoproj.io/v1alpha1\nkind: Application\nspec:\n  project: default\n": false,
+		"apiVersion: batch/v1\nkind: Job\n":                                                false,
+	} {
+		if got := IsWorkflow(input); got != want {
+			t.Errorf("IsWorkflow(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/golang-jwt/jwt"
+	"github.com/step-security/secure-repo/remediation/storage"
+)
+
+const (
+	// APIKeysEnv has the API keys of the tenants as comma separated tenant=key pairs, e.g. acme=6f1c...,globex=93ab...
+	APIKeysEnv = "API_KEYS"
+	// APIKeysTableEnv is the DynamoDB table the API keys are looked up in, instead of API_KEYS
+	APIKeysTableEnv = "API_KEYS_TABLE"
+	// APIKeysStorageEnv set to true looks up the API keys in the storage of STORAGE_URL, instead of API_KEYS
+	APIKeysStorageEnv = "API_KEYS_STORAGE"
+	// JWTSecretEnv is the secret of HS256 JWTs, whose subject is the tenant
+	JWTSecretEnv = "API_JWT_SECRET"
+	// RateLimitEnv is the number of requests per minute allowed for a tenant that does not have its own limit
+	RateLimitEnv = "API_RATE_LIMIT"
+
+	APIKeyHeader        = "x-api-key"
+	AuthorizationHeader = "authorization"
+)
+
+// Tenant is a consumer of the API. RequestsPerMinute overrides the default rate limit, if it is set.
+type Tenant struct {
+	ID                string
+	RequestsPerMinute int
+}
+
+// KeyStore looks up the tenant of an API key. It returns nil if the key is not found.
+type KeyStore interface {
+	GetTenant(apiKey string) (*Tenant, error)
+}
+
+// Error is an error of authentication, with the status code of the response
+type Error struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is the number of seconds after which a rate limited request can be retried
+	RetryAfter int
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// HashKey returns the hex SHA-256 of an API key, which is how keys are stored
+func HashKey(apiKey string) string {
+	hash := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(hash[:])
+}
+
+// StaticKeyStore has the hashes of the API keys of the tenants
+type StaticKeyStore map[string]Tenant
+
+// ParseStaticKeyStore parses tenant=key pairs separated by commas
+func ParseStaticKeyStore(keys string) (StaticKeyStore, error) {
+	store := StaticKeyStore{}
+	for _, pair := range strings.Split(keys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid API key, expected tenant=key")
+		}
+		store[HashKey(parts[1])] = Tenant{ID: parts[0]}
+	}
+	return store, nil
+}
+
+func (s StaticKeyStore) GetTenant(apiKey string) (*Tenant, error) {
+	hash := HashKey(apiKey)
+	for keyHash, tenant := range s {
+		if subtle.ConstantTimeCompare([]byte(keyHash), []byte(hash)) == 1 {
+			tenant := tenant
+			return &tenant, nil
+		}
+	}
+	return nil, nil
+}
+
+// Authenticator authenticates requests with an API key in the x-api-key header, or a JWT in the authorization header,
+// and limits the requests of each tenant
+type Authenticator struct {
+	Keys      KeyStore
+	JWTSecret []byte
+	Limiter   *RateLimiter
+	// RequestsPerMinute is the rate limit of tenants without their own limit, 0 for no limit
+	RequestsPerMinute int
+}
+
+// NewAuthenticator returns an authenticator with the key store, the secret of JWTs and the default rate limit. Either can be empty.
+func NewAuthenticator(keys KeyStore, jwtSecret []byte, requestsPerMinute int) *Authenticator {
+	return &Authenticator{Keys: keys, JWTSecret: jwtSecret, Limiter: NewRateLimiter(), RequestsPerMinute: requestsPerMinute}
+}
+
+// NewAuthenticatorFromEnv returns the authenticator configured by API_KEYS, API_KEYS_TABLE or API_KEYS_STORAGE,
+// API_JWT_SECRET and API_RATE_LIMIT, or nil if neither API keys nor JWTs are configured, in which case the API is not
+// authenticated.
+func NewAuthenticatorFromEnv(svc dynamodbiface.DynamoDBAPI) (*Authenticator, error) {
+	var keys KeyStore
+	if tableName := os.Getenv(APIKeysTableEnv); tableName != "" {
+		keys = &DynamoDBKeyStore{TableName: tableName, Svc: svc}
+	} else if os.Getenv(APIKeysStorageEnv) == "true" {
+		store, err := storage.Default()
+		if err != nil {
+			return nil, err
+		}
+		if store == nil {
+			return nil, fmt.Errorf("%s requires %s", APIKeysStorageEnv, storage.URLEnv)
+		}
+		keys = &StorageKeyStore{Store: store}
+	} else if apiKeys := os.Getenv(APIKeysEnv); apiKeys != "" {
+		store, err := ParseStaticKeyStore(apiKeys)
+		if err != nil {
+			return nil, err
+		}
+		keys = store
+	}
+	jwtSecret := os.Getenv(JWTSecretEnv)
+	if keys == nil && jwtSecret == "" {
+		return nil, nil
+	}
+	requestsPerMinute := 0
+	if rateLimit := os.Getenv(RateLimitEnv); rateLimit != "" {
+		var err error
+		requestsPerMinute, err = strconv.Atoi(rateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s", RateLimitEnv, rateLimit)
+		}
+	}
+	return NewAuthenticator(keys, []byte(jwtSecret), requestsPerMinute), nil
+}
+
+// getJWTTenant returns the tenant of a JWT signed with the secret, which is its subject, or nil if the JWT is not valid.
+// A JWT without an expiry is not valid, so a token cannot be used forever once it is signed.
+func (a *Authenticator) getJWTTenant(tokenString string) *Tenant {
+	claims := &jwt.StandardClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return a.JWTSecret, nil
+	})
+	if err != nil || claims.Subject == "" || claims.ExpiresAt == 0 {
+		return nil
+	}
+	return &Tenant{ID: claims.Subject}
+}
+
+// Authenticate returns the tenant of the request headers, whose names are lower case. The error is an *Error with
+// 401 if the request is not authenticated, and 429 if the tenant made too many requests.
+func (a *Authenticator) Authenticate(headers map[string]string) (*Tenant, error) {
+	var tenant *Tenant
+	var err error
+	if apiKey := headers[APIKeyHeader]; apiKey != "" && a.Keys != nil {
+		tenant, err = a.Keys.GetTenant(apiKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get API key: %v", err)
+		}
+	} else if bearer := headers[AuthorizationHeader]; strings.HasPrefix(bearer, "Bearer ") && len(a.JWTSecret) > 0 {
+		tenant = a.getJWTTenant(strings.TrimPrefix(bearer, "Bearer "))
+	}
+	if tenant == nil {
+		return nil, &Error{StatusCode: http.StatusUnauthorized, Message: "invalid or missing API key"}
+	}
+
+	limit := a.RequestsPerMinute
+	if tenant.RequestsPerMinute > 0 {
+		limit = tenant.RequestsPerMinute
+	}
+	if allowed, retryAfter := a.Limiter.Allow(tenant.ID, limit); !allowed {
+		return tenant, &Error{StatusCode: http.StatusTooManyRequests, Message: "rate limit exceeded", RetryAfter: int(retryAfter.Seconds()) + 1}
+	}
+	return tenant, nil
+}
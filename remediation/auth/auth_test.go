@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/step-security/secure-repo/remediation/storage"
+)
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	keys, err := ParseStaticKeyStore("acme=acme-key, globex=globex-key")
+	if err != nil {
+		t.Fatalf("ParseStaticKeyStore() returned error: %v", err)
+	}
+	authenticator := NewAuthenticator(keys, nil, 0)
+
+	tenant, err := authenticator.Authenticate(map[string]string{APIKeyHeader: "globex-key"})
+	if err != nil {
+		t.Fatalf("Authenticate() returned error: %v", err)
+	}
+	if tenant.ID != "globex" {
+		t.Errorf("tenant = %s, want globex", tenant.ID)
+	}
+
+	for _, headers := range []map[string]string{{APIKeyHeader: "unknown-key"}, {}} {
+		_, err = authenticator.Authenticate(headers)
+		if authError, ok := err.(*Error); !ok || authError.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authenticate(%v) error = %v, want 401", headers, err)
+		}
+	}
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	secret := []byte("jwt-secret")
+	authenticator := NewAuthenticator(nil, secret, 0)
+
+	sign := func(claims jwt.StandardClaims, key []byte) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("unable to sign token: %v", err)
+		}
+		return "Bearer " + token
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	tenant, err := authenticator.Authenticate(map[string]string{AuthorizationHeader: sign(jwt.StandardClaims{Subject: "acme", ExpiresAt: expiresAt}, secret)})
+	if err != nil {
+		t.Fatalf("Authenticate() returned error: %v", err)
+	}
+	if tenant.ID != "acme" {
+		t.Errorf("tenant = %s, want acme", tenant.ID)
+	}
+
+	invalid := []string{
+		sign(jwt.StandardClaims{Subject: "acme", ExpiresAt: expiresAt}, []byte("other-secret")),
+		sign(jwt.StandardClaims{Subject: "acme", ExpiresAt: time.Now().Add(-time.Minute).Unix()}, secret),
+		// a token without an expiry would be valid forever
+		sign(jwt.StandardClaims{Subject: "acme"}, secret),
+		sign(jwt.StandardClaims{ExpiresAt: expiresAt}, secret),
+	}
+	for _, bearer := range invalid {
+		_, err = authenticator.Authenticate(map[string]string{AuthorizationHeader: bearer})
+		if authError, ok := err.(*Error); !ok || authError.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authenticate(%s) error = %v, want 401", bearer, err)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	keys := StaticKeyStore{HashKey("acme-key"): {ID: "acme"}, HashKey("globex-key"): {ID: "globex", RequestsPerMinute: 3}}
+	authenticator := NewAuthenticator(keys, nil, 2)
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	authenticator.Limiter.now = func() time.Time { return now }
+
+	authenticate := func(apiKey string) error {
+		_, err := authenticator.Authenticate(map[string]string{APIKeyHeader: apiKey})
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := authenticate("acme-key"); err != nil {
+			t.Fatalf("request %d returned error: %v", i+1, err)
+		}
+	}
+	err := authenticate("acme-key")
+	authError, ok := err.(*Error)
+	if !ok || authError.StatusCode != http.StatusTooManyRequests || authError.RetryAfter != 61 {
+		t.Errorf("third request error = %v, want 429 with retry after 61 seconds", err)
+	}
+
+	// the limit of the tenant overrides the default
+	for i := 0; i < 3; i++ {
+		if err := authenticate("globex-key"); err != nil {
+			t.Errorf("request %d of globex returned error: %v", i+1, err)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if err := authenticate("acme-key"); err != nil {
+		t.Errorf("request in the next minute returned error: %v", err)
+	}
+	// the window of globex ended, and is removed
+	if _, found := authenticator.Limiter.windows["globex"]; found || len(authenticator.Limiter.windows) != 1 {
+		t.Errorf("expected only the window of acme, got %v", authenticator.Limiter.windows)
+	}
+}
+
+func TestStorageKeyStore(t *testing.T) {
+	store := &storage.FileStore{Dir: t.TempDir()}
+	store.Put("api-keys/"+HashKey("acme-key"), []byte(`{"Tenant": "acme", "RequestsPerMinute": 10}`))
+	store.Put("api-keys/"+HashKey("disabled-key"), []byte(`{"Tenant": "globex", "Disabled": true}`))
+	keys := &StorageKeyStore{Store: store}
+
+	tenant, err := keys.GetTenant("acme-key")
+	if err != nil || tenant == nil || tenant.ID != "acme" || tenant.RequestsPerMinute != 10 {
+		t.Errorf("GetTenant() = %+v, %v, want acme", tenant, err)
+	}
+	for _, apiKey := range []string{"disabled-key", "unknown-key"} {
+		if tenant, err := keys.GetTenant(apiKey); tenant != nil || err != nil {
+			t.Errorf("GetTenant(%s) = %+v, %v, want nil", apiKey, tenant, err)
+		}
+	}
+}
+
+func TestNewAuthenticatorFromEnv(t *testing.T) {
+	os.Unsetenv(APIKeysEnv)
+	os.Unsetenv(APIKeysTableEnv)
+	os.Unsetenv(JWTSecretEnv)
+	authenticator, err := NewAuthenticatorFromEnv(nil)
+	if err != nil || authenticator != nil {
+		t.Errorf("NewAuthenticatorFromEnv() = %v, %v, want nil when authentication is not configured", authenticator, err)
+	}
+
+	os.Setenv(APIKeysEnv, "acme=acme-key")
+	os.Setenv(RateLimitEnv, "60")
+	defer os.Unsetenv(APIKeysEnv)
+	defer os.Unsetenv(RateLimitEnv)
+	authenticator, err = NewAuthenticatorFromEnv(nil)
+	if err != nil {
+		t.Fatalf("NewAuthenticatorFromEnv() returned error: %v", err)
+	}
+	if authenticator.RequestsPerMinute != 60 {
+		t.Errorf("RequestsPerMinute = %d, want 60", authenticator.RequestsPerMinute)
+	}
+
+	os.Setenv(APIKeysEnv, "acme")
+	if _, err = NewAuthenticatorFromEnv(nil); err == nil {
+		t.Errorf("NewAuthenticatorFromEnv() expected error for a key without tenant")
+	}
+}
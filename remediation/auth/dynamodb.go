@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// APIKey is an item of the API keys table. The key is stored as its hash, so the table does not have the keys.
+type APIKey struct {
+	KeyHash           string
+	Tenant            string
+	RequestsPerMinute int
+	Disabled          bool
+}
+
+// DynamoDBKeyStore looks up API keys in a DynamoDB table with KeyHash as the hash key
+type DynamoDBKeyStore struct {
+	TableName string
+	Svc       dynamodbiface.DynamoDBAPI
+}
+
+func (s *DynamoDBKeyStore) GetTenant(apiKey string) (*Tenant, error) {
+	result, err := s.Svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.TableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"KeyHash": {
+				S: aws.String(HashKey(apiKey)),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	item := APIKey{}
+	err = dynamodbattribute.UnmarshalMap(result.Item, &item)
+	if err != nil {
+		return nil, err
+	}
+	if item.Disabled || item.Tenant == "" {
+		return nil, nil
+	}
+	return &Tenant{ID: item.Tenant, RequestsPerMinute: item.RequestsPerMinute}, nil
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// RateLimiter counts the requests of each tenant in windows of a minute. The counts are kept in memory, so with
+// several instances each one limits the requests it serves. The windows that ended are removed once a minute, so the
+// memory does not grow with each tenant that ever made a request.
+type RateLimiter struct {
+	mutex     sync.Mutex
+	windows   map[string]*window
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{windows: map[string]*window{}, now: time.Now}
+}
+
+// Allow counts a request of the tenant, and returns whether it is within the limit of requests per minute, and if not,
+// how long until the next window. A limit of 0 allows all requests.
+func (l *RateLimiter) Allow(tenant string, limit int) (bool, time.Duration) {
+	if limit <= 0 {
+		return true, 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.prune(now)
+	w, found := l.windows[tenant]
+	if !found || now.Sub(w.start) >= time.Minute {
+		w = &window{start: now}
+		l.windows[tenant] = w
+	}
+	if w.count >= limit {
+		return false, w.start.Add(time.Minute).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// prune removes the windows that ended, at most once a minute
+func (l *RateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	for tenant, w := range l.windows {
+		if now.Sub(w.start) >= time.Minute {
+			delete(l.windows, tenant)
+		}
+	}
+	l.lastPrune = now
+}
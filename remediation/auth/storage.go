@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"encoding/json"
+
+	"github.com/step-security/secure-repo/remediation/storage"
+)
+
+// StorageKeyStore looks up API keys in a storage.Store, where each key is the JSON of an APIKey at api-keys/<KeyHash>
+type StorageKeyStore struct {
+	Store storage.Store
+}
+
+func (s *StorageKeyStore) GetTenant(apiKey string) (*Tenant, error) {
+	value, err := s.Store.Get("api-keys/" + HashKey(apiKey))
+	if err != nil || value == nil {
+		return nil, err
+	}
+	item := APIKey{}
+	if err := json.Unmarshal(value, &item); err != nil {
+		return nil, err
+	}
+	if item.Disabled || item.Tenant == "" {
+		return nil, nil
+	}
+	return &Tenant{ID: item.Tenant, RequestsPerMinute: item.RequestsPerMinute}, nil
+}
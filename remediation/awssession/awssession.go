@@ -0,0 +1,21 @@
+// Package awssession creates the session of the AWS clients once for each instance, when it is first used, instead of
+// for each request or each client. Creating a session reads the environment and the shared configuration, which adds to
+// the start of the Lambda function and to each request served by it.
+package awssession
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+var defaultSession = sync.OnceValue(func() *session.Session {
+	return session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+})
+
+// Default returns the session shared by the AWS clients, which is created by the first call
+func Default() *session.Session {
+	return defaultSession()
+}
@@ -0,0 +1,274 @@
+package azurepipelines
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/metrics"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"golang.org/x/oauth2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleUnpinnedRepository = "azure-unpinned-repository"
+	RuleMajorVersionTask   = "azure-task-major-version"
+)
+
+var (
+	shaRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	// majorVersionTaskRegex matches tasks referenced by their major version, e.g. Npm@1, which runs the latest minor
+	// version of the major version that is installed in the organization
+	majorVersionTaskRegex = regexp.MustCompile(`^([\w.-]+)@(\d+)$`)
+)
+
+// SecurePipelineResponse is the result of the remediations of an Azure Pipelines configuration
+type SecurePipelineResponse struct {
+	OriginalInput      string
+	FinalOutput        string
+	IsChanged          bool
+	PinnedRepositories bool
+	PinnedImages       bool
+	Findings           []findings.Finding
+}
+
+// IsPipeline returns true for azure-pipelines.yml, and the YAML files in the .azure-pipelines directory
+func IsPipeline(filePath string) bool {
+	name := path.Base(filePath)
+	if name == "azure-pipelines.yml" || name == "azure-pipelines.yaml" {
+		return true
+	}
+	isYaml := strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")
+	return isYaml && (strings.HasPrefix(filePath, ".azure-pipelines/") || strings.Contains(filePath, "/.azure-pipelines/"))
+}
+
+// ResolveCommit returns the SHA of the commit a ref of a GitHub repository points to, e.g. refs/tags/v1.2, with the
+// token in SECURE_REPO_PAT or PAT
+var ResolveCommit = resolveCommit
+
+func resolveCommit(ctx context.Context, repository, ref string) (string, error) {
+	parts := strings.SplitN(repository, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid repository %s", repository)
+	}
+	token := os.Getenv("SECURE_REPO_PAT")
+	if token == "" {
+		token = os.Getenv("PAT")
+	}
+	client := github.NewClient(oauth2.NewClient(metrics.WithGitHubClient(ctx), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+	sha, _, err := client.Repositories.GetCommitSHA1(ctx, parts[0], parts[1], ref, "")
+	if err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+// edit replaces the old value of a node with the new one, and keeps the old value in a comment. If insert is set, the
+// line is inserted after the line of the node instead.
+type edit struct {
+	node    *yaml.Node
+	old     string
+	new     string
+	comment string
+	insert  string
+}
+
+// applyEdits applies the edits to the lines from the end, so the lines and columns of the other edits do not move
+func applyEdits(inputYaml string, edits []edit) string {
+	buffer := textedit.NewBuffer(inputYaml)
+	for _, e := range edits {
+		if e.insert != "" {
+			buffer.InsertLine(e.node.Line, e.insert)
+			continue
+		}
+		line, lineStart := buffer.Line(e.node.Line)
+		// the column is the start of the value, which may be quoted
+		start := textedit.ColumnOffset(line, e.node.Column)
+		offset := strings.Index(line[start:], e.old)
+		if offset == -1 {
+			continue
+		}
+		start += offset
+		rest := line[start+len(e.old):]
+		// the old value is kept in a comment if the value ends the line
+		if e.comment != "" && strings.TrimSpace(strings.Trim(rest, `"'`)) == "" {
+			buffer.Replace(lineStart+start, lineStart+len(line), strings.TrimRight(e.new+rest, " \t\r")+"  # "+e.comment)
+			continue
+		}
+		buffer.Replace(lineStart+start, lineStart+start+len(e.old), e.new)
+	}
+	return buffer.String()
+}
+
+// findRepositoryEdits returns the edits that pin the GitHub repository resources to the SHA of their commit, and the
+// findings of the repositories of other types, whose commits are not resolved. Refs set with variables or template
+// expressions are skipped.
+func findRepositoryEdits(ctx context.Context, topNode *yaml.Node) ([]edit, []findings.Finding, error) {
+	var edits []edit
+	var repositoryFindings []findings.Finding
+	for _, repository := range document.Sequence(document.MappingValue(document.MappingValue(topNode, "resources"), "repositories")) {
+		nameKey, nameNode := document.MappingEntry(repository, "name")
+		typeNode, refNode := document.MappingValue(repository, "type"), document.MappingValue(repository, "ref")
+		if nameNode == nil || nameNode.Kind != yaml.ScalarNode || typeNode == nil {
+			continue
+		}
+		if refNode != nil && (refNode.Kind != yaml.ScalarNode || shaRegex.MatchString(refNode.Value) ||
+			strings.Contains(refNode.Value, "$")) {
+			continue
+		}
+		if typeNode.Value != "github" {
+			repositoryFindings = append(repositoryFindings, findings.Finding{
+				RuleID:     RuleUnpinnedRepository,
+				Message:    fmt.Sprintf("Templates of repository %s are used from a branch or tag, which can change without a change to the pipeline", nameNode.Value),
+				Line:       nameNode.Line,
+				Column:     nameNode.Column,
+				Suggestion: "Set ref to the SHA of a commit of the repository",
+			})
+			continue
+		}
+		if refNode == nil {
+			// a repository without a ref is used from its default branch, whose head is pinned on a new line
+			if repository.Style&yaml.FlowStyle != 0 {
+				continue
+			}
+			sha, err := ResolveCommit(ctx, nameNode.Value, "HEAD")
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to get commit of %s: %v", nameNode.Value, err)
+			}
+			edits = append(edits, edit{node: nameNode, insert: strings.Repeat(" ", nameKey.Column-1) + "ref: " + sha + "  # default branch"})
+			continue
+		}
+		ref := strings.TrimPrefix(strings.TrimPrefix(refNode.Value, "refs/heads/"), "refs/tags/")
+		sha, err := ResolveCommit(ctx, nameNode.Value, ref)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to get commit of %s@%s: %v", nameNode.Value, refNode.Value, err)
+		}
+		edits = append(edits, edit{node: refNode, old: refNode.Value, new: sha, comment: refNode.Value})
+	}
+	return edits, repositoryFindings, nil
+}
+
+// getJobs returns the jobs of the pipeline, in its stages or at its top level
+func getJobs(topNode *yaml.Node) []*yaml.Node {
+	jobs := append([]*yaml.Node{}, document.Sequence(document.MappingValue(topNode, "jobs"))...)
+	for _, stage := range document.Sequence(document.MappingValue(topNode, "stages")) {
+		jobs = append(jobs, document.Sequence(document.MappingValue(stage, "jobs"))...)
+	}
+	return jobs
+}
+
+// findImages returns the image nodes of the container resources and of the containers of the jobs that are not pinned
+// to a digest. The container of a job is either an image or the name of a container resource, which is pinned in the
+// resource. Images set with variables or template expressions are skipped.
+func findImages(topNode *yaml.Node) []*yaml.Node {
+	var images []*yaml.Node
+	add := func(node *yaml.Node) {
+		if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" || strings.Contains(node.Value, "@") ||
+			strings.Contains(node.Value, "$") {
+			return
+		}
+		images = append(images, node)
+	}
+	resources := map[string]bool{}
+	for _, container := range document.Sequence(document.MappingValue(document.MappingValue(topNode, "resources"), "containers")) {
+		if name := document.MappingValue(container, "container"); name != nil {
+			resources[name.Value] = true
+		}
+		add(document.MappingValue(container, "image"))
+	}
+	// the pipeline of a single job has the container at its top level
+	for _, job := range append([]*yaml.Node{topNode}, getJobs(topNode)...) {
+		container := document.MappingValue(job, "container")
+		if container != nil && container.Kind == yaml.MappingNode {
+			add(document.MappingValue(container, "image"))
+		} else if container != nil && !resources[container.Value] {
+			add(container)
+		}
+	}
+	return images
+}
+
+// findImageEdits returns the edits that pin the images to their digest, keeping the tag for readability
+func findImageEdits(ctx context.Context, topNode *yaml.Node) ([]edit, error) {
+	var edits []edit
+	for _, image := range findImages(topNode) {
+		pinned, err := docker.PinImage(ctx, image.Value)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit{node: image, old: image.Value, new: pinned})
+	}
+	return edits, nil
+}
+
+// findMajorVersionTasks returns findings for the tasks of the steps that are referenced by their major version
+func findMajorVersionTasks(node *yaml.Node, taskFindings []findings.Finding) []findings.Finding {
+	if node.Kind == yaml.MappingNode {
+		if taskNode := document.MappingValue(node, "task"); taskNode != nil && taskNode.Kind == yaml.ScalarNode {
+			if match := majorVersionTaskRegex.FindStringSubmatch(taskNode.Value); match != nil {
+				taskFindings = append(taskFindings, findings.Finding{
+					RuleID:     RuleMajorVersionTask,
+					Message:    fmt.Sprintf("Task %s is referenced by its major version, so a new minor version runs without a change to the pipeline", taskNode.Value),
+					Action:     match[1],
+					Line:       taskNode.Line,
+					Column:     taskNode.Column,
+					Suggestion: fmt.Sprintf("Reference the full version of the task, e.g. %s@%s.x.y", match[1], match[2]),
+				})
+			}
+		}
+	}
+	for _, child := range node.Content {
+		taskFindings = findMajorVersionTasks(child, taskFindings)
+	}
+	return taskFindings
+}
+
+// SecurePipeline runs the remediations for an Azure Pipelines configuration. The GitHub repository resources of the
+// templates are pinned to the SHA of their commit unless pinRepositories is false, and the container images to their
+// digest unless pinImages is false. Repositories of other types and tasks referenced by their major version are
+// reported as findings.
+func SecurePipeline(ctx context.Context, queryStringParams map[string]string, inputYaml string) (*SecurePipelineResponse, error) {
+	response := &SecurePipelineResponse{OriginalInput: inputYaml, FinalOutput: inputYaml}
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(inputYaml), &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return response, nil
+	}
+	topNode := doc.Content[0]
+
+	var edits []edit
+	if queryStringParams["pinRepositories"] != "false" {
+		repositoryEdits, repositoryFindings, err := findRepositoryEdits(ctx, topNode)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, repositoryEdits...)
+		response.PinnedRepositories = len(repositoryEdits) > 0
+		response.Findings = append(response.Findings, repositoryFindings...)
+	}
+	if queryStringParams["pinImages"] != "false" {
+		imageEdits, err := findImageEdits(ctx, topNode)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, imageEdits...)
+		response.PinnedImages = len(imageEdits) > 0
+	}
+	response.Findings = findMajorVersionTasks(topNode, response.Findings)
+
+	if len(edits) > 0 {
+		response.FinalOutput = applyEdits(inputYaml, edits)
+		response.IsChanged = true
+	}
+	return response, nil
+}
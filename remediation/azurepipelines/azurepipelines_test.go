@@ -0,0 +1,142 @@
+package azurepipelines
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/internal/testutil"
+)
+
+func TestSecurePipeline(t *testing.T) {
+	const sha = "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2"
+	digest := testutil.MockRegistry(t, "library/ubuntu/manifests/22.04", "library/node/manifests/20", "library/python/manifests/latest")
+	saveResolveCommit := ResolveCommit
+	var resolved []string
+	ResolveCommit = func(_ context.Context, repository, ref string) (string, error) {
+		resolved = append(resolved, repository+"@"+ref)
+		return sha, nil
+	}
+	defer func() { ResolveCommit = saveResolveCommit }()
+
+	input := `resources:
+  repositories:
+    - repository: templates
+      type: github
+      name: contoso/pipeline-templates
+      ref: refs/tags/v1.2
+      endpoint: contoso
+    - repository: shared
+      type: github
+      name: contoso/shared
+      endpoint: contoso
+    - repository: internal
+      type: git
+      name: Contoso/templates
+  containers:
+    - container: linux
+      image: ubuntu:22.04
+stages:
+  - stage: Build
+    jobs:
+      - job: build
+        container: linux
+        steps:
+          - task: NodeTool@0
+          - task: Npm@1.238.1
+          - template: steps/build.yml@templates
+      - job: test
+        container: "node:20"
+        steps:
+          - script: npm test
+  - stage: Lint
+    jobs:
+      - job: lint
+        container:
+          image: python
+        steps:
+          - task: UsePythonVersion@0
+`
+	want := `resources:
+  repositories:
+    - repository: templates
+      type: github
+      name: contoso/pipeline-templates
+      ref: ` + sha + `  # refs/tags/v1.2
+      endpoint: contoso
+    - repository: shared
+      type: github
+      name: contoso/shared
+      ref: ` + sha + `  # default branch
+      endpoint: contoso
+    - repository: internal
+      type: git
+      name: Contoso/templates
+  containers:
+    - container: linux
+      image: ubuntu:22.04@` + digest + `
+stages:
+  - stage: Build
+    jobs:
+      - job: build
+        container: linux
+        steps:
+          - task: NodeTool@0
+          - task: Npm@1.238.1
+          - template: steps/build.yml@templates
+      - job: test
+        container: "node:20@` + digest + `"
+        steps:
+          - script: npm test
+  - stage: Lint
+    jobs:
+      - job: lint
+        container:
+          image: python:latest@` + digest + `
+        steps:
+          - task: UsePythonVersion@0
+`
+	response, err := SecurePipeline(context.Background(), map[string]string{}, input)
+	if err != nil {
+		t.Fatalf("SecurePipeline() returned error: %v", err)
+	}
+	if !response.IsChanged || !response.PinnedRepositories || !response.PinnedImages || response.FinalOutput != want {
+		t.Errorf("SecurePipeline() = %+v,\n%s\nwant\n%s", response, response.FinalOutput, want)
+	}
+	if fmt.Sprint(resolved) != "[contoso/pipeline-templates@v1.2 contoso/shared@HEAD]" {
+		t.Errorf("resolved refs = %v", resolved)
+	}
+	var rules []string
+	for _, finding := range response.Findings {
+		rules = append(rules, fmt.Sprintf("%s:%d", finding.RuleID, finding.Line))
+	}
+	if fmt.Sprint(rules) != "[azure-unpinned-repository:14 azure-task-major-version:24 azure-task-major-version:37]" {
+		t.Errorf("findings = %v, want the repository of Azure Repos and the tasks with a major version", rules)
+	}
+
+	response, err = SecurePipeline(context.Background(), map[string]string{}, want)
+	if err != nil || response.IsChanged || response.FinalOutput != want {
+		t.Errorf("expected pinned pipeline to be unchanged, got %+v, %v", response, err)
+	}
+
+	// the pinning is turned off with the parameters, and the findings are still reported
+	response, err = SecurePipeline(context.Background(), map[string]string{"pinRepositories": "false", "pinImages": "false"}, input)
+	if err != nil || response.IsChanged || len(response.Findings) != 2 {
+		t.Errorf("expected no changes, got %+v, %v", response, err)
+	}
+}
+
+func TestIsPipeline(t *testing.T) {
+	for filePath, want := range map[string]bool{
+		"azure-pipelines.yml":               true,
+		"services/api/azure-pipelines.yaml": true,
+		".azure-pipelines/release.yml":      true,
+		"ci/.azure-pipelines/build.yaml":    true,
+		".azure-pipelines/README.md":        false,
+		"pipelines/build.yml":               false,
+	} {
+		if got := IsPipeline(filePath); got != want {
+			t.Errorf("IsPipeline(%s) = %v, want %v", filePath, got, want)
+		}
+	}
+}
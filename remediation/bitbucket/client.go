@@ -0,0 +1,272 @@
+package bitbucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/outbound"
+)
+
+const (
+	// environment variables with the URL of the API, which defaults to DefaultURL, and the access token used when the
+	// request has none
+	URLEnv   = "BITBUCKET_URL"
+	TokenEnv = "BITBUCKET_TOKEN"
+
+	DefaultURL = "https://api.bitbucket.org"
+
+	// TokenHeader is the header of the access token in the requests to the API of secure-repo, since the authorization
+	// header authenticates the requests themselves
+	TokenHeader = "X-Bitbucket-Token"
+)
+
+// Client is a client of the REST API of Bitbucket Cloud, authenticated with a repository, project or workspace access token
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// Error is an error response of the API
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("status code %d: %s", e.StatusCode, e.Message)
+}
+
+// Repository is a Bitbucket repository
+type Repository struct {
+	FullName   string `json:"full_name"`
+	MainBranch struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+}
+
+type branch struct {
+	Target struct {
+		Hash string `json:"hash"`
+	} `json:"target"`
+}
+
+type srcPage struct {
+	Values []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	} `json:"values"`
+	Next string `json:"next"`
+}
+
+// PullRequest is a Bitbucket pull request
+type PullRequest struct {
+	ID    int `json:"id"`
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+}
+
+type pullRequestPage struct {
+	Values []PullRequest `json:"values"`
+}
+
+// NewClient returns a client of the API in BITBUCKET_URL, authenticated with the token or with BITBUCKET_TOKEN if the
+// token is empty
+func NewClient(token string) *Client {
+	baseURL := os.Getenv(URLEnv)
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	if token == "" {
+		token = os.Getenv(TokenEnv)
+	}
+	return &Client{BaseURL: baseURL, Token: token}
+}
+
+// repositoryPath returns the path of the API of the repository, whose name is the workspace and the slug, e.g. workspace/app
+func repositoryPath(repository string) string {
+	parts := strings.SplitN(repository, "/", 2)
+	if len(parts) != 2 {
+		return "/2.0/repositories/" + url.PathEscape(repository)
+	}
+	return "/2.0/repositories/" + url.PathEscape(parts[0]) + "/" + url.PathEscape(parts[1])
+}
+
+// do sends a request to the API, and decodes the JSON response into result if it is not nil. A path starting with the
+// base URL, such as the next page of a list, is requested as is.
+func (c *Client) do(ctx context.Context, method, apiPath, contentType string, body io.Reader, result interface{}) error {
+	requestURL := apiPath
+	if !strings.HasPrefix(apiPath, strings.TrimSuffix(c.BaseURL, "/")+"/") {
+		requestURL = strings.TrimSuffix(c.BaseURL, "/") + apiPath
+	}
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = outbound.Client()
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	switch v := result.(type) {
+	case nil:
+		return nil
+	case *string:
+		*v = string(data)
+		return nil
+	default:
+		return json.Unmarshal(data, result)
+	}
+}
+
+func (c *Client) doJSON(ctx context.Context, method, apiPath string, body, result interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, apiPath, "application/json", bytes.NewReader(payload), result)
+}
+
+// GetRepository returns the repository
+func (c *Client) GetRepository(ctx context.Context, repository string) (*Repository, error) {
+	result := &Repository{}
+	if err := c.do(ctx, http.MethodGet, repositoryPath(repository), "", nil, result); err != nil {
+		return nil, fmt.Errorf("unable to get repository: %v", err)
+	}
+	return result, nil
+}
+
+// getBranchCommit returns the hash of the commit at the head of the branch, or an empty hash if the branch does not exist
+func (c *Client) getBranchCommit(ctx context.Context, repository, branchName string) (string, error) {
+	result := &branch{}
+	err := c.do(ctx, http.MethodGet, repositoryPath(repository)+"/refs/branches/"+url.PathEscape(branchName), "", nil, result)
+	if apiError, ok := err.(*Error); ok && apiError.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to get branch %s: %v", branchName, err)
+	}
+	return result.Target.Hash, nil
+}
+
+func (c *Client) deleteBranch(ctx context.Context, repository, branchName string) error {
+	if err := c.do(ctx, http.MethodDelete, repositoryPath(repository)+"/refs/branches/"+url.PathEscape(branchName), "", nil, nil); err != nil {
+		return fmt.Errorf("unable to delete branch %s: %v", branchName, err)
+	}
+	return nil
+}
+
+// getFilePaths returns the paths of the files of the repository at the commit, following the pages of the listing
+func (c *Client) getFilePaths(ctx context.Context, repository, sha string) ([]string, error) {
+	var paths []string
+	query := url.Values{"max_depth": {"100"}, "pagelen": {"100"}}
+	for next := repositoryPath(repository) + "/src/" + url.PathEscape(sha) + "/?" + query.Encode(); next != ""; {
+		page := &srcPage{}
+		if err := c.do(ctx, http.MethodGet, next, "", nil, page); err != nil {
+			return nil, fmt.Errorf("unable to list files: %v", err)
+		}
+		for _, value := range page.Values {
+			if value.Type == "commit_file" {
+				paths = append(paths, value.Path)
+			}
+		}
+		next = page.Next
+	}
+	return paths, nil
+}
+
+// getFile returns the content of the file at the commit
+func (c *Client) getFile(ctx context.Context, repository, filePath, sha string) (string, error) {
+	var content string
+	err := c.do(ctx, http.MethodGet, repositoryPath(repository)+"/src/"+url.PathEscape(sha)+"/"+(&url.URL{Path: filePath}).EscapedPath(), "", nil, &content)
+	return content, err
+}
+
+// commit commits the files to the branch, with parent as its parent. The branch is created if it does not exist, and
+// otherwise parent must be the head of the branch.
+func (c *Client) commit(ctx context.Context, repository, branchName, parent, message string, files map[string]string) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fields := [][2]string{{"message", message}, {"branch", branchName}, {"parents", parent}}
+	for _, field := range fields {
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	for _, filePath := range sortedPaths(files) {
+		// the files are fields named by their path
+		if err := writer.WriteField(filePath, files[filePath]); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if err := c.do(ctx, http.MethodPost, repositoryPath(repository)+"/src", writer.FormDataContentType(), &body, nil); err != nil {
+		return fmt.Errorf("unable to commit to branch %s: %v", branchName, err)
+	}
+	return nil
+}
+
+// findPullRequest returns the open pull request from the source branch to the destination branch, or nil if there is none
+func (c *Client) findPullRequest(ctx context.Context, repository, sourceBranch, destinationBranch string) (*PullRequest, error) {
+	query := url.Values{"state": {"OPEN"}, "q": {fmt.Sprintf("source.branch.name=%q AND destination.branch.name=%q", sourceBranch, destinationBranch)}}
+	page := &pullRequestPage{}
+	if err := c.do(ctx, http.MethodGet, repositoryPath(repository)+"/pullrequests?"+query.Encode(), "", nil, page); err != nil {
+		return nil, fmt.Errorf("unable to list pull requests: %v", err)
+	}
+	if len(page.Values) == 0 {
+		return nil, nil
+	}
+	return &page.Values[0], nil
+}
+
+func (c *Client) createPullRequest(ctx context.Context, repository, sourceBranch, destinationBranch, title, description string) (*PullRequest, error) {
+	body := map[string]interface{}{
+		"title":               title,
+		"description":         description,
+		"source":              map[string]interface{}{"branch": map[string]string{"name": sourceBranch}},
+		"destination":         map[string]interface{}{"branch": map[string]string{"name": destinationBranch}},
+		"close_source_branch": true,
+	}
+	result := &PullRequest{}
+	if err := c.doJSON(ctx, http.MethodPost, repositoryPath(repository)+"/pullrequests", body, result); err != nil {
+		return nil, fmt.Errorf("unable to create pull request: %v", err)
+	}
+	return result, nil
+}
+
+func (c *Client) updatePullRequest(ctx context.Context, repository string, id int, title, description string) (*PullRequest, error) {
+	result := &PullRequest{}
+	body := map[string]string{"title": title, "description": description}
+	if err := c.doJSON(ctx, http.MethodPut, fmt.Sprintf("%s/pullrequests/%d", repositoryPath(repository), id), body, result); err != nil {
+		return nil, fmt.Errorf("unable to update pull request: %v", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,141 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/githubapp"
+	"github.com/step-security/secure-repo/remediation/notify"
+	"github.com/step-security/secure-repo/remediation/prbody"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+)
+
+const (
+	pullRequestTitle = "[StepSecurity] Apply security best practices"
+	commitMessage    = "[StepSecurity] Apply security best practices"
+)
+
+// RepositoryResult is the outcome of remediating a Bitbucket repository
+type RepositoryResult struct {
+	Repository     string
+	IsChanged      bool
+	PullRequestURL string `json:",omitempty"`
+	Error          string `json:",omitempty"`
+}
+
+func sortedPaths(files map[string]string) []string {
+	paths := make([]string, 0, len(files))
+	for filePath := range files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// getFiles returns the content of the files of the repository at the commit that may have remediations, along with the
+// configuration of the repository
+func getFiles(ctx context.Context, client *Client, repository, sha string) (map[string]string, error) {
+	paths, err := client.getFilePaths(ctx, repository, sha)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string)
+	for _, filePath := range paths {
+		if !securerepo.ShouldFetch(filePath) {
+			continue
+		}
+		content, err := client.getFile(ctx, repository, filePath, sha)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get %s: %v", filePath, err)
+		}
+		files[filePath] = content
+	}
+	return files, nil
+}
+
+// createOrUpdatePullRequest commits the files to the remediation branch, and opens a pull request from it with the
+// description if there is no open one. Bitbucket cannot force update a branch, so without an open pull request the branch is recreated from the base
+// commit, and with one the files are committed on top of the branch, which would otherwise close the pull request.
+func createOrUpdatePullRequest(ctx context.Context, client *Client, repository, baseBranch, baseSHA string, files map[string]string, description string) (string, error) {
+	pullRequest, err := client.findPullRequest(ctx, repository, githubapp.RemediationBranch, baseBranch)
+	if err != nil {
+		return "", err
+	}
+	branchSHA, err := client.getBranchCommit(ctx, repository, githubapp.RemediationBranch)
+	if err != nil {
+		return "", err
+	}
+	parent := baseSHA
+	if pullRequest != nil && branchSHA != "" {
+		parent = branchSHA
+	} else if branchSHA != "" {
+		if err := client.deleteBranch(ctx, repository, githubapp.RemediationBranch); err != nil {
+			return "", err
+		}
+	}
+	if err := client.commit(ctx, repository, githubapp.RemediationBranch, parent, commitMessage, files); err != nil {
+		return "", err
+	}
+
+	if pullRequest != nil {
+		// the files changed by the remediation may be different from when the pull request was opened
+		pullRequest, err = client.updatePullRequest(ctx, repository, pullRequest.ID, pullRequestTitle, description)
+	} else {
+		pullRequest, err = client.createPullRequest(ctx, repository, githubapp.RemediationBranch, baseBranch, pullRequestTitle, description)
+	}
+	if err != nil {
+		return "", err
+	}
+	return pullRequest.Links.HTML.Href, nil
+}
+
+// RemediateRepository runs the remediations on the files of the repository at the head of its main branch, and opens or
+// updates the remediation pull request with the changes, the same way the GitHub App does. The repository is the
+// workspace and the slug of the repository, e.g. workspace/app.
+func RemediateRepository(ctx context.Context, client *Client, repository string, svc dynamodbiface.DynamoDBAPI) RepositoryResult {
+	result := RepositoryResult{Repository: repository}
+	fail := func(err error) RepositoryResult {
+		result.Error = err.Error()
+		return result
+	}
+
+	bitbucketRepository, err := client.GetRepository(ctx, repository)
+	if err != nil {
+		return fail(err)
+	}
+	mainBranch := bitbucketRepository.MainBranch.Name
+	sha, err := client.getBranchCommit(ctx, repository, mainBranch)
+	if err != nil {
+		return fail(err)
+	}
+	if sha == "" {
+		return fail(fmt.Errorf("branch %s not found", mainBranch))
+	}
+
+	files, err := getFiles(ctx, client, repository, sha)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Dependabot does not run on Bitbucket, so its configuration is not added
+	queryStringParams := map[string]string{"updateDependabotConfig": "false"}
+	response, err := securerepo.SecureRepo(ctx, queryStringParams, securerepo.SecureRepoRequest{Files: files}, svc)
+	if err != nil {
+		return fail(err)
+	}
+	if !response.IsChanged {
+		return result
+	}
+	result.IsChanged = true
+
+	description := prbody.Describe(prbody.KindPullRequest, repository, response)
+	result.PullRequestURL, err = createOrUpdatePullRequest(ctx, client, repository, mainBranch, sha, response.Files, description)
+	if err != nil {
+		return fail(err)
+	}
+	notify.Notify(&notify.Notification{Event: notify.EventApplied, Repository: repository, PullRequestURL: result.PullRequestURL,
+		Report: response.Report})
+	return result
+}
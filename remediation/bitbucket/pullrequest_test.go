@@ -0,0 +1,99 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/step-security/secure-repo/remediation/docker"
+)
+
+func TestRemediateRepository(t *testing.T) {
+	t.Setenv(TokenEnv, "env-token")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	saveTr := docker.Tr
+	defer func() { docker.Tr = saveTr }()
+	docker.Tr = httpmock.DefaultTransport
+	httpmock.RegisterResponder("GET", "https://index.docker.io/v2/", httpmock.NewStringResponder(http.StatusOK, `{}`))
+	httpmock.RegisterResponder("GET", "https://index.docker.io/v2/library/node/manifests/20",
+		httpmock.NewStringResponder(http.StatusOK, httpmock.File("../../testfiles/dockerfiles/response.json").String()))
+
+	const api = "https://api.bitbucket.org/2.0/repositories/workspace/app"
+	var authorizations []string
+	respond := func(status int, body string) httpmock.Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			authorizations = append(authorizations, req.Header.Get("Authorization"))
+			return httpmock.NewStringResponse(status, body), nil
+		}
+	}
+	httpmock.RegisterResponder("GET", api, respond(http.StatusOK, `{"full_name": "workspace/app", "mainbranch": {"name": "main"}}`))
+	httpmock.RegisterResponder("GET", api+"/refs/branches/main", respond(http.StatusOK, `{"name": "main", "target": {"hash": "base-sha"}}`))
+	httpmock.RegisterResponder("GET", api+"/src/base-sha/?max_depth=100&pagelen=100",
+		respond(http.StatusOK, `{"values": [{"path": "README.md", "type": "commit_file"}, {"path": "build", "type": "commit_directory"}],
+			"next": "https://api.bitbucket.org/2.0/repositories/workspace/app/src/base-sha/?max_depth=100&page=2&pagelen=100"}`))
+	httpmock.RegisterResponder("GET", api+"/src/base-sha/?max_depth=100&page=2&pagelen=100",
+		respond(http.StatusOK, `{"values": [{"path": "build/Dockerfile", "type": "commit_file"}]}`))
+	httpmock.RegisterResponder("GET", api+"/src/base-sha/build/Dockerfile", respond(http.StatusOK, "FROM node:20\nRUN npm ci\n"))
+	httpmock.RegisterResponder("GET", api+`/pullrequests?q=source.branch.name%3D%22stepsecurity%2Fremediation%22+AND+destination.branch.name%3D%22main%22&state=OPEN`,
+		respond(http.StatusOK, `{"values": []}`))
+	httpmock.RegisterResponder("GET", api+"/refs/branches/stepsecurity%2Fremediation",
+		respond(http.StatusNotFound, `{"type": "error", "error": {"message": "Branch not found"}}`))
+
+	var commit map[string]string
+	httpmock.RegisterResponder("POST", api+"/src", func(req *http.Request) (*http.Response, error) {
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			return nil, err
+		}
+		commit = map[string]string{}
+		for key, values := range req.MultipartForm.Value {
+			commit[key] = values[0]
+		}
+		return httpmock.NewStringResponse(http.StatusCreated, ""), nil
+	})
+	var pullRequest map[string]interface{}
+	httpmock.RegisterResponder("POST", api+"/pullrequests", func(req *http.Request) (*http.Response, error) {
+		if err := json.NewDecoder(req.Body).Decode(&pullRequest); err != nil {
+			return nil, err
+		}
+		return httpmock.NewStringResponse(http.StatusCreated,
+			`{"id": 5, "links": {"html": {"href": "https://bitbucket.org/workspace/app/pull-requests/5"}}}`), nil
+	})
+
+	result := RemediateRepository(context.Background(), NewClient(""), "workspace/app", nil)
+	if result.Error != "" {
+		t.Fatalf("RemediateRepository() returned error: %s", result.Error)
+	}
+	if !result.IsChanged || result.PullRequestURL != "https://bitbucket.org/workspace/app/pull-requests/5" {
+		t.Errorf("expected a pull request to be opened, got %+v", result)
+	}
+	if commit["branch"] != "stepsecurity/remediation" || commit["parents"] != "base-sha" ||
+		!strings.HasPrefix(commit["build/Dockerfile"], "FROM node:20@sha256:") {
+		t.Errorf("expected the pinned Dockerfile to be committed to the remediation branch, got %v", commit)
+	}
+	destination, _ := json.Marshal(pullRequest["destination"])
+	if string(destination) != `{"branch":{"name":"main"}}` || !strings.Contains(pullRequest["description"].(string), "`build/Dockerfile`") {
+		t.Errorf("unexpected pull request %v", pullRequest)
+	}
+	for _, authorization := range authorizations {
+		if authorization != "Bearer env-token" {
+			t.Errorf("expected requests to be authenticated with the token, got %q", authorization)
+		}
+	}
+
+	// with an open pull request, the files are committed on top of the remediation branch
+	httpmock.RegisterResponder("GET", api+`/pullrequests?q=source.branch.name%3D%22stepsecurity%2Fremediation%22+AND+destination.branch.name%3D%22main%22&state=OPEN`,
+		respond(http.StatusOK, `{"values": [{"id": 5}]}`))
+	httpmock.RegisterResponder("GET", api+"/refs/branches/stepsecurity%2Fremediation",
+		respond(http.StatusOK, `{"name": "stepsecurity/remediation", "target": {"hash": "remediation-sha"}}`))
+	httpmock.RegisterResponder("PUT", api+"/pullrequests/5",
+		respond(http.StatusOK, `{"id": 5, "links": {"html": {"href": "https://bitbucket.org/workspace/app/pull-requests/5"}}}`))
+	result = RemediateRepository(context.Background(), NewClient(""), "workspace/app", nil)
+	if result.Error != "" || result.PullRequestURL != "https://bitbucket.org/workspace/app/pull-requests/5" || commit["parents"] != "remediation-sha" {
+		t.Errorf("expected the pull request to be updated, got %+v and commit %v", result, commit)
+	}
+}
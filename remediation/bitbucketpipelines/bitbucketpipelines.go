@@ -0,0 +1,248 @@
+package bitbucketpipelines
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigPath is the path of the configuration of Bitbucket Pipelines
+const ConfigPath = "bitbucket-pipelines.yml"
+
+const (
+	RuleUnpinnedPipe           = "bitbucket-unpinned-pipe"
+	RuleUnrestrictedDeployment = "bitbucket-unrestricted-deployment"
+
+	// atlassianPipePrefix is the prefix of the pipes of Atlassian, whose images are published as bitbucketpipelines/<name>
+	atlassianPipePrefix = "atlassian/"
+	dockerPipePrefix    = "docker://"
+)
+
+// SecurePipelineResponse is the result of the remediations of a Bitbucket Pipelines configuration
+type SecurePipelineResponse struct {
+	OriginalInput string
+	FinalOutput   string
+	IsChanged     bool
+	PinnedPipes   bool
+	PinnedImages  bool
+	Findings      []findings.Finding
+}
+
+// step is a step of a pipeline, with whether the pipeline runs for every branch or pull request
+type step struct {
+	node      *yaml.Node
+	pipeline  string
+	unguarded bool
+}
+
+// getSteps returns the steps of the items of a pipeline, which are steps, parallel steps or the steps of stages
+func getSteps(items *yaml.Node, pipeline string, unguarded bool) []step {
+	if items == nil || items.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var steps []step
+	for _, item := range items.Content {
+		if stepNode := document.MappingValue(item, "step"); stepNode != nil {
+			// steps are often defined once with an anchor in definitions, and used with an alias
+			if stepNode.Kind == yaml.AliasNode {
+				stepNode = stepNode.Alias
+			}
+			steps = append(steps, step{node: stepNode, pipeline: pipeline, unguarded: unguarded})
+		}
+		// parallel is a list of steps, or a mapping with the list in steps
+		parallel := document.MappingValue(item, "parallel")
+		if parallel != nil && parallel.Kind == yaml.MappingNode {
+			parallel = document.MappingValue(parallel, "steps")
+		}
+		steps = append(steps, getSteps(parallel, pipeline, unguarded)...)
+		steps = append(steps, getSteps(document.MappingValue(document.MappingValue(item, "stage"), "steps"), pipeline, unguarded)...)
+	}
+	return steps
+}
+
+// getAllSteps returns the steps of all pipelines. The default pipeline, the pipelines of pull requests, and those of
+// branch patterns with a wildcard are run for branches anyone with write access can push.
+func getAllSteps(topNode *yaml.Node) []step {
+	pipelines := document.MappingValue(topNode, "pipelines")
+	if pipelines == nil || pipelines.Kind != yaml.MappingNode {
+		return nil
+	}
+	var steps []step
+	for i := 0; i+1 < len(pipelines.Content); i += 2 {
+		kind, value := pipelines.Content[i].Value, pipelines.Content[i+1]
+		if kind == "default" {
+			steps = append(steps, getSteps(value, "default", true)...)
+			continue
+		}
+		if value.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			pattern := value.Content[j].Value
+			unguarded := kind == "pull-requests" || (kind == "branches" && strings.Contains(pattern, "*"))
+			steps = append(steps, getSteps(value.Content[j+1], kind+" "+pattern, unguarded)...)
+		}
+	}
+	return steps
+}
+
+// getImage returns the scalar node with the name of an image, which is either the value of image, or its name
+func getImage(node *yaml.Node) *yaml.Node {
+	if node != nil && node.Kind == yaml.MappingNode {
+		node = document.MappingValue(node, "name")
+	}
+	if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" || strings.Contains(node.Value, "@") ||
+		strings.Contains(node.Value, "$") {
+		return nil
+	}
+	return node
+}
+
+// findImageEdits returns the edits that pin the default image, the images of the steps, and the images of the
+// services to their digest
+func findImageEdits(ctx context.Context, topNode *yaml.Node, steps []step) ([]textedit.Replacement, error) {
+	images := []*yaml.Node{getImage(document.MappingValue(topNode, "image"))}
+	services := document.MappingValue(document.MappingValue(topNode, "definitions"), "services")
+	if services != nil && services.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(services.Content); i += 2 {
+			images = append(images, getImage(document.MappingValue(services.Content[i+1], "image")))
+		}
+	}
+	for _, s := range steps {
+		images = append(images, getImage(document.MappingValue(s.node, "image")))
+	}
+
+	var edits []textedit.Replacement
+	seen := map[*yaml.Node]bool{}
+	for _, image := range images {
+		if image == nil || seen[image] {
+			continue
+		}
+		seen[image] = true
+		pinned, err := docker.PinImage(ctx, image.Value)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, textedit.Replacement{Line: image.Line, Column: image.Column, Old: image.Value, New: pinned})
+	}
+	return edits, nil
+}
+
+// findPipeEdits returns the edits that pin the pipes of the steps to the digest of their image, and the findings of the
+// pipes that are not images, whose pipe.yml is read from their repository. Pipes of Atlassian are replaced by their
+// image, e.g. atlassian/aws-s3-deploy:1.1.0 by docker://bitbucketpipelines/aws-s3-deploy:1.1.0@sha256:...
+func findPipeEdits(ctx context.Context, steps []step) ([]textedit.Replacement, []findings.Finding, error) {
+	var edits []textedit.Replacement
+	var pipeFindings []findings.Finding
+	seen := map[*yaml.Node]bool{}
+	for _, s := range steps {
+		script := document.MappingValue(s.node, "script")
+		if script == nil || script.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, item := range script.Content {
+			pipe := document.MappingValue(item, "pipe")
+			if pipe == nil || pipe.Kind != yaml.ScalarNode || seen[pipe] || strings.Contains(pipe.Value, "@") ||
+				strings.Contains(pipe.Value, "$") {
+				continue
+			}
+			seen[pipe] = true
+			var image string
+			switch {
+			case strings.HasPrefix(pipe.Value, dockerPipePrefix):
+				image = strings.TrimPrefix(pipe.Value, dockerPipePrefix)
+			case strings.HasPrefix(pipe.Value, atlassianPipePrefix):
+				image = "bitbucketpipelines/" + strings.TrimPrefix(pipe.Value, atlassianPipePrefix)
+			default:
+				pipeFindings = append(pipeFindings, findings.Finding{
+					RuleID:     RuleUnpinnedPipe,
+					Message:    fmt.Sprintf("Pipe %s is referenced by its tag, which can be moved to another version", pipe.Value),
+					Action:     pipe.Value,
+					Line:       pipe.Line,
+					Column:     pipe.Column,
+					Suggestion: "Reference the image of the pipe with docker:// and its digest",
+				})
+				continue
+			}
+			pinned, err := docker.PinImage(ctx, image)
+			if err != nil {
+				return nil, nil, err
+			}
+			edits = append(edits, textedit.Replacement{Line: pipe.Line, Column: pipe.Column, Old: pipe.Value, New: dockerPipePrefix + pinned})
+		}
+	}
+	return edits, pipeFindings, nil
+}
+
+// findUnrestrictedDeployments returns findings for the deployment steps of the pipelines that run for every branch or
+// pull request, so any branch that is pushed can use the variables of the deployment
+func findUnrestrictedDeployments(steps []step) []findings.Finding {
+	var deploymentFindings []findings.Finding
+	for _, s := range steps {
+		deployment := document.MappingValue(s.node, "deployment")
+		if deployment == nil || !s.unguarded {
+			continue
+		}
+		name := "step"
+		if nameNode := document.MappingValue(s.node, "name"); nameNode != nil {
+			name = nameNode.Value
+		}
+		deploymentFindings = append(deploymentFindings, findings.Finding{
+			RuleID:     RuleUnrestrictedDeployment,
+			Message:    fmt.Sprintf("Step %s deploys to %s in the %s pipeline, which runs for branches anyone with write access can push", name, deployment.Value, s.pipeline),
+			JobName:    name,
+			Line:       deployment.Line,
+			Column:     deployment.Column,
+			Suggestion: "Move the step to the pipeline of the main branch, and restrict the deployment to it in the deployment settings",
+		})
+	}
+	return deploymentFindings
+}
+
+// SecurePipeline runs the remediations for a Bitbucket Pipelines configuration. Pipes are pinned to the digest of their
+// image unless pinPipes is false, and the images of the pipeline, its steps and its services unless pinImages is false.
+// Pipes that cannot be pinned and deployment steps that run for every branch are reported as findings.
+func SecurePipeline(ctx context.Context, queryStringParams map[string]string, inputYaml string) (*SecurePipelineResponse, error) {
+	response := &SecurePipelineResponse{OriginalInput: inputYaml, FinalOutput: inputYaml}
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(inputYaml), &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return response, nil
+	}
+	topNode := doc.Content[0]
+	steps := getAllSteps(topNode)
+
+	var edits []textedit.Replacement
+	if queryStringParams["pinPipes"] != "false" {
+		pipeEdits, pipeFindings, err := findPipeEdits(ctx, steps)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, pipeEdits...)
+		response.PinnedPipes = len(pipeEdits) > 0
+		response.Findings = append(response.Findings, pipeFindings...)
+	}
+	if queryStringParams["pinImages"] != "false" {
+		imageEdits, err := findImageEdits(ctx, topNode, steps)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, imageEdits...)
+		response.PinnedImages = len(imageEdits) > 0
+	}
+	response.Findings = append(response.Findings, findUnrestrictedDeployments(steps)...)
+
+	if len(edits) > 0 {
+		response.FinalOutput = textedit.ApplyReplacements(inputYaml, edits)
+		response.IsChanged = true
+	}
+	return response, nil
+}
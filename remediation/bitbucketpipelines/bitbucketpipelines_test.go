@@ -0,0 +1,105 @@
+package bitbucketpipelines
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/internal/testutil"
+)
+
+func TestSecurePipeline(t *testing.T) {
+	digest := testutil.MockRegistry(t, "library/node/manifests/20", "library/postgres/manifests/15", "library/python/manifests/3.12",
+		"bitbucketpipelines/aws-s3-deploy/manifests/1.1.0", "acme/notify/manifests/2.0")
+
+	input := `image: node:20
+definitions:
+  services:
+    postgres:
+      image: postgres:15
+  steps:
+    - step: &test
+        name: Test
+        image: python:3.12
+        script:
+          - pytest
+pipelines:
+  default:
+    - step: *test
+    - step:
+        name: Deploy preview
+        deployment: staging
+        script:
+          - pipe: atlassian/aws-s3-deploy:1.1.0
+            variables:
+              S3_BUCKET: preview
+  branches:
+    main:
+      - step: *test
+      - step:
+          name: Deploy
+          deployment: production
+          script:
+            - pipe: docker://acme/notify:2.0
+            - pipe: acme/custom-pipe:1.0.0
+  pull-requests:
+    '**':
+      - parallel:
+          - step: *test
+`
+	want := `image: node:20@` + digest + `
+definitions:
+  services:
+    postgres:
+      image: postgres:15@` + digest + `
+  steps:
+    - step: &test
+        name: Test
+        image: python:3.12@` + digest + `
+        script:
+          - pytest
+pipelines:
+  default:
+    - step: *test
+    - step:
+        name: Deploy preview
+        deployment: staging
+        script:
+          - pipe: docker://bitbucketpipelines/aws-s3-deploy:1.1.0@` + digest + `
+            variables:
+              S3_BUCKET: preview
+  branches:
+    main:
+      - step: *test
+      - step:
+          name: Deploy
+          deployment: production
+          script:
+            - pipe: docker://acme/notify:2.0@` + digest + `
+            - pipe: acme/custom-pipe:1.0.0
+  pull-requests:
+    '**':
+      - parallel:
+          - step: *test
+`
+	response, err := SecurePipeline(context.Background(), map[string]string{}, input)
+	if err != nil {
+		t.Fatalf("SecurePipeline() returned error: %v", err)
+	}
+	if !response.IsChanged || !response.PinnedPipes || !response.PinnedImages || response.FinalOutput != want {
+		t.Errorf("SecurePipeline() = %+v,\n%s\nwant\n%s", response, response.FinalOutput, want)
+	}
+	var rules []string
+	for _, finding := range response.Findings {
+		rules = append(rules, fmt.Sprintf("%s:%d:%s", finding.RuleID, finding.Line, finding.JobName))
+	}
+	// the deployment of the main branch is not reported
+	if fmt.Sprint(rules) != "[bitbucket-unpinned-pipe:30: bitbucket-unrestricted-deployment:17:Deploy preview]" {
+		t.Errorf("findings = %v, want the pipe of a repository and the deployment of the default pipeline", rules)
+	}
+
+	response, err = SecurePipeline(context.Background(), map[string]string{}, want)
+	if err != nil || response.IsChanged || response.FinalOutput != want {
+		t.Errorf("expected pinned pipeline to be unchanged, got %+v, %v", response, err)
+	}
+}
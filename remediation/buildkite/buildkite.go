@@ -0,0 +1,253 @@
+package buildkite
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleUnpinnedPlugin = "buildkite-unpinned-plugin"
+	RuleUnpinnedImage  = "buildkite-unpinned-image"
+
+	// pluginSuffix is appended to the name of the repository of a plugin that is referenced by its short name
+	pluginSuffix = "-buildkite-plugin"
+	// pluginsOrg is the organization of the plugins that are referenced without one, e.g. docker#v5.10.0
+	pluginsOrg = "buildkite-plugins"
+)
+
+var (
+	shaRegex        = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	githubRepoRegex = regexp.MustCompile(`^(?:https://|ssh://git@|git@)?github\.com[/:]([\w.-]+)/([\w.-]+?)(?:\.git)?$`)
+)
+
+// ResolveRef returns the commit of a tag or branch of a GitHub repository, which is resolved and cached like the refs
+// of actions
+var ResolveRef = pin.ResolveRef
+
+// SecurePipelineResponse is the result of the remediations of a Buildkite pipeline
+type SecurePipelineResponse struct {
+	OriginalInput string
+	FinalOutput   string
+	IsChanged     bool
+	PinnedPlugins bool
+	PinnedImages  bool
+	Findings      []findings.Finding
+}
+
+// IsPipeline returns true for the pipelines Buildkite uploads from a repository, which are in the .buildkite directory
+// or named buildkite.yml
+func IsPipeline(filePath string) bool {
+	name := path.Base(filePath)
+	if !strings.HasSuffix(name, ".yml") && !strings.HasSuffix(name, ".yaml") {
+		return false
+	}
+	return strings.HasPrefix(filePath, ".buildkite/") || filePath == "buildkite.yml" || filePath == "buildkite.yaml"
+}
+
+// plugin is a plugin of a step, with the node of its reference and its configuration
+type plugin struct {
+	ref    *yaml.Node
+	config *yaml.Node
+	step   string
+}
+
+// getPlugins returns the plugins of the command steps, including the steps of groups. Plugins are a sequence of
+// references, each with its configuration, or a mapping of references to their configuration.
+func getPlugins(steps *yaml.Node) []plugin {
+	if steps == nil || steps.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var plugins []plugin
+	for _, stepNode := range steps.Content {
+		if group := document.MappingValue(stepNode, "steps"); group != nil {
+			plugins = append(plugins, getPlugins(group)...)
+			continue
+		}
+		name := ""
+		for _, key := range []string{"label", "key", "name"} {
+			if node := document.MappingValue(stepNode, key); node != nil {
+				name = node.Value
+				break
+			}
+		}
+		pluginsNode := document.MappingValue(stepNode, "plugins")
+		if pluginsNode == nil {
+			continue
+		}
+		var entries []*yaml.Node
+		if pluginsNode.Kind == yaml.MappingNode {
+			entries = []*yaml.Node{pluginsNode}
+		} else if pluginsNode.Kind == yaml.SequenceNode {
+			entries = pluginsNode.Content
+		}
+		for _, entry := range entries {
+			switch entry.Kind {
+			case yaml.ScalarNode:
+				plugins = append(plugins, plugin{ref: entry, step: name})
+			case yaml.MappingNode:
+				for i := 0; i+1 < len(entry.Content); i += 2 {
+					plugins = append(plugins, plugin{ref: entry.Content[i], config: entry.Content[i+1], step: name})
+				}
+			}
+		}
+	}
+	return plugins
+}
+
+// getPluginRepo returns the GitHub repository of a plugin reference without its version, e.g.
+// buildkite-plugins/docker-buildkite-plugin for docker, or false if the plugin is not on GitHub
+func getPluginRepo(name string) (string, string, bool) {
+	if match := githubRepoRegex.FindStringSubmatch(name); match != nil {
+		return match[1], match[2], true
+	}
+	if strings.Contains(name, ":") || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "/") {
+		return "", "", false
+	}
+	parts := strings.Split(name, "/")
+	switch len(parts) {
+	case 1:
+		return pluginsOrg, parts[0] + pluginSuffix, true
+	case 2:
+		repo := parts[1]
+		if !strings.HasSuffix(repo, pluginSuffix) {
+			repo += pluginSuffix
+		}
+		return parts[0], repo, true
+	}
+	return "", "", false
+}
+
+// isDockerPlugin returns true for the docker plugin of Buildkite, which runs the commands of the step in its image
+func isDockerPlugin(name string) bool {
+	owner, repo, found := getPluginRepo(name)
+	return found && owner == pluginsOrg && repo == "docker"+pluginSuffix
+}
+
+// findPluginEdits returns the edits that pin the plugins on GitHub to the SHA of the commit of their version, with the
+// version in a comment, and the findings of the plugins that are not on GitHub or have no version
+func findPluginEdits(ctx context.Context, plugins []plugin) ([]textedit.Replacement, []findings.Finding, error) {
+	var edits []textedit.Replacement
+	var pluginFindings []findings.Finding
+	for _, p := range plugins {
+		name, version, _ := strings.Cut(p.ref.Value, "#")
+		if shaRegex.MatchString(version) || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "/") ||
+			strings.HasPrefix(name, "file://") {
+			// local plugins are checked out with the repository
+			continue
+		}
+		owner, repo, onGitHub := getPluginRepo(name)
+		if !onGitHub || version == "" {
+			message := fmt.Sprintf("Plugin %s of step %s is not on GitHub, so it cannot be pinned to a commit", p.ref.Value, p.step)
+			if version == "" {
+				message = fmt.Sprintf("Plugin %s of step %s has no version, so the latest commit of its default branch is run", p.ref.Value, p.step)
+			}
+			pluginFindings = append(pluginFindings, findings.Finding{
+				RuleID:     RuleUnpinnedPlugin,
+				Message:    message,
+				JobName:    p.step,
+				Action:     p.ref.Value,
+				Line:       p.ref.Line,
+				Column:     p.ref.Column,
+				Suggestion: "Reference the plugin with the SHA of the commit of its version, e.g. docker#<sha>",
+			})
+			continue
+		}
+		resolved, err := ResolveRef(ctx, owner, repo, version)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to get commit of plugin %s: %v", p.ref.Value, err)
+		}
+		edits = append(edits, textedit.Replacement{Line: p.ref.Line, Column: p.ref.Column, Old: p.ref.Value, New: name + "#" + resolved.CommitSHA, Comment: resolved.Version})
+	}
+	return edits, pluginFindings, nil
+}
+
+// findImageEdits returns the edits that pin the images of the docker plugin to their digest, and the findings of the
+// images set with variables
+func findImageEdits(ctx context.Context, plugins []plugin) ([]textedit.Replacement, []findings.Finding, error) {
+	var edits []textedit.Replacement
+	var imageFindings []findings.Finding
+	for _, p := range plugins {
+		name, _, _ := strings.Cut(p.ref.Value, "#")
+		if !isDockerPlugin(name) {
+			continue
+		}
+		image := document.MappingValue(p.config, "image")
+		if image == nil || image.Kind != yaml.ScalarNode || image.Value == "" || strings.Contains(image.Value, "@") {
+			continue
+		}
+		if strings.Contains(image.Value, "$") {
+			imageFindings = append(imageFindings, findings.Finding{
+				RuleID:     RuleUnpinnedImage,
+				Message:    fmt.Sprintf("Image %s of step %s is set with a variable, so it cannot be pinned to a digest", image.Value, p.step),
+				JobName:    p.step,
+				Action:     image.Value,
+				Line:       image.Line,
+				Column:     image.Column,
+				Suggestion: "Set the image with its digest, e.g. node:20@sha256:...",
+			})
+			continue
+		}
+		pinned, err := docker.PinImage(ctx, image.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		edits = append(edits, textedit.Replacement{Line: image.Line, Column: image.Column, Old: image.Value, New: pinned})
+	}
+	return edits, imageFindings, nil
+}
+
+// SecurePipeline runs the remediations for a Buildkite pipeline. Plugins are pinned to the SHA of the commit of their
+// version unless pinPlugins is false, and the images of the docker plugin to their digest unless pinImages is false.
+// Plugins that are not on GitHub or have no version, and images set with variables, are reported as findings.
+func SecurePipeline(ctx context.Context, queryStringParams map[string]string, inputYaml string) (*SecurePipelineResponse, error) {
+	response := &SecurePipelineResponse{OriginalInput: inputYaml, FinalOutput: inputYaml}
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(inputYaml), &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(doc.Content) == 0 {
+		return response, nil
+	}
+	// the steps are the top node of pipelines in the legacy format
+	steps := doc.Content[0]
+	if steps.Kind == yaml.MappingNode {
+		steps = document.MappingValue(steps, "steps")
+	}
+	plugins := getPlugins(steps)
+
+	var edits []textedit.Replacement
+	if queryStringParams["pinPlugins"] != "false" {
+		pluginEdits, pluginFindings, err := findPluginEdits(ctx, plugins)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, pluginEdits...)
+		response.PinnedPlugins = len(pluginEdits) > 0
+		response.Findings = append(response.Findings, pluginFindings...)
+	}
+	if queryStringParams["pinImages"] != "false" {
+		imageEdits, imageFindings, err := findImageEdits(ctx, plugins)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, imageEdits...)
+		response.PinnedImages = len(imageEdits) > 0
+		response.Findings = append(response.Findings, imageFindings...)
+	}
+
+	if len(edits) > 0 {
+		response.FinalOutput = textedit.ApplyReplacements(inputYaml, edits)
+		response.IsChanged = true
+	}
+	return response, nil
+}
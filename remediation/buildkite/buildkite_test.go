@@ -0,0 +1,113 @@
+package buildkite
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/internal/testutil"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+)
+
+// mockResolveRef resolves the refs of the repositories to the commits, with the version of the ref as the most
+// specific tag
+func mockResolveRef(t *testing.T, commits map[string]string) {
+	saveResolveRef := ResolveRef
+	ResolveRef = func(_ context.Context, owner, repo, tagOrBranch string) (*pin.ResolvedRef, error) {
+		commit, found := commits[owner+"/"+repo+"#"+tagOrBranch]
+		if !found {
+			return nil, fmt.Errorf("unexpected ref %s/%s#%s", owner, repo, tagOrBranch)
+		}
+		return &pin.ResolvedRef{CommitSHA: commit, Version: tagOrBranch + ".0"}, nil
+	}
+	t.Cleanup(func() { ResolveRef = saveResolveRef })
+}
+
+func TestIsPipeline(t *testing.T) {
+	for filePath, want := range map[string]bool{
+		".buildkite/pipeline.yml":        true,
+		".buildkite/deploy/release.yaml": true,
+		"buildkite.yml":                  true,
+		".buildkite/hooks/pre-command":   false,
+		"pipeline.yml":                   false,
+	} {
+		if got := IsPipeline(filePath); got != want {
+			t.Errorf("IsPipeline(%s) = %v, want %v", filePath, got, want)
+		}
+	}
+}
+
+func TestSecurePipeline(t *testing.T) {
+	digest := testutil.MockRegistry(t, "library/node/manifests/20")
+	dockerSHA := "9a0f3a3a6c9f2b0d1bd3b0bd1c9d4fbc3d1b1e6a"
+	ecrSHA := "1c2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e"
+	cacheSHA := "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c"
+	mockResolveRef(t, map[string]string{
+		"buildkite-plugins/docker-buildkite-plugin#v5.10":  dockerSHA,
+		"buildkite-plugins/ecr-buildkite-plugin#v2.7":      ecrSHA,
+		"acme/cache-buildkite-plugin#v1":                   cacheSHA,
+		"buildkite-plugins/docker-buildkite-plugin#v5.9.0": dockerSHA,
+	})
+
+	input := `steps:
+  - label: ":nodejs: test"
+    command: npm test
+    plugins:
+      - ecr#v2.7
+      - docker#v5.10:
+          image: node:20
+      - acme/cache#v1:
+          path: node_modules
+  - group: deploy
+    steps:
+      - label: deploy
+        command: ./deploy.sh
+        plugins:
+          - docker#v5.9.0:
+              image: "${DEPLOY_IMAGE}"
+          - gitlab.com/acme/deploy-buildkite-plugin#v1
+          - seek-oss/aws-sm
+  - label: lint
+    plugins: [ecr#v2.7]
+`
+	want := `steps:
+  - label: ":nodejs: test"
+    command: npm test
+    plugins:
+      - ecr#` + ecrSHA + ` # v2.7.0
+      - docker#` + dockerSHA + `: # v5.10.0
+          image: node:20@` + digest + `
+      - acme/cache#` + cacheSHA + `: # v1.0
+          path: node_modules
+  - group: deploy
+    steps:
+      - label: deploy
+        command: ./deploy.sh
+        plugins:
+          - docker#` + dockerSHA + `: # v5.9.0.0
+              image: "${DEPLOY_IMAGE}"
+          - gitlab.com/acme/deploy-buildkite-plugin#v1
+          - seek-oss/aws-sm
+  - label: lint
+    plugins: [ecr#` + ecrSHA + `]
+`
+	response, err := SecurePipeline(context.Background(), map[string]string{}, input)
+	if err != nil {
+		t.Fatalf("SecurePipeline() returned error: %v", err)
+	}
+	if !response.IsChanged || !response.PinnedPlugins || !response.PinnedImages || response.FinalOutput != want {
+		t.Errorf("SecurePipeline() = %+v,\n%s\nwant\n%s", response, response.FinalOutput, want)
+	}
+	var got []string
+	for _, finding := range response.Findings {
+		got = append(got, fmt.Sprintf("%s:%d:%s:%s", finding.RuleID, finding.Line, finding.JobName, finding.Action))
+	}
+	if fmt.Sprint(got) != "[buildkite-unpinned-plugin:17:deploy:gitlab.com/acme/deploy-buildkite-plugin#v1 buildkite-unpinned-plugin:18:deploy:seek-oss/aws-sm buildkite-unpinned-image:16:deploy:${DEPLOY_IMAGE}]" {
+		t.Errorf("findings = %v, want the plugin not on GitHub, the plugin without a version and the image set with a variable", got)
+	}
+
+	response, err = SecurePipeline(context.Background(), map[string]string{}, want)
+	if err != nil || response.IsChanged || response.FinalOutput != want {
+		t.Errorf("expected pinned pipeline to be unchanged, got %+v, %v", response, err)
+	}
+}
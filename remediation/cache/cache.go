@@ -0,0 +1,239 @@
+// Package cache caches the responses of the remediations, keyed by a hash of the input, the options and the version of
+// the knowledge base. Workflows created from the same template are common across the repositories of an organization,
+// so they are remediated once, without looking up the commits of their actions again.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/step-security/secure-repo/remediation/awssession"
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/metrics"
+	"github.com/step-security/secure-repo/remediation/storage"
+	"github.com/step-security/secure-repo/remediation/workflow/metadata"
+)
+
+const (
+	// SizeEnv is the number of responses cached in memory by each instance. The cache is disabled if neither the size,
+	// Redis, the table nor the storage are set.
+	SizeEnv = "RESPONSE_CACHE_SIZE"
+	// TTLEnv is how long responses are cached, e.g. 30m, which is DefaultTTL if it is not set. The commits of the tags
+	// of actions are cached as long, so a tag that is moved is pinned to its new commit after the TTL.
+	TTLEnv = "RESPONSE_CACHE_TTL"
+	// TableEnv is the DynamoDB table the responses are cached in, which is shared by the instances. Without it, the
+	// responses are cached in the storage of STORAGE_URL, if it is set.
+	TableEnv = "RESPONSE_CACHE_TABLE"
+	// KBVersionEnv is the version of the knowledge base read from the KBFolder without an index, e.g. the commit it was
+	// built from. If it is not set, the version is the hash of the files in the KBFolder.
+	KBVersionEnv = "KB_VERSION"
+
+	DefaultTTL = time.Hour
+)
+
+// Entry is a cached response, as JSON
+type Entry struct {
+	Value     []byte
+	ExpiresAt time.Time
+}
+
+// Store is where the responses are cached. Get returns nil if there is no response for the key, or if it expired.
+type Store interface {
+	Get(key string) (*Entry, error)
+	Set(key string, entry *Entry) error
+}
+
+// Cache caches the responses in its stores, which are looked up in order. A response found in a store is also cached in
+// the stores before it, e.g. in memory when it is found in DynamoDB.
+type Cache struct {
+	TTL    time.Duration
+	Stores []Store
+	now    func() time.Time
+}
+
+func New(ttl time.Duration, stores ...Store) *Cache {
+	return &Cache{TTL: ttl, Stores: stores, now: time.Now}
+}
+
+// NewFromEnv returns the cache configured by RESPONSE_CACHE_SIZE, RESPONSE_CACHE_REDIS_URL, RESPONSE_CACHE_TABLE or
+// STORAGE_URL and RESPONSE_CACHE_TTL, or nil if caching is not configured
+func NewFromEnv() (*Cache, error) {
+	ttl := DefaultTTL
+	if value := os.Getenv(TTLEnv); value != "" {
+		var err error
+		if ttl, err = time.ParseDuration(value); err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid %s %s", TTLEnv, value)
+		}
+	}
+	var stores []Store
+	if value := os.Getenv(SizeEnv); value != "" && value != "0" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid %s %s", SizeEnv, value)
+		}
+		stores = append(stores, NewMemoryStore(size))
+	}
+	if redisURL := os.Getenv(RedisURLEnv); redisURL != "" {
+		store, err := NewRedisStore(redisURL)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	} else if tableName := os.Getenv(TableEnv); tableName != "" {
+		stores = append(stores, &DynamoDBStore{TableName: tableName, Svc: dynamodb.New(awssession.Default())})
+	} else {
+		store, err := storage.Default()
+		if err != nil {
+			return nil, err
+		}
+		if store != nil {
+			stores = append(stores, &StorageStore{Store: store})
+		}
+	}
+	if len(stores) == 0 {
+		return nil, nil
+	}
+	return New(ttl, stores...), nil
+}
+
+var defaultCache = sync.OnceValue(func() *Cache {
+	c, err := NewFromEnv()
+	if err != nil {
+		logging.Logger().Error("unable to configure the response cache", "error", err)
+	}
+	return c
+})
+
+// Default returns the cache configured by the environment variables, or nil if caching is not configured
+func Default() *Cache {
+	return defaultCache()
+}
+
+var kbVersion = sync.OnceValue(func() string {
+	if version := os.Getenv(KBVersionEnv); version != "" {
+		return version
+	}
+	kbFolder := os.Getenv("KBFolder")
+	if kbFolder == "" {
+		return ""
+	}
+	// the files are walked in lexical order, so the hash only changes with their paths and contents
+	hash := sha256.New()
+	err := filepath.WalkDir(kbFolder, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		fmt.Fprintf(hash, "%s\x00", filepath.ToSlash(filePath))
+		_, err = io.Copy(hash, file)
+		return err
+	})
+	if err != nil {
+		logging.Logger().Warn("unable to hash the knowledge base", "error", err)
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+})
+
+// KBVersion returns the version of the knowledge base, which is part of the keys, so the responses cached with an older
+// knowledge base are not used after it is updated. The version of an indexed knowledge base is the hash of its files,
+// which changes when it is refreshed.
+func KBVersion() string {
+	if index := metadata.CurrentIndex(); index != nil {
+		return index.Version()
+	}
+	return kbVersion()
+}
+
+// Key returns the hash of the version of the knowledge base and the parts, e.g. the route, the query parameters and the
+// content of the file. The parts are hashed as JSON, so maps are hashed the same regardless of the order of their keys.
+func Key(parts ...interface{}) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00", KBVersion())
+	encoder := json.NewEncoder(hash)
+	for _, part := range parts {
+		if err := encoder.Encode(part); err != nil {
+			// a part that cannot be hashed would make different requests share a key
+			panic(fmt.Sprintf("unable to hash %T: %v", part, err))
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Do returns the response cached for the key, or computes it and caches it. Errors are not cached, and the errors of the
+// stores are logged, since the response is then computed. A nil cache computes the response every time.
+func Do[T any](c *Cache, key string, compute func() (*T, error)) (*T, error) {
+	if c == nil {
+		return compute()
+	}
+	for i, store := range c.Stores {
+		entry, err := store.Get(key)
+		if err != nil {
+			logging.Logger().Warn("unable to get cached response", "error", err)
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+		response := new(T)
+		if err := json.Unmarshal(entry.Value, response); err != nil {
+			logging.Logger().Warn("unable to unmarshal cached response", "error", err)
+			continue
+		}
+		metrics.CacheRequests.Inc("hit")
+		c.set(c.Stores[:i], key, entry)
+		return response, nil
+	}
+
+	metrics.CacheRequests.Inc("miss")
+	response, err := compute()
+	if err != nil {
+		return response, err
+	}
+	value, err := json.Marshal(response)
+	if err != nil {
+		logging.Logger().Warn("unable to marshal response", "error", err)
+		return response, nil
+	}
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	c.set(c.Stores, key, &Entry{Value: value, ExpiresAt: now().Add(c.TTL)})
+	return response, nil
+}
+
+func (c *Cache) set(stores []Store, key string, entry *Entry) {
+	for _, store := range stores {
+		if err := store.Set(key, entry); err != nil {
+			logging.Logger().Warn("unable to cache response", "error", err)
+		}
+	}
+}
+
+// WithoutParams returns a copy of the query parameters without the names, e.g. to leave the repository of a file out of
+// the key when the response does not depend on it
+func WithoutParams(queryStringParams map[string]string, names ...string) map[string]string {
+	params := make(map[string]string, len(queryStringParams))
+	for key, value := range queryStringParams {
+		params[key] = value
+	}
+	for _, name := range names {
+		delete(params, name)
+	}
+	return params
+}
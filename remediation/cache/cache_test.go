@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/storage"
+)
+
+type response struct {
+	Output string
+}
+
+type mockDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func (m *mockDynamoDBClient) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: m.items[aws.StringValue(input.Key["Key"].S)]}, nil
+}
+
+func (m *mockDynamoDBClient) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	m.items[aws.StringValue(input.Item["Key"].S)] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestDo(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	memory := NewMemoryStore(2)
+	memory.now = func() time.Time { return now }
+	svc := &mockDynamoDBClient{items: map[string]map[string]*dynamodb.AttributeValue{}}
+	c := New(time.Hour, memory, &DynamoDBStore{TableName: "ResponseCache", Svc: svc, now: func() time.Time { return now }})
+	c.now = func() time.Time { return now }
+
+	computed := 0
+	compute := func(output string) func() (*response, error) {
+		return func() (*response, error) {
+			computed++
+			return &response{Output: output}, nil
+		}
+	}
+	for i := 0; i < 2; i++ {
+		r, err := Do(c, "a", compute("a"))
+		if err != nil || r.Output != "a" {
+			t.Fatalf("Do() = %v, %v", r, err)
+		}
+	}
+	if computed != 1 || len(svc.items) != 1 {
+		t.Errorf("expected the response to be computed once and cached in DynamoDB, computed %d times", computed)
+	}
+
+	// the least recently used response is removed from memory, and is then found in DynamoDB
+	Do(c, "b", compute("b"))
+	Do(c, "c", compute("c"))
+	if entry, _ := memory.Get("a"); entry != nil {
+		t.Errorf("expected a to be removed from memory")
+	}
+	if r, _ := Do(c, "a", compute("a")); r.Output != "a" || computed != 3 {
+		t.Errorf("expected a to be found in DynamoDB, computed %d times", computed)
+	}
+	if entry, _ := memory.Get("a"); entry == nil || !entry.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected a to be cached in memory again until it expires, got %+v", entry)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if r, _ := Do(c, "a", compute("a2")); r.Output != "a2" || computed != 4 {
+		t.Errorf("expected an expired response to be computed again, got %v", r)
+	}
+
+	if _, err := Do(c, "error", func() (*response, error) { return nil, errors.New("unable to pin") }); err == nil {
+		t.Errorf("expected the error of computing the response")
+	}
+	if entry, _ := memory.Get("error"); entry != nil {
+		t.Errorf("expected errors not to be cached")
+	}
+
+	if r, err := Do(nil, "a", compute("a3")); err != nil || r.Output != "a3" {
+		t.Errorf("expected a nil cache to compute the response, got %v, %v", r, err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	first := Key("secure-workflow", map[string]string{"pinActions": "false", "addHardenRunner": "false"}, "on: push\n")
+	second := Key("secure-workflow", map[string]string{"addHardenRunner": "false", "pinActions": "false"}, "on: push\n")
+	if first != second {
+		t.Errorf("expected the keys of the same parameters in another order to be the same")
+	}
+	if first == Key("secure-workflow", map[string]string{"pinActions": "false"}, "on: push\n") {
+		t.Errorf("expected the keys of different parameters to be different")
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	os.Unsetenv(SizeEnv)
+	os.Unsetenv(TableEnv)
+	if c, err := NewFromEnv(); c != nil || err != nil {
+		t.Errorf("NewFromEnv() = %v, %v, want nil when caching is not configured", c, err)
+	}
+
+	os.Setenv(SizeEnv, "100")
+	os.Setenv(TTLEnv, "30m")
+	defer os.Unsetenv(SizeEnv)
+	defer os.Unsetenv(TTLEnv)
+	c, err := NewFromEnv()
+	if err != nil || c == nil || c.TTL != 30*time.Minute || len(c.Stores) != 1 {
+		t.Errorf("NewFromEnv() = %+v, %v", c, err)
+	}
+
+	os.Setenv(TTLEnv, "30")
+	if _, err := NewFromEnv(); err == nil {
+		t.Errorf("expected an error for a TTL without a unit")
+	}
+}
+
+func TestStorageStore(t *testing.T) {
+	now := time.Now()
+	store := &StorageStore{Store: &storage.FileStore{Dir: t.TempDir()}, now: func() time.Time { return now }}
+	c := New(time.Hour, store)
+	calls := 0
+	compute := func() (*response, error) {
+		calls++
+		return &response{Output: "pinned"}, nil
+	}
+	for i := 0; i < 2; i++ {
+		if value, err := Do(c, "key", compute); err != nil || value.Output != "pinned" {
+			t.Fatalf("Do() = %v, %v", value, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the response to be computed once, got %d", calls)
+	}
+
+	// the expired response is deleted from the storage
+	now = now.Add(2 * time.Hour)
+	if entry, err := store.Get("key"); entry != nil || err != nil {
+		t.Errorf("Get() = %v, %v, want nil for an expired response", entry, err)
+	}
+	if value, _ := store.Store.Get("responses/key"); value != nil {
+		t.Errorf("expected the expired response to be deleted")
+	}
+}
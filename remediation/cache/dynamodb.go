@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// maxItemSize is the size of the largest response cached in DynamoDB, which is below its limit of 400 KB per item
+const maxItemSize = 350 * 1024
+
+// DynamoDBStore caches the responses in a DynamoDB table with Key as the hash key. ExpiresAt is the time the response
+// expires in Unix seconds, which can be the TTL attribute of the table, so the expired responses are deleted.
+type DynamoDBStore struct {
+	TableName string
+	Svc       dynamodbiface.DynamoDBAPI
+	now       func() time.Time
+}
+
+func (s *DynamoDBStore) Get(key string) (*Entry, error) {
+	result, err := s.Svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.TableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Key": {
+				S: aws.String(key),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil || result.Item["Value"] == nil || result.Item["ExpiresAt"] == nil {
+		return nil, nil
+	}
+	expiresAt, err := strconv.ParseInt(aws.StringValue(result.Item["ExpiresAt"].N), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	// the items are deleted some time after they expire, so they are checked as well
+	entry := &Entry{Value: result.Item["Value"].B, ExpiresAt: time.Unix(expiresAt, 0)}
+	if !now().Before(entry.ExpiresAt) {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// Set caches the entry, unless it is too large for an item
+func (s *DynamoDBStore) Set(key string, entry *Entry) error {
+	if len(entry.Value) > maxItemSize {
+		return nil
+	}
+	_, err := s.Svc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			"Key":       {S: aws.String(key)},
+			"Value":     {B: entry.Value},
+			"ExpiresAt": {N: aws.String(strconv.FormatInt(entry.ExpiresAt.Unix(), 10))},
+		},
+	})
+	return err
+}
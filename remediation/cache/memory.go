@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	key   string
+	entry *Entry
+}
+
+// MemoryStore keeps the most recently used responses in memory, so they are kept across the requests served by an
+// instance. With several instances, each one caches the responses it computed.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// recent has the entries from the most to the least recently used
+	recent *list.List
+	now    func() time.Time
+}
+
+func NewMemoryStore(size int) *MemoryStore {
+	return &MemoryStore{size: size, entries: map[string]*list.Element{}, recent: list.New(), now: time.Now}
+}
+
+func (s *MemoryStore) Get(key string) (*Entry, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	element, found := s.entries[key]
+	if !found {
+		return nil, nil
+	}
+	entry := element.Value.(*memoryEntry).entry
+	if !s.now().Before(entry.ExpiresAt) {
+		s.recent.Remove(element)
+		delete(s.entries, key)
+		return nil, nil
+	}
+	s.recent.MoveToFront(element)
+	return entry, nil
+}
+
+// Set caches the entry, and removes the least recently used entry if the store is full
+func (s *MemoryStore) Set(key string, entry *Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if element, found := s.entries[key]; found {
+		element.Value.(*memoryEntry).entry = entry
+		s.recent.MoveToFront(element)
+		return nil
+	}
+	s.entries[key] = s.recent.PushFront(&memoryEntry{key: key, entry: entry})
+	for s.recent.Len() > s.size {
+		oldest := s.recent.Back()
+		s.recent.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// RedisURLEnv is the URL of a Redis server the responses are cached in, e.g. redis://:password@host:6379/0, or
+	// rediss:// for TLS. It is shared by the instances, and is used instead of RESPONSE_CACHE_TABLE and STORAGE_URL.
+	RedisURLEnv = "RESPONSE_CACHE_REDIS_URL"
+
+	// redisPrefix is the prefix of the keys of the responses, so the server can be shared with other applications
+	redisPrefix = "secure-repo:responses:"
+	// redisTimeout is how long a command waits for the server, after which the response is computed
+	redisTimeout = 2 * time.Second
+	// redisIdleConns is the number of connections kept open for the next commands
+	redisIdleConns = 8
+)
+
+// RedisStore caches the responses in Redis, so the instances share the commits of refs, the digests of images and the
+// responses they computed. The responses expire in Redis with the TTL of the cache.
+type RedisStore struct {
+	// Addr is the host and port of the server
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	// TLS is the configuration of the connections, which are not encrypted if it is nil
+	TLS   *tls.Config
+	conns chan *redisConn
+	now   func() time.Time
+}
+
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisStore returns the store of a redis:// or rediss:// URL
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	redisURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", RedisURLEnv, err)
+	}
+	if redisURL.Scheme != "redis" && redisURL.Scheme != "rediss" {
+		return nil, fmt.Errorf("unsupported %s scheme %s, expected redis or rediss", RedisURLEnv, redisURL.Scheme)
+	}
+	if redisURL.Hostname() == "" {
+		return nil, fmt.Errorf("%s has no host", RedisURLEnv)
+	}
+	store := &RedisStore{Addr: redisURL.Host, conns: make(chan *redisConn, redisIdleConns), now: time.Now}
+	if redisURL.Port() == "" {
+		store.Addr = net.JoinHostPort(redisURL.Hostname(), "6379")
+	}
+	if redisURL.User != nil {
+		store.Username = redisURL.User.Username()
+		store.Password, _ = redisURL.User.Password()
+	}
+	if db := strings.Trim(redisURL.Path, "/"); db != "" {
+		if store.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid %s database %s", RedisURLEnv, db)
+		}
+	}
+	if redisURL.Scheme == "rediss" {
+		store.TLS = &tls.Config{ServerName: redisURL.Hostname(), MinVersion: tls.VersionTLS12}
+	}
+	return store, nil
+}
+
+func (s *RedisStore) Get(key string) (*Entry, error) {
+	value, err := s.do("GET", redisPrefix+key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	entry := &Entry{}
+	if err := json.Unmarshal(value, entry); err != nil {
+		return nil, err
+	}
+	if !s.now().Before(entry.ExpiresAt) {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// Set caches the entry until it expires, unless it already did
+func (s *RedisStore) Set(key string, entry *Entry) error {
+	ttl := entry.ExpiresAt.Sub(s.now()).Milliseconds()
+	if ttl <= 0 {
+		return nil
+	}
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.do("SET", redisPrefix+key, string(value), "PX", strconv.FormatInt(ttl, 10))
+	return err
+}
+
+// do runs a command on an idle connection, or on a new one, and returns its reply, which is nil for a nil reply
+func (s *RedisStore) do(args ...string) ([]byte, error) {
+	var conn *redisConn
+	select {
+	case conn = <-s.conns:
+	default:
+		var err error
+		if conn, err = s.dial(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := conn.do(args...)
+	if err != nil {
+		// the connection is closed, since the reply may still be sent on it
+		if _, isReplyErr := err.(redisError); !isReplyErr {
+			conn.Close()
+			return nil, err
+		}
+	}
+	select {
+	case s.conns <- conn:
+	default:
+		conn.Close()
+	}
+	return reply, err
+}
+
+func (s *RedisStore) dial() (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var netConn net.Conn
+	var err error
+	if s.TLS != nil {
+		netConn, err = tls.DialWithDialer(dialer, "tcp", s.Addr, s.TLS)
+	} else {
+		netConn, err = dialer.Dial("tcp", s.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to redis: %v", err)
+	}
+	conn := &redisConn{Conn: netConn, reader: bufio.NewReader(netConn)}
+	if s.Password != "" {
+		args := []string{"AUTH", s.Password}
+		if s.Username != "" {
+			args = []string{"AUTH", s.Username, s.Password}
+		}
+		if _, err := conn.do(args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("unable to authenticate to redis: %v", err)
+		}
+	}
+	if s.DB != 0 {
+		if _, err := conn.do("SELECT", strconv.Itoa(s.DB)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("unable to select redis database %d: %v", s.DB, err)
+		}
+	}
+	return conn, nil
+}
+
+// redisError is an error reply of the server, after which the connection can still be used
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// do sends the command as an array of bulk strings and reads its reply
+func (c *redisConn) do(args ...string) ([]byte, error) {
+	if err := c.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c, command.String()); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply reads a simple string, error, integer or bulk string reply. Arrays are not read, since the commands of
+// the store do not reply with them.
+func (c *redisConn) readReply() ([]byte, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty redis reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis reply %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, value); err != nil {
+			return nil, err
+		}
+		return value[:size], nil
+	}
+	return nil, fmt.Errorf("unexpected redis reply %q", line)
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis serves GET, SET, AUTH and SELECT, and records the commands it received
+type fakeRedis struct {
+	mutex    sync.Mutex
+	values   map[string]string
+	commands []string
+}
+
+func (f *fakeRedis) serve(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.handle(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, count)
+		for i := range args {
+			line, _ = reader.ReadString('\n')
+			size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			arg := make([]byte, size+2)
+			io.ReadFull(reader, arg)
+			args[i] = string(arg[:size])
+		}
+		f.mutex.Lock()
+		f.commands = append(f.commands, strings.Join(args, " "))
+		switch args[0] {
+		case "AUTH":
+			if args[len(args)-1] == "secret" {
+				io.WriteString(conn, "+OK\r\n")
+			} else {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+			}
+		case "SELECT":
+			io.WriteString(conn, "+OK\r\n")
+		case "SET":
+			f.values[args[1]] = args[2]
+			io.WriteString(conn, "+OK\r\n")
+		case "GET":
+			if value, found := f.values[args[1]]; found {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+			} else {
+				io.WriteString(conn, "$-1\r\n")
+			}
+		}
+		f.mutex.Unlock()
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	server := &fakeRedis{values: map[string]string{}}
+	addr := server.serve(t)
+
+	now := time.Now()
+	store, err := NewRedisStore("redis://:secret@" + addr + "/2")
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	store.now = func() time.Time { return now }
+	// two caches share the store, like two instances of the handler
+	first, second := New(time.Hour, store), New(time.Hour, store)
+	calls := 0
+	compute := func() (*response, error) {
+		calls++
+		return &response{Output: "pinned"}, nil
+	}
+	for _, c := range []*Cache{first, second} {
+		if value, err := Do(c, "key", compute); err != nil || value.Output != "pinned" {
+			t.Fatalf("Do() = %v, %v", value, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the response to be computed once, got %d", calls)
+	}
+	if len(server.commands) < 3 || server.commands[0] != "AUTH secret" || server.commands[1] != "SELECT 2" {
+		t.Errorf("expected the connection to authenticate and select the database, got %v", server.commands)
+	}
+	if set := server.commands[3]; !strings.HasPrefix(set, "SET secure-repo:responses:key ") || !strings.HasSuffix(set, " PX 3600000") {
+		t.Errorf("expected the response to be set with the TTL, got %s", set)
+	}
+
+	// the response expires
+	now = now.Add(2 * time.Hour)
+	if entry, err := store.Get("key"); entry != nil || err != nil {
+		t.Errorf("Get() = %v, %v, want nil for an expired response", entry, err)
+	}
+
+	wrongPassword, _ := NewRedisStore("redis://:wrong@" + addr)
+	if _, err := wrongPassword.Get("key"); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+	for _, rawURL := range []string{"http://localhost", "redis://", "redis://localhost/db"} {
+		if _, err := NewRedisStore(rawURL); err == nil {
+			t.Errorf("expected an error for %s", rawURL)
+		}
+	}
+	if store, _ := NewRedisStore("rediss://cache.example.com"); store.Addr != "cache.example.com:6379" || store.TLS == nil {
+		t.Errorf("expected the default port and TLS, got %+v", store)
+	}
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/step-security/secure-repo/remediation/storage"
+)
+
+// storagePrefix is the prefix of the keys of the responses in the storage
+const storagePrefix = "responses/"
+
+// StorageStore caches the responses in a storage.Store, e.g. a directory when the service is hosted outside AWS. The
+// storage does not expire values, so an expired response is deleted when it is read.
+type StorageStore struct {
+	Store storage.Store
+	now   func() time.Time
+}
+
+func (s *StorageStore) Get(key string) (*Entry, error) {
+	value, err := s.Store.Get(storagePrefix + key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	entry := &Entry{}
+	if err := json.Unmarshal(value, entry); err != nil {
+		return nil, err
+	}
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	if !now().Before(entry.ExpiresAt) {
+		return nil, s.Store.Delete(storagePrefix + key)
+	}
+	return entry, nil
+}
+
+func (s *StorageStore) Set(key string, entry *Entry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.Store.Put(storagePrefix+key, value)
+}
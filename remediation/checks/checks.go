@@ -0,0 +1,149 @@
+// Package checks posts the findings of the files of a commit as a GitHub Check Run, with an annotation on the line of
+// each finding, so the findings of the workflows changed by a pull request are shown in its Files changed view.
+package checks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/sarif"
+)
+
+const (
+	// Name is the name of the check run
+	Name = "StepSecurity"
+
+	// maxAnnotations is the number of annotations GitHub accepts in a request, the others are added by updating the check run
+	maxAnnotations = 50
+)
+
+// File is a file of the commit and the findings reported for it
+type File struct {
+	Path     string
+	Findings []findings.Finding
+}
+
+// getAnnotationLevel returns the level of the annotation of the SARIF level of a rule
+func getAnnotationLevel(level string) string {
+	switch level {
+	case "error":
+		return "failure"
+	case "note":
+		return "notice"
+	default:
+		return "warning"
+	}
+}
+
+// NewAnnotations returns an annotation for each finding, on its line, or the first line for the findings of the whole file
+func NewAnnotations(files []File) []*github.CheckRunAnnotation {
+	var annotations []*github.CheckRunAnnotation
+	for _, file := range files {
+		for _, finding := range file.Findings {
+			rule := sarif.GetRule(finding.RuleID)
+			message := finding.Message
+			if finding.Suggestion != "" {
+				message += ". " + finding.Suggestion
+			}
+			if finding.Fixed {
+				message += ". This is fixed by the remediations of secure-repo."
+			}
+			line := finding.Line
+			if line < 1 {
+				line = 1
+			}
+			annotation := &github.CheckRunAnnotation{
+				Path:            github.String(file.Path),
+				StartLine:       github.Int(line),
+				EndLine:         github.Int(line),
+				AnnotationLevel: github.String(getAnnotationLevel(rule.Level)),
+				Title:           github.String(rule.Description),
+				Message:         github.String(message),
+				RawDetails:      github.String(finding.RuleID),
+			}
+			// columns are only accepted on annotations of a single line
+			if finding.Column > 0 {
+				annotation.StartColumn, annotation.EndColumn = github.Int(finding.Column), github.Int(finding.Column)
+			}
+			annotations = append(annotations, annotation)
+		}
+	}
+	return annotations
+}
+
+// getOutput returns the output of the check run, with the count of the findings by file
+func getOutput(files []File) *github.CheckRunOutput {
+	total, fixed := 0, 0
+	counts := make(map[string]int)
+	for _, file := range files {
+		for _, finding := range file.Findings {
+			total++
+			counts[file.Path]++
+			if finding.Fixed {
+				fixed++
+			}
+		}
+	}
+	if total == 0 {
+		return &github.CheckRunOutput{Title: github.String("No findings"), Summary: github.String("No insecure workflow patterns were found.")}
+	}
+
+	paths := make([]string, 0, len(counts))
+	for path := range counts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	summary := fmt.Sprintf("%d findings, of which %d are fixed by the remediations of secure-repo.\n\n", total, fixed)
+	for _, path := range paths {
+		summary += fmt.Sprintf("- `%s`: %d\n", path, counts[path])
+	}
+	return &github.CheckRunOutput{Title: github.String(fmt.Sprintf("%d findings", total)), Summary: github.String(summary)}
+}
+
+// CreateCheckRun posts a completed check run on the commit with the annotations of the findings of the files. The conclusion
+// is neutral if there are findings, so the check informs the review without blocking the merge.
+func CreateCheckRun(ctx context.Context, client *github.Client, owner, repo, headSHA string, files []File) (*github.CheckRun, error) {
+	annotations := NewAnnotations(files)
+	conclusion := "success"
+	if len(annotations) > 0 {
+		conclusion = "neutral"
+	}
+	output := getOutput(files)
+	first := annotations
+	if len(first) > maxAnnotations {
+		first = first[:maxAnnotations]
+	}
+	output.Annotations = first
+
+	checkRun, _, err := client.Checks.CreateCheckRun(ctx, owner, repo, github.CreateCheckRunOptions{
+		Name:        Name,
+		HeadSHA:     headSHA,
+		Status:      github.String("completed"),
+		Conclusion:  github.String(conclusion),
+		CompletedAt: &github.Timestamp{Time: time.Now()},
+		Output:      output,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create check run: %v", err)
+	}
+
+	// the annotations of an update are added to the ones of the check run
+	for start := maxAnnotations; start < len(annotations); start += maxAnnotations {
+		end := start + maxAnnotations
+		if end > len(annotations) {
+			end = len(annotations)
+		}
+		_, _, err := client.Checks.UpdateCheckRun(ctx, owner, repo, checkRun.GetID(), github.UpdateCheckRunOptions{
+			Name:   Name,
+			Output: &github.CheckRunOutput{Title: output.Title, Summary: output.Summary, Annotations: annotations[start:end]},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to add annotations to check run: %v", err)
+		}
+	}
+	return checkRun, nil
+}
@@ -0,0 +1,62 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v40/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/step-security/secure-repo/remediation/findings"
+)
+
+func TestNewAnnotations(t *testing.T) {
+	files := []File{{Path: ".github/workflows/ci.yml", Findings: []findings.Finding{
+		{RuleID: "dangerous-trigger", Message: "Pull request code is checked out", Line: 12, Column: 9},
+		{RuleID: "missing-permissions", Message: "Workflow does not set top level permissions", Suggestion: "Set top level permissions", Fixed: true},
+	}}}
+	annotations := NewAnnotations(files)
+	if len(annotations) != 2 {
+		t.Fatalf("NewAnnotations() returned %d annotations, want 2", len(annotations))
+	}
+	if annotations[0].GetAnnotationLevel() != "failure" || annotations[0].GetStartLine() != 12 || annotations[0].GetStartColumn() != 9 {
+		t.Errorf("unexpected annotation of the dangerous trigger %+v", annotations[0])
+	}
+	second := annotations[1]
+	if second.GetAnnotationLevel() != "warning" || second.GetStartLine() != 1 || second.StartColumn != nil ||
+		second.GetMessage() != "Workflow does not set top level permissions. Set top level permissions. This is fixed by the remediations of secure-repo." {
+		t.Errorf("unexpected annotation of the missing permissions %+v", second)
+	}
+}
+
+func TestCreateCheckRun(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var requests []github.CheckRunOutput
+	responder := func(status int) httpmock.Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			var checkRun struct{ Output github.CheckRunOutput }
+			json.Unmarshal(body, &checkRun)
+			requests = append(requests, checkRun.Output)
+			return httpmock.NewStringResponse(status, `{"id": 5}`), nil
+		}
+	}
+	httpmock.RegisterResponder("POST", "https://api.github.com/repos/octo-org/app/check-runs", responder(http.StatusCreated))
+	httpmock.RegisterResponder("PATCH", "https://api.github.com/repos/octo-org/app/check-runs/5", responder(http.StatusOK))
+
+	var fileFindings []findings.Finding
+	for i := 1; i <= 120; i++ {
+		fileFindings = append(fileFindings, findings.Finding{RuleID: "unpinned-action", Message: "action is not pinned", Line: i})
+	}
+	_, err := CreateCheckRun(context.Background(), github.NewClient(nil), "octo-org", "app", "head-sha", []File{{Path: "ci.yml", Findings: fileFindings}})
+	if err != nil {
+		t.Fatalf("CreateCheckRun() returned error: %v", err)
+	}
+	if len(requests) != 3 || len(requests[0].Annotations) != 50 || len(requests[2].Annotations) != 20 || requests[0].GetTitle() != "120 findings" {
+		t.Errorf("expected the annotations to be posted in batches of 50, got %d requests", len(requests))
+	}
+}
@@ -0,0 +1,281 @@
+package circleci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/outbound"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigPath is the path of the configuration of CircleCI
+const ConfigPath = ".circleci/config.yml"
+
+const (
+	RuleUnpinnedOrb   = "circleci-unpinned-orb"
+	RuleBroadContext  = "circleci-broad-context"
+	orgGlobalContext  = "org-global"
+	defaultGraphQLURL = "https://circleci.com/graphql-unstable"
+	// graphQLURLEnv is the GraphQL API of a CircleCI server instance
+	graphQLURLEnv = "CIRCLECI_GRAPHQL_URL"
+)
+
+var exactVersionRegex = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// SecureConfigResponse is the result of the remediations of a CircleCI configuration
+type SecureConfigResponse struct {
+	OriginalInput string
+	FinalOutput   string
+	IsChanged     bool
+	PinnedOrbs    bool
+	PinnedImages  bool
+	Findings      []findings.Finding
+}
+
+// ResolveOrb returns the exact version of an orb reference, e.g. 5.2.0 for circleci/node@5 or circleci/node@volatile
+var ResolveOrb = resolveOrb
+
+func resolveOrb(ctx context.Context, orb string) (string, error) {
+	graphQLURL := os.Getenv(graphQLURLEnv)
+	if graphQLURL == "" {
+		graphQLURL = defaultGraphQLURL
+	}
+	request, err := json.Marshal(map[string]interface{}{
+		"query":     "query($orbVersionRef: String) { orbVersion(orbVersionRef: $orbVersionRef) { version } }",
+		"variables": map[string]string{"orbVersionRef": orb},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, graphQLURL, bytes.NewReader(request))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := outbound.Client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	result := struct {
+		Data struct {
+			OrbVersion *struct {
+				Version string `json:"version"`
+			} `json:"orbVersion"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", err
+	}
+	if len(result.Errors) > 0 {
+		return "", fmt.Errorf("%s", result.Errors[0].Message)
+	}
+	if result.Data.OrbVersion == nil || !exactVersionRegex.MatchString(result.Data.OrbVersion.Version) {
+		return "", fmt.Errorf("orb %s not found", orb)
+	}
+	return result.Data.OrbVersion.Version, nil
+}
+
+// findOrbEdits returns the edits that pin the orbs to their exact version, and the findings of the dev orbs, which
+// cannot be pinned. Orbs defined inline are skipped.
+func findOrbEdits(ctx context.Context, topNode *yaml.Node) ([]textedit.Replacement, []findings.Finding, error) {
+	orbsNode := document.MappingValue(topNode, "orbs")
+	if orbsNode == nil || orbsNode.Kind != yaml.MappingNode {
+		return nil, nil, nil
+	}
+	var edits []textedit.Replacement
+	var orbFindings []findings.Finding
+	for i := 0; i+1 < len(orbsNode.Content); i += 2 {
+		orbNode := orbsNode.Content[i+1]
+		if orbNode.Kind != yaml.ScalarNode || strings.Contains(orbNode.Value, "<<") {
+			continue
+		}
+		at := strings.LastIndex(orbNode.Value, "@")
+		if at == -1 {
+			continue
+		}
+		name, version := orbNode.Value[:at], orbNode.Value[at+1:]
+		if exactVersionRegex.MatchString(version) {
+			continue
+		}
+		if strings.HasPrefix(version, "dev:") {
+			orbFindings = append(orbFindings, findings.Finding{
+				RuleID:     RuleUnpinnedOrb,
+				Message:    fmt.Sprintf("Orb %s is a development version, which can be overwritten and expires after 90 days", orbNode.Value),
+				Action:     name,
+				Line:       orbNode.Line,
+				Column:     orbNode.Column,
+				Suggestion: "Publish a version of the orb, and use its exact version",
+			})
+			continue
+		}
+		exactVersion, err := ResolveOrb(ctx, orbNode.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to get version of orb %s: %v", orbNode.Value, err)
+		}
+		edits = append(edits, textedit.Replacement{Line: orbNode.Line, Column: orbNode.Column, Old: orbNode.Value, New: name + "@" + exactVersion})
+	}
+	return edits, orbFindings, nil
+}
+
+// findImages returns the images of the docker executors of the jobs and of the reusable executors, which are not
+// pinned to a digest. Images set with parameters or environment variables are skipped.
+func findImages(topNode *yaml.Node) []*yaml.Node {
+	var images []*yaml.Node
+	for _, key := range []string{"executors", "jobs"} {
+		parent := document.MappingValue(topNode, key)
+		if parent == nil || parent.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(parent.Content); i += 2 {
+			dockerNode := document.MappingValue(parent.Content[i+1], "docker")
+			if dockerNode == nil || dockerNode.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, container := range dockerNode.Content {
+				image := document.MappingValue(container, "image")
+				if image == nil || image.Kind != yaml.ScalarNode || image.Value == "" || strings.Contains(image.Value, "@") ||
+					strings.Contains(image.Value, "<<") || strings.Contains(image.Value, "$") {
+					continue
+				}
+				images = append(images, image)
+			}
+		}
+	}
+	return images
+}
+
+// findImageEdits returns the edits that pin the images to their digest, keeping the tag for readability
+func findImageEdits(ctx context.Context, topNode *yaml.Node) ([]textedit.Replacement, error) {
+	var edits []textedit.Replacement
+	for _, image := range findImages(topNode) {
+		pinned, err := docker.PinImage(ctx, image.Value)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, textedit.Replacement{Line: image.Line, Column: image.Column, Old: image.Value, New: pinned})
+	}
+	return edits, nil
+}
+
+// findBroadContexts returns findings for the jobs of the workflows that are run with the org-global context, which is
+// shared with all projects of the organization, and for the jobs that are run with a context on every branch, so the
+// secrets of the context can be used by any branch that is pushed
+func findBroadContexts(topNode *yaml.Node) []findings.Finding {
+	var contextFindings []findings.Finding
+	workflows := document.MappingValue(topNode, "workflows")
+	if workflows == nil || workflows.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(workflows.Content); i += 2 {
+		jobs := document.MappingValue(workflows.Content[i+1], "jobs")
+		if jobs == nil || jobs.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, job := range jobs.Content {
+			// a job is its name, or a mapping of its name to its parameters
+			if job.Kind != yaml.MappingNode || len(job.Content) != 2 {
+				continue
+			}
+			jobName, params := job.Content[0].Value, job.Content[1]
+			contextKey, contextNode := document.MappingEntry(params, "context")
+			if contextNode == nil {
+				continue
+			}
+			contexts := []*yaml.Node{contextNode}
+			if contextNode.Kind == yaml.SequenceNode {
+				contexts = contextNode.Content
+			}
+			filters := document.MappingValue(params, "filters")
+			onlyBranches := document.MappingValue(document.MappingValue(filters, "branches"), "only")
+			onlyTags := document.MappingValue(filters, "tags")
+			for _, context := range contexts {
+				if context.Kind != yaml.ScalarNode {
+					continue
+				}
+				if context.Value == orgGlobalContext {
+					contextFindings = append(contextFindings, findings.Finding{
+						RuleID:     RuleBroadContext,
+						Message:    fmt.Sprintf("Job %s is run with the org-global context, whose secrets are shared with all projects of the organization", jobName),
+						JobName:    jobName,
+						Line:       context.Line,
+						Column:     context.Column,
+						Suggestion: "Move the secrets the job needs to a context of the project, restricted to a security group",
+					})
+				} else if onlyBranches == nil && onlyTags == nil {
+					contextFindings = append(contextFindings, findings.Finding{
+						RuleID:     RuleBroadContext,
+						Message:    fmt.Sprintf("Job %s is run with the context %s on every branch, so any branch that is pushed can use its secrets", jobName, context.Value),
+						JobName:    jobName,
+						Line:       contextKey.Line,
+						Column:     contextKey.Column,
+						Suggestion: "Add filters: branches: only: to the job, and restrict the context to a security group",
+					})
+				}
+			}
+		}
+	}
+	return contextFindings
+}
+
+// SecureConfig runs the remediations for a CircleCI configuration. Orbs are pinned to their exact version unless
+// pinOrbs is false, and the images of docker executors to their digest unless pinImages is false. Development orbs and
+// jobs run with contexts whose secrets are broadly available are reported as findings.
+func SecureConfig(ctx context.Context, queryStringParams map[string]string, inputYaml string) (*SecureConfigResponse, error) {
+	response := &SecureConfigResponse{OriginalInput: inputYaml, FinalOutput: inputYaml}
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(inputYaml), &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return response, nil
+	}
+	topNode := doc.Content[0]
+
+	var edits []textedit.Replacement
+	if queryStringParams["pinOrbs"] != "false" {
+		orbEdits, orbFindings, err := findOrbEdits(ctx, topNode)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, orbEdits...)
+		response.PinnedOrbs = len(orbEdits) > 0
+		response.Findings = append(response.Findings, orbFindings...)
+	}
+	if queryStringParams["pinImages"] != "false" {
+		imageEdits, err := findImageEdits(ctx, topNode)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, imageEdits...)
+		response.PinnedImages = len(imageEdits) > 0
+	}
+	response.Findings = append(response.Findings, findBroadContexts(topNode)...)
+
+	if len(edits) > 0 {
+		response.FinalOutput = textedit.ApplyReplacements(inputYaml, edits)
+		response.IsChanged = true
+	}
+	return response, nil
+}
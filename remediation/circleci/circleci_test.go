@@ -0,0 +1,136 @@
+package circleci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/step-security/secure-repo/remediation/internal/testutil"
+)
+
+func TestSecureConfig(t *testing.T) {
+	digest := testutil.MockRegistry(t, "cimg/node/manifests/20.11", "library/redis/manifests/latest")
+	saveResolveOrb := ResolveOrb
+	var resolved []string
+	ResolveOrb = func(_ context.Context, orb string) (string, error) {
+		resolved = append(resolved, orb)
+		return "5.2.0", nil
+	}
+	defer func() { ResolveOrb = saveResolveOrb }()
+
+	input := `version: 2.1
+orbs:
+  node: circleci/node@5
+  aws-cli: circleci/aws-cli@volatile
+  slack: circleci/slack@4.12.5
+  internal: acme/tools@dev:alpha
+executors:
+  default:
+    docker:
+      - image: cimg/node:20.11
+      - image: redis
+jobs:
+  test:
+    executor: default
+    steps:
+      - checkout
+  deploy:
+    docker:
+      - image: << parameters.image >>
+    steps:
+      - checkout
+workflows:
+  main:
+    jobs:
+      - test
+      - deploy:
+          context: [org-global, aws-prod]
+      - publish:
+          context: npm
+          filters:
+            branches:
+              only: main
+`
+	want := `version: 2.1
+orbs:
+  node: circleci/node@5.2.0
+  aws-cli: circleci/aws-cli@5.2.0
+  slack: circleci/slack@4.12.5
+  internal: acme/tools@dev:alpha
+executors:
+  default:
+    docker:
+      - image: cimg/node:20.11@` + digest + `
+      - image: redis:latest@` + digest + `
+jobs:
+  test:
+    executor: default
+    steps:
+      - checkout
+  deploy:
+    docker:
+      - image: << parameters.image >>
+    steps:
+      - checkout
+workflows:
+  main:
+    jobs:
+      - test
+      - deploy:
+          context: [org-global, aws-prod]
+      - publish:
+          context: npm
+          filters:
+            branches:
+              only: main
+`
+	response, err := SecureConfig(context.Background(), map[string]string{}, input)
+	if err != nil {
+		t.Fatalf("SecureConfig() returned error: %v", err)
+	}
+	if !response.IsChanged || !response.PinnedOrbs || !response.PinnedImages || response.FinalOutput != want {
+		t.Errorf("SecureConfig() = %+v,\n%s\nwant\n%s", response, response.FinalOutput, want)
+	}
+	if fmt.Sprint(resolved) != "[circleci/node@5 circleci/aws-cli@volatile]" {
+		t.Errorf("resolved orbs = %v", resolved)
+	}
+	var rules []string
+	for _, finding := range response.Findings {
+		rules = append(rules, fmt.Sprintf("%s:%d:%s", finding.RuleID, finding.Line, finding.JobName))
+	}
+	if fmt.Sprint(rules) != "[circleci-unpinned-orb:6: circleci-broad-context:27:deploy circleci-broad-context:27:deploy]" {
+		t.Errorf("findings = %v, want the dev orb, the org-global context and the context on every branch", rules)
+	}
+
+	response, err = SecureConfig(context.Background(), map[string]string{}, want)
+	if err != nil || response.IsChanged || response.FinalOutput != want {
+		t.Errorf("expected pinned config to be unchanged, got %+v, %v", response, err)
+	}
+}
+
+func TestResolveOrb(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", defaultGraphQLURL, func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		request := struct {
+			Variables map[string]string `json:"variables"`
+		}{}
+		json.Unmarshal(body, &request)
+		if request.Variables["orbVersionRef"] != "circleci/node@5" {
+			return httpmock.NewStringResponse(http.StatusOK, `{"data": {"orbVersion": null}}`), nil
+		}
+		return httpmock.NewStringResponse(http.StatusOK, `{"data": {"orbVersion": {"version": "5.2.0"}}}`), nil
+	})
+	if version, err := resolveOrb(context.Background(), "circleci/node@5"); err != nil || version != "5.2.0" {
+		t.Errorf("resolveOrb() = %s, %v", version, err)
+	}
+	if _, err := resolveOrb(context.Background(), "circleci/unknown@1"); err == nil {
+		t.Errorf("resolveOrb() expected an error for an unknown orb")
+	}
+}
@@ -0,0 +1,155 @@
+package codeowners
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultPatterns are the paths that require review by the configured owners
+var DefaultPatterns = []string{"/.github/workflows/", "/.github/actions/"}
+
+type UpdateCodeownersResponse struct {
+	OriginalInput        string
+	FinalOutput          string
+	IsChanged            bool
+	CodeownersFetchError bool
+}
+
+type UpdateCodeownersRequest struct {
+	Owners   []string
+	Patterns []string `json:",omitempty"`
+	Content  string
+}
+
+// normalizePattern returns the pattern in a form that can be compared, e.g. .github/workflows and /.github/workflows/ are the same
+func normalizePattern(pattern string) string {
+	pattern = strings.TrimSuffix(pattern, "**")
+	pattern = strings.TrimPrefix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	return pattern
+}
+
+// isValidOwner returns true if the owner is a user or team (@org/team), or an email address
+func isValidOwner(owner string) bool {
+	if strings.ContainsAny(owner, " \t#") {
+		return false
+	}
+	return (strings.HasPrefix(owner, "@") && len(owner) > 1) || strings.Contains(owner, "@")
+}
+
+// parseLine splits a CODEOWNERS line into the pattern, owners, and trailing comment
+func parseLine(line string) (string, []string, string) {
+	comment := ""
+	if index := strings.Index(line, " #"); index != -1 {
+		comment = line[index:]
+		line = line[:index]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil, comment
+	}
+	return fields[0], fields[1:], comment
+}
+
+func containsOwner(owners []string, owner string) bool {
+	for _, o := range owners {
+		if strings.EqualFold(o, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddCodeowners adds the owners to the rules for each pattern in the CODEOWNERS content.
+// If a rule for a pattern already exists, missing owners are appended to the last such rule, since the last matching rule takes precedence.
+// Otherwise a new rule is appended to the end of the file. Existing rules and comments are left as is.
+func AddCodeowners(content string, owners []string, patterns []string) (string, bool, error) {
+	if len(owners) == 0 {
+		return content, false, fmt.Errorf("at least one owner is required")
+	}
+	for _, owner := range owners {
+		if !isValidOwner(owner) {
+			return content, false, fmt.Errorf("invalid owner %s, must be @user, @org/team or an email address", owner)
+		}
+	}
+	if len(patterns) == 0 {
+		patterns = DefaultPatterns
+	}
+
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	isChanged := false
+	var newLines []string
+	for _, pattern := range patterns {
+		lastRule := -1
+		for i, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			linePattern, _, _ := parseLine(trimmed)
+			if normalizePattern(linePattern) == normalizePattern(pattern) {
+				lastRule = i
+			}
+		}
+
+		if lastRule == -1 {
+			newLines = append(newLines, fmt.Sprintf("%s %s", pattern, strings.Join(owners, " ")))
+			isChanged = true
+			continue
+		}
+
+		linePattern, lineOwners, comment := parseLine(strings.TrimSpace(lines[lastRule]))
+		missing := false
+		for _, owner := range owners {
+			if !containsOwner(lineOwners, owner) {
+				lineOwners = append(lineOwners, owner)
+				missing = true
+			}
+		}
+		if missing {
+			lines[lastRule] = fmt.Sprintf("%s %s%s", linePattern, strings.Join(lineOwners, " "), comment)
+			isChanged = true
+		}
+	}
+
+	if !isChanged {
+		return content, false, nil
+	}
+
+	if len(newLines) > 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "# Changes to GitHub Actions workflows and actions require review")
+		lines = append(lines, newLines...)
+	}
+
+	return strings.Join(lines, "\n") + "\n", true, nil
+}
+
+// UpdateCodeowners is used to add owners for workflow files to the CODEOWNERS file and returns an UpdateCodeownersResponse.
+func UpdateCodeowners(updateCodeownersRequest string) (*UpdateCodeownersResponse, error) {
+	var request UpdateCodeownersRequest
+
+	err := json.Unmarshal([]byte(updateCodeownersRequest), &request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON from codeowners request: %v", err)
+	}
+
+	response := new(UpdateCodeownersResponse)
+	response.OriginalInput = request.Content
+
+	output, isChanged, err := AddCodeowners(request.Content, request.Owners, request.Patterns)
+	if err != nil {
+		return nil, err
+	}
+	response.FinalOutput = output
+	response.IsChanged = isChanged
+
+	return response, nil
+}
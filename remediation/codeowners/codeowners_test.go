@@ -0,0 +1,64 @@
+package codeowners
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"path"
+	"testing"
+)
+
+func TestUpdateCodeowners(t *testing.T) {
+
+	const inputDirectory = "../../testfiles/codeowners/input"
+	const outputDirectory = "../../testfiles/codeowners/output"
+
+	tests := []struct {
+		fileName  string
+		owners    []string
+		isChanged bool
+	}{
+		{fileName: "empty", owners: []string{"@org/security"}, isChanged: true},
+		{fileName: "existing-rules", owners: []string{"@org/security"}, isChanged: true},
+		{fileName: "existing-workflow-rule", owners: []string{"@org/security"}, isChanged: true},
+		{fileName: "already-owned", owners: []string{"@org/security"}, isChanged: false},
+	}
+
+	for _, test := range tests {
+		input, err := ioutil.ReadFile(path.Join(inputDirectory, test.fileName))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		request := UpdateCodeownersRequest{Owners: test.owners, Content: string(input)}
+		inputRequest, err := json.Marshal(request)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		output, err := UpdateCodeowners(string(inputRequest))
+		if err != nil {
+			t.Fatalf("Error not expected: %s", err)
+		}
+
+		expectedOutput, err := ioutil.ReadFile(path.Join(outputDirectory, test.fileName))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if string(expectedOutput) != output.FinalOutput {
+			t.Errorf("test failed %s did not match expected output\n%s", test.fileName, output.FinalOutput)
+		}
+
+		if output.IsChanged != test.isChanged {
+			t.Errorf("test failed %s did not match IsChanged, Expected: %v Got: %v", test.fileName, test.isChanged, output.IsChanged)
+		}
+	}
+}
+
+func TestUpdateCodeownersInvalidOwner(t *testing.T) {
+	_, err := UpdateCodeowners(`{"Owners": ["org/security"], "Content": ""}`)
+	if err == nil {
+		t.Errorf("expected error for owner without @")
+	}
+}
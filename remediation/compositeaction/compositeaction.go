@@ -0,0 +1,176 @@
+package compositeaction
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/lineending"
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/workflow/deprecatedcommands"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/workflow/expressions"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleTokenInput      = "composite-token-input"
+	RuleScriptInjection = "composite-script-injection"
+)
+
+// tokenInputRegex matches the names of inputs that take credentials
+var tokenInputRegex = regexp.MustCompile(`(?i)(token|password|secret|api[-_]?key|credentials)`)
+
+type SecureCompositeActionResponse struct {
+	OriginalInput             string
+	FinalOutput               string
+	Diff                      string `json:",omitempty"`
+	IsChanged                 bool
+	PinnedActions             bool
+	RewroteDeprecatedCommands bool
+	HasErrors                 bool
+	IncorrectYaml             bool
+	ActionFetchError          bool
+	Findings                  []findings.Finding
+}
+
+// IsCompositeAction returns true if the yaml is an action with runs.using set to composite
+func IsCompositeAction(inputYaml string) bool {
+	t := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(inputYaml), &t); err != nil || len(t.Content) == 0 {
+		return false
+	}
+	usingNode := document.MappingValue(document.MappingValue(t.Content[0], "runs"), "using")
+	return usingNode != nil && usingNode.Value == "composite"
+}
+
+// findTokenInputs returns findings for inputs of the composite action that take credentials.
+// Tokens passed by callers usually have more permissions than the action needs, so the GITHUB_TOKEN is suggested as the default.
+func findTokenInputs(topNode *yaml.Node) []findings.Finding {
+	inputsNode := document.MappingValue(topNode, "inputs")
+	if inputsNode == nil || inputsNode.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var tokenFindings []findings.Finding
+	for i := 0; i+1 < len(inputsNode.Content); i += 2 {
+		inputKeyNode, inputNode := inputsNode.Content[i], inputsNode.Content[i+1]
+		if !tokenInputRegex.MatchString(inputKeyNode.Value) {
+			continue
+		}
+		finding := findings.Finding{
+			RuleID:  RuleTokenInput,
+			Message: fmt.Sprintf("Input %s of the composite action takes a credential, callers should pass one with the least privileges needed", inputKeyNode.Value),
+			Line:    inputKeyNode.Line,
+			Column:  inputKeyNode.Column,
+		}
+		if strings.Contains(strings.ToLower(inputKeyNode.Value), "token") && document.MappingValue(inputNode, "default") == nil {
+			finding.Suggestion = "default: ${{ github.token }}"
+		}
+		tokenFindings = append(tokenFindings, finding)
+	}
+	return tokenFindings
+}
+
+// findScriptInjection returns findings for run steps of the composite action that use inputs, or untrusted contexts, in the script.
+// Inputs are set by the workflows that use the action, and may come from untrusted contexts in those workflows.
+func findScriptInjection(topNode *yaml.Node) []findings.Finding {
+	stepsNode := document.MappingValue(document.MappingValue(topNode, "runs"), "steps")
+	if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	var injectionFindings []findings.Finding
+	for _, stepNode := range stepsNode.Content {
+		runKeyNode, runNode := document.MappingEntry(stepNode, "run")
+		if runNode == nil || runNode.Kind != yaml.ScalarNode {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, match := range expressions.ExpressionRegex.FindAllStringSubmatch(runNode.Value, -1) {
+			expression := match[1]
+			if seen[expression] || !(strings.HasPrefix(expression, "inputs.") || expressions.IsUntrusted(expression)) {
+				continue
+			}
+			seen[expression] = true
+			envName := strings.ToUpper(regexp.MustCompile(`[^A-Za-z0-9]+`).ReplaceAllString(expression[strings.LastIndex(expression, ".")+1:], "_"))
+			injectionFindings = append(injectionFindings, findings.Finding{
+				RuleID:     RuleScriptInjection,
+				Message:    fmt.Sprintf("%s is used in a run step of the composite action, and can be used to inject code", expression),
+				Line:       runKeyNode.Line,
+				Column:     runKeyNode.Column,
+				Suggestion: fmt.Sprintf("Pass %s to the step as the environment variable %s", expression, envName),
+			})
+		}
+	}
+	return injectionFindings
+}
+
+// SecureCompositeAction runs the remediations for a composite action (action.yml with runs.using: composite).
+// Nested actions are pinned unless pinActions is false, and deprecated workflow commands are rewritten
+// unless rewriteDeprecatedCommands is false. Token inputs and script injection in run steps are reported as findings.
+// The optional params are the exempted actions ([]string) and whether to pin to immutable actions (bool). A context of
+// the requests to GitHub can be passed in any position.
+func SecureCompositeAction(queryStringParams map[string]string, inputYaml string, params ...interface{}) (*SecureCompositeActionResponse, error) {
+	exemptedActions, pinToImmutable, ctx := []string{}, false, context.Background()
+	for _, param := range params {
+		if v, ok := param.(context.Context); ok && v != nil {
+			ctx = v
+		}
+	}
+	if len(params) > 0 {
+		if v, ok := params[0].([]string); ok {
+			exemptedActions = v
+		}
+	}
+	if len(params) > 1 {
+		if v, ok := params[1].(bool); ok {
+			pinToImmutable = v
+		}
+	}
+
+	// the remediations edit the action with LF line endings, and the output has the line endings of the input
+	response := &SecureCompositeActionResponse{OriginalInput: inputYaml}
+	inputYaml = lineending.Normalize(inputYaml)
+	response.FinalOutput = inputYaml
+
+	t := yaml.Node{}
+	err := yaml.Unmarshal([]byte(inputYaml), &t)
+	if err != nil {
+		response.IncorrectYaml = true
+		response.HasErrors = true
+		return response, nil
+	}
+	if !IsCompositeAction(inputYaml) {
+		return nil, fmt.Errorf("not a composite action, runs.using must be composite")
+	}
+	topNode := t.Content[0]
+
+	response.Findings = append(findTokenInputs(topNode), findScriptInjection(topNode)...)
+
+	if queryStringParams["rewriteDeprecatedCommands"] != "false" {
+		response.FinalOutput, response.RewroteDeprecatedCommands, err = deprecatedcommands.RewriteDeprecatedCommands(response.FinalOutput)
+		if err != nil {
+			logging.Logger().Error("unable to rewrite deprecated commands", "module", "deprecatedcommands", "error", err)
+			response.HasErrors = true
+		}
+	}
+
+	if queryStringParams["pinActions"] != "false" {
+		response.FinalOutput, response.PinnedActions, err = pin.PinActions(ctx, response.FinalOutput, exemptedActions, pinToImmutable, nil)
+		if err != nil {
+			logging.Logger().Error("unable to pin actions", "module", "pin", "error", err)
+			response.HasErrors = true
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	response.IsChanged = response.FinalOutput != inputYaml
+	response.FinalOutput = lineending.Restore(response.OriginalInput, response.FinalOutput)
+	return response, nil
+}
@@ -0,0 +1,73 @@
+package compositeaction
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestSecureCompositeAction(t *testing.T) {
+	const inputDirectory = "../../testfiles/compositeaction/input"
+	const outputDirectory = "../../testfiles/compositeaction/output"
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/setup-node/commits/v1",
+		httpmock.NewStringResponder(200, `56899e050abffc08c2b3b61f3ec6a79a9dc3223d`))
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/setup-node/git/matching-refs/tags",
+		httpmock.NewStringResponder(200,
+			`[
+				{
+					"ref": "refs/tags/v1.4.4",
+					"object": {
+					"sha": "56899e050abffc08c2b3b61f3ec6a79a9dc3223d",
+					"type": "commit"
+					}
+				}
+			]`))
+
+	input, err := ioutil.ReadFile(path.Join(inputDirectory, "action.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+
+	got, err := SecureCompositeAction(map[string]string{}, string(input))
+	if err != nil {
+		t.Fatalf("SecureCompositeAction() unexpected error = %v", err)
+	}
+
+	output, err := ioutil.ReadFile(path.Join(outputDirectory, "action.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+	if got.FinalOutput != string(output) {
+		t.Errorf("SecureCompositeAction() = %v, want %v", got.FinalOutput, string(output))
+	}
+	if !got.IsChanged || !got.PinnedActions || !got.RewroteDeprecatedCommands || got.HasErrors {
+		t.Errorf("SecureCompositeAction() unexpected response: %+v", got)
+	}
+
+	if len(got.Findings) != 3 {
+		t.Fatalf("SecureCompositeAction() returned %d findings, want 3: %v", len(got.Findings), got.Findings)
+	}
+	if got.Findings[0].RuleID != RuleTokenInput || got.Findings[0].Line != 4 || got.Findings[0].Suggestion != "default: ${{ github.token }}" {
+		t.Errorf("unexpected finding for token input: %+v", got.Findings[0])
+	}
+	if got.Findings[1].RuleID != RuleScriptInjection || got.Findings[1].Line != 15 || got.Findings[1].Suggestion != "Pass inputs.version to the step as the environment variable VERSION" {
+		t.Errorf("unexpected finding for input in script: %+v", got.Findings[1])
+	}
+	if got.Findings[2].RuleID != RuleScriptInjection || got.Findings[2].Line != 19 {
+		t.Errorf("unexpected finding for untrusted context in script: %+v", got.Findings[2])
+	}
+}
+
+func TestSecureCompositeActionNotComposite(t *testing.T) {
+	_, err := SecureCompositeAction(map[string]string{}, "runs:\n  using: node20\n  main: index.js\n")
+	if err == nil {
+		t.Errorf("SecureCompositeAction() expected an error for a javascript action")
+	}
+}
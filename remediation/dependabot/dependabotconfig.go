@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	dependabot "github.com/paulvollmer/dependabot-config-go"
+	"github.com/step-security/secure-repo/remediation/textedit"
 	"gopkg.in/yaml.v3"
 )
 
@@ -363,7 +364,7 @@ func insertAfterLine(lines []string, afterLine int, newLines []string) []string
 // preserving any trailing content (e.g. comments) on the same line.
 func replaceScalarOnLine(lines []string, lineIdx int, node *yaml.Node, newVal string) {
 	line := lines[lineIdx]
-	col := node.Column - 1
+	col := textedit.ColumnOffset(line, node.Column)
 	end := col + len(node.Value)
 	// When the original value is quoted, node.Column points to the opening quote
 	// but node.Value is the unquoted content. Account for the surrounding quotes.
@@ -388,7 +389,7 @@ func replaceSequence(lines []string, seqNode *yaml.Node, newValues []string, lin
 	if seqNode.Style == yaml.FlowStyle {
 		lineIdx := seqNode.Line - 1 + lineOffset
 		line := lines[lineIdx]
-		col := seqNode.Column - 1
+		col := textedit.ColumnOffset(line, seqNode.Column)
 		openIdx := strings.Index(line[col:], "[")
 		if openIdx < 0 {
 			return lines, 0, false
@@ -789,6 +789,15 @@ func TestUpdateSubtractiveFields(t *testing.T) {
 			},
 			isChanged: true,
 		},
+		{
+			// Subtractive — the interval and the directories follow values with characters of several bytes on their
+			// lines, whose columns are in characters.
+			fileName: "multibyte-flow-values.yml",
+			ecosystems: []Ecosystem{
+				{PackageEcosystem: "github-actions", Directory: "/ünits", Interval: "weekly"},
+			},
+			isChanged: true,
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,247 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change, as in git diff
+const contextLines = 3
+
+type operation struct {
+	kind byte
+	line string
+}
+
+// splitLines splits the content into lines, keeping the newline at the end of each line
+func splitLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// getOperations returns the lines of before and after, marked as unchanged, removed or added, using the longest common subsequence
+func getOperations(before, after []string) []operation {
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var operations []operation
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			operations = append(operations, operation{' ', before[i]})
+			i++
+			j++
+		case j == len(after) || (i < len(before) && lcs[i+1][j] >= lcs[i][j+1]):
+			operations = append(operations, operation{'-', before[i]})
+			i++
+		default:
+			operations = append(operations, operation{'+', after[j]})
+			j++
+		}
+	}
+	return operations
+}
+
+// getRange returns the range of a hunk in the format of the hunk header. Empty ranges start at the line before the hunk.
+func getRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// Unified returns the changes from before to after as a unified diff for the file, which can be applied with git apply.
+// An empty string is returned if the content is the same. New files are diffed against /dev/null.
+func Unified(filePath, before, after string) string {
+	if before == after {
+		return ""
+	}
+
+	// the newline at the end of the file is compared separately, so the last line is reported if it is added or removed
+	beforeLines, afterLines := splitLines(before), splitLines(after)
+	if len(beforeLines) > 0 && !strings.HasSuffix(before, "\n") {
+		beforeLines[len(beforeLines)-1] += "\n\\ No newline at end of file\n"
+	}
+	if len(afterLines) > 0 && !strings.HasSuffix(after, "\n") {
+		afterLines[len(afterLines)-1] += "\n\\ No newline at end of file\n"
+	}
+	operations := getOperations(beforeLines, afterLines)
+
+	var sb strings.Builder
+	if before == "" {
+		sb.WriteString("--- /dev/null\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("--- a/%s\n", filePath))
+	}
+	sb.WriteString(fmt.Sprintf("+++ b/%s\n", filePath))
+
+	for start := 0; start < len(operations); {
+		if operations[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		// the hunk is extended until there are more than twice the context lines without changes
+		first := start - contextLines
+		if first < 0 {
+			first = 0
+		}
+		end := start
+		for unchanged := 0; end < len(operations) && unchanged <= 2*contextLines; end++ {
+			if operations[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		last := end
+		for last > start && operations[last-1].kind == ' ' {
+			last--
+		}
+		last += contextLines
+		if last > len(operations) {
+			last = len(operations)
+		}
+
+		beforeStart, afterStart := 1, 1
+		for _, op := range operations[:first] {
+			if op.kind != '+' {
+				beforeStart++
+			}
+			if op.kind != '-' {
+				afterStart++
+			}
+		}
+		beforeCount, afterCount := 0, 0
+		for _, op := range operations[first:last] {
+			if op.kind != '+' {
+				beforeCount++
+			}
+			if op.kind != '-' {
+				afterCount++
+			}
+		}
+
+		sb.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", getRange(beforeStart, beforeCount), getRange(afterStart, afterCount)))
+		for _, op := range operations[first:last] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+		}
+		start = last
+	}
+
+	return sb.String()
+}
+
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Modified = "modified"
+)
+
+// LineChange is a line that was added, removed or modified. Line is the line number in after,
+// except for removed lines, where it is the line number in before.
+type LineChange struct {
+	Line   int
+	Kind   string
+	Before string
+	After  string
+}
+
+// LineChanges returns the lines changed from before to after. Removed lines followed by added lines are
+// reported as modified, pairing them in order, and the rest of the lines as removed or added.
+func LineChanges(before, after string) []LineChange {
+	if before == after {
+		return nil
+	}
+	operations := getOperations(splitLines(before), splitLines(after))
+
+	var changes []LineChange
+	beforeLine, afterLine := 1, 1
+	for i := 0; i < len(operations); {
+		if operations[i].kind == ' ' {
+			beforeLine++
+			afterLine++
+			i++
+			continue
+		}
+		var removed, added []string
+		for ; i < len(operations) && operations[i].kind == '-'; i++ {
+			removed = append(removed, strings.TrimSuffix(operations[i].line, "\n"))
+		}
+		for ; i < len(operations) && operations[i].kind == '+'; i++ {
+			added = append(added, strings.TrimSuffix(operations[i].line, "\n"))
+		}
+		for j := 0; j < len(removed) || j < len(added); j++ {
+			switch {
+			case j < len(removed) && j < len(added):
+				changes = append(changes, LineChange{Line: afterLine, Kind: Modified, Before: removed[j], After: added[j]})
+				beforeLine++
+				afterLine++
+			case j < len(removed):
+				changes = append(changes, LineChange{Line: beforeLine, Kind: Removed, Before: removed[j]})
+				beforeLine++
+			default:
+				changes = append(changes, LineChange{Line: afterLine, Kind: Added, After: added[j]})
+				afterLine++
+			}
+		}
+	}
+	return changes
+}
+
+// Hunk is a block of consecutive changed lines, without context. Removed lines starting at Line in before are replaced
+// with Inserted. If no lines are removed, Inserted is added before Line.
+type Hunk struct {
+	Line     int
+	Removed  int
+	Inserted string
+}
+
+// Hunks returns the blocks of changed lines from before to after, in order
+func Hunks(before, after string) []Hunk {
+	if before == after {
+		return nil
+	}
+	operations := getOperations(splitLines(before), splitLines(after))
+
+	var hunks []Hunk
+	beforeLine := 1
+	for i := 0; i < len(operations); {
+		if operations[i].kind == ' ' {
+			beforeLine++
+			i++
+			continue
+		}
+		hunk := Hunk{Line: beforeLine}
+		var inserted strings.Builder
+		for ; i < len(operations) && operations[i].kind != ' '; i++ {
+			if operations[i].kind == '-' {
+				hunk.Removed++
+			} else {
+				inserted.WriteString(operations[i].line)
+			}
+		}
+		hunk.Inserted = inserted.String()
+		beforeLine += hunk.Removed
+		hunks = append(hunks, hunk)
+	}
+	return hunks
+}
@@ -0,0 +1,101 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   string
+		after    string
+		expected string
+	}{
+		{
+			name:     "no changes",
+			before:   "a\nb\n",
+			after:    "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "changed line",
+			before:   "a\nb\nc\n",
+			after:    "a\nB\nc\n",
+			expected: "--- a/file.yml\n+++ b/file.yml\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name:     "new file",
+			before:   "",
+			after:    "a\n",
+			expected: "--- /dev/null\n+++ b/file.yml\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+		{
+			name:     "no newline at end of file",
+			before:   "a\nb",
+			after:    "a\nb\n",
+			expected: "--- a/file.yml\n+++ b/file.yml\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name:     "separate hunks",
+			before:   "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			after:    "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			expected: "--- a/file.yml\n+++ b/file.yml\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,3 +9,4 @@\n 9\n 10\n 11\n+12\n",
+		},
+	}
+
+	for _, test := range tests {
+		got := Unified("file.yml", test.before, test.after)
+		if got != test.expected {
+			t.Errorf("%s: Unified() = %q, want %q", test.name, got, test.expected)
+		}
+	}
+}
+
+func TestLineChanges(t *testing.T) {
+	before := "a\nb\nc\nd\n"
+	after := "a\nB\nc\nc2\nc3\n"
+	expected := []LineChange{
+		{Line: 2, Kind: Modified, Before: "b", After: "B"},
+		{Line: 4, Kind: Modified, Before: "d", After: "c2"},
+		{Line: 5, Kind: Added, After: "c3"},
+	}
+
+	changes := LineChanges(before, after)
+	if len(changes) != len(expected) {
+		t.Fatalf("LineChanges() = %v, want %v", changes, expected)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("LineChanges()[%d] = %v, want %v", i, changes[i], expected[i])
+		}
+	}
+
+	changes = LineChanges("a\nb\nc\n", "a\nc\n")
+	if len(changes) != 1 || changes[0] != (LineChange{Line: 2, Kind: Removed, Before: "b"}) {
+		t.Errorf("LineChanges() = %v, want line 2 removed", changes)
+	}
+	if changes := LineChanges("a\n", "a\n"); changes != nil {
+		t.Errorf("LineChanges() = %v, want no changes", changes)
+	}
+}
+
+func TestHunks(t *testing.T) {
+	before := "a\nb\nc\nd\n"
+	after := "x\na\nB\nc\n"
+	expected := []Hunk{
+		{Line: 1, Removed: 0, Inserted: "x\n"},
+		{Line: 2, Removed: 1, Inserted: "B\n"},
+		{Line: 4, Removed: 1, Inserted: ""},
+	}
+
+	hunks := Hunks(before, after)
+	if len(hunks) != len(expected) {
+		t.Fatalf("Hunks() = %v, want %v", hunks, expected)
+	}
+	for i := range expected {
+		if hunks[i] != expected[i] {
+			t.Errorf("Hunks()[%d] = %v, want %v", i, hunks[i], expected[i])
+		}
+	}
+	if hunks := Hunks("a\n", "a\n"); hunks != nil {
+		t.Errorf("Hunks() = %v, want no changes", hunks)
+	}
+}
@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/asottile/dockerfile"
+)
+
+// NonRootUser is the name of the user created when the base image does not have a non-root user
+const NonRootUser = "nonroot"
+
+// NonRootUID is used when the base image is not known, since a numeric user does not need to exist in /etc/passwd
+const NonRootUID = "65532:65532"
+
+// baseImageUsers are base images that already ship with a non-root user
+var baseImageUsers = map[string]string{
+	"node":                     "node",
+	"gcr.io/distroless":        "nonroot",
+	"cgr.dev/chainguard":       "nonroot",
+	"mcr.microsoft.com/dotnet": "app",
+}
+
+// isRootUser returns true if the value of a USER instruction refers to root
+func isRootUser(user string) bool {
+	name := strings.Split(user, ":")[0]
+	return name == "root" || name == "0"
+}
+
+// getImageName returns the image without the tag or digest, e.g. python for python:3.7@sha256:...
+func getImageName(image string) string {
+	image = strings.Split(image, "@")[0]
+	lastSlash := strings.LastIndex(image, "/")
+	if colon := strings.LastIndex(image, ":"); colon > lastSlash {
+		image = image[:colon]
+	}
+	return strings.TrimPrefix(image, "docker.io/")
+}
+
+// getUserInstructions returns the instructions to add to switch to a non-root user, based on the base image
+func getUserInstructions(image string) []string {
+	imageName := getImageName(image)
+	baseName := strings.TrimPrefix(imageName, "library/")
+
+	for prefix, user := range baseImageUsers {
+		if baseName == prefix || strings.HasPrefix(baseName, prefix+"/") {
+			return []string{fmt.Sprintf("USER %s", user)}
+		}
+	}
+
+	switch {
+	case baseName == "alpine" || baseName == "busybox" || strings.Contains(image, "alpine"):
+		return []string{
+			fmt.Sprintf("RUN addgroup -S %s && adduser -S %s -G %s", NonRootUser, NonRootUser, NonRootUser),
+			fmt.Sprintf("USER %s", NonRootUser),
+		}
+	case baseName == "debian" || baseName == "ubuntu" || baseName == "python" || baseName == "golang" ||
+		baseName == "ruby" || baseName == "openjdk" || baseName == "eclipse-temurin" || baseName == "php":
+		return []string{
+			fmt.Sprintf("RUN groupadd --system %s && useradd --system --gid %s --no-create-home %s", NonRootUser, NonRootUser, NonRootUser),
+			fmt.Sprintf("USER %s", NonRootUser),
+		}
+	}
+
+	return []string{fmt.Sprintf("USER %s", NonRootUID)}
+}
+
+// AddNonRootUser adds a USER instruction to the final stage of the Dockerfile, if it does not already switch to a non-root user.
+// For common base images, the user is created before switching to it.
+// The instructions are added before the CMD/ENTRYPOINT of the final stage, or at the end if there are none.
+func AddNonRootUser(inputDockerFile string) (string, bool, error) {
+	cmds, err := dockerfile.ParseReader(strings.NewReader(inputDockerFile))
+	if err != nil {
+		return inputDockerFile, false, err
+	}
+
+	// find the final stage, and resolve the base image through stage names
+	stageImages := make(map[string]string)
+	finalStage := -1
+	finalImage := ""
+	for i, c := range cmds {
+		if !strings.EqualFold(c.Cmd, "FROM") || len(c.Value) == 0 {
+			continue
+		}
+		image := c.Value[0]
+		if baseImage, found := stageImages[strings.ToLower(image)]; found {
+			image = baseImage
+		}
+		if len(c.Value) == 3 && strings.EqualFold(c.Value[1], "AS") {
+			stageImages[strings.ToLower(c.Value[2])] = image
+		}
+		finalStage = i
+		finalImage = image
+	}
+
+	if finalStage == -1 {
+		return inputDockerFile, false, nil
+	}
+
+	insertBefore := -1
+	nonRoot := false
+	for _, c := range cmds[finalStage+1:] {
+		switch strings.ToUpper(c.Cmd) {
+		case "USER":
+			nonRoot = len(c.Value) > 0 && !isRootUser(c.Value[0])
+			insertBefore = -1
+		case "CMD", "ENTRYPOINT":
+			if insertBefore == -1 {
+				insertBefore = c.StartLine - 1
+			}
+		case "RUN", "COPY", "ADD":
+			// instructions that may need root must stay before the USER instruction
+			insertBefore = -1
+		}
+	}
+
+	if nonRoot {
+		return inputDockerFile, false, nil
+	}
+
+	inputLines := strings.Split(inputDockerFile, "\n")
+	userInstructions := getUserInstructions(finalImage)
+
+	var output []string
+	if insertBefore == -1 {
+		// append at the end, keeping the trailing newline if any
+		output = inputLines
+		if len(output) > 0 && output[len(output)-1] == "" {
+			output = append(output[:len(output)-1], append(userInstructions, "")...)
+		} else {
+			output = append(output, userInstructions...)
+		}
+	} else {
+		output = append(output, inputLines[:insertBefore]...)
+		output = append(output, userInstructions...)
+		output = append(output, inputLines[insertBefore:]...)
+	}
+
+	return strings.Join(output, "\n"), true, nil
+}
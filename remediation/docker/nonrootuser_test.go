@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"io/ioutil"
+	"log"
+	"path"
+	"testing"
+)
+
+func TestAddNonRootUser(t *testing.T) {
+	const inputDirectory = "../../testfiles/nonrootuser/input"
+	const outputDirectory = "../../testfiles/nonrootuser/output"
+
+	tests := []struct {
+		fileName  string
+		isChanged bool
+	}{
+		{fileName: "Dockerfile-python", isChanged: true},
+		{fileName: "Dockerfile-alpine-multistage", isChanged: true},
+		{fileName: "Dockerfile-node", isChanged: true},
+		{fileName: "Dockerfile-root-user", isChanged: true},
+		{fileName: "Dockerfile-unknown-image", isChanged: true},
+		{fileName: "Dockerfile-scratch", isChanged: true},
+		{fileName: "Dockerfile-non-root", isChanged: false},
+	}
+
+	for _, test := range tests {
+		input, err := ioutil.ReadFile(path.Join(inputDirectory, test.fileName))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		output, isChanged, err := AddNonRootUser(string(input))
+		if err != nil {
+			t.Fatalf("Error not expected: %s", err)
+		}
+
+		expectedOutput, err := ioutil.ReadFile(path.Join(outputDirectory, test.fileName))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if string(expectedOutput) != output {
+			t.Errorf("test failed %s did not match expected output\n%s", test.fileName, output)
+		}
+
+		if isChanged != test.isChanged {
+			t.Errorf("test failed %s did not match IsChanged, Expected: %v Got: %v", test.fileName, test.isChanged, isChanged)
+		}
+	}
+}
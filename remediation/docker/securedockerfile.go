@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,23 +10,32 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/step-security/secure-repo/remediation/cache"
+	"github.com/step-security/secure-repo/remediation/lineending"
+	"github.com/step-security/secure-repo/remediation/outbound"
 	"github.com/step-security/secure-repo/remediation/workflow/pin"
 )
 
-var Tr http.RoundTripper = remote.DefaultTransport
+var Tr http.RoundTripper = outbound.Default()
 
 type SecureDockerfileResponse struct {
 	OriginalInput        string
 	FinalOutput          string
+	Diff                 string `json:",omitempty"`
 	IsChanged            bool
 	DockerfileFetchError bool
+	AddedNonRootUser     bool
 }
 
 type DockerfileConfig struct {
 	ExemptedImages []string
+	AddNonRootUser bool
 }
 
-func SecureDockerFile(inputDockerFile string, opts ...DockerfileConfig) (*SecureDockerfileResponse, error) {
+func SecureDockerFile(ctx context.Context, inputDockerFile string, opts ...DockerfileConfig) (*SecureDockerfileResponse, error) {
+	// the instructions are replaced in the Dockerfile with LF line endings, and the output has the line endings of the input
+	originalInput := inputDockerFile
+	inputDockerFile = lineending.Normalize(inputDockerFile)
 	reader := strings.NewReader(inputDockerFile)
 	cmds, err := dockerfile.ParseReader(reader)
 	if err != nil {
@@ -34,13 +44,15 @@ func SecureDockerFile(inputDockerFile string, opts ...DockerfileConfig) (*Secure
 
 	response := new(SecureDockerfileResponse)
 	response.FinalOutput = inputDockerFile
-	response.OriginalInput = inputDockerFile
+	response.OriginalInput = originalInput
 	response.IsChanged = false
 
 	// Get exempted images list, default to empty if no config provided
 	var exemptedImages []string
+	addNonRootUser := false
 	if len(opts) > 0 {
 		exemptedImages = opts[0].ExemptedImages
+		addNonRootUser = opts[0].AddNonRootUser
 	}
 
 	for _, c := range cmds {
@@ -83,7 +95,7 @@ func SecureDockerFile(inputDockerFile string, opts ...DockerfileConfig) (*Secure
 			}
 
 			if !isPinned {
-				sha, err := getSHA(image, tag)
+				sha, err := getSHA(ctx, image, tag)
 				if err != nil {
 					return nil, err
 				}
@@ -98,18 +110,57 @@ func SecureDockerFile(inputDockerFile string, opts ...DockerfileConfig) (*Secure
 		}
 	}
 
+	if addNonRootUser {
+		output, added, err := AddNonRootUser(response.FinalOutput)
+		if err != nil {
+			return nil, err
+		}
+		response.FinalOutput = output
+		response.AddedNonRootUser = added
+		response.IsChanged = response.IsChanged || added
+	}
+	response.FinalOutput = lineending.Restore(originalInput, response.FinalOutput)
+
 	return response, nil
 }
-func getSHA(image string, tag string) (string, error) {
+
+// GetDigest returns the digest of the image, e.g. sha256:..., for the latest tag if the image has no tag
+func GetDigest(ctx context.Context, image string) (string, error) {
+	return getSHA(ctx, image, "latest")
+}
+
+// PinImage returns the image pinned to its digest, keeping the tag for readability. The latest tag is made explicit for
+// an image without a tag, so the pinned digest is of a known tag.
+func PinImage(ctx context.Context, image string) (string, error) {
+	digest, err := GetDigest(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("unable to get digest of %s: %v", image, err)
+	}
+	if !strings.Contains(image, ":") || strings.LastIndex(image, ":") < strings.LastIndex(image, "/") {
+		return image + ":latest@" + digest, nil
+	}
+	return image + "@" + digest, nil
+}
+
+// getSHA returns the digest of the image, which is kept in the response cache, so the instances sharing it look up the
+// digest of a tag once until it expires
+func getSHA(ctx context.Context, image string, tag string) (string, error) {
 
 	ref, err := name.ParseReference(image, name.WithDefaultTag(tag))
 	if err != nil {
 		return "", err
 	}
-	desc, err := remote.Get(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(Tr))
+	digest, err := cache.Do(cache.Default(), cache.Key("image-digest", ref.Name()), func() (*string, error) {
+		desc, err := remote.Get(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(Tr))
+		if err != nil {
+			return nil, err
+		}
+		digest := desc.Digest.String()
+		return &digest, nil
+	})
 
 	if err != nil {
 		return "", err
 	}
-	return desc.Digest.String(), nil
+	return *digest, nil
 }
@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"context"
 	"io/ioutil"
 	"log"
 	"path"
@@ -74,9 +75,9 @@ func TestSecureDockerFile(t *testing.T) {
 			config := DockerfileConfig{
 				ExemptedImages: test.exemptedImages,
 			}
-			output, err = SecureDockerFile(string(input), config)
+			output, err = SecureDockerFile(context.Background(), string(input), config)
 		} else {
-			output, err = SecureDockerFile(string(input))
+			output, err = SecureDockerFile(context.Background(), string(input))
 		}
 		if err != nil {
 			t.Fatalf("Error not expected: %s", err)
@@ -139,7 +140,7 @@ func Test_getSHA(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getSHA(tt.args.image, tt.args.tag)
+			got, err := getSHA(context.Background(), tt.args.image, tt.args.tag)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getSHA() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -150,3 +151,30 @@ func Test_getSHA(t *testing.T) {
 		})
 	}
 }
+
+func TestPinImage(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	saveTr := Tr
+	defer func() { Tr = saveTr }()
+	Tr = httpmock.DefaultTransport
+
+	httpmock.RegisterResponder("GET", "https://index.docker.io/v2/", httpmock.NewStringResponder(200, `{}`))
+	httpmock.RegisterResponder("GET", "https://index.docker.io/v2/library/python/manifests/3.7", httpmock.NewStringResponder(200, resp))
+	httpmock.RegisterResponder("GET", "https://index.docker.io/v2/library/python/manifests/latest", httpmock.NewStringResponder(200, resp))
+
+	const digest = "sha256:5fb6f4b9d73ddeb0e431c938bee25c69157a1e3c880a81ff72c43a8055628de5"
+	for image, want := range map[string]string{
+		"python:3.7": "python:3.7@" + digest,
+		// the latest tag is made explicit
+		"python": "python:latest@" + digest,
+	} {
+		if got, err := PinImage(context.Background(), image); err != nil || got != want {
+			t.Errorf("PinImage(%s) = %s, %v, want %s", image, got, err, want)
+		}
+	}
+	if _, err := PinImage(context.Background(), "python:missing"); err == nil {
+		t.Errorf("PinImage() expected an error for a missing tag")
+	}
+}
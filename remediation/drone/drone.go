@@ -0,0 +1,153 @@
+package drone
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/multidoc"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigPath is the path of the configuration of Drone
+const ConfigPath = ".drone.yml"
+
+const (
+	RuleUnpinnedImage = "drone-unpinned-image"
+	RulePrivileged    = "drone-privileged-step"
+)
+
+// SecureConfigResponse is the result of the remediations of a Drone configuration
+type SecureConfigResponse struct {
+	OriginalInput string
+	FinalOutput   string
+	IsChanged     bool
+	PinnedImages  bool
+	Findings      []findings.Finding
+}
+
+// step is a step or a service of a pipeline, with the name of the pipeline
+type step struct {
+	node     *yaml.Node
+	pipeline string
+}
+
+// getSteps returns the steps and services of the pipelines that run in containers, i.e. docker and kubernetes
+// pipelines. Pipelines of other types, e.g. exec, and the other kinds of documents, e.g. secrets, have no images.
+func getSteps(documents []*yaml.Node) []step {
+	var steps []step
+	for _, topNode := range documents {
+		kind, pipelineType := document.MappingValue(topNode, "kind"), document.MappingValue(topNode, "type")
+		if kind == nil || kind.Value != "pipeline" {
+			continue
+		}
+		if pipelineType != nil && pipelineType.Value != "docker" && pipelineType.Value != "kubernetes" {
+			continue
+		}
+		name := "default"
+		if nameNode := document.MappingValue(topNode, "name"); nameNode != nil {
+			name = nameNode.Value
+		}
+		for _, node := range append(document.Sequence(document.MappingValue(topNode, "steps")), document.Sequence(document.MappingValue(topNode, "services"))...) {
+			if node.Kind == yaml.MappingNode {
+				steps = append(steps, step{node: node, pipeline: name})
+			}
+		}
+	}
+	return steps
+}
+
+func getStepName(s step) string {
+	if nameNode := document.MappingValue(s.node, "name"); nameNode != nil {
+		return nameNode.Value
+	}
+	return ""
+}
+
+// findImageEdits returns the edits that pin the images of the steps, which are the images of plugins for steps with
+// settings, and of the services to their digest, and the findings of the images set with variables
+func findImageEdits(ctx context.Context, steps []step) ([]textedit.Replacement, []findings.Finding, error) {
+	var edits []textedit.Replacement
+	var imageFindings []findings.Finding
+	for _, s := range steps {
+		image := document.MappingValue(s.node, "image")
+		if image == nil || image.Kind != yaml.ScalarNode || image.Value == "" || strings.Contains(image.Value, "@") {
+			continue
+		}
+		if strings.Contains(image.Value, "$") {
+			imageFindings = append(imageFindings, findings.Finding{
+				RuleID:     RuleUnpinnedImage,
+				Message:    fmt.Sprintf("Image %s of step %s is set with a variable, so it cannot be pinned to a digest", image.Value, getStepName(s)),
+				JobName:    s.pipeline,
+				Action:     image.Value,
+				Line:       image.Line,
+				Column:     image.Column,
+				Suggestion: "Set the image with its digest, e.g. plugins/docker:20@sha256:...",
+			})
+			continue
+		}
+		pinned, err := docker.PinImage(ctx, image.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		edits = append(edits, textedit.Replacement{Line: image.Line, Column: image.Column, Old: image.Value, New: pinned})
+	}
+	return edits, imageFindings, nil
+}
+
+// findPrivilegedSteps returns findings for the steps and services that run privileged, which have access to the host
+// of the runner and its docker daemon
+func findPrivilegedSteps(steps []step) []findings.Finding {
+	var privilegedFindings []findings.Finding
+	for _, s := range steps {
+		privileged := document.MappingValue(s.node, "privileged")
+		if privileged == nil || privileged.Value != "true" {
+			continue
+		}
+		name := getStepName(s)
+		privilegedFindings = append(privilegedFindings, findings.Finding{
+			RuleID:     RulePrivileged,
+			Message:    fmt.Sprintf("Step %s of pipeline %s runs privileged, so it has access to the host of the runner", name, s.pipeline),
+			JobName:    s.pipeline,
+			Action:     name,
+			Line:       privileged.Line,
+			Column:     privileged.Column,
+			Suggestion: "Remove privileged, or build images with a plugin that does not need it, e.g. plugins/kaniko",
+		})
+	}
+	return privilegedFindings
+}
+
+// SecureConfig runs the remediations for a Drone configuration. The images of the steps and services of the pipelines
+// are pinned to their digest unless pinImages is false. Images set with variables and privileged steps are reported
+// as findings.
+func SecureConfig(ctx context.Context, queryStringParams map[string]string, inputYaml string) (*SecureConfigResponse, error) {
+	response := &SecureConfigResponse{OriginalInput: inputYaml, FinalOutput: inputYaml}
+	documents, err := multidoc.Mappings(inputYaml)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	steps := getSteps(documents)
+
+	var edits []textedit.Replacement
+	if queryStringParams["pinImages"] != "false" {
+		imageEdits, imageFindings, err := findImageEdits(ctx, steps)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, imageEdits...)
+		response.PinnedImages = len(imageEdits) > 0
+		response.Findings = append(response.Findings, imageFindings...)
+	}
+	response.Findings = append(response.Findings, findPrivilegedSteps(steps)...)
+
+	if len(edits) > 0 {
+		response.FinalOutput = textedit.ApplyReplacements(inputYaml, edits)
+		response.IsChanged = true
+	}
+	return response, nil
+}
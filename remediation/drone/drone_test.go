@@ -0,0 +1,103 @@
+package drone
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/internal/testutil"
+)
+
+func TestSecureConfig(t *testing.T) {
+	digest := testutil.MockRegistry(t, "library/golang/manifests/1.22", "plugins/docker/manifests/latest", "library/postgres/manifests/15")
+
+	input := `kind: pipeline
+type: docker
+name: default
+
+steps:
+  - name: test
+    image: golang:1.22
+    commands:
+      - go test ./...
+  - name: publish
+    image: plugins/docker
+    privileged: true
+    settings:
+      repo: acme/app
+  - name: notify
+    image: ${NOTIFY_IMAGE}
+
+services:
+  - name: database
+    image: postgres:15
+---
+kind: pipeline
+type: exec
+name: release
+
+steps:
+  - name: release
+    commands:
+      - make release
+---
+kind: secret
+name: token
+get:
+  path: secret/data/drone
+`
+	want := `kind: pipeline
+type: docker
+name: default
+
+steps:
+  - name: test
+    image: golang:1.22@` + digest + `
+    commands:
+      - go test ./...
+  - name: publish
+    image: plugins/docker:latest@` + digest + `
+    privileged: true
+    settings:
+      repo: acme/app
+  - name: notify
+    image: ${NOTIFY_IMAGE}
+
+services:
+  - name: database
+    image: postgres:15@` + digest + `
+---
+kind: pipeline
+type: exec
+name: release
+
+steps:
+  - name: release
+    commands:
+      - make release
+---
+kind: secret
+name: token
+get:
+  path: secret/data/drone
+`
+	response, err := SecureConfig(context.Background(), map[string]string{}, input)
+	if err != nil {
+		t.Fatalf("SecureConfig() returned error: %v", err)
+	}
+	if !response.IsChanged || !response.PinnedImages || response.FinalOutput != want {
+		t.Errorf("SecureConfig() = %+v,\n%s\nwant\n%s", response, response.FinalOutput, want)
+	}
+	var got []string
+	for _, finding := range response.Findings {
+		got = append(got, fmt.Sprintf("%s:%d:%s:%s", finding.RuleID, finding.Line, finding.JobName, finding.Action))
+	}
+	if fmt.Sprint(got) != "[drone-unpinned-image:16:default:${NOTIFY_IMAGE} drone-privileged-step:12:default:publish]" {
+		t.Errorf("findings = %v, want the image set with a variable and the privileged step", got)
+	}
+
+	response, err = SecureConfig(context.Background(), map[string]string{}, want)
+	if err != nil || response.IsChanged || response.FinalOutput != want {
+		t.Errorf("expected pinned config to be unchanged, got %+v, %v", response, err)
+	}
+}
@@ -0,0 +1,15 @@
+package findings
+
+// Finding is an issue detected in a file, along with where it was found.
+// Findings are reported whether or not the issue was fixed.
+type Finding struct {
+	RuleID  string
+	Message string
+	JobName string
+	Action  string
+	Line    int
+	// Column is in characters, as the columns of the YAML parser, from 1
+	Column     int
+	Suggestion string
+	Fixed      bool
+}
@@ -0,0 +1,18 @@
+package findings
+
+// MarkFixed marks the findings for the original file as fixed if the same issue is not found in the remediated file.
+// Findings are matched by rule, job and action, since the lines change when the file is remediated.
+func MarkFixed(original, remaining []Finding) {
+	type key struct {
+		ruleID, jobName, action string
+	}
+	found := make(map[key]bool)
+	for _, finding := range remaining {
+		found[key{finding.RuleID, finding.JobName, finding.Action}] = true
+	}
+	for i, finding := range original {
+		if !found[key{finding.RuleID, finding.JobName, finding.Action}] {
+			original[i].Fixed = true
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package githubapp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/storage"
+)
+
+const (
+	// CampaignsTableEnv is the DynamoDB table of the campaigns and the results of their repositories
+	CampaignsTableEnv = "CAMPAIGNS_TABLE"
+	// TokenHeader has the installation token of the organization a campaign is run for
+	TokenHeader = "X-GitHub-Token"
+
+	DefaultCampaignBatchSize = 10
+	MaxCampaignBatchSize     = 50
+	MaxCampaignShards        = 100
+	// campaignLeaseDuration is how long a run holds the lease of a shard after it saved its progress, which is longer
+	// than the timeout of the function, so the shard of a run that was stopped can be run again after it
+	campaignLeaseDuration = 15 * time.Minute
+
+	CampaignStatusRunning   = "running"
+	CampaignStatusCompleted = "completed"
+)
+
+// ErrShardRunning is returned when the shard of a request is being run by another request
+var ErrShardRunning = errors.New("shard of the campaign is being run by another request")
+
+// CampaignRequest starts a campaign, or runs the next batch of the campaign with the ID. Params are the query parameters
+// of /v1/secure-repo, which enable the remediations, and with dryRun=true the repositories are only evaluated. A campaign
+// is run for all repositories of the installation, or for the Repositories, e.g. octo-org/app. A new campaign is split
+// into Shards, 1 by default, and each request runs the next batch of its Shard, so the shards can be run by concurrent
+// requests.
+type CampaignRequest struct {
+	ID           string            `json:",omitempty"`
+	Params       map[string]string `json:",omitempty"`
+	Repositories []string          `json:",omitempty"`
+	BatchSize    int               `json:",omitempty"`
+	Shards       int               `json:",omitempty"`
+	Shard        int               `json:",omitempty"`
+}
+
+// Campaign rolls out the remediations to the repositories of an organization, a batch at a time. The repositories are
+// listed when the campaign starts, and split into Shards. Processed, Changed, PullRequests and Failed are the totals of
+// the shards. Results are the results of the batch that was run, or of all processed repositories for its status.
+type Campaign struct {
+	ID           string
+	Status       string
+	Params       map[string]string `json:",omitempty"`
+	Repositories []string
+	Processed    int
+	Changed      int
+	PullRequests int
+	Failed       int
+	Shards       []CampaignShard `json:",omitempty"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Results      []RepositoryResult `json:",omitempty"`
+}
+
+// CampaignShard is the progress of a shard of a campaign, which remediates the repositories from Start up to End in
+// order. Next is the index of the next repository, which is saved after each repository, so the shard is resumed from
+// it. A run of the shard holds its lease until LeaseExpiresAt, so a concurrent request for the same shard fails instead
+// of remediating the same repositories.
+type CampaignShard struct {
+	Index          int
+	Start          int
+	End            int
+	Next           int
+	Changed        int
+	PullRequests   int
+	Failed         int
+	LeaseID        string `json:",omitempty"`
+	LeaseExpiresAt time.Time
+	UpdatedAt      time.Time
+}
+
+// CampaignStore stores the campaigns, the results of their repositories and the progress of their shards
+type CampaignStore interface {
+	// GetCampaign returns the campaign without its results and shards, or nil if there is no campaign with the id
+	GetCampaign(id string) (*Campaign, error)
+	SaveCampaign(campaign *Campaign) error
+	// AddResult stores the result of the repository with the index
+	AddResult(id string, index int, result RepositoryResult) error
+	// GetResults returns the results of the processed repositories, in the order of the repositories
+	GetResults(id string) ([]RepositoryResult, error)
+	// GetShards returns the shards of the campaign in order, which are none for a campaign started before the campaigns
+	// were sharded
+	GetShards(id string) ([]CampaignShard, error)
+	// SaveShard stores the shard unless the stored shard is leased by another run until after now, and returns false if
+	// it is
+	SaveShard(id string, shard *CampaignShard, now time.Time) (bool, error)
+}
+
+// NewCampaignStoreFromEnv returns the store of the table in CAMPAIGNS_TABLE, or of the storage of STORAGE_URL if the
+// table is not set, or nil if campaigns are not configured
+func NewCampaignStoreFromEnv(svc dynamodbiface.DynamoDBAPI) (CampaignStore, error) {
+	if tableName := os.Getenv(CampaignsTableEnv); tableName != "" {
+		return &DynamoDBCampaignStore{TableName: tableName, Svc: svc}, nil
+	}
+	store, err := storage.Default()
+	if err != nil || store == nil {
+		return nil, err
+	}
+	return &StorageCampaignStore{Store: store}, nil
+}
+
+// setTotals sets the totals of the campaign to the sums of its shards
+func (c *Campaign) setTotals() {
+	c.Processed, c.Changed, c.PullRequests, c.Failed = 0, 0, 0, 0
+	for _, shard := range c.Shards {
+		c.Processed += shard.Next - shard.Start
+		c.Changed += shard.Changed
+		c.PullRequests += shard.PullRequests
+		c.Failed += shard.Failed
+		if shard.UpdatedAt.After(c.UpdatedAt) {
+			c.UpdatedAt = shard.UpdatedAt
+		}
+	}
+	c.setStatus()
+}
+
+func (c *Campaign) setStatus() {
+	if c.Processed >= len(c.Repositories) {
+		c.Status = CampaignStatusCompleted
+	} else {
+		c.Status = CampaignStatusRunning
+	}
+}
+
+// listRepositories returns the full names of the repositories of the installation that are not archived, in order
+func listRepositories(ctx context.Context, client *github.Client) ([]string, error) {
+	var repositories []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, response, err := client.Apps.ListRepos(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list repositories: %v", err)
+		}
+		for _, repository := range list.Repositories {
+			if !repository.GetArchived() {
+				repositories = append(repositories, repository.GetFullName())
+			}
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		opts.Page = response.NextPage
+	}
+	sort.Strings(repositories)
+	return repositories, nil
+}
+
+// newCampaign lists the repositories of the campaign, and stores it before any repository is remediated
+func newCampaign(ctx context.Context, client *github.Client, store CampaignStore, request CampaignRequest) (*Campaign, error) {
+	repositories, err := listRepositories(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	if len(request.Repositories) > 0 {
+		installed := make(map[string]bool, len(repositories))
+		for _, repository := range repositories {
+			installed[strings.ToLower(repository)] = true
+		}
+		repositories = nil
+		for _, repository := range request.Repositories {
+			if !installed[strings.ToLower(repository)] {
+				return nil, fmt.Errorf("repository %s is not accessible to the installation", repository)
+			}
+			repositories = append(repositories, repository)
+		}
+	}
+	if len(repositories) == 0 {
+		return nil, fmt.Errorf("no repositories to remediate")
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create campaign id: %v", err)
+	}
+	now := time.Now().UTC()
+	campaign := &Campaign{ID: id, Params: request.Params, Repositories: repositories, CreatedAt: now, UpdatedAt: now}
+	// the shards are split evenly, and there are no empty shards
+	shards := min(max(request.Shards, 1), len(repositories))
+	for i := 0; i < shards; i++ {
+		start, end := i*len(repositories)/shards, (i+1)*len(repositories)/shards
+		campaign.Shards = append(campaign.Shards, CampaignShard{Index: i, Start: start, End: end, Next: start, UpdatedAt: now})
+	}
+	// the shards are saved before the campaign, so a campaign that is found has all its shards
+	for i := range campaign.Shards {
+		if _, err := store.SaveShard(campaign.ID, &campaign.Shards[i], now); err != nil {
+			return nil, fmt.Errorf("unable to save shard %d: %v", i, err)
+		}
+	}
+	campaign.setTotals()
+	if err := store.SaveCampaign(campaign); err != nil {
+		return nil, fmt.Errorf("unable to save campaign: %v", err)
+	}
+	return campaign, nil
+}
+
+func newID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// loadCampaign returns the campaign with the progress of its shards, or nil if there is no campaign with the id
+func loadCampaign(store CampaignStore, id string) (*Campaign, error) {
+	campaign, err := store.GetCampaign(id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get campaign: %v", err)
+	}
+	if campaign == nil {
+		return nil, nil
+	}
+	if campaign.Shards, err = store.GetShards(id); err != nil {
+		return nil, fmt.Errorf("unable to get shards: %v", err)
+	}
+	if len(campaign.Shards) == 0 {
+		// a campaign started before the campaigns were sharded has a single shard, whose progress is in the campaign
+		campaign.Shards = []CampaignShard{{End: len(campaign.Repositories), Next: campaign.Processed, Changed: campaign.Changed,
+			PullRequests: campaign.PullRequests, Failed: campaign.Failed, UpdatedAt: campaign.UpdatedAt}}
+	}
+	campaign.setTotals()
+	return campaign, nil
+}
+
+// RunCampaign starts a campaign for the repositories of the installation of the token, or resumes the campaign of the
+// request, and remediates the next batch of repositories of the shard of the request. The progress of the shard is saved
+// after each repository, so a batch that is interrupted, e.g. by the timeout of the function, is resumed from the
+// repository it stopped at. It returns ErrShardRunning if another request is running the shard, and nil if there is no
+// campaign with the id of the request.
+func RunCampaign(ctx context.Context, token string, store CampaignStore, request CampaignRequest, svc dynamodbiface.DynamoDBAPI) (*Campaign, error) {
+	batchSize := request.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultCampaignBatchSize
+	}
+	if batchSize > MaxCampaignBatchSize {
+		return nil, fmt.Errorf("batch size %d is larger than %d", batchSize, MaxCampaignBatchSize)
+	}
+	if request.Shards > MaxCampaignShards {
+		return nil, fmt.Errorf("number of shards %d is larger than %d", request.Shards, MaxCampaignShards)
+	}
+	client := getClient(ctx, token)
+
+	var campaign *Campaign
+	var err error
+	if request.ID == "" {
+		campaign, err = newCampaign(ctx, client, store, request)
+	} else {
+		campaign, err = loadCampaign(store, request.ID)
+	}
+	if err != nil || campaign == nil {
+		return nil, err
+	}
+	if request.Shard < 0 || request.Shard >= len(campaign.Shards) {
+		return nil, fmt.Errorf("shard %d is not one of the %d shards of the campaign", request.Shard, len(campaign.Shards))
+	}
+
+	shard := &campaign.Shards[request.Shard]
+	if shard.LeaseID, err = newID(); err != nil {
+		return nil, fmt.Errorf("unable to create lease id: %v", err)
+	}
+	// the lease is extended each time the progress is saved, and expires when the run is done
+	saveShard := func(leaseDuration time.Duration) error {
+		now := time.Now().UTC()
+		shard.LeaseExpiresAt = now.Add(leaseDuration)
+		saved, err := store.SaveShard(campaign.ID, shard, now)
+		if err != nil {
+			return fmt.Errorf("unable to save shard %d: %v", shard.Index, err)
+		}
+		if !saved {
+			return ErrShardRunning
+		}
+		return nil
+	}
+	if err := saveShard(campaignLeaseDuration); err != nil {
+		return nil, err
+	}
+
+	logger := logging.Logger().With("campaign_id", campaign.ID, "shard", shard.Index)
+	end := shard.Next + batchSize
+	for shard.Next < shard.End && shard.Next < end && ctx.Err() == nil {
+		index, fullName := shard.Next, campaign.Repositories[shard.Next]
+		var result RepositoryResult
+		if parts := strings.SplitN(fullName, "/", 2); len(parts) == 2 {
+			result = remediateRepository(ctx, client, parts[0], parts[1], nil, campaign.Params, svc)
+		} else {
+			result = RepositoryResult{Repository: fullName, Error: "invalid repository name"}
+		}
+		if err := store.AddResult(campaign.ID, index, result); err != nil {
+			return nil, fmt.Errorf("unable to save result of %s: %v", fullName, err)
+		}
+		if result.Error != "" {
+			shard.Failed++
+			logger.Warn("unable to remediate repository", "repository", fullName, "error", result.Error)
+		}
+		if result.IsChanged {
+			shard.Changed++
+		}
+		if result.PullRequestURL != "" {
+			shard.PullRequests++
+		}
+		campaign.Results = append(campaign.Results, result)
+
+		shard.Next++
+		shard.UpdatedAt = time.Now().UTC()
+		if err := saveShard(campaignLeaseDuration); err != nil {
+			return nil, err
+		}
+	}
+	if err := saveShard(0); err != nil {
+		return nil, err
+	}
+	campaign.setTotals()
+	return campaign, nil
+}
+
+// GetCampaign returns the campaign with the results of all processed repositories, or nil if there is no campaign with
+// the id
+func GetCampaign(store CampaignStore, id string) (*Campaign, error) {
+	campaign, err := loadCampaign(store, id)
+	if err != nil || campaign == nil {
+		return nil, err
+	}
+	if campaign.Results, err = store.GetResults(id); err != nil {
+		return nil, fmt.Errorf("unable to get results: %v", err)
+	}
+	return campaign, nil
+}
@@ -0,0 +1,198 @@
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/step-security/secure-repo/remediation/storage"
+)
+
+// registerCampaignResponders answers the requests of a campaign for the installation of octo-org/app, whose workflow is
+// changed, octo-org/web, which cannot be remediated, and the archived octo-org/old
+func registerCampaignResponders() {
+	const api = "https://api.github.com"
+	httpmock.RegisterResponder("GET", api+"/installation/repositories",
+		httpmock.NewStringResponder(http.StatusOK, `{"total_count": 3, "repositories": [
+  {"full_name": "octo-org/web"}, {"full_name": "octo-org/app"}, {"full_name": "octo-org/old", "archived": true}]}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app",
+		httpmock.NewStringResponder(http.StatusOK, `{"full_name": "octo-org/app", "default_branch": "main"}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/branches/main",
+		httpmock.NewStringResponder(http.StatusOK, `{"name": "main", "commit": {"sha": "base-sha"}}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/git/trees/base-sha",
+		httpmock.NewStringResponder(http.StatusOK, `{"sha": "base-tree", "tree": [{"path": ".github/workflows/ci.yml", "type": "blob"}, {"path": "README.md", "type": "blob"}]}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/contents/.github/workflows/ci.yml",
+		fileResponder("name: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make\n"))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/web",
+		httpmock.NewStringResponder(http.StatusNotFound, `{"message": "Not Found"}`))
+}
+
+func TestRunCampaign(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerCampaignResponders()
+
+	store := &StorageCampaignStore{Store: &storage.FileStore{Dir: t.TempDir()}}
+	params := map[string]string{"dryRun": "true", "pinActions": "false", "addHardenRunner": "false", "addProjectComment": "false",
+		"updateDependabotConfig": "false"}
+	campaign, err := RunCampaign(context.Background(), "installation-token", store, CampaignRequest{Params: params, BatchSize: 1}, nil)
+	if err != nil {
+		t.Fatalf("RunCampaign() unexpected error = %v", err)
+	}
+	if campaign.Status != CampaignStatusRunning || len(campaign.Repositories) != 2 || campaign.Processed != 1 || campaign.Changed != 1 ||
+		campaign.PullRequests != 0 || len(campaign.Results) != 1 || campaign.Results[0].Repository != "octo-org/app" {
+		t.Fatalf("expected the first batch with the changes of octo-org/app and no pull request, got %+v", campaign)
+	}
+
+	// the campaign is resumed from the next repository
+	campaign, err = RunCampaign(context.Background(), "installation-token", store, CampaignRequest{ID: campaign.ID, BatchSize: 1}, nil)
+	if err != nil {
+		t.Fatalf("RunCampaign() unexpected error = %v", err)
+	}
+	if campaign.Status != CampaignStatusCompleted || campaign.Failed != 1 || len(campaign.Results) != 1 || campaign.Results[0].Repository != "octo-org/web" {
+		t.Errorf("expected the second batch with the error of octo-org/web, got %+v", campaign)
+	}
+
+	status, err := GetCampaign(store, campaign.ID)
+	if err != nil || status.Processed != 2 || len(status.Results) != 2 {
+		t.Errorf("GetCampaign() = %+v, %v, want the results of both repositories", status, err)
+	}
+	if campaign, err := RunCampaign(context.Background(), "installation-token", store, CampaignRequest{ID: "unknown"}, nil); campaign != nil || err != nil {
+		t.Errorf("RunCampaign() = %v, %v, want nil for an unknown campaign", campaign, err)
+	}
+	if _, err := RunCampaign(context.Background(), "installation-token", store, CampaignRequest{Repositories: []string{"other-org/app"}}, nil); err == nil {
+		t.Errorf("expected an error for a repository the installation cannot access")
+	}
+}
+
+func TestRunCampaignShards(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerCampaignResponders()
+
+	store := &StorageCampaignStore{Store: &storage.FileStore{Dir: t.TempDir()}}
+	params := map[string]string{"dryRun": "true", "pinActions": "false", "addHardenRunner": "false", "addProjectComment": "false",
+		"updateDependabotConfig": "false"}
+	campaign, err := RunCampaign(context.Background(), "installation-token", store, CampaignRequest{Params: params, Shards: 2, Shard: 1}, nil)
+	if err != nil {
+		t.Fatalf("RunCampaign() unexpected error = %v", err)
+	}
+	if len(campaign.Shards) != 2 || campaign.Processed != 1 || campaign.Status != CampaignStatusRunning ||
+		len(campaign.Results) != 1 || campaign.Results[0].Repository != "octo-org/web" {
+		t.Fatalf("expected the second shard to remediate octo-org/web, got %+v", campaign)
+	}
+
+	// the lease of a shard that is being run is not taken by another request
+	shard := campaign.Shards[0]
+	shard.LeaseID, shard.LeaseExpiresAt = "other-run", time.Now().Add(time.Minute)
+	if saved, err := store.SaveShard(campaign.ID, &shard, time.Now()); !saved || err != nil {
+		t.Fatalf("SaveShard() = %v, %v, want the shard to be saved", saved, err)
+	}
+	if _, err := RunCampaign(context.Background(), "installation-token", store, CampaignRequest{ID: campaign.ID}, nil); !errors.Is(err, ErrShardRunning) {
+		t.Fatalf("RunCampaign() error = %v, want %v", err, ErrShardRunning)
+	}
+
+	// the lease expires, e.g. when the run was stopped by the timeout of the function
+	shard.LeaseExpiresAt = time.Now().Add(-time.Second)
+	store.SaveShard(campaign.ID, &shard, time.Now())
+	campaign, err = RunCampaign(context.Background(), "installation-token", store, CampaignRequest{ID: campaign.ID}, nil)
+	if err != nil {
+		t.Fatalf("RunCampaign() unexpected error = %v", err)
+	}
+	if campaign.Status != CampaignStatusCompleted || len(campaign.Results) != 1 || campaign.Results[0].Repository != "octo-org/app" {
+		t.Errorf("expected the first shard to remediate octo-org/app, got %+v", campaign)
+	}
+	status, err := GetCampaign(store, campaign.ID)
+	if err != nil || status.Processed != 2 || status.Changed != 1 || status.Failed != 1 || len(status.Results) != 2 ||
+		status.Results[0].Repository != "octo-org/app" {
+		t.Errorf("GetCampaign() = %+v, %v, want the results of both shards in the order of the repositories", status, err)
+	}
+
+	// a run of a completed shard does not remediate its repositories again
+	campaign, err = RunCampaign(context.Background(), "installation-token", store, CampaignRequest{ID: campaign.ID, Shard: 1}, nil)
+	if err != nil || len(campaign.Results) != 0 || campaign.Processed != 2 {
+		t.Errorf("RunCampaign() = %+v, %v, want no repositories to be remediated again", campaign, err)
+	}
+	if _, err := RunCampaign(context.Background(), "installation-token", store, CampaignRequest{ID: campaign.ID, Shard: 2}, nil); err == nil {
+		t.Errorf("expected an error for a shard the campaign does not have")
+	}
+}
+
+func TestRunCampaignWithoutShards(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerCampaignResponders()
+
+	// a campaign started before the campaigns were sharded is resumed from its progress
+	store := &StorageCampaignStore{Store: &storage.FileStore{Dir: t.TempDir()}}
+	legacy := &Campaign{ID: "1234", Params: map[string]string{"dryRun": "true"}, Repositories: []string{"octo-org/app", "octo-org/web"},
+		Processed: 1, Changed: 1}
+	if err := store.SaveCampaign(legacy); err != nil {
+		t.Fatalf("SaveCampaign() unexpected error = %v", err)
+	}
+	campaign, err := RunCampaign(context.Background(), "installation-token", store, CampaignRequest{ID: "1234"}, nil)
+	if err != nil {
+		t.Fatalf("RunCampaign() unexpected error = %v", err)
+	}
+	if campaign.Status != CampaignStatusCompleted || campaign.Processed != 2 || campaign.Changed != 1 || campaign.Failed != 1 ||
+		len(campaign.Results) != 1 || campaign.Results[0].Repository != "octo-org/web" {
+		t.Errorf("expected the campaign to be resumed from octo-org/web, got %+v", campaign)
+	}
+}
+
+func TestStorageCampaignStore(t *testing.T) {
+	store := &StorageCampaignStore{Store: &storage.FileStore{Dir: t.TempDir()}}
+	campaign := &Campaign{ID: "1234", Status: CampaignStatusRunning, Repositories: []string{"octo-org/app", "octo-org/web"},
+		Results: []RepositoryResult{{Repository: "octo-org/app"}}}
+	if err := store.SaveCampaign(campaign); err != nil {
+		t.Fatalf("SaveCampaign() unexpected error = %v", err)
+	}
+	stored, err := store.GetCampaign("1234")
+	if err != nil || stored == nil || len(stored.Repositories) != 2 || stored.Results != nil {
+		t.Errorf("GetCampaign() = %+v, %v, want the campaign without its results", stored, err)
+	}
+	if stored, err := store.GetCampaign("unknown"); stored != nil || err != nil {
+		t.Errorf("GetCampaign() = %+v, %v, want nil for an unknown campaign", stored, err)
+	}
+
+	// the results are returned in the order of the repositories, past the order of their keys as strings
+	for i := 11; i >= 0; i-- {
+		if err := store.AddResult("1234", i, RepositoryResult{Repository: fmt.Sprintf("octo-org/repo-%d", i)}); err != nil {
+			t.Fatalf("AddResult() unexpected error = %v", err)
+		}
+	}
+	results, err := store.GetResults("1234")
+	if err != nil || len(results) != 12 || results[2].Repository != "octo-org/repo-2" || results[11].Repository != "octo-org/repo-11" {
+		t.Errorf("GetResults() = %+v, %v, want the results in order", results, err)
+	}
+
+	now := time.Now()
+	shard := &CampaignShard{Index: 1, Start: 1, End: 2, Next: 1, LeaseID: "run-1", LeaseExpiresAt: now.Add(time.Minute)}
+	if saved, err := store.SaveShard("1234", shard, now); !saved || err != nil {
+		t.Fatalf("SaveShard() = %v, %v, want the shard to be saved", saved, err)
+	}
+	other := *shard
+	other.LeaseID = "run-2"
+	if saved, err := store.SaveShard("1234", &other, now); saved || err != nil {
+		t.Errorf("SaveShard() = %v, %v, want the shard leased by another run not to be saved", saved, err)
+	}
+	if saved, err := store.SaveShard("1234", &other, now.Add(2*time.Minute)); !saved || err != nil {
+		t.Errorf("SaveShard() = %v, %v, want the shard to be saved after the lease expired", saved, err)
+	}
+	store.SaveShard("1234", &CampaignShard{Index: 0, End: 1}, now)
+	shards, err := store.GetShards("1234")
+	if err != nil || len(shards) != 2 || shards[0].Index != 0 || shards[1].LeaseID != "run-2" {
+		t.Errorf("GetShards() = %+v, %v, want the shards in order", shards, err)
+	}
+}
@@ -0,0 +1,283 @@
+package githubapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/storage"
+)
+
+// DynamoDBCampaignStore stores the campaigns in a DynamoDB table with CampaignID as the hash key and Index as the range
+// key. The campaign is the item with Index 0, and the result of the repository with index i is the item with Index i+1,
+// so the results are queried in order. The shard with index i is the item with Index -(i+1), whose lease is checked
+// with a condition, so two runs cannot hold it.
+type DynamoDBCampaignStore struct {
+	TableName string
+	Svc       dynamodbiface.DynamoDBAPI
+}
+
+func campaignKey(id string, index int) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"CampaignID": {S: aws.String(id)},
+		"Index":      {N: aws.String(strconv.Itoa(index))},
+	}
+}
+
+func (s *DynamoDBCampaignStore) GetCampaign(id string) (*Campaign, error) {
+	result, err := s.Svc.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(s.TableName),
+		Key:            campaignKey(id, 0),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil || result.Item["Campaign"] == nil {
+		return nil, nil
+	}
+	campaign := &Campaign{}
+	if err := json.Unmarshal(result.Item["Campaign"].B, campaign); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// SaveCampaign stores the campaign without the results of its batch and its shards, which are stored by AddResult and
+// SaveShard
+func (s *DynamoDBCampaignStore) SaveCampaign(campaign *Campaign) error {
+	stored := *campaign
+	stored.Results, stored.Shards = nil, nil
+	value, err := json.Marshal(&stored)
+	if err != nil {
+		return err
+	}
+	item := campaignKey(campaign.ID, 0)
+	item["Campaign"] = &dynamodb.AttributeValue{B: value}
+	_, err = s.Svc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.TableName),
+		Item:      item,
+	})
+	return err
+}
+
+func (s *DynamoDBCampaignStore) AddResult(id string, index int, result RepositoryResult) error {
+	value, err := json.Marshal(&result)
+	if err != nil {
+		return err
+	}
+	item := campaignKey(id, index+1)
+	item["Result"] = &dynamodb.AttributeValue{B: value}
+	_, err = s.Svc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.TableName),
+		Item:      item,
+	})
+	return err
+}
+
+func (s *DynamoDBCampaignStore) GetResults(id string) ([]RepositoryResult, error) {
+	var results []RepositoryResult
+	var unmarshalErr error
+	err := s.Svc.QueryPages(&dynamodb.QueryInput{
+		TableName:              aws.String(s.TableName),
+		KeyConditionExpression: aws.String("CampaignID = :id AND #index > :campaign"),
+		// Index is a reserved word of DynamoDB
+		ExpressionAttributeNames: map[string]*string{"#index": aws.String("Index")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id":       {S: aws.String(id)},
+			":campaign": {N: aws.String("0")},
+		},
+		ConsistentRead: aws.Bool(true),
+	}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			if item["Result"] == nil {
+				continue
+			}
+			var result RepositoryResult
+			if unmarshalErr = json.Unmarshal(item["Result"].B, &result); unmarshalErr != nil {
+				return false
+			}
+			results = append(results, result)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, unmarshalErr
+}
+
+func (s *DynamoDBCampaignStore) GetShards(id string) ([]CampaignShard, error) {
+	var shards []CampaignShard
+	var unmarshalErr error
+	err := s.Svc.QueryPages(&dynamodb.QueryInput{
+		TableName:                aws.String(s.TableName),
+		KeyConditionExpression:   aws.String("CampaignID = :id AND #index < :campaign"),
+		ExpressionAttributeNames: map[string]*string{"#index": aws.String("Index")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id":       {S: aws.String(id)},
+			":campaign": {N: aws.String("0")},
+		},
+		ConsistentRead: aws.Bool(true),
+	}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			if item["Shard"] == nil {
+				continue
+			}
+			var shard CampaignShard
+			if unmarshalErr = json.Unmarshal(item["Shard"].B, &shard); unmarshalErr != nil {
+				return false
+			}
+			shards = append(shards, shard)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the items of the shards are queried from the last shard
+	sort.Slice(shards, func(i, j int) bool { return shards[i].Index < shards[j].Index })
+	return shards, unmarshalErr
+}
+
+// SaveShard stores the shard with its lease in the LeaseID and LeaseExpiresAt attributes, the latter in Unix
+// milliseconds, with a condition that the stored shard has no lease, the same lease, or a lease that expired
+func (s *DynamoDBCampaignStore) SaveShard(id string, shard *CampaignShard, now time.Time) (bool, error) {
+	value, err := json.Marshal(shard)
+	if err != nil {
+		return false, err
+	}
+	item := campaignKey(id, -(shard.Index + 1))
+	item["Shard"] = &dynamodb.AttributeValue{B: value}
+	item["LeaseExpiresAt"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(shard.LeaseExpiresAt.UnixMilli(), 10))}
+	condition := "attribute_not_exists(LeaseID) OR LeaseExpiresAt < :now"
+	values := map[string]*dynamodb.AttributeValue{":now": {N: aws.String(strconv.FormatInt(now.UnixMilli(), 10))}}
+	if shard.LeaseID != "" {
+		item["LeaseID"] = &dynamodb.AttributeValue{S: aws.String(shard.LeaseID)}
+		condition += " OR LeaseID = :lease"
+		values[":lease"] = &dynamodb.AttributeValue{S: aws.String(shard.LeaseID)}
+	}
+	_, err = s.Svc.PutItem(&dynamodb.PutItemInput{
+		TableName:                 aws.String(s.TableName),
+		Item:                      item,
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeValues: values,
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// StorageCampaignStore stores the campaigns in a storage.Store. The campaign is at campaigns/<id>/campaign, and the
+// result of the repository with index i at campaigns/<id>/results/<i>, and the shard with index i at
+// campaigns/<id>/shards/<i>, with i padded with zeros so the keys are listed in order. The leases of the shards are not
+// checked atomically, so concurrent requests for the same shard may both run it.
+type StorageCampaignStore struct {
+	Store storage.Store
+}
+
+func (s *StorageCampaignStore) GetCampaign(id string) (*Campaign, error) {
+	value, err := s.Store.Get("campaigns/" + id + "/campaign")
+	if err != nil || value == nil {
+		return nil, err
+	}
+	campaign := &Campaign{}
+	if err := json.Unmarshal(value, campaign); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// SaveCampaign stores the campaign without the results of its batch and its shards, which are stored by AddResult and
+// SaveShard
+func (s *StorageCampaignStore) SaveCampaign(campaign *Campaign) error {
+	stored := *campaign
+	stored.Results, stored.Shards = nil, nil
+	value, err := json.Marshal(&stored)
+	if err != nil {
+		return err
+	}
+	return s.Store.Put("campaigns/"+campaign.ID+"/campaign", value)
+}
+
+func (s *StorageCampaignStore) AddResult(id string, index int, result RepositoryResult) error {
+	value, err := json.Marshal(&result)
+	if err != nil {
+		return err
+	}
+	return s.Store.Put(fmt.Sprintf("campaigns/%s/results/%08d", id, index), value)
+}
+
+func (s *StorageCampaignStore) GetResults(id string) ([]RepositoryResult, error) {
+	keys, err := s.Store.List("campaigns/" + id + "/results/")
+	if err != nil {
+		return nil, err
+	}
+	var results []RepositoryResult
+	for _, key := range keys {
+		value, err := s.Store.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			continue
+		}
+		var result RepositoryResult
+		if err := json.Unmarshal(value, &result); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *StorageCampaignStore) GetShards(id string) ([]CampaignShard, error) {
+	keys, err := s.Store.List("campaigns/" + id + "/shards/")
+	if err != nil {
+		return nil, err
+	}
+	var shards []CampaignShard
+	for _, key := range keys {
+		value, err := s.Store.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			continue
+		}
+		var shard CampaignShard
+		if err := json.Unmarshal(value, &shard); err != nil {
+			return nil, err
+		}
+		shards = append(shards, shard)
+	}
+	return shards, nil
+}
+
+func (s *StorageCampaignStore) SaveShard(id string, shard *CampaignShard, now time.Time) (bool, error) {
+	key := fmt.Sprintf("campaigns/%s/shards/%04d", id, shard.Index)
+	value, err := s.Store.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if value != nil {
+		var stored CampaignShard
+		if err := json.Unmarshal(value, &stored); err != nil {
+			return false, err
+		}
+		if stored.LeaseID != "" && stored.LeaseID != shard.LeaseID && now.Before(stored.LeaseExpiresAt) {
+			return false, nil
+		}
+	}
+	if value, err = json.Marshal(shard); err != nil {
+		return false, err
+	}
+	return true, s.Store.Put(key, value)
+}
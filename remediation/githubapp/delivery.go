@@ -0,0 +1,248 @@
+package githubapp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/storage"
+)
+
+const (
+	// DeliveriesTableEnv is the DynamoDB table of the handled webhook deliveries
+	DeliveriesTableEnv = "WEBHOOK_DELIVERIES_TABLE"
+	// MaxEventAgeEnv is the age of the oldest event that is handled, e.g. 30m
+	MaxEventAgeEnv = "WEBHOOK_MAX_EVENT_AGE"
+
+	DefaultMaxEventAge = time.Hour
+	// clockSkew is how far in the future the time of an event can be, since it is set by the clock of GitHub
+	clockSkew = 5 * time.Minute
+	// deliveryTTL is how long the key of a delivery is kept, which is longer than the age of the handled events since
+	// some events have no time
+	deliveryTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrDuplicateDelivery = errors.New("delivery was already handled")
+	ErrStaleEvent        = errors.New("event is too old")
+)
+
+// DeliveryStore records the keys of the webhook deliveries, so a replayed delivery is not handled twice
+type DeliveryStore interface {
+	// Record records the delivery until it expires, and returns false if it was already recorded
+	Record(id string, expiresAt time.Time) (bool, error)
+	// Forget removes the delivery, so it is handled again when it is redelivered
+	Forget(id string) error
+}
+
+// NewDeliveryStoreFromEnv returns the store of the table in WEBHOOK_DELIVERIES_TABLE, or of the storage of STORAGE_URL if
+// the table is not set, or a store in memory, which only detects the deliveries replayed to the same instance
+func NewDeliveryStoreFromEnv(svc dynamodbiface.DynamoDBAPI) (DeliveryStore, error) {
+	if tableName := os.Getenv(DeliveriesTableEnv); tableName != "" {
+		return &DynamoDBDeliveryStore{TableName: tableName, Svc: svc}, nil
+	}
+	store, err := storage.Default()
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		conditionalStore, ok := store.(storage.ConditionalStore)
+		if !ok {
+			return nil, fmt.Errorf("the storage of %s does not support conditional writes", storage.URLEnv)
+		}
+		return &StorageDeliveryStore{Store: conditionalStore}, nil
+	}
+	return NewMemoryDeliveryStore(), nil
+}
+
+// WebhookVerifier rejects the webhook deliveries that were already handled and the events that are too old, so replayed
+// events do not run the remediations or open pull requests again. The signature of the delivery is verified before.
+type WebhookVerifier struct {
+	Store       DeliveryStore
+	MaxEventAge time.Duration
+	now         func() time.Time
+}
+
+// NewWebhookVerifierFromEnv returns a verifier with the store of NewDeliveryStoreFromEnv and the max age of the events
+// in WEBHOOK_MAX_EVENT_AGE
+func NewWebhookVerifierFromEnv(svc dynamodbiface.DynamoDBAPI) (*WebhookVerifier, error) {
+	store, err := NewDeliveryStoreFromEnv(svc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create delivery store: %v", err)
+	}
+	maxEventAge := DefaultMaxEventAge
+	if value := os.Getenv(MaxEventAgeEnv); value != "" {
+		if maxEventAge, err = time.ParseDuration(value); err != nil || maxEventAge <= 0 {
+			return nil, fmt.Errorf("invalid %s %q", MaxEventAgeEnv, value)
+		}
+	}
+	return &WebhookVerifier{Store: store, MaxEventAge: maxEventAge}, nil
+}
+
+// Verify returns ErrStaleEvent if the time of the event is older than the max age, and ErrDuplicateDelivery if the
+// delivery was already recorded, otherwise the delivery is recorded. The time of an event is when the repository was
+// pushed to, the pull request was updated or the app was installed, and the events without a time are only deduplicated.
+// The deliveries are recorded by the hash of their payload, since the signature covers the payload but not the
+// X-GitHub-Delivery header, so a replayed payload with another delivery id is a duplicate as well.
+func (v *WebhookVerifier) Verify(deliveryID, eventType string, payload []byte) error {
+	if deliveryID == "" {
+		return fmt.Errorf("delivery id is required")
+	}
+	now := time.Now()
+	if v.now != nil {
+		now = v.now()
+	}
+	event, err := github.ParseWebHook(eventType, payload)
+	if err == nil {
+		if eventTime, ok := getEventTime(event); ok {
+			if eventTime.Before(now.Add(-v.MaxEventAge)) || eventTime.After(now.Add(clockSkew)) {
+				return ErrStaleEvent
+			}
+		}
+	}
+	// the stores expire the deliveries with the time of the instance
+	recorded, err := v.Store.Record(deliveryKey(payload), time.Now().Add(deliveryTTL))
+	if err != nil {
+		return fmt.Errorf("unable to record delivery: %v", err)
+	}
+	if !recorded {
+		return ErrDuplicateDelivery
+	}
+	return nil
+}
+
+// Forget forgets the delivery of the payload, so it is handled again when it is redelivered, e.g. after an error
+func (v *WebhookVerifier) Forget(payload []byte) error {
+	return v.Store.Forget(deliveryKey(payload))
+}
+
+// deliveryKey returns the key a delivery is recorded with, which is the SHA-256 of its signed payload
+func deliveryKey(payload []byte) string {
+	hash := sha256.Sum256(payload)
+	return hex.EncodeToString(hash[:])
+}
+
+// getEventTime returns the time of the event, or false if the event has no time that changes with each delivery
+func getEventTime(event interface{}) (time.Time, bool) {
+	var eventTime time.Time
+	switch event := event.(type) {
+	case *github.PushEvent:
+		eventTime = event.GetRepo().GetPushedAt().Time
+	case *github.PullRequestEvent:
+		eventTime = event.GetPullRequest().GetUpdatedAt()
+	case *github.InstallationEvent:
+		if event.GetAction() == "created" {
+			eventTime = event.GetInstallation().GetCreatedAt().Time
+		}
+	}
+	return eventTime, !eventTime.IsZero()
+}
+
+// DynamoDBDeliveryStore stores the deliveries in a DynamoDB table with Delivery as the hash key. ExpiresAt is the time
+// the delivery expires in Unix seconds, which can be the TTL attribute of the table.
+type DynamoDBDeliveryStore struct {
+	TableName string
+	Svc       dynamodbiface.DynamoDBAPI
+}
+
+func (s *DynamoDBDeliveryStore) Record(id string, expiresAt time.Time) (bool, error) {
+	_, err := s.Svc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			"Delivery":  {S: aws.String(id)},
+			"ExpiresAt": {N: aws.String(strconv.FormatInt(expiresAt.Unix(), 10))},
+		},
+		// the TTL of DynamoDB deletes the expired items within days, so they are replaced until then
+		ConditionExpression:       aws.String("attribute_not_exists(Delivery) OR ExpiresAt < :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":now": {N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))}},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *DynamoDBDeliveryStore) Forget(id string) error {
+	_, err := s.Svc.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(s.TableName),
+		Key:       map[string]*dynamodb.AttributeValue{"Delivery": {S: aws.String(id)}},
+	})
+	return err
+}
+
+// StorageDeliveryStore stores the deliveries in a storage.Store at deliveries/<key>, with the time they expire. The
+// deliveries are written only if they are not recorded yet, so only one of the concurrent deliveries with the same key
+// is handled.
+type StorageDeliveryStore struct {
+	Store storage.ConditionalStore
+}
+
+func (s *StorageDeliveryStore) Record(id string, expiresAt time.Time) (bool, error) {
+	key := "deliveries/" + id
+	value := []byte(expiresAt.UTC().Format(time.RFC3339))
+	recorded, err := s.Store.PutIfAbsent(key, value)
+	if err != nil || recorded {
+		return recorded, err
+	}
+	existing, err := s.Store.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if expires, err := time.Parse(time.RFC3339, string(existing)); err == nil && time.Now().Before(expires) {
+		return false, nil
+	}
+	// an expired delivery is deleted and recorded again, so only the concurrent deliveries of an expired key can both
+	// be handled
+	if err := s.Store.Delete(key); err != nil {
+		return false, err
+	}
+	return s.Store.PutIfAbsent(key, value)
+}
+
+func (s *StorageDeliveryStore) Forget(id string) error {
+	return s.Store.Delete("deliveries/" + id)
+}
+
+// MemoryDeliveryStore stores the deliveries in memory, so they are kept across the requests served by the instance
+type MemoryDeliveryStore struct {
+	mu         sync.Mutex
+	deliveries map[string]time.Time
+}
+
+func NewMemoryDeliveryStore() *MemoryDeliveryStore {
+	return &MemoryDeliveryStore{deliveries: map[string]time.Time{}}
+}
+
+func (s *MemoryDeliveryStore) Record(id string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if expires, ok := s.deliveries[id]; ok && now.Before(expires) {
+		return false, nil
+	}
+	// the expired deliveries are removed, so the map does not grow with each delivery
+	for other, expires := range s.deliveries {
+		if !now.Before(expires) {
+			delete(s.deliveries, other)
+		}
+	}
+	s.deliveries[id] = expiresAt
+	return true, nil
+}
+
+func (s *MemoryDeliveryStore) Forget(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.deliveries, id)
+	return nil
+}
@@ -0,0 +1,100 @@
+package githubapp
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/step-security/secure-repo/remediation/storage"
+)
+
+func TestWebhookVerifier(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	verifier := &WebhookVerifier{Store: NewMemoryDeliveryStore(), MaxEventAge: time.Hour, now: func() time.Time { return now }}
+
+	push := func(pushedAt time.Time) []byte {
+		return []byte(`{"ref": "refs/heads/main", "repository": {"full_name": "octo-org/app", "pushed_at": ` + strconv.FormatInt(pushedAt.Unix(), 10) + `}}`)
+	}
+	tests := []struct {
+		name       string
+		deliveryID string
+		eventType  string
+		payload    []byte
+		want       error
+	}{
+		{name: "push", deliveryID: "1", eventType: "push", payload: push(now.Add(-time.Minute))},
+		{name: "replayed push", deliveryID: "1", eventType: "push", payload: push(now.Add(-time.Minute)), want: ErrDuplicateDelivery},
+		// the delivery id is not signed, so the signed payload is a duplicate with another id
+		{name: "replayed push with another id", deliveryID: "7", eventType: "push", payload: push(now.Add(-time.Minute)), want: ErrDuplicateDelivery},
+		{name: "old push", deliveryID: "2", eventType: "push", payload: push(now.Add(-2 * time.Hour)), want: ErrStaleEvent},
+		{name: "push in the future", deliveryID: "3", eventType: "push", payload: push(now.Add(time.Hour)), want: ErrStaleEvent},
+		{name: "old pull request", deliveryID: "4", eventType: "pull_request",
+			payload: []byte(`{"action": "synchronize", "pull_request": {"updated_at": "2023-04-01T12:00:00Z"}}`), want: ErrStaleEvent},
+		{name: "pull request", deliveryID: "5", eventType: "pull_request",
+			payload: []byte(`{"action": "synchronize", "pull_request": {"updated_at": "2023-05-01T11:50:00Z"}}`)},
+		// the events without a time are only deduplicated
+		{name: "repositories added", deliveryID: "6", eventType: "installation_repositories",
+			payload: []byte(`{"action": "added", "installation": {"id": 42}}`)},
+		{name: "replayed repositories added", deliveryID: "6", eventType: "installation_repositories",
+			payload: []byte(`{"action": "added", "installation": {"id": 42}}`), want: ErrDuplicateDelivery},
+	}
+	for _, tt := range tests {
+		if got := verifier.Verify(tt.deliveryID, tt.eventType, tt.payload); got != tt.want {
+			t.Errorf("%s: Verify() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if err := verifier.Verify("", "push", push(now)); err == nil {
+		t.Errorf("Verify() expected an error without a delivery id")
+	}
+
+	// a delivery is handled again once it is forgotten, e.g. after an error
+	if err := verifier.Forget(push(now.Add(-time.Minute))); err != nil {
+		t.Fatalf("Forget() unexpected error = %v", err)
+	}
+	if err := verifier.Verify("1", "push", push(now.Add(-time.Minute))); err != nil {
+		t.Errorf("Verify() = %v, want the forgotten delivery to be handled", err)
+	}
+}
+
+func TestDeliveryStores(t *testing.T) {
+	for name, store := range map[string]DeliveryStore{
+		"memory":  NewMemoryDeliveryStore(),
+		"storage": &StorageDeliveryStore{Store: &storage.FileStore{Dir: t.TempDir()}},
+	} {
+		expiresAt := time.Now().Add(time.Hour)
+		if recorded, err := store.Record("72d3162e-cc78-11e3-81ab-4c9367dc0958", expiresAt); !recorded || err != nil {
+			t.Errorf("%s: Record() = %v, %v, want the delivery recorded", name, recorded, err)
+		}
+		if recorded, err := store.Record("72d3162e-cc78-11e3-81ab-4c9367dc0958", expiresAt); recorded || err != nil {
+			t.Errorf("%s: Record() = %v, %v, want the duplicate rejected", name, recorded, err)
+		}
+		// an expired delivery is recorded again
+		if recorded, err := store.Record("expired", time.Now().Add(-time.Second)); !recorded || err != nil {
+			t.Errorf("%s: Record() = %v, %v", name, recorded, err)
+		}
+		if recorded, err := store.Record("expired", expiresAt); !recorded || err != nil {
+			t.Errorf("%s: Record() = %v, %v, want the expired delivery recorded again", name, recorded, err)
+		}
+	}
+	store := &StorageDeliveryStore{Store: &storage.FileStore{Dir: t.TempDir()}}
+	// only one of the concurrent deliveries with the same id is recorded
+	results := make(chan bool)
+	for i := 0; i < 10; i++ {
+		go func() {
+			recorded, _ := store.Record("concurrent", time.Now().Add(time.Hour))
+			results <- recorded
+		}()
+	}
+	recordedCount := 0
+	for i := 0; i < 10; i++ {
+		if <-results {
+			recordedCount++
+		}
+	}
+	if recordedCount != 1 {
+		t.Errorf("Record() recorded %d concurrent deliveries, want 1", recordedCount)
+	}
+	if _, err := store.Record("../secrets", time.Now()); err == nil {
+		t.Errorf("Record() expected an error for an id outside the deliveries")
+	}
+}
@@ -0,0 +1,160 @@
+package githubapp
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/metrics"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// environment variables with the credentials of the GitHub App
+	AppIDEnv         = "GITHUB_APP_ID"
+	AppPrivateKeyEnv = "GITHUB_APP_PRIVATE_KEY"
+	WebhookSecretEnv = "GITHUB_WEBHOOK_SECRET"
+
+	RemediationBranch = "stepsecurity/remediation"
+	pullRequestTitle  = "[StepSecurity] Apply security best practices"
+	commitMessage     = "[StepSecurity] Apply security best practices"
+)
+
+// getAppToken returns a JWT to authenticate as the GitHub App, which is valid for 10 minutes.
+// It is issued a minute in the past to allow for clock drift.
+func getAppToken(appID string, privateKey *rsa.PrivateKey) (string, error) {
+	now := time.Now()
+	claims := jwt.StandardClaims{
+		IssuedAt:  now.Add(-time.Minute).Unix(),
+		ExpiresAt: now.Add(9 * time.Minute).Unix(),
+		Issuer:    appID,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
+}
+
+func getClient(ctx context.Context, token string) *github.Client {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	return github.NewClient(oauth2.NewClient(metrics.WithGitHubClient(ctx), ts))
+}
+
+// GetInstallationToken returns an installation token of the GitHub App, which is valid for an hour.
+// The app id and private key are read from GITHUB_APP_ID and GITHUB_APP_PRIVATE_KEY.
+func GetInstallationToken(ctx context.Context, installationID int64) (string, error) {
+	appID, privateKeyPEM := os.Getenv(AppIDEnv), os.Getenv(AppPrivateKeyEnv)
+	if appID == "" || privateKeyPEM == "" {
+		return "", fmt.Errorf("%s and %s must be set", AppIDEnv, AppPrivateKeyEnv)
+	}
+	if _, err := strconv.ParseInt(appID, 10, 64); err != nil {
+		return "", fmt.Errorf("invalid app id %s", appID)
+	}
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(privateKeyPEM))
+	if err != nil {
+		return "", fmt.Errorf("unable to parse app private key: %v", err)
+	}
+	appToken, err := getAppToken(appID, privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	installationToken, _, err := getClient(ctx, appToken).Apps.CreateInstallationToken(ctx, installationID, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to create installation token: %v", err)
+	}
+	return installationToken.GetToken(), nil
+}
+
+// getInstallationClient returns a client authenticated with an installation token of the GitHub App
+func getInstallationClient(ctx context.Context, installationID int64) (*github.Client, error) {
+	token, err := GetInstallationToken(ctx, installationID)
+	if err != nil {
+		return nil, err
+	}
+	return getClient(ctx, token), nil
+}
+
+// createOrUpdatePullRequest commits the files on top of the base commit to the remediation branch, and opens a pull request
+// from it with the description if there is no open one. The branch is force updated, so it always has a single commit on the default branch.
+// The branch and the pull request are left as they are if they already have the files and the description, e.g. when a
+// campaign that was interrupted remediates the repository again. It returns the URL of the pull request, and whether
+// the branch or the pull request were changed.
+func createOrUpdatePullRequest(ctx context.Context, client *github.Client, owner, repo, baseBranch, baseSHA string, files map[string]string, description string) (string, bool, error) {
+	baseCommit, _, err := client.Git.GetCommit(ctx, owner, repo, baseSHA)
+	if err != nil {
+		return "", false, fmt.Errorf("unable to get commit %s: %v", baseSHA, err)
+	}
+
+	paths := make([]string, 0, len(files))
+	for filePath := range files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	var entries []*github.TreeEntry
+	for _, filePath := range paths {
+		entries = append(entries, &github.TreeEntry{Path: github.String(filePath), Mode: github.String("100644"), Type: github.String("blob"), Content: github.String(files[filePath])})
+	}
+	tree, _, err := client.Git.CreateTree(ctx, owner, repo, baseCommit.GetTree().GetSHA(), entries)
+	if err != nil {
+		return "", false, fmt.Errorf("unable to create tree: %v", err)
+	}
+
+	branch, response, err := client.Git.GetRef(ctx, owner, repo, "heads/"+RemediationBranch)
+	exists := err == nil
+	if !exists && (response == nil || response.StatusCode != 404) {
+		return "", false, fmt.Errorf("unable to get branch %s: %v", RemediationBranch, err)
+	}
+	upToDate := false
+	if exists {
+		// the tree of the same files is the same, so the commit of the branch is kept if it has the tree on the base commit
+		head, _, err := client.Git.GetCommit(ctx, owner, repo, branch.GetObject().GetSHA())
+		if err != nil {
+			return "", false, fmt.Errorf("unable to get commit of branch %s: %v", RemediationBranch, err)
+		}
+		upToDate = head.GetTree().GetSHA() == tree.GetSHA() && len(head.Parents) == 1 && head.Parents[0].GetSHA() == baseSHA
+	}
+	if !upToDate {
+		commit, _, err := client.Git.CreateCommit(ctx, owner, repo, &github.Commit{Message: github.String(commitMessage), Tree: tree, Parents: []*github.Commit{{SHA: github.String(baseSHA)}}})
+		if err != nil {
+			return "", false, fmt.Errorf("unable to create commit: %v", err)
+		}
+		ref := &github.Reference{Ref: github.String("refs/heads/" + RemediationBranch), Object: &github.GitObject{SHA: commit.SHA}}
+		if exists {
+			if _, _, err := client.Git.UpdateRef(ctx, owner, repo, ref, true); err != nil {
+				return "", false, fmt.Errorf("unable to update branch %s: %v", RemediationBranch, err)
+			}
+		} else if _, _, err := client.Git.CreateRef(ctx, owner, repo, ref); err != nil {
+			return "", false, fmt.Errorf("unable to create branch %s: %v", RemediationBranch, err)
+		}
+	}
+
+	pullRequests, _, err := client.PullRequests.List(ctx, owner, repo, &github.PullRequestListOptions{State: "open", Head: owner + ":" + RemediationBranch, Base: baseBranch})
+	if err != nil {
+		return "", false, fmt.Errorf("unable to list pull requests: %v", err)
+	}
+	if len(pullRequests) > 0 {
+		if upToDate && pullRequests[0].GetBody() == description {
+			return pullRequests[0].GetHTMLURL(), false, nil
+		}
+		// the files changed by the remediation may be different from when the pull request was opened
+		pullRequest, _, err := client.PullRequests.Edit(ctx, owner, repo, pullRequests[0].GetNumber(), &github.PullRequest{Body: github.String(description)})
+		if err != nil {
+			return "", false, fmt.Errorf("unable to update pull request: %v", err)
+		}
+		return pullRequest.GetHTMLURL(), true, nil
+	}
+	pullRequest, _, err := client.PullRequests.Create(ctx, owner, repo, &github.NewPullRequest{
+		Title: github.String(pullRequestTitle),
+		Head:  github.String(RemediationBranch),
+		Base:  github.String(baseBranch),
+		Body:  github.String(description),
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("unable to create pull request: %v", err)
+	}
+	return pullRequest.GetHTMLURL(), true, nil
+}
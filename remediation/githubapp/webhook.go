@@ -0,0 +1,295 @@
+package githubapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/checks"
+	"github.com/step-security/secure-repo/remediation/notify"
+	"github.com/step-security/secure-repo/remediation/prbody"
+	"github.com/step-security/secure-repo/remediation/repoconfig"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+)
+
+// RepositoryResult is the outcome of remediating a repository for a webhook event
+type RepositoryResult struct {
+	Repository     string
+	IsChanged      bool
+	PullRequestURL string `json:",omitempty"`
+	Error          string `json:",omitempty"`
+}
+
+type WebhookResponse struct {
+	Event        string
+	Action       string             `json:",omitempty"`
+	Ignored      bool               `json:",omitempty"`
+	CheckRunURL  string             `json:",omitempty"`
+	Repositories []RepositoryResult `json:",omitempty"`
+}
+
+// getFiles returns the content of the files at the commit. If paths is nil, all files that may have remediations are returned,
+// otherwise only the paths that may have remediations, along with the configuration of the repository.
+func getFiles(ctx context.Context, client *github.Client, owner, repo, sha string, paths []string) (map[string]string, error) {
+	if paths == nil {
+		tree, _, err := client.Git.GetTree(ctx, owner, repo, sha, true)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get tree: %v", err)
+		}
+		for _, entry := range tree.Entries {
+			if entry.GetType() == "blob" {
+				paths = append(paths, entry.GetPath())
+			}
+		}
+	} else {
+		paths = append(append([]string{}, paths...), repoconfig.ConfigPaths...)
+	}
+
+	files := make(map[string]string)
+	for _, filePath := range paths {
+		if _, found := files[filePath]; found || !securerepo.ShouldFetch(filePath) {
+			continue
+		}
+		fileContent, _, response, err := client.Repositories.GetContents(ctx, owner, repo, filePath, &github.RepositoryContentGetOptions{Ref: sha})
+		if err != nil {
+			// the configuration is optional
+			if response != nil && response.StatusCode == 404 {
+				continue
+			}
+			return nil, fmt.Errorf("unable to get %s: %v", filePath, err)
+		}
+		if fileContent == nil {
+			continue
+		}
+		content, err := fileContent.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode %s: %v", filePath, err)
+		}
+		files[filePath] = content
+	}
+	return files, nil
+}
+
+// remediateRepository runs the remediations on the files of the repository at the head of the default branch, and opens or
+// updates the remediation pull request with the changes. If paths is not nil, only those files are remediated. The params
+// are the query parameters of /v1/secure-repo, and with dryRun=true the changes are only evaluated.
+func remediateRepository(ctx context.Context, client *github.Client, owner, repo string, paths []string, params map[string]string, svc dynamodbiface.DynamoDBAPI) RepositoryResult {
+	result := RepositoryResult{Repository: owner + "/" + repo}
+	fail := func(err error) RepositoryResult {
+		result.Error = err.Error()
+		return result
+	}
+
+	repository, _, err := client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return fail(fmt.Errorf("unable to get repository: %v", err))
+	}
+	if repository.GetArchived() {
+		return result
+	}
+	defaultBranch := repository.GetDefaultBranch()
+	branch, _, err := client.Repositories.GetBranch(ctx, owner, repo, defaultBranch, false)
+	if err != nil {
+		return fail(fmt.Errorf("unable to get branch %s: %v", defaultBranch, err))
+	}
+	sha := branch.GetCommit().GetSHA()
+
+	files, err := getFiles(ctx, client, owner, repo, sha, paths)
+	if err != nil {
+		return fail(err)
+	}
+
+	queryStringParams := map[string]string{}
+	for key, value := range params {
+		queryStringParams[key] = value
+	}
+	queryStringParams["owner"], queryStringParams["repo"] = owner, repo
+	// the dependabot config is only updated when the whole repository is remediated, since it depends on all files
+	if paths != nil {
+		queryStringParams["updateDependabotConfig"] = "false"
+	}
+	response, err := securerepo.SecureRepo(ctx, queryStringParams, securerepo.SecureRepoRequest{Files: files}, svc)
+	if err != nil {
+		return fail(err)
+	}
+	if !response.IsChanged {
+		return result
+	}
+	result.IsChanged = true
+	if queryStringParams["dryRun"] == "true" {
+		return result
+	}
+
+	description := prbody.Describe(prbody.KindPullRequest, result.Repository, response)
+	var updated bool
+	result.PullRequestURL, updated, err = createOrUpdatePullRequest(ctx, client, owner, repo, defaultBranch, sha, response.Files, description)
+	if err != nil {
+		return fail(err)
+	}
+	if !updated {
+		// the pull request was opened with the same changes before, which was notified then
+		return result
+	}
+	notify.Notify(&notify.Notification{Event: notify.EventApplied, Repository: result.Repository, PullRequestURL: result.PullRequestURL,
+		Report: response.Report})
+	return result
+}
+
+// getPullRequestFiles returns the files added or modified by a pull request that may have findings
+func getPullRequestFiles(ctx context.Context, client *github.Client, owner, repo string, number int) ([]string, error) {
+	var paths []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		files, response, err := client.PullRequests.ListFiles(ctx, owner, repo, number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list files of pull request: %v", err)
+		}
+		for _, file := range files {
+			if file.GetStatus() != "removed" && securerepo.ShouldFetch(file.GetFilename()) {
+				paths = append(paths, file.GetFilename())
+			}
+		}
+		if response.NextPage == 0 {
+			return paths, nil
+		}
+		opts.Page = response.NextPage
+	}
+}
+
+// checkPullRequest runs the remediations on the files changed by a pull request at its head commit, without changing them,
+// and posts their findings as a check run, so they are annotated on the lines of the pull request. It returns the result
+// of the repository with the URL of the check run.
+func checkPullRequest(ctx context.Context, client *github.Client, owner, repo, headSHA string, paths []string, svc dynamodbiface.DynamoDBAPI) (RepositoryResult, string) {
+	result := RepositoryResult{Repository: owner + "/" + repo}
+	files, err := getFiles(ctx, client, owner, repo, headSHA, paths)
+	if err != nil {
+		result.Error = err.Error()
+		return result, ""
+	}
+	queryStringParams := map[string]string{"owner": owner, "repo": repo, "dryRun": "true", "updateDependabotConfig": "false"}
+	response, err := securerepo.SecureRepo(ctx, queryStringParams, securerepo.SecureRepoRequest{Files: files}, svc)
+	if err != nil {
+		result.Error = err.Error()
+		return result, ""
+	}
+
+	changed := make(map[string]bool, len(paths))
+	for _, filePath := range paths {
+		changed[filePath] = true
+	}
+	var checkFiles []checks.File
+	for _, fileReport := range response.Report {
+		if changed[fileReport.Path] {
+			checkFiles = append(checkFiles, checks.File{Path: fileReport.Path, Findings: fileReport.Findings})
+		}
+	}
+	result.IsChanged = response.IsChanged
+	checkRun, err := checks.CreateCheckRun(ctx, client, owner, repo, headSHA, checkFiles)
+	if err != nil {
+		result.Error = err.Error()
+		return result, ""
+	}
+	return result, checkRun.GetHTMLURL()
+}
+
+// getChangedFiles returns the files added or modified by the commits of a push
+func getChangedFiles(event *github.PushEvent) []string {
+	var paths []string
+	changed := make(map[string]bool)
+	for _, commit := range event.Commits {
+		for _, filePath := range append(commit.Added, commit.Modified...) {
+			if !changed[filePath] {
+				changed[filePath] = true
+				paths = append(paths, filePath)
+			}
+		}
+	}
+	return paths
+}
+
+// HandleWebhook runs the remediations for a GitHub App webhook event. All repositories are remediated when the app is
+// installed or repositories are added to the installation, and the changed files are remediated on a push to the default branch.
+// The remediations are opened as a pull request from the stepsecurity/remediation branch, which is updated on later events.
+// The findings of the files changed by a pull request are posted as a check run when it is opened or updated.
+func HandleWebhook(ctx context.Context, eventType string, payload []byte, svc dynamodbiface.DynamoDBAPI) (*WebhookResponse, error) {
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		return nil, err
+	}
+	response := &WebhookResponse{Event: eventType}
+
+	var installationID int64
+	var repositories []*github.Repository
+	var paths []string
+	switch event := event.(type) {
+	case *github.InstallationEvent:
+		response.Action = event.GetAction()
+		if event.GetAction() == "created" {
+			installationID, repositories = event.GetInstallation().GetID(), event.Repositories
+		}
+	case *github.InstallationRepositoriesEvent:
+		response.Action = event.GetAction()
+		if event.GetAction() == "added" {
+			installationID, repositories = event.GetInstallation().GetID(), event.RepositoriesAdded
+		}
+	case *github.PushEvent:
+		// pushes to other branches, including the remediation branch, are not remediated
+		if event.GetRef() == "refs/heads/"+event.GetRepo().GetDefaultBranch() && !event.GetDeleted() {
+			for _, filePath := range getChangedFiles(event) {
+				if securerepo.ShouldFetch(filePath) {
+					paths = append(paths, filePath)
+				}
+			}
+			if len(paths) > 0 {
+				installationID = event.GetInstallation().GetID()
+				repositories = []*github.Repository{{Name: event.GetRepo().Name, FullName: event.GetRepo().FullName}}
+			}
+		}
+	case *github.PullRequestEvent:
+		response.Action = event.GetAction()
+		if action := event.GetAction(); action == "opened" || action == "synchronize" || action == "reopened" {
+			return handlePullRequest(ctx, event, response, svc)
+		}
+	}
+	if len(repositories) == 0 {
+		response.Ignored = true
+		return response, nil
+	}
+
+	client, err := getInstallationClient(ctx, installationID)
+	if err != nil {
+		return nil, err
+	}
+	for _, repository := range repositories {
+		parts := strings.SplitN(repository.GetFullName(), "/", 2)
+		if len(parts) != 2 {
+			response.Repositories = append(response.Repositories, RepositoryResult{Repository: repository.GetFullName(), Error: "invalid repository name"})
+			continue
+		}
+		response.Repositories = append(response.Repositories, remediateRepository(ctx, client, parts[0], parts[1], paths, nil, svc))
+	}
+	return response, nil
+}
+
+// handlePullRequest posts the check run of a pull request, which is ignored if it changes no files that may have findings
+func handlePullRequest(ctx context.Context, event *github.PullRequestEvent, response *WebhookResponse, svc dynamodbiface.DynamoDBAPI) (*WebhookResponse, error) {
+	client, err := getInstallationClient(ctx, event.GetInstallation().GetID())
+	if err != nil {
+		return nil, err
+	}
+	owner, repo := event.GetRepo().GetOwner().GetLogin(), event.GetRepo().GetName()
+	paths, err := getPullRequestFiles(ctx, client, owner, repo, event.GetNumber())
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		response.Ignored = true
+		return response, nil
+	}
+	// the head commit of a pull request from a fork is fetched from the repository of the pull request
+	result, checkRunURL := checkPullRequest(ctx, client, owner, repo, event.GetPullRequest().GetHead().GetSHA(), paths, svc)
+	response.Repositories, response.CheckRunURL = append(response.Repositories, result), checkRunURL
+	return response, nil
+}
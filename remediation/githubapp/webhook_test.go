@@ -0,0 +1,231 @@
+package githubapp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func setAppCredentials(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	os.Setenv(AppIDEnv, "1234")
+	os.Setenv(AppPrivateKeyEnv, string(privateKeyPEM))
+}
+
+func fileResponder(content string) httpmock.Responder {
+	return httpmock.NewJsonResponderOrPanic(http.StatusOK, map[string]string{
+		"type":     "file",
+		"encoding": "base64",
+		"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+	})
+}
+
+func TestHandleWebhookPush(t *testing.T) {
+	setAppCredentials(t)
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	const api = "https://api.github.com"
+	httpmock.RegisterResponder("POST", api+"/app/installations/42/access_tokens",
+		httpmock.NewStringResponder(http.StatusCreated, `{"token": "installation-token"}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app",
+		httpmock.NewStringResponder(http.StatusOK, `{"full_name": "octo-org/app", "default_branch": "main"}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/branches/main",
+		httpmock.NewStringResponder(http.StatusOK, `{"name": "main", "commit": {"sha": "base-sha"}}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/contents/.github/workflows/ci.yml",
+		fileResponder("name: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make\n"))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/contents/.github/stepsecurity.yml",
+		fileResponder("remediations:\n  pinActions: false\n  addHardenRunner: false\n  addProjectComment: false\n"))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/contents/.github/stepsecurity.yaml",
+		httpmock.NewStringResponder(http.StatusNotFound, `{"message": "Not Found"}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/git/commits/base-sha",
+		httpmock.NewStringResponder(http.StatusOK, `{"sha": "base-sha", "tree": {"sha": "base-tree"}}`))
+
+	var tree struct {
+		BaseTree string `json:"base_tree"`
+		Tree     []struct {
+			Path    string
+			Content string
+		}
+	}
+	httpmock.RegisterResponder("POST", api+"/repos/octo-org/app/git/trees", func(req *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &tree)
+		return httpmock.NewStringResponse(http.StatusCreated, `{"sha": "new-tree"}`), nil
+	})
+	httpmock.RegisterResponder("POST", api+"/repos/octo-org/app/git/commits",
+		httpmock.NewStringResponder(http.StatusCreated, `{"sha": "new-sha"}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/git/ref/heads/stepsecurity/remediation",
+		httpmock.NewStringResponder(http.StatusNotFound, `{"message": "Not Found"}`))
+	httpmock.RegisterResponder("POST", api+"/repos/octo-org/app/git/refs",
+		httpmock.NewStringResponder(http.StatusCreated, `{"ref": "refs/heads/stepsecurity/remediation"}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/pulls",
+		httpmock.NewStringResponder(http.StatusOK, `[]`))
+	httpmock.RegisterResponder("POST", api+"/repos/octo-org/app/pulls",
+		httpmock.NewStringResponder(http.StatusCreated, `{"number": 7, "html_url": "https://github.com/octo-org/app/pull/7"}`))
+
+	payload := `{
+  "ref": "refs/heads/main",
+  "repository": {"name": "app", "full_name": "octo-org/app", "default_branch": "main"},
+  "installation": {"id": 42},
+  "commits": [{"added": [], "modified": [".github/workflows/ci.yml", "README.md"]}]
+}`
+	response, err := HandleWebhook(context.Background(), "push", []byte(payload), nil)
+	if err != nil {
+		t.Fatalf("HandleWebhook() unexpected error = %v", err)
+	}
+	if len(response.Repositories) != 1 {
+		t.Fatalf("HandleWebhook() = %+v, want one repository", response)
+	}
+	result := response.Repositories[0]
+	if result.Error != "" || !result.IsChanged || result.PullRequestURL != "https://github.com/octo-org/app/pull/7" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if tree.BaseTree != "base-tree" || len(tree.Tree) != 1 || tree.Tree[0].Path != ".github/workflows/ci.yml" {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+	expected := "name: CI\non: push\npermissions:\n  contents: read\n\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make\n"
+	if tree.Tree[0].Content != expected {
+		t.Errorf("unexpected content of the workflow\n%s", tree.Tree[0].Content)
+	}
+}
+
+func TestHandleWebhookPullRequest(t *testing.T) {
+	setAppCredentials(t)
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	const api = "https://api.github.com"
+	httpmock.RegisterResponder("POST", api+"/app/installations/42/access_tokens",
+		httpmock.NewStringResponder(http.StatusCreated, `{"token": "installation-token"}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/pulls/3/files",
+		httpmock.NewStringResponder(http.StatusOK, `[{"filename": ".github/workflows/ci.yml", "status": "modified"}, {"filename": "README.md", "status": "modified"}]`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/contents/.github/workflows/ci.yml",
+		fileResponder("name: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v2\n"))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/contents/.github/stepsecurity.yml",
+		fileResponder("remediations:\n  pinActions: false\n  checkUnmaintainedActions: false\n"))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/contents/.github/stepsecurity.yaml",
+		httpmock.NewStringResponder(http.StatusNotFound, `{"message": "Not Found"}`))
+
+	var checkRun struct {
+		Name       string
+		HeadSHA    string `json:"head_sha"`
+		Conclusion string
+		Output     struct {
+			Annotations []struct {
+				Path       string
+				StartLine  int    `json:"start_line"`
+				RawDetails string `json:"raw_details"`
+			}
+		}
+	}
+	httpmock.RegisterResponder("POST", api+"/repos/octo-org/app/check-runs", func(req *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &checkRun)
+		return httpmock.NewStringResponse(http.StatusCreated, `{"id": 5, "html_url": "https://github.com/octo-org/app/runs/5"}`), nil
+	})
+
+	payload := `{
+  "action": "synchronize",
+  "number": 3,
+  "pull_request": {"head": {"sha": "head-sha"}},
+  "repository": {"name": "app", "full_name": "octo-org/app", "owner": {"login": "octo-org"}},
+  "installation": {"id": 42}
+}`
+	response, err := HandleWebhook(context.Background(), "pull_request", []byte(payload), nil)
+	if err != nil {
+		t.Fatalf("HandleWebhook() unexpected error = %v", err)
+	}
+	if len(response.Repositories) != 1 || response.CheckRunURL != "https://github.com/octo-org/app/runs/5" {
+		t.Fatalf("HandleWebhook() = %+v, want the check run of the pull request", response)
+	}
+	if checkRun.HeadSHA != "head-sha" || checkRun.Conclusion != "neutral" {
+		t.Errorf("unexpected check run %+v", checkRun)
+	}
+	annotated := make(map[string]int)
+	for _, annotation := range checkRun.Output.Annotations {
+		if annotation.Path != ".github/workflows/ci.yml" {
+			t.Errorf("unexpected annotation of %s", annotation.Path)
+		}
+		annotated[annotation.RawDetails] = annotation.StartLine
+	}
+	if annotated["unpinned-action"] != 7 || annotated["missing-permissions"] == 0 {
+		t.Errorf("expected annotations of the unpinned action and the missing permissions, got %v", annotated)
+	}
+}
+
+func TestHandleWebhookIgnored(t *testing.T) {
+	tests := []struct {
+		eventType string
+		payload   string
+	}{
+		{"push", `{"ref": "refs/heads/stepsecurity/remediation", "repository": {"full_name": "octo-org/app", "default_branch": "main"}, "commits": [{"modified": [".github/workflows/ci.yml"]}]}`},
+		{"push", `{"ref": "refs/heads/main", "repository": {"full_name": "octo-org/app", "default_branch": "main"}, "commits": [{"modified": ["README.md"]}]}`},
+		{"installation", `{"action": "deleted", "installation": {"id": 42}}`},
+		{"ping", `{"zen": "Design for failure."}`},
+	}
+	for _, test := range tests {
+		response, err := HandleWebhook(context.Background(), test.eventType, []byte(test.payload), nil)
+		if err != nil {
+			t.Errorf("HandleWebhook(%s) unexpected error = %v", test.eventType, err)
+			continue
+		}
+		if !response.Ignored {
+			t.Errorf("HandleWebhook(%s) = %+v, want the event to be ignored", test.eventType, response)
+		}
+	}
+}
+
+func TestCreateOrUpdatePullRequestUpToDate(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	const api = "https://api.github.com"
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/git/commits/base-sha",
+		httpmock.NewStringResponder(http.StatusOK, `{"sha": "base-sha", "tree": {"sha": "base-tree"}}`))
+	httpmock.RegisterResponder("POST", api+"/repos/octo-org/app/git/trees",
+		httpmock.NewStringResponder(http.StatusCreated, `{"sha": "new-tree"}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/git/ref/heads/stepsecurity/remediation",
+		httpmock.NewStringResponder(http.StatusOK, `{"ref": "refs/heads/stepsecurity/remediation", "object": {"sha": "branch-sha"}}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/git/commits/branch-sha",
+		httpmock.NewStringResponder(http.StatusOK, `{"sha": "branch-sha", "tree": {"sha": "new-tree"}, "parents": [{"sha": "base-sha"}]}`))
+	httpmock.RegisterResponder("GET", api+"/repos/octo-org/app/pulls",
+		httpmock.NewStringResponder(http.StatusOK, `[{"number": 7, "body": "description", "html_url": "https://github.com/octo-org/app/pull/7"}]`))
+
+	// the branch has the same files on the same base commit, so a repository that is remediated again is not changed
+	client := getClient(context.Background(), "installation-token")
+	files := map[string]string{".github/workflows/ci.yml": "on: push\n"}
+	url, updated, err := createOrUpdatePullRequest(context.Background(), client, "octo-org", "app", "main", "base-sha", files, "description")
+	if err != nil || updated || url != "https://github.com/octo-org/app/pull/7" {
+		t.Errorf("createOrUpdatePullRequest() = %s, %v, %v, want the pull request without changes", url, updated, err)
+	}
+	calls := httpmock.GetCallCountInfo()
+	if calls["POST "+api+"/repos/octo-org/app/git/commits"] != 0 || calls["PATCH "+api+"/repos/octo-org/app/git/refs/heads/stepsecurity/remediation"] != 0 {
+		t.Errorf("expected no commit to be pushed, got calls %v", calls)
+	}
+
+	// a description that changed updates the pull request
+	httpmock.RegisterResponder("PATCH", api+"/repos/octo-org/app/pulls/7",
+		httpmock.NewStringResponder(http.StatusOK, `{"number": 7, "html_url": "https://github.com/octo-org/app/pull/7"}`))
+	if _, updated, err := createOrUpdatePullRequest(context.Background(), client, "octo-org", "app", "main", "base-sha", files, "new description"); err != nil || !updated {
+		t.Errorf("createOrUpdatePullRequest() = %v, %v, want the description to be updated", updated, err)
+	}
+}
@@ -0,0 +1,232 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/outbound"
+)
+
+const (
+	// environment variables with the URL of the GitLab instance, which defaults to DefaultURL, and the project or group
+	// access token used when the request has none
+	URLEnv   = "GITLAB_URL"
+	TokenEnv = "GITLAB_TOKEN"
+
+	DefaultURL = "https://gitlab.com"
+
+	// TokenHeader is the header GitLab authenticates access tokens with
+	TokenHeader = "Private-Token"
+)
+
+// Client is a client of the REST API of GitLab, authenticated with a project, group or personal access token
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// Error is an error response of the API
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("status code %d: %s", e.StatusCode, e.Message)
+}
+
+// Project is a GitLab project
+type Project struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	DefaultBranch     string `json:"default_branch"`
+	Archived          bool   `json:"archived"`
+}
+
+type branch struct {
+	Commit struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+}
+
+type treeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+// commitAction is a file created or updated by a commit
+type commitAction struct {
+	Action   string `json:"action"`
+	FilePath string `json:"file_path"`
+	Content  string `json:"content"`
+}
+
+// MergeRequest is a GitLab merge request
+type MergeRequest struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+}
+
+// NewClient returns a client of the GitLab instance in GITLAB_URL, authenticated with the token or with GITLAB_TOKEN if
+// the token is empty
+func NewClient(token string) *Client {
+	baseURL := os.Getenv(URLEnv)
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	if token == "" {
+		token = os.Getenv(TokenEnv)
+	}
+	return &Client{BaseURL: baseURL, Token: token}
+}
+
+// projectPath returns the path of the API of the project, whose id can be its numeric id or its path, e.g. group/app
+func projectPath(project string) string {
+	return "/projects/" + url.PathEscape(project)
+}
+
+// do sends a request to the API, and decodes the JSON response into result if it is not nil
+func (c *Client) do(ctx context.Context, method, apiPath string, body, result interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+"/api/v4"+apiPath, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(TokenHeader, c.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = outbound.Client()
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	if result == nil {
+		return resp, nil
+	}
+	switch v := result.(type) {
+	case *string:
+		*v = string(data)
+		return resp, nil
+	default:
+		return resp, json.Unmarshal(data, result)
+	}
+}
+
+// GetProject returns the project
+func (c *Client) GetProject(ctx context.Context, project string) (*Project, error) {
+	result := &Project{}
+	if _, err := c.do(ctx, http.MethodGet, projectPath(project), nil, result); err != nil {
+		return nil, fmt.Errorf("unable to get project: %v", err)
+	}
+	return result, nil
+}
+
+// getBranchCommit returns the id of the commit at the head of the branch
+func (c *Client) getBranchCommit(ctx context.Context, project, branchName string) (string, error) {
+	result := &branch{}
+	if _, err := c.do(ctx, http.MethodGet, projectPath(project)+"/repository/branches/"+url.PathEscape(branchName), nil, result); err != nil {
+		return "", fmt.Errorf("unable to get branch %s: %v", branchName, err)
+	}
+	return result.Commit.ID, nil
+}
+
+// getTree returns the paths of the files of the project at the commit, following the pages of the tree
+func (c *Client) getTree(ctx context.Context, project, sha string) ([]string, error) {
+	var paths []string
+	for page := "1"; page != ""; {
+		var entries []treeEntry
+		query := url.Values{"ref": {sha}, "recursive": {"true"}, "per_page": {"100"}, "page": {page}}
+		resp, err := c.do(ctx, http.MethodGet, projectPath(project)+"/repository/tree?"+query.Encode(), nil, &entries)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get tree: %v", err)
+		}
+		for _, entry := range entries {
+			if entry.Type == "blob" {
+				paths = append(paths, entry.Path)
+			}
+		}
+		page = resp.Header.Get("X-Next-Page")
+		if _, err := strconv.Atoi(page); err != nil {
+			page = ""
+		}
+	}
+	return paths, nil
+}
+
+// getFile returns the content of the file at the commit
+func (c *Client) getFile(ctx context.Context, project, filePath, sha string) (string, error) {
+	var content string
+	query := url.Values{"ref": {sha}}
+	_, err := c.do(ctx, http.MethodGet, projectPath(project)+"/repository/files/"+url.PathEscape(filePath)+"/raw?"+query.Encode(), nil, &content)
+	return content, err
+}
+
+// commit commits the actions to the branch on top of the commit. The branch is created if it does not exist, and
+// overwritten if it does.
+func (c *Client) commit(ctx context.Context, project, branchName, startSHA, message string, actions []commitAction) error {
+	body := map[string]interface{}{"branch": branchName, "start_sha": startSHA, "commit_message": message, "actions": actions, "force": true}
+	if _, err := c.do(ctx, http.MethodPost, projectPath(project)+"/repository/commits", body, nil); err != nil {
+		return fmt.Errorf("unable to commit to branch %s: %v", branchName, err)
+	}
+	return nil
+}
+
+// findMergeRequest returns the open merge request from the source branch to the target branch, or nil if there is none
+func (c *Client) findMergeRequest(ctx context.Context, project, sourceBranch, targetBranch string) (*MergeRequest, error) {
+	var mergeRequests []MergeRequest
+	query := url.Values{"state": {"opened"}, "source_branch": {sourceBranch}, "target_branch": {targetBranch}}
+	if _, err := c.do(ctx, http.MethodGet, projectPath(project)+"/merge_requests?"+query.Encode(), nil, &mergeRequests); err != nil {
+		return nil, fmt.Errorf("unable to list merge requests: %v", err)
+	}
+	if len(mergeRequests) == 0 {
+		return nil, nil
+	}
+	return &mergeRequests[0], nil
+}
+
+func (c *Client) createMergeRequest(ctx context.Context, project, sourceBranch, targetBranch, title, description string) (*MergeRequest, error) {
+	body := map[string]interface{}{"source_branch": sourceBranch, "target_branch": targetBranch, "title": title,
+		"description": description, "remove_source_branch": true}
+	result := &MergeRequest{}
+	if _, err := c.do(ctx, http.MethodPost, projectPath(project)+"/merge_requests", body, result); err != nil {
+		return nil, fmt.Errorf("unable to create merge request: %v", err)
+	}
+	return result, nil
+}
+
+func (c *Client) updateMergeRequest(ctx context.Context, project string, iid int, description string) (*MergeRequest, error) {
+	result := &MergeRequest{}
+	body := map[string]string{"description": description}
+	if _, err := c.do(ctx, http.MethodPut, projectPath(project)+"/merge_requests/"+strconv.Itoa(iid), body, result); err != nil {
+		return nil, fmt.Errorf("unable to update merge request: %v", err)
+	}
+	return result, nil
+}
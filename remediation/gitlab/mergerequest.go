@@ -0,0 +1,134 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/githubapp"
+	"github.com/step-security/secure-repo/remediation/notify"
+	"github.com/step-security/secure-repo/remediation/prbody"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+)
+
+const (
+	mergeRequestTitle = "[StepSecurity] Apply security best practices"
+	commitMessage     = "[StepSecurity] Apply security best practices"
+)
+
+// ProjectResult is the outcome of remediating a GitLab project
+type ProjectResult struct {
+	Project         string
+	IsChanged       bool
+	MergeRequestURL string `json:",omitempty"`
+	Error           string `json:",omitempty"`
+}
+
+// getFiles returns the content of the files of the project at the commit that may have remediations, along with the
+// configuration of the project
+func getFiles(ctx context.Context, client *Client, project, sha string) (map[string]string, error) {
+	paths, err := client.getTree(ctx, project, sha)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string)
+	for _, filePath := range paths {
+		if !securerepo.ShouldFetch(filePath) {
+			continue
+		}
+		content, err := client.getFile(ctx, project, filePath, sha)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get %s: %v", filePath, err)
+		}
+		files[filePath] = content
+	}
+	return files, nil
+}
+
+// createOrUpdateMergeRequest commits the files on top of the base commit to the remediation branch, and opens a merge
+// request from it with the description if there is no open one. The branch is overwritten, so it always has a single commit on the default branch.
+func createOrUpdateMergeRequest(ctx context.Context, client *Client, project, baseBranch, baseSHA string, originalFiles, files map[string]string, description string) (string, error) {
+	paths := make([]string, 0, len(files))
+	for filePath := range files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	var actions []commitAction
+	for _, filePath := range paths {
+		action := "update"
+		if _, found := originalFiles[filePath]; !found {
+			action = "create"
+		}
+		actions = append(actions, commitAction{Action: action, FilePath: filePath, Content: files[filePath]})
+	}
+	if err := client.commit(ctx, project, githubapp.RemediationBranch, baseSHA, commitMessage, actions); err != nil {
+		return "", err
+	}
+
+	mergeRequest, err := client.findMergeRequest(ctx, project, githubapp.RemediationBranch, baseBranch)
+	if err != nil {
+		return "", err
+	}
+	if mergeRequest != nil {
+		// the files changed by the remediation may be different from when the merge request was opened
+		mergeRequest, err = client.updateMergeRequest(ctx, project, mergeRequest.IID, description)
+	} else {
+		mergeRequest, err = client.createMergeRequest(ctx, project, githubapp.RemediationBranch, baseBranch, mergeRequestTitle, description)
+	}
+	if err != nil {
+		return "", err
+	}
+	return mergeRequest.WebURL, nil
+}
+
+// RemediateProject runs the remediations on the files of the project at the head of its default branch, and opens or
+// updates the remediation merge request with the changes, the same way the GitHub App opens a pull request. The project
+// is its numeric id or its path, e.g. group/app.
+func RemediateProject(ctx context.Context, client *Client, project string, svc dynamodbiface.DynamoDBAPI) ProjectResult {
+	result := ProjectResult{Project: project}
+	fail := func(err error) ProjectResult {
+		result.Error = err.Error()
+		return result
+	}
+
+	gitlabProject, err := client.GetProject(ctx, project)
+	if err != nil {
+		return fail(err)
+	}
+	if gitlabProject.PathWithNamespace != "" {
+		result.Project = gitlabProject.PathWithNamespace
+	}
+	if gitlabProject.Archived {
+		return result
+	}
+	sha, err := client.getBranchCommit(ctx, project, gitlabProject.DefaultBranch)
+	if err != nil {
+		return fail(err)
+	}
+
+	files, err := getFiles(ctx, client, project, sha)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Dependabot does not run on GitLab, so its configuration is not added
+	queryStringParams := map[string]string{"updateDependabotConfig": "false"}
+	response, err := securerepo.SecureRepo(ctx, queryStringParams, securerepo.SecureRepoRequest{Files: files}, svc)
+	if err != nil {
+		return fail(err)
+	}
+	if !response.IsChanged {
+		return result
+	}
+	result.IsChanged = true
+
+	description := prbody.Describe(prbody.KindMergeRequest, result.Project, response)
+	result.MergeRequestURL, err = createOrUpdateMergeRequest(ctx, client, project, gitlabProject.DefaultBranch, sha, files, response.Files, description)
+	if err != nil {
+		return fail(err)
+	}
+	notify.Notify(&notify.Notification{Event: notify.EventApplied, Repository: result.Project, PullRequestURL: result.MergeRequestURL,
+		Report: response.Report})
+	return result
+}
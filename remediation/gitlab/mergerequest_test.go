@@ -0,0 +1,103 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/step-security/secure-repo/remediation/docker"
+)
+
+func TestRemediateProject(t *testing.T) {
+	t.Setenv(URLEnv, "https://gitlab.example.com")
+	t.Setenv(TokenEnv, "env-token")
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	saveTr := docker.Tr
+	defer func() { docker.Tr = saveTr }()
+	docker.Tr = httpmock.DefaultTransport
+	httpmock.RegisterResponder("GET", "https://index.docker.io/v2/", httpmock.NewStringResponder(http.StatusOK, `{}`))
+	httpmock.RegisterResponder("GET", "https://index.docker.io/v2/library/node/manifests/20",
+		httpmock.NewStringResponder(http.StatusOK, httpmock.File("../../testfiles/dockerfiles/response.json").String()))
+
+	const api = "https://gitlab.example.com/api/v4/projects/group%2Fapp"
+	var tokens []string
+	respond := func(status int, body string) httpmock.Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			tokens = append(tokens, req.Header.Get(TokenHeader))
+			return httpmock.NewStringResponse(status, body), nil
+		}
+	}
+	httpmock.RegisterResponder("GET", api,
+		respond(http.StatusOK, `{"id": 7, "path_with_namespace": "group/app", "default_branch": "main"}`))
+	httpmock.RegisterResponder("GET", api+"/repository/branches/main",
+		respond(http.StatusOK, `{"name": "main", "commit": {"id": "base-sha"}}`))
+	httpmock.RegisterResponder("GET", api+"/repository/tree?page=1&per_page=100&recursive=true&ref=base-sha",
+		func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusOK, `[{"path": ".gitlab-ci.yml", "type": "blob"}, {"path": "src", "type": "tree"}]`)
+			resp.Header.Set("X-Next-Page", "2")
+			return resp, nil
+		})
+	httpmock.RegisterResponder("GET", api+"/repository/tree?page=2&per_page=100&recursive=true&ref=base-sha",
+		respond(http.StatusOK, `[{"path": "src/main.go", "type": "blob"}]`))
+	httpmock.RegisterResponder("GET", api+"/repository/files/.gitlab-ci.yml/raw?ref=base-sha",
+		respond(http.StatusOK, "build:\n  image: node:20\n  script: npm run build\n"))
+
+	var commit struct {
+		Branch   string `json:"branch"`
+		StartSHA string `json:"start_sha"`
+		Force    bool   `json:"force"`
+		Actions  []commitAction
+	}
+	httpmock.RegisterResponder("POST", api+"/repository/commits", func(req *http.Request) (*http.Response, error) {
+		if err := json.NewDecoder(req.Body).Decode(&commit); err != nil {
+			return nil, err
+		}
+		return httpmock.NewStringResponse(http.StatusCreated, `{"id": "remediation-sha"}`), nil
+	})
+	httpmock.RegisterResponder("GET", api+"/merge_requests?source_branch=stepsecurity%2Fremediation&state=opened&target_branch=main",
+		respond(http.StatusOK, `[]`))
+	var mergeRequest map[string]interface{}
+	httpmock.RegisterResponder("POST", api+"/merge_requests", func(req *http.Request) (*http.Response, error) {
+		if err := json.NewDecoder(req.Body).Decode(&mergeRequest); err != nil {
+			return nil, err
+		}
+		return httpmock.NewStringResponse(http.StatusCreated, `{"iid": 3, "web_url": "https://gitlab.example.com/group/app/-/merge_requests/3"}`), nil
+	})
+
+	result := RemediateProject(context.Background(), NewClient(""), "group/app", nil)
+	if result.Error != "" {
+		t.Fatalf("RemediateProject() returned error: %s", result.Error)
+	}
+	if !result.IsChanged || result.MergeRequestURL != "https://gitlab.example.com/group/app/-/merge_requests/3" {
+		t.Errorf("expected a merge request to be opened, got %+v", result)
+	}
+	if commit.Branch != "stepsecurity/remediation" || commit.StartSHA != "base-sha" || !commit.Force || len(commit.Actions) != 1 ||
+		commit.Actions[0].Action != "update" || !strings.Contains(commit.Actions[0].Content, "image: node:20@sha256:") {
+		t.Errorf("expected the pinned image to be committed to the remediation branch, got %+v", commit)
+	}
+	if mergeRequest["target_branch"] != "main" || !strings.Contains(mergeRequest["description"].(string), "`.gitlab-ci.yml`") {
+		t.Errorf("unexpected merge request %v", mergeRequest)
+	}
+	for _, token := range tokens {
+		if token != "env-token" {
+			t.Errorf("expected requests to be authenticated with the token, got %q", token)
+		}
+	}
+}
+
+func TestRemediateProjectError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://gitlab.com/api/v4/projects/42",
+		httpmock.NewStringResponder(http.StatusNotFound, `{"message": "404 Project Not Found"}`))
+
+	result := RemediateProject(context.Background(), &Client{BaseURL: DefaultURL, Token: "token"}, "42", nil)
+	if !strings.Contains(result.Error, "status code 404") || result.IsChanged {
+		t.Errorf("expected the error of the API to be returned, got %+v", result)
+	}
+}
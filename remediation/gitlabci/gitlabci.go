@@ -0,0 +1,143 @@
+package gitlabci
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigPath is the path of the CI/CD configuration of a GitLab project
+const ConfigPath = ".gitlab-ci.yml"
+
+// keywords are the top level keys of the configuration that are not jobs
+var keywords = map[string]bool{"include": true, "stages": true, "variables": true, "workflow": true, "spec": true}
+
+// imageNode is the node of an image in the configuration
+type imageNode struct {
+	node  *yaml.Node
+	image string
+}
+
+// SecureConfigResponse is the result of the remediations of a GitLab CI configuration
+type SecureConfigResponse struct {
+	OriginalInput  string
+	FinalOutput    string
+	IsChanged      bool
+	PinnedImages   bool
+	PinnedIncludes bool
+	Findings       []findings.Finding
+}
+
+// getImage returns the scalar node with the name of an image, which is either the value of image, or its name
+func getImage(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml.MappingNode {
+		node = document.MappingValue(node, "name")
+	}
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return nil
+	}
+	return node
+}
+
+// findImages returns the images and services of the jobs and of the defaults, which are not pinned to a digest. Images
+// set with variables are skipped, since their value is only known when the pipeline runs.
+func findImages(topNode *yaml.Node) []imageNode {
+	var images []imageNode
+	add := func(node *yaml.Node) {
+		if node == nil || node.Value == "" || strings.Contains(node.Value, "@") || strings.Contains(node.Value, "$") {
+			return
+		}
+		images = append(images, imageNode{node: node, image: node.Value})
+	}
+	addServices := func(services *yaml.Node) {
+		if services != nil && services.Kind == yaml.SequenceNode {
+			for _, service := range services.Content {
+				add(getImage(service))
+			}
+		}
+	}
+	for i := 0; i+1 < len(topNode.Content); i += 2 {
+		switch key, value := topNode.Content[i].Value, topNode.Content[i+1]; {
+		case keywords[key]:
+		// the global image and services are deprecated in favor of default, but still used
+		case key == "image":
+			add(getImage(value))
+		case key == "services":
+			addServices(value)
+		default:
+			add(getImage(document.MappingValue(value, "image")))
+			addServices(document.MappingValue(value, "services"))
+		}
+	}
+	// replaced from the end, so the columns of the other images on a line do not move
+	sort.SliceStable(images, func(i, j int) bool {
+		if images[i].node.Line != images[j].node.Line {
+			return images[i].node.Line > images[j].node.Line
+		}
+		return images[i].node.Column > images[j].node.Column
+	})
+	return images
+}
+
+// PinImages pins the images and services of the jobs of a GitLab CI configuration to their digest, keeping the tag for
+// readability, e.g. node:20 is replaced by node:20@sha256:...
+func PinImages(ctx context.Context, inputYaml string) (string, bool, error) {
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(inputYaml), &doc); err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return inputYaml, false, nil
+	}
+
+	var edits []textedit.Replacement
+	for _, image := range findImages(doc.Content[0]) {
+		pinned, err := docker.PinImage(ctx, image.image)
+		if err != nil {
+			return inputYaml, false, err
+		}
+		edits = append(edits, textedit.Replacement{Line: image.node.Line, Column: image.node.Column, Old: image.image, New: pinned})
+	}
+	out := textedit.ApplyReplacements(inputYaml, edits)
+	return out, out != inputYaml, nil
+}
+
+// SecureConfig runs the remediations for a GitLab CI configuration. The images and services of the jobs are pinned to
+// their digest unless pinImages is false, and the includes of projects and components to the SHA of their commit unless
+// pinIncludes is false. Hard-coded GitLab tokens and jobs that use access tokens instead of the job token are reported
+// as findings, along with the includes that cannot be pinned.
+func SecureConfig(ctx context.Context, queryStringParams map[string]string, inputYaml string) (*SecureConfigResponse, error) {
+	response := &SecureConfigResponse{OriginalInput: inputYaml, FinalOutput: inputYaml}
+	tokenFindings, err := FindTokens(inputYaml)
+	if err != nil {
+		return nil, err
+	}
+	if queryStringParams["pinIncludes"] != "false" {
+		output, pinned, includeFindings, err := PinIncludes(ctx, response.FinalOutput)
+		if err != nil {
+			return nil, err
+		}
+		response.FinalOutput, response.PinnedIncludes = output, pinned
+		response.Findings = append(response.Findings, includeFindings...)
+	}
+	if queryStringParams["pinImages"] != "false" {
+		output, pinned, err := PinImages(ctx, response.FinalOutput)
+		if err != nil {
+			return nil, err
+		}
+		response.FinalOutput, response.PinnedImages = output, pinned
+	}
+	response.Findings = append(response.Findings, tokenFindings...)
+	response.IsChanged = response.PinnedImages || response.PinnedIncludes
+	return response, nil
+}
@@ -0,0 +1,58 @@
+package gitlabci
+
+import (
+	"context"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/internal/testutil"
+)
+
+func TestPinImages(t *testing.T) {
+	digest := testutil.MockRegistry(t, "library/node/manifests/20", "library/postgres/manifests/15", "library/redis/manifests/latest",
+		"library/alpine/manifests/3.19")
+
+	input := `image: alpine:3.19
+variables:
+  image: not-an-image
+default:
+  image:
+    name: "node:20"
+    entrypoint: [""]
+test:
+  services: [postgres:15, redis]
+  script: npm test
+build:
+  image: $CI_REGISTRY_IMAGE:latest
+  services:
+    - name: postgres:15@sha256:0000
+  script: make
+`
+	want := `image: alpine:3.19@` + digest + `
+variables:
+  image: not-an-image
+default:
+  image:
+    name: "node:20@` + digest + `"
+    entrypoint: [""]
+test:
+  services: [postgres:15@` + digest + `, redis:latest@` + digest + `]
+  script: npm test
+build:
+  image: $CI_REGISTRY_IMAGE:latest
+  services:
+    - name: postgres:15@sha256:0000
+  script: make
+`
+	output, updated, err := PinImages(context.Background(), input)
+	if err != nil {
+		t.Fatalf("PinImages() returned error: %v", err)
+	}
+	if !updated || output != want {
+		t.Errorf("PinImages() = %v,\n%s\nwant\n%s", updated, output, want)
+	}
+
+	output, updated, err = PinImages(context.Background(), want)
+	if err != nil || updated || output != want {
+		t.Errorf("expected pinned images to be unchanged, got %v, %v\n%s", updated, err, output)
+	}
+}
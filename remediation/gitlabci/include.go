@@ -0,0 +1,250 @@
+package gitlabci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/outbound"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleUnpinnedInclude = "gitlab-unpinned-include"
+
+	// environment variables of the GitLab instance and its token, which are the ones of the gitlab package
+	urlEnv     = "GITLAB_URL"
+	tokenEnv   = "GITLAB_TOKEN"
+	defaultURL = "https://gitlab.com"
+)
+
+var shaRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ResolveCommit returns the SHA of the commit the ref of a project points to, on the GitLab instance in GITLAB_URL
+var ResolveCommit = resolveCommit
+
+func resolveCommit(ctx context.Context, project, ref string) (string, error) {
+	baseURL := os.Getenv(urlEnv)
+	if baseURL == "" {
+		baseURL = defaultURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/api/v4/projects/"+url.PathEscape(project)+
+		"/repository/commits/"+url.PathEscape(ref), nil)
+	if err != nil {
+		return "", err
+	}
+	// public projects are resolved without a token
+	if token := os.Getenv(tokenEnv); token != "" {
+		req.Header.Set("Private-Token", token)
+	}
+	resp, err := outbound.Client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	commit := struct {
+		ID string `json:"id"`
+	}{}
+	if err := json.Unmarshal(data, &commit); err != nil {
+		return "", err
+	}
+	if !shaRegex.MatchString(commit.ID) {
+		return "", fmt.Errorf("invalid commit %q", commit.ID)
+	}
+	return commit.ID, nil
+}
+
+// instanceHost returns the host of the GitLab instance, which components on other hosts are not resolved with
+func instanceHost() string {
+	baseURL := os.Getenv(urlEnv)
+	if baseURL == "" {
+		baseURL = defaultURL
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// edit replaces the value of a node with the SHA, and keeps the ref in a comment. If insert is set, the line is
+// inserted after the line of the node instead.
+type edit struct {
+	node    *yaml.Node
+	old     string
+	new     string
+	comment string
+	insert  string
+}
+
+// getIncludes returns the mappings of the includes, which is a single include or a list of them
+func getIncludes(topNode *yaml.Node) []*yaml.Node {
+	includeNode := document.MappingValue(topNode, "include")
+	if includeNode == nil {
+		return nil
+	}
+	if includeNode.Kind == yaml.MappingNode {
+		return []*yaml.Node{includeNode}
+	}
+	var includes []*yaml.Node
+	if includeNode.Kind == yaml.SequenceNode {
+		for _, node := range includeNode.Content {
+			if node.Kind == yaml.MappingNode {
+				includes = append(includes, node)
+			}
+		}
+	}
+	return includes
+}
+
+// parseComponent returns the project and version of a component, e.g. gitlab.com/group/components/lint@1.0 is the lint
+// component of group/components, or false if the component is not on the GitLab instance
+func parseComponent(component string) (string, string, bool) {
+	at := strings.LastIndex(component, "@")
+	if at == -1 {
+		return "", "", false
+	}
+	componentPath, version := component[:at], component[at+1:]
+	parts := strings.Split(componentPath, "/")
+	if len(parts) < 4 {
+		return "", "", false
+	}
+	host := parts[0]
+	if host != "$CI_SERVER_FQDN" && host != "${CI_SERVER_FQDN}" && host != instanceHost() {
+		return "", "", false
+	}
+	return strings.Join(parts[1:len(parts)-1], "/"), version, true
+}
+
+// findIncludeEdits returns the edits that pin the project and component includes to the SHA of their commit, and the
+// findings of the includes that cannot be pinned. Refs set with variables are skipped.
+func findIncludeEdits(ctx context.Context, topNode *yaml.Node) ([]edit, []findings.Finding, error) {
+	var edits []edit
+	var includeFindings []findings.Finding
+	for _, include := range getIncludes(topNode) {
+		if projectKey, projectNode := document.MappingEntry(include, "project"); projectNode != nil && projectNode.Kind == yaml.ScalarNode {
+			if strings.Contains(projectNode.Value, "$") {
+				continue
+			}
+			refNode := document.MappingValue(include, "ref")
+			if refNode == nil {
+				// an include without a ref is of the default branch, whose head is pinned on a new line
+				if include.Style&yaml.FlowStyle != 0 || projectNode.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+					continue
+				}
+				sha, err := ResolveCommit(ctx, projectNode.Value, "HEAD")
+				if err != nil {
+					return nil, nil, fmt.Errorf("unable to get commit of %s: %v", projectNode.Value, err)
+				}
+				edits = append(edits, edit{node: projectNode, insert: strings.Repeat(" ", projectKey.Column-1) + "ref: " + sha + "  # default branch"})
+				continue
+			}
+			if refNode.Kind != yaml.ScalarNode || shaRegex.MatchString(refNode.Value) || strings.Contains(refNode.Value, "$") {
+				continue
+			}
+			sha, err := ResolveCommit(ctx, projectNode.Value, refNode.Value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to get commit of %s@%s: %v", projectNode.Value, refNode.Value, err)
+			}
+			edits = append(edits, edit{node: refNode, old: refNode.Value, new: sha, comment: refNode.Value})
+			continue
+		}
+
+		if componentNode := document.MappingValue(include, "component"); componentNode != nil && componentNode.Kind == yaml.ScalarNode {
+			project, version, ok := parseComponent(componentNode.Value)
+			if !ok || shaRegex.MatchString(version) || strings.Contains(project, "$") {
+				continue
+			}
+			if strings.HasPrefix(version, "~") {
+				// ~latest is the latest release, which is not a ref
+				includeFindings = append(includeFindings, findings.Finding{
+					RuleID:     RuleUnpinnedInclude,
+					Message:    fmt.Sprintf("Component %s is included with its latest release, which can change without a change to the configuration", componentNode.Value),
+					Line:       componentNode.Line,
+					Column:     componentNode.Column,
+					Suggestion: "Include the component with the SHA of the commit of a release",
+				})
+				continue
+			}
+			sha, err := ResolveCommit(ctx, project, version)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to get commit of %s: %v", componentNode.Value, err)
+			}
+			pinned := componentNode.Value[:len(componentNode.Value)-len(version)] + sha
+			edits = append(edits, edit{node: componentNode, old: componentNode.Value, new: pinned, comment: version})
+			continue
+		}
+
+		if remoteNode := document.MappingValue(include, "remote"); remoteNode != nil && document.MappingValue(include, "integrity") == nil {
+			includeFindings = append(includeFindings, findings.Finding{
+				RuleID:     RuleUnpinnedInclude,
+				Message:    fmt.Sprintf("Remote configuration %s is included without its integrity, so it can change without a change to the configuration", remoteNode.Value),
+				Line:       remoteNode.Line,
+				Column:     remoteNode.Column,
+				Suggestion: "Add integrity: sha256-... with the base64 SHA-256 hash of the file",
+			})
+		}
+	}
+	return edits, includeFindings, nil
+}
+
+// applyEdits applies the edits to the lines from the end, so the lines and columns of the other edits do not move
+func applyEdits(inputYaml string, edits []edit) string {
+	buffer := textedit.NewBuffer(inputYaml)
+	for _, e := range edits {
+		if e.insert != "" {
+			buffer.InsertLine(e.node.Line, e.insert)
+			continue
+		}
+		line, lineStart := buffer.Line(e.node.Line)
+		// the column is the start of the value, which may be quoted
+		start := textedit.ColumnOffset(line, e.node.Column)
+		offset := strings.Index(line[start:], e.old)
+		if offset == -1 {
+			continue
+		}
+		start += offset
+		rest := line[start+len(e.old):]
+		// the ref is kept in a comment if the value ends the line
+		if e.comment != "" && strings.TrimSpace(strings.Trim(rest, `"'`)) == "" {
+			buffer.Replace(lineStart+start, lineStart+len(line), strings.TrimRight(e.new+rest, " \t\r")+"  # "+e.comment)
+			continue
+		}
+		buffer.Replace(lineStart+start, lineStart+start+len(e.old), e.new)
+	}
+	return buffer.String()
+}
+
+// PinIncludes pins the includes of projects and components of a GitLab CI configuration to the SHA of their commit, keeping
+// the ref in a comment, e.g. ref: v1.2 is replaced by ref: <sha>  # v1.2. Remote includes without integrity, and
+// components included with ~latest are returned as findings.
+func PinIncludes(ctx context.Context, inputYaml string) (string, bool, []findings.Finding, error) {
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(inputYaml), &doc); err != nil {
+		return inputYaml, false, nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return inputYaml, false, nil, nil
+	}
+	edits, includeFindings, err := findIncludeEdits(ctx, doc.Content[0])
+	if err != nil || len(edits) == 0 {
+		return inputYaml, false, includeFindings, err
+	}
+	return applyEdits(inputYaml, edits), true, includeFindings, nil
+}
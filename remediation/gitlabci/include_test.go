@@ -0,0 +1,117 @@
+package gitlabci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestPinIncludes(t *testing.T) {
+	const sha = "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2"
+	saveResolveCommit := ResolveCommit
+	var resolved []string
+	ResolveCommit = func(_ context.Context, project, ref string) (string, error) {
+		resolved = append(resolved, project+"@"+ref)
+		if project == "group/missing" {
+			return "", fmt.Errorf("status code 404")
+		}
+		return sha, nil
+	}
+	defer func() { ResolveCommit = saveResolveCommit }()
+
+	input := `include:
+  - local: /templates/build.yml
+  - project: group/templates
+    ref: v1.2
+    file: /ci/test.yml
+  - project: 'group/lint'
+    file: /lint.yml
+  - { project: group/flow, ref: main, file: /flow.yml }
+  - project: group/pinned
+    ref: ` + sha + `
+  - project: $TEMPLATES_PROJECT
+    ref: main
+  - component: $CI_SERVER_FQDN/group/components/sast@1.0.0
+  - component: gitlab.com/group/components/lint@~latest
+  - component: example.com/group/components/deploy@2.0
+  - remote: https://example.com/ci.yml
+stages: [test]
+`
+	want := `include:
+  - local: /templates/build.yml
+  - project: group/templates
+    ref: ` + sha + `  # v1.2
+    file: /ci/test.yml
+  - project: 'group/lint'
+    ref: ` + sha + `  # default branch
+    file: /lint.yml
+  - { project: group/flow, ref: ` + sha + `, file: /flow.yml }
+  - project: group/pinned
+    ref: ` + sha + `
+  - project: $TEMPLATES_PROJECT
+    ref: main
+  - component: $CI_SERVER_FQDN/group/components/sast@` + sha + `  # 1.0.0
+  - component: gitlab.com/group/components/lint@~latest
+  - component: example.com/group/components/deploy@2.0
+  - remote: https://example.com/ci.yml
+stages: [test]
+`
+	output, updated, includeFindings, err := PinIncludes(context.Background(), input)
+	if err != nil {
+		t.Fatalf("PinIncludes() returned error: %v", err)
+	}
+	if !updated || output != want {
+		t.Errorf("PinIncludes() = %v,\n%s\nwant\n%s", updated, output, want)
+	}
+	if fmt.Sprint(resolved) != "[group/templates@v1.2 group/lint@HEAD group/flow@main group/components@1.0.0]" {
+		t.Errorf("resolved refs = %v", resolved)
+	}
+	if len(includeFindings) != 2 || includeFindings[0].Line != 14 || includeFindings[1].Line != 16 || includeFindings[1].RuleID != RuleUnpinnedInclude {
+		t.Errorf("findings = %+v, want the component with ~latest and the remote include", includeFindings)
+	}
+
+	output, updated, _, err = PinIncludes(context.Background(), want)
+	if err != nil || updated || output != want {
+		t.Errorf("expected pinned includes to be unchanged, got %v, %v\n%s", updated, err, output)
+	}
+
+	if _, _, _, err := PinIncludes(context.Background(), "include:\n  project: group/missing\n  ref: main\n"); err == nil {
+		t.Errorf("PinIncludes() expected an error when the ref cannot be resolved")
+	}
+}
+
+func TestResolveCommit(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	os.Setenv(urlEnv, "https://gitlab.example.com/")
+	os.Setenv(tokenEnv, "glpat-token")
+	defer os.Unsetenv(urlEnv)
+	defer os.Unsetenv(tokenEnv)
+
+	httpmock.RegisterResponder("GET", "https://gitlab.example.com/api/v4/projects/group%2Ftemplates/repository/commits/v1.2",
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Private-Token") != "glpat-token" {
+				return httpmock.NewStringResponse(http.StatusUnauthorized, `{"message": "401 Unauthorized"}`), nil
+			}
+			return httpmock.NewStringResponse(http.StatusOK, `{"id": "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2"}`), nil
+		})
+	sha, err := resolveCommit(context.Background(), "group/templates", "v1.2")
+	if err != nil || sha != "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2" {
+		t.Errorf("resolveCommit() = %s, %v", sha, err)
+	}
+	if _, err := resolveCommit(context.Background(), "group/templates", "v2"); err == nil {
+		t.Errorf("resolveCommit() expected an error for an unknown ref")
+	}
+
+	// components of other hosts than the instance are not resolved
+	if _, _, ok := parseComponent("gitlab.com/group/components/lint@1.0"); ok {
+		t.Errorf("parseComponent() expected a component of gitlab.com to be skipped on gitlab.example.com")
+	}
+	if project, version, ok := parseComponent("gitlab.example.com/group/sub/components/lint@1.0"); !ok || project != "group/sub/components" || version != "1.0" {
+		t.Errorf("parseComponent() = %s, %s, %v", project, version, ok)
+	}
+}
@@ -0,0 +1,108 @@
+package gitlabci
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleHardcodedToken = "gitlab-hardcoded-token"
+	RuleAccessToken    = "gitlab-access-token"
+)
+
+// hardcodedTokenRegex matches the prefixes of the access, deploy, trigger, runner and OAuth tokens of GitLab
+var hardcodedTokenRegex = regexp.MustCompile(`\b(glpat|gldt|glptt|glrt|gloas|GR1348941)-?[0-9A-Za-z_-]{20,}`)
+
+// accessTokenRegexes match the variables of tokens used to call the API or clone repositories in a script. Unlike
+// CI_JOB_TOKEN, the token has the permissions of its user or bot in all of its projects, and is valid after the job.
+var accessTokenRegexes = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)PRIVATE-TOKEN:\s*\$\{?(\w+)`),
+	regexp.MustCompile(`(?i)https?://[^\s:/@]+:\$\{?(\w+)\}?@`),
+	regexp.MustCompile(`(?i)glab\s+auth\s+login\b[^\n]*--token[=\s]+"?\$\{?(\w+)`),
+}
+
+// scriptKeys are the keys of the scripts of a job
+var scriptKeys = []string{"before_script", "script", "after_script"}
+
+// findHardcodedTokens returns findings for the values of the configuration that are GitLab tokens, which are exposed to
+// everyone who can read the repository
+func findHardcodedTokens(node *yaml.Node, tokenFindings []findings.Finding) []findings.Finding {
+	if node.Kind == yaml.ScalarNode {
+		if match := hardcodedTokenRegex.FindStringSubmatch(node.Value); match != nil {
+			tokenFindings = append(tokenFindings, findings.Finding{
+				RuleID:     RuleHardcodedToken,
+				Message:    fmt.Sprintf("A GitLab token starting with %s is in the configuration", match[1]),
+				Line:       node.Line,
+				Column:     node.Column,
+				Suggestion: "Revoke the token, and store a new one as a masked and protected CI/CD variable",
+			})
+		}
+		return tokenFindings
+	}
+	for _, child := range node.Content {
+		tokenFindings = findHardcodedTokens(child, tokenFindings)
+	}
+	return tokenFindings
+}
+
+// findAccessTokens returns findings for the jobs whose scripts use a token other than CI_JOB_TOKEN
+func findAccessTokens(topNode *yaml.Node) []findings.Finding {
+	var tokenFindings []findings.Finding
+	for i := 0; i+1 < len(topNode.Content); i += 2 {
+		jobName, jobNode := topNode.Content[i].Value, topNode.Content[i+1]
+		if keywords[jobName] || jobNode.Kind != yaml.MappingNode {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, key := range scriptKeys {
+			scriptNode := document.MappingValue(jobNode, key)
+			if scriptNode == nil {
+				continue
+			}
+			lines := []*yaml.Node{scriptNode}
+			if scriptNode.Kind == yaml.SequenceNode {
+				lines = scriptNode.Content
+			}
+			for _, line := range lines {
+				if line.Kind != yaml.ScalarNode {
+					continue
+				}
+				for _, regex := range accessTokenRegexes {
+					for _, match := range regex.FindAllStringSubmatch(line.Value, -1) {
+						variable := match[1]
+						if variable == "CI_JOB_TOKEN" || seen[variable] {
+							continue
+						}
+						seen[variable] = true
+						tokenFindings = append(tokenFindings, findings.Finding{
+							RuleID:     RuleAccessToken,
+							Message:    fmt.Sprintf("Job %s uses the token in %s, which has the permissions of its user or bot in all of its projects", jobName, variable),
+							JobName:    jobName,
+							Line:       line.Line,
+							Column:     line.Column,
+							Suggestion: "Use CI_JOB_TOKEN, and add the project to the job token allowlist of the projects it accesses",
+						})
+					}
+				}
+			}
+		}
+	}
+	return tokenFindings
+}
+
+// FindTokens returns findings for the GitLab tokens in a GitLab CI configuration, and for the jobs that use access tokens
+// where the job token is enough
+func FindTokens(inputYaml string) ([]findings.Finding, error) {
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(inputYaml), &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	return append(findHardcodedTokens(doc.Content[0], nil), findAccessTokens(doc.Content[0])...), nil
+}
@@ -0,0 +1,269 @@
+// Package gitpush clones a repository, applies the changes of the remediations on a branch, and commits and pushes them
+// with a standardized message, for consumers that push the remediations themselves instead of opening pull requests
+// through the API of a hosting service.
+package gitpush
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/githubapp"
+)
+
+const (
+	DefaultMessage     = "[StepSecurity] Apply security best practices"
+	DefaultAuthorName  = "StepSecurity"
+	DefaultAuthorEmail = "bot@stepsecurity.io"
+	// DefaultUsername is the username of tokens for GitHub. GitLab accepts any username, and Bitbucket access tokens use x-token-auth.
+	DefaultUsername = "x-access-token"
+
+	// Trailer is added to the commit messages, so the commits of the remediations can be found, e.g. with
+	// git log --grep "Remediated-by: secure-repo"
+	Trailer = "Remediated-by: secure-repo"
+)
+
+// Options configures the repository that is cloned, and the branch and commit the changes are pushed with
+type Options struct {
+	// URL is the HTTPS URL of the repository, e.g. https://github.com/octo-org/app.git
+	URL string
+	// Token authenticates the clone and the push. If it is empty and InstallationID is set, an installation token of the
+	// GitHub App is used.
+	Token          string
+	Username       string
+	InstallationID int64
+	// BaseBranch is the branch the changes are applied to, which defaults to the default branch of the repository
+	BaseBranch string
+	// Branch is the branch the changes are pushed to, which defaults to the remediation branch of the GitHub App. It is
+	// force pushed, so it always has a single commit on the base branch.
+	Branch      string
+	Message     string
+	AuthorName  string
+	AuthorEmail string
+	// Dir is the directory the repository is cloned to, which defaults to a temporary directory removed by Close
+	Dir string
+}
+
+// Checkout is a clone of the repository, on the branch the changes are pushed to
+type Checkout struct {
+	Dir        string
+	BaseBranch string
+	opts       Options
+	env        []string
+	temporary  bool
+	changed    []string
+}
+
+// Result is the outcome of pushing the changes. Pushed is false if the changes did not change any file.
+type Result struct {
+	Branch string
+	Commit string
+	Pushed bool
+}
+
+// authEnv returns the environment variables that pass the token to git as a header, so it is not stored in the remote
+// URL of the clone, and not visible in the arguments of the git processes
+func authEnv(username, token string) []string {
+	if token == "" {
+		return nil
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + token))
+	return []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0=Authorization: Basic " + credentials}
+}
+
+// git runs a git command in the directory, and returns its output
+func (c *Checkout) git(ctx context.Context, stdin string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = c.Dir
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), c.env...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Clone clones the base branch of the repository, and creates the branch the changes are pushed to from it
+func Clone(ctx context.Context, opts Options) (*Checkout, error) {
+	if opts.Username == "" {
+		opts.Username = DefaultUsername
+	}
+	if opts.Branch == "" {
+		opts.Branch = githubapp.RemediationBranch
+	}
+	if opts.Token == "" && opts.InstallationID != 0 {
+		token, err := githubapp.GetInstallationToken(ctx, opts.InstallationID)
+		if err != nil {
+			return nil, err
+		}
+		opts.Token = token
+	}
+
+	checkout := &Checkout{Dir: opts.Dir, opts: opts, env: authEnv(opts.Username, opts.Token)}
+	if checkout.Dir == "" {
+		dir, err := os.MkdirTemp("", "secure-repo-")
+		if err != nil {
+			return nil, err
+		}
+		checkout.Dir, checkout.temporary = dir, true
+	}
+	args := []string{"clone", "--depth", "1", "--no-tags"}
+	if opts.BaseBranch != "" {
+		args = append(args, "--branch", opts.BaseBranch)
+	}
+	if _, err := checkout.git(ctx, "", append(args, "--", opts.URL, ".")...); err != nil {
+		checkout.Close()
+		return nil, err
+	}
+
+	checkout.BaseBranch = opts.BaseBranch
+	if checkout.BaseBranch == "" {
+		branch, err := checkout.git(ctx, "", "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			checkout.Close()
+			return nil, err
+		}
+		checkout.BaseBranch = branch
+	}
+	if _, err := checkout.git(ctx, "", "checkout", "-B", opts.Branch); err != nil {
+		checkout.Close()
+		return nil, err
+	}
+	return checkout, nil
+}
+
+// Apply writes the files, keyed by their slash separated path in the repository. Paths outside of the repository, in
+// its .git directory, or through a symlink of the repository are rejected.
+func (c *Checkout) Apply(files map[string]string) error {
+	paths := make([]string, 0, len(files))
+	for filePath := range files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	for _, filePath := range paths {
+		fullPath, err := c.createParents(filePath)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(fullPath, []byte(files[filePath]), 0644); err != nil {
+			return err
+		}
+		c.changed = append(c.changed, filePath)
+	}
+	return nil
+}
+
+// createParents creates the missing parent directories of the path, and returns its full path. Each component of the
+// path is checked with Lstat, so a symlink committed to the repository cannot redirect the write outside of it, and a
+// .git component is rejected in any case, as the file systems of macOS and Windows are case insensitive.
+func (c *Checkout) createParents(filePath string) (string, error) {
+	localPath := filepath.FromSlash(filePath)
+	if !filepath.IsLocal(localPath) {
+		return "", fmt.Errorf("invalid path %s", filePath)
+	}
+	components := strings.Split(filepath.Clean(localPath), string(filepath.Separator))
+	fullPath := c.Dir
+	for i, component := range components {
+		if strings.EqualFold(component, ".git") {
+			return "", fmt.Errorf("invalid path %s", filePath)
+		}
+		fullPath = filepath.Join(fullPath, component)
+		info, err := os.Lstat(fullPath)
+		if os.IsNotExist(err) {
+			if i < len(components)-1 {
+				if err := os.Mkdir(fullPath, 0755); err != nil {
+					return "", err
+				}
+			}
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("invalid path %s: %s is a symlink", filePath, filepath.ToSlash(filepath.Join(components[:i+1]...)))
+		}
+		if i < len(components)-1 && !info.IsDir() || i == len(components)-1 && !info.Mode().IsRegular() {
+			return "", fmt.Errorf("invalid path %s", filePath)
+		}
+	}
+	return fullPath, nil
+}
+
+// getMessage returns the commit message, with the changed files and the trailer
+func (c *Checkout) getMessage() string {
+	message := c.opts.Message
+	if message == "" {
+		message = DefaultMessage
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.TrimSpace(message) + "\n\n")
+	for _, filePath := range c.changed {
+		sb.WriteString("- " + filePath + "\n")
+	}
+	sb.WriteString("\n" + Trailer + "\n")
+	return sb.String()
+}
+
+// CommitAndPush commits the applied files, and force pushes the branch. Nothing is pushed if the files did not change.
+func (c *Checkout) CommitAndPush(ctx context.Context) (*Result, error) {
+	result := &Result{Branch: c.opts.Branch}
+	if len(c.changed) == 0 {
+		return result, nil
+	}
+	if _, err := c.git(ctx, "", append([]string{"add", "--"}, c.changed...)...); err != nil {
+		return nil, err
+	}
+	if status, err := c.git(ctx, "", "status", "--porcelain"); err != nil || status == "" {
+		return result, err
+	}
+
+	authorName, authorEmail := c.opts.AuthorName, c.opts.AuthorEmail
+	if authorName == "" {
+		authorName = DefaultAuthorName
+	}
+	if authorEmail == "" {
+		authorEmail = DefaultAuthorEmail
+	}
+	if _, err := c.git(ctx, c.getMessage(), "-c", "user.name="+authorName, "-c", "user.email="+authorEmail, "commit", "--no-verify", "-F", "-"); err != nil {
+		return nil, err
+	}
+	commit, err := c.git(ctx, "", "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.git(ctx, "", "push", "--force", "origin", "HEAD:refs/heads/"+c.opts.Branch); err != nil {
+		return nil, err
+	}
+	result.Commit, result.Pushed = commit, true
+	return result, nil
+}
+
+// Close removes the clone, if it was cloned to a temporary directory
+func (c *Checkout) Close() error {
+	if !c.temporary {
+		return nil
+	}
+	return os.RemoveAll(c.Dir)
+}
+
+// ApplyAndPush clones the repository, applies the files on the branch, and commits and pushes them
+func ApplyAndPush(ctx context.Context, opts Options, files map[string]string) (*Result, error) {
+	checkout, err := Clone(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer checkout.Close()
+	if err := checkout.Apply(files); err != nil {
+		return nil, err
+	}
+	return checkout.CommitAndPush(ctx)
+}
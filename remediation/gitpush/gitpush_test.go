@@ -0,0 +1,111 @@
+package gitpush
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runGit runs git in the directory, and fails the test if it fails
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v: %s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// newRemote returns the URL of a bare repository with a commit with a workflow on main
+func newRemote(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	remote, work := filepath.Join(t.TempDir(), "remote.git"), t.TempDir()
+	runGit(t, work, "init", "--bare", "--initial-branch=main", remote)
+	runGit(t, work, "init", "--initial-branch=main")
+	os.MkdirAll(filepath.Join(work, ".github", "workflows"), 0755)
+	os.WriteFile(filepath.Join(work, ".github", "workflows", "ci.yml"), []byte("on: push\n"), 0644)
+	runGit(t, work, "add", ".")
+	runGit(t, work, "commit", "-m", "initial")
+	runGit(t, work, "push", remote, "main")
+	return "file://" + remote
+}
+
+func TestApplyAndPush(t *testing.T) {
+	remote := newRemote(t)
+	files := map[string]string{".github/workflows/ci.yml": "on: push\npermissions:\n  contents: read\n", ".github/dependabot.yml": "version: 2\n"}
+
+	result, err := ApplyAndPush(context.Background(), Options{URL: remote, Token: "token"}, files)
+	if err != nil {
+		t.Fatalf("ApplyAndPush() returned error: %v", err)
+	}
+	if !result.Pushed || result.Branch != "stepsecurity/remediation" || result.Commit == "" {
+		t.Fatalf("expected the changes to be pushed, got %+v", result)
+	}
+
+	remoteDir := strings.TrimPrefix(remote, "file://")
+	message := runGit(t, remoteDir, "log", "-1", "--format=%B", "stepsecurity/remediation")
+	want := DefaultMessage + "\n\n- .github/dependabot.yml\n- .github/workflows/ci.yml\n\n" + Trailer
+	if message != want {
+		t.Errorf("commit message = %q, want %q", message, want)
+	}
+	if parent := runGit(t, remoteDir, "rev-parse", "stepsecurity/remediation^"); parent != runGit(t, remoteDir, "rev-parse", "main") {
+		t.Errorf("expected the branch to have a single commit on main")
+	}
+	if content := runGit(t, remoteDir, "show", "stepsecurity/remediation:.github/workflows/ci.yml"); !strings.Contains(content, "contents: read") {
+		t.Errorf("expected the workflow to be changed, got %s", content)
+	}
+
+	// the branch is force pushed, and unchanged files are not committed
+	result, err = ApplyAndPush(context.Background(), Options{URL: remote, BaseBranch: "main", Message: "Pin actions"}, files)
+	if err != nil || !result.Pushed {
+		t.Fatalf("ApplyAndPush() = %+v, %v", result, err)
+	}
+	if count := runGit(t, remoteDir, "rev-list", "--count", "main..stepsecurity/remediation"); count != "1" {
+		t.Errorf("expected the branch to be replaced, got %s commits", count)
+	}
+	result, err = ApplyAndPush(context.Background(), Options{URL: remote}, map[string]string{".github/workflows/ci.yml": "on: push\n"})
+	if err != nil || result.Pushed {
+		t.Errorf("expected nothing to be pushed for unchanged files, got %+v, %v", result, err)
+	}
+}
+
+func TestApplyInvalidPath(t *testing.T) {
+	checkout := &Checkout{Dir: t.TempDir()}
+	for _, filePath := range []string{"../outside", "/etc/passwd", ".git/config", ".GIT/config", "a/../../outside", "sub/.Git/hooks/pre-commit"} {
+		if err := checkout.Apply(map[string]string{filePath: "content"}); err == nil {
+			t.Errorf("expected an error for %s", filePath)
+		}
+	}
+}
+
+func TestApplySymlink(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	// the repository has a symlinked directory and a symlinked file, both pointing outside of it
+	os.Symlink(outside, filepath.Join(dir, ".github"))
+	os.WriteFile(filepath.Join(outside, "target"), []byte("target"), 0644)
+	os.Symlink(filepath.Join(outside, "target"), filepath.Join(dir, "README.md"))
+
+	checkout := &Checkout{Dir: dir}
+	for _, filePath := range []string{".github/workflows/ci.yml", ".github/dependabot.yml", "README.md"} {
+		if err := checkout.Apply(map[string]string{filePath: "content"}); err == nil {
+			t.Errorf("expected an error for %s", filePath)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 1 {
+		t.Errorf("expected nothing to be written outside of the repository, got %v", entries)
+	}
+	if content, _ := os.ReadFile(filepath.Join(outside, "target")); string(content) != "target" {
+		t.Errorf("expected the target of the symlink not to be changed, got %s", content)
+	}
+
+	if err := checkout.Apply(map[string]string{"docs/security/policy.md": "content"}); err != nil {
+		t.Errorf("Apply() returned error: %v", err)
+	}
+}
@@ -0,0 +1,358 @@
+// Package grpcapi serves the remediation APIs over gRPC, for the clients that use gRPC instead of the HTTP API. The
+// service is defined in proto/securerepo/v1, and runs the same remediations as the routes of the HTTP API. Archives of
+// repositories are streamed in chunks in both directions, so large repositories do not need to fit in a single message.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	securerepov1 "github.com/step-security/secure-repo/proto/securerepo/v1"
+	"github.com/step-security/secure-repo/remediation/auth"
+	"github.com/step-security/secure-repo/remediation/cache"
+	"github.com/step-security/secure-repo/remediation/diff"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/metrics"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+	"github.com/step-security/secure-repo/remediation/workflow"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+	"github.com/step-security/secure-repo/remediation/workflow/runnerlabel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// AddrEnv is the address the gRPC API is served on, e.g. :8081. It is not served if it is not set.
+	AddrEnv = "GRPC_ADDR"
+	// ChunkSize is the size of the chunks of the archives that are sent back, below the default message size of gRPC
+	ChunkSize = 1024 * 1024
+)
+
+// Server implements the SecureRepoService of proto/securerepo/v1
+type Server struct {
+	securerepov1.UnimplementedSecureRepoServiceServer
+	// Authenticator authenticates the calls with the x-api-key or authorization metadata, as the requests are with the
+	// headers, and limits the calls of each tenant. The calls are not authenticated if it is nil.
+	Authenticator *auth.Authenticator
+	// DynamoDB is the client of the table the missing actions are stored in
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// NewServer returns a gRPC server with the service registered, which logs each call and counts it in the metrics of
+// the requests by method
+func NewServer(server *Server, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{grpc.UnaryInterceptor(server.interceptUnary), grpc.StreamInterceptor(server.interceptStream)}, opts...)
+	grpcServer := grpc.NewServer(opts...)
+	securerepov1.RegisterSecureRepoServiceServer(grpcServer, server)
+	return grpcServer
+}
+
+// authenticate returns the status of a call that is not authenticated or is over the rate limit, and sets the
+// retry-after header when the tenant is over the rate limit
+func (s *Server) authenticate(ctx context.Context, setHeader func(metadata.MD) error) error {
+	if s.Authenticator == nil {
+		return nil
+	}
+	incoming, _ := metadata.FromIncomingContext(ctx)
+	headers := map[string]string{}
+	for _, header := range []string{auth.APIKeyHeader, auth.AuthorizationHeader} {
+		if values := incoming.Get(header); len(values) > 0 {
+			headers[header] = values[0]
+		}
+	}
+	_, err := s.Authenticator.Authenticate(headers)
+	if err == nil {
+		return nil
+	}
+	authError, ok := err.(*auth.Error)
+	if !ok {
+		return status.Error(codes.Internal, err.Error())
+	}
+	switch authError.StatusCode {
+	case http.StatusUnauthorized:
+		return status.Error(codes.Unauthenticated, authError.Message)
+	case http.StatusTooManyRequests:
+		if authError.RetryAfter > 0 {
+			setHeader(metadata.Pairs("retry-after", strconv.Itoa(authError.RetryAfter)))
+		}
+		return status.Error(codes.ResourceExhausted, authError.Message)
+	}
+	return status.Error(codes.Internal, authError.Message)
+}
+
+// observe logs the call with its status and duration, and counts it in the metrics of the requests by method
+func observe(method string, start time.Time, err error) {
+	duration := time.Since(start)
+	code := status.Code(err)
+	metrics.Requests.Inc(method, code.String())
+	metrics.RequestDuration.Observe(duration.Seconds(), method)
+	attrs := []any{"method", method, "code", code.String(), "duration_ms", duration.Milliseconds()}
+	switch code {
+	case codes.Internal, codes.Unknown:
+		logging.Logger().Error("handled call", append(attrs, "error", err)...)
+	default:
+		logging.Logger().Info("handled call", attrs...)
+	}
+}
+
+func (s *Server) interceptUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	var resp interface{}
+	err := s.authenticate(ctx, func(md metadata.MD) error { return grpc.SetHeader(ctx, md) })
+	if err == nil {
+		resp, err = handler(ctx, req)
+	}
+	observe(info.FullMethod, start, err)
+	return resp, err
+}
+
+func (s *Server) interceptStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := s.authenticate(stream.Context(), stream.SetHeader)
+	if err == nil {
+		err = handler(srv, stream)
+	}
+	observe(info.FullMethod, start, err)
+	return err
+}
+
+// toStatus returns the status of an error of the remediations, which is the status of the context when the call was
+// canceled or timed out
+func toStatus(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// copyParams returns a copy of the params of a request, since the remediations add their own params to them
+func copyParams(params map[string]string) map[string]string {
+	copied := make(map[string]string, len(params))
+	for key, value := range params {
+		copied[key] = value
+	}
+	return copied
+}
+
+// SecureWorkflow runs the enabled remediations on the workflow, as the /secure-workflow route
+func (s *Server) SecureWorkflow(ctx context.Context, request *securerepov1.SecureWorkflowRequest) (*securerepov1.SecureWorkflowResponse, error) {
+	if request.Workflow == "" {
+		return nil, status.Error(codes.InvalidArgument, "workflow is empty")
+	}
+	params := copyParams(request.Params)
+	exemptedActions := request.ExemptedActions
+	if exemptedActions == nil {
+		exemptedActions = []string{}
+	}
+	runnerLabels := request.RunnerLabels
+	if runnerLabels == nil {
+		runnerLabels = map[string]string{}
+	}
+	logger := logging.Logger().With("method", securerepov1.SecureRepoService_SecureWorkflow_FullMethodName)
+	// the maintained actions and the commits of actions keep their defaults, as for the HTTP API
+	fixResponse, err := workflow.SecureWorkflowCached(ctx, cache.Default(), params, request.Workflow, s.DynamoDB, workflow.Options{
+		ExemptedActions: exemptedActions, PinToImmutable: request.PinToImmutable, RunnerLabels: runnerLabels, Logger: logger})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &securerepov1.SecureWorkflowResponse{
+		FinalOutput:          fixResponse.FinalOutput,
+		IsChanged:            fixResponse.FinalOutput != fixResponse.OriginalInput,
+		HasErrors:            fixResponse.HasErrors,
+		PinnedActions:        fixResponse.PinnedActions,
+		AddedHardenRunner:    fixResponse.AddedHardenRunner,
+		AddedPermissions:     fixResponse.AddedPermissions,
+		ReplacedRunnerLabels: fixResponse.ReplacedRunnerLabels,
+		MissingActions:       fixResponse.MissingActions,
+		Findings:             toFindings(fixResponse.Findings),
+	}
+	if fixResponse.Report != nil {
+		response.Report = toModuleReports(fixResponse.Report.Modules)
+	}
+	if params["output"] == "diff" {
+		diffPath := params["path"]
+		if diffPath == "" {
+			diffPath = ".github/workflows/workflow.yml"
+		}
+		response.Diff = diff.Unified(diffPath, fixResponse.OriginalInput, fixResponse.FinalOutput)
+		response.FinalOutput = ""
+	}
+	return response, nil
+}
+
+// PinActions pins the actions and docker images of the workflow or composite action
+func (s *Server) PinActions(ctx context.Context, request *securerepov1.PinActionsRequest) (*securerepov1.PinActionsResponse, error) {
+	if request.Input == "" {
+		return nil, status.Error(codes.InvalidArgument, "input is empty")
+	}
+	exemptedActions := request.ExemptedActions
+	if exemptedActions == nil {
+		exemptedActions = []string{}
+	}
+	output, pinnedActions, err := pin.PinActions(ctx, request.Input, exemptedActions, request.PinToImmutable, map[string]string{})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	output, pinnedImages, err := pin.PinDocker(ctx, output)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &securerepov1.PinActionsResponse{FinalOutput: output, PinnedActions: pinnedActions || pinnedImages}, nil
+}
+
+// ReplaceRunnerLabels replaces the runner labels of the jobs in the workflow
+func (s *Server) ReplaceRunnerLabels(ctx context.Context, request *securerepov1.ReplaceRunnerLabelsRequest) (*securerepov1.ReplaceRunnerLabelsResponse, error) {
+	if request.Workflow == "" {
+		return nil, status.Error(codes.InvalidArgument, "workflow is empty")
+	}
+	output, replaced, err := runnerlabel.ReplaceRunnerLabels(request.Workflow, request.RunnerLabels)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &securerepov1.ReplaceRunnerLabelsResponse{FinalOutput: output, ReplacedRunnerLabels: replaced}, nil
+}
+
+// SecureRepo runs the remediations on the files of the repository, as the /secure-repo route
+func (s *Server) SecureRepo(ctx context.Context, request *securerepov1.SecureRepoRequest) (*securerepov1.SecureRepoResponse, error) {
+	if len(request.Files) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "files are empty")
+	}
+	secureRepoRequest := securerepo.SecureRepoRequest{}
+	for _, file := range request.Files {
+		secureRepoRequest.FileList = append(secureRepoRequest.FileList, securerepo.File{Path: file.Path, Content: file.Content})
+	}
+	fixResponse, err := securerepo.SecureRepo(ctx, copyParams(request.Params), secureRepoRequest, s.DynamoDB)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toSecureRepoResponse(fixResponse), nil
+}
+
+// SecureRepoArchive reads the archive from the chunks of the stream, runs the remediations on its files, and sends the
+// response followed by the remediated archive in chunks. The format and the params are read from the first chunk.
+func (s *Server) SecureRepoArchive(stream securerepov1.SecureRepoService_SecureRepoArchiveServer) error {
+	var first *securerepov1.ArchiveChunk
+	var data []byte
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if first == nil {
+			first = chunk
+		}
+		if len(data)+len(chunk.Data) > securerepo.MaxArchiveSize {
+			return status.Errorf(codes.ResourceExhausted, "archive is larger than %d bytes", securerepo.MaxArchiveSize)
+		}
+		data = append(data, chunk.Data...)
+	}
+	if len(data) == 0 {
+		return status.Error(codes.InvalidArgument, "archive is empty")
+	}
+
+	fixResponse, err := securerepo.SecureRepo(stream.Context(), copyParams(first.Params),
+		securerepo.SecureRepoRequest{Archive: data, ArchiveFormat: first.ArchiveFormat}, s.DynamoDB)
+	if err != nil {
+		return toStatus(err)
+	}
+	// the files are in the archive, so only the report is sent before it
+	response := toSecureRepoResponse(fixResponse)
+	response.Files = nil
+	if err := stream.Send(&securerepov1.SecureRepoArchiveResponse{Result: &securerepov1.SecureRepoArchiveResponse_Response{Response: response}}); err != nil {
+		return err
+	}
+	for start := 0; start < len(fixResponse.Archive); start += ChunkSize {
+		end := start + ChunkSize
+		if end > len(fixResponse.Archive) {
+			end = len(fixResponse.Archive)
+		}
+		if err := stream.Send(&securerepov1.SecureRepoArchiveResponse{Result: &securerepov1.SecureRepoArchiveResponse_Data{Data: fixResponse.Archive[start:end]}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toFindings(detected []findings.Finding) []*securerepov1.Finding {
+	var result []*securerepov1.Finding
+	for _, finding := range detected {
+		result = append(result, &securerepov1.Finding{
+			RuleId:     finding.RuleID,
+			Message:    finding.Message,
+			JobName:    finding.JobName,
+			Action:     finding.Action,
+			Line:       int32(finding.Line),
+			Column:     int32(finding.Column),
+			Suggestion: finding.Suggestion,
+			Fixed:      finding.Fixed,
+		})
+	}
+	return result
+}
+
+func toModuleReports(modules []report.Module) []*securerepov1.ModuleReport {
+	var result []*securerepov1.ModuleReport
+	for _, module := range modules {
+		moduleReport := &securerepov1.ModuleReport{Name: module.Name, Errors: module.Errors}
+		for _, change := range module.Changes {
+			moduleReport.Changes = append(moduleReport.Changes, &securerepov1.Change{File: change.File, Line: int32(change.Line),
+				Kind: change.Kind, Before: change.Before, After: change.After})
+		}
+		for _, skipped := range module.Skipped {
+			moduleReport.Skipped = append(moduleReport.Skipped, &securerepov1.Skipped{File: skipped.File, Line: int32(skipped.Line),
+				Item: skipped.Item, Reason: skipped.Reason})
+		}
+		result = append(result, moduleReport)
+	}
+	return result
+}
+
+// toFiles returns the files sorted by path, so the responses do not depend on the order of the map
+func toFiles(files map[string]string) []*securerepov1.File {
+	paths := make([]string, 0, len(files))
+	for filePath := range files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	var result []*securerepov1.File
+	for _, filePath := range paths {
+		result = append(result, &securerepov1.File{Path: filePath, Content: files[filePath]})
+	}
+	return result
+}
+
+func toSecureRepoResponse(fixResponse *securerepo.SecureRepoResponse) *securerepov1.SecureRepoResponse {
+	response := &securerepov1.SecureRepoResponse{
+		Files:          toFiles(fixResponse.Files),
+		Diffs:          toFiles(fixResponse.Diffs),
+		IsChanged:      fixResponse.IsChanged,
+		HasErrors:      fixResponse.HasErrors,
+		MissingActions: fixResponse.MissingActions,
+	}
+	for _, fileReport := range fixResponse.Report {
+		response.Report = append(response.Report, &securerepov1.FileReport{
+			Path:      fileReport.Path,
+			FileType:  fileReport.FileType,
+			IsChanged: fileReport.IsChanged,
+			IsNew:     fileReport.IsNew,
+			HasErrors: fileReport.HasErrors,
+			Error:     fileReport.Error,
+			Findings:  toFindings(fileReport.Findings),
+		})
+	}
+	return response
+}
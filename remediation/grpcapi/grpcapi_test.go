@@ -0,0 +1,217 @@
+package grpcapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	securerepov1 "github.com/step-security/secure-repo/proto/securerepo/v1"
+	"github.com/step-security/secure-repo/remediation/auth"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const workflowInput = `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - run: make
+`
+
+// the remediations that look up actions and images on GitHub and the registries are disabled
+var params = map[string]string{"pinActions": "false", "addHardenRunner": "false"}
+
+// newClient returns a client of the server, which is served on an in-memory listener until the test ends
+func newClient(t *testing.T, server *Server) securerepov1.SecureRepoServiceClient {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := NewServer(server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unable to dial the server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return securerepov1.NewSecureRepoServiceClient(conn)
+}
+
+func TestSecureWorkflow(t *testing.T) {
+	client := newClient(t, &Server{})
+	response, err := client.SecureWorkflow(context.Background(), &securerepov1.SecureWorkflowRequest{Workflow: workflowInput, Params: params,
+		RunnerLabels: map[string]string{"ubuntu-latest": "ubuntu-24.04"}})
+	if err != nil {
+		t.Fatalf("SecureWorkflow() returned error: %v", err)
+	}
+	if !response.IsChanged || !response.AddedPermissions || !response.ReplacedRunnerLabels {
+		t.Errorf("expected the permissions to be added and the runner labels to be replaced, got %+v", response)
+	}
+	if !strings.Contains(response.FinalOutput, "contents: read") || !strings.Contains(response.FinalOutput, "runs-on: ubuntu-24.04") {
+		t.Errorf("unexpected output\n%s", response.FinalOutput)
+	}
+	if len(response.Report) == 0 {
+		t.Errorf("expected the report of the modules")
+	}
+
+	diffParams := map[string]string{"output": "diff", "path": ".github/workflows/ci.yml"}
+	for key, value := range params {
+		diffParams[key] = value
+	}
+	response, err = client.SecureWorkflow(context.Background(), &securerepov1.SecureWorkflowRequest{Workflow: workflowInput, Params: diffParams})
+	if err != nil {
+		t.Fatalf("SecureWorkflow() returned error: %v", err)
+	}
+	if response.FinalOutput != "" || !strings.HasPrefix(response.Diff, "--- a/.github/workflows/ci.yml") {
+		t.Errorf("expected a diff instead of the output, got %+v", response)
+	}
+
+	if _, err = client.SecureWorkflow(context.Background(), &securerepov1.SecureWorkflowRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an empty workflow, got %v", err)
+	}
+}
+
+func TestPinActionsAndReplaceRunnerLabels(t *testing.T) {
+	client := newClient(t, &Server{})
+	// the exempted actions are not looked up
+	pinResponse, err := client.PinActions(context.Background(), &securerepov1.PinActionsRequest{Input: workflowInput, ExemptedActions: []string{"actions/*"}})
+	if err != nil {
+		t.Fatalf("PinActions() returned error: %v", err)
+	}
+	if pinResponse.PinnedActions || pinResponse.FinalOutput != workflowInput {
+		t.Errorf("expected the exempted actions not to be pinned, got %+v", pinResponse)
+	}
+
+	labelsResponse, err := client.ReplaceRunnerLabels(context.Background(), &securerepov1.ReplaceRunnerLabelsRequest{Workflow: workflowInput,
+		RunnerLabels: map[string]string{"ubuntu-latest": "self-hosted"}})
+	if err != nil {
+		t.Fatalf("ReplaceRunnerLabels() returned error: %v", err)
+	}
+	if !labelsResponse.ReplacedRunnerLabels || !strings.Contains(labelsResponse.FinalOutput, "runs-on: self-hosted") {
+		t.Errorf("expected the runner label to be replaced, got %+v", labelsResponse)
+	}
+}
+
+func TestSecureRepo(t *testing.T) {
+	client := newClient(t, &Server{})
+	response, err := client.SecureRepo(context.Background(), &securerepov1.SecureRepoRequest{Params: params,
+		Files: []*securerepov1.File{{Path: ".github/workflows/ci.yml", Content: workflowInput}, {Path: "README.md", Content: "# app\n"}}})
+	if err != nil {
+		t.Fatalf("SecureRepo() returned error: %v", err)
+	}
+	if !response.IsChanged || len(response.Report) == 0 || response.Report[0].Path != ".github/workflows/ci.yml" {
+		t.Errorf("expected the workflow to be changed, got %+v", response)
+	}
+	var paths []string
+	for _, file := range response.Files {
+		paths = append(paths, file.Path)
+	}
+	if strings.Join(paths, ",") != ".github/dependabot.yml,.github/workflows/ci.yml" {
+		t.Errorf("unexpected files %v", paths)
+	}
+}
+
+func TestSecureRepoArchive(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	file, _ := writer.Create("app-main/.github/workflows/ci.yml")
+	file.Write([]byte(workflowInput))
+	writer.Close()
+
+	client := newClient(t, &Server{})
+	stream, err := client.SecureRepoArchive(context.Background())
+	if err != nil {
+		t.Fatalf("SecureRepoArchive() returned error: %v", err)
+	}
+	// the archive is sent in chunks, with the format and the params in the first one
+	archive := buffer.Bytes()
+	for start := 0; start < len(archive); start += 100 {
+		chunk := &securerepov1.ArchiveChunk{Data: archive[start:min(start+100, len(archive))]}
+		if start == 0 {
+			chunk.ArchiveFormat, chunk.Params = securerepo.ArchiveFormatZip, params
+		}
+		if err := stream.Send(chunk); err != nil {
+			t.Fatalf("Send() returned error: %v", err)
+		}
+	}
+	stream.CloseSend()
+
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() returned error: %v", err)
+	}
+	response := first.GetResponse()
+	if response == nil || !response.IsChanged || len(response.Files) != 0 {
+		t.Fatalf("expected the report of the changed archive first, got %+v", first)
+	}
+	var output []byte
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() returned error: %v", err)
+		}
+		output = append(output, chunk.GetData()...)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(output), int64(len(output)))
+	if err != nil {
+		t.Fatalf("unable to read the archive: %v", err)
+	}
+	var workflow []byte
+	for _, f := range reader.File {
+		if f.Name == "app-main/.github/workflows/ci.yml" {
+			content, _ := f.Open()
+			workflow, _ = io.ReadAll(content)
+		}
+	}
+	if !strings.Contains(string(workflow), "contents: read") {
+		t.Errorf("expected the workflow in the archive to be remediated\n%s", workflow)
+	}
+
+	// an empty archive is rejected
+	stream, _ = client.SecureRepoArchive(context.Background())
+	stream.CloseSend()
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an empty archive, got %v", err)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	keys, _ := auth.ParseStaticKeyStore("acme=secret-key")
+	client := newClient(t, &Server{Authenticator: auth.NewAuthenticator(keys, nil, 1)})
+	request := &securerepov1.ReplaceRunnerLabelsRequest{Workflow: workflowInput}
+
+	if _, err := client.ReplaceRunnerLabels(context.Background(), request); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without an API key, got %v", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyHeader, "secret-key")
+	if _, err := client.ReplaceRunnerLabels(ctx, request); err != nil {
+		t.Errorf("ReplaceRunnerLabels() returned error: %v", err)
+	}
+	// the streams share the rate limit of the tenant
+	var header metadata.MD
+	stream, err := client.SecureRepoArchive(ctx, grpc.Header(&header))
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.ResourceExhausted || len(header.Get("retry-after")) == 0 {
+		t.Errorf("expected ResourceExhausted with retry-after over the rate limit, got %v, %v", err, header)
+	}
+}
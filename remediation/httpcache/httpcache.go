@@ -0,0 +1,282 @@
+// Package httpcache caches the responses of the GitHub API, e.g. the metadata of actions and the commits of their tags,
+// and revalidates them with conditional requests. GitHub does not count the requests answered with 304 Not Modified
+// against the rate limit, so warm requests make almost no counted calls, and the cached responses are returned while
+// the rate limit is exceeded.
+package httpcache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/outbound"
+)
+
+const (
+	// SizeEnv is the number of responses kept in memory by each instance, which is DefaultSize if it is not set. The
+	// responses are also kept in the storage of STORAGE_URL, if it is set, so they are shared by the instances and
+	// kept across restarts.
+	SizeEnv = "GITHUB_CACHE_SIZE"
+
+	DefaultSize = 1000
+
+	// ResultHeader is set on the responses returned from the cache, to revalidated if GitHub answered the conditional
+	// request with 304 Not Modified, or to stale if the rate limit is exceeded
+	ResultHeader = "X-Secure-Repo-Cache"
+
+	// storagePrefix is the prefix of the keys of the responses in the persistent store
+	storagePrefix = "github/"
+	// maxBodySize is the size of the largest body that is cached, so archives are not kept in memory
+	maxBodySize = 1 << 20
+)
+
+// Store is where the responses are kept, which storage.Store implements
+type Store interface {
+	// Get returns nil if there is no value for the key
+	Get(key string) ([]byte, error)
+	Put(key string, value []byte) error
+}
+
+// PersistentStore returns the store the responses are kept in besides memory, or nil. It is set to the storage of
+// STORAGE_URL by the workflow package, since the packages compiled to WebAssembly cannot depend on the storage.
+var PersistentStore func() (Store, error)
+
+// entry is a cached response with the validators of its conditional requests
+type entry struct {
+	StatusCode   int
+	Header       http.Header
+	Body         []byte
+	ETag         string
+	LastModified string
+}
+
+// Transport caches the successful GET responses that have an ETag or a Last-Modified header, and sends the requests
+// of cached responses with If-None-Match and If-Modified-Since. The base transport is http.DefaultTransport at the
+// time of the request if Base is nil, so it can be replaced, e.g. by httpmock in tests.
+type Transport struct {
+	Base   http.RoundTripper
+	Stores []Store
+}
+
+// key returns the key of the request, which includes the hash of its authorization, so the responses of private
+// repositories are only returned for the token they were fetched with
+func key(req *http.Request) string {
+	hash := sha256.New()
+	io.WriteString(hash, req.URL.String()+"\x00"+req.Header.Get("Accept")+"\x00"+req.Header.Get("Authorization"))
+	return storagePrefix + hex.EncodeToString(hash.Sum(nil))
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) get(key string) *entry {
+	for i, store := range t.Stores {
+		value, err := store.Get(key)
+		if err != nil {
+			logging.Logger().Warn("unable to get cached GitHub response", "error", err)
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		cached := &entry{}
+		if err := json.Unmarshal(value, cached); err != nil {
+			logging.Logger().Warn("unable to unmarshal cached GitHub response", "error", err)
+			continue
+		}
+		// a response found in the persistent store is kept in memory too
+		t.put(t.Stores[:i], key, value)
+		return cached
+	}
+	return nil
+}
+
+func (t *Transport) put(stores []Store, key string, value []byte) {
+	for _, store := range stores {
+		if err := store.Put(key, value); err != nil {
+			logging.Logger().Warn("unable to cache GitHub response", "error", err)
+		}
+	}
+}
+
+// isRateLimited returns true if the response is an error because the rate limit of the token is exceeded
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	return resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != ""
+}
+
+// response returns the cached response for the request, with the rate limit headers of the response GitHub sent, so
+// the remaining rate limit is still observed
+func (cached *entry) response(req *http.Request, resp *http.Response, result string) *http.Response {
+	header := cached.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	for name, values := range resp.Header {
+		if strings.HasPrefix(http.CanonicalHeaderKey(name), "X-Ratelimit-") {
+			header[name] = values
+		}
+	}
+	header.Set(ResultHeader, result)
+	return &http.Response{
+		Status:        strconv.Itoa(cached.StatusCode) + " " + http.StatusText(cached.StatusCode),
+		StatusCode:    cached.StatusCode,
+		Proto:         resp.Proto,
+		ProtoMajor:    resp.ProtoMajor,
+		ProtoMinor:    resp.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       req,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" || len(t.Stores) == 0 {
+		return t.base().RoundTrip(req)
+	}
+	requestKey := key(req)
+	cached := t.get(requestKey)
+	if cached != nil {
+		// the request is cloned, since a RoundTripper must not modify it
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case cached != nil && resp.StatusCode == http.StatusNotModified:
+		resp.Body.Close()
+		return cached.response(req, resp, "revalidated"), nil
+	case cached != nil && isRateLimited(resp):
+		resp.Body.Close()
+		return cached.response(req, resp, "stale"), nil
+	case resp.StatusCode != http.StatusOK:
+		return resp, nil
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return resp, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxBodySize {
+		// the rest of the body is read by the caller
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	value, err := json.Marshal(&entry{StatusCode: resp.StatusCode, Header: resp.Header, Body: body, ETag: etag, LastModified: lastModified})
+	if err == nil {
+		t.put(t.Stores, requestKey, value)
+	}
+	return resp, nil
+}
+
+// MemoryStore keeps the most recently used responses in memory
+type MemoryStore struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// recent has the keys and values from the most to the least recently used
+	recent *list.List
+}
+
+type memoryEntry struct {
+	key   string
+	value []byte
+}
+
+func NewMemoryStore(size int) *MemoryStore {
+	return &MemoryStore{size: size, entries: map[string]*list.Element{}, recent: list.New()}
+}
+
+func (s *MemoryStore) Get(key string) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	element, found := s.entries[key]
+	if !found {
+		return nil, nil
+	}
+	s.recent.MoveToFront(element)
+	return element.Value.(*memoryEntry).value, nil
+}
+
+// Put keeps the value, and removes the least recently used value if the store is full
+func (s *MemoryStore) Put(key string, value []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if element, found := s.entries[key]; found {
+		element.Value.(*memoryEntry).value = value
+		s.recent.MoveToFront(element)
+		return nil
+	}
+	s.entries[key] = s.recent.PushFront(&memoryEntry{key: key, value: value})
+	for s.recent.Len() > s.size {
+		oldest := s.recent.Back()
+		s.recent.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+var defaultTransport = sync.OnceValue(func() *Transport {
+	transport := &Transport{Base: outbound.Default()}
+	size := DefaultSize
+	if value := os.Getenv(SizeEnv); value != "" {
+		var err error
+		if size, err = strconv.Atoi(value); err != nil || size < 0 {
+			logging.Logger().Error("invalid size of the GitHub cache, using the default", "env", SizeEnv, "value", value, "default", DefaultSize)
+			size = DefaultSize
+		}
+	}
+	if size > 0 {
+		transport.Stores = append(transport.Stores, NewMemoryStore(size))
+	}
+	if PersistentStore != nil {
+		store, err := PersistentStore()
+		if err != nil {
+			logging.Logger().Error("unable to configure the persistent GitHub cache", "error", err)
+		} else if store != nil {
+			transport.Stores = append(transport.Stores, store)
+		}
+	}
+	return transport
+})
+
+// Default returns the transport with the stores configured by GITHUB_CACHE_SIZE and PersistentStore, which sends the
+// requests with the outbound transport
+func Default() *Transport {
+	return defaultTransport()
+}
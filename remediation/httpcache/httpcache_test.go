@@ -0,0 +1,91 @@
+package httpcache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	var requests, notModified int
+	rateLimited := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(100-requests))
+		switch {
+		case rateLimited:
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/no-etag":
+			fmt.Fprint(w, "no etag")
+		case r.Header.Get("If-None-Match") == `"v1"`:
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprintf(w, "%s of %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{Stores: []Store{NewMemoryStore(10)}}}
+	get := func(path, authorization string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Error not expected: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	if resp, body := get("/repos/actions/checkout", "token a"); body != "/repos/actions/checkout of token a" || resp.Header.Get(ResultHeader) != "" {
+		t.Errorf("unexpected first response %q with result %q", body, resp.Header.Get(ResultHeader))
+	}
+	resp, body := get("/repos/actions/checkout", "token a")
+	if body != "/repos/actions/checkout of token a" || resp.StatusCode != http.StatusOK || resp.Header.Get(ResultHeader) != "revalidated" || notModified != 1 {
+		t.Errorf("expected the cached response to be revalidated, got %d %q with result %q", resp.StatusCode, body, resp.Header.Get(ResultHeader))
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "98" {
+		t.Errorf("expected the rate limit of the revalidation, got %s", resp.Header.Get("X-RateLimit-Remaining"))
+	}
+
+	// the responses of other tokens are not shared
+	if _, body := get("/repos/actions/checkout", "token b"); body != "/repos/actions/checkout of token b" || notModified != 1 {
+		t.Errorf("expected the response of another token not to be cached, got %q", body)
+	}
+	// responses without validators are not cached
+	get("/no-etag", "")
+	get("/no-etag", "")
+	if notModified != 1 || requests != 5 {
+		t.Errorf("expected 5 requests with 1 revalidated, got %d with %d revalidated", requests, notModified)
+	}
+
+	rateLimited = true
+	if resp, body := get("/repos/actions/checkout", "token a"); body != "/repos/actions/checkout of token a" || resp.Header.Get(ResultHeader) != "stale" {
+		t.Errorf("expected the stale response while rate limited, got %q with result %q", body, resp.Header.Get(ResultHeader))
+	}
+	if resp, _ := get("/repos/actions/setup-node", "token a"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected the rate limit error of an uncached response, got %d", resp.StatusCode)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(2)
+	store.Put("a", []byte("1"))
+	store.Put("b", []byte("2"))
+	store.Get("a")
+	store.Put("c", []byte("3"))
+	for key, want := range map[string]string{"a": "1", "b": "", "c": "3"} {
+		if value, _ := store.Get(key); string(value) != want {
+			t.Errorf("Get(%s) = %q, want %q", key, value, want)
+		}
+	}
+}
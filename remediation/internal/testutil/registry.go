@@ -0,0 +1,35 @@
+// Package testutil has the helpers shared by the tests of the remediation modules
+package testutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/step-security/secure-repo/remediation/docker"
+)
+
+// MockRegistry serves the manifests of the images from Docker Hub with httpmock, e.g. library/alpine/manifests/3.19,
+// and returns their digest. The mock is removed when the test ends.
+func MockRegistry(t *testing.T, images ...string) string {
+	_, file, _, _ := runtime.Caller(0)
+	manifest := httpmock.File(filepath.Join(filepath.Dir(file), "../../../testfiles/dockerfiles/response.json")).String()
+	httpmock.Activate()
+	saveTr := docker.Tr
+	docker.Tr = httpmock.DefaultTransport
+	t.Cleanup(func() {
+		docker.Tr = saveTr
+		httpmock.DeactivateAndReset()
+	})
+
+	httpmock.RegisterResponder("GET", "https://index.docker.io/v2/", httpmock.NewStringResponder(http.StatusOK, `{}`))
+	for _, image := range images {
+		httpmock.RegisterResponder("GET", "https://index.docker.io/v2/"+image, httpmock.NewStringResponder(http.StatusOK, manifest))
+	}
+	hash := sha256.Sum256([]byte(manifest))
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,253 @@
+package jenkins
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/metrics"
+	"golang.org/x/oauth2"
+)
+
+const (
+	RuleUnpinnedLibrary = "jenkins-unpinned-library"
+	RuleUnpinnedImage   = "jenkins-unpinned-image"
+
+	// LibrariesEnv maps the names of the shared libraries to their GitHub repositories, e.g. pipeline-lib=acme/pipeline-lib,
+	// since the repository of a library is configured in Jenkins and not in the Jenkinsfile
+	LibrariesEnv = "JENKINS_LIBRARIES"
+)
+
+var (
+	shaRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	// releaseRegex matches the tags of releases, which are kept as they are
+	releaseRegex = regexp.MustCompile(`^v?\d+\.\d+\.\d+$`)
+
+	// libraryAnnotationRegex matches @Library('lib@ref') and @Library(['lib@ref', 'other']), and libraryStepRegex the
+	// library step of scripted pipelines, e.g. library 'lib@ref' or library(identifier: 'lib@ref', ...)
+	libraryAnnotationRegex = regexp.MustCompile(`@Library\(\s*(\[[^\]]*\]|'[^']*'|"[^"]*")\s*\)`)
+	libraryStepRegex       = regexp.MustCompile(`\blibrary\s*\(?\s*(?:identifier:\s*)?('[^']*'|"[^"]*")`)
+	quotedRegex            = regexp.MustCompile(`'[^']*'|"[^"]*"`)
+
+	// imageRegexes match the images of docker agents, e.g. docker { image 'maven:3.9' } or docker 'maven:3.9', and of
+	// scripted pipelines, e.g. docker.image('maven:3.9').inside
+	imageRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`\bdocker\s*\{[^}]*?\bimage\s*\(?\s*('[^']*'|"[^"]*")`),
+		regexp.MustCompile(`\bagent\s*\{\s*docker\s*\(?\s*('[^']*'|"[^"]*")`),
+		regexp.MustCompile(`\bdocker\.image\(\s*('[^']*'|"[^"]*")`),
+	}
+)
+
+// SecureJenkinsfileResponse is the result of the remediations of a Jenkinsfile
+type SecureJenkinsfileResponse struct {
+	OriginalInput   string
+	FinalOutput     string
+	IsChanged       bool
+	PinnedLibraries bool
+	PinnedImages    bool
+	Findings        []findings.Finding
+}
+
+// IsJenkinsfile returns true for Jenkinsfile, Jenkinsfile.release and release.jenkinsfile
+func IsJenkinsfile(filePath string) bool {
+	name := path.Base(filePath)
+	return name == "Jenkinsfile" || strings.HasPrefix(name, "Jenkinsfile.") || strings.HasSuffix(strings.ToLower(name), ".jenkinsfile")
+}
+
+// ResolveCommit returns the SHA of the commit a ref of a GitHub repository points to, with the token in SECURE_REPO_PAT
+// or PAT
+var ResolveCommit = resolveCommit
+
+func resolveCommit(ctx context.Context, repository, ref string) (string, error) {
+	parts := strings.SplitN(repository, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid repository %s", repository)
+	}
+	token := os.Getenv("SECURE_REPO_PAT")
+	if token == "" {
+		token = os.Getenv("PAT")
+	}
+	client := github.NewClient(oauth2.NewClient(metrics.WithGitHubClient(ctx), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+	sha, _, err := client.Repositories.GetCommitSHA1(ctx, parts[0], parts[1], ref, "")
+	if err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+// getLibraries returns the GitHub repositories of the shared libraries in JENKINS_LIBRARIES
+func getLibraries() map[string]string {
+	libraries := map[string]string{}
+	for _, entry := range strings.Split(os.Getenv(LibrariesEnv), ",") {
+		if name, repository, found := strings.Cut(strings.TrimSpace(entry), "="); found {
+			libraries[name] = repository
+		}
+	}
+	return libraries
+}
+
+// edit replaces the text from start to end
+type edit struct {
+	start, end int
+	text       string
+}
+
+// getPosition returns the line and column of the offset in the text, with the column in characters like the columns of
+// the findings of YAML files
+func getPosition(text string, offset int) (int, int) {
+	line := strings.Count(text[:offset], "\n") + 1
+	return line, utf8.RuneCountInString(text[strings.LastIndex(text[:offset], "\n")+1:offset]) + 1
+}
+
+// pipeline is the Jenkinsfile being remediated, with its edits and findings
+type pipeline struct {
+	text     string
+	edits    []edit
+	findings []findings.Finding
+}
+
+func (p *pipeline) addFinding(ruleID string, offset int, action, message, suggestion string) {
+	line, column := getPosition(p.text, offset)
+	p.findings = append(p.findings, findings.Finding{RuleID: ruleID, Message: message, Action: action, Line: line, Column: column,
+		Suggestion: suggestion})
+}
+
+// pinLibrary pins the library in the quoted string at the offset to the SHA of the commit of its ref, if its repository
+// is known. The ref is kept in a comment at the end of the line if comment is true.
+func (p *pipeline) pinLibrary(ctx context.Context, quoted string, offset int, libraries map[string]string, comment bool) error {
+	library := quoted[1 : len(quoted)-1]
+	name, ref, found := strings.Cut(library, "@")
+	switch {
+	case strings.Contains(library, "$"):
+		p.addFinding(RuleUnpinnedLibrary, offset, name, fmt.Sprintf("Shared library %s is loaded with an expression, whose version is only known when the pipeline runs", library),
+			"Load the library with the SHA of a commit")
+		return nil
+	case !found:
+		p.addFinding(RuleUnpinnedLibrary, offset, name, fmt.Sprintf("Shared library %s is loaded with its default version, which is configured in Jenkins and can change", name),
+			fmt.Sprintf("Load the library with the SHA of a commit, e.g. %s@<sha>", name))
+		return nil
+	case shaRegex.MatchString(ref) || releaseRegex.MatchString(ref):
+		return nil
+	}
+	repository, known := libraries[name]
+	if !known {
+		p.addFinding(RuleUnpinnedLibrary, offset, name, fmt.Sprintf("Shared library %s is loaded from %s, which can change without a change to the Jenkinsfile", name, ref),
+			fmt.Sprintf("Load the library with the SHA of a commit, e.g. %s@<sha>", name))
+		return nil
+	}
+	sha, err := ResolveCommit(ctx, repository, ref)
+	if err != nil {
+		return fmt.Errorf("unable to get commit of %s@%s: %v", repository, ref, err)
+	}
+	start := offset + 1 + len(name) + 1
+	p.edits = append(p.edits, edit{start: start, end: start + len(ref), text: sha})
+	if comment {
+		lineEnd := strings.Index(p.text[offset:], "\n")
+		if lineEnd == -1 {
+			lineEnd = len(p.text) - offset
+		}
+		end := offset + lineEnd
+		trimmed := strings.TrimRight(p.text[:end], " \t\r")
+		p.edits = append(p.edits, edit{start: len(trimmed), end: len(trimmed), text: " // " + ref})
+	}
+	return nil
+}
+
+// pinImage pins the image in the quoted string at the offset to its digest, keeping the tag for readability
+func (p *pipeline) pinImage(ctx context.Context, quoted string, offset int) error {
+	image := quoted[1 : len(quoted)-1]
+	if image == "" || strings.Contains(image, "@") {
+		return nil
+	}
+	if strings.Contains(image, "$") {
+		p.addFinding(RuleUnpinnedImage, offset, image, fmt.Sprintf("Image %s of a docker agent is set with an expression, so it cannot be pinned to a digest", image),
+			"Set the image with its digest, e.g. maven:3.9@sha256:...")
+		return nil
+	}
+	pinned, err := docker.PinImage(ctx, image)
+	if err != nil {
+		return err
+	}
+	p.edits = append(p.edits, edit{start: offset + 1, end: offset + 1 + len(image), text: pinned})
+	return nil
+}
+
+// isCommentAllowed returns true if the library annotation or step ends its line, so a comment can be added after it.
+// An annotation is followed by the import it annotates, which is _ for libraries without imports.
+func isCommentAllowed(text string, end int) bool {
+	lineEnd := strings.Index(text[end:], "\n")
+	if lineEnd == -1 {
+		lineEnd = len(text) - end
+	}
+	rest := strings.TrimSpace(text[end : end+lineEnd])
+	return rest == "" || rest == "_" || rest == ")"
+}
+
+// SecureJenkinsfile runs the remediations for a Jenkinsfile. Shared libraries loaded from a branch are pinned to the
+// SHA of its commit if their GitHub repository is in JENKINS_LIBRARIES, unless pinLibraries is false, and the images
+// of docker agents to their digest unless pinImages is false. The Jenkinsfile is not parsed, so libraries and images
+// set with expressions, libraries of unknown repositories and libraries without a version are reported as findings.
+func SecureJenkinsfile(ctx context.Context, queryStringParams map[string]string, input string) (*SecureJenkinsfileResponse, error) {
+	response := &SecureJenkinsfileResponse{OriginalInput: input, FinalOutput: input}
+	p := &pipeline{text: input}
+
+	if queryStringParams["pinLibraries"] != "false" {
+		libraries := getLibraries()
+		for _, regex := range []*regexp.Regexp{libraryAnnotationRegex, libraryStepRegex} {
+			for _, match := range regex.FindAllStringSubmatchIndex(input, -1) {
+				argumentStart, argumentEnd := match[2], match[3]
+				quotedStrings := quotedRegex.FindAllStringIndex(input[argumentStart:argumentEnd], -1)
+				comment := len(quotedStrings) == 1 && isCommentAllowed(input, match[1])
+				for _, quoted := range quotedStrings {
+					offset := argumentStart + quoted[0]
+					if err := p.pinLibrary(ctx, input[offset:argumentStart+quoted[1]], offset, libraries, comment); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		response.PinnedLibraries = len(p.edits) > 0
+	}
+	if queryStringParams["pinImages"] != "false" {
+		libraryEdits := len(p.edits)
+		seen := map[int]bool{}
+		for _, regex := range imageRegexes {
+			for _, match := range regex.FindAllStringSubmatchIndex(input, -1) {
+				if seen[match[2]] {
+					continue
+				}
+				seen[match[2]] = true
+				if err := p.pinImage(ctx, input[match[2]:match[3]], match[2]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		response.PinnedImages = len(p.edits) > libraryEdits
+	}
+	sort.SliceStable(p.findings, func(i, j int) bool {
+		return p.findings[i].Line < p.findings[j].Line
+	})
+	response.Findings = p.findings
+
+	if len(p.edits) > 0 {
+		// applied from the end, so the offsets of the other edits do not move
+		sort.SliceStable(p.edits, func(i, j int) bool {
+			return p.edits[i].start > p.edits[j].start
+		})
+		output := input
+		for _, e := range p.edits {
+			output = output[:e.start] + e.text + output[e.end:]
+		}
+		response.FinalOutput = output
+		response.IsChanged = true
+	}
+	return response, nil
+}
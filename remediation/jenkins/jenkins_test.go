@@ -0,0 +1,133 @@
+package jenkins
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/internal/testutil"
+)
+
+func TestSecureJenkinsfile(t *testing.T) {
+	const sha = "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2"
+	digest := testutil.MockRegistry(t, "library/maven/manifests/3.9", "library/node/manifests/latest")
+	saveResolveCommit := ResolveCommit
+	var resolved []string
+	ResolveCommit = func(_ context.Context, repository, ref string) (string, error) {
+		resolved = append(resolved, repository+"@"+ref)
+		return sha, nil
+	}
+	defer func() { ResolveCommit = saveResolveCommit }()
+	os.Setenv(LibrariesEnv, "pipeline-lib=acme/pipeline-lib, deploy-lib=acme/deploy-lib")
+	defer os.Unsetenv(LibrariesEnv)
+
+	input := `@Library('pipeline-lib@master') _
+@Library(['deploy-lib@main', 'shared@v1.2.3', 'utils']) import com.acme.Utils
+
+pipeline {
+  agent {
+    docker {
+      image 'maven:3.9'
+      args '-v $HOME/.m2:/root/.m2'
+    }
+  }
+  stages {
+    stage('Test') {
+      agent { docker "${params.IMAGE}" }
+      steps {
+        sh 'mvn test'
+      }
+    }
+    stage('Lint') {
+      steps {
+        script {
+          library "scripts@${env.BRANCH_NAME}"
+          docker.image('node').inside {
+            sh 'npm run lint'
+          }
+        }
+      }
+    }
+  }
+}
+`
+	want := `@Library('pipeline-lib@` + sha + `') _ // master
+@Library(['deploy-lib@` + sha + `', 'shared@v1.2.3', 'utils']) import com.acme.Utils
+
+pipeline {
+  agent {
+    docker {
+      image 'maven:3.9@` + digest + `'
+      args '-v $HOME/.m2:/root/.m2'
+    }
+  }
+  stages {
+    stage('Test') {
+      agent { docker "${params.IMAGE}" }
+      steps {
+        sh 'mvn test'
+      }
+    }
+    stage('Lint') {
+      steps {
+        script {
+          library "scripts@${env.BRANCH_NAME}"
+          docker.image('node:latest@` + digest + `').inside {
+            sh 'npm run lint'
+          }
+        }
+      }
+    }
+  }
+}
+`
+	response, err := SecureJenkinsfile(context.Background(), map[string]string{}, input)
+	if err != nil {
+		t.Fatalf("SecureJenkinsfile() returned error: %v", err)
+	}
+	if !response.IsChanged || !response.PinnedLibraries || !response.PinnedImages || response.FinalOutput != want {
+		t.Errorf("SecureJenkinsfile() = %+v,\n%s\nwant\n%s", response, response.FinalOutput, want)
+	}
+	if fmt.Sprint(resolved) != "[acme/pipeline-lib@master acme/deploy-lib@main]" {
+		t.Errorf("resolved refs = %v", resolved)
+	}
+	var got []string
+	for _, finding := range response.Findings {
+		got = append(got, fmt.Sprintf("%s:%d:%d:%s", finding.RuleID, finding.Line, finding.Column, finding.Action))
+	}
+	want = "[jenkins-unpinned-library:2:47:utils jenkins-unpinned-image:13:22:${params.IMAGE} jenkins-unpinned-library:21:19:scripts]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("findings = %v, want %v", got, want)
+	}
+
+	response, err = SecureJenkinsfile(context.Background(), map[string]string{}, response.FinalOutput)
+	if err != nil || response.IsChanged {
+		t.Errorf("expected pinned Jenkinsfile to be unchanged, got %+v, %v", response, err)
+	}
+}
+
+func TestSecureJenkinsfileUnknownLibrary(t *testing.T) {
+	input := "@Library('pipeline-lib@master') _\nnode { sh 'make' }\n"
+	response, err := SecureJenkinsfile(context.Background(), map[string]string{"pinImages": "false"}, input)
+	if err != nil || response.IsChanged {
+		t.Fatalf("SecureJenkinsfile() = %+v, %v, want no changes without the repository of the library", response, err)
+	}
+	if len(response.Findings) != 1 || response.Findings[0].Message != "Shared library pipeline-lib is loaded from master, which can change without a change to the Jenkinsfile" {
+		t.Errorf("findings = %+v", response.Findings)
+	}
+}
+
+func TestIsJenkinsfile(t *testing.T) {
+	for filePath, want := range map[string]bool{
+		"Jenkinsfile":                  true,
+		"ci/Jenkinsfile.release":       true,
+		"pipelines/deploy.jenkinsfile": true,
+		"Jenkinsfile-notes.md":         false,
+		"jenkins/config.yml":           false,
+	} {
+		if got := IsJenkinsfile(filePath); got != want {
+			t.Errorf("IsJenkinsfile(%s) = %v, want %v", filePath, got, want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package jobs
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/apiv2"
+)
+
+// maxResultSize is the size of the largest result stored, which is below the limit of DynamoDB of 400 KB per item
+const maxResultSize = 350 * 1024
+
+// DynamoDBStore stores the jobs in a DynamoDB table with JobID as the hash key and Task as the range key. The job is the
+// item with Task 0, and the result of the task with index i is the item with Task i+1, so the results are queried in
+// order. ExpiresAt is the time the job expires in Unix seconds, which can be the TTL attribute of the table.
+type DynamoDBStore struct {
+	TableName string
+	Svc       dynamodbiface.DynamoDBAPI
+}
+
+func jobKey(id string, task int) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"JobID": {S: aws.String(id)},
+		"Task":  {N: aws.String(strconv.Itoa(task))},
+	}
+}
+
+func number(value int64) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(value, 10))}
+}
+
+func (s *DynamoDBStore) CreateJob(job *Job, expiresAt time.Time) error {
+	item := jobKey(job.ID, 0)
+	item["Total"] = number(int64(job.Total))
+	item["Completed"] = number(0)
+	item["Failed"] = number(0)
+	item["CreatedAt"] = number(job.CreatedAt.Unix())
+	item["ExpiresAt"] = number(expiresAt.Unix())
+	if job.CallbackURL != "" {
+		item["CallbackURL"] = &dynamodb.AttributeValue{S: aws.String(job.CallbackURL)}
+	}
+	_, err := s.Svc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.TableName),
+		Item:      item,
+	})
+	return err
+}
+
+func (s *DynamoDBStore) GetJob(id string) (*Job, error) {
+	result, err := s.Svc.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(s.TableName),
+		Key:            jobKey(id, 0),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+	job := &Job{ID: id}
+	counts := map[string]*int{"Total": &job.Total, "Completed": &job.Completed, "Failed": &job.Failed}
+	for name, count := range counts {
+		if result.Item[name] != nil {
+			if *count, err = strconv.Atoi(aws.StringValue(result.Item[name].N)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if result.Item["CreatedAt"] != nil {
+		createdAt, err := strconv.ParseInt(aws.StringValue(result.Item["CreatedAt"].N), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		job.CreatedAt = time.Unix(createdAt, 0).UTC()
+	}
+	if result.Item["CallbackURL"] != nil {
+		job.CallbackURL = aws.StringValue(result.Item["CallbackURL"].S)
+	}
+	job.setStatus()
+	return job, nil
+}
+
+// AddResult puts the result and counts it in the job in a transaction, on the condition that the result is not stored yet,
+// since a message can be received more than once
+func (s *DynamoDBStore) AddResult(task *Task, result *apiv2.WorkflowResult, failed bool) (*Job, error) {
+	value, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) > maxResultSize {
+		// the workflow can still be secured on its own with /v2/secure-workflow
+		failed = true
+		value, _ = json.Marshal(&apiv2.WorkflowResult{Path: result.Path, HasErrors: true, Error: "the result is too large for a job"})
+	}
+	failedCount := int64(0)
+	if failed {
+		failedCount = 1
+	}
+	item := jobKey(task.JobID, task.Index+1)
+	item["Result"] = &dynamodb.AttributeValue{B: value}
+
+	_, err = s.Svc.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Put: &dynamodb.Put{
+					TableName:           aws.String(s.TableName),
+					Item:                item,
+					ConditionExpression: aws.String("attribute_not_exists(JobID)"),
+				},
+			},
+			{
+				Update: &dynamodb.Update{
+					TableName:        aws.String(s.TableName),
+					Key:              jobKey(task.JobID, 0),
+					UpdateExpression: aws.String("ADD Completed :one, Failed :failed"),
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+						":one":    number(1),
+						":failed": number(failedCount),
+					},
+					ConditionExpression: aws.String("attribute_exists(JobID)"),
+				},
+			},
+		},
+	})
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return s.GetJob(task.JobID)
+}
+
+// isConditionalCheckFailed returns true if the error is a transaction canceled by a condition
+func isConditionalCheckFailed(err error) bool {
+	if canceled, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		for _, reason := range canceled.CancellationReasons {
+			if aws.StringValue(reason.Code) == "ConditionalCheckFailed" {
+				return true
+			}
+		}
+		return false
+	}
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+	}
+	return false
+}
+
+func (s *DynamoDBStore) GetResults(id string) ([]apiv2.WorkflowResult, error) {
+	results := []apiv2.WorkflowResult{}
+	var unmarshalErr error
+	err := s.Svc.QueryPages(&dynamodb.QueryInput{
+		TableName:              aws.String(s.TableName),
+		KeyConditionExpression: aws.String("JobID = :id AND Task > :job"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id":  {S: aws.String(id)},
+			":job": number(0),
+		},
+		ConsistentRead: aws.Bool(true),
+	}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			if item["Result"] == nil {
+				continue
+			}
+			var result apiv2.WorkflowResult
+			if unmarshalErr = json.Unmarshal(item["Result"].B, &result); unmarshalErr != nil {
+				return false
+			}
+			results = append(results, result)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, unmarshalErr
+}
+
+func (s *DynamoDBStore) MarkNotified(id string) (bool, error) {
+	_, err := s.Svc.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:        aws.String(s.TableName),
+		Key:              jobKey(id, 0),
+		UpdateExpression: aws.String("SET Notified = :notified"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":notified": {BOOL: aws.Bool(true)},
+		},
+		ConditionExpression: aws.String("attribute_exists(JobID) AND attribute_not_exists(Notified)"),
+	})
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,298 @@
+// Package jobs runs batches of workflows asynchronously, for batches that take longer than a synchronous request, such as
+// the workflows of an organization or of a monorepo. A job is submitted with the workflows, and each workflow is a task
+// sent to an SQS queue, whose messages invoke the Lambda. The results of the tasks are stored with the job in DynamoDB as
+// they complete, so the status of a job is polled with the results so far, and a callback is posted when it completes.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/step-security/secure-repo/remediation/apiv2"
+	"github.com/step-security/secure-repo/remediation/awssession"
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/notify"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+)
+
+const (
+	// QueueURLEnv is the URL of the SQS queue of the tasks, which invokes the Lambda
+	QueueURLEnv = "JOBS_QUEUE_URL"
+	// TableEnv is the DynamoDB table of the jobs and the results of their tasks
+	TableEnv = "JOBS_TABLE"
+	// MaxAttemptsEnv is the number of times a task that fails is run, which is DefaultMaxAttempts if it is not set. The
+	// maxReceiveCount of the redrive policy of the queue should be higher, so the last error is stored as the result.
+	MaxAttemptsEnv = "JOBS_MAX_ATTEMPTS"
+
+	DefaultMaxAttempts = 3
+
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+
+	// EventCompleted is the event of the callback of a job, in the X-StepSecurity-Event header
+	EventCompleted = "job.completed"
+
+	// maxTaskSize is the size of the largest task, which is below the limit of 256 KB of a message
+	maxTaskSize = 250 * 1024
+	// expiration is how long the jobs and their results are kept
+	expiration = 7 * 24 * time.Hour
+)
+
+// secureWorkflowFile secures the workflow of a task
+var secureWorkflowFile = apiv2.SecureWorkflowFile
+
+// SubmitRequest is the request of /v2/secure-workflow with the URL that is posted the status of the job when it completes.
+// The callback is signed like the notifications, with NOTIFY_WEBHOOK_SECRET.
+type SubmitRequest struct {
+	apiv2.SecureWorkflowRequest
+	CallbackURL string `json:",omitempty"`
+}
+
+// Job is a batch of workflows. Completed counts the tasks that have a result, and Failed the ones whose result is an
+// error after all attempts.
+type Job struct {
+	ID          string
+	Status      string
+	CallbackURL string `json:",omitempty"`
+	Total       int
+	Completed   int
+	Failed      int
+	CreatedAt   time.Time
+}
+
+// JobStatus is the job with the results of the tasks that completed, in the order of the request
+type JobStatus struct {
+	Job
+	Results []apiv2.WorkflowResult
+	Summary apiv2.Summary
+}
+
+// Task is the message of a workflow of a job. Index is the position of the workflow in the request.
+type Task struct {
+	JobID  string
+	Index  int
+	Params map[string]string `json:",omitempty"`
+	File   securerepo.File
+}
+
+// Store stores the jobs and the results of their tasks
+type Store interface {
+	CreateJob(job *Job, expiresAt time.Time) error
+	// GetJob returns nil if there is no job with the id
+	GetJob(id string) (*Job, error)
+	// AddResult stores the result of a task and counts it as completed, once for each task. It returns the job with the
+	// result counted, or nil if the result of the task was already stored.
+	AddResult(task *Task, result *apiv2.WorkflowResult, failed bool) (*Job, error)
+	// GetResults returns the results stored so far, in the order of the tasks
+	GetResults(id string) ([]apiv2.WorkflowResult, error)
+	// MarkNotified returns true the first time it is called for a job, so the callback is posted once
+	MarkNotified(id string) (bool, error)
+}
+
+// Queue sends the tasks to the workers
+type Queue interface {
+	Send(tasks []*Task) error
+}
+
+// BatchResponse is the response to the SQS event source mapping with ReportBatchItemFailures, whose failed messages are
+// received again
+type BatchResponse struct {
+	BatchItemFailures []BatchItemFailure `json:"batchItemFailures"`
+}
+
+type BatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// Manager submits jobs and runs their tasks
+type Manager struct {
+	Store       Store
+	Queue       Queue
+	MaxAttempts int
+	now         func() time.Time
+}
+
+// NewManagerFromEnv returns the manager of the queue in JOBS_QUEUE_URL and the table in JOBS_TABLE, or nil if jobs are
+// not configured
+func NewManagerFromEnv(svc dynamodbiface.DynamoDBAPI) (*Manager, error) {
+	queueURL, tableName := os.Getenv(QueueURLEnv), os.Getenv(TableEnv)
+	if queueURL == "" || tableName == "" {
+		return nil, nil
+	}
+	maxAttempts := DefaultMaxAttempts
+	if value := os.Getenv(MaxAttemptsEnv); value != "" {
+		var err error
+		if maxAttempts, err = strconv.Atoi(value); err != nil || maxAttempts < 1 {
+			return nil, fmt.Errorf("invalid %s %s", MaxAttemptsEnv, value)
+		}
+	}
+	return &Manager{
+		Store:       &DynamoDBStore{TableName: tableName, Svc: svc},
+		Queue:       &SQSQueue{QueueURL: queueURL, Svc: sqs.New(awssession.Default())},
+		MaxAttempts: maxAttempts,
+	}, nil
+}
+
+func (m *Manager) getNow() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
+
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Submit creates a job for the workflows of the request, and sends a task for each workflow. The query parameters and the
+// parameters of the request are the ones of /v2/secure-workflow.
+func (m *Manager) Submit(queryStringParams map[string]string, request SubmitRequest) (*Job, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if request.CallbackURL != "" {
+		callbackURL, err := url.Parse(request.CallbackURL)
+		if err != nil || callbackURL.Scheme != "https" || callbackURL.Host == "" {
+			return nil, fmt.Errorf("invalid callback URL %q, it must be an https URL", request.CallbackURL)
+		}
+	}
+	id, err := newJobID()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create job id: %v", err)
+	}
+	job := &Job{ID: id, Status: StatusQueued, CallbackURL: request.CallbackURL, Total: len(request.Workflows), CreatedAt: m.getNow()}
+
+	var tasks []*Task
+	for i, file := range request.Workflows {
+		task := &Task{JobID: id, Index: i, Params: apiv2.WorkflowParams(queryStringParams, request.Params, file.Path), File: file}
+		body, err := json.Marshal(task)
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > maxTaskSize {
+			return nil, fmt.Errorf("workflow %s is too large for a job", file.Path)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := m.Store.CreateJob(job, job.CreatedAt.Add(expiration)); err != nil {
+		return nil, fmt.Errorf("unable to create job: %v", err)
+	}
+	if err := m.Queue.Send(tasks); err != nil {
+		return nil, fmt.Errorf("unable to queue tasks: %v", err)
+	}
+	return job, nil
+}
+
+// Status returns the status of the job with the results so far, or nil if there is no job with the id
+func (m *Manager) Status(id string) (*JobStatus, error) {
+	job, err := m.Store.GetJob(id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get job: %v", err)
+	}
+	if job == nil {
+		return nil, nil
+	}
+	results, err := m.Store.GetResults(id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get results: %v", err)
+	}
+	job.setStatus()
+	status := &JobStatus{Job: *job, Results: results}
+	for _, result := range results {
+		status.Summary.Add(result.IsChanged, result.Findings, result.Modules)
+	}
+	return status, nil
+}
+
+// setStatus sets the status of the job from the number of tasks that completed
+func (j *Job) setStatus() {
+	switch {
+	case j.Completed >= j.Total:
+		j.Status = StatusCompleted
+	case j.Completed > 0:
+		j.Status = StatusRunning
+	default:
+		j.Status = StatusQueued
+	}
+}
+
+// HandleEvent runs the tasks of the messages. A task whose workflow cannot be secured is returned as a failure, so its
+// message is received again, until it was received MaxAttempts times, after which the error is stored as its result.
+func (m *Manager) HandleEvent(ctx context.Context, event *events.SQSEvent, svc dynamodbiface.DynamoDBAPI) *BatchResponse {
+	response := &BatchResponse{BatchItemFailures: []BatchItemFailure{}}
+	for _, record := range event.Records {
+		if err := m.runTask(ctx, record, svc); err != nil {
+			logging.Logger().Warn("unable to run task", "message_id", record.MessageId, "error", err)
+			response.BatchItemFailures = append(response.BatchItemFailures, BatchItemFailure{ItemIdentifier: record.MessageId})
+		}
+	}
+	return response
+}
+
+func (m *Manager) runTask(ctx context.Context, record events.SQSMessage, svc dynamodbiface.DynamoDBAPI) error {
+	task := &Task{}
+	if err := json.Unmarshal([]byte(record.Body), task); err != nil {
+		// the message is not retried, since it cannot be parsed the next time either
+		logging.Logger().Error("unable to parse task", "message_id", record.MessageId, "error", err)
+		return nil
+	}
+	logger := logging.Logger().With("job_id", task.JobID, "path", task.File.Path)
+	attempts, _ := strconv.Atoi(record.Attributes["ApproximateReceiveCount"])
+	result := secureWorkflowFile(ctx, task.Params, task.File, svc, logger)
+	failed := result.Error != ""
+	if failed && attempts < m.MaxAttempts {
+		return fmt.Errorf("attempt %d of %d: %s", attempts, m.MaxAttempts, result.Error)
+	}
+
+	job, err := m.Store.AddResult(task, &result, failed)
+	if err != nil {
+		return fmt.Errorf("unable to store result: %v", err)
+	}
+	if job == nil || job.Completed < job.Total || job.CallbackURL == "" {
+		return nil
+	}
+	notified, err := m.Store.MarkNotified(job.ID)
+	if err != nil || !notified {
+		return err
+	}
+	// the callback is posted once, and its errors are logged, since the job can still be polled
+	if err := m.postCallback(job); err != nil {
+		logger.Warn("unable to post job callback", "error", err)
+	}
+	return nil
+}
+
+// postCallback posts the status of the completed job to its callback URL, as the Report of a notification
+func (m *Manager) postCallback(job *Job) error {
+	status, err := m.Status(job.ID)
+	if err != nil {
+		return err
+	}
+	return notify.Send([]string{job.CallbackURL}, os.Getenv(notify.WebhookSecretEnv), &notify.Notification{
+		Event:  EventCompleted,
+		Report: status,
+	})
+}
+
+// IsEvent returns true if the payload of the invocation is an SQS event
+func IsEvent(req []byte, event *events.SQSEvent) bool {
+	if err := json.Unmarshal(req, event); err != nil || len(event.Records) == 0 {
+		return false
+	}
+	return event.Records[0].EventSource == "aws:sqs"
+}
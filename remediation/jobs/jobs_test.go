@@ -0,0 +1,264 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/apiv2"
+	"github.com/step-security/secure-repo/remediation/notify"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+)
+
+type memoryStore struct {
+	jobs     map[string]*Job
+	results  map[string]map[int]apiv2.WorkflowResult
+	notified map[string]bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{jobs: map[string]*Job{}, results: map[string]map[int]apiv2.WorkflowResult{}, notified: map[string]bool{}}
+}
+
+func (s *memoryStore) CreateJob(job *Job, expiresAt time.Time) error {
+	stored := *job
+	s.jobs[job.ID], s.results[job.ID] = &stored, map[int]apiv2.WorkflowResult{}
+	return nil
+}
+
+func (s *memoryStore) GetJob(id string) (*Job, error) {
+	if s.jobs[id] == nil {
+		return nil, nil
+	}
+	job := *s.jobs[id]
+	return &job, nil
+}
+
+func (s *memoryStore) AddResult(task *Task, result *apiv2.WorkflowResult, failed bool) (*Job, error) {
+	if _, ok := s.results[task.JobID][task.Index]; ok {
+		return nil, nil
+	}
+	s.results[task.JobID][task.Index] = *result
+	s.jobs[task.JobID].Completed++
+	if failed {
+		s.jobs[task.JobID].Failed++
+	}
+	return s.GetJob(task.JobID)
+}
+
+func (s *memoryStore) GetResults(id string) ([]apiv2.WorkflowResult, error) {
+	var indexes []int
+	for index := range s.results[id] {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	var results []apiv2.WorkflowResult
+	for _, index := range indexes {
+		results = append(results, s.results[id][index])
+	}
+	return results, nil
+}
+
+func (s *memoryStore) MarkNotified(id string) (bool, error) {
+	if s.notified[id] {
+		return false, nil
+	}
+	s.notified[id] = true
+	return true, nil
+}
+
+type memoryQueue struct {
+	tasks []*Task
+}
+
+func (q *memoryQueue) Send(tasks []*Task) error {
+	q.tasks = append(q.tasks, tasks...)
+	return nil
+}
+
+// message returns the SQS message of the task, received the number of times
+func message(t *testing.T, task *Task, receiveCount int) events.SQSMessage {
+	body, err := json.Marshal(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events.SQSMessage{
+		MessageId:   task.JobID + "-" + strconv.Itoa(task.Index),
+		Body:        string(body),
+		Attributes:  map[string]string{"ApproximateReceiveCount": strconv.Itoa(receiveCount)},
+		EventSource: "aws:sqs",
+	}
+}
+
+func TestJob(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	store, queue := newMemoryStore(), &memoryQueue{}
+	m := &Manager{Store: store, Queue: queue, MaxAttempts: 2}
+	// the workflow of the unreachable repository cannot be secured, e.g. when GitHub is unavailable
+	defer func() { secureWorkflowFile = apiv2.SecureWorkflowFile }()
+	secureWorkflowFile = func(ctx context.Context, params map[string]string, file securerepo.File, svc dynamodbiface.DynamoDBAPI, logger *slog.Logger) apiv2.WorkflowResult {
+		if file.Path == ".github/workflows/unreachable.yml" {
+			return apiv2.WorkflowResult{Path: file.Path, HasErrors: true, Error: "unable to get repository"}
+		}
+		return apiv2.SecureWorkflowFile(ctx, params, file, svc, logger)
+	}
+
+	request := SubmitRequest{SecureWorkflowRequest: apiv2.SecureWorkflowRequest{
+		Params: map[string]string{"pinActions": "false", "addHardenRunner": "false", "addProjectComment": "false"},
+		Workflows: []securerepo.File{
+			{Path: ".github/workflows/ci.yml", Content: "on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make\n"},
+			{Path: ".github/workflows/unreachable.yml", Content: "on: push\n"},
+		},
+	}}
+	job, err := m.Submit(nil, request)
+	if err != nil {
+		t.Fatalf("Submit() returned error: %v", err)
+	}
+	if job.Status != StatusQueued || job.Total != 2 || len(queue.tasks) != 2 || queue.tasks[1].Params["path"] != ".github/workflows/unreachable.yml" {
+		t.Fatalf("expected a queued job with a task for each workflow, got %+v", job)
+	}
+
+	// the unreachable workflow is retried until its last attempt
+	response := m.HandleEvent(context.Background(), &events.SQSEvent{Records: []events.SQSMessage{message(t, queue.tasks[0], 1), message(t, queue.tasks[1], 1)}}, nil)
+	if len(response.BatchItemFailures) != 1 || response.BatchItemFailures[0].ItemIdentifier != job.ID+"-1" {
+		t.Errorf("expected the unreachable workflow to be retried, got %+v", response.BatchItemFailures)
+	}
+	status, _ := m.Status(job.ID)
+	if status.Status != StatusRunning || len(status.Results) != 1 || !status.Results[0].IsChanged || status.Summary.ChangedFiles != 1 {
+		t.Errorf("expected a running job with the result of the first workflow, got %+v", status)
+	}
+
+	// a message received again does not count twice
+	m.HandleEvent(context.Background(), &events.SQSEvent{Records: []events.SQSMessage{message(t, queue.tasks[0], 2)}}, nil)
+	response = m.HandleEvent(context.Background(), &events.SQSEvent{Records: []events.SQSMessage{message(t, queue.tasks[1], 2)}}, nil)
+	if len(response.BatchItemFailures) != 0 {
+		t.Errorf("expected the error to be stored after the last attempt, got %+v", response.BatchItemFailures)
+	}
+	status, _ = m.Status(job.ID)
+	if status.Status != StatusCompleted || status.Completed != 2 || status.Failed != 1 || status.Results[1].Error == "" {
+		t.Errorf("expected a completed job with the error of the unreachable workflow, got %+v", status)
+	}
+
+	if status, err := m.Status("unknown"); status != nil || err != nil {
+		t.Errorf("Status() = %v, %v, want nil for an unknown job", status, err)
+	}
+}
+
+func TestSubmitErrors(t *testing.T) {
+	m := &Manager{Store: newMemoryStore(), Queue: &memoryQueue{}, MaxAttempts: 1}
+	workflows := []securerepo.File{{Path: "ci.yml", Content: "on: push\n"}}
+	tests := []struct {
+		name    string
+		request SubmitRequest
+	}{
+		{name: "no workflows", request: SubmitRequest{}},
+		{name: "http callback", request: SubmitRequest{SecureWorkflowRequest: apiv2.SecureWorkflowRequest{Workflows: workflows}, CallbackURL: "http://example.com/jobs"}},
+		{name: "too large", request: SubmitRequest{SecureWorkflowRequest: apiv2.SecureWorkflowRequest{Workflows: []securerepo.File{{Path: "ci.yml", Content: string(make([]byte, maxTaskSize))}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.Submit(nil, tt.request); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestCallback(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	os.Setenv(notify.WebhookSecretEnv, "secret")
+	defer os.Unsetenv(notify.WebhookSecretEnv)
+
+	var callbacks []notify.Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(notify.SignatureHeader) != notify.Sign([]byte("secret"), body) {
+			t.Errorf("expected the callback to be signed")
+		}
+		var notification notify.Notification
+		json.Unmarshal(body, &notification)
+		callbacks = append(callbacks, notification)
+	}))
+	defer server.Close()
+
+	store := newMemoryStore()
+	m := &Manager{Store: store, Queue: &memoryQueue{}, MaxAttempts: 1}
+	// the callback URLs of submitted jobs are https, which the test server is not
+	store.CreateJob(&Job{ID: "job", CallbackURL: server.URL, Total: 1}, time.Now())
+	task := &Task{JobID: "job", Params: map[string]string{"pinActions": "false"}, File: securerepo.File{Path: "ci.yml", Content: "on: push\n"}}
+	for i := 1; i <= 2; i++ {
+		m.HandleEvent(context.Background(), &events.SQSEvent{Records: []events.SQSMessage{message(t, task, i)}}, nil)
+	}
+	if len(callbacks) != 1 || callbacks[0].Event != EventCompleted {
+		t.Errorf("expected one callback for the completed job, got %+v", callbacks)
+	}
+}
+
+type mockDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func itemKey(key map[string]*dynamodb.AttributeValue) string {
+	return aws.StringValue(key["JobID"].S) + "/" + aws.StringValue(key["Task"].N)
+}
+
+func (m *mockDynamoDBClient) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	m.items[itemKey(input.Item)] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m *mockDynamoDBClient) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: m.items[itemKey(input.Key)]}, nil
+}
+
+func (m *mockDynamoDBClient) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	put, update := input.TransactItems[0].Put, input.TransactItems[1].Update
+	if m.items[itemKey(put.Item)] != nil {
+		return nil, &dynamodb.TransactionCanceledException{CancellationReasons: []*dynamodb.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}}}
+	}
+	m.items[itemKey(put.Item)] = put.Item
+	job := m.items[itemKey(update.Key)]
+	for name, value := range map[string]string{"Completed": ":one", "Failed": ":failed"} {
+		count, _ := strconv.Atoi(aws.StringValue(job[name].N))
+		add, _ := strconv.Atoi(aws.StringValue(update.ExpressionAttributeValues[value].N))
+		job[name] = number(int64(count + add))
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func TestDynamoDBStore(t *testing.T) {
+	svc := &mockDynamoDBClient{items: map[string]map[string]*dynamodb.AttributeValue{}}
+	store := &DynamoDBStore{TableName: "Jobs", Svc: svc}
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := store.CreateJob(&Job{ID: "job", CallbackURL: "https://example.com/jobs", Total: 2, CreatedAt: createdAt}, createdAt.Add(expiration)); err != nil {
+		t.Fatalf("CreateJob() returned error: %v", err)
+	}
+
+	task := &Task{JobID: "job", Index: 1}
+	job, err := store.AddResult(task, &apiv2.WorkflowResult{Path: "ci.yml", HasErrors: true, Error: "unable to parse"}, true)
+	if err != nil || job == nil || job.Completed != 1 || job.Failed != 1 || job.Status != StatusRunning {
+		t.Errorf("AddResult() = %+v, %v", job, err)
+	}
+	if job, err := store.AddResult(task, &apiv2.WorkflowResult{Path: "ci.yml"}, false); job != nil || err != nil {
+		t.Errorf("AddResult() = %+v, %v, want nil for a result stored already", job, err)
+	}
+	if job, _ := store.GetJob("job"); !job.CreatedAt.Equal(createdAt) || job.CallbackURL != "https://example.com/jobs" || job.Total != 2 {
+		t.Errorf("GetJob() = %+v", job)
+	}
+	if result := svc.items["job/2"]["Result"]; result == nil {
+		t.Errorf("expected the result of the task with index 1 to be the item with Task 2")
+	}
+}
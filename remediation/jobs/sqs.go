@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// maxBatchSize is the largest number of messages SQS sends in a batch
+const maxBatchSize = 10
+
+// SQSQueue sends each task as a message to an SQS queue
+type SQSQueue struct {
+	QueueURL string
+	Svc      sqsiface.SQSAPI
+}
+
+func (q *SQSQueue) Send(tasks []*Task) error {
+	for start := 0; start < len(tasks); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(tasks) {
+			end = len(tasks)
+		}
+		var entries []*sqs.SendMessageBatchRequestEntry
+		for _, task := range tasks[start:end] {
+			body, err := json.Marshal(task)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, &sqs.SendMessageBatchRequestEntry{
+				Id:          aws.String(strconv.Itoa(task.Index)),
+				MessageBody: aws.String(string(body)),
+			})
+		}
+		output, err := q.Svc.SendMessageBatch(&sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(q.QueueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return err
+		}
+		if len(output.Failed) > 0 {
+			return fmt.Errorf("%d of %d messages were not sent: %s", len(output.Failed), len(entries), aws.StringValue(output.Failed[0].Message))
+		}
+	}
+	return nil
+}
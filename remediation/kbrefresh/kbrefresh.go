@@ -0,0 +1,164 @@
+// Package kbrefresh refreshes the knowledge base of actions in the background from an archive of the repository, so the
+// permissions of new actions are used without a release. The archive is downloaded again only when it changed.
+package kbrefresh
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/outbound"
+	"github.com/step-security/secure-repo/remediation/workflow/metadata"
+)
+
+const (
+	// URLEnv is the URL of a .tar.gz archive of the repository, e.g.
+	// https://github.com/step-security/secure-repo/archive/refs/heads/main.tar.gz, whose knowledge-base/actions folder
+	// replaces the knowledge base. The knowledge base is not refreshed if it is not set.
+	URLEnv = "KB_REFRESH_URL"
+	// IntervalEnv is how long to wait between refreshes, e.g. 30m, which is DefaultInterval if it is not set
+	IntervalEnv = "KB_REFRESH_INTERVAL"
+
+	DefaultInterval = time.Hour
+
+	// kbFolder is the folder of the knowledge base in the archive, after the folder of the repository
+	kbFolder = "knowledge-base/actions/"
+	// maxArchiveSize is the size of the largest archive that is read, after it is decompressed
+	maxArchiveSize = 256 << 20
+)
+
+// Refresher replaces the knowledge base with the one of the archive at URL every Interval
+type Refresher struct {
+	URL      string
+	Interval time.Duration
+	Client   *http.Client
+	// etag is the ETag of the archive of the current knowledge base, so an archive that did not change is not read
+	etag string
+}
+
+// NewRefresherFromEnv returns the refresher configured by KB_REFRESH_URL and KB_REFRESH_INTERVAL, or nil if the knowledge
+// base is not refreshed
+func NewRefresherFromEnv() (*Refresher, error) {
+	url := os.Getenv(URLEnv)
+	if url == "" {
+		return nil, nil
+	}
+	interval := DefaultInterval
+	if value := os.Getenv(IntervalEnv); value != "" {
+		var err error
+		if interval, err = time.ParseDuration(value); err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid %s %s", IntervalEnv, value)
+		}
+	}
+	return &Refresher{URL: url, Interval: interval, Client: outbound.NewClient(time.Minute)}, nil
+}
+
+// Refresh downloads the archive, and replaces the knowledge base if it changed. It returns false if the archive did
+// not change.
+func (r *Refresher) Refresh(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return false, fmt.Errorf("unable to create the request of the knowledge base: %v", err)
+	}
+	if r.etag != "" {
+		req.Header.Set("If-None-Match", r.etag)
+	}
+	client := r.Client
+	if client == nil {
+		client = outbound.Client()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("unable to download the knowledge base: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unable to download the knowledge base: unexpected status %d", resp.StatusCode)
+	}
+
+	files, err := readArchive(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	if len(files) == 0 {
+		// an archive without a knowledge base, e.g. of another repository, does not remove the permissions of all actions
+		return false, fmt.Errorf("the archive of the knowledge base has no %s folder", strings.TrimSuffix(kbFolder, "/"))
+	}
+	index := metadata.NewIndexFromFiles(files)
+	current := metadata.CurrentIndex()
+	r.etag = resp.Header.Get("ETag")
+	if current != nil && current.Version() == index.Version() {
+		return false, nil
+	}
+	metadata.SetIndex(index)
+	return true, nil
+}
+
+// readArchive returns the files of the knowledge base in the archive by their path in the knowledge base, e.g.
+// actions/checkout/action-security.yml
+func readArchive(archive io.Reader) (map[string][]byte, error) {
+	gzipReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the archive of the knowledge base: %v", err)
+	}
+	defer gzipReader.Close()
+	limited := &io.LimitedReader{R: gzipReader, N: maxArchiveSize}
+	tarReader := tar.NewReader(limited)
+	files := map[string][]byte{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			if limited.N <= 0 {
+				return nil, fmt.Errorf("the archive of the knowledge base is larger than %d bytes", maxArchiveSize)
+			}
+			return nil, fmt.Errorf("unable to read the archive of the knowledge base: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		// the files are in the folder of the repository, e.g. secure-repo-main/knowledge-base/actions
+		_, filePath, found := strings.Cut(header.Name, kbFolder)
+		if !found || !strings.HasSuffix(filePath, "/action-security.yml") {
+			continue
+		}
+		content, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %v", header.Name, err)
+		}
+		files[filePath] = content
+	}
+}
+
+// Run refreshes the knowledge base every Interval until the context is done. The errors are logged, and the current
+// knowledge base is kept.
+func (r *Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		refreshed, err := r.Refresh(ctx)
+		if err != nil {
+			logging.Logger().Warn("unable to refresh the knowledge base", "error", err)
+		} else if refreshed {
+			index := metadata.CurrentIndex()
+			logging.Logger().Info("refreshed the knowledge base", "version", index.Version(), "actions", len(index.Actions()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package kbrefresh
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/workflow/metadata"
+)
+
+// archive returns a .tar.gz of the files, like the archives of the repositories on GitHub
+func archive(t *testing.T, files map[string]string) []byte {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, content := range files {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("Error not expected: %v", err)
+		}
+		tarWriter.Write([]byte(content))
+	}
+	tarWriter.Close()
+	gzipWriter.Close()
+	return buffer.Bytes()
+}
+
+func TestRefresh(t *testing.T) {
+	defer metadata.SetIndex(nil)
+	content := archive(t, map[string]string{
+		"secure-repo-main/knowledge-base/actions/octo-org/deploy/action-security.yml": "name: Deploy\n",
+		"secure-repo-main/README.md": "not indexed",
+	})
+	etag := `"v1"`
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	refresher := &Refresher{URL: server.URL}
+	if refreshed, err := refresher.Refresh(context.Background()); err != nil || !refreshed {
+		t.Fatalf("Refresh() = %v, %v", refreshed, err)
+	}
+	if actionMetadata, err := metadata.GetActionKnowledgeBase("octo-org/deploy"); err != nil || actionMetadata.Name != "Deploy" {
+		t.Errorf("GetActionKnowledgeBase() = %+v, %v, want the refreshed knowledge base", actionMetadata, err)
+	}
+	// the archive that did not change is not downloaded again
+	if refreshed, err := refresher.Refresh(context.Background()); err != nil || refreshed || downloads != 1 {
+		t.Errorf("Refresh() = %v, %v with %d downloads, want the archive to be downloaded once", refreshed, err, downloads)
+	}
+
+	// an archive without a knowledge base keeps the current one
+	content, etag = archive(t, map[string]string{"other/README.md": "no knowledge base"}), `"v2"`
+	if _, err := refresher.Refresh(context.Background()); err == nil {
+		t.Errorf("expected an error for an archive without a knowledge base")
+	}
+	if _, err := metadata.GetActionKnowledgeBase("octo-org/deploy"); err != nil {
+		t.Errorf("expected the knowledge base to be kept, got %v", err)
+	}
+}
+
+func TestNewRefresherFromEnv(t *testing.T) {
+	t.Setenv(URLEnv, "")
+	if refresher, err := NewRefresherFromEnv(); refresher != nil || err != nil {
+		t.Errorf("NewRefresherFromEnv() = %v, %v, want nil when the knowledge base is not refreshed", refresher, err)
+	}
+	t.Setenv(URLEnv, "https://github.com/step-security/secure-repo/archive/refs/heads/main.tar.gz")
+	t.Setenv(IntervalEnv, "15m")
+	if refresher, err := NewRefresherFromEnv(); err != nil || refresher.Interval.Minutes() != 15 {
+		t.Errorf("NewRefresherFromEnv() = %+v, %v", refresher, err)
+	}
+	t.Setenv(IntervalEnv, "15")
+	if _, err := NewRefresherFromEnv(); err == nil {
+		t.Errorf("expected an error for an interval without a unit")
+	}
+}
@@ -0,0 +1,56 @@
+// Package lineending keeps the line endings of the files that are remediated. The remediations read and write lines
+// ending with LF, so a file authored on Windows is remediated with LF line endings, and the lines of the output end like
+// most of the lines of the file, instead of the lines added with LF and the others with CRLF. The UTF-8 byte order mark
+// some Windows editors write at the start of a file is also removed for the remediations and written back, since the
+// parser does not count it in the columns of the first line, and a line inserted before the first line would move it.
+package lineending
+
+import "strings"
+
+// Style is the line ending of the lines of a text
+type Style int
+
+const (
+	LF Style = iota
+	CRLF
+)
+
+// Detect returns the line ending of most of the lines of the text, and LF when as many lines end with CRLF as with LF
+func Detect(text string) Style {
+	crlf := strings.Count(text, "\r\n")
+	if crlf > strings.Count(text, "\n")-crlf {
+		return CRLF
+	}
+	return LF
+}
+
+// BOM is the UTF-8 byte order mark
+const BOM = "\ufeff"
+
+// Normalize returns the text without its byte order mark, and with the lines ending with CRLF ending with LF
+func Normalize(text string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(text, BOM), "\r\n", "\n")
+}
+
+// Apply returns the text with its lines ending with the line ending of the style
+func (s Style) Apply(text string) string {
+	text = Normalize(text)
+	if s == CRLF {
+		return strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	return text
+}
+
+// Restore returns the output of the remediation of the normalized input with the line ending and the byte order mark of
+// the input. The input is returned when the output is the normalized input, so a file with mixed line endings is only
+// changed when it is remediated.
+func Restore(input, output string) string {
+	if output == Normalize(input) {
+		return input
+	}
+	output = Detect(input).Apply(output)
+	if strings.HasPrefix(input, BOM) {
+		return BOM + output
+	}
+	return output
+}
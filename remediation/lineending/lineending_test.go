@@ -0,0 +1,45 @@
+package lineending
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want Style
+	}{
+		{text: "on: push\njobs:\n", want: LF},
+		{text: "on: push\r\njobs:\r\n", want: CRLF},
+		{text: "on: push\r\njobs:\r\n  build:\n", want: CRLF},
+		{text: "on: push\r\njobs:\n", want: LF},
+		{text: "on: push", want: LF},
+	}
+	for _, test := range tests {
+		if got := Detect(test.text); got != test.want {
+			t.Errorf("Detect(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		output string
+		want   string
+	}{
+		{name: "CRLF", input: "jobs:\r\n  build:\r\n", output: "jobs:\n  build:\n    permissions: {}\n", want: "jobs:\r\n  build:\r\n    permissions: {}\r\n"},
+		{name: "LF", input: "jobs:\n  build:\n", output: "jobs:\n  build:\n    permissions: {}\n", want: "jobs:\n  build:\n    permissions: {}\n"},
+		{name: "mixed", input: "jobs:\r\n  build:\r\n    steps:\n", output: "jobs:\n  build:\n    steps: []\n", want: "jobs:\r\n  build:\r\n    steps: []\r\n"},
+		{name: "unchanged mixed", input: "jobs:\r\n  build:\r\n    steps:\n", output: "jobs:\n  build:\n    steps:\n", want: "jobs:\r\n  build:\r\n    steps:\n"},
+		{name: "output with CRLF", input: "jobs:\r\n", output: "jobs:\r\n  build: {}\n", want: "jobs:\r\n  build: {}\r\n"},
+		{name: "byte order mark", input: "\ufeffjobs:\r\n", output: "permissions: {}\njobs:\n", want: "\ufeffpermissions: {}\r\njobs:\r\n"},
+		{name: "unchanged byte order mark", input: "\ufeffjobs:\n", output: "jobs:\n", want: "\ufeffjobs:\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Restore(test.input, test.output); got != test.want {
+				t.Errorf("Restore() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
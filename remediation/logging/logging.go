@@ -0,0 +1,52 @@
+// Package logging has the structured logger of the remediations. The logger writes JSON to stderr by default, and can be
+// replaced with SetLogger, e.g. to route the logs of a server deployment to its own handler.
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// LogFormatEnv is the format of the logs, json or text
+	LogFormatEnv = "LOG_FORMAT"
+	// LogLevelEnv is the minimum level of the logs, debug, info, warn or error
+	LogLevelEnv = "LOG_LEVEL"
+)
+
+var logger atomic.Pointer[slog.Logger]
+
+func init() {
+	logger.Store(NewLoggerFromEnv(os.Stderr))
+}
+
+// NewLoggerFromEnv returns a logger that writes to w in the format and from the level of LOG_FORMAT and LOG_LEVEL
+func NewLoggerFromEnv(w io.Writer) *slog.Logger {
+	level := slog.LevelInfo
+	switch strings.ToLower(os.Getenv(LogLevelEnv)) {
+	case "debug":
+		level = slog.LevelDebug
+	case "warn":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	}
+	options := &slog.HandlerOptions{Level: level}
+	if strings.ToLower(os.Getenv(LogFormatEnv)) == "text" {
+		return slog.New(slog.NewTextHandler(w, options))
+	}
+	return slog.New(slog.NewJSONHandler(w, options))
+}
+
+// Logger returns the logger of the remediations
+func Logger() *slog.Logger {
+	return logger.Load()
+}
+
+// SetLogger replaces the logger of the remediations
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
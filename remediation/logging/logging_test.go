@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewLoggerFromEnv(t *testing.T) {
+	defer os.Unsetenv(LogFormatEnv)
+	defer os.Unsetenv(LogLevelEnv)
+
+	var logs bytes.Buffer
+	NewLoggerFromEnv(&logs).Info("pinned actions", "module", "pin")
+	if !strings.Contains(logs.String(), `"msg":"pinned actions","module":"pin"`) {
+		t.Errorf("expected JSON log, got %s", logs.String())
+	}
+
+	os.Setenv(LogFormatEnv, "text")
+	os.Setenv(LogLevelEnv, "warn")
+	logs.Reset()
+	logger := NewLoggerFromEnv(&logs)
+	logger.Info("pinned actions", "module", "pin")
+	logger.Warn("skipping replacement", "module", "maintainedactions")
+	if strings.Contains(logs.String(), "pinned actions") {
+		t.Errorf("expected info log to be filtered, got %s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "msg=\"skipping replacement\" module=maintainedactions") {
+		t.Errorf("expected text log, got %s", logs.String())
+	}
+}
@@ -0,0 +1,419 @@
+// Package lsp is a Language Server Protocol server for the workflows in .github/workflows. The findings of the analyzers
+// are published as diagnostics while a workflow is edited, and the remediations are offered as code actions: a quick fix
+// for each of pinning actions, setting the permissions and adding Harden-Runner, and a fix of all findings with the
+// remediations enabled by the query parameters. The server reads messages from stdin and writes them to stdout, so
+// editors run it as a command, e.g. secure-repo lsp.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/diff"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow"
+	"github.com/step-security/secure-repo/remediation/workflow/hardenrunner"
+	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+)
+
+// source is the source of the diagnostics, which the code actions fix
+const source = "secure-repo"
+
+// codeContentModified is the error of resolving a code action of a document that changed since it was offered
+const codeContentModified = -32801
+
+// SecureFunc runs the remediations enabled by the query parameters on a workflow, e.g. workflow.SecureWorkflow
+type SecureFunc func(queryStringParams map[string]string, inputYaml string) (*permissions.SecureWorkflowReponse, error)
+
+// fix is a remediation offered as a quick fix of the diagnostics of its rules. The quick fix runs only the remediation,
+// with the query parameters that enable it, and the query parameters in options that configure it.
+type fix struct {
+	name    string
+	title   string
+	rules   []string
+	params  map[string]string
+	options []string
+}
+
+// fixAll is the name of the code action that fixes all findings with the remediations enabled by the query parameters
+const fixAll = "all"
+
+var fixes = []fix{
+	{name: "pin", title: "Pin actions to a full length commit SHA", rules: []string{pin.RuleUnpinnedAction},
+		params: map[string]string{"pinActions": "true", "addPermissions": "false", "addHardenRunner": "false"}},
+	{name: "permissions", title: "Set minimum GITHUB_TOKEN permissions", rules: []string{permissions.RuleMissingPermissions},
+		params:  map[string]string{"pinActions": "false", "addPermissions": "true", "addHardenRunner": "false"},
+		options: []string{"addEmptyTopLevelPermissions", "addProjectComment"}},
+	{name: "hardenrunner", title: "Add Harden-Runner to each job", rules: []string{hardenrunner.RuleMissingHardenRunner},
+		params:  map[string]string{"pinActions": "false", "addPermissions": "false", "addHardenRunner": "true"},
+		options: []string{"skipHardenRunnerForContainers"}},
+}
+
+type document struct {
+	version int
+	text    string
+}
+
+// Server is a language server of workflows. It is not safe for concurrent use, and serves one client.
+type Server struct {
+	queryStringParams map[string]string
+	secure            SecureFunc
+	documents         map[string]*document
+	// resolveEdits is whether the client resolves the edits of code actions, so they are computed when they are applied
+	resolveEdits bool
+	shutdown     bool
+	out          io.Writer
+}
+
+// NewServer returns a server that runs the remediations with workflow.SecureWorkflow, and the query parameters, which
+// are merged with the params of the initializationOptions of the client
+func NewServer(queryStringParams map[string]string) *Server {
+	return NewServerWithSecureFunc(queryStringParams, func(queryStringParams map[string]string, inputYaml string) (*permissions.SecureWorkflowReponse, error) {
+		return workflow.SecureWorkflow(context.Background(), queryStringParams, inputYaml, nil, workflow.Options{})
+	})
+}
+
+// NewServerWithSecureFunc returns a server that runs the remediations with secure
+func NewServerWithSecureFunc(queryStringParams map[string]string, secure SecureFunc) *Server {
+	params := map[string]string{}
+	for key, value := range queryStringParams {
+		params[key] = value
+	}
+	return &Server{queryStringParams: params, secure: secure, documents: map[string]*document{}}
+}
+
+// Serve reads the messages of the client from in, and writes the responses and diagnostics to out, until the client
+// sends exit. It returns an error if in is closed or exit is sent before shutdown.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+	r := bufio.NewReader(in)
+	for {
+		m, err := readMessage(r)
+		var parseError *responseError
+		if errors.As(err, &parseError) {
+			if err := writeMessage(out, &message{ID: rawNull(), Error: parseError}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("connection closed before exit")
+			}
+			return err
+		}
+		if m.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit before shutdown")
+			}
+			return nil
+		}
+
+		result, err := s.handle(m)
+		if m.ID == nil {
+			if err != nil {
+				logging.Logger().Error("unable to handle notification", "method", m.Method, "error", err)
+			}
+			continue
+		}
+		response := &message{ID: m.ID, Result: result}
+		if err != nil {
+			var rpcError *responseError
+			if !errors.As(err, &rpcError) {
+				rpcError = &responseError{Code: codeInternalError, Message: err.Error()}
+			}
+			response.Result, response.Error = nil, rpcError
+		} else if result == nil {
+			// the result of a request without one, e.g. shutdown, is null
+			response.Result = rawNull()
+		}
+		if err := writeMessage(out, response); err != nil {
+			return err
+		}
+	}
+}
+
+func rawNull() *json.RawMessage {
+	null := json.RawMessage("null")
+	return &null
+}
+
+// handle returns the result of a request, or handles a notification
+func (s *Server) handle(m *message) (interface{}, error) {
+	switch m.Method {
+	case "initialize":
+		return s.initialize(m.Params)
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		params := didOpenParams{}
+		if err := unmarshalParams(m.Params, &params); err != nil {
+			return nil, err
+		}
+		if !isWorkflow(params.TextDocument.URI) {
+			return nil, nil
+		}
+		s.documents[params.TextDocument.URI] = &document{version: params.TextDocument.Version, text: params.TextDocument.Text}
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didChange":
+		params := didChangeParams{}
+		if err := unmarshalParams(m.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, found := s.documents[params.TextDocument.URI]
+		if !found || len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// the changes have the full text of the document, since the sync is full
+		doc.version, doc.text = params.TextDocument.Version, params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didClose":
+		params := didCloseParams{}
+		if err := unmarshalParams(m.Params, &params); err != nil {
+			return nil, err
+		}
+		if _, found := s.documents[params.TextDocument.URI]; !found {
+			return nil, nil
+		}
+		delete(s.documents, params.TextDocument.URI)
+		// the diagnostics of a closed document are cleared
+		return nil, s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []diagnostic{}})
+	case "textDocument/codeAction":
+		params := codeActionParams{}
+		if err := unmarshalParams(m.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.codeActions(params)
+	case "codeAction/resolve":
+		action := codeAction{}
+		if err := unmarshalParams(m.Params, &action); err != nil {
+			return nil, err
+		}
+		return s.resolveCodeAction(action)
+	}
+	if m.ID != nil {
+		return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %s is not supported", m.Method)}
+	}
+	// other notifications, e.g. initialized and $/cancelRequest, are ignored
+	return nil, nil
+}
+
+func (s *Server) initialize(rawParams json.RawMessage) (interface{}, error) {
+	params := initializeParams{}
+	if err := unmarshalParams(rawParams, &params); err != nil {
+		return nil, err
+	}
+	if resolveSupport := params.Capabilities.TextDocument.CodeAction.ResolveSupport; resolveSupport != nil {
+		for _, property := range resolveSupport.Properties {
+			s.resolveEdits = s.resolveEdits || property == "edit"
+		}
+	}
+	if params.InitializationOptions != nil {
+		for key, value := range params.InitializationOptions.Params {
+			s.queryStringParams[key] = value
+		}
+	}
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync": textDocumentSyncFull,
+			"codeActionProvider": map[string]interface{}{
+				"codeActionKinds": []string{codeActionQuickFix, codeActionFixAll},
+				"resolveProvider": true,
+			},
+		},
+		"serverInfo": map[string]string{"name": source},
+	}, nil
+}
+
+// isWorkflow returns whether the document is a workflow in .github/workflows
+func isWorkflow(uri string) bool {
+	documentURL, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	extension := path.Ext(documentURL.Path)
+	return path.Base(path.Dir(documentURL.Path)) == "workflows" && path.Base(path.Dir(path.Dir(documentURL.Path))) == ".github" &&
+		(extension == ".yml" || extension == ".yaml")
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.out, &message{Method: method, Params: rawParams})
+}
+
+// getParams returns a copy of the query parameters of the server, with the overrides
+func (s *Server) getParams(overrides map[string]string) map[string]string {
+	params := map[string]string{"ignoreMissingKBs": "true"}
+	for key, value := range s.queryStringParams {
+		params[key] = value
+	}
+	for key, value := range overrides {
+		params[key] = value
+	}
+	return params
+}
+
+// publishDiagnostics publishes the findings of the analyzers and the checks enabled by the query parameters, and the
+// jobs without Harden-Runner, unless it is disabled. The workflow is not changed, so no GitHub API requests are made
+// unless a check enabled by the query parameters makes them.
+func (s *Server) publishDiagnostics(uri string) error {
+	doc := s.documents[uri]
+	overrides := map[string]string{"pinActions": "false", "addPermissions": "false", "addHardenRunner": "false"}
+	for _, param := range workflow.AnalyzerParams {
+		if _, found := s.queryStringParams[param]; !found {
+			overrides[param] = "true"
+		}
+	}
+	var detected []findings.Finding
+	// the workflow may not be valid YAML while it is edited, so the errors are only logged
+	response, err := s.secure(s.getParams(overrides), doc.text)
+	if err != nil {
+		logging.Logger().Debug("unable to analyze workflow", "uri", uri, "error", err)
+	} else {
+		detected = response.Findings
+	}
+	if s.queryStringParams["addHardenRunner"] != "false" {
+		missing, _ := hardenrunner.FindJobsWithoutHardenRunner(doc.text)
+		detected = append(detected, missing...)
+	}
+
+	lines := strings.Split(doc.text, "\n")
+	diagnostics := []diagnostic{}
+	for _, finding := range detected {
+		message := finding.Message
+		if finding.Suggestion != "" {
+			message += "\n" + finding.Suggestion
+		}
+		diagnostics = append(diagnostics, diagnostic{Range: getRange(lines, finding), Severity: severityWarning, Code: finding.RuleID,
+			Source: source, Message: message})
+	}
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Version: doc.version, Diagnostics: diagnostics})
+}
+
+// getRange returns the range from the line and column of the finding to the end of its line. LSP counts characters in
+// UTF-16 code units, while the columns of findings count characters.
+func getRange(lines []string, finding findings.Finding) textRange {
+	line := finding.Line - 1
+	if line < 0 || line >= len(lines) {
+		line = 0
+	}
+	text := strings.TrimSuffix(lines[line], "\r")
+	column := textedit.ColumnOffset(text, finding.Column)
+	return textRange{Start: position{Line: line, Character: utf16Length(text[:column])}, End: position{Line: line, Character: utf16Length(text)}}
+}
+
+func utf16Length(text string) int {
+	length := 0
+	// invalid UTF-8 is decoded as the replacement character, which is one code unit
+	for _, r := range text {
+		if r >= 0x10000 {
+			// runes outside the basic multilingual plane are surrogate pairs
+			length += 2
+		} else {
+			length++
+		}
+	}
+	return length
+}
+
+// codeActions returns the quick fixes of the diagnostics in the context, and the fix of all findings. The edits are
+// computed when the client resolves the code actions, if it can.
+func (s *Server) codeActions(params codeActionParams) ([]codeAction, error) {
+	doc, found := s.documents[params.TextDocument.URI]
+	if !found {
+		return []codeAction{}, nil
+	}
+	var actions []codeAction
+	for _, f := range fixes {
+		var fixed []diagnostic
+		for _, d := range params.Context.Diagnostics {
+			for _, rule := range f.rules {
+				if d.Source == source && d.Code == rule {
+					fixed = append(fixed, d)
+				}
+			}
+		}
+		if len(fixed) > 0 {
+			actions = append(actions, codeAction{Title: f.title, Kind: codeActionQuickFix, Diagnostics: fixed, IsPreferred: true,
+				Data: &codeActionData{URI: params.TextDocument.URI, Version: doc.version, Fix: f.name}})
+		}
+	}
+	actions = append(actions, codeAction{Title: "Fix all secure-repo findings", Kind: codeActionFixAll,
+		Data: &codeActionData{URI: params.TextDocument.URI, Version: doc.version, Fix: fixAll}})
+	if s.resolveEdits {
+		return actions, nil
+	}
+
+	resolved := []codeAction{}
+	for _, action := range actions {
+		action, err := s.resolveCodeAction(action)
+		if err != nil {
+			return nil, err
+		}
+		// the code actions that would not change the workflow are not offered
+		if len(action.Edit.Changes[params.TextDocument.URI]) > 0 {
+			resolved = append(resolved, action)
+		}
+	}
+	return resolved, nil
+}
+
+// resolveCodeAction adds the edit of the fix of the code action, which is computed for the version of the document
+// the code action was offered for
+func (s *Server) resolveCodeAction(action codeAction) (codeAction, error) {
+	if action.Data == nil {
+		return action, &responseError{Code: codeInvalidParams, Message: "code action has no data"}
+	}
+	doc, found := s.documents[action.Data.URI]
+	if !found || doc.version != action.Data.Version {
+		return action, &responseError{Code: codeContentModified, Message: "document changed since the code action was offered"}
+	}
+	response, err := s.secure(s.getFixParams(action.Data.Fix), doc.text)
+	if err != nil {
+		return action, fmt.Errorf("unable to secure workflow: %v", err)
+	}
+	edits := []textEdit{}
+	for _, hunk := range diff.Hunks(doc.text, response.FinalOutput) {
+		edits = append(edits, textEdit{
+			Range:   textRange{Start: position{Line: hunk.Line - 1}, End: position{Line: hunk.Line - 1 + hunk.Removed}},
+			NewText: hunk.Inserted,
+		})
+	}
+	action.Edit = &workspaceEdit{Changes: map[string][]textEdit{action.Data.URI: edits}}
+	return action, nil
+}
+
+// getFixParams returns the query parameters of a quick fix, which are the ones that enable its remediation and the
+// options that configure it, or the query parameters of the server for the fix of all findings
+func (s *Server) getFixParams(name string) map[string]string {
+	for _, f := range fixes {
+		if f.name != name {
+			continue
+		}
+		params := map[string]string{"ignoreMissingKBs": "true"}
+		for _, option := range f.options {
+			if value, found := s.queryStringParams[option]; found {
+				params[option] = value
+			}
+		}
+		for key, value := range f.params {
+			params[key] = value
+		}
+		return params
+	}
+	return s.getParams(nil)
+}
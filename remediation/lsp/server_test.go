@@ -0,0 +1,178 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+)
+
+const workflowURI = "file:///src/app/.github/workflows/ci.yml"
+
+const workflowText = `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+`
+
+// exchange sends the messages to a server, followed by shutdown and exit, and returns the messages it wrote
+func exchange(t *testing.T, server *Server, messages ...string) []map[string]interface{} {
+	var in bytes.Buffer
+	for _, m := range append(messages, `{"jsonrpc": "2.0", "id": 99, "method": "shutdown"}`, `{"jsonrpc": "2.0", "method": "exit"}`) {
+		in.WriteString("Content-Length: " + strconv.Itoa(len(m)) + "\r\n\r\n" + m)
+	}
+	var out bytes.Buffer
+	if err := server.Serve(&in, &out); err != nil {
+		t.Fatalf("Serve() unexpected error = %v", err)
+	}
+	var written []map[string]interface{}
+	r := bufio.NewReader(&out)
+	for {
+		m, err := readMessage(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unable to read message: %v", err)
+		}
+		var decoded map[string]interface{}
+		raw, _ := json.Marshal(m)
+		json.Unmarshal(raw, &decoded)
+		written = append(written, decoded)
+	}
+	return written
+}
+
+func toJSON(v interface{}) string {
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+func TestServer(t *testing.T) {
+	open := toJSON(map[string]interface{}{"jsonrpc": "2.0", "method": "textDocument/didOpen",
+		"params": map[string]interface{}{"textDocument": map[string]interface{}{"uri": workflowURI, "version": 1, "text": workflowText}}})
+	codeActions := toJSON(map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "textDocument/codeAction", "params": map[string]interface{}{
+		"textDocument": map[string]string{"uri": workflowURI},
+		"range":        textRange{Start: position{Line: 3}, End: position{Line: 3}},
+		"context": map[string]interface{}{"diagnostics": []diagnostic{
+			{Range: textRange{Start: position{Line: 3, Character: 2}, End: position{Line: 3, Character: 8}}, Source: source, Code: "missing-permissions"},
+		}},
+	}})
+	resolve := toJSON(map[string]interface{}{"jsonrpc": "2.0", "id": 3, "method": "codeAction/resolve", "params": codeAction{
+		Title: "Set minimum GITHUB_TOKEN permissions", Kind: codeActionQuickFix, Data: &codeActionData{URI: workflowURI, Version: 1, Fix: "permissions"}}})
+
+	written := exchange(t, NewServer(nil),
+		`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"capabilities": {"textDocument": {"codeAction": {"resolveSupport": {"properties": ["edit"]}}}}}}`,
+		`{"jsonrpc": "2.0", "method": "initialized", "params": {}}`,
+		open,
+		// only workflows are analyzed
+		`{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": {"textDocument": {"uri": "file:///src/app/config.yml", "version": 1, "text": "a: b"}}}`,
+		codeActions,
+		resolve,
+		`{"jsonrpc": "2.0", "id": 4, "method": "textDocument/hover", "params": {}}`,
+	)
+	if len(written) != 6 {
+		t.Fatalf("expected 6 messages, got %d: %v", len(written), written)
+	}
+
+	capabilities := toJSON(written[0]["result"])
+	if !strings.Contains(capabilities, `"textDocumentSync":1`) || !strings.Contains(capabilities, `"resolveProvider":true`) {
+		t.Errorf("initialize result = %s, want full sync and resolvable code actions", capabilities)
+	}
+
+	if written[1]["method"] != "textDocument/publishDiagnostics" {
+		t.Fatalf("expected diagnostics, got %v", written[1])
+	}
+	published := publishDiagnosticsParams{}
+	json.Unmarshal([]byte(toJSON(written[1]["params"])), &published)
+	var rules []string
+	for _, d := range published.Diagnostics {
+		rules = append(rules, d.Code)
+	}
+	if published.URI != workflowURI || published.Version != 1 ||
+		strings.Join(rules, ",") != "unpinned-action,missing-permissions,missing-permissions,missing-harden-runner" {
+		t.Fatalf("diagnostics = %+v, want the findings of the analyzers and the job without harden-runner", published)
+	}
+	// the unpinned action is reported from its column to the end of the line
+	if got := published.Diagnostics[0]; got.Range != (textRange{Start: position{Line: 6, Character: 14}, End: position{Line: 6, Character: 33}}) ||
+		got.Severity != severityWarning || got.Source != source {
+		t.Errorf("diagnostic = %+v", got)
+	}
+
+	actions := written[2]["result"].([]interface{})
+	if len(actions) != 2 || toJSON(actions[0].(map[string]interface{})["title"]) != `"Set minimum GITHUB_TOKEN permissions"` ||
+		actions[0].(map[string]interface{})["edit"] != nil || actions[1].(map[string]interface{})["kind"] != codeActionFixAll {
+		t.Errorf("code actions = %s, want the permissions quick fix and the fix of all findings, without edits", toJSON(actions))
+	}
+
+	edit := workspaceEdit{}
+	json.Unmarshal([]byte(toJSON(written[3]["result"].(map[string]interface{})["edit"])), &edit)
+	want := workspaceEdit{Changes: map[string][]textEdit{workflowURI: {
+		{Range: textRange{Start: position{Line: 2}, End: position{Line: 2}}, NewText: "permissions:  # added using https://github.com/step-security/secure-repo\n  contents: read\n\n"},
+	}}}
+	if !reflect.DeepEqual(edit, want) {
+		t.Errorf("resolved edit = %+v, want %+v", edit, want)
+	}
+
+	if toJSON(written[4]["error"]) != `{"code":-32601,"message":"method textDocument/hover is not supported"}` {
+		t.Errorf("expected method not found, got %v", written[4])
+	}
+	if toJSON(written[5]["id"]) != "99" || written[5]["result"] != nil {
+		t.Errorf("expected the null result of shutdown, got %v", written[5])
+	}
+}
+
+func TestServerWithoutResolveSupport(t *testing.T) {
+	open := toJSON(map[string]interface{}{"jsonrpc": "2.0", "method": "textDocument/didOpen",
+		"params": map[string]interface{}{"textDocument": map[string]interface{}{"uri": workflowURI, "version": 1, "text": workflowText}}})
+	codeActions := toJSON(map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "textDocument/codeAction", "params": map[string]interface{}{
+		"textDocument": map[string]string{"uri": workflowURI},
+		"context":      map[string]interface{}{"diagnostics": []diagnostic{}},
+	}})
+	// the fix of all findings only sets the permissions, since the other remediations are disabled
+	server := NewServer(map[string]string{"pinActions": "false", "addHardenRunner": "false"})
+	written := exchange(t, server, `{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"capabilities": {}}}`, open, codeActions)
+
+	actions := written[2]["result"].([]interface{})
+	if len(actions) != 1 || !strings.Contains(toJSON(actions[0]), `"newText":"permissions:  # added using https://github.com/step-security/secure-repo\n  contents: read\n\n"`) {
+		t.Errorf("code actions = %s, want the fix of all findings with its edit", toJSON(actions))
+	}
+	if strings.Contains(toJSON(written[1]["params"]), "missing-harden-runner") {
+		t.Errorf("expected no harden-runner diagnostics when it is disabled")
+	}
+}
+
+func TestIsWorkflow(t *testing.T) {
+	for uri, want := range map[string]bool{
+		workflowURI: true,
+		"file:///src/app/.github/workflows/release.yaml":      true,
+		"file:///src/app/.github/workflows/scripts/build.yml": false,
+		"file:///src/app/.github/dependabot.yml":              false,
+		"file:///src/app/workflows/ci.yml":                    false,
+	} {
+		if got := isWorkflow(uri); got != want {
+			t.Errorf("isWorkflow(%s) = %v, want %v", uri, got, want)
+		}
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	lines := strings.Split("jobs:\n  build:\r\n    name: \"🔒 ${{ github.head_ref }}\"\n", "\n")
+	got := getRange(lines, findings.Finding{Line: 3, Column: 14})
+	// the emoji is one character, two UTF-16 code units, and four bytes
+	if got != (textRange{Start: position{Line: 2, Character: 14}, End: position{Line: 2, Character: 37}}) {
+		t.Errorf("getRange() = %+v", got)
+	}
+	if got := getRange(lines, findings.Finding{Line: 2, Column: 3}); got != (textRange{Start: position{Line: 1, Character: 2}, End: position{Line: 1, Character: 8}}) {
+		t.Errorf("getRange() = %+v, want the carriage return left out", got)
+	}
+}
@@ -0,0 +1,187 @@
+// Package metrics has counters, gauges and histograms, which are written in the Prometheus text format to be scraped
+// from the /metrics route.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds in seconds of the buckets of the duration histograms
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// series is a metric with the values of its labels. The values of the histogram are the counts of the buckets.
+type series struct {
+	labelValues []string
+	value       float64
+	buckets     []uint64
+	count       uint64
+}
+
+// metric is a counter, gauge or histogram with its series, keyed by the values of the labels
+type metric struct {
+	mutex      sync.Mutex
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	buckets    []float64
+	series     map[string]*series
+}
+
+func (m *metric) getSeries(labelValues []string) *series {
+	if len(labelValues) != len(m.labelNames) {
+		panic(fmt.Sprintf("metric %s has labels %v, got values %v", m.name, m.labelNames, labelValues))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, found := m.series[key]
+	if !found {
+		s = &series{labelValues: append([]string{}, labelValues...), buckets: make([]uint64, len(m.buckets))}
+		m.series[key] = s
+	}
+	return s
+}
+
+// Counter is a value that only goes up, e.g. the number of requests
+type Counter struct{ m *metric }
+
+// Inc adds 1 to the counter with the label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds a value, which must not be negative, to the counter with the label values
+func (c *Counter) Add(value float64, labelValues ...string) {
+	c.m.mutex.Lock()
+	defer c.m.mutex.Unlock()
+	c.m.getSeries(labelValues).value += value
+}
+
+// Gauge is a value that goes up and down, e.g. the remaining rate limit
+type Gauge struct{ m *metric }
+
+// Set sets the gauge with the label values
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.m.mutex.Lock()
+	defer g.m.mutex.Unlock()
+	g.m.getSeries(labelValues).value = value
+}
+
+// Histogram counts observations, e.g. durations, in buckets
+type Histogram struct{ m *metric }
+
+// Observe adds a value to the histogram with the label values
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.m.mutex.Lock()
+	defer h.m.mutex.Unlock()
+	s := h.m.getSeries(labelValues)
+	for i, upperBound := range h.m.buckets {
+		if value <= upperBound {
+			s.buckets[i]++
+		}
+	}
+	s.count++
+	s.value += value
+}
+
+// Registry has the metrics, which are written in the order they were registered
+type Registry struct {
+	mutex   sync.Mutex
+	metrics []*metric
+}
+
+// DefaultRegistry has the metrics of the remediations and the handler
+var DefaultRegistry = &Registry{}
+
+func (r *Registry) register(name, help, kind string, buckets []float64, labelNames []string) *metric {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, m := range r.metrics {
+		if m.name == name {
+			panic(fmt.Sprintf("metric %s is already registered", name))
+		}
+	}
+	m := &metric{name: name, help: help, kind: kind, labelNames: labelNames, buckets: buckets, series: map[string]*series{}}
+	r.metrics = append(r.metrics, m)
+	return m
+}
+
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	return &Counter{m: r.register(name, help, "counter", nil, labelNames)}
+}
+
+func (r *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	return &Gauge{m: r.register(name, help, "gauge", nil, labelNames)}
+}
+
+// NewHistogram returns a histogram with the upper bounds of the buckets, which are sorted
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	return &Histogram{m: r.register(name, help, "histogram", buckets, labelNames)}
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels returns the labels in braces, with the extra label of the bucket of a histogram if it is set
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	var labels []string
+	for i, name := range names {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, name, labelValueReplacer.Replace(values[i])))
+	}
+	if extraName != "" {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, extraName, extraValue))
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// Write writes the metrics in the Prometheus text format. The series of each metric are sorted by their label values.
+func (r *Registry) Write(w io.Writer) error {
+	r.mutex.Lock()
+	metrics := append([]*metric{}, r.metrics...)
+	r.mutex.Unlock()
+
+	var sb strings.Builder
+	for _, m := range metrics {
+		m.mutex.Lock()
+		keys := make([]string, 0, len(m.series))
+		for key := range m.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, key := range keys {
+			s := m.series[key]
+			if m.kind != "histogram" {
+				fmt.Fprintf(&sb, "%s%s %s\n", m.name, formatLabels(m.labelNames, s.labelValues, "", ""), formatValue(s.value))
+				continue
+			}
+			for i, upperBound := range m.buckets {
+				fmt.Fprintf(&sb, "%s_bucket%s %d\n", m.name, formatLabels(m.labelNames, s.labelValues, "le", formatValue(upperBound)), s.buckets[i])
+			}
+			fmt.Fprintf(&sb, "%s_bucket%s %d\n", m.name, formatLabels(m.labelNames, s.labelValues, "le", "+Inf"), s.count)
+			fmt.Fprintf(&sb, "%s_sum%s %s\n", m.name, formatLabels(m.labelNames, s.labelValues, "", ""), formatValue(s.value))
+			fmt.Fprintf(&sb, "%s_count%s %d\n", m.name, formatLabels(m.labelNames, s.labelValues, "", ""), s.count)
+		}
+		m.mutex.Unlock()
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/report"
+)
+
+func TestWrite(t *testing.T) {
+	registry := &Registry{}
+	requests := registry.NewCounter("test_requests_total", "Requests.", "route", "code")
+	remaining := registry.NewGauge("test_remaining", "Remaining.")
+	duration := registry.NewHistogram("test_duration_seconds", "Duration.", []float64{0.1, 1}, "route")
+
+	requests.Inc("secure-workflow", "200")
+	requests.Inc("secure-workflow", "200")
+	requests.Inc("secure-repo", "500")
+	remaining.Set(4999)
+	duration.Observe(0.05, "secure-workflow")
+	duration.Observe(0.5, "secure-workflow")
+
+	var sb strings.Builder
+	if err := registry.Write(&sb); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	want := `# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{route="secure-repo",code="500"} 1
+test_requests_total{route="secure-workflow",code="200"} 2
+# HELP test_remaining Remaining.
+# TYPE test_remaining gauge
+test_remaining 4999
+# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{route="secure-workflow",le="0.1"} 1
+test_duration_seconds_bucket{route="secure-workflow",le="1"} 2
+test_duration_seconds_bucket{route="secure-workflow",le="+Inf"} 2
+test_duration_seconds_sum{route="secure-workflow"} 0.55
+test_duration_seconds_count{route="secure-workflow"} 2
+`
+	if sb.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
+
+func TestObserveReport(t *testing.T) {
+	workflowReport := &report.Report{Modules: []report.Module{
+		{Name: "test-pin", Changes: []report.Change{{Line: 7}, {Line: 9}}, Skipped: []report.Skipped{{Item: "docker://alpine", Reason: "unable to find the digest of the image"}}},
+		{Name: "test-dispatchinputs", Skipped: []report.Skipped{{Item: "deploy", Reason: "unable to fix automatically: input is used in a script"}}, Errors: []string{"unable to parse yaml"}},
+	}}
+	ObserveReport(workflowReport)
+
+	var sb strings.Builder
+	DefaultRegistry.Write(&sb)
+	for _, line := range []string{
+		`securerepo_module_changes_total{module="test-pin"} 2`,
+		`securerepo_module_skipped_total{module="test-pin",reason="unable to find the digest of the image"} 1`,
+		`securerepo_module_skipped_total{module="test-dispatchinputs",reason="unable to fix automatically"} 1`,
+		`securerepo_module_errors_total{module="test-dispatchinputs"} 1`,
+	} {
+		if !strings.Contains(sb.String(), line) {
+			t.Errorf("metrics do not have %s", line)
+		}
+	}
+}
+
+func TestGitHubHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "4321")
+		w.Header().Set("X-RateLimit-Resource", "core")
+	}))
+	defer server.Close()
+
+	resp, err := GitHubHTTPClient().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+	resp.Body.Close()
+
+	var sb strings.Builder
+	DefaultRegistry.Write(&sb)
+	if !strings.Contains(sb.String(), `securerepo_github_rate_limit_remaining{resource="core"} 4321`) {
+		t.Errorf("metrics do not have the remaining rate limit:\n%s", sb.String())
+	}
+	if !strings.Contains(sb.String(), `securerepo_github_request_duration_seconds_count{code="200"} 1`) {
+		t.Errorf("metrics do not have the duration of the request:\n%s", sb.String())
+	}
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/step-security/secure-repo/remediation/httpcache"
+	"github.com/step-security/secure-repo/remediation/outbound"
+	"github.com/step-security/secure-repo/remediation/report"
+	"golang.org/x/oauth2"
+)
+
+var (
+	Requests        = DefaultRegistry.NewCounter("securerepo_requests_total", "Requests to the API by route and status code.", "route", "code")
+	RequestDuration = DefaultRegistry.NewHistogram("securerepo_request_duration_seconds", "Duration of the requests to the API by route.", DefaultBuckets, "route")
+
+	ModuleDuration = DefaultRegistry.NewHistogram("securerepo_module_duration_seconds", "Duration of the remediation modules.", DefaultBuckets, "module")
+	ModuleChanges  = DefaultRegistry.NewCounter("securerepo_module_changes_total", "Lines changed by the remediation modules.", "module")
+	ModuleSkipped  = DefaultRegistry.NewCounter("securerepo_module_skipped_total", "Items skipped by the remediation modules by reason.", "module", "reason")
+	ModuleErrors   = DefaultRegistry.NewCounter("securerepo_module_errors_total", "Errors of the remediation modules.", "module")
+
+	CacheRequests = DefaultRegistry.NewCounter("securerepo_cache_requests_total", "Lookups of the response cache by result, which is hit or miss.", "result")
+
+	GitHubCacheRequests      = DefaultRegistry.NewCounter("securerepo_github_cache_requests_total", "Requests to the GitHub API answered from the cache by result, which is revalidated or stale.", "result")
+	GitHubRequestDuration    = DefaultRegistry.NewHistogram("securerepo_github_request_duration_seconds", "Duration of the requests to the GitHub API by status code.", DefaultBuckets, "code")
+	GitHubRateLimitRemaining = DefaultRegistry.NewGauge("securerepo_github_rate_limit_remaining", "Requests remaining in the rate limit of the GitHub API by resource.", "resource")
+
+	OutboundEvents = DefaultRegistry.NewCounter("securerepo_outbound_events_total", "Retries, timeouts and open circuits of the requests to other services by host and event.", "host", "event")
+)
+
+func init() {
+	outbound.Observe = func(host, event string) {
+		OutboundEvents.Inc(host, event)
+	}
+}
+
+// ObserveReport counts the changes, skipped items and errors of the modules in the report of a workflow
+func ObserveReport(workflowReport *report.Report) {
+	if workflowReport == nil {
+		return
+	}
+	for _, module := range workflowReport.Modules {
+		if len(module.Changes) > 0 {
+			ModuleChanges.Add(float64(len(module.Changes)), module.Name)
+		}
+		for _, skipped := range module.Skipped {
+			ModuleSkipped.Inc(module.Name, reasonLabel(skipped.Reason))
+		}
+		if len(module.Errors) > 0 {
+			ModuleErrors.Add(float64(len(module.Errors)), module.Name)
+		}
+	}
+}
+
+// reasonLabel returns the reason without the details after the first colon, e.g. the message of a finding, so the
+// number of series stays small
+func reasonLabel(reason string) string {
+	if i := strings.Index(reason, ":"); i > 0 {
+		return reason[:i]
+	}
+	return reason
+}
+
+// githubTransport observes the duration of the requests to the GitHub API and the remaining rate limit. The requests
+// are sent with the transport of httpcache, which uses the default transport at the time of the request, so it can be
+// replaced, e.g. by httpmock in tests.
+type githubTransport struct{}
+
+func (t githubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := httpcache.Default().RoundTrip(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+		if result := resp.Header.Get(httpcache.ResultHeader); result != "" {
+			GitHubCacheRequests.Inc(result)
+		}
+		if remaining, parseErr := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); parseErr == nil {
+			resource := resp.Header.Get("X-RateLimit-Resource")
+			if resource == "" {
+				resource = "core"
+			}
+			GitHubRateLimitRemaining.Set(float64(remaining), resource)
+		}
+	}
+	GitHubRequestDuration.Observe(time.Since(start).Seconds(), code)
+	return resp, err
+}
+
+// GitHubHTTPClient returns an HTTP client for unauthenticated requests to the GitHub API, whose requests are observed
+func GitHubHTTPClient() *http.Client {
+	return &http.Client{Transport: githubTransport{}}
+}
+
+// WithGitHubClient returns a context for oauth2.NewClient, so the requests of the client it returns are observed
+func WithGitHubClient(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, GitHubHTTPClient())
+}
@@ -0,0 +1,109 @@
+// Package multidoc splits the YAML files that have several documents separated by "---", e.g. the workflows concatenated
+// by tooling into one file, so each document is remediated as a file of its own, and joins the remediated documents with
+// the separators of the file.
+package multidoc
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a document of a YAML file, with the lines before it which end the previous document and start it, such
+// as "---". The first document has no separator if the file does not start with one.
+type Document struct {
+	Separator string
+	Content   string
+	// Line is the line of the file the content starts at
+	Line int
+}
+
+// isMarker returns true if the line is a document marker, which is "---" or "..." at the start of the line, followed by
+// the end of the line, a comment or the content of the document
+func isMarker(line string) bool {
+	line = strings.TrimRight(line, "\r\n")
+	for _, marker := range []string{"---", "..."} {
+		if line == marker || strings.HasPrefix(line, marker+" ") || strings.HasPrefix(line, marker+"\t") {
+			return true
+		}
+	}
+	return false
+}
+
+// Split returns the documents of the text. Joining them returns the text.
+func Split(text string) []Document {
+	var documents []Document
+	// the separator of the current document is text[start:contentStart], and its content text[contentStart:offset]
+	start, contentStart, line, contentLine := 0, 0, 1, 1
+	for offset := 0; offset < len(text); line++ {
+		end := strings.IndexByte(text[offset:], '\n') + 1
+		if end == 0 {
+			end = len(text) - offset
+		}
+		if isMarker(text[offset : offset+end]) {
+			// the markers of consecutive lines, such as "..." and "---", are the separator of the same document
+			if contentStart < offset {
+				documents = append(documents, Document{Separator: text[start:contentStart], Content: text[contentStart:offset], Line: contentLine})
+				start = offset
+			}
+			contentStart, contentLine = offset+end, line+1
+		}
+		offset += end
+	}
+	return append(documents, Document{Separator: text[start:contentStart], Content: text[contentStart:], Line: contentLine})
+}
+
+// Join returns the text of the documents
+func Join(documents []Document) string {
+	var builder strings.Builder
+	for _, document := range documents {
+		builder.WriteString(document.Separator)
+		builder.WriteString(document.Content)
+	}
+	return builder.String()
+}
+
+// IsEmpty returns true if the document has no content other than blank lines and comments
+func (d Document) IsEmpty() bool {
+	for _, line := range strings.Split(d.Content, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of documents of the text that are not empty
+func Count(text string) int {
+	count := 0
+	for _, document := range Split(text) {
+		if !document.IsEmpty() {
+			count++
+		}
+	}
+	return count
+}
+
+// Mappings returns the top mappings of the documents of the text, for the modules of files with several resources,
+// e.g. Kubernetes manifests, which edit the text of the file at the lines of the nodes. The documents that are not
+// mappings, e.g. empty documents, are skipped.
+func Mappings(text string) ([]*yaml.Node, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(text))
+	var mappings []*yaml.Node
+	for {
+		document := yaml.Node{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return mappings, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(document.Content) > 0 && document.Content[0].Kind == yaml.MappingNode {
+			mappings = append(mappings, document.Content[0])
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package multidoc
+
+import "testing"
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Document
+	}{
+		{name: "one document", text: "on: push\njobs: {}\n", want: []Document{{Content: "on: push\njobs: {}\n", Line: 1}}},
+		{name: "leading marker", text: "---\non: push\n", want: []Document{{Separator: "---\n", Content: "on: push\n", Line: 2}}},
+		{name: "two documents", text: "# ci\non: push\n--- # release\non: release\n", want: []Document{
+			{Content: "# ci\non: push\n", Line: 1},
+			{Separator: "--- # release\n", Content: "on: release\n", Line: 4},
+		}},
+		{name: "end and start markers", text: "on: push\n...\n---\non: release", want: []Document{
+			{Content: "on: push\n", Line: 1},
+			{Separator: "...\n---\n", Content: "on: release", Line: 4},
+		}},
+		{name: "trailing marker", text: "on: push\n---\n", want: []Document{
+			{Content: "on: push\n", Line: 1},
+			{Separator: "---\n", Line: 3},
+		}},
+		{name: "marker in a block scalar", text: "run: |\n  ---\n  echo\n", want: []Document{{Content: "run: |\n  ---\n  echo\n", Line: 1}}},
+		{name: "CRLF", text: "on: push\r\n---\r\non: release\r\n", want: []Document{
+			{Content: "on: push\r\n", Line: 1},
+			{Separator: "---\r\n", Content: "on: release\r\n", Line: 3},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Split(test.text)
+			if len(got) != len(test.want) {
+				t.Fatalf("Split() = %+v, want %+v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("Split()[%d] = %+v, want %+v", i, got[i], test.want[i])
+				}
+			}
+			if joined := Join(got); joined != test.text {
+				t.Errorf("Join() = %q, want %q", joined, test.text)
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "on: push\n", want: 1},
+		{text: "---\non: push\n---\n", want: 1},
+		{text: "on: push\n---\n# disabled\n...\n---\non: release\n", want: 2},
+		{text: "", want: 0},
+	}
+	for _, test := range tests {
+		if got := Count(test.text); got != test.want {
+			t.Errorf("Count(%q) = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
+
+func TestMappings(t *testing.T) {
+	mappings, err := Mappings("kind: Task\n---\n# empty\n---\n- item\n---\nkind: Pipeline\n")
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(mappings) != 2 || mappings[0].Line != 1 || mappings[1].Line != 7 {
+		t.Errorf("Mappings() = %v, want the mappings on lines 1 and 7", mappings)
+	}
+	if _, err := Mappings("kind: [Task\n"); err == nil {
+		t.Errorf("expected an error for invalid YAML")
+	}
+}
@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/outbound"
+)
+
+const (
+	// environment variables with the comma separated URLs of the webhooks, and the secret used to sign their payloads
+	WebhookURLsEnv   = "NOTIFY_WEBHOOK_URLS"
+	WebhookSecretEnv = "NOTIFY_WEBHOOK_SECRET"
+
+	// SignatureHeader has the HMAC-SHA256 of the payload with the secret, in the same format as the signatures of GitHub webhooks
+	SignatureHeader = "X-StepSecurity-Signature-256"
+	EventHeader     = "X-StepSecurity-Event"
+
+	// EventComputed is sent when remediations are returned by the API, and EventApplied when they are opened as a pull request
+	EventComputed = "remediations.computed"
+	EventApplied  = "remediations.applied"
+)
+
+var httpClient = outbound.NewClient(10 * time.Second)
+
+// Notification is the payload of the webhooks. Report is the JSON report of the request, which is the report of the
+// workflow for a workflow, and the reports of the files for a repository.
+type Notification struct {
+	Event          string
+	Repository     string `json:",omitempty"`
+	Path           string `json:",omitempty"`
+	PullRequestURL string `json:",omitempty"`
+	Report         interface{}
+	Timestamp      time.Time
+}
+
+// Sign returns the signature of the payload with the secret, e.g. sha256=6a2f...
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GetWebhookURLs returns the URLs of the webhooks from NOTIFY_WEBHOOK_URLS
+func GetWebhookURLs() []string {
+	var urls []string
+	for _, url := range strings.Split(os.Getenv(WebhookURLsEnv), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// Send posts the notification to each webhook, signed with the secret if it is set. All webhooks are sent to, and the
+// errors of the ones that failed are returned together.
+func Send(urls []string, secret string, notification *Notification) error {
+	if notification.Timestamp.IsZero() {
+		notification.Timestamp = time.Now().UTC()
+	}
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("unable to marshal notification: %v", err)
+	}
+
+	var errors []string
+	for _, url := range urls {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", url, err))
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(EventHeader, notification.Event)
+		if secret != "" {
+			req.Header.Set(SignatureHeader, Sign([]byte(secret), payload))
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", url, err))
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			errors = append(errors, fmt.Sprintf("%s: status code %d", url, resp.StatusCode))
+		}
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("unable to send notification: %s", strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+// Notify sends the notification to the webhooks configured with NOTIFY_WEBHOOK_URLS and NOTIFY_WEBHOOK_SECRET. The errors
+// are logged, since the remediations should not fail when a webhook does.
+func Notify(notification *Notification) {
+	urls := GetWebhookURLs()
+	if len(urls) == 0 {
+		return
+	}
+	if err := Send(urls, os.Getenv(WebhookSecretEnv), notification); err != nil {
+		logging.Logger().Error("unable to send notification", "event", notification.Event, "error", err)
+	}
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/report"
+)
+
+func TestSend(t *testing.T) {
+	var payload []byte
+	var signature, event string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ = ioutil.ReadAll(r.Body)
+		signature, event = r.Header.Get(SignatureHeader), r.Header.Get(EventHeader)
+	}))
+	defer server.Close()
+
+	workflowReport := &report.Report{Modules: []report.Module{{Name: "pin", Changes: []report.Change{{Line: 7, Kind: "modified"}}}}}
+	err := Send([]string{server.URL}, "secret", &Notification{Event: EventComputed, Path: ".github/workflows/ci.yml", Report: workflowReport})
+	if err != nil {
+		t.Fatalf("Send() returned error: %v", err)
+	}
+
+	if event != EventComputed {
+		t.Errorf("event header = %q, want %q", event, EventComputed)
+	}
+	if want := Sign([]byte("secret"), payload); signature != want {
+		t.Errorf("signature header = %q, want %q", signature, want)
+	}
+	var notification struct {
+		Event  string
+		Path   string
+		Report report.Report
+	}
+	if err := json.Unmarshal(payload, &notification); err != nil {
+		t.Fatalf("unable to unmarshal payload: %v", err)
+	}
+	if notification.Path != ".github/workflows/ci.yml" || len(notification.Report.Modules) != 1 || notification.Report.Modules[0].Name != "pin" {
+		t.Errorf("unexpected payload %s", payload)
+	}
+}
+
+func TestSendError(t *testing.T) {
+	received := 0
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+	}))
+	defer working.Close()
+
+	err := Send([]string{failing.URL, working.URL}, "", &Notification{Event: EventApplied})
+	if err == nil || !strings.Contains(err.Error(), "status code 500") {
+		t.Errorf("Send() error = %v, want status code 500", err)
+	}
+	if received != 1 {
+		t.Errorf("working webhook received %d notifications, want 1", received)
+	}
+}
+
+func TestGetWebhookURLs(t *testing.T) {
+	os.Setenv(WebhookURLsEnv, "https://siem.example.com/hook, ,https://tickets.example.com/hook")
+	defer os.Unsetenv(WebhookURLsEnv)
+
+	urls := GetWebhookURLs()
+	if len(urls) != 2 || urls[0] != "https://siem.example.com/hook" || urls[1] != "https://tickets.example.com/hook" {
+		t.Errorf("GetWebhookURLs() = %v", urls)
+	}
+}
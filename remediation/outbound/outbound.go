@@ -0,0 +1,343 @@
+// Package outbound sends the requests of the remediations to other services, e.g. the GitHub API, the container
+// registries, OSV and the webhooks of the notifications. The requests share a pool of connections, each attempt has a
+// timeout, failed idempotent requests are retried within a budget, and the requests to a host that keeps failing fail
+// at once for a while, so one slow service cannot stall the remediation of a whole request.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/step-security/secure-repo/remediation/logging"
+)
+
+const (
+	// TimeoutEnv is the timeout of each attempt of a request, including reading its body, e.g. 10s, which is
+	// DefaultTimeout if it is not set
+	TimeoutEnv = "OUTBOUND_TIMEOUT"
+	// MaxRetriesEnv is the number of times a failed idempotent request is retried, which is DefaultMaxRetries if it is
+	// not set, or 0 to not retry
+	MaxRetriesEnv = "OUTBOUND_MAX_RETRIES"
+
+	DefaultTimeout    = 30 * time.Second
+	DefaultMaxRetries = 2
+
+	// budgetRatio is the number of retries earned by each request, so the retries add at most a tenth to the requests
+	// to a service that is failing
+	budgetRatio = 0.1
+	// budgetMax is the number of retries that can be made at once after a period without failures
+	budgetMax = 10
+	// failureThreshold is the number of consecutive failures of a host that open its circuit
+	failureThreshold = 5
+	// openDuration is how long the requests to a host whose circuit is open fail at once, before one request is sent
+	// to probe it
+	openDuration = 30 * time.Second
+	// backoff is the wait before the first retry, which doubles for each retry
+	backoff = 100 * time.Millisecond
+	// maxDrainSize is the size of the largest body that is read before the response of a failed attempt is closed, so
+	// its connection can be reused
+	maxDrainSize = 64 << 10
+)
+
+// ErrCircuitOpen is returned for the requests to a host whose circuit is open
+var ErrCircuitOpen = errors.New("circuit is open after consecutive failures")
+
+// Observe is called with the host of a request and an event, which is retry, timeout, open when the circuit of the
+// host opens, or rejected when a request fails because it is open. It is set by the metrics package.
+var Observe func(host, event string)
+
+func observe(host, event string) {
+	if Observe != nil {
+		Observe(host, event)
+	}
+}
+
+// original is http.DefaultTransport when the program starts, which is replaced in tests, e.g. by httpmock
+var original = http.DefaultTransport
+
+// pooled is the transport of the attempts, which keeps more idle connections to each host than http.DefaultTransport,
+// since most requests are to a few hosts such as api.github.com
+var pooled = func() http.RoundTripper {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return http.DefaultTransport
+	}
+	transport = transport.Clone()
+	transport.MaxIdleConns = 256
+	transport.MaxIdleConnsPerHost = 64
+	return transport
+}()
+
+// Transport sends the requests with a timeout for each attempt, and retries the idempotent requests that failed with
+// an error or a 502, 503 or 504 response, with exponential backoff, as long as the retry budget shared by the
+// transports allows. The circuits of the hosts are shared by the transports too, so a host that keeps failing fails
+// the requests of all the clients.
+type Transport struct {
+	// base is the transport of the attempts, which is the pooled transport if it is nil, or http.DefaultTransport at
+	// the time of the request if it was replaced
+	base       http.RoundTripper
+	timeout    time.Duration
+	maxRetries int
+}
+
+var defaultTransport = sync.OnceValue(func() *Transport {
+	transport := &Transport{timeout: DefaultTimeout, maxRetries: DefaultMaxRetries}
+	if value := os.Getenv(TimeoutEnv); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			logging.Logger().Error("invalid outbound timeout, using the default", "env", TimeoutEnv, "value", value, "default", DefaultTimeout)
+		} else {
+			transport.timeout = timeout
+		}
+	}
+	if value := os.Getenv(MaxRetriesEnv); value != "" {
+		maxRetries, err := strconv.Atoi(value)
+		if err != nil || maxRetries < 0 {
+			logging.Logger().Error("invalid number of outbound retries, using the default", "env", MaxRetriesEnv, "value", value, "default", DefaultMaxRetries)
+		} else {
+			transport.maxRetries = maxRetries
+		}
+	}
+	return transport
+})
+
+// Default returns the transport configured by OUTBOUND_TIMEOUT and OUTBOUND_MAX_RETRIES
+func Default() *Transport {
+	return defaultTransport()
+}
+
+// NewTransport returns a transport like Default with another timeout, e.g. for downloading large archives
+func NewTransport(timeout time.Duration) *Transport {
+	transport := *Default()
+	transport.timeout = timeout
+	return &transport
+}
+
+// Client returns a client of the Default transport
+func Client() *http.Client {
+	return &http.Client{Transport: Default()}
+}
+
+// NewClient returns a client of a transport with the timeout
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: NewTransport(timeout)}
+}
+
+func (t *Transport) baseTransport() http.RoundTripper {
+	if t.base != nil {
+		return t.base
+	}
+	if http.DefaultTransport != original {
+		return http.DefaultTransport
+	}
+	return pooled
+}
+
+// RoundTrip sends the request, and returns the response of its last attempt. The response of an attempt that failed
+// with a 5xx status is returned like any other response when it is not retried.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	retryBudget.deposit()
+	for attempt := 0; ; attempt++ {
+		if !hostCircuits.allow(host, time.Now()) {
+			observe(host, "rejected")
+			return nil, fmt.Errorf("unable to send request to %s: %w", host, ErrCircuitOpen)
+		}
+		resp, err := t.attempt(req, attempt)
+		failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+		if req.Context().Err() != nil {
+			// the request was canceled by the caller, which says nothing about the host
+			hostCircuits.record(host, outcomeIgnored, time.Now())
+			return resp, err
+		}
+		outcome := outcomeSuccess
+		if failed {
+			outcome = outcomeFailure
+		}
+		if hostCircuits.record(host, outcome, time.Now()) {
+			observe(host, "open")
+			logging.Logger().Warn("circuit opened after consecutive failures", "host", host, "duration", openDuration)
+		}
+		if !failed || !retryable(req, resp) || attempt >= t.maxRetries || !retryBudget.withdraw() {
+			return resp, err
+		}
+		if resp != nil {
+			io.CopyN(io.Discard, resp.Body, maxDrainSize)
+			resp.Body.Close()
+		}
+		observe(host, "retry")
+		wait := backoff << attempt
+		wait += time.Duration(rand.Int63n(int64(wait)))
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends the request once, with a context that expires after the timeout and is canceled when the body of
+// the response is closed
+func (t *Transport) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	attemptReq := req.WithContext(ctx)
+	if attempt > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("unable to get body of request to %s: %v", req.URL.Host, err)
+		}
+		attemptReq.Body = body
+	}
+	resp, err := t.baseTransport().RoundTrip(attemptReq)
+	if err != nil {
+		cancel()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && req.Context().Err() == nil {
+			observe(req.URL.Host, "timeout")
+			return nil, fmt.Errorf("no response from %s in %v: %w", req.URL.Host, t.timeout, err)
+		}
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// retryable returns true if the request is idempotent and can be sent again, and the response of its failed attempt
+// is of a service that is unavailable rather than of an error of the request
+func retryable(req *http.Request, resp *http.Response) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if resp == nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// cancelBody cancels the context of the attempt when the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// budget is the number of retries that can be made, which grows by budgetRatio with each request up to budgetMax, so
+// the retries stop when most requests fail instead of multiplying the load of the failing services
+type budget struct {
+	sync.Mutex
+	tokens float64
+}
+
+var retryBudget = &budget{tokens: budgetMax}
+
+func (b *budget) deposit() {
+	b.Lock()
+	defer b.Unlock()
+	b.tokens = min(b.tokens+budgetRatio, budgetMax)
+}
+
+// withdraw returns true if a retry can be made
+func (b *budget) withdraw() bool {
+	b.Lock()
+	defer b.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnored
+)
+
+// circuit is the state of the requests to a host. It is open when the host failed failureThreshold times in a row,
+// until openUntil, and then half open while a single request probes the host.
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+type circuits struct {
+	sync.Mutex
+	hosts map[string]*circuit
+}
+
+var hostCircuits = &circuits{hosts: map[string]*circuit{}}
+
+// allow returns true if a request can be sent to the host, which is false while its circuit is open or while another
+// request probes it
+func (c *circuits) allow(host string, now time.Time) bool {
+	c.Lock()
+	defer c.Unlock()
+	hostCircuit := c.hosts[host]
+	if hostCircuit == nil || hostCircuit.failures < failureThreshold {
+		return true
+	}
+	if now.Before(hostCircuit.openUntil) || hostCircuit.probing {
+		return false
+	}
+	hostCircuit.probing = true
+	return true
+}
+
+// record records the outcome of a request to the host, and returns true if it opened the circuit of the host. A
+// success closes the circuit, and a failure of the probe opens it again.
+func (c *circuits) record(host string, result outcome, now time.Time) bool {
+	c.Lock()
+	defer c.Unlock()
+	hostCircuit := c.hosts[host]
+	switch {
+	case result == outcomeSuccess:
+		delete(c.hosts, host)
+		return false
+	case result == outcomeIgnored:
+		if hostCircuit != nil {
+			hostCircuit.probing = false
+		}
+		return false
+	case hostCircuit == nil:
+		hostCircuit = &circuit{}
+		c.hosts[host] = hostCircuit
+	}
+	probed := hostCircuit.probing
+	hostCircuit.probing = false
+	hostCircuit.failures++
+	if hostCircuit.failures < failureThreshold {
+		return false
+	}
+	opened := hostCircuit.failures == failureThreshold || probed
+	if opened {
+		hostCircuit.openUntil = now.Add(openDuration)
+	}
+	return opened
+}
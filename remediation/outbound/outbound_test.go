@@ -0,0 +1,156 @@
+package outbound
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestTransport returns a transport of the responses of the statuses, one for each attempt, and resets the state
+// shared by the transports
+func newTestTransport(t *testing.T, statuses ...int) (*Transport, *int) {
+	t.Helper()
+	retryBudget = &budget{tokens: budgetMax}
+	hostCircuits = &circuits{hosts: map[string]*circuit{}}
+	attempts := 0
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		status := statuses[min(attempts, len(statuses)-1)]
+		attempts++
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("body")), Request: req}, nil
+	})
+	return &Transport{base: base, timeout: time.Second, maxRetries: 2}, &attempts
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		statuses   []int
+		wantStatus int
+		want       int
+	}{
+		{name: "retried until success", method: http.MethodGet, statuses: []int{503, 502, 200}, wantStatus: 200, want: 3},
+		{name: "retried until max retries", method: http.MethodGet, statuses: []int{504}, wantStatus: 504, want: 3},
+		{name: "internal server error", method: http.MethodGet, statuses: []int{500, 200}, wantStatus: 500, want: 1},
+		{name: "not idempotent", method: http.MethodPost, statuses: []int{503, 200}, wantStatus: 503, want: 1},
+		{name: "client error", method: http.MethodGet, statuses: []int{404, 200}, wantStatus: 404, want: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transport, attempts := newTestTransport(t, test.statuses...)
+			req, _ := http.NewRequest(test.method, "https://registry.example.com/v2/", nil)
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("Error not expected: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != test.wantStatus || *attempts != test.want {
+				t.Errorf("RoundTrip() = %d after %d attempts, want %d after %d", resp.StatusCode, *attempts, test.wantStatus, test.want)
+			}
+		})
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	transport, attempts := newTestTransport(t, 503)
+	retryBudget.tokens = 1
+	req, _ := http.NewRequest(http.MethodGet, "https://registry.example.com/v2/", nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	resp.Body.Close()
+	// the request earns a tenth of a retry, so only one of the two retries is made
+	if *attempts != 2 {
+		t.Errorf("RoundTrip() made %d attempts, want 2", *attempts)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	transport, _ := newTestTransport(t, 200)
+	transport.maxRetries = 0
+	transport.timeout = 10 * time.Millisecond
+	transport.base = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	req, _ := http.NewRequest(http.MethodGet, "https://slow.example.com/", nil)
+	start := time.Now()
+	_, err := transport.RoundTrip(req)
+	if err == nil || !strings.Contains(err.Error(), "no response from slow.example.com") {
+		t.Errorf("RoundTrip() error = %v, want no response", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("RoundTrip() took %v, want the timeout", elapsed)
+	}
+}
+
+func TestCircuit(t *testing.T) {
+	transport, attempts := newTestTransport(t, 500)
+	for i := 0; i < failureThreshold; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "https://down.example.com/", nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Error not expected: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://down.example.com/", nil)
+	if _, err := transport.RoundTrip(req); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("RoundTrip() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if *attempts != failureThreshold {
+		t.Errorf("RoundTrip() made %d attempts, want %d", *attempts, failureThreshold)
+	}
+	// the other hosts are not affected
+	if !hostCircuits.allow("up.example.com", time.Now()) {
+		t.Errorf("allow() = false for another host")
+	}
+
+	// after openDuration a single request probes the host, and its success closes the circuit
+	later := time.Now().Add(openDuration)
+	if !hostCircuits.allow("down.example.com", later) {
+		t.Fatalf("allow() = false for the probe")
+	}
+	if hostCircuits.allow("down.example.com", later) {
+		t.Errorf("allow() = true while the host is probed")
+	}
+	if hostCircuits.record("down.example.com", outcomeFailure, later) != true {
+		t.Errorf("record() = false for the failure of the probe, want the circuit opened again")
+	}
+	if hostCircuits.allow("down.example.com", later) {
+		t.Errorf("allow() = true after the failure of the probe")
+	}
+	later = later.Add(openDuration)
+	hostCircuits.allow("down.example.com", later)
+	hostCircuits.record("down.example.com", outcomeSuccess, later)
+	if !hostCircuits.allow("down.example.com", later) {
+		t.Errorf("allow() = false after the success of the probe")
+	}
+}
+
+func TestDefaultTransportReplaced(t *testing.T) {
+	saved := http.DefaultTransport
+	defer func() { http.DefaultTransport = saved }()
+	http.DefaultTransport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusTeapot, Body: http.NoBody, Request: req}, nil
+	})
+	resp, err := Client().Get("https://api.github.com/")
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("Get() = %d, want the response of http.DefaultTransport", resp.StatusCode)
+	}
+}
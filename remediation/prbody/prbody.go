@@ -0,0 +1,228 @@
+// Package prbody generates the description of the pull requests and merge requests opened with the changes of the
+// remediations, from the results of securing the repository. The description has a section for each kind of fix, with
+// the files it changed and a link to its documentation, and is generated with a text/template, which tenants can replace.
+package prbody
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/step-security/secure-repo/remediation/apiv2"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/score"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+)
+
+const (
+	KindPullRequest  = "pull request"
+	KindMergeRequest = "merge request"
+
+	readmeURL = "https://github.com/step-security/secure-repo#"
+	// hardeningURL documents the fixes that do not have a section in the README
+	hardeningURL = "https://docs.github.com/en/actions/security-guides/security-hardening-for-github-actions"
+)
+
+// DefaultTemplate is the template of the description, unless the tenant has its own
+const DefaultTemplate = `This {{.Kind}} was created by StepSecurity to apply security best practices{{with .Repository}} to {{.}}{{end}}.
+{{range .Sections}}
+### {{if .Link}}[{{.Title}}]({{.Link}}){{else}}{{.Title}}{{end}}
+{{if .Changes}}
+{{.Changes}} lines changed{{if .NeedsReview}}, {{.NeedsReview}} of which need review{{end}}.
+{{end}}
+{{range .Files}}- ` + "`{{.}}`" + `
+{{end}}{{end}}
+{{- if .Findings}}
+{{.FixedFindings}} of {{.Findings}} findings are fixed.
+{{end}}
+{{- with .Score}}
+The security score of the workflows goes from {{.Before.Score}} to {{.After.Score}} out of 10.
+{{end}}
+The {{.Kind}} is updated when the repository is remediated again. Exemptions can be added in ` + "`.github/stepsecurity.yml`" + `.
+`
+
+// section is the title and the documentation of a kind of fix
+type section struct {
+	title string
+	link  string
+}
+
+// sections are the kinds of fixes, which are the remediations of workflows by module, and the other files by file type
+var sections = map[string]section{
+	"permissions":        {title: "Restrict GITHUB_TOKEN permissions", link: readmeURL + "1-automatically-set-minimum-github_token-permissions"},
+	"hardenrunner":       {title: "Add Harden-Runner to each job", link: readmeURL + "2-add-harden-runner-github-action-to-each-job"},
+	"pin":                {title: "Pin actions to a full length commit SHA", link: readmeURL + "3-pin-actions-to-a-full-length-commit-sha"},
+	"maintainedactions":  {title: "Replace actions with maintained actions", link: hardeningURL},
+	"unmaintained":       {title: "Replace unmaintained actions", link: hardeningURL},
+	"actionpolicy":       {title: "Replace actions not allowed by the policy", link: hardeningURL},
+	"advisories":         {title: "Update actions with known vulnerabilities", link: hardeningURL},
+	"typosquat":          {title: "Replace typosquatted actions", link: hardeningURL},
+	"runnerlabel":        {title: "Replace runner labels", link: hardeningURL},
+	"deprecatedrunner":   {title: "Replace deprecated runners", link: hardeningURL},
+	"githubtoken":        {title: "Remove unnecessary tokens", link: hardeningURL},
+	"forkguard":          {title: "Guard jobs from fork pull requests", link: hardeningURL},
+	"repoguard":          {title: "Guard jobs from forks of the repository", link: hardeningURL},
+	"dispatchinputs":     {title: "Pass workflow dispatch inputs through environment variables", link: hardeningURL},
+	"scriptinjection":    {title: "Pass untrusted input through environment variables", link: hardeningURL},
+	"githubenv":          {title: "Sanitize untrusted writes to GITHUB_ENV", link: hardeningURL},
+	"triggers":           {title: "Fix dangerous triggers", link: hardeningURL},
+	"shelldefaults":      {title: "Set default shell options", link: hardeningURL},
+	"deprecatedcommands": {title: "Replace deprecated workflow commands", link: hardeningURL},
+	"pintools":           {title: "Pin tools installed in run steps", link: hardeningURL},
+	"privileged":         {title: "Remove privileged containers", link: hardeningURL},
+	"buildargs":          {title: "Remove secrets from build arguments", link: hardeningURL},
+	"attestation":        {title: "Add build provenance", link: hardeningURL},
+	"signing":            {title: "Sign artifacts with cosign", link: hardeningURL},
+	"sbom":               {title: "Generate an SBOM", link: hardeningURL},
+
+	securerepo.FileTypeCompositeAction: {title: "Secure composite actions", link: readmeURL + "3-pin-actions-to-a-full-length-commit-sha"},
+	securerepo.FileTypeDockerfile:      {title: "Pin image tags to digests in Dockerfiles", link: readmeURL + "4-pin-image-tags-to-digests-in-dockerfiles"},
+	securerepo.FileTypeDependabot:      {title: "Add or update Dependabot configuration", link: readmeURL + "5-add-or-update-dependabot-configuration"},
+	securerepo.FileTypeRenovate:        {title: "Add or update Renovate configuration"},
+	securerepo.FileTypeCodeowners:      {title: "Add code owners of the workflows"},
+	securerepo.FileTypeGitLabCI:        {title: "Pin images of GitLab CI/CD jobs to digests"},
+	securerepo.FileTypeAzurePipelines:  {title: "Pin templates and container images of Azure Pipelines"},
+	securerepo.FileTypeCircleCI:        {title: "Pin orbs and images of CircleCI"},
+	securerepo.FileTypeBitbucket:       {title: "Pin pipes and images of Bitbucket Pipelines"},
+	securerepo.FileTypeJenkins:         {title: "Pin shared libraries and agent images of Jenkinsfiles"},
+	securerepo.FileTypeTekton:          {title: "Pin bundles and step images of Tekton resources"},
+	securerepo.FileTypeArgo:            {title: "Pin container images of Argo Workflows"},
+	securerepo.FileTypeDrone:           {title: "Pin plugin and service images of Drone pipelines"},
+	securerepo.FileTypePrecommit:       {title: "Pin pre-commit hooks to commits"},
+	securerepo.FileTypeBuildkite:       {title: "Pin plugins and images of Buildkite pipelines"},
+}
+
+// Section is a kind of fix, with the files it changed. Changes and NeedsReview count the lines changed in workflows.
+type Section struct {
+	Name        string
+	Title       string
+	Link        string
+	Files       []string
+	Changes     int
+	NeedsReview int
+}
+
+// Data is what the template of the description is executed with
+type Data struct {
+	// Kind is pull request or merge request
+	Kind       string
+	Repository string
+	// Files are the files changed or added, sorted by path
+	Files         []string
+	Sections      []Section
+	Findings      int
+	FixedFindings int
+	Score         *score.ScoreChange
+}
+
+// NewData returns the data of the description of the changed files of the results of securing the repository, with a
+// section for each module that changed workflows, in the order the modules ran, followed by one for each other file type
+func NewData(kind, repository string, response *apiv2.SecureRepoResponse) Data {
+	data := Data{Kind: kind, Repository: repository, Findings: response.Summary.Findings, FixedFindings: response.Summary.FixedFindings,
+		Score: response.Score}
+	indexes := map[string]int{}
+	getSection := func(name string) *Section {
+		if i, found := indexes[name]; found {
+			return &data.Sections[i]
+		}
+		s, found := sections[name]
+		if !found {
+			s = section{title: name}
+		}
+		indexes[name] = len(data.Sections)
+		data.Sections = append(data.Sections, Section{Name: name, Title: s.title, Link: s.link})
+		return &data.Sections[len(data.Sections)-1]
+	}
+
+	files := append([]apiv2.FileResult(nil), response.Files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	var otherFiles []apiv2.FileResult
+	for _, file := range files {
+		if !file.IsChanged {
+			continue
+		}
+		data.Files = append(data.Files, file.Path)
+		if file.FileType != securerepo.FileTypeWorkflow {
+			otherFiles = append(otherFiles, file)
+			continue
+		}
+		for _, module := range file.Modules {
+			if len(module.Changes) == 0 {
+				continue
+			}
+			s := getSection(module.Name)
+			s.Files = append(s.Files, file.Path)
+			s.Changes += len(module.Changes)
+			for _, change := range module.Changes {
+				if change.Confidence == report.ConfidenceNeedsReview {
+					s.NeedsReview++
+				}
+			}
+		}
+	}
+	for _, file := range otherFiles {
+		s := getSection(file.FileType)
+		s.Files = append(s.Files, file.Path)
+	}
+	return data
+}
+
+// Generate returns the description of the data with the template, or the default template if it is empty
+func Generate(text string, data Data) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		text = DefaultTemplate
+	}
+	tmpl, err := template.New("description").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse template: %v", err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("unable to execute template: %v", err)
+	}
+	return sb.String(), nil
+}
+
+// Describe returns the description of the changes of securing the repository with the default template
+func Describe(kind, repository string, response *securerepo.SecureRepoResponse) string {
+	// the default template is executed with the fields of the data, which cannot fail
+	description, _ := Generate(DefaultTemplate, NewData(kind, repository, apiv2.NewSecureRepoResponse(response)))
+	return description
+}
+
+// DescriptionRequest is the request of /pull-request-description, which is the response of /v2/secure-repo with the repository
+// and the kind of the request the description is for
+type DescriptionRequest struct {
+	Kind       string `json:",omitempty"`
+	Repository string `json:",omitempty"`
+	apiv2.SecureRepoResponse
+}
+
+// DescriptionResponse has the description generated for the request
+type DescriptionResponse struct {
+	Description string
+}
+
+// GenerateForTenant returns the description of the request with the template of the tenant, if the store has one
+func GenerateForTenant(store TemplateStore, tenant string, request DescriptionRequest) (*DescriptionResponse, error) {
+	text := ""
+	if store != nil && tenant != "" {
+		var err error
+		text, err = store.GetTemplate(tenant)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get template of tenant %s: %v", tenant, err)
+		}
+	}
+	kind := request.Kind
+	if kind == "" {
+		kind = KindPullRequest
+	}
+	description, err := Generate(text, NewData(kind, request.Repository, &request.SecureRepoResponse))
+	if err != nil {
+		return nil, err
+	}
+	return &DescriptionResponse{Description: description}, nil
+}
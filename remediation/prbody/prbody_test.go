@@ -0,0 +1,109 @@
+package prbody
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/apiv2"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/score"
+	"github.com/step-security/secure-repo/remediation/securerepo"
+	"github.com/step-security/secure-repo/remediation/storage"
+)
+
+func getResponse() *apiv2.SecureRepoResponse {
+	return &apiv2.SecureRepoResponse{
+		Files: []apiv2.FileResult{
+			{Path: ".github/workflows/release.yml", FileType: securerepo.FileTypeWorkflow, IsChanged: true, Modules: []report.Module{
+				{Name: "pin", Changes: []report.Change{{Line: 9, Kind: "modified", Confidence: report.ConfidenceSafe}}},
+			}},
+			{Path: "Dockerfile", FileType: securerepo.FileTypeDockerfile, IsChanged: true},
+			{Path: ".github/workflows/ci.yml", FileType: securerepo.FileTypeWorkflow, IsChanged: true, Modules: []report.Module{
+				{Name: "permissions", Changes: []report.Change{{Line: 2, Kind: "added", Confidence: report.ConfidenceSafe},
+					{Line: 3, Kind: "added", Confidence: report.ConfidenceNeedsReview}}},
+				{Name: "pin", Changes: []report.Change{{Line: 10, Kind: "modified", Confidence: report.ConfidenceSafe}}},
+				{Name: "hardenrunner", Skipped: []report.Skipped{{Item: "build", Reason: "exempted"}}},
+			}},
+			{Path: ".github/workflows/lint.yml", FileType: securerepo.FileTypeWorkflow},
+		},
+		Summary: apiv2.Summary{Findings: 4, FixedFindings: 3},
+		Score:   &score.ScoreChange{Before: score.Score{Score: 4.4}, After: score.Score{Score: 7.8}, Delta: 3.4},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	description, err := Generate("", NewData(KindPullRequest, "octo-org/app", getResponse()))
+	if err != nil {
+		t.Fatalf("Generate() unexpected error = %v", err)
+	}
+	want := "This pull request was created by StepSecurity to apply security best practices to octo-org/app.\n" +
+		"\n### [Restrict GITHUB_TOKEN permissions](https://github.com/step-security/secure-repo#1-automatically-set-minimum-github_token-permissions)\n" +
+		"\n2 lines changed, 1 of which need review.\n\n- `.github/workflows/ci.yml`\n" +
+		"\n### [Pin actions to a full length commit SHA](https://github.com/step-security/secure-repo#3-pin-actions-to-a-full-length-commit-sha)\n" +
+		"\n2 lines changed.\n\n- `.github/workflows/ci.yml`\n- `.github/workflows/release.yml`\n" +
+		"\n### [Pin image tags to digests in Dockerfiles](https://github.com/step-security/secure-repo#4-pin-image-tags-to-digests-in-dockerfiles)\n" +
+		"\n- `Dockerfile`\n" +
+		"\n3 of 4 findings are fixed.\n" +
+		"\nThe security score of the workflows goes from 4.4 to 7.8 out of 10.\n" +
+		"\nThe pull request is updated when the repository is remediated again. Exemptions can be added in `.github/stepsecurity.yml`.\n"
+	if description != want {
+		t.Errorf("Generate() =\n%s\nwant\n%s", description, want)
+	}
+
+	custom := "{{range .Sections}}{{.Name}}: {{len .Files}}\n{{end}}"
+	description, err = Generate(custom, NewData(KindMergeRequest, "", getResponse()))
+	if err != nil || description != "permissions: 1\npin: 2\ndockerfile: 1\n" {
+		t.Errorf("Generate() = %q, %v with a custom template", description, err)
+	}
+	if _, err := Generate("{{.Unknown}}", Data{}); err == nil {
+		t.Errorf("expected an error for a field that is not in the data")
+	}
+}
+
+type mockDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	templates map[string]string
+}
+
+func (m *mockDynamoDBClient) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	tenant := aws.StringValue(input.Key["Tenant"].S)
+	if tenant == "broken" {
+		return nil, fmt.Errorf("throttled")
+	}
+	template, found := m.templates[tenant]
+	if !found {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{"Tenant": {S: aws.String(tenant)}, "Template": {S: aws.String(template)}}}, nil
+}
+
+func TestGenerateForTenant(t *testing.T) {
+	store := &DynamoDBTemplateStore{TableName: "templates", Svc: &mockDynamoDBClient{templates: map[string]string{"acme": "Changed {{len .Files}} files of {{.Repository}}"}}}
+	request := DescriptionRequest{Repository: "octo-org/app", SecureRepoResponse: *getResponse()}
+
+	response, err := GenerateForTenant(store, "acme", request)
+	if err != nil || response.Description != "Changed 3 files of octo-org/app" {
+		t.Errorf("GenerateForTenant() = %+v, %v, want the template of the tenant", response, err)
+	}
+	response, err = GenerateForTenant(store, "globex", request)
+	if err != nil || !strings.HasPrefix(response.Description, "This pull request was created by StepSecurity") {
+		t.Errorf("GenerateForTenant() = %+v, %v, want the default template", response, err)
+	}
+	if _, err := GenerateForTenant(store, "broken", request); err == nil {
+		t.Errorf("expected an error when the template cannot be looked up")
+	}
+}
+
+func TestStorageTemplateStore(t *testing.T) {
+	store := &StorageTemplateStore{Store: &storage.FileStore{Dir: t.TempDir()}}
+	store.Store.Put("pull-request-templates/acme", []byte("Secured {{.Repository}}"))
+	for tenant, want := range map[string]string{"acme": "Secured {{.Repository}}", "globex": ""} {
+		if text, err := store.GetTemplate(tenant); text != want || err != nil {
+			t.Errorf("GetTemplate(%s) = %q, %v, want %q", tenant, text, err, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package prbody
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/storage"
+)
+
+// TemplatesTableEnv is the DynamoDB table of the templates of the tenants
+const TemplatesTableEnv = "PR_TEMPLATES_TABLE"
+
+// TemplateStore looks up the template of the description of a tenant. It returns an empty template if the tenant does
+// not have one, in which case the default template is used.
+type TemplateStore interface {
+	GetTemplate(tenant string) (string, error)
+}
+
+// NewTemplateStoreFromEnv returns the store of the table in PR_TEMPLATES_TABLE, or of the storage of STORAGE_URL if the
+// table is not set, or nil if neither is set
+func NewTemplateStoreFromEnv(svc dynamodbiface.DynamoDBAPI) (TemplateStore, error) {
+	if tableName := os.Getenv(TemplatesTableEnv); tableName != "" {
+		return &DynamoDBTemplateStore{TableName: tableName, Svc: svc}, nil
+	}
+	store, err := storage.Default()
+	if err != nil || store == nil {
+		return nil, err
+	}
+	return &StorageTemplateStore{Store: store}, nil
+}
+
+// DynamoDBTemplateStore looks up the templates in a DynamoDB table with Tenant as the hash key, and the template in
+// the Template attribute
+type DynamoDBTemplateStore struct {
+	TableName string
+	Svc       dynamodbiface.DynamoDBAPI
+}
+
+func (s *DynamoDBTemplateStore) GetTemplate(tenant string) (string, error) {
+	result, err := s.Svc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.TableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Tenant": {S: aws.String(tenant)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.Item == nil || result.Item["Template"] == nil {
+		return "", nil
+	}
+	return aws.StringValue(result.Item["Template"].S), nil
+}
+
+// StorageTemplateStore looks up the templates in a storage.Store, where the template of a tenant is at
+// pull-request-templates/<tenant>
+type StorageTemplateStore struct {
+	Store storage.Store
+}
+
+func (s *StorageTemplateStore) GetTemplate(tenant string) (string, error) {
+	value, err := s.Store.Get("pull-request-templates/" + tenant)
+	return string(value), err
+}
@@ -0,0 +1,118 @@
+package precommit
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigPath is the path of the configuration of pre-commit
+const ConfigPath = ".pre-commit-config.yaml"
+
+const RuleUnpinnedRev = "precommit-unpinned-rev"
+
+var (
+	shaRegex        = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	githubRepoRegex = regexp.MustCompile(`^https://github\.com/([\w.-]+)/([\w.-]+?)(?:\.git)?/?$`)
+)
+
+// ResolveRef returns the commit of a tag or branch of a GitHub repository, which is resolved and cached like the refs
+// of actions
+var ResolveRef = pin.ResolveRef
+
+// SecurePrecommitConfigResponse is the result of the remediations of a pre-commit configuration
+type SecurePrecommitConfigResponse struct {
+	OriginalInput string
+	FinalOutput   string
+	IsChanged     bool
+	Findings      []findings.Finding
+}
+
+// pinRev replaces the rev on its line with the SHA of its commit. The version is kept in a frozen comment, which
+// pre-commit autoupdate --freeze writes and reads, unless the rev is followed by other keys of a flow mapping.
+func pinRev(line string, rev *yaml.Node, sha, version string) string {
+	start := textedit.ColumnOffset(line, rev.Column)
+	end := start + len(rev.Value)
+	if rev.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0 {
+		end += 2
+	}
+	if end > len(line) {
+		return line
+	}
+	rest := line[end:]
+	if trimmed := strings.TrimSpace(rest); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		rest = "  # frozen: " + version
+	}
+	return line[:start] + sha + rest
+}
+
+// SecurePrecommitConfig pins the revs of the repositories of hooks to the SHA of their commit unless pinRevs is false,
+// since the tags of hook repositories can be moved to run other code on every commit. Repositories that are not on
+// GitHub are reported as findings.
+func SecurePrecommitConfig(ctx context.Context, queryStringParams map[string]string, inputYaml string) (*SecurePrecommitConfigResponse, error) {
+	response := &SecurePrecommitConfigResponse{OriginalInput: inputYaml, FinalOutput: inputYaml}
+	if queryStringParams["pinRevs"] == "false" {
+		return response, nil
+	}
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(inputYaml), &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(doc.Content) == 0 {
+		return response, nil
+	}
+	repos := document.MappingValue(doc.Content[0], "repos")
+	if repos == nil || repos.Kind != yaml.SequenceNode {
+		return response, nil
+	}
+
+	type pinnedRev struct {
+		rev      *yaml.Node
+		resolved *pin.ResolvedRef
+	}
+	var pinnedRevs []pinnedRev
+	for _, repo := range repos.Content {
+		repoNode, rev := document.MappingValue(repo, "repo"), document.MappingValue(repo, "rev")
+		if repoNode == nil || rev == nil || rev.Kind != yaml.ScalarNode || shaRegex.MatchString(rev.Value) {
+			continue
+		}
+		match := githubRepoRegex.FindStringSubmatch(repoNode.Value)
+		if match == nil {
+			response.Findings = append(response.Findings, findings.Finding{
+				RuleID:     RuleUnpinnedRev,
+				Message:    fmt.Sprintf("Hooks of %s are run from %s, which can be moved to another commit", repoNode.Value, rev.Value),
+				Action:     repoNode.Value,
+				Line:       rev.Line,
+				Column:     rev.Column,
+				Suggestion: fmt.Sprintf("Set rev to the SHA of the commit of %s, with the tag in a frozen comment", rev.Value),
+			})
+			continue
+		}
+		resolved, err := ResolveRef(ctx, match[1], match[2], rev.Value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get commit of %s@%s: %v", repoNode.Value, rev.Value, err)
+		}
+		pinnedRevs = append(pinnedRevs, pinnedRev{rev: rev, resolved: resolved})
+	}
+
+	// pinned from the end, so the columns of the other revs on a line of a flow sequence do not move
+	lines := strings.Split(inputYaml, "\n")
+	for i := len(pinnedRevs) - 1; i >= 0; i-- {
+		rev, resolved := pinnedRevs[i].rev, pinnedRevs[i].resolved
+		lines[rev.Line-1] = pinRev(lines[rev.Line-1], rev, resolved.CommitSHA, resolved.Version)
+	}
+
+	output := strings.Join(lines, "\n")
+	if output != inputYaml {
+		response.FinalOutput = output
+		response.IsChanged = true
+	}
+	return response, nil
+}
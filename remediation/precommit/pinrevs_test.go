@@ -0,0 +1,113 @@
+package precommit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+)
+
+func TestSecurePrecommitConfig(t *testing.T) {
+	const sha = "2c9f875913ee60ca25ce70243dc24d5b6415598c"
+	saveResolveRef := ResolveRef
+	var resolved []string
+	ResolveRef = func(_ context.Context, owner, repo, tagOrBranch string) (*pin.ResolvedRef, error) {
+		resolved = append(resolved, owner+"/"+repo+"@"+tagOrBranch)
+		version := tagOrBranch
+		if tagOrBranch == "v4" {
+			version = "v4.6.0"
+		}
+		return &pin.ResolvedRef{CommitSHA: sha, Version: version}, nil
+	}
+	defer func() { ResolveRef = saveResolveRef }()
+
+	input := `repos:
+  - repo: https://github.com/pre-commit/pre-commit-hooks
+    rev: v4  # keep up to date
+    hooks:
+      - id: trailing-whitespace
+  - repo: https://github.com/psf/black.git
+    rev: '24.4.2'
+    hooks:
+      - id: black
+  - {repo: https://github.com/pycqa/flake8, rev: main, hooks: [{id: flake8}]}
+  - repo: https://gitlab.com/acme/hooks
+    rev: v1.0.0
+    hooks:
+      - id: lint
+  - repo: https://github.com/gitleaks/gitleaks
+    rev: 2c9f875913ee60ca25ce70243dc24d5b6415598c  # frozen: v8.18.4
+    hooks:
+      - id: gitleaks
+  - repo: local
+    hooks:
+      - id: test
+        name: test
+        entry: make test
+        language: system
+`
+	want := `repos:
+  - repo: https://github.com/pre-commit/pre-commit-hooks
+    rev: ` + sha + `  # frozen: v4.6.0
+    hooks:
+      - id: trailing-whitespace
+  - repo: https://github.com/psf/black.git
+    rev: ` + sha + `  # frozen: 24.4.2
+    hooks:
+      - id: black
+  - {repo: https://github.com/pycqa/flake8, rev: ` + sha + `, hooks: [{id: flake8}]}
+  - repo: https://gitlab.com/acme/hooks
+    rev: v1.0.0
+    hooks:
+      - id: lint
+  - repo: https://github.com/gitleaks/gitleaks
+    rev: 2c9f875913ee60ca25ce70243dc24d5b6415598c  # frozen: v8.18.4
+    hooks:
+      - id: gitleaks
+  - repo: local
+    hooks:
+      - id: test
+        name: test
+        entry: make test
+        language: system
+`
+	response, err := SecurePrecommitConfig(context.Background(), map[string]string{}, input)
+	if err != nil {
+		t.Fatalf("SecurePrecommitConfig() returned error: %v", err)
+	}
+	if !response.IsChanged || response.FinalOutput != want {
+		t.Errorf("SecurePrecommitConfig() =\n%s\nwant\n%s", response.FinalOutput, want)
+	}
+	if fmt.Sprint(resolved) != "[pre-commit/pre-commit-hooks@v4 psf/black@24.4.2 pycqa/flake8@main]" {
+		t.Errorf("resolved refs = %v", resolved)
+	}
+	if len(response.Findings) != 1 || response.Findings[0].RuleID != RuleUnpinnedRev || response.Findings[0].Line != 12 {
+		t.Errorf("findings = %+v, want the repository on GitLab", response.Findings)
+	}
+
+	response, err = SecurePrecommitConfig(context.Background(), map[string]string{"pinRevs": "false"}, input)
+	if err != nil || response.IsChanged {
+		t.Errorf("expected revs not to be pinned with pinRevs=false, got %+v, %v", response, err)
+	}
+}
+
+func TestSecurePrecommitConfigMultibyte(t *testing.T) {
+	const sha = "2c9f875913ee60ca25ce70243dc24d5b6415598c"
+	saveResolveRef := ResolveRef
+	ResolveRef = func(_ context.Context, owner, repo, tagOrBranch string) (*pin.ResolvedRef, error) {
+		return &pin.ResolvedRef{CommitSHA: sha, Version: tagOrBranch}, nil
+	}
+	defer func() { ResolveRef = saveResolveRef }()
+
+	// the columns of the parser are in characters, so the rev is after more bytes than its column
+	input := "repos:\n  - {repo: https://github.com/pycqa/flake8, hooks: [{id: flake8, name: «flake8 ✓»}], rev: main}\n"
+	want := "repos:\n  - {repo: https://github.com/pycqa/flake8, hooks: [{id: flake8, name: «flake8 ✓»}], rev: " + sha + "}\n"
+	response, err := SecurePrecommitConfig(context.Background(), map[string]string{}, input)
+	if err != nil {
+		t.Fatalf("SecurePrecommitConfig() returned error: %v", err)
+	}
+	if response.FinalOutput != want {
+		t.Errorf("SecurePrecommitConfig() =\n%s\nwant\n%s", response.FinalOutput, want)
+	}
+}
@@ -116,7 +116,7 @@ func GetHooks(precommitConfig string) ([]Repo, error) {
 	for _, repo := range newHooks {
 		repos = append(repos, repo)
 	}
-	sort.Slice(repos, func(i, j int) bool {
+	sort.SliceStable(repos, func(i, j int) bool {
 		return repos[i].Repo < repos[j].Repo
 	})
 	return repos, nil
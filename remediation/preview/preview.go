@@ -0,0 +1,167 @@
+// Package preview runs the remediations of workflows that need neither the network nor the state of the handler, so it
+// compiles to WebAssembly, and the dashboard can preview the fixes of unsaved workflows without a request to the API.
+// The remediations are the built-in remediations of remediators.Builtin, enabled by the query parameters of
+// /secure-workflow, and the response has the same fields. Actions are not pinned and harden-runner is added with its
+// tag, since the commits of tags are looked up with the GitHub API, and the checks that look up the actions, e.g. their
+// advisories, do not run.
+package preview
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+	"github.com/step-security/secure-repo/remediation/workflow/remediators"
+)
+
+// run finds the findings of the remediation in the output of the response, fixes them if it is enabled to, and returns
+// whether they were fixed. The output is changed even if fixing them fails, if the fix returns one.
+func run(r remediators.Remediation, response *permissions.SecureWorkflowReponse) ([]findings.Finding, bool, error) {
+	detected, err := r.Remediator.Detect(response.FinalOutput)
+	if err != nil {
+		return nil, false, err
+	}
+	if !r.Fix {
+		return detected, false, nil
+	}
+	output, fixed, err := r.Remediator.Apply(response.FinalOutput, detected)
+	if output != "" {
+		response.FinalOutput = output
+	}
+	return detected, fixed, err
+}
+
+// getRemediations returns the built-in remediations enabled by the query parameters, in the order SecureWorkflow runs
+// them, without the ones that need the network. The permissions are added by permissionsRemediator, which sets the fields
+// of the response.
+func getRemediations(params map[string]string, response *permissions.SecureWorkflowReponse) []remediators.Remediation {
+	before, after := remediators.Builtin(remediators.Options{Params: params, Ctx: context.Background(),
+		Permissions: &permissionsRemediator{addEmptyTopLevelPermissions: params["addEmptyTopLevelPermissions"] == "true",
+			addProjectComment: params["addProjectComment"] != "false", response: response}})
+	return append(before, after...)
+}
+
+// requestError is an error of adding the permissions, which fails the preview
+type requestError struct {
+	err error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+// permissionsRemediator adds the permissions of the jobs, and the permissions of the workflow if all jobs have their
+// permissions or the errors of the jobs are that they already have permissions, as the permissions module of
+// SecureWorkflow does. The errors of the jobs are set in the response.
+type permissionsRemediator struct {
+	addEmptyTopLevelPermissions bool
+	addProjectComment           bool
+	response                    *permissions.SecureWorkflowReponse
+}
+
+func (r *permissionsRemediator) Name() string {
+	return "permissions"
+}
+
+func (r *permissionsRemediator) Detect(inputYaml string) ([]findings.Finding, error) {
+	return nil, nil
+}
+
+func (r *permissionsRemediator) Apply(inputYaml string, detected []findings.Finding) (string, bool, error) {
+	permissionsResponse, err := permissions.AddJobLevelPermissions(inputYaml, r.addEmptyTopLevelPermissions)
+	if err != nil {
+		return "", false, &requestError{err: err}
+	}
+	r.response.AlreadyHasPermissions = permissionsResponse.AlreadyHasPermissions
+	r.response.IncorrectYaml = permissionsResponse.IncorrectYaml
+	r.response.JobErrors = permissionsResponse.JobErrors
+	r.response.MissingActions = permissionsResponse.MissingActions
+	if permissionsResponse.HasErrors && !permissions.ShouldAddWorkflowLevelPermissions(permissionsResponse.JobErrors) {
+		r.response.HasErrors = true
+		return permissionsResponse.FinalOutput, false, nil
+	}
+	output, err := permissions.AddWorkflowLevelPermissions(permissionsResponse.FinalOutput, r.addProjectComment, r.addEmptyTopLevelPermissions)
+	if err != nil {
+		return permissionsResponse.FinalOutput, false, err
+	}
+	return output, true, nil
+}
+
+// Report adds the jobs whose permissions were not added to the report
+func (r *permissionsRemediator) Report(workflowReport *report.Report, path string, detected []findings.Finding) {
+	for _, jobError := range r.response.JobErrors {
+		workflowReport.AddSkipped(r.Name(), report.Skipped{File: path, Item: jobError.JobName, Reason: strings.Join(jobError.Errors, ", ")})
+	}
+}
+
+// Preview runs the remediations enabled by the query parameters on the workflow, and returns the remediated workflow with
+// the findings and the report of the changes. The errors of the modules are in the report, and only the errors of adding
+// the permissions fail the preview, as they fail SecureWorkflow.
+func Preview(queryStringParams map[string]string, inputYaml string) (*permissions.SecureWorkflowReponse, error) {
+	response := &permissions.SecureWorkflowReponse{FinalOutput: inputYaml, OriginalInput: inputYaml}
+	workflowReport, workflowPath := &report.Report{}, queryStringParams["path"]
+
+	// the pin module looks up the actions, so the unpinned actions are not checked
+	analyzers := remediators.Analyzers(nil)
+	analyzerFindings := make([][]findings.Finding, len(analyzers))
+	for i, analyzer := range analyzers {
+		if queryStringParams[analyzer.Param] != "true" {
+			continue
+		}
+		var err error
+		if analyzerFindings[i], err = analyzer.Find(inputYaml); err != nil {
+			response.HasErrors = true
+			workflowReport.AddError(analyzer.Name, err)
+		}
+	}
+
+	// the remediations run one after the other on the output of the previous one, and the changes of each are recorded
+	var remediationFindings []findings.Finding
+	changed := map[string]bool{}
+	for _, r := range getRemediations(queryStringParams, response) {
+		name, input := r.Remediator.Name(), response.FinalOutput
+		detected, fixed, err := run(r, response)
+		var failure *requestError
+		if errors.As(err, &failure) {
+			return nil, failure.err
+		}
+		if err != nil {
+			response.HasErrors = true
+			workflowReport.AddError(name, err)
+		}
+		remediationFindings = append(remediationFindings, detected...)
+		changed[name] = fixed
+		r.Remediator.Report(workflowReport, workflowPath, detected)
+		workflowReport.AddChanges(name, workflowPath, input, response.FinalOutput, remediators.Confidence(r.Remediator))
+	}
+
+	response.AddedPermissions = changed["permissions"]
+	response.AddedHardenRunner = changed["hardenrunner"]
+	response.RemovedUnnecessaryTokens = changed["githubtoken"]
+	response.AddedForkPullRequestGuards = changed["forkguard"]
+	response.AddedShellDefaults = changed["shelldefaults"]
+	response.AddedRepositoryGuards = changed["repoguard"]
+	response.AddedBuildProvenance = changed["attestation"]
+	response.AddedCosignSigning = changed["signing"]
+	response.AddedSBOM = changed["sbom"]
+	response.FixedTyposquattedActions = changed["typosquat"]
+	response.FixedDispatchInputs = changed["dispatchinputs"]
+	response.RewroteDeprecatedCommands = changed["deprecatedcommands"]
+	response.FixedSecretBuildArgs = changed["buildargs"]
+	response.SanitizedUntrustedEnvWrites = changed["githubenv"]
+	var allFindings []findings.Finding
+	for i, analyzer := range analyzers {
+		if analyzer.MarkFixed && len(analyzerFindings[i]) > 0 {
+			remaining, _ := analyzer.Find(response.FinalOutput)
+			findings.MarkFixed(analyzerFindings[i], remaining)
+		}
+		allFindings = append(allFindings, analyzerFindings[i]...)
+	}
+	response.Findings = append(allFindings, remediationFindings...)
+	response.IsChanged = response.FinalOutput != inputYaml
+	response.Report = workflowReport
+	return response, nil
+}
@@ -0,0 +1,85 @@
+package preview
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+const workflow = `name: CI
+on: pull_request_target
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+      - run: echo "${{ github.event.pull_request.title }}"
+`
+
+func TestPreview(t *testing.T) {
+	response, err := Preview(map[string]string{"checkScriptInjection": "true", "checkMissingPermissions": "true"}, workflow)
+	if err != nil {
+		t.Fatalf("Preview() unexpected error = %v", err)
+	}
+	if !response.IsChanged || !response.AddedPermissions || !response.AddedHardenRunner || response.PinnedActions {
+		t.Errorf("Preview() = %+v, want permissions and harden-runner added, and actions not pinned", response)
+	}
+	for _, want := range []string{"permissions:  # added using https://github.com/step-security/secure-repo\n  contents: read\n",
+		"uses: step-security/harden-runner@v2\n", "uses: actions/checkout@v3\n"} {
+		if !strings.Contains(response.FinalOutput, want) {
+			t.Errorf("Preview() output does not contain %q:\n%s", want, response.FinalOutput)
+		}
+	}
+	if len(response.Findings) != 3 || !response.Findings[0].Fixed || !response.Findings[1].Fixed || response.Findings[2].RuleID != "script-injection" ||
+		response.Findings[2].Fixed {
+		t.Errorf("Preview() findings = %+v, want the missing permissions fixed and the script injection not fixed", response.Findings)
+	}
+	if len(response.Report.Modules) != 2 || response.Report.Modules[0].Name != "permissions" || response.Report.Modules[1].Name != "hardenrunner" {
+		t.Errorf("Preview() report = %+v, want the changes of permissions and hardenrunner", response.Report)
+	}
+
+	// the remediations are disabled with the query parameters of /secure-workflow
+	response, err = Preview(map[string]string{"addPermissions": "false", "addHardenRunner": "false"}, workflow)
+	if err != nil || response.IsChanged || response.FinalOutput != workflow {
+		t.Errorf("Preview() = %+v, %v, want the workflow unchanged", response, err)
+	}
+
+	// the checks that look up the actions do not run, and the others of SecureWorkflow do
+	typosquatted := strings.Replace(workflow, "actions/checkout@v3", "actions/chekout@v3", 1)
+	response, err = Preview(map[string]string{"addPermissions": "false", "addHardenRunner": "false", "checkVulnerableActions": "true",
+		"checkUnmaintainedActions": "true", "checkTyposquattedActions": "true"}, typosquatted)
+	if err != nil || len(response.Findings) != 1 || response.Findings[0].RuleID != "typosquatted-action" {
+		t.Errorf("Preview() = %+v, %v, want the typosquatted action only", response, err)
+	}
+
+	response, err = Preview(map[string]string{}, "jobs: [build")
+	if err != nil || !response.IncorrectYaml || !response.HasErrors {
+		t.Errorf("Preview() = %+v, %v, want the YAML reported as incorrect", response, err)
+	}
+}
+
+// TestDependencies checks that the WebAssembly build of the preview depends neither on the packages of the handler nor on
+// the packages that look up actions and images with the network
+func TestDependencies(t *testing.T) {
+	goBinary, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+	cmd := exec.Command(goBinary, "list", "-deps", "../../cmd/secure-repo-wasm")
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("unable to list dependencies: %v", err)
+	}
+	excluded := map[string]bool{"net/http": true, "github.com/google/go-github/v40/github": true}
+	for _, name := range []string{"auth", "cache", "githubapp", "jobs", "storage", "metrics", "outbound", "httpcache", "workflow/pin",
+		"workflow/actionrepo", "workflow/maintainedactions", "workflow/unmaintained", "workflow/advisories"} {
+		excluded["github.com/step-security/secure-repo/remediation/"+name] = true
+	}
+	for _, dependency := range strings.Fields(string(output)) {
+		if strings.HasPrefix(dependency, "github.com/aws/") || excluded[dependency] {
+			t.Errorf("preview depends on %s", dependency)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Package profiling writes the profiles of the runtime of an instance, in the format of net/http/pprof, so the memory
+// and the goroutines of the remediations of a running instance can be looked at with go tool pprof.
+package profiling
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+)
+
+// EnabledEnv is the environment variable that enables the /debug/pprof route when it is true
+const EnabledEnv = "PPROF_ENABLED"
+
+// Enabled returns true if the profiles can be fetched
+func Enabled() bool {
+	return os.Getenv(EnabledEnv) == "true"
+}
+
+// Profiles returns the names of the profiles, e.g. heap, allocs and goroutine
+func Profiles() []string {
+	var names []string
+	for _, profile := range pprof.Profiles() {
+		names = append(names, profile.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Write writes the profile of the name to w. With debug 0, the profile is in the gzipped protobuf format of go tool
+// pprof, and with a debug above 0 it is text. The heap is garbage collected first, so the heap profile is up to date,
+// as with the gc parameter of net/http/pprof. A CPU profile is not served, since the function handles one request at
+// a time and would only profile the request for the profile; the benchmarks are profiled with -cpuprofile instead.
+func Write(w io.Writer, name string, debug int) error {
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		return fmt.Errorf("unknown profile %s", name)
+	}
+	if name == "heap" {
+		runtime.GC()
+	}
+	return profile.WriteTo(w, debug)
+}
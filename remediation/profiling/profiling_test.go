@@ -0,0 +1,49 @@
+package profiling
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func TestEnabled(t *testing.T) {
+	t.Setenv(EnabledEnv, "")
+	if Enabled() {
+		t.Errorf("Enabled() = true, want false by default")
+	}
+	t.Setenv(EnabledEnv, "true")
+	if !Enabled() {
+		t.Errorf("Enabled() = false, want true")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	names := strings.Join(Profiles(), ",")
+	for _, name := range []string{"allocs", "goroutine", "heap"} {
+		if !strings.Contains(names, name) {
+			t.Errorf("Profiles() = %s, want %s", names, name)
+		}
+	}
+
+	// the profiles for go tool pprof are gzipped
+	var profile bytes.Buffer
+	if err := Write(&profile, "heap", 0); err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if _, err := gzip.NewReader(&profile); err != nil {
+		t.Errorf("heap profile is not gzipped: %v", err)
+	}
+
+	var text bytes.Buffer
+	if err := Write(&text, "goroutine", 1); err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if !strings.HasPrefix(text.String(), "goroutine profile:") {
+		t.Errorf("goroutine profile = %q, want text", text.String())
+	}
+
+	if err := Write(&text, "profile", 0); err == nil {
+		t.Errorf("Error expected for the CPU profile")
+	}
+}
@@ -0,0 +1,207 @@
+package renovate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ConfigPath is the path of the Renovate configuration that is added to a repository without one
+const ConfigPath = "renovate.json"
+
+// ConfigPaths are the paths Renovate reads its configuration from, in the order it looks for them
+var ConfigPaths = []string{"renovate.json", "renovate.json5", ".github/renovate.json", ".github/renovate.json5",
+	".gitlab/renovate.json", ".gitlab/renovate.json5", ".renovaterc", ".renovaterc.json", ".renovaterc.json5"}
+
+const (
+	ManagerGitHubActions = "github-actions"
+	ManagerDockerfile    = "dockerfile"
+)
+
+// manager is how the dependencies of a manager of Renovate are kept up to date: the preset that pins them to digests,
+// and the name of the group their updates are proposed in
+type manager struct {
+	preset    string
+	groupName string
+}
+
+var managers = map[string]manager{
+	ManagerGitHubActions: {preset: "helpers:pinGitHubActionDigests", groupName: "GitHub Actions"},
+	ManagerDockerfile:    {preset: "docker:pinDigests", groupName: "Docker images"},
+}
+
+type UpdateRenovateConfigResponse struct {
+	OriginalInput string
+	FinalOutput   string
+	IsChanged     bool
+}
+
+// IsConfigPath returns true if Renovate reads its configuration from the path
+func IsConfigPath(filePath string) bool {
+	for _, configPath := range ConfigPaths {
+		if filePath == configPath {
+			return true
+		}
+	}
+	return false
+}
+
+// field is a key of the configuration with its value, which is kept as is so the other keys are not reformatted
+type field struct {
+	key   string
+	value json.RawMessage
+}
+
+// parseConfig returns the keys of the configuration in the order they are in the file
+func parseConfig(content string) ([]field, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("not a JSON object")
+	}
+	var fields []field
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field{key: token.(string), value: value})
+	}
+	return fields, nil
+}
+
+func writeConfig(fields []field) (string, error) {
+	var out bytes.Buffer
+	out.WriteString("{\n")
+	for i, f := range fields {
+		key, _ := json.Marshal(f.key)
+		var value bytes.Buffer
+		if err := json.Indent(&value, f.value, "  ", "  "); err != nil {
+			return "", err
+		}
+		out.WriteString("  " + string(key) + ": " + value.String())
+		if i < len(fields)-1 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("}\n")
+	return out.String(), nil
+}
+
+type packageRule struct {
+	MatchManagers []string `json:"matchManagers"`
+	GroupName     string   `json:"groupName"`
+}
+
+// UpdateRenovateConfig adds the presets that pin the dependencies of the managers to digests, and package rules that
+// group their updates in one pull request per manager, to the configuration. The configuration is created with the
+// recommended presets if content is empty. Presets and rules already in the configuration are kept, and a manager that
+// already has a package rule is not grouped again. Configurations that are not JSON, e.g. JSON5 with comments, are
+// returned unchanged with an error.
+func UpdateRenovateConfig(content string, managerNames []string) (*UpdateRenovateConfigResponse, error) {
+	response := &UpdateRenovateConfigResponse{OriginalInput: content, FinalOutput: content}
+	if len(managerNames) == 0 {
+		return response, nil
+	}
+
+	fields := []field{
+		{key: "$schema", value: json.RawMessage(`"https://docs.renovatebot.com/renovate-schema.json"`)},
+		{key: "extends", value: json.RawMessage(`["config:recommended"]`)},
+	}
+	if strings.TrimSpace(content) != "" {
+		var err error
+		fields, err = parseConfig(content)
+		if err != nil {
+			return response, fmt.Errorf("unable to parse renovate config: %v", err)
+		}
+	}
+	getValue := func(key string) json.RawMessage {
+		for _, f := range fields {
+			if f.key == key {
+				return f.value
+			}
+		}
+		return nil
+	}
+	setValue := func(key string, value interface{}) {
+		raw, _ := json.Marshal(value)
+		for i := range fields {
+			if fields[i].key == key {
+				fields[i].value = raw
+				return
+			}
+		}
+		fields = append(fields, field{key: key, value: raw})
+	}
+
+	var extends []string
+	if value := getValue("extends"); value != nil {
+		if err := json.Unmarshal(value, &extends); err != nil {
+			return response, fmt.Errorf("unable to parse extends of renovate config: %v", err)
+		}
+	}
+	var rules []json.RawMessage
+	if value := getValue("packageRules"); value != nil {
+		if err := json.Unmarshal(value, &rules); err != nil {
+			return response, fmt.Errorf("unable to parse packageRules of renovate config: %v", err)
+		}
+	}
+	groupedManagers := map[string]bool{}
+	for _, rule := range rules {
+		var existing packageRule
+		if json.Unmarshal(rule, &existing) == nil && existing.GroupName != "" {
+			for _, name := range existing.MatchManagers {
+				groupedManagers[name] = true
+			}
+		}
+	}
+
+	extendsChanged, rulesChanged := false, false
+	for _, name := range managerNames {
+		m, found := managers[name]
+		if !found {
+			continue
+		}
+		if !contains(extends, m.preset) {
+			extends = append(extends, m.preset)
+			extendsChanged = true
+		}
+		if !groupedManagers[name] {
+			groupedManagers[name] = true
+			rule, _ := json.Marshal(packageRule{MatchManagers: []string{name}, GroupName: m.groupName})
+			rules = append(rules, rule)
+			rulesChanged = true
+		}
+	}
+	if !extendsChanged && !rulesChanged {
+		return response, nil
+	}
+	if extendsChanged {
+		setValue("extends", extends)
+	}
+	if rulesChanged {
+		setValue("packageRules", rules)
+	}
+
+	output, err := writeConfig(fields)
+	if err != nil {
+		return response, fmt.Errorf("unable to write renovate config: %v", err)
+	}
+	response.FinalOutput = output
+	response.IsChanged = output != content
+	return response, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package renovate
+
+import (
+	"testing"
+)
+
+func TestUpdateRenovateConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		managers  []string
+		want      string
+		isChanged bool
+		wantErr   bool
+	}{
+		{
+			name:     "new config",
+			managers: []string{ManagerGitHubActions, ManagerDockerfile},
+			want: `{
+  "$schema": "https://docs.renovatebot.com/renovate-schema.json",
+  "extends": [
+    "config:recommended",
+    "helpers:pinGitHubActionDigests",
+    "docker:pinDigests"
+  ],
+  "packageRules": [
+    {
+      "matchManagers": [
+        "github-actions"
+      ],
+      "groupName": "GitHub Actions"
+    },
+    {
+      "matchManagers": [
+        "dockerfile"
+      ],
+      "groupName": "Docker images"
+    }
+  ]
+}
+`,
+			isChanged: true,
+		},
+		{
+			name: "existing config keeps its keys and rules",
+			content: `{
+  "extends": ["config:base"],
+  "packageRules": [
+    {"matchManagers": ["dockerfile"], "groupName": "images"}
+  ],
+  "timezone": "Europe/Berlin"
+}
+`,
+			managers: []string{ManagerGitHubActions, ManagerDockerfile},
+			want: `{
+  "extends": [
+    "config:base",
+    "helpers:pinGitHubActionDigests",
+    "docker:pinDigests"
+  ],
+  "packageRules": [
+    {
+      "matchManagers": [
+        "dockerfile"
+      ],
+      "groupName": "images"
+    },
+    {
+      "matchManagers": [
+        "github-actions"
+      ],
+      "groupName": "GitHub Actions"
+    }
+  ],
+  "timezone": "Europe/Berlin"
+}
+`,
+			isChanged: true,
+		},
+		{
+			name: "up to date config",
+			content: `{"extends": ["helpers:pinGitHubActionDigests"], "packageRules": [{"matchManagers": ["github-actions"], "groupName": "actions"}]}
+`,
+			managers: []string{ManagerGitHubActions},
+			want: `{"extends": ["helpers:pinGitHubActionDigests"], "packageRules": [{"matchManagers": ["github-actions"], "groupName": "actions"}]}
+`,
+		},
+		{
+			name: "json5 config",
+			content: `{
+  // keep the defaults
+  extends: ["config:recommended"],
+}
+`,
+			managers: []string{ManagerGitHubActions},
+			want: `{
+  // keep the defaults
+  extends: ["config:recommended"],
+}
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := UpdateRenovateConfig(tt.content, tt.managers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateRenovateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if response.FinalOutput != tt.want || response.IsChanged != tt.isChanged {
+				t.Errorf("UpdateRenovateConfig() = %v\n%s\nwant %v\n%s", response.IsChanged, response.FinalOutput, tt.isChanged, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package repoconfig
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigPaths are the paths the configuration is read from, in order of preference
+var ConfigPaths = []string{".github/stepsecurity.yml", ".github/stepsecurity.yaml"}
+
+// Config is the optional .github/stepsecurity.yml in a repository, which controls the remediations applied to it, e.g.
+//
+//	remediations:
+//	  addHardenRunner: false
+//	  addShellDefaults: true
+//	exemptions:
+//	  files: [".github/workflows/release.yml"]
+//	  actions: ["myorg/*"]
+//	  jobs: ["deploy"]
+//	pin:
+//	  immutable: true
+//	runner-labels:
+//	  ubuntu-latest: ubuntu-22.04
+type Config struct {
+	// Remediations are query parameters, e.g. pinActions: false, which override the parameters of the request
+	Remediations map[string]string `yaml:"remediations"`
+	Exemptions   Exemptions        `yaml:"exemptions"`
+	Pin          PinPolicy         `yaml:"pin"`
+	// RunnerLabels maps runner labels to the labels they are replaced with
+	RunnerLabels map[string]string `yaml:"runner-labels"`
+}
+
+type Exemptions struct {
+	// Files are path.Match patterns, or directories ending with /
+	Files []string `yaml:"files"`
+	// Actions are patterns of actions that are not pinned, replaced or reported, e.g. myorg/*
+	Actions []string `yaml:"actions"`
+	// Jobs are names of jobs that are left unchanged in all workflows
+	Jobs []string `yaml:"jobs"`
+}
+
+type PinPolicy struct {
+	// Immutable pins actions that are published as immutable actions to their semantic version instead of a commit SHA
+	Immutable bool `yaml:"immutable"`
+}
+
+// FindConfig returns the path and parsed configuration from the files of a repository, or nil if there is no configuration
+func FindConfig(files map[string]string) (string, *Config, error) {
+	for _, configPath := range ConfigPaths {
+		if content, found := files[configPath]; found {
+			config, err := ParseConfig(content)
+			return configPath, config, err
+		}
+	}
+	return "", nil, nil
+}
+
+// ParseConfig parses the configuration. Remediations can be set to booleans or strings, e.g. pinActions: false or sbomFormat: spdx.
+func ParseConfig(content string) (*Config, error) {
+	config := &Config{}
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	decoder.KnownFields(true)
+	err := decoder.Decode(config)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("unable to parse config: %v", err)
+	}
+	return config, nil
+}
+
+// ApplyRemediations returns the query parameters with the remediations of the configuration applied
+func (c *Config) ApplyRemediations(queryStringParams map[string]string) map[string]string {
+	params := make(map[string]string)
+	for key, value := range queryStringParams {
+		params[key] = value
+	}
+	if c == nil {
+		return params
+	}
+	for key, value := range c.Remediations {
+		params[key] = value
+	}
+	return params
+}
+
+// IsFileExempted returns true if the file matches one of the exempted file patterns
+func (c *Config) IsFileExempted(filePath string) bool {
+	if c == nil {
+		return false
+	}
+	for _, pattern := range c.Exemptions.Files {
+		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(filePath, pattern) {
+			return true
+		}
+		if matched, _ := path.Match(pattern, filePath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// IsJobExempted returns true if the job is exempted
+func (c *Config) IsJobExempted(jobName string) bool {
+	if c == nil || jobName == "" {
+		return false
+	}
+	for _, job := range c.Exemptions.Jobs {
+		if job == jobName {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActionExempted returns true if the action matches one of the exempted action patterns. The ref of the action is ignored.
+func (c *Config) IsActionExempted(action string) bool {
+	if c == nil || action == "" {
+		return false
+	}
+	return pin.ActionExists(strings.Split(action, "@")[0], c.Exemptions.Actions)
+}
+
+// FilterFindings returns the findings that are not for exempted jobs or actions
+func (c *Config) FilterFindings(fileFindings []findings.Finding) []findings.Finding {
+	if c == nil {
+		return fileFindings
+	}
+	var filtered []findings.Finding
+	for _, finding := range fileFindings {
+		if !c.IsJobExempted(finding.JobName) && !c.IsActionExempted(finding.Action) {
+			filtered = append(filtered, finding)
+		}
+	}
+	return filtered
+}
+
+// getJobLines returns the 0-based first and last lines of each job, including comments and blank lines after the job
+func getJobLines(inputYaml string) (map[string][2]int, error) {
+	t := yaml.Node{}
+	err := yaml.Unmarshal([]byte(inputYaml), &t)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	jobLines := make(map[string][2]int)
+	if len(t.Content) == 0 || t.Content[0].Kind != yaml.MappingNode {
+		return jobLines, nil
+	}
+	topNode := t.Content[0]
+
+	var jobsNode *yaml.Node
+	nextTopLevelLine := strings.Count(inputYaml, "\n")
+	if !strings.HasSuffix(inputYaml, "\n") {
+		nextTopLevelLine++
+	}
+	for i := 0; i+1 < len(topNode.Content); i += 2 {
+		if jobsNode != nil {
+			nextTopLevelLine = topNode.Content[i].Line - 1
+			break
+		}
+		if topNode.Content[i].Value == "jobs" && topNode.Content[i+1].Kind == yaml.MappingNode {
+			jobsNode = topNode.Content[i+1]
+		}
+	}
+	if jobsNode == nil || jobsNode.Style&yaml.FlowStyle != 0 {
+		return jobLines, nil
+	}
+
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		lastLine := nextTopLevelLine - 1
+		if i+2 < len(jobsNode.Content) {
+			lastLine = jobsNode.Content[i+2].Line - 2
+		}
+		jobLines[jobsNode.Content[i].Value] = [2]int{jobsNode.Content[i].Line - 1, lastLine}
+	}
+	return jobLines, nil
+}
+
+// RestoreExemptedJobs replaces the exempted jobs in the remediated workflow with the jobs from the original workflow,
+// so the remediations do not change them. Changes outside of the jobs, e.g. workflow level permissions, are kept.
+func (c *Config) RestoreExemptedJobs(originalYaml, outputYaml string) (string, error) {
+	if c == nil || len(c.Exemptions.Jobs) == 0 || originalYaml == outputYaml {
+		return outputYaml, nil
+	}
+
+	originalJobs, err := getJobLines(originalYaml)
+	if err != nil {
+		return outputYaml, err
+	}
+	outputJobs, err := getJobLines(outputYaml)
+	if err != nil {
+		return outputYaml, err
+	}
+
+	type restore struct {
+		output   [2]int
+		original [2]int
+	}
+	var restores []restore
+	for _, job := range c.Exemptions.Jobs {
+		originalLines, foundOriginal := originalJobs[job]
+		outputLines, foundOutput := outputJobs[job]
+		if foundOriginal && foundOutput {
+			restores = append(restores, restore{output: outputLines, original: originalLines})
+		}
+	}
+	// jobs are restored from the end, so the lines of the other jobs do not move
+	sort.Slice(restores, func(i, j int) bool { return restores[i].output[0] > restores[j].output[0] })
+
+	originalLines := strings.Split(originalYaml, "\n")
+	outputLines := strings.Split(outputYaml, "\n")
+	for _, r := range restores {
+		var lines []string
+		lines = append(lines, outputLines[:r.output[0]]...)
+		lines = append(lines, originalLines[r.original[0]:r.original[1]+1]...)
+		lines = append(lines, outputLines[r.output[1]+1:]...)
+		outputLines = lines
+	}
+	return strings.Join(outputLines, "\n"), nil
+}
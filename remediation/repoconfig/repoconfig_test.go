@@ -0,0 +1,146 @@
+package repoconfig
+
+import (
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+)
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig("remediations:\n  pinActions: false\n  sbomFormat: spdx\npin:\n  immutable: true\n")
+	if err != nil {
+		t.Fatalf("ParseConfig() unexpected error = %v", err)
+	}
+	if config.Remediations["pinActions"] != "false" || config.Remediations["sbomFormat"] != "spdx" || !config.Pin.Immutable {
+		t.Errorf("ParseConfig() = %+v", config)
+	}
+
+	params := config.ApplyRemediations(map[string]string{"pinActions": "true", "addHardenRunner": "false"})
+	if params["pinActions"] != "false" || params["addHardenRunner"] != "false" {
+		t.Errorf("ApplyRemediations() = %v", params)
+	}
+
+	if _, err := ParseConfig("exemptions:\n  workflows: []\n"); err == nil {
+		t.Errorf("ParseConfig() expected error for unknown field")
+	}
+
+	config, err = ParseConfig("")
+	if err != nil || config == nil {
+		t.Errorf("ParseConfig() = %v, %v for empty config", config, err)
+	}
+}
+
+func TestExemptions(t *testing.T) {
+	config := &Config{Exemptions: Exemptions{
+		Files:   []string{".github/workflows/release.yml", "vendor/", "*.Dockerfile"},
+		Actions: []string{"myorg/*"},
+		Jobs:    []string{"deploy"},
+	}}
+
+	tests := []struct {
+		filePath string
+		exempted bool
+	}{
+		{filePath: ".github/workflows/release.yml", exempted: true},
+		{filePath: ".github/workflows/ci.yml", exempted: false},
+		{filePath: "vendor/x/action.yml", exempted: true},
+		{filePath: "prod.Dockerfile", exempted: true},
+		{filePath: "docker/prod.Dockerfile", exempted: false},
+	}
+	for _, test := range tests {
+		if got := config.IsFileExempted(test.filePath); got != test.exempted {
+			t.Errorf("IsFileExempted(%s) = %v, want %v", test.filePath, got, test.exempted)
+		}
+	}
+
+	if !config.IsActionExempted("myorg/setup@v1") || config.IsActionExempted("actions/checkout@v4") {
+		t.Errorf("IsActionExempted() did not match the exempted actions")
+	}
+
+	filtered := config.FilterFindings([]findings.Finding{
+		{RuleID: "a", JobName: "deploy"},
+		{RuleID: "b", Action: "myorg/setup@v1"},
+		{RuleID: "c", JobName: "build", Action: "actions/checkout@v4"},
+	})
+	if len(filtered) != 1 || filtered[0].RuleID != "c" {
+		t.Errorf("FilterFindings() = %v", filtered)
+	}
+
+	var nilConfig *Config
+	if nilConfig.IsFileExempted("a") || nilConfig.IsJobExempted("a") || len(nilConfig.FilterFindings([]findings.Finding{{}})) != 1 {
+		t.Errorf("nil config must not exempt anything")
+	}
+}
+
+func TestRestoreExemptedJobs(t *testing.T) {
+	original := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+  release:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+env:
+  A: b
+`
+	output := `on: push
+permissions:
+  contents: read
+
+jobs:
+  build:
+    runs-on: ubuntu-22.04
+    steps:
+      - run: make
+
+  deploy:
+    runs-on: ubuntu-22.04
+    steps:
+      - uses: actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11 # v4
+  release:
+    runs-on: ubuntu-22.04
+    permissions:
+      contents: read
+    steps:
+      - uses: actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11 # v4
+env:
+  A: b
+`
+	expected := `on: push
+permissions:
+  contents: read
+
+jobs:
+  build:
+    runs-on: ubuntu-22.04
+    steps:
+      - run: make
+
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+  release:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+env:
+  A: b
+`
+	config := &Config{Exemptions: Exemptions{Jobs: []string{"deploy", "release", "missing"}}}
+	got, err := config.RestoreExemptedJobs(original, output)
+	if err != nil {
+		t.Fatalf("RestoreExemptedJobs() unexpected error = %v", err)
+	}
+	if got != expected {
+		t.Errorf("RestoreExemptedJobs() = %v, want %v", got, expected)
+	}
+}
@@ -0,0 +1,258 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/diff"
+	"github.com/step-security/secure-repo/remediation/findings"
+)
+
+const (
+	// ConfidenceSafe is the confidence of changes that keep the behavior of the workflow, so they can be merged without review
+	ConfidenceSafe = "safe"
+	// ConfidenceNeedsReview is the confidence of changes that may change the behavior of the workflow
+	ConfidenceNeedsReview = "needs-review"
+)
+
+const (
+	// StatusChanged is the status of a module that changed the file
+	StatusChanged = "changed"
+	// StatusUnchanged is the status of a module that left the file unchanged, without errors
+	StatusUnchanged = "unchanged"
+	// StatusFailed is the status of a module that ran into errors. The changes it made are kept, and the modules after it
+	// still run.
+	StatusFailed = "failed"
+)
+
+// Change is a line of a file changed by a remediation. Kind is added, removed or modified. Confidence is safe or
+// needs-review, and Revert is the edit that undoes the change.
+type Change struct {
+	File       string `json:",omitempty"`
+	Line       int
+	Kind       string
+	Before     string `json:",omitempty"`
+	After      string `json:",omitempty"`
+	Confidence string `json:",omitempty"`
+	Revert     Edit
+}
+
+// Edit is a line edit of the output of a module. Kind is added, removed or modified, Before is the line at Line, and After
+// is the line that replaces it, or is added before Line. The reverts of the changes of a module are applied to its output
+// in the reverse order of the changes, so the line numbers of the ones not applied yet stay the same.
+type Edit struct {
+	Line   int
+	Kind   string
+	Before string `json:",omitempty"`
+	After  string `json:",omitempty"`
+}
+
+// Skipped is an item, such as a job or an action, that a remediation did not change, along with the reason
+type Skipped struct {
+	File   string `json:",omitempty"`
+	Line   int    `json:",omitempty"`
+	Item   string
+	Reason string
+}
+
+// Module has what a remediation changed, skipped and the errors it ran into
+type Module struct {
+	Name    string
+	Changes []Change  `json:",omitempty"`
+	Skipped []Skipped `json:",omitempty"`
+	Errors  []string  `json:",omitempty"`
+}
+
+// Status is whether a module that ran changed the file, left it unchanged or failed, along with its errors
+type Status struct {
+	Module string
+	Status string
+	Errors []string `json:",omitempty"`
+}
+
+// Report has the modules in the order they ran. Modules without changes, skipped items or errors are not included, and
+// Statuses has the status of each module that ran.
+type Report struct {
+	Modules  []Module
+	Statuses []Status `json:",omitempty"`
+}
+
+func (r *Report) getModule(name string) *Module {
+	for i := range r.Modules {
+		if r.Modules[i].Name == name {
+			return &r.Modules[i]
+		}
+	}
+	r.Modules = append(r.Modules, Module{Name: name})
+	return &r.Modules[len(r.Modules)-1]
+}
+
+// AddChanges adds the lines changed by the module from before to after, with the confidence of the module
+func (r *Report) AddChanges(module, file, before, after, confidence string) {
+	lineChanges := diff.LineChanges(before, after)
+	if len(lineChanges) == 0 {
+		return
+	}
+	m := r.getModule(module)
+	// offset is the number of lines added minus the ones removed before a line, to get the line in after of removed lines
+	offset := 0
+	for _, lineChange := range lineChanges {
+		change := Change{File: file, Line: lineChange.Line, Kind: lineChange.Kind, Before: lineChange.Before, After: lineChange.After, Confidence: confidence}
+		switch lineChange.Kind {
+		case diff.Added:
+			change.Revert = Edit{Line: lineChange.Line, Kind: diff.Removed, Before: lineChange.After}
+			offset++
+		case diff.Removed:
+			change.Revert = Edit{Line: lineChange.Line + offset, Kind: diff.Added, After: lineChange.Before}
+			offset--
+		default:
+			change.Revert = Edit{Line: lineChange.Line, Kind: diff.Modified, Before: lineChange.After, After: lineChange.Before}
+		}
+		m.Changes = append(m.Changes, change)
+	}
+}
+
+// LineDelta returns the number of lines the changes of the module added minus the ones they removed
+func (m Module) LineDelta() int {
+	delta := 0
+	for _, change := range m.Changes {
+		switch change.Kind {
+		case diff.Added:
+			delta++
+		case diff.Removed:
+			delta--
+		}
+	}
+	return delta
+}
+
+// AddModule adds what a module changed, skipped and the errors it ran into in a part of a file, e.g. a document of a
+// file with several documents, which starts after linesBefore lines of the input of the module, and after linesAfter
+// lines of its output. The lines of removed lines and skipped items are lines of the input, and the others of the output.
+func (r *Report) AddModule(module Module, linesBefore, linesAfter int) {
+	m := r.getModule(module.Name)
+	for _, change := range module.Changes {
+		if change.Kind == diff.Removed {
+			change.Line += linesBefore
+		} else {
+			change.Line += linesAfter
+		}
+		change.Revert.Line += linesAfter
+		m.Changes = append(m.Changes, change)
+	}
+	for _, skipped := range module.Skipped {
+		if skipped.Line > 0 {
+			skipped.Line += linesBefore
+		}
+		m.Skipped = append(m.Skipped, skipped)
+	}
+	m.Errors = append(m.Errors, module.Errors...)
+}
+
+// Revert applies the reverts of the changes to the output of the module that made them, and returns the input of the module
+func Revert(output string, changes []Change) (string, error) {
+	lines := strings.SplitAfter(output, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		edit := changes[i].Revert
+		index := edit.Line - 1
+		if edit.Kind == diff.Added {
+			if index < 0 || index > len(lines) {
+				return "", fmt.Errorf("unable to revert line %d: out of range", edit.Line)
+			}
+			lines = append(lines[:index], append([]string{edit.After + "\n"}, lines[index:]...)...)
+			continue
+		}
+		if index < 0 || index >= len(lines) || strings.TrimSuffix(lines[index], "\n") != edit.Before {
+			return "", fmt.Errorf("unable to revert line %d: the line was changed", edit.Line)
+		}
+		if edit.Kind == diff.Removed {
+			lines = append(lines[:index], lines[index+1:]...)
+		} else {
+			lines[index] = edit.After + lines[index][len(edit.Before):]
+		}
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// AddSkipped adds an item that the module did not change
+func (r *Report) AddSkipped(module string, skipped Skipped) {
+	m := r.getModule(module)
+	m.Skipped = append(m.Skipped, skipped)
+}
+
+// AddUnfixed adds the findings of the module that were not fixed as skipped items
+func (r *Report) AddUnfixed(module, file string, moduleFindings []findings.Finding) {
+	for _, finding := range moduleFindings {
+		if finding.Fixed {
+			continue
+		}
+		item := finding.Action
+		if item == "" {
+			item = finding.JobName
+		}
+		reason := "unable to fix automatically: " + finding.Message
+		r.AddSkipped(module, Skipped{File: file, Line: finding.Line, Item: item, Reason: reason})
+	}
+}
+
+// AddError adds an error that the module ran into
+func (r *Report) AddError(module string, err error) {
+	if err == nil {
+		return
+	}
+	m := r.getModule(module)
+	m.Errors = append(m.Errors, err.Error())
+}
+
+// SetStatuses sets the statuses of the modules that ran, in the order of their names, from their changes and errors.
+// The modules with errors that are not in the names, e.g. the score, are added after them.
+func (r *Report) SetStatuses(names []string) {
+	r.Statuses = nil
+	added := map[string]bool{}
+	addStatus := func(name string) {
+		if added[name] {
+			return
+		}
+		added[name] = true
+		status := Status{Module: name, Status: StatusUnchanged}
+		for _, m := range r.Modules {
+			if m.Name != name {
+				continue
+			}
+			if len(m.Changes) > 0 {
+				status.Status = StatusChanged
+			}
+			if len(m.Errors) > 0 {
+				status.Status, status.Errors = StatusFailed, m.Errors
+			}
+		}
+		r.Statuses = append(r.Statuses, status)
+	}
+	for _, name := range names {
+		addStatus(name)
+	}
+	for _, m := range r.Modules {
+		if len(m.Errors) > 0 {
+			addStatus(m.Name)
+		}
+	}
+}
+
+// SetFile sets the file of the changes and skipped items that do not have one
+func (r *Report) SetFile(file string) {
+	for i := range r.Modules {
+		for j := range r.Modules[i].Changes {
+			if r.Modules[i].Changes[j].File == "" {
+				r.Modules[i].Changes[j].File = file
+			}
+		}
+		for j := range r.Modules[i].Skipped {
+			if r.Modules[i].Skipped[j].File == "" {
+				r.Modules[i].Skipped[j].File = file
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package report
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+)
+
+func TestReport(t *testing.T) {
+	r := &Report{}
+	r.AddChanges("pin", "", "uses: actions/checkout@v4\n", "uses: actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11 # v4\n", ConfidenceSafe)
+	r.AddChanges("shelldefaults", "", "a\n", "a\n", ConfidenceNeedsReview)
+	r.AddError("permissions", errors.New("unable to parse yaml"))
+	r.AddError("permissions", nil)
+	r.AddUnfixed("forkguard", "", []findings.Finding{
+		{RuleID: "fork-pull-request-secrets", Message: "job build uses secrets on pull requests from forks", JobName: "build", Line: 4},
+		{RuleID: "fork-pull-request-secrets", JobName: "test", Fixed: true},
+	})
+	r.SetFile("ci.yml")
+
+	if len(r.Modules) != 3 || r.Modules[0].Name != "pin" || r.Modules[1].Name != "permissions" || r.Modules[2].Name != "forkguard" {
+		t.Fatalf("unexpected modules %+v", r.Modules)
+	}
+	expectedChange := Change{File: "ci.yml", Line: 1, Kind: "modified", Before: "uses: actions/checkout@v4", After: "uses: actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11 # v4",
+		Confidence: ConfidenceSafe, Revert: Edit{Line: 1, Kind: "modified", Before: "uses: actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11 # v4", After: "uses: actions/checkout@v4"}}
+	if len(r.Modules[0].Changes) != 1 || r.Modules[0].Changes[0] != expectedChange {
+		t.Errorf("unexpected changes %+v", r.Modules[0].Changes)
+	}
+	if len(r.Modules[1].Errors) != 1 || r.Modules[1].Errors[0] != "unable to parse yaml" {
+		t.Errorf("unexpected errors %+v", r.Modules[1].Errors)
+	}
+	expectedSkipped := Skipped{File: "ci.yml", Line: 4, Item: "build", Reason: "unable to fix automatically: job build uses secrets on pull requests from forks"}
+	if len(r.Modules[2].Skipped) != 1 || r.Modules[2].Skipped[0] != expectedSkipped {
+		t.Errorf("unexpected skipped items %+v", r.Modules[2].Skipped)
+	}
+
+	r.AddError("score", errors.New("unable to compute score"))
+	r.SetStatuses([]string{"pin", "shelldefaults", "permissions", "forkguard"})
+	expectedStatuses := []Status{
+		{Module: "pin", Status: StatusChanged},
+		{Module: "shelldefaults", Status: StatusUnchanged},
+		{Module: "permissions", Status: StatusFailed, Errors: []string{"unable to parse yaml"}},
+		{Module: "forkguard", Status: StatusUnchanged},
+		{Module: "score", Status: StatusFailed, Errors: []string{"unable to compute score"}},
+	}
+	if !reflect.DeepEqual(r.Statuses, expectedStatuses) {
+		t.Errorf("unexpected statuses %+v", r.Statuses)
+	}
+}
+
+func TestRevert(t *testing.T) {
+	tests := []struct {
+		before string
+		after  string
+	}{
+		{before: "a\nb\nc\nd\n", after: "a\nx\nc\ny\nz\n"},
+		{before: "a\nb\nc\nd\ne\n", after: "a\nd\nf\n"},
+		{before: "a\nb\n", after: "x\ny\na\nb\nc\n"},
+		{before: "a\nb\nc\n", after: "c\n"},
+	}
+	for _, tt := range tests {
+		r := &Report{}
+		r.AddChanges("module", "", tt.before, tt.after, ConfidenceNeedsReview)
+		got, err := Revert(tt.after, r.Modules[0].Changes)
+		if err != nil {
+			t.Fatalf("Revert() returned error: %v", err)
+		}
+		if got != tt.before {
+			t.Errorf("Revert() = %q, want %q", got, tt.before)
+		}
+	}
+
+	r := &Report{}
+	r.AddChanges("module", "", "a\n", "b\n", ConfidenceNeedsReview)
+	if _, err := Revert("c\n", r.Modules[0].Changes); err == nil {
+		t.Errorf("expected an error reverting a line that was changed")
+	}
+}
+
+func TestAddModule(t *testing.T) {
+	// the reports of the documents of a file, whose changes are reverted with the output of the file
+	before, after := []string{"a\nb\n", "c\nd\ne\n"}, []string{"x\na\n", "c\ne\nf\n"}
+	r := &Report{}
+	linesBefore, linesAfter := 0, 0
+	for i := range before {
+		document := &Report{}
+		document.AddChanges("module", "", before[i], after[i], ConfidenceNeedsReview)
+		document.AddSkipped("module", Skipped{Line: 2, Item: "build"})
+		r.AddModule(document.Modules[0], linesBefore, linesAfter)
+		if delta := document.Modules[0].LineDelta(); delta != 0 {
+			t.Errorf("LineDelta() = %d, want 0", delta)
+		}
+		linesBefore, linesAfter = linesBefore+strings.Count(before[i], "\n"), linesAfter+strings.Count(after[i], "\n")
+	}
+	got, err := Revert(strings.Join(after, ""), r.Modules[0].Changes)
+	if err != nil {
+		t.Fatalf("Revert() returned error: %v", err)
+	}
+	if want := strings.Join(before, ""); got != want {
+		t.Errorf("Revert() = %q, want %q", got, want)
+	}
+	if skipped := r.Modules[0].Skipped; len(skipped) != 2 || skipped[0].Line != 2 || skipped[1].Line != 4 {
+		t.Errorf("unexpected skipped items %+v", skipped)
+	}
+}
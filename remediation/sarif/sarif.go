@@ -0,0 +1,265 @@
+package sarif
+
+import (
+	"sort"
+
+	"github.com/step-security/secure-repo/remediation/diff"
+	"github.com/step-security/secure-repo/remediation/findings"
+)
+
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	ToolName           = "secure-repo"
+	ToolInformationURI = "https://github.com/step-security/secure-repo"
+)
+
+// Rule describes a rule in the tool section of the log. SecuritySeverity is a score from 0 to 10,
+// which GitHub code scanning uses to show the severity of the alerts.
+type Rule struct {
+	Description      string
+	Level            string
+	SecuritySeverity string
+}
+
+// Rules are the rules of the analyzers that report findings. Findings for other rules are reported as warnings.
+var Rules = map[string]Rule{
+	"unpinned-action":             {Description: "Action is not pinned to a full length commit SHA", Level: "warning", SecuritySeverity: "5.0"},
+	"missing-permissions":         {Description: "GITHUB_TOKEN permissions are not set", Level: "warning", SecuritySeverity: "5.0"},
+	"script-injection":            {Description: "Untrusted input is used in a script", Level: "error", SecuritySeverity: "8.0"},
+	"dangerous-trigger":           {Description: "Pull request code is checked out in a privileged workflow", Level: "error", SecuritySeverity: "9.0"},
+	"composite-script-injection":  {Description: "Input is used in a script in a composite action", Level: "error", SecuritySeverity: "8.0"},
+	"composite-token-input":       {Description: "Composite action takes a token as input", Level: "note", SecuritySeverity: "3.0"},
+	"dispatch-input-injection":    {Description: "Dispatch input is used in a script", Level: "warning", SecuritySeverity: "6.0"},
+	"untrusted-github-env-write":  {Description: "Untrusted input is written to GITHUB_ENV", Level: "error", SecuritySeverity: "8.0"},
+	"untrusted-github-path-write": {Description: "Untrusted input is written to GITHUB_PATH", Level: "error", SecuritySeverity: "8.0"},
+	"vulnerable-action":           {Description: "Action has a known vulnerability", Level: "error", SecuritySeverity: "7.5"},
+	"typosquatted-action":         {Description: "Action name is similar to a popular action", Level: "error", SecuritySeverity: "8.0"},
+	"archived-action":             {Description: "Action repository is archived", Level: "warning", SecuritySeverity: "4.0"},
+	"unmaintained-action":         {Description: "Action is not maintained", Level: "note", SecuritySeverity: "3.0"},
+	"denied-action":               {Description: "Action is denied by the action policy", Level: "error", SecuritySeverity: "7.0"},
+	"disallowed-action":           {Description: "Action is not allowed by the action policy", Level: "warning", SecuritySeverity: "5.0"},
+	"fork-pull-request-secrets":   {Description: "Job uses secrets on pull requests from forks", Level: "note", SecuritySeverity: "2.0"},
+	"unguarded-publish-job":       {Description: "Publish job can run in forks of the repository", Level: "warning", SecuritySeverity: "4.0"},
+	"privileged-container":        {Description: "Container is run in privileged mode", Level: "error", SecuritySeverity: "7.0"},
+	"container-sys-admin":         {Description: "Container has the SYS_ADMIN capability", Level: "error", SecuritySeverity: "7.0"},
+	"docker-socket-mount":         {Description: "Docker socket is mounted in a container", Level: "error", SecuritySeverity: "7.0"},
+	"secret-build-arg":            {Description: "Secret is passed to docker build as a build arg", Level: "error", SecuritySeverity: "7.0"},
+}
+
+// File is a file and the findings reported for it. Hunks are the changes made to the file, which are added as fixes
+// to the results of the findings that were fixed.
+type File struct {
+	Path     string
+	Findings []findings.Finding
+	Hunks    []diff.Hunk
+}
+
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string                `json:"name"`
+	InformationURI string                `json:"informationUri"`
+	Rules          []ReportingDescriptor `json:"rules"`
+}
+
+type ReportingDescriptor struct {
+	ID                   string                 `json:"id"`
+	ShortDescription     Message                `json:"shortDescription"`
+	HelpURI              string                 `json:"helpUri,omitempty"`
+	DefaultConfiguration Configuration          `json:"defaultConfiguration"`
+	Properties           map[string]interface{} `json:"properties,omitempty"`
+}
+
+type Configuration struct {
+	Level string `json:"level"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Result struct {
+	RuleID     string                 `json:"ruleId"`
+	RuleIndex  int                    `json:"ruleIndex"`
+	Level      string                 `json:"level"`
+	Message    Message                `json:"message"`
+	Locations  []Location             `json:"locations"`
+	Fixes      []Fix                  `json:"fixes,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// Fix is a proposed fix for a result, as a set of replacements in the file
+type Fix struct {
+	Description     Message          `json:"description"`
+	ArtifactChanges []ArtifactChange `json:"artifactChanges"`
+}
+
+type ArtifactChange struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Replacements     []Replacement    `json:"replacements"`
+}
+
+// Replacement replaces the deleted region with the inserted content. An empty region is an insertion point.
+type Replacement struct {
+	DeletedRegion   Region           `json:"deletedRegion"`
+	InsertedContent *ArtifactContent `json:"insertedContent,omitempty"`
+}
+
+type ArtifactContent struct {
+	Text string `json:"text"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           Region           `json:"region"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// GetRule returns the rule with the ID, or a warning described by the ID for rules that are not in Rules
+func GetRule(ruleID string) Rule {
+	if rule, found := Rules[ruleID]; found {
+		return rule
+	}
+	return Rule{Description: ruleID, Level: "warning"}
+}
+
+// getHunks returns the hunks that fixed a finding on the line. These are the hunks that change the line, or else the
+// nearest hunk, since some fixes add lines, e.g. permissions are added above the jobs.
+func getHunks(hunks []diff.Hunk, line int) []diff.Hunk {
+	var changed []diff.Hunk
+	nearest, distance := -1, 0
+	for i, hunk := range hunks {
+		if line >= hunk.Line && line < hunk.Line+hunk.Removed {
+			changed = append(changed, hunk)
+		}
+		hunkDistance := hunk.Line - line
+		if hunkDistance < 0 {
+			hunkDistance = -hunkDistance
+		}
+		if nearest == -1 || hunkDistance < distance {
+			nearest, distance = i, hunkDistance
+		}
+	}
+	if len(changed) == 0 && nearest != -1 {
+		changed = append(changed, hunks[nearest])
+	}
+	return changed
+}
+
+// getFix returns the fix for a finding in the file, with whole lines replaced by each hunk
+func getFix(file File, finding findings.Finding) Fix {
+	description := finding.Suggestion
+	if description == "" {
+		description = GetRule(finding.RuleID).Description
+	}
+	change := ArtifactChange{ArtifactLocation: ArtifactLocation{URI: file.Path}}
+	for _, hunk := range getHunks(file.Hunks, finding.Line) {
+		replacement := Replacement{
+			DeletedRegion: Region{StartLine: hunk.Line, StartColumn: 1, EndLine: hunk.Line + hunk.Removed, EndColumn: 1},
+		}
+		if hunk.Inserted != "" {
+			replacement.InsertedContent = &ArtifactContent{Text: hunk.Inserted}
+		}
+		change.Replacements = append(change.Replacements, replacement)
+	}
+	return Fix{Description: Message{Text: description}, ArtifactChanges: []ArtifactChange{change}}
+}
+
+// NewLog returns a SARIF log with a result for each finding. Only the rules of the findings are added to the tool section,
+// sorted by ID. Findings that were fixed are reported as well, since the fixes are applied when the changes are merged,
+// and their results have the changes that fixed them, so they can be shown as suggested fixes.
+func NewLog(files []File) *Log {
+	ruleIDs := make(map[string]bool)
+	for _, file := range files {
+		for _, finding := range file.Findings {
+			ruleIDs[finding.RuleID] = true
+		}
+	}
+	var sortedRuleIDs []string
+	for ruleID := range ruleIDs {
+		sortedRuleIDs = append(sortedRuleIDs, ruleID)
+	}
+	sort.Strings(sortedRuleIDs)
+
+	run := Run{
+		Tool:    Tool{Driver: Driver{Name: ToolName, InformationURI: ToolInformationURI, Rules: []ReportingDescriptor{}}},
+		Results: []Result{},
+	}
+	ruleIndex := make(map[string]int)
+	for i, ruleID := range sortedRuleIDs {
+		rule := GetRule(ruleID)
+		ruleIndex[ruleID] = i
+		descriptor := ReportingDescriptor{
+			ID:                   ruleID,
+			ShortDescription:     Message{Text: rule.Description},
+			HelpURI:              ToolInformationURI,
+			DefaultConfiguration: Configuration{Level: rule.Level},
+			Properties:           map[string]interface{}{"tags": []string{"security"}},
+		}
+		if rule.SecuritySeverity != "" {
+			descriptor.Properties["security-severity"] = rule.SecuritySeverity
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, descriptor)
+	}
+
+	for _, file := range files {
+		for _, finding := range file.Findings {
+			text := finding.Message
+			if finding.Suggestion != "" {
+				text += ". " + finding.Suggestion
+			}
+			line := finding.Line
+			if line < 1 {
+				line = 1
+			}
+			result := Result{
+				RuleID:    finding.RuleID,
+				RuleIndex: ruleIndex[finding.RuleID],
+				Level:     GetRule(finding.RuleID).Level,
+				Message:   Message{Text: text},
+				Locations: []Location{{PhysicalLocation: PhysicalLocation{
+					ArtifactLocation: ArtifactLocation{URI: file.Path},
+					Region:           Region{StartLine: line, StartColumn: finding.Column},
+				}}},
+			}
+			if finding.Fixed {
+				result.Properties = map[string]interface{}{"fixed": true}
+				if len(file.Hunks) > 0 {
+					result.Fixes = []Fix{getFix(file, finding)}
+				}
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	return &Log{Schema: Schema, Version: Version, Runs: []Run{run}}
+}
@@ -0,0 +1,92 @@
+package sarif
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/diff"
+	"github.com/step-security/secure-repo/remediation/findings"
+)
+
+func TestNewLog(t *testing.T) {
+	files := []File{
+		{Path: ".github/workflows/ci.yml", Findings: []findings.Finding{
+			{RuleID: "unpinned-action", Message: "actions/checkout@v4 is not pinned to a full length commit SHA", Suggestion: "Pin actions/checkout to a full length commit SHA", Line: 8, Column: 15, Fixed: true},
+			{RuleID: "script-injection", Message: "github.head_ref is used in a script in job build, and can be used to inject code", Line: 10, Column: 9},
+		}, Hunks: []diff.Hunk{
+			{Line: 3, Inserted: "permissions:\n  contents: read\n"},
+			{Line: 8, Removed: 1, Inserted: "      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2\n"},
+		}},
+		{Path: "action.yml", Findings: []findings.Finding{
+			{RuleID: "custom-rule", Message: "custom"},
+		}},
+		{Path: "Dockerfile"},
+	}
+
+	log := NewLog(files)
+	if log.Version != Version || len(log.Runs) != 1 {
+		t.Fatalf("NewLog() = %+v", log)
+	}
+	run := log.Runs[0]
+
+	// rules are sorted by ID
+	var ruleIDs []string
+	for _, rule := range run.Tool.Driver.Rules {
+		ruleIDs = append(ruleIDs, rule.ID)
+	}
+	if len(ruleIDs) != 3 || ruleIDs[0] != "custom-rule" || ruleIDs[1] != "script-injection" || ruleIDs[2] != "unpinned-action" {
+		t.Errorf("unexpected rules %v", ruleIDs)
+	}
+	if run.Tool.Driver.Rules[1].Properties["security-severity"] != "8.0" || run.Tool.Driver.Rules[0].DefaultConfiguration.Level != "warning" {
+		t.Errorf("unexpected rule properties %+v", run.Tool.Driver.Rules)
+	}
+
+	if len(run.Results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(run.Results))
+	}
+	result := run.Results[0]
+	if result.RuleIndex != 2 || result.Level != "warning" || result.Properties["fixed"] != true ||
+		result.Message.Text != "actions/checkout@v4 is not pinned to a full length commit SHA. Pin actions/checkout to a full length commit SHA" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	location := result.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != ".github/workflows/ci.yml" || location.Region.StartLine != 8 || location.Region.StartColumn != 15 {
+		t.Errorf("unexpected location %+v", location)
+	}
+	// the fix of the result is the hunk that changed its line
+	if len(result.Fixes) != 1 || result.Fixes[0].Description.Text != "Pin actions/checkout to a full length commit SHA" {
+		t.Fatalf("unexpected fixes %+v", result.Fixes)
+	}
+	change := result.Fixes[0].ArtifactChanges[0]
+	if change.ArtifactLocation.URI != ".github/workflows/ci.yml" || len(change.Replacements) != 1 ||
+		change.Replacements[0].DeletedRegion != (Region{StartLine: 8, StartColumn: 1, EndLine: 9, EndColumn: 1}) ||
+		!strings.Contains(change.Replacements[0].InsertedContent.Text, "11bd71901bbe5b1630ceea73d27597364c9af683") {
+		t.Errorf("unexpected artifact change %+v", change)
+	}
+	if run.Results[1].Level != "error" || run.Results[1].Properties != nil || run.Results[1].Fixes != nil {
+		t.Errorf("unexpected result %+v", run.Results[1])
+	}
+	// results must have a line to be shown by code scanning
+	if run.Results[2].Locations[0].PhysicalLocation.Region.StartLine != 1 {
+		t.Errorf("expected line 1 for findings without a line")
+	}
+
+	output, err := json.Marshal(log)
+	if err != nil {
+		t.Fatalf("unable to marshal log: %v", err)
+	}
+	var decoded map[string]interface{}
+	json.Unmarshal(output, &decoded)
+	if decoded["$schema"] != Schema {
+		t.Errorf("unexpected schema %v", decoded["$schema"])
+	}
+}
+
+func TestNewLogEmpty(t *testing.T) {
+	output, _ := json.Marshal(NewLog(nil))
+	expected := `{"$schema":"https://json.schemastore.org/sarif-2.1.0.json","version":"2.1.0","runs":[{"tool":{"driver":{"name":"secure-repo","informationUri":"https://github.com/step-security/secure-repo","rules":[]}},"results":[]}]}`
+	if string(output) != expected {
+		t.Errorf("NewLog() = %s, want %s", output, expected)
+	}
+}
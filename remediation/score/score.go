@@ -0,0 +1,250 @@
+// Package score computes a score of the security of a workflow from 0 to 10, with a subset of the checks of the
+// OpenSSF Scorecard: Pinned-Dependencies, Token-Permissions and Dangerous-Workflow. The score of the input and of the
+// remediated output are compared, so the improvement made by the remediations can be shown in dashboards and pull requests.
+package score
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	CheckPinnedDependencies = "Pinned-Dependencies"
+	CheckTokenPermissions   = "Token-Permissions"
+	CheckDangerousWorkflow  = "Dangerous-Workflow"
+
+	maxScore = 10
+)
+
+// weights are the weights of the checks in the score, which are the ones of their risk in Scorecard
+var weights = map[string]float64{
+	CheckPinnedDependencies: 5,
+	CheckTokenPermissions:   7.5,
+	CheckDangerousWorkflow:  10,
+}
+
+// CheckScore is the score of a check from 0 to 10, with the reason it is not 10
+type CheckScore struct {
+	Name   string
+	Score  float64
+	Reason string `json:",omitempty"`
+}
+
+// Score is the weighted average of the scores of the checks
+type Score struct {
+	Score  float64
+	Checks []CheckScore
+}
+
+// ScoreChange has the score of the input and of the remediated output, and the difference between them
+type ScoreChange struct {
+	Before Score
+	After  Score
+	Delta  float64
+}
+
+// round rounds the score to one decimal
+func round(score float64) float64 {
+	return math.Round(score*10) / 10
+}
+
+// countActions returns the number of actions, reusable workflows and docker images that can be pinned, in the jobs of a
+// workflow or the steps of a composite action. Local actions cannot be pinned, and are not counted.
+func countActions(topNode *yaml.Node) int {
+	count := 0
+	countUses := func(usesNode *yaml.Node) {
+		if usesNode == nil || strings.HasPrefix(usesNode.Value, "./") {
+			return
+		}
+		if strings.HasPrefix(usesNode.Value, "docker://") || strings.Contains(usesNode.Value, "@") {
+			count++
+		}
+	}
+	countSteps := func(stepsNode *yaml.Node) {
+		if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, step := range stepsNode.Content {
+			countUses(document.MappingValue(step, "uses"))
+		}
+	}
+
+	if jobsNode := document.MappingValue(topNode, "jobs"); jobsNode != nil && jobsNode.Kind == yaml.MappingNode {
+		for i := 1; i < len(jobsNode.Content); i += 2 {
+			countUses(document.MappingValue(jobsNode.Content[i], "uses"))
+			countSteps(document.MappingValue(jobsNode.Content[i], "steps"))
+		}
+	}
+	countSteps(document.MappingValue(document.MappingValue(topNode, "runs"), "steps"))
+	return count
+}
+
+// getWriteScopes returns the scopes of the permissions with write access, or write-all
+func getWriteScopes(permissionsNode *yaml.Node) []string {
+	if permissionsNode.Kind == yaml.ScalarNode {
+		if permissionsNode.Value == "write-all" {
+			return []string{"write-all"}
+		}
+		return nil
+	}
+	var scopes []string
+	for i := 0; i+1 < len(permissionsNode.Content); i += 2 {
+		if permissionsNode.Content[i+1].Value == "write" {
+			scopes = append(scopes, permissionsNode.Content[i].Value)
+		}
+	}
+	return scopes
+}
+
+// getPinnedDependencies scores the share of the actions that are pinned
+func getPinnedDependencies(topNode *yaml.Node, detected []findings.Finding) CheckScore {
+	check := CheckScore{Name: CheckPinnedDependencies, Score: maxScore}
+	total, unpinned := countActions(topNode), 0
+	for _, finding := range detected {
+		if finding.RuleID == "unpinned-action" {
+			unpinned++
+		}
+	}
+	if total == 0 || unpinned == 0 {
+		return check
+	}
+	if unpinned > total {
+		unpinned = total
+	}
+	check.Score = round(maxScore * float64(total-unpinned) / float64(total))
+	check.Reason = fmt.Sprintf("%d of %d actions are not pinned to a commit SHA", unpinned, total)
+	return check
+}
+
+// getTokenPermissions scores the permissions of the GITHUB_TOKEN. As in Scorecard, the workflow needs top level
+// permissions, which are read only, and jobs get write access in their own permissions.
+func getTokenPermissions(topNode *yaml.Node) CheckScore {
+	check := CheckScore{Name: CheckTokenPermissions, Score: maxScore}
+	// composite actions do not have permissions
+	if document.MappingValue(topNode, "jobs") == nil {
+		return check
+	}
+	permissionsNode := document.MappingValue(topNode, "permissions")
+	if permissionsNode == nil {
+		check.Score, check.Reason = 0, "no top level permissions"
+		return check
+	}
+	scopes := getWriteScopes(permissionsNode)
+	if len(scopes) == 1 && scopes[0] == "write-all" {
+		check.Score, check.Reason = 0, "top level permissions are write-all"
+		return check
+	}
+	var reasons []string
+	if len(scopes) > 0 {
+		check.Score = 5
+		reasons = append(reasons, fmt.Sprintf("top level permissions grant write access to %s", strings.Join(scopes, ", ")))
+	}
+	jobsNode := document.MappingValue(topNode, "jobs")
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobPermissions := document.MappingValue(jobsNode.Content[i+1], "permissions")
+		if jobPermissions != nil && jobPermissions.Kind == yaml.ScalarNode && jobPermissions.Value == "write-all" {
+			check.Score = 5
+			reasons = append(reasons, fmt.Sprintf("permissions of job %s are write-all", jobsNode.Content[i].Value))
+		}
+	}
+	check.Reason = strings.Join(reasons, ", ")
+	return check
+}
+
+// getDangerousWorkflow scores the dangerous patterns, which fail the check as in Scorecard
+func getDangerousWorkflow(detected []findings.Finding) CheckScore {
+	check := CheckScore{Name: CheckDangerousWorkflow, Score: maxScore}
+	counts := map[string]int{}
+	for _, finding := range detected {
+		if finding.RuleID == "dangerous-trigger" || finding.RuleID == "script-injection" {
+			counts[finding.RuleID]++
+		}
+	}
+	if len(counts) == 0 {
+		return check
+	}
+	var reasons []string
+	for _, ruleID := range []string{"dangerous-trigger", "script-injection"} {
+		if counts[ruleID] > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d %s findings", counts[ruleID], ruleID))
+		}
+	}
+	check.Score, check.Reason = 0, strings.Join(reasons, ", ")
+	return check
+}
+
+// newScore returns the score of the checks, which is their average weighted by their risk
+func newScore(checks []CheckScore) Score {
+	total, weight := 0.0, 0.0
+	for _, check := range checks {
+		total += weights[check.Name] * check.Score
+		weight += weights[check.Name]
+	}
+	score := Score{Score: maxScore, Checks: checks}
+	if weight > 0 {
+		score.Score = round(total / weight)
+	}
+	return score
+}
+
+// Compute returns the score of the workflow or composite action, with the findings of its unpinned actions, dangerous
+// triggers and script injections
+func Compute(inputYaml string, detected []findings.Finding) (Score, error) {
+	t := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(inputYaml), &t); err != nil {
+		return Score{}, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	var topNode *yaml.Node
+	if len(t.Content) > 0 {
+		topNode = t.Content[0]
+	}
+	return newScore([]CheckScore{
+		getPinnedDependencies(topNode, detected),
+		getTokenPermissions(topNode),
+		getDangerousWorkflow(detected),
+	}), nil
+}
+
+// NewScoreChange returns the change from the score before the remediations to the score after them
+func NewScoreChange(before, after Score) *ScoreChange {
+	return &ScoreChange{Before: before, After: after, Delta: round(after.Score - before.Score)}
+}
+
+// average returns the average of the scores, by check
+func average(scores []Score) Score {
+	var names []string
+	sums := map[string]float64{}
+	for _, score := range scores {
+		for _, check := range score.Checks {
+			if _, found := sums[check.Name]; !found {
+				names = append(names, check.Name)
+			}
+			sums[check.Name] += check.Score
+		}
+	}
+	checks := make([]CheckScore, 0, len(names))
+	for _, name := range names {
+		checks = append(checks, CheckScore{Name: name, Score: round(sums[name] / float64(len(scores)))})
+	}
+	return newScore(checks)
+}
+
+// Average returns the change of the score of a repository, which is the average of the scores of its workflows, or nil
+// if no score was computed
+func Average(changes []*ScoreChange) *ScoreChange {
+	var before, after []Score
+	for _, change := range changes {
+		if change != nil {
+			before, after = append(before, change.Before), append(after, change.After)
+		}
+	}
+	if len(before) == 0 {
+		return nil
+	}
+	return NewScoreChange(average(before), average(after))
+}
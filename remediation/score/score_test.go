@@ -0,0 +1,70 @@
+package score
+
+import (
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+)
+
+func TestCompute(t *testing.T) {
+	input := `name: CI
+on: pull_request_target
+permissions:
+  contents: write
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    permissions: write-all
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@0c52d547c9bc32b1aa3301fd7a9cb496313a4491
+      - uses: ./.github/actions/build
+`
+	detected := []findings.Finding{{RuleID: "unpinned-action", Action: "actions/checkout@v4"}, {RuleID: "dangerous-trigger"},
+		{RuleID: "missing-permissions"}}
+	score, err := Compute(input, detected)
+	if err != nil {
+		t.Fatalf("Compute() unexpected error = %v", err)
+	}
+	want := []CheckScore{
+		{Name: CheckPinnedDependencies, Score: 5, Reason: "1 of 2 actions are not pinned to a commit SHA"},
+		{Name: CheckTokenPermissions, Score: 5, Reason: "top level permissions grant write access to contents, permissions of job build are write-all"},
+		{Name: CheckDangerousWorkflow, Score: 0, Reason: "1 dangerous-trigger findings"},
+	}
+	for i, check := range score.Checks {
+		if check != want[i] {
+			t.Errorf("check %d = %+v, want %+v", i, check, want[i])
+		}
+	}
+	// (5*5 + 7.5*5 + 10*0) / 22.5
+	if score.Score != 2.8 {
+		t.Errorf("Compute() score = %v, want 2.8", score.Score)
+	}
+
+	secured, err := Compute("on: push\npermissions: read-all\njobs:\n  build:\n    runs-on: ubuntu-latest\n", nil)
+	if err != nil || secured.Score != 10 {
+		t.Errorf("Compute() = %+v, %v, want a score of 10", secured, err)
+	}
+	missing, _ := Compute("on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n", nil)
+	if missing.Checks[1].Score != 0 || missing.Score != 6.7 {
+		t.Errorf("expected a score of 0 for the missing permissions, got %+v", missing)
+	}
+	if _, err := Compute("on: [push", nil); err == nil {
+		t.Errorf("expected an error for invalid yaml")
+	}
+}
+
+func TestAverage(t *testing.T) {
+	first := NewScoreChange(newScore([]CheckScore{{Name: CheckTokenPermissions, Score: 0}}), newScore([]CheckScore{{Name: CheckTokenPermissions, Score: 10}}))
+	second := NewScoreChange(newScore([]CheckScore{{Name: CheckTokenPermissions, Score: 5}}), newScore([]CheckScore{{Name: CheckTokenPermissions, Score: 10}}))
+	if first.Delta != 10 {
+		t.Errorf("NewScoreChange() delta = %v, want 10", first.Delta)
+	}
+	average := Average([]*ScoreChange{first, nil, second})
+	if average.Before.Score != 2.5 || average.After.Score != 10 || average.Delta != 7.5 {
+		t.Errorf("Average() = %+v, want 2.5 before and 10 after", average)
+	}
+	if Average([]*ScoreChange{nil}) != nil {
+		t.Errorf("expected no average without scores")
+	}
+}
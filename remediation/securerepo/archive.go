@@ -0,0 +1,219 @@
+package securerepo
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	ArchiveFormatZip   = "zip"
+	ArchiveFormatTarGz = "tar.gz"
+
+	// MaxArchiveSize is the maximum total size of the uncompressed files in an archive
+	MaxArchiveSize = 200 * 1024 * 1024
+)
+
+// archiveEntry is a file or directory in an archive, kept in order so the archive can be written back as it was
+type archiveEntry struct {
+	name      string
+	isDir     bool
+	content   []byte
+	zipHeader *zip.FileHeader
+	tarHeader *tar.Header
+}
+
+type archive struct {
+	format  string
+	entries []*archiveEntry
+	// prefix is the top level directory that all files are in, e.g. owner-repo-sha/ in GitHub tarballs
+	prefix string
+}
+
+func readAll(reader io.Reader, total *int64) ([]byte, error) {
+	content, err := io.ReadAll(io.LimitReader(reader, MaxArchiveSize-*total+1))
+	if err != nil {
+		return nil, err
+	}
+	*total += int64(len(content))
+	if *total > MaxArchiveSize {
+		return nil, fmt.Errorf("archive is larger than %d bytes", MaxArchiveSize)
+	}
+	return content, nil
+}
+
+func readZip(data []byte) (*archive, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read zip archive: %v", err)
+	}
+
+	result := &archive{format: ArchiveFormatZip}
+	var total int64
+	for _, file := range zipReader.File {
+		header := file.FileHeader
+		entry := &archiveEntry{name: file.Name, isDir: file.FileInfo().IsDir(), zipHeader: &header}
+		if !entry.isDir {
+			reader, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("unable to read %s from zip archive: %v", file.Name, err)
+			}
+			entry.content, err = readAll(reader, &total)
+			reader.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		result.entries = append(result.entries, entry)
+	}
+	result.prefix = getPrefix(result.entries)
+
+	return result, nil
+}
+
+func readTarGz(data []byte) (*archive, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read gzip archive: %v", err)
+	}
+	defer gzipReader.Close()
+
+	result := &archive{format: ArchiveFormatTarGz}
+	var total int64
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read tar archive: %v", err)
+		}
+
+		entry := &archiveEntry{name: header.Name, isDir: header.Typeflag == tar.TypeDir, tarHeader: header}
+		if header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA {
+			entry.content, err = readAll(tarReader, &total)
+			if err != nil {
+				return nil, err
+			}
+		}
+		result.entries = append(result.entries, entry)
+	}
+	result.prefix = getPrefix(result.entries)
+
+	return result, nil
+}
+
+func readArchive(data []byte, format string) (*archive, error) {
+	switch format {
+	case ArchiveFormatZip:
+		return readZip(data)
+	case ArchiveFormatTarGz, "tgz":
+		return readTarGz(data)
+	}
+	return nil, fmt.Errorf("unsupported archive format %s, must be %s or %s", format, ArchiveFormatZip, ArchiveFormatTarGz)
+}
+
+// getPrefix returns the top level directory if all entries are in the same directory, as in archives downloaded from GitHub
+func getPrefix(entries []*archiveEntry) string {
+	prefix := ""
+	for _, entry := range entries {
+		// skip pax global headers, which GitHub adds to tarballs
+		if entry.tarHeader != nil && entry.tarHeader.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		index := strings.Index(entry.name, "/")
+		if index == -1 || (entry.name[:index+1] != prefix && prefix != "") {
+			return ""
+		}
+		prefix = entry.name[:index+1]
+	}
+	return prefix
+}
+
+// isFile returns true if the entry is a regular file whose content was read. The symlinks and the other entries that
+// are not regular files are written back unchanged, but are not remediated.
+func (entry *archiveEntry) isFile() bool {
+	if entry.isDir {
+		return false
+	}
+	if entry.tarHeader != nil {
+		return entry.tarHeader.Typeflag == tar.TypeReg || entry.tarHeader.Typeflag == tar.TypeRegA
+	}
+	return entry.zipHeader.Mode().IsRegular()
+}
+
+// files returns the content of the files in the archive, keyed by path relative to the prefix
+func (a *archive) files() map[string]string {
+	files := make(map[string]string)
+	for _, entry := range a.entries {
+		if entry.isFile() {
+			files[strings.TrimPrefix(entry.name, a.prefix)] = string(entry.content)
+		}
+	}
+	return files
+}
+
+// write returns the archive with the changed files replaced and the new files added, in the original format
+func (a *archive) write(changedFiles map[string]string, newFiles []string) ([]byte, error) {
+	for _, entry := range a.entries {
+		if content, found := changedFiles[strings.TrimPrefix(entry.name, a.prefix)]; found && entry.isFile() {
+			entry.content = []byte(content)
+		}
+	}
+	for _, path := range newFiles {
+		entry := &archiveEntry{name: a.prefix + path, content: []byte(changedFiles[path])}
+		if a.format == ArchiveFormatZip {
+			entry.zipHeader = &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+			entry.zipHeader.SetMode(0644)
+		} else {
+			entry.tarHeader = &tar.Header{Name: entry.name, Mode: 0644, Typeflag: tar.TypeReg}
+		}
+		a.entries = append(a.entries, entry)
+	}
+
+	var buffer bytes.Buffer
+	if a.format == ArchiveFormatZip {
+		zipWriter := zip.NewWriter(&buffer)
+		for _, entry := range a.entries {
+			writer, err := zipWriter.CreateHeader(entry.zipHeader)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := writer.Write(entry.content); err != nil {
+				return nil, err
+			}
+		}
+		if err := zipWriter.Close(); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, entry := range a.entries {
+		if entry.isFile() {
+			entry.tarHeader.Size = int64(len(entry.content))
+		}
+		if err := tarWriter.WriteHeader(entry.tarHeader); err != nil {
+			return nil, err
+		}
+		if entry.isFile() {
+			if _, err := tarWriter.Write(entry.content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,651 @@
+package securerepo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/argo"
+	"github.com/step-security/secure-repo/remediation/azurepipelines"
+	"github.com/step-security/secure-repo/remediation/bitbucketpipelines"
+	"github.com/step-security/secure-repo/remediation/buildkite"
+	"github.com/step-security/secure-repo/remediation/cache"
+	"github.com/step-security/secure-repo/remediation/circleci"
+	"github.com/step-security/secure-repo/remediation/codeowners"
+	"github.com/step-security/secure-repo/remediation/compositeaction"
+	"github.com/step-security/secure-repo/remediation/dependabot"
+	"github.com/step-security/secure-repo/remediation/diff"
+	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/drone"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/gitlabci"
+	"github.com/step-security/secure-repo/remediation/jenkins"
+	"github.com/step-security/secure-repo/remediation/lineending"
+	"github.com/step-security/secure-repo/remediation/precommit"
+	"github.com/step-security/secure-repo/remediation/renovate"
+	"github.com/step-security/secure-repo/remediation/repoconfig"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/sarif"
+	"github.com/step-security/secure-repo/remediation/score"
+	"github.com/step-security/secure-repo/remediation/tekton"
+	"github.com/step-security/secure-repo/remediation/travis"
+	"github.com/step-security/secure-repo/remediation/workers"
+	"github.com/step-security/secure-repo/remediation/workflow"
+)
+
+const (
+	FileTypeWorkflow        = "workflow"
+	FileTypeCompositeAction = "composite-action"
+	FileTypeDockerfile      = "dockerfile"
+	FileTypeDependabot      = "dependabot"
+	FileTypeCodeowners      = "codeowners"
+	FileTypeGitLabCI        = "gitlab-ci"
+	FileTypeAzurePipelines  = "azure-pipelines"
+	FileTypeCircleCI        = "circleci"
+	FileTypeBitbucket       = "bitbucket-pipelines"
+	FileTypeJenkins         = "jenkinsfile"
+	FileTypeTekton          = "tekton"
+	FileTypeArgo            = "argo-workflows"
+	FileTypeDrone           = "drone"
+	FileTypeTravis          = "travis"
+	FileTypePrecommit       = "precommit"
+	FileTypeRenovate        = "renovate"
+	FileTypeBuildkite       = "buildkite"
+
+	DependabotConfigPath = ".github/dependabot.yml"
+	CodeownersPath       = ".github/CODEOWNERS"
+)
+
+// File is a file in the repository, with its path relative to the root of the repository
+type File struct {
+	Path    string
+	Content string
+}
+
+type SecureRepoRequest struct {
+	// Files maps the path of each file in the repository to its content. Either Files, FileList or Archive must be set.
+	Files map[string]string `json:",omitempty"`
+	// FileList is the files as a list, e.g. the workflows, composite actions and Dockerfiles of a repository
+	FileList []File `json:",omitempty"`
+	// Archive is a zip or tar.gz of the repository, e.g. as downloaded from GitHub
+	Archive       []byte `json:",omitempty"`
+	ArchiveFormat string `json:",omitempty"`
+}
+
+type FileReport struct {
+	Path      string
+	FileType  string
+	IsChanged bool
+	IsNew     bool
+	HasErrors bool
+	// FailedStrictChecks is true with strict=true if a module failed, or a construct of the workflow was not resolved
+	FailedStrictChecks bool
+	Error              string             `json:",omitempty"`
+	Findings           []findings.Finding `json:",omitempty"`
+	// Score is the change of the score of a workflow, if computeScore=true is passed
+	Score *score.ScoreChange `json:",omitempty"`
+	// hunks are the changes made to the file, which are added to the SARIF log as fixes
+	hunks []diff.Hunk
+	// modules are the changes of each remediation of a workflow, and statuses whether each remediation that ran changed
+	// it, left it unchanged or failed, which version 2 of the API returns
+	modules  []report.Module
+	statuses []report.Status
+}
+
+// Modules returns the changes, skipped items and errors of each remediation of a workflow
+func (r FileReport) Modules() []report.Module {
+	return r.modules
+}
+
+// Statuses returns whether each remediation that ran on a workflow changed it, left it unchanged or failed
+func (r FileReport) Statuses() []report.Status {
+	return r.statuses
+}
+
+type SecureRepoResponse struct {
+	// Files has the content of the changed and new files, keyed by path
+	Files map[string]string
+	// Diffs has a unified diff of each changed and new file instead of its content, if output=diff is passed
+	Diffs map[string]string `json:",omitempty"`
+	// Archive is the input archive with the changes applied, if an archive was sent
+	Archive            []byte `json:",omitempty"`
+	Report             []FileReport
+	IsChanged          bool
+	HasErrors          bool
+	FailedStrictChecks bool
+	MissingActions     []string
+	// Score is the average of the scores of the workflows, if computeScore=true is passed
+	Score *score.ScoreChange `json:",omitempty"`
+}
+
+// isDockerfile returns true for Dockerfile, Dockerfile.prod, prod.Dockerfile and prod.dockerfile
+func isDockerfile(filePath string) bool {
+	name := path.Base(filePath)
+	return name == "Dockerfile" || strings.HasPrefix(name, "Dockerfile.") || strings.HasSuffix(strings.ToLower(name), ".dockerfile")
+}
+
+// GetFileType returns the type of file based on its path and content, or an empty string if there is no remediation for it
+func GetFileType(filePath string, content string) string {
+	name := path.Base(filePath)
+	isYaml := strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")
+
+	switch {
+	case isYaml && path.Dir(filePath) == ".github/workflows":
+		return FileTypeWorkflow
+	case filePath == ".github/dependabot.yml" || filePath == ".github/dependabot.yaml":
+		return FileTypeDependabot
+	case renovate.IsConfigPath(filePath):
+		return FileTypeRenovate
+	case filePath == gitlabci.ConfigPath:
+		return FileTypeGitLabCI
+	case azurepipelines.IsPipeline(filePath):
+		return FileTypeAzurePipelines
+	case filePath == circleci.ConfigPath:
+		return FileTypeCircleCI
+	case filePath == bitbucketpipelines.ConfigPath:
+		return FileTypeBitbucket
+	case filePath == drone.ConfigPath:
+		return FileTypeDrone
+	case filePath == travis.ConfigPath:
+		return FileTypeTravis
+	case filePath == precommit.ConfigPath:
+		return FileTypePrecommit
+	case buildkite.IsPipeline(filePath):
+		return FileTypeBuildkite
+	case jenkins.IsJenkinsfile(filePath):
+		return FileTypeJenkins
+	case isYaml && tekton.IsTektonResource(content):
+		return FileTypeTekton
+	case isYaml && argo.IsWorkflow(content):
+		return FileTypeArgo
+	case filePath == "CODEOWNERS" || filePath == ".github/CODEOWNERS" || filePath == "docs/CODEOWNERS":
+		return FileTypeCodeowners
+	case name == "action.yml" || name == "action.yaml":
+		if compositeaction.IsCompositeAction(content) {
+			return FileTypeCompositeAction
+		}
+	case isDockerfile(filePath):
+		return FileTypeDockerfile
+	}
+	return ""
+}
+
+// ShouldFetch returns true if the file may have remediations or configures them, for integrations that fetch the files
+// of a repository. Composite actions are found by their name, since the content is needed to tell them apart from other
+// actions, and Tekton resources and Argo workflows by their directory.
+func ShouldFetch(filePath string) bool {
+	for _, configPath := range repoconfig.ConfigPaths {
+		if filePath == configPath {
+			return true
+		}
+	}
+	name := path.Base(filePath)
+	return GetFileType(filePath, "") != "" || name == "action.yml" || name == "action.yaml" || tekton.IsTektonPath(filePath) ||
+		argo.IsArgoPath(filePath)
+}
+
+// getDependabotEcosystems returns the ecosystems to keep up to date, for the GitHub Actions and Dockerfiles in the repository
+func getDependabotEcosystems(report []FileReport) []dependabot.Ecosystem {
+	var ecosystems []dependabot.Ecosystem
+	directories := make(map[string]bool)
+	hasActions := false
+	for _, fileReport := range report {
+		switch fileReport.FileType {
+		case FileTypeWorkflow, FileTypeCompositeAction:
+			hasActions = true
+		case FileTypeDockerfile:
+			directory := "/" + strings.TrimPrefix(path.Dir(fileReport.Path), ".")
+			directory = strings.TrimSuffix(directory, "/")
+			if directory == "" {
+				directory = "/"
+			}
+			if !directories[directory] {
+				directories[directory] = true
+				ecosystems = append(ecosystems, dependabot.Ecosystem{PackageEcosystem: "docker", Directory: directory, Interval: "daily"})
+			}
+		}
+	}
+	if hasActions {
+		ecosystems = append([]dependabot.Ecosystem{{PackageEcosystem: "github-actions", Directory: "/", Interval: "daily"}}, ecosystems...)
+	}
+	return ecosystems
+}
+
+// secureFile runs the remediations for the type of file on the file with LF line endings, and returns the output with
+// the line endings of the file.
+func secureFile(ctx context.Context, queryStringParams map[string]string, fileReport *FileReport, content string, svc dynamodbiface.DynamoDBAPI, config *repoconfig.Config) (string, []string, error) {
+	output, missingActions, err := secureContent(ctx, queryStringParams, fileReport, lineending.Normalize(content), svc, config)
+	return lineending.Restore(content, output), missingActions, err
+}
+
+// secureContent runs the remediations for the type of file. The exempted actions, pin policy and runner labels of the
+// repository configuration are passed to the remediations, and exempted jobs are restored after the workflow is remediated.
+func secureContent(ctx context.Context, queryStringParams map[string]string, fileReport *FileReport, content string, svc dynamodbiface.DynamoDBAPI, config *repoconfig.Config) (string, []string, error) {
+	exemptedActions, pinToImmutable, runnerLabelMap := []string{}, false, map[string]string{}
+	if config != nil {
+		exemptedActions, pinToImmutable = config.Exemptions.Actions, config.Pin.Immutable
+		if config.RunnerLabels != nil {
+			runnerLabelMap = config.RunnerLabels
+		}
+	}
+	// the files left when the context is done are not remediated, since their requests would fail
+	if err := ctx.Err(); err != nil {
+		return content, nil, err
+	}
+
+	switch fileReport.FileType {
+	case FileTypeWorkflow:
+		secureWorkflowReponse, err := workflow.SecureWorkflowCached(ctx, cache.Default(), queryStringParams, content, svc,
+			workflow.Options{ExemptedActions: exemptedActions, PinToImmutable: pinToImmutable, RunnerLabels: runnerLabelMap})
+		if err != nil {
+			return content, nil, err
+		}
+		output, err := config.RestoreExemptedJobs(content, secureWorkflowReponse.FinalOutput)
+		if err != nil {
+			return content, nil, err
+		}
+		fileReport.Findings = config.FilterFindings(secureWorkflowReponse.Findings)
+		fileReport.Score = secureWorkflowReponse.Score
+		if secureWorkflowReponse.Report != nil {
+			secureWorkflowReponse.Report.SetFile(fileReport.Path)
+			fileReport.modules, fileReport.statuses = secureWorkflowReponse.Report.Modules, secureWorkflowReponse.Report.Statuses
+		}
+		// already having permissions is reported as an error, but needs no action
+		fileReport.HasErrors = secureWorkflowReponse.HasErrors && !secureWorkflowReponse.AlreadyHasPermissions
+		fileReport.FailedStrictChecks = secureWorkflowReponse.FailedStrictChecks
+		return output, secureWorkflowReponse.MissingActions, nil
+	case FileTypeCompositeAction:
+		secureCompositeActionResponse, err := compositeaction.SecureCompositeAction(queryStringParams, content, exemptedActions, pinToImmutable, ctx)
+		if err != nil {
+			return content, nil, err
+		}
+		fileReport.Findings = config.FilterFindings(secureCompositeActionResponse.Findings)
+		fileReport.HasErrors = secureCompositeActionResponse.HasErrors
+		return secureCompositeActionResponse.FinalOutput, nil, nil
+	case FileTypeDockerfile:
+		config := docker.DockerfileConfig{AddNonRootUser: queryStringParams["addNonRootUser"] == "true"}
+		secureDockerfileResponse, err := docker.SecureDockerFile(ctx, content, config)
+		if err != nil {
+			return content, nil, err
+		}
+		return secureDockerfileResponse.FinalOutput, nil, nil
+	case FileTypeGitLabCI:
+		secureConfigResponse, err := gitlabci.SecureConfig(ctx, queryStringParams, content)
+		if err != nil {
+			return content, nil, err
+		}
+		fileReport.Findings = config.FilterFindings(secureConfigResponse.Findings)
+		return secureConfigResponse.FinalOutput, nil, nil
+	case FileTypeAzurePipelines:
+		securePipelineResponse, err := azurepipelines.SecurePipeline(ctx, queryStringParams, content)
+		if err != nil {
+			return content, nil, err
+		}
+		fileReport.Findings = config.FilterFindings(securePipelineResponse.Findings)
+		return securePipelineResponse.FinalOutput, nil, nil
+	case FileTypeCircleCI:
+		secureConfigResponse, err := circleci.SecureConfig(ctx, queryStringParams, content)
+		if err != nil {
+			return content, nil, err
+		}
+		fileReport.Findings = config.FilterFindings(secureConfigResponse.Findings)
+		return secureConfigResponse.FinalOutput, nil, nil
+	case FileTypeBitbucket:
+		securePipelineResponse, err := bitbucketpipelines.SecurePipeline(ctx, queryStringParams, content)
+		if err != nil {
+			return content, nil, err
+		}
+		fileReport.Findings = config.FilterFindings(securePipelineResponse.Findings)
+		return securePipelineResponse.FinalOutput, nil, nil
+	case FileTypeJenkins:
+		secureJenkinsfileResponse, err := jenkins.SecureJenkinsfile(ctx, queryStringParams, content)
+		if err != nil {
+			return content, nil, err
+		}
+		fileReport.Findings = config.FilterFindings(secureJenkinsfileResponse.Findings)
+		return secureJenkinsfileResponse.FinalOutput, nil, nil
+	case FileTypeTekton:
+		secureResourceResponse, err := tekton.SecureResource(ctx, queryStringParams, content)
+		if err != nil {
+			return content, nil, err
+		}
+		fileReport.Findings = config.FilterFindings(secureResourceResponse.Findings)
+		return secureResourceResponse.FinalOutput, nil, nil
+	case FileTypeArgo:
+		secureWorkflowResponse, err := argo.SecureWorkflow(ctx, queryStringParams, content)
+		if err != nil {
+			return content, nil, err
+		}
+		fileReport.Findings = config.FilterFindings(secureWorkflowResponse.Findings)
+		return secureWorkflowResponse.FinalOutput, nil, nil
+	case FileTypeDrone:
+		secureConfigResponse, err := drone.SecureConfig(ctx, queryStringParams, content)
+		if err != nil {
+			return content, nil, err
+		}
+		fileReport.Findings = config.FilterFindings(secureConfigResponse.Findings)
+		return secureConfigResponse.FinalOutput, nil, nil
+	case FileTypePrecommit:
+		securePrecommitConfigResponse, err := precommit.SecurePrecommitConfig(ctx, queryStringParams, content)
+		if err != nil {
+			return content, nil, err
+		}
+		fileReport.Findings = config.FilterFindings(securePrecommitConfigResponse.Findings)
+		return securePrecommitConfigResponse.FinalOutput, nil, nil
+	case FileTypeBuildkite:
+		securePipelineResponse, err := buildkite.SecurePipeline(ctx, queryStringParams, content)
+		if err != nil {
+			return content, nil, err
+		}
+		fileReport.Findings = config.FilterFindings(securePipelineResponse.Findings)
+		return securePipelineResponse.FinalOutput, nil, nil
+	}
+	return content, nil, nil
+}
+
+// GetSARIF returns the findings in the report as a SARIF log, which can be uploaded to GitHub code scanning.
+// The results of the fixed findings have the changes made to the file as fixes.
+func GetSARIF(report []FileReport) *sarif.Log {
+	var files []sarif.File
+	for _, fileReport := range report {
+		files = append(files, sarif.File{Path: fileReport.Path, Findings: fileReport.Findings, Hunks: fileReport.hunks})
+	}
+	return sarif.NewLog(files)
+}
+
+// migrateTravis converts the Travis CI configuration to a workflow, which is remediated like the other workflows, so the
+// migration and the hardening of the workflow are in the same pull request. The keys that are not converted are reported
+// in the report of the configuration. It returns the new workflow, or an empty string if it is not added.
+func migrateTravis(ctx context.Context, queryStringParams map[string]string, files map[string]string, travisPath string, addReport func(FileReport, string, string, error),
+	svc dynamodbiface.DynamoDBAPI, config *repoconfig.Config) (string, []string) {
+	travisReport := FileReport{Path: travisPath, FileType: FileTypeTravis}
+	if _, found := files[travis.WorkflowPath]; found {
+		addReport(travisReport, files[travisPath], files[travisPath], fmt.Errorf("unable to migrate %s: %s already exists", travisPath, travis.WorkflowPath))
+		return "", nil
+	}
+	convertResponse, err := travis.ConvertConfig(files[travisPath])
+	if err != nil {
+		addReport(travisReport, files[travisPath], files[travisPath], err)
+		return "", nil
+	}
+	travisReport.Findings = config.FilterFindings(convertResponse.Findings)
+	addReport(travisReport, files[travisPath], files[travisPath], nil)
+
+	fileReport := FileReport{Path: travis.WorkflowPath, FileType: FileTypeWorkflow, IsNew: true}
+	output, fileMissingActions, err := secureFile(ctx, queryStringParams, &fileReport, convertResponse.Workflow, svc, config)
+	addReport(fileReport, "", output, err)
+	return output, fileMissingActions
+}
+
+// getRenovateManagers returns the managers of Renovate of the ecosystems Dependabot would keep up to date
+func getRenovateManagers(ecosystems []dependabot.Ecosystem) []string {
+	var managers []string
+	hasDockerfiles := false
+	for _, ecosystem := range ecosystems {
+		switch ecosystem.PackageEcosystem {
+		case "github-actions":
+			managers = append(managers, renovate.ManagerGitHubActions)
+		case "docker":
+			hasDockerfiles = true
+		}
+	}
+	if hasDockerfiles {
+		managers = append(managers, renovate.ManagerDockerfile)
+	}
+	return managers
+}
+
+func updateDependabotConfig(content string, ecosystems []dependabot.Ecosystem) (string, error) {
+	request, err := json.Marshal(dependabot.UpdateDependabotConfigRequest{Ecosystems: ecosystems, Content: lineending.Normalize(content)})
+	if err != nil {
+		return content, err
+	}
+	response, err := dependabot.UpdateDependabotConfig(string(request))
+	if err != nil {
+		return content, err
+	}
+	return lineending.Restore(content, response.FinalOutput), nil
+}
+
+// getFiles returns the files of the request keyed by path. Paths in the file list are cleaned, so ./Dockerfile is Dockerfile.
+func getFiles(request SecureRepoRequest) (map[string]string, error) {
+	if len(request.FileList) == 0 {
+		return request.Files, nil
+	}
+	files := make(map[string]string, len(request.Files)+len(request.FileList))
+	for filePath, content := range request.Files {
+		files[filePath] = content
+	}
+	for _, file := range request.FileList {
+		filePath := strings.TrimPrefix(path.Clean(file.Path), "/")
+		if file.Path == "" || filePath == "." || filePath == ".." || strings.HasPrefix(filePath, "../") {
+			return nil, fmt.Errorf("invalid path %q", file.Path)
+		}
+		if _, found := files[filePath]; found {
+			return nil, fmt.Errorf("file %s is sent more than once", filePath)
+		}
+		files[filePath] = file.Content
+	}
+	return files, nil
+}
+
+// SecureRepo finds the workflows, composite actions, Dockerfiles, dependabot config and CODEOWNERS in a repository,
+// runs the enabled remediations on each of them, and returns the changed files along with a report for each file.
+// Query parameters are passed on to SecureWorkflow. Dependabot config is updated unless updateDependabotConfig is false,
+// or with updateRenovateConfig=true the Renovate config is updated instead, and CODEOWNERS is updated if codeowners has a comma separated list of owners. With output=diff, unified diffs of the
+// changed files are returned instead of their content. With dryRun=true, all checks are run and only the diffs are returned.
+// If the repository has a .github/stepsecurity.yml, its remediations override the query parameters, and its exemptions,
+// pin policy and runner labels are applied to every file. The error of the context is returned when it is done before
+// the files are remediated.
+func SecureRepo(ctx context.Context, queryStringParams map[string]string, request SecureRepoRequest, svc dynamodbiface.DynamoDBAPI) (*SecureRepoResponse, error) {
+	files, err := getFiles(request)
+	if err != nil {
+		return nil, err
+	}
+	var repoArchive *archive
+	if len(request.Archive) > 0 {
+		repoArchive, err = readArchive(request.Archive, request.ArchiveFormat)
+		if err != nil {
+			return nil, err
+		}
+		files = repoArchive.files()
+	}
+
+	// the configuration of the repository overrides the query parameters
+	_, config, err := repoconfig.FindConfig(files)
+	if err != nil {
+		return nil, err
+	}
+	queryStringParams = config.ApplyRemediations(queryStringParams)
+
+	paths := make([]string, 0, len(files))
+	for filePath := range files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+
+	// missing actions are stored once for the repository below
+	workflowParams := map[string]string{}
+	for key, value := range queryStringParams {
+		workflowParams[key] = value
+	}
+	workflowParams["ignoreMissingKBs"] = "true"
+	// for a dry run, the files are remediated with all checks enabled, and only the diffs are returned
+	dryRun := queryStringParams["dryRun"] == "true"
+	if dryRun {
+		workflowParams = workflow.GetDryRunParams(workflowParams)
+		delete(workflowParams, "dryRun")
+	}
+
+	response := &SecureRepoResponse{Files: map[string]string{}}
+	missingActions := make(map[string]bool)
+	var newFiles []string
+
+	addReport := func(fileReport FileReport, content, output string, err error) {
+		if err != nil {
+			fileReport.HasErrors = true
+			fileReport.Error = err.Error()
+		}
+		if output != content {
+			fileReport.IsChanged = true
+			fileReport.hunks = diff.Hunks(content, output)
+			response.Files[fileReport.Path] = output
+			response.IsChanged = true
+		}
+		response.HasErrors = response.HasErrors || fileReport.HasErrors
+		response.FailedStrictChecks = response.FailedStrictChecks || fileReport.FailedStrictChecks
+		response.Report = append(response.Report, fileReport)
+	}
+
+	dependabotPath, renovatePath, codeownersPath, travisPath := "", "", "", ""
+	var securedFiles []FileReport
+	for _, filePath := range paths {
+		fileType := GetFileType(filePath, files[filePath])
+		if config.IsFileExempted(filePath) {
+			continue
+		}
+		switch fileType {
+		case "":
+			continue
+		case FileTypeDependabot:
+			// updated after the other files, since the ecosystems depend on them
+			dependabotPath = filePath
+			continue
+		case FileTypeRenovate:
+			// the first path Renovate reads its configuration from is updated, like the dependabot config
+			if renovatePath == "" {
+				renovatePath = filePath
+			}
+			continue
+		case FileTypeCodeowners:
+			if codeownersPath == "" || filePath == CodeownersPath {
+				codeownersPath = filePath
+			}
+			continue
+		case FileTypeTravis:
+			// converted to a new workflow after the other files, if migrateTravis is true
+			travisPath = filePath
+			continue
+		}
+
+		securedFiles = append(securedFiles, FileReport{Path: filePath, FileType: fileType})
+	}
+
+	// the files are remediated by a pool of workers, which share the caches of the responses and of the refs, and the
+	// results are added in the order of the paths, so the response does not depend on which file is done first
+	type securedFile struct {
+		report         FileReport
+		output         string
+		missingActions []string
+		err            error
+	}
+	results := workers.Map(securedFiles, workers.Count(), func(fileReport FileReport) securedFile {
+		output, fileMissingActions, err := secureFile(ctx, workflowParams, &fileReport, files[fileReport.Path], svc, config)
+		return securedFile{report: fileReport, output: output, missingActions: fileMissingActions, err: err}
+	})
+	for _, result := range results {
+		for _, action := range result.missingActions {
+			if !missingActions[action] {
+				missingActions[action] = true
+				response.MissingActions = append(response.MissingActions, action)
+			}
+		}
+		addReport(result.report, files[result.report.Path], result.output, result.err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if travisPath != "" && queryStringParams["migrateTravis"] == "true" {
+		output, fileMissingActions := migrateTravis(ctx, workflowParams, files, travisPath, addReport, svc, config)
+		if output != "" {
+			newFiles = append(newFiles, travis.WorkflowPath)
+		}
+		for _, action := range fileMissingActions {
+			if !missingActions[action] {
+				missingActions[action] = true
+				response.MissingActions = append(response.MissingActions, action)
+			}
+		}
+	}
+
+	if queryStringParams["updateRenovateConfig"] == "true" {
+		// for repositories that keep their dependencies up to date with Renovate instead of Dependabot
+		managers := getRenovateManagers(getDependabotEcosystems(response.Report))
+		if len(managers) > 0 {
+			fileReport := FileReport{Path: renovatePath, FileType: FileTypeRenovate}
+			if renovatePath == "" {
+				fileReport.Path = renovate.ConfigPath
+				fileReport.IsNew = true
+			}
+			content := files[renovatePath]
+			renovateResponse, err := renovate.UpdateRenovateConfig(lineending.Normalize(content), managers)
+			output := lineending.Restore(content, renovateResponse.FinalOutput)
+			if fileReport.IsNew && output != content {
+				newFiles = append(newFiles, fileReport.Path)
+			}
+			addReport(fileReport, content, output, err)
+		}
+	} else if queryStringParams["updateDependabotConfig"] != "false" {
+		ecosystems := getDependabotEcosystems(response.Report)
+		if len(ecosystems) > 0 {
+			fileReport := FileReport{Path: dependabotPath, FileType: FileTypeDependabot}
+			if dependabotPath == "" {
+				fileReport.Path = DependabotConfigPath
+				fileReport.IsNew = true
+			}
+			content := files[dependabotPath]
+			output, err := updateDependabotConfig(content, ecosystems)
+			if fileReport.IsNew && output != content {
+				newFiles = append(newFiles, fileReport.Path)
+			}
+			addReport(fileReport, content, output, err)
+		}
+	}
+
+	if owners := queryStringParams["codeowners"]; owners != "" {
+		fileReport := FileReport{Path: codeownersPath, FileType: FileTypeCodeowners}
+		if codeownersPath == "" {
+			fileReport.Path = CodeownersPath
+			fileReport.IsNew = true
+		}
+		content := files[codeownersPath]
+		output, _, err := codeowners.AddCodeowners(lineending.Normalize(content), strings.Split(owners, ","), nil)
+		output = lineending.Restore(content, output)
+		if fileReport.IsNew && output != content {
+			newFiles = append(newFiles, fileReport.Path)
+		}
+		addReport(fileReport, content, output, err)
+	}
+
+	var scores []*score.ScoreChange
+	for _, fileReport := range response.Report {
+		scores = append(scores, fileReport.Score)
+	}
+	response.Score = score.Average(scores)
+
+	// the diffs replace the content of the files and the archive
+	if queryStringParams["output"] == "diff" || dryRun {
+		response.Diffs = map[string]string{}
+		for filePath, content := range response.Files {
+			response.Diffs[filePath] = diff.Unified(filePath, files[filePath], content)
+		}
+		response.Files = map[string]string{}
+	} else if repoArchive != nil {
+		output, err := repoArchive.write(response.Files, newFiles)
+		if err != nil {
+			return nil, fmt.Errorf("unable to write archive: %v", err)
+		}
+		response.Archive = output
+	}
+
+	if len(response.MissingActions) > 0 && queryStringParams["ignoreMissingKBs"] != "true" && svc != nil {
+		workflow.StoreMissingActions(response.MissingActions, svc)
+	}
+
+	return response, nil
+}
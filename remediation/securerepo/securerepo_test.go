@@ -0,0 +1,637 @@
+package securerepo
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/workers"
+)
+
+const inputDirectory = "../../testfiles/securerepo/input"
+const outputDirectory = "../../testfiles/securerepo/output"
+
+var queryParams = map[string]string{
+	"pinActions":        "false",
+	"addHardenRunner":   "false",
+	"addProjectComment": "false",
+	"addNonRootUser":    "true",
+	"codeowners":        "@org/security",
+}
+
+func readFiles(directory string) map[string]string {
+	files := make(map[string]string)
+	err := filepath.Walk(directory, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		relativePath, _ := filepath.Rel(directory, filePath)
+		files[filepath.ToSlash(relativePath)] = string(content)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return files
+}
+
+func checkResponse(t *testing.T, response *SecureRepoResponse) {
+	expectedFiles := readFiles(outputDirectory)
+	for filePath, expectedContent := range expectedFiles {
+		if response.Files[filePath] != expectedContent {
+			t.Errorf("test failed %s did not match expected output\n%s", filePath, response.Files[filePath])
+		}
+	}
+
+	// the new dependabot config and CODEOWNERS are also returned
+	if len(response.Files) != len(expectedFiles)+2 {
+		t.Errorf("expected %d changed files, got %d", len(expectedFiles)+2, len(response.Files))
+	}
+
+	for _, newFile := range []string{DependabotConfigPath, CodeownersPath} {
+		if _, found := response.Files[newFile]; !found {
+			t.Errorf("expected %s to be added", newFile)
+		}
+	}
+
+	if len(response.Report) != 5 {
+		t.Errorf("expected 5 files in report, got %d: %+v", len(response.Report), response.Report)
+	}
+
+	if !response.IsChanged || response.HasErrors {
+		t.Errorf("expected IsChanged without errors, got IsChanged %v, HasErrors %v", response.IsChanged, response.HasErrors)
+	}
+}
+
+func TestSecureRepoFiles(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	response, err := SecureRepo(context.Background(), queryParams, SecureRepoRequest{Files: readFiles(inputDirectory)}, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+
+	checkResponse(t, response)
+
+	if response.Files[DependabotConfigPath] != "version: 2\nupdates:\n  - package-ecosystem: github-actions\n    directory: /\n    schedule:\n      interval: daily\n\n  - package-ecosystem: docker\n    directory: /\n    schedule:\n      interval: daily\n" {
+		t.Errorf("unexpected dependabot config\n%s", response.Files[DependabotConfigPath])
+	}
+}
+
+func TestSecureRepoLineEndings(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	files := readFiles(inputDirectory)
+	for filePath, content := range files {
+		files[filePath] = strings.ReplaceAll(content, "\n", "\r\n")
+	}
+	response, err := SecureRepo(context.Background(), queryParams, SecureRepoRequest{Files: files}, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+
+	// the files authored on Windows are returned with CRLF line endings, and the new files with LF line endings
+	for filePath, expectedContent := range readFiles(outputDirectory) {
+		if want := strings.ReplaceAll(expectedContent, "\n", "\r\n"); response.Files[filePath] != want {
+			t.Errorf("%s = %q, want %q", filePath, response.Files[filePath], want)
+		}
+	}
+	if strings.Contains(response.Files[CodeownersPath], "\r") {
+		t.Errorf("unexpected CRLF line endings in the new %s", CodeownersPath)
+	}
+}
+
+func TestSecureRepoArchive(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	files := readFiles(inputDirectory)
+	const prefix = "owner-repo-0123456/"
+
+	var zipBuffer bytes.Buffer
+	zipWriter := zip.NewWriter(&zipBuffer)
+	var tarBuffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&tarBuffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for filePath, content := range files {
+		writer, _ := zipWriter.Create(prefix + filePath)
+		writer.Write([]byte(content))
+		tarWriter.WriteHeader(&tar.Header{Name: prefix + filePath, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tarWriter.Write([]byte(content))
+	}
+	zipWriter.Close()
+	tarWriter.Close()
+	gzipWriter.Close()
+
+	tests := []struct {
+		format  string
+		archive []byte
+	}{
+		{format: ArchiveFormatZip, archive: zipBuffer.Bytes()},
+		{format: ArchiveFormatTarGz, archive: tarBuffer.Bytes()},
+	}
+
+	for _, test := range tests {
+		response, err := SecureRepo(context.Background(), queryParams, SecureRepoRequest{Archive: test.archive, ArchiveFormat: test.format}, nil)
+		if err != nil {
+			t.Fatalf("Error not expected: %v", err)
+		}
+
+		checkResponse(t, response)
+
+		outputArchive, err := readArchive(response.Archive, test.format)
+		if err != nil {
+			t.Fatalf("Error reading output archive: %v", err)
+		}
+		if outputArchive.prefix != prefix {
+			t.Errorf("expected prefix %s, got %s", prefix, outputArchive.prefix)
+		}
+
+		outputFiles := outputArchive.files()
+		if len(outputFiles) != len(files)+2 {
+			t.Errorf("expected %d files in %s archive, got %d", len(files)+2, test.format, len(outputFiles))
+		}
+		for filePath, content := range response.Files {
+			if outputFiles[filePath] != content {
+				t.Errorf("%s in %s archive did not match the changed file", filePath, test.format)
+			}
+		}
+		if outputFiles["README.md"] != files["README.md"] {
+			t.Errorf("README.md in %s archive was changed", test.format)
+		}
+	}
+}
+
+func TestSecureRepoArchiveSymlink(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	const workflow = "on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make\n"
+	var zipBuffer bytes.Buffer
+	zipWriter := zip.NewWriter(&zipBuffer)
+	writer, _ := zipWriter.Create("repo/.github/workflows/ci.yml")
+	writer.Write([]byte(workflow))
+	// the content of a symlink is its target, which is not remediated even if it is a workflow
+	header := &zip.FileHeader{Name: "repo/.github/workflows/link.yml"}
+	header.SetMode(os.ModeSymlink | 0777)
+	writer, _ = zipWriter.CreateHeader(header)
+	writer.Write([]byte(workflow))
+	zipWriter.Close()
+
+	response, err := SecureRepo(context.Background(), queryParams, SecureRepoRequest{Archive: zipBuffer.Bytes(), ArchiveFormat: ArchiveFormatZip}, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if _, found := response.Files[".github/workflows/link.yml"]; found {
+		t.Errorf("expected the symlink not to be remediated")
+	}
+	if _, found := response.Files[".github/workflows/ci.yml"]; !found {
+		t.Errorf("expected the workflow to be remediated, got %v", response.Files)
+	}
+
+	// the symlink is written back unchanged
+	reader, err := zip.NewReader(bytes.NewReader(response.Archive), int64(len(response.Archive)))
+	if err != nil {
+		t.Fatalf("Error reading output archive: %v", err)
+	}
+	for _, file := range reader.File {
+		if file.Name != header.Name {
+			continue
+		}
+		content, _ := file.Open()
+		target, _ := io.ReadAll(content)
+		if file.Mode()&os.ModeSymlink == 0 || string(target) != workflow {
+			t.Errorf("expected the symlink to be unchanged, got mode %v and %q", file.Mode(), target)
+		}
+	}
+}
+
+func TestSecureRepoConfig(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	const workflow = `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make build
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make deploy
+`
+	files := map[string]string{
+		".github/stepsecurity.yml": `remediations:
+  updateDependabotConfig: false
+exemptions:
+  files: [".github/workflows/legacy.yml"]
+  jobs: [deploy]
+runner-labels:
+  ubuntu-latest: ubuntu-22.04
+`,
+		".github/workflows/ci.yml":     workflow,
+		".github/workflows/legacy.yml": workflow,
+	}
+	params := map[string]string{"pinActions": "false", "addHardenRunner": "false", "addProjectComment": "false"}
+
+	response, err := SecureRepo(context.Background(), params, SecureRepoRequest{Files: files}, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+
+	// the runner label of the exempted job is not replaced
+	expected := `name: CI
+on: push
+permissions:
+  contents: read
+
+jobs:
+  build:
+    runs-on: ubuntu-22.04
+    steps:
+      - run: make build
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make deploy
+`
+	if response.Files[".github/workflows/ci.yml"] != expected {
+		t.Errorf("unexpected workflow\n%s", response.Files[".github/workflows/ci.yml"])
+	}
+
+	// the exempted file and the dependabot config are not in the report
+	if len(response.Report) != 1 || len(response.Files) != 1 {
+		t.Errorf("expected only ci.yml to be changed, got %+v", response.Report)
+	}
+
+	files[".github/stepsecurity.yml"] = "remediation: {}\n"
+	if _, err := SecureRepo(context.Background(), params, SecureRepoRequest{Files: files}, nil); err == nil {
+		t.Errorf("expected error for unknown field in config")
+	}
+}
+
+func TestSecureRepoDiffOutput(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	files := map[string]string{
+		".github/workflows/ci.yml": `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make build
+`,
+	}
+	params := map[string]string{"pinActions": "false", "addHardenRunner": "false", "addProjectComment": "false", "output": "diff"}
+
+	response, err := SecureRepo(context.Background(), params, SecureRepoRequest{Files: files}, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+
+	expected := `--- a/.github/workflows/ci.yml
++++ b/.github/workflows/ci.yml
+@@ -1,5 +1,8 @@
+ name: CI
+ on: push
++permissions:
++  contents: read
++
+ jobs:
+   build:
+     runs-on: ubuntu-latest
+`
+	if len(response.Files) != 0 || response.Diffs[".github/workflows/ci.yml"] != expected {
+		t.Errorf("unexpected diffs %v, files %v", response.Diffs, response.Files)
+	}
+	// the new dependabot config is diffed against /dev/null
+	if !strings.HasPrefix(response.Diffs[DependabotConfigPath], "--- /dev/null\n+++ b/.github/dependabot.yml\n") {
+		t.Errorf("unexpected dependabot diff %s", response.Diffs[DependabotConfigPath])
+	}
+}
+
+func TestSecureRepoFileList(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	request := SecureRepoRequest{FileList: []File{
+		{Path: "./.github/workflows/ci.yml", Content: "name: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make build\n"},
+		{Path: "/build/Dockerfile", Content: "FROM scratch\n"},
+		{Path: "README.md", Content: "# readme\n"},
+	}}
+	params := map[string]string{"pinActions": "false", "addHardenRunner": "false", "addProjectComment": "false", "updateDependabotConfig": "false"}
+
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.Report) != 2 || response.Report[0].Path != ".github/workflows/ci.yml" || response.Report[1].Path != "build/Dockerfile" {
+		t.Errorf("unexpected report %+v", response.Report)
+	}
+	if !response.Report[0].IsChanged || response.Report[1].IsChanged {
+		t.Errorf("expected only the workflow to be changed, got %+v", response.Report)
+	}
+
+	for _, fileList := range [][]File{
+		{{Path: "a/../Dockerfile"}, {Path: "Dockerfile"}},
+		{{Path: "../Dockerfile"}},
+		{{Path: ""}},
+	} {
+		if _, err := SecureRepo(context.Background(), params, SecureRepoRequest{FileList: fileList}, nil); err == nil {
+			t.Errorf("expected error for file list %v", fileList)
+		}
+	}
+}
+
+func TestSecureRepoDryRun(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	files := map[string]string{
+		".github/workflows/ci.yml": "name: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make build\n",
+	}
+	params := map[string]string{"pinActions": "false", "addHardenRunner": "false", "addProjectComment": "false", "updateDependabotConfig": "false", "dryRun": "true"}
+
+	response, err := SecureRepo(context.Background(), params, SecureRepoRequest{Files: files}, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.Files) != 0 || !strings.Contains(response.Diffs[".github/workflows/ci.yml"], "+permissions:") {
+		t.Errorf("unexpected diffs %v, files %v", response.Diffs, response.Files)
+	}
+	if len(response.Report) != 1 || len(response.Report[0].Findings) == 0 || response.Report[0].Findings[0].RuleID != "missing-permissions" {
+		t.Errorf("expected findings of the checks, got %+v", response.Report)
+	}
+}
+
+func TestSecureRepoAzurePipelines(t *testing.T) {
+	request := SecureRepoRequest{Files: map[string]string{
+		"azure-pipelines.yml": "pool:\n  vmImage: ubuntu-latest\nsteps:\n  - task: NodeTool@0\n  - script: npm test\n",
+	}}
+	params := map[string]string{"updateDependabotConfig": "false"}
+
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.Report) != 1 || response.Report[0].FileType != FileTypeAzurePipelines || response.Report[0].IsChanged {
+		t.Fatalf("unexpected report %+v", response.Report)
+	}
+	if findings := response.Report[0].Findings; len(findings) != 1 || findings[0].RuleID != "azure-task-major-version" || findings[0].Line != 4 {
+		t.Errorf("expected the task with a major version to be reported, got %+v", findings)
+	}
+}
+
+func TestSecureRepoCircleCI(t *testing.T) {
+	request := SecureRepoRequest{Files: map[string]string{
+		".circleci/config.yml": "version: 2.1\njobs:\n  deploy:\n    machine: true\n    steps:\n      - checkout\nworkflows:\n  main:\n    jobs:\n      - deploy:\n          context: org-global\n",
+	}}
+	params := map[string]string{"updateDependabotConfig": "false"}
+
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.Report) != 1 || response.Report[0].FileType != FileTypeCircleCI || response.Report[0].IsChanged {
+		t.Fatalf("unexpected report %+v", response.Report)
+	}
+	if findings := response.Report[0].Findings; len(findings) != 1 || findings[0].RuleID != "circleci-broad-context" || findings[0].JobName != "deploy" {
+		t.Errorf("expected the org-global context to be reported, got %+v", findings)
+	}
+}
+
+func TestSecureRepoBitbucketPipelines(t *testing.T) {
+	request := SecureRepoRequest{Files: map[string]string{
+		"bitbucket-pipelines.yml": "pipelines:\n  default:\n    - step:\n        name: Deploy\n        deployment: production\n        script:\n          - ./deploy.sh\n",
+	}}
+	params := map[string]string{"updateDependabotConfig": "false"}
+
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.Report) != 1 || response.Report[0].FileType != FileTypeBitbucket || response.Report[0].IsChanged {
+		t.Fatalf("unexpected report %+v", response.Report)
+	}
+	if findings := response.Report[0].Findings; len(findings) != 1 || findings[0].RuleID != "bitbucket-unrestricted-deployment" || findings[0].JobName != "Deploy" {
+		t.Errorf("expected the deployment of the default pipeline to be reported, got %+v", findings)
+	}
+}
+
+func TestSecureRepoJenkinsfile(t *testing.T) {
+	request := SecureRepoRequest{Files: map[string]string{
+		"Jenkinsfile": "@Library('pipeline-lib@master') _\nnode { sh 'make' }\n",
+	}}
+	params := map[string]string{"updateDependabotConfig": "false"}
+
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.Report) != 1 || response.Report[0].FileType != FileTypeJenkins || response.Report[0].IsChanged {
+		t.Fatalf("unexpected report %+v", response.Report)
+	}
+	if findings := response.Report[0].Findings; len(findings) != 1 || findings[0].RuleID != "jenkins-unpinned-library" || findings[0].Action != "pipeline-lib" {
+		t.Errorf("expected the library without a known repository to be reported, got %+v", findings)
+	}
+}
+
+func TestSecureRepoTekton(t *testing.T) {
+	request := SecureRepoRequest{Files: map[string]string{
+		".tekton/pipeline.yaml": "apiVersion: tekton.dev/v1\nkind: Pipeline\nmetadata:\n  name: release\nspec:\n  tasks:\n    - name: clone\n      taskRef:\n        resolver: hub\n        params:\n          - name: name\n            value: git-clone\n",
+	}}
+	params := map[string]string{"updateDependabotConfig": "false"}
+
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.Report) != 1 || response.Report[0].FileType != FileTypeTekton || response.Report[0].IsChanged {
+		t.Fatalf("unexpected report %+v", response.Report)
+	}
+	if findings := response.Report[0].Findings; len(findings) != 1 || findings[0].RuleID != "tekton-floating-task" || findings[0].Action != "git-clone" {
+		t.Errorf("expected the task of Tekton Hub without a version to be reported, got %+v", findings)
+	}
+	if !ShouldFetch(".tekton/pipeline.yaml") {
+		t.Errorf("expected the resources in .tekton to be fetched")
+	}
+}
+
+func TestSecureRepoArgoWorkflow(t *testing.T) {
+	request := SecureRepoRequest{Files: map[string]string{
+		"argo/build.yaml": "apiVersion: argoproj.io/v1alpha1\nkind: Workflow\nspec:\n  templates:\n    - name: main\n      container:\n        image: \"{{inputs.parameters.image}}\"\n        securityContext:\n          privileged: true\n",
+	}}
+	params := map[string]string{"updateDependabotConfig": "false"}
+
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.Report) != 1 || response.Report[0].FileType != FileTypeArgo || response.Report[0].IsChanged {
+		t.Fatalf("unexpected report %+v", response.Report)
+	}
+	if findings := response.Report[0].Findings; len(findings) != 2 || findings[0].RuleID != "argo-unpinned-image" || findings[1].RuleID != "argo-privileged-container" {
+		t.Errorf("expected the image set with a parameter and the privileged container to be reported, got %+v", findings)
+	}
+	if !ShouldFetch("argo/build.yaml") {
+		t.Errorf("expected the workflows in argo to be fetched")
+	}
+}
+
+func TestSecureRepoDrone(t *testing.T) {
+	request := SecureRepoRequest{Files: map[string]string{
+		".drone.yml": "kind: pipeline\nname: default\nsteps:\n  - name: build\n    image: ${BUILD_IMAGE}\n    privileged: true\n",
+	}}
+	params := map[string]string{"updateDependabotConfig": "false"}
+
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.Report) != 1 || response.Report[0].FileType != FileTypeDrone || response.Report[0].IsChanged {
+		t.Fatalf("unexpected report %+v", response.Report)
+	}
+	if findings := response.Report[0].Findings; len(findings) != 2 || findings[0].RuleID != "drone-unpinned-image" || findings[1].RuleID != "drone-privileged-step" {
+		t.Errorf("expected the image set with a variable and the privileged step to be reported, got %+v", findings)
+	}
+}
+
+func TestSecureRepoMigrateTravis(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	request := SecureRepoRequest{Files: map[string]string{
+		".travis.yml": "language: go\ngo: \"1.22\"\ndeploy:\n  provider: releases\n",
+	}}
+	params := map[string]string{"pinActions": "false", "addProjectComment": "false", "updateDependabotConfig": "false", "migrateTravis": "true"}
+
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.Report) != 2 || response.Report[0].FileType != FileTypeTravis || response.Report[0].IsChanged {
+		t.Fatalf("unexpected report %+v", response.Report)
+	}
+	if findings := response.Report[0].Findings; len(findings) != 1 || findings[0].Action != "deploy" {
+		t.Errorf("expected deploy to be reported, got %+v", findings)
+	}
+	workflowReport := response.Report[1]
+	if workflowReport.Path != ".github/workflows/ci.yml" || workflowReport.FileType != FileTypeWorkflow || !workflowReport.IsNew || workflowReport.HasErrors {
+		t.Errorf("unexpected report of the workflow %+v", workflowReport)
+	}
+	workflow := response.Files[".github/workflows/ci.yml"]
+	for _, expected := range []string{"uses: step-security/harden-runner@", "permissions:\n  contents: read", "uses: actions/setup-go@v5", "run: go test -v ./..."} {
+		if !strings.Contains(workflow, expected) {
+			t.Errorf("expected the workflow to have %q\n%s", expected, workflow)
+		}
+	}
+
+	// the configuration is not converted without migrateTravis
+	delete(params, "migrateTravis")
+	response, err = SecureRepo(context.Background(), params, request, nil)
+	if err != nil || len(response.Report) != 0 || len(response.Files) != 0 {
+		t.Errorf("expected no changes without migrateTravis, got %+v, %v", response, err)
+	}
+}
+
+func TestSecureRepoPrecommitConfig(t *testing.T) {
+	request := SecureRepoRequest{Files: map[string]string{
+		".pre-commit-config.yaml": "repos:\n  - repo: https://gitlab.com/acme/hooks\n    rev: v1.0.0\n    hooks:\n      - id: lint\n",
+	}}
+	params := map[string]string{"updateDependabotConfig": "false"}
+
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.Report) != 1 || response.Report[0].FileType != FileTypePrecommit || response.Report[0].IsChanged {
+		t.Fatalf("unexpected report %+v", response.Report)
+	}
+	if findings := response.Report[0].Findings; len(findings) != 1 || findings[0].RuleID != "precommit-unpinned-rev" {
+		t.Errorf("expected the repository on GitLab to be reported, got %+v", findings)
+	}
+}
+
+func TestSecureRepoBuildkite(t *testing.T) {
+	request := SecureRepoRequest{Files: map[string]string{
+		".buildkite/pipeline.yml": "steps:\n  - label: test\n    plugins:\n      - gitlab.com/acme/test-buildkite-plugin#v1\n",
+	}}
+	params := map[string]string{"updateDependabotConfig": "false"}
+
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.Report) != 1 || response.Report[0].FileType != FileTypeBuildkite || response.Report[0].IsChanged {
+		t.Fatalf("unexpected report %+v", response.Report)
+	}
+	if findings := response.Report[0].Findings; len(findings) != 1 || findings[0].RuleID != "buildkite-unpinned-plugin" {
+		t.Errorf("expected the plugin on GitLab to be reported, got %+v", findings)
+	}
+}
+
+func TestSecureRepoRenovateConfig(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	request := SecureRepoRequest{Files: map[string]string{
+		".github/workflows/ci.yml": "name: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make build\n",
+		"build/Dockerfile":         "FROM scratch\n",
+		".github/renovate.json":    "{\n  \"extends\": [\"config:recommended\"]\n}\n",
+	}}
+	params := map[string]string{"pinActions": "false", "addHardenRunner": "false", "addProjectComment": "false", "updateRenovateConfig": "true"}
+
+	response, err := SecureRepo(context.Background(), params, request, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if _, found := response.Files[DependabotConfigPath]; found {
+		t.Errorf("expected the dependabot config not to be added with updateRenovateConfig")
+	}
+	renovateReport := response.Report[len(response.Report)-1]
+	if renovateReport.Path != ".github/renovate.json" || renovateReport.FileType != FileTypeRenovate || renovateReport.IsNew || !renovateReport.IsChanged {
+		t.Fatalf("unexpected report of the renovate config %+v", renovateReport)
+	}
+	config := response.Files[".github/renovate.json"]
+	for _, expected := range []string{`"helpers:pinGitHubActionDigests"`, `"docker:pinDigests"`, `"groupName": "GitHub Actions"`, `"groupName": "Docker images"`} {
+		if !strings.Contains(config, expected) {
+			t.Errorf("expected the renovate config to have %s\n%s", expected, config)
+		}
+	}
+}
+
+func TestSecureRepoWorkers(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	files := map[string]string{}
+	for i := 0; i < 20; i++ {
+		files[fmt.Sprintf(".github/workflows/ci-%02d.yml", i)] = fmt.Sprintf("name: CI %d\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make build\n", i)
+		files[fmt.Sprintf("services/%02d/Dockerfile", i)] = "FROM scratch\n"
+	}
+	params := map[string]string{"pinActions": "false", "addProjectComment": "false"}
+
+	// the response is the same whether the files are remediated one after the other or by a pool of workers
+	t.Setenv(workers.CountEnv, "1")
+	sequential, err := SecureRepo(context.Background(), params, SecureRepoRequest{Files: files}, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	t.Setenv(workers.CountEnv, "8")
+	concurrent, err := SecureRepo(context.Background(), params, SecureRepoRequest{Files: files}, nil)
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if !reflect.DeepEqual(sequential, concurrent) {
+		t.Errorf("expected the same response with 1 and 8 workers")
+	}
+	if len(concurrent.Report) != 41 || concurrent.Report[0].Path != ".github/workflows/ci-00.yml" {
+		t.Errorf("expected a report for each file in the order of the paths, got %d reports", len(concurrent.Report))
+	}
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DynamoDBStore stores each value in an item of a DynamoDB table with Key as the hash key, and the value in Value. The
+// items are limited to 400 KB by DynamoDB.
+type DynamoDBStore struct {
+	TableName string
+	Svc       dynamodbiface.DynamoDBAPI
+}
+
+func (s *DynamoDBStore) Get(key string) ([]byte, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	result, err := s.Svc.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(s.TableName),
+		Key:            map[string]*dynamodb.AttributeValue{"Key": {S: aws.String(key)}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil || result.Item["Value"] == nil {
+		return nil, nil
+	}
+	return result.Item["Value"].B, nil
+}
+
+func (s *DynamoDBStore) Put(key string, value []byte) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	_, err := s.Svc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			"Key":   {S: aws.String(key)},
+			"Value": {B: value},
+		},
+	})
+	return err
+}
+
+func (s *DynamoDBStore) PutIfAbsent(key string, value []byte) (bool, error) {
+	if err := ValidateKey(key); err != nil {
+		return false, err
+	}
+	_, err := s.Svc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			"Key":   {S: aws.String(key)},
+			"Value": {B: value},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#key)"),
+		// Key is a reserved word of DynamoDB
+		ExpressionAttributeNames: map[string]*string{"#key": aws.String("Key")},
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *DynamoDBStore) Delete(key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	_, err := s.Svc.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(s.TableName),
+		Key:       map[string]*dynamodb.AttributeValue{"Key": {S: aws.String(key)}},
+	})
+	return err
+}
+
+// List scans the table for the keys, since the items are only keyed by their hash key
+func (s *DynamoDBStore) List(prefix string) ([]string, error) {
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String(s.TableName),
+		ProjectionExpression: aws.String("#key"),
+		// Key is a reserved word of DynamoDB
+		ExpressionAttributeNames: map[string]*string{"#key": aws.String("Key")},
+		ConsistentRead:           aws.Bool(true),
+	}
+	if prefix != "" {
+		input.FilterExpression = aws.String("begins_with(#key, :prefix)")
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{":prefix": {S: aws.String(prefix)}}
+	}
+	var keys []string
+	err := s.Svc.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			if item["Key"] != nil {
+				keys = append(keys, aws.StringValue(item["Key"].S))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// S3Store stores each value in an object of the bucket, whose key is the key of the value under the prefix
+type S3Store struct {
+	Bucket string
+	Prefix string
+	Svc    s3iface.S3API
+}
+
+func (s *S3Store) objectKey(key string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	return path.Join(s.Prefix, key), nil
+}
+
+func (s *S3Store) Get(key string) ([]byte, error) {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.Svc.GetObject(&s3.GetObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(objectKey)})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer result.Body.Close()
+	return io.ReadAll(result.Body)
+}
+
+func (s *S3Store) Put(key string, value []byte) error {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.Svc.PutObject(&s3.PutObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(objectKey), Body: bytes.NewReader(value)})
+	return err
+}
+
+// PutIfAbsent puts the object with If-None-Match: *, which S3 rejects if the object exists
+func (s *S3Store) PutIfAbsent(key string, value []byte) (bool, error) {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return false, err
+	}
+	// the PutObjectInput of the SDK has no If-None-Match
+	ifNoneMatch := func(r *request.Request) { r.HTTPRequest.Header.Set("If-None-Match", "*") }
+	_, err = s.Svc.PutObjectWithContext(aws.BackgroundContext(),
+		&s3.PutObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(objectKey), Body: bytes.NewReader(value)}, ifNoneMatch)
+	if awsErr, ok := err.(awserr.Error); ok {
+		switch awsErr.Code() {
+		// ConditionalRequestConflict is returned while another conditional write of the object is in progress
+		case "PreconditionFailed", "ConditionalRequestConflict":
+			return false, nil
+		}
+	}
+	return err == nil, err
+}
+
+func (s *S3Store) Delete(key string) error {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.Svc.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(objectKey)})
+	return err
+}
+
+func (s *S3Store) List(prefix string) ([]string, error) {
+	objectPrefix := prefix
+	if s.Prefix != "" {
+		objectPrefix = s.Prefix + "/" + prefix
+	}
+	var keys []string
+	err := s.Svc.ListObjectsV2Pages(&s3.ListObjectsV2Input{Bucket: aws.String(s.Bucket), Prefix: aws.String(objectPrefix)},
+		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, object := range page.Contents {
+				key := aws.StringValue(object.Key)
+				if s.Prefix != "" {
+					key = strings.TrimPrefix(key, s.Prefix+"/")
+				}
+				keys = append(keys, key)
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
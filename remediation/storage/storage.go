@@ -0,0 +1,93 @@
+// Package storage stores the state of the handler, such as the cached responses, the configuration of the tenants and
+// the progress of campaigns, as values keyed by slash separated paths. The backends are a local directory, an S3 bucket
+// and a DynamoDB table, so the service can be hosted outside AWS with the local directory, e.g. a mounted volume.
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/step-security/secure-repo/remediation/awssession"
+)
+
+// URLEnv is the URL of the backend, which is file:///var/lib/secure-repo for a directory, s3://bucket/prefix for an S3
+// bucket, or dynamodb://table for a DynamoDB table with Key as the hash key
+const URLEnv = "STORAGE_URL"
+
+// Store stores values by key. Keys are slash separated paths, e.g. campaigns/1234/campaign, without empty, . or ..
+// elements.
+type Store interface {
+	// Get returns nil if there is no value for the key
+	Get(key string) ([]byte, error)
+	Put(key string, value []byte) error
+	// Delete does not return an error if there is no value for the key
+	Delete(key string) error
+	// List returns the keys that start with the prefix, sorted
+	List(prefix string) ([]string, error)
+}
+
+// ConditionalStore is a Store that writes a value only if the key has none, atomically, so only one of the concurrent
+// writers of a key succeeds. The backends are all conditional stores.
+type ConditionalStore interface {
+	Store
+	// PutIfAbsent returns false, and does not write the value, if the key already has a value
+	PutIfAbsent(key string, value []byte) (bool, error)
+}
+
+// ValidateKey returns an error if the key is not a slash separated path, which the directory backend would resolve
+// outside its directory
+func ValidateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("empty key")
+	}
+	for _, element := range strings.Split(key, "/") {
+		if element == "" || element == "." || element == ".." || strings.Contains(element, "\\") {
+			return fmt.Errorf("invalid key %s", key)
+		}
+	}
+	return nil
+}
+
+// Open returns the store of the URL of a backend
+func Open(rawURL string) (Store, error) {
+	storeURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", URLEnv, err)
+	}
+	switch storeURL.Scheme {
+	case "file":
+		if storeURL.Path == "" {
+			return nil, fmt.Errorf("%s has no directory", URLEnv)
+		}
+		return &FileStore{Dir: storeURL.Path}, nil
+	case "s3":
+		if storeURL.Host == "" {
+			return nil, fmt.Errorf("%s has no bucket", URLEnv)
+		}
+		return &S3Store{Bucket: storeURL.Host, Prefix: strings.Trim(storeURL.Path, "/"), Svc: s3.New(awssession.Default())}, nil
+	case "dynamodb":
+		if storeURL.Host == "" {
+			return nil, fmt.Errorf("%s has no table", URLEnv)
+		}
+		return &DynamoDBStore{TableName: storeURL.Host, Svc: dynamodb.New(awssession.Default())}, nil
+	}
+	return nil, fmt.Errorf("unsupported %s scheme %s, expected file, s3 or dynamodb", URLEnv, storeURL.Scheme)
+}
+
+var defaultStore = sync.OnceValues(func() (Store, error) {
+	rawURL := os.Getenv(URLEnv)
+	if rawURL == "" {
+		return nil, nil
+	}
+	return Open(rawURL)
+})
+
+// Default returns the store of STORAGE_URL, or nil if it is not set
+func Default() (Store, error) {
+	return defaultStore()
+}
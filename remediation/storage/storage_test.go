@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// testStore checks the behavior that all backends share
+func testStore(t *testing.T, store ConditionalStore) {
+	if value, err := store.Get("campaigns/1/campaign"); value != nil || err != nil {
+		t.Errorf("Get() = %q, %v, want nil for a missing key", value, err)
+	}
+	for _, key := range []string{"campaigns/1/results/00000001", "campaigns/1/results/00000000", "campaigns/10/campaign", "responses/abc"} {
+		if err := store.Put(key, []byte(key)); err != nil {
+			t.Fatalf("Put(%s) unexpected error = %v", key, err)
+		}
+	}
+	if value, err := store.Get("responses/abc"); string(value) != "responses/abc" || err != nil {
+		t.Errorf("Get() = %q, %v, want the value", value, err)
+	}
+	keys, err := store.List("campaigns/1/")
+	if err != nil || !reflect.DeepEqual(keys, []string{"campaigns/1/results/00000000", "campaigns/1/results/00000001"}) {
+		t.Errorf("List() = %v, %v, want the results of the campaign in order", keys, err)
+	}
+	if err := store.Delete("responses/abc"); err != nil {
+		t.Errorf("Delete() unexpected error = %v", err)
+	}
+	if err := store.Delete("responses/abc"); err != nil {
+		t.Errorf("Delete() of a missing key unexpected error = %v", err)
+	}
+	if value, _ := store.Get("responses/abc"); value != nil {
+		t.Errorf("expected the value to be deleted")
+	}
+	if written, err := store.PutIfAbsent("deliveries/1", []byte("first")); !written || err != nil {
+		t.Errorf("PutIfAbsent() = %v, %v, want the value written", written, err)
+	}
+	if written, err := store.PutIfAbsent("deliveries/1", []byte("second")); written || err != nil {
+		t.Errorf("PutIfAbsent() = %v, %v, want the existing value kept", written, err)
+	}
+	if value, _ := store.Get("deliveries/1"); string(value) != "first" {
+		t.Errorf("Get() = %q, want the first value", value)
+	}
+	for _, key := range []string{"", "../secrets", "campaigns//campaign", "campaigns/./campaign"} {
+		if err := store.Put(key, nil); err == nil {
+			t.Errorf("expected an error for the key %q", key)
+		}
+		if _, err := store.PutIfAbsent(key, nil); err == nil {
+			t.Errorf("expected an error for the key %q", key)
+		}
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	testStore(t, &FileStore{Dir: t.TempDir()})
+
+	keys, err := (&FileStore{Dir: t.TempDir() + "/missing"}).List("")
+	if err != nil || len(keys) != 0 {
+		t.Errorf("List() = %v, %v, want no keys for a missing directory", keys, err)
+	}
+}
+
+type mockS3Client struct {
+	s3iface.S3API
+	objects map[string][]byte
+}
+
+func (m *mockS3Client) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	value, found := m.objects[aws.StringValue(input.Key)]
+	if !found {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil)
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(value))}, nil
+}
+
+func (m *mockS3Client) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	value, _ := io.ReadAll(input.Body)
+	m.objects[aws.StringValue(input.Key)] = value
+	return &s3.PutObjectOutput{}, nil
+}
+
+// PutObjectWithContext only handles the If-None-Match of the options
+func (m *mockS3Client) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, options ...request.Option) (*s3.PutObjectOutput, error) {
+	r := &request.Request{HTTPRequest: &http.Request{Header: http.Header{}}}
+	r.ApplyOptions(options...)
+	if _, found := m.objects[aws.StringValue(input.Key)]; found && r.HTTPRequest.Header.Get("If-None-Match") == "*" {
+		return nil, awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil)
+	}
+	return m.PutObject(input)
+}
+
+func (m *mockS3Client) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	delete(m.objects, aws.StringValue(input.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (m *mockS3Client) ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	page := &s3.ListObjectsV2Output{}
+	for key := range m.objects {
+		if strings.HasPrefix(key, aws.StringValue(input.Prefix)) {
+			page.Contents = append(page.Contents, &s3.Object{Key: aws.String(key)})
+		}
+	}
+	fn(page, true)
+	return nil
+}
+
+func TestS3Store(t *testing.T) {
+	client := &mockS3Client{objects: map[string][]byte{}}
+	testStore(t, &S3Store{Bucket: "state", Prefix: "secure-repo", Svc: client})
+	if _, found := client.objects["secure-repo/campaigns/10/campaign"]; !found {
+		t.Errorf("expected the objects to be under the prefix, got %v", client.objects)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	store, err := Open("file:///var/lib/secure-repo")
+	if fileStore, ok := store.(*FileStore); err != nil || !ok || fileStore.Dir != "/var/lib/secure-repo" {
+		t.Errorf("Open() = %+v, %v, want the directory", store, err)
+	}
+	store, err = Open("s3://state/secure-repo/")
+	if s3Store, ok := store.(*S3Store); err != nil || !ok || s3Store.Bucket != "state" || s3Store.Prefix != "secure-repo" {
+		t.Errorf("Open() = %+v, %v, want the bucket and prefix", store, err)
+	}
+	store, err = Open("dynamodb://State")
+	if dynamoDBStore, ok := store.(*DynamoDBStore); err != nil || !ok || dynamoDBStore.TableName != "State" {
+		t.Errorf("Open() = %+v, %v, want the table", store, err)
+	}
+	for _, rawURL := range []string{"redis://localhost", "s3:///prefix", "file://"} {
+		if _, err := Open(rawURL); err == nil {
+			t.Errorf("expected an error for %s", rawURL)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package tekton
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/docker"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/multidoc"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleFloatingTask  = "tekton-floating-task"
+	RuleUnpinnedImage = "tekton-unpinned-image"
+
+	apiGroupPrefix = "tekton.dev/"
+)
+
+var shaRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// SecureResourceResponse is the result of the remediations of a file with Tekton resources
+type SecureResourceResponse struct {
+	OriginalInput string
+	FinalOutput   string
+	IsChanged     bool
+	PinnedBundles bool
+	PinnedImages  bool
+	Findings      []findings.Finding
+}
+
+// IsTektonPath returns true for the YAML files in the .tekton and tekton directories, where Pipelines as Code and most
+// repositories keep their Tekton resources. The resources are found by their content, so other files are remediated too.
+func IsTektonPath(filePath string) bool {
+	name := path.Base(filePath)
+	if !strings.HasSuffix(name, ".yml") && !strings.HasSuffix(name, ".yaml") {
+		return false
+	}
+	for _, dir := range strings.Split(path.Dir(filePath), "/") {
+		if dir == ".tekton" || dir == "tekton" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTektonResource returns true if a document of the YAML is a Tekton resource, e.g. a Task or a Pipeline
+func IsTektonResource(inputYaml string) bool {
+	documents, err := multidoc.Mappings(inputYaml)
+	if err != nil {
+		return false
+	}
+	for _, topNode := range documents {
+		if apiVersion := document.MappingValue(topNode, "apiVersion"); apiVersion != nil && strings.HasPrefix(apiVersion.Value, apiGroupPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// getParams returns the value nodes of the params of a resolver by their name
+func getParams(ref *yaml.Node) map[string]*yaml.Node {
+	params := map[string]*yaml.Node{}
+	for _, param := range document.Sequence(document.MappingValue(ref, "params")) {
+		name, value := document.MappingValue(param, "name"), document.MappingValue(param, "value")
+		if name != nil && value != nil && value.Kind == yaml.ScalarNode {
+			params[name.Value] = value
+		}
+	}
+	return params
+}
+
+// resource is a Tekton resource in the file, with the refs to tasks and pipelines and the specs of the tasks it has
+type resource struct {
+	name      string
+	refs      []*yaml.Node
+	taskSpecs []*yaml.Node
+	// images are the images of StepActions, which are set in their spec
+	images []*yaml.Node
+}
+
+// addPipelineSpec adds the refs and the embedded specs of the tasks and finally tasks of a pipeline
+func (r *resource) addPipelineSpec(spec *yaml.Node) {
+	tasks := append(append([]*yaml.Node{}, document.Sequence(document.MappingValue(spec, "tasks"))...), document.Sequence(document.MappingValue(spec, "finally"))...)
+	for _, task := range tasks {
+		if ref := document.MappingValue(task, "taskRef"); ref != nil {
+			r.refs = append(r.refs, ref)
+		}
+		if taskSpec := document.MappingValue(task, "taskSpec"); taskSpec != nil {
+			r.taskSpecs = append(r.taskSpecs, taskSpec)
+		}
+	}
+}
+
+// getResource returns the refs and the task specs of a Task, Pipeline, TaskRun, PipelineRun or StepAction
+func getResource(topNode *yaml.Node) *resource {
+	apiVersion := document.MappingValue(topNode, "apiVersion")
+	if apiVersion == nil || !strings.HasPrefix(apiVersion.Value, apiGroupPrefix) {
+		return nil
+	}
+	r := &resource{}
+	if name := document.MappingValue(document.MappingValue(topNode, "metadata"), "name"); name != nil {
+		r.name = name.Value
+	} else if generateName := document.MappingValue(document.MappingValue(topNode, "metadata"), "generateName"); generateName != nil {
+		r.name = generateName.Value
+	}
+	spec := document.MappingValue(topNode, "spec")
+	kind := document.MappingValue(topNode, "kind")
+	if kind == nil {
+		return r
+	}
+	switch kind.Value {
+	case "Task", "ClusterTask":
+		r.taskSpecs = append(r.taskSpecs, spec)
+	case "Pipeline":
+		r.addPipelineSpec(spec)
+	case "TaskRun":
+		if ref := document.MappingValue(spec, "taskRef"); ref != nil {
+			r.refs = append(r.refs, ref)
+		}
+		if taskSpec := document.MappingValue(spec, "taskSpec"); taskSpec != nil {
+			r.taskSpecs = append(r.taskSpecs, taskSpec)
+		}
+	case "PipelineRun":
+		if ref := document.MappingValue(spec, "pipelineRef"); ref != nil {
+			r.refs = append(r.refs, ref)
+		}
+		r.addPipelineSpec(document.MappingValue(spec, "pipelineSpec"))
+	case "StepAction":
+		r.images = append(r.images, document.MappingValue(spec, "image"))
+	}
+	// steps reference StepActions with a ref, which is resolved like the ref of a task
+	for _, taskSpec := range r.taskSpecs {
+		for _, step := range document.Sequence(document.MappingValue(taskSpec, "steps")) {
+			if ref := document.MappingValue(step, "ref"); ref != nil {
+				r.refs = append(r.refs, ref)
+			}
+		}
+	}
+	return r
+}
+
+// findBundleEdits returns the edits that pin the bundles of the refs to their digest, and the findings of the bundles
+// set with params and of the tasks fetched from a branch of a git repository or the latest version in Tekton Hub
+func findBundleEdits(ctx context.Context, r *resource) ([]textedit.Replacement, []findings.Finding, error) {
+	var edits []textedit.Replacement
+	var bundleFindings []findings.Finding
+	addFinding := func(node *yaml.Node, action, message, suggestion string) {
+		bundleFindings = append(bundleFindings, findings.Finding{
+			RuleID:     RuleFloatingTask,
+			Message:    message,
+			JobName:    r.name,
+			Action:     action,
+			Line:       node.Line,
+			Column:     node.Column,
+			Suggestion: suggestion,
+		})
+	}
+	for _, ref := range r.refs {
+		// bundle is the field of the refs of v1beta1, which are resolved by the bundles resolver in v1
+		bundle := document.MappingValue(ref, "bundle")
+		params := getParams(ref)
+		resolver := document.MappingValue(ref, "resolver")
+		if resolver != nil && resolver.Value == "bundles" {
+			bundle = params["bundle"]
+		}
+		if bundle != nil {
+			switch {
+			case bundle.Kind != yaml.ScalarNode || bundle.Value == "" || strings.Contains(bundle.Value, "@"):
+			case strings.Contains(bundle.Value, "$("):
+				addFinding(bundle, bundle.Value, fmt.Sprintf("Bundle %s is set with a param, so it cannot be pinned to a digest", bundle.Value),
+					"Set the bundle with its digest, e.g. gcr.io/tekton-releases/catalog/upstream/git-clone:0.9@sha256:...")
+			default:
+				pinned, err := docker.PinImage(ctx, bundle.Value)
+				if err != nil {
+					return nil, nil, err
+				}
+				edits = append(edits, textedit.Replacement{Line: bundle.Line, Column: bundle.Column, Old: bundle.Value, New: pinned})
+			}
+			continue
+		}
+		if resolver == nil {
+			continue
+		}
+		switch resolver.Value {
+		case "git":
+			revision := params["revision"]
+			if revision != nil && shaRegex.MatchString(revision.Value) {
+				continue
+			}
+			node, branch := resolver, "the default branch"
+			if revision != nil {
+				node, branch = revision, revision.Value
+			}
+			addFinding(node, branch, fmt.Sprintf("Task is fetched from %s of a git repository, which can change without a change to the resource", branch),
+				"Set the revision param to the SHA of a commit")
+		case "hub":
+			if params["version"] != nil {
+				continue
+			}
+			action := ""
+			if name := params["name"]; name != nil {
+				action = name.Value
+			}
+			addFinding(resolver, action, fmt.Sprintf("Task %s is fetched from Tekton Hub without a version, so its latest version is used", action),
+				"Set the version param, or use the bundle of the task pinned to its digest")
+		}
+	}
+	return edits, bundleFindings, nil
+}
+
+// findImageEdits returns the edits that pin the images of the steps, the sidecars and the step templates of the tasks,
+// and of StepActions, to their digest, and the findings of the images set with params
+func findImageEdits(ctx context.Context, r *resource) ([]textedit.Replacement, []findings.Finding, error) {
+	images := append([]*yaml.Node{}, r.images...)
+	for _, taskSpec := range r.taskSpecs {
+		images = append(images, document.MappingValue(document.MappingValue(taskSpec, "stepTemplate"), "image"))
+		for _, container := range append(document.Sequence(document.MappingValue(taskSpec, "steps")), document.Sequence(document.MappingValue(taskSpec, "sidecars"))...) {
+			images = append(images, document.MappingValue(container, "image"))
+		}
+	}
+
+	var edits []textedit.Replacement
+	var imageFindings []findings.Finding
+	for _, image := range images {
+		if image == nil || image.Kind != yaml.ScalarNode || image.Value == "" || strings.Contains(image.Value, "@") {
+			continue
+		}
+		if strings.Contains(image.Value, "$(") {
+			imageFindings = append(imageFindings, findings.Finding{
+				RuleID:     RuleUnpinnedImage,
+				Message:    fmt.Sprintf("Image %s is set with a param, so it cannot be pinned to a digest", image.Value),
+				JobName:    r.name,
+				Action:     image.Value,
+				Line:       image.Line,
+				Column:     image.Column,
+				Suggestion: "Set the default of the param to an image with its digest",
+			})
+			continue
+		}
+		pinned, err := docker.PinImage(ctx, image.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		edits = append(edits, textedit.Replacement{Line: image.Line, Column: image.Column, Old: image.Value, New: pinned})
+	}
+	return edits, imageFindings, nil
+}
+
+// SecureResource runs the remediations for a file with Tekton resources. The bundles of tasks and pipelines are pinned
+// to their digest unless pinBundles is false, and the images of the steps and sidecars of tasks unless pinImages is
+// false. Bundles and images set with params, and tasks fetched from a branch or the latest version in Tekton Hub, are
+// reported as findings.
+func SecureResource(ctx context.Context, queryStringParams map[string]string, inputYaml string) (*SecureResourceResponse, error) {
+	response := &SecureResourceResponse{OriginalInput: inputYaml, FinalOutput: inputYaml}
+	documents, err := multidoc.Mappings(inputYaml)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+
+	var edits []textedit.Replacement
+	for _, document := range documents {
+		r := getResource(document)
+		if r == nil {
+			continue
+		}
+		if queryStringParams["pinBundles"] != "false" {
+			bundleEdits, bundleFindings, err := findBundleEdits(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			edits = append(edits, bundleEdits...)
+			response.PinnedBundles = response.PinnedBundles || len(bundleEdits) > 0
+			response.Findings = append(response.Findings, bundleFindings...)
+		}
+		if queryStringParams["pinImages"] != "false" {
+			imageEdits, imageFindings, err := findImageEdits(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			edits = append(edits, imageEdits...)
+			response.PinnedImages = response.PinnedImages || len(imageEdits) > 0
+			response.Findings = append(response.Findings, imageFindings...)
+		}
+	}
+
+	if len(edits) > 0 {
+		response.FinalOutput = textedit.ApplyReplacements(inputYaml, edits)
+		response.IsChanged = true
+	}
+	return response, nil
+}
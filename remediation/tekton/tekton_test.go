@@ -0,0 +1,185 @@
+package tekton
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/internal/testutil"
+)
+
+func TestSecureResource(t *testing.T) {
+	digest := testutil.MockRegistry(t, "library/golang/manifests/1.22", "library/redis/manifests/latest",
+		"acme/tasks/manifests/1.0", "acme/catalog/manifests/0.9")
+
+	input := `apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: build
+spec:
+  params:
+    - name: linter
+  steps:
+    - name: build
+      image: golang:1.22
+      script: go build ./...
+    - name: lint
+      image: $(params.linter)
+  sidecars:
+    - name: cache
+      image: redis
+---
+apiVersion: tekton.dev/v1
+kind: Pipeline
+metadata:
+  name: release
+spec:
+  tasks:
+    - name: clone
+      taskRef:
+        resolver: hub
+        params:
+          - name: name
+            value: git-clone
+    - name: build
+      taskRef:
+        name: build
+    - name: test
+      taskRef:
+        resolver: bundles
+        params:
+          - name: bundle
+            value: acme/catalog:0.9
+          - name: name
+            value: test
+    - name: scan
+      taskRef:
+        resolver: git
+        params:
+          - name: url
+            value: https://github.com/acme/tasks
+          - name: revision
+            value: main
+  finally:
+    - name: notify
+      taskSpec:
+        steps:
+          - name: notify
+            image: golang:1.22@sha256:0000000000000000000000000000000000000000000000000000000000000000
+---
+apiVersion: tekton.dev/v1beta1
+kind: PipelineRun
+metadata:
+  generateName: deploy-
+spec:
+  pipelineRef:
+    name: deploy
+    bundle: docker.io/acme/tasks:1.0
+`
+	want := `apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: build
+spec:
+  params:
+    - name: linter
+  steps:
+    - name: build
+      image: golang:1.22@` + digest + `
+      script: go build ./...
+    - name: lint
+      image: $(params.linter)
+  sidecars:
+    - name: cache
+      image: redis:latest@` + digest + `
+---
+apiVersion: tekton.dev/v1
+kind: Pipeline
+metadata:
+  name: release
+spec:
+  tasks:
+    - name: clone
+      taskRef:
+        resolver: hub
+        params:
+          - name: name
+            value: git-clone
+    - name: build
+      taskRef:
+        name: build
+    - name: test
+      taskRef:
+        resolver: bundles
+        params:
+          - name: bundle
+            value: acme/catalog:0.9@` + digest + `
+          - name: name
+            value: test
+    - name: scan
+      taskRef:
+        resolver: git
+        params:
+          - name: url
+            value: https://github.com/acme/tasks
+          - name: revision
+            value: main
+  finally:
+    - name: notify
+      taskSpec:
+        steps:
+          - name: notify
+            image: golang:1.22@sha256:0000000000000000000000000000000000000000000000000000000000000000
+---
+apiVersion: tekton.dev/v1beta1
+kind: PipelineRun
+metadata:
+  generateName: deploy-
+spec:
+  pipelineRef:
+    name: deploy
+    bundle: docker.io/acme/tasks:1.0@` + digest + `
+`
+	response, err := SecureResource(context.Background(), map[string]string{}, input)
+	if err != nil {
+		t.Fatalf("SecureResource() returned error: %v", err)
+	}
+	if !response.IsChanged || !response.PinnedBundles || !response.PinnedImages || response.FinalOutput != want {
+		t.Errorf("SecureResource() = %+v,\n%s\nwant\n%s", response, response.FinalOutput, want)
+	}
+	var got []string
+	for _, finding := range response.Findings {
+		got = append(got, fmt.Sprintf("%s:%d:%s:%s", finding.RuleID, finding.Line, finding.JobName, finding.Action))
+	}
+	if fmt.Sprint(got) != "[tekton-unpinned-image:13:build:$(params.linter) tekton-floating-task:26:release:git-clone tekton-floating-task:48:release:main]" {
+		t.Errorf("findings = %v, want the image set with a param, the task of Tekton Hub and the task of a branch", got)
+	}
+
+	response, err = SecureResource(context.Background(), map[string]string{}, want)
+	if err != nil || response.IsChanged || response.FinalOutput != want {
+		t.Errorf("expected pinned resources to be unchanged, got %+v, %v", response, err)
+	}
+}
+
+func TestIsTektonResource(t *testing.T) {
+	for input, want := range map[string]bool{
+		"apiVersion: tekton.dev/v1\nkind: Task\n":                           true,
+		"apiVersion: v1\nkind: ConfigMap\n---\napiVersion: tekton.dev/v1\n": true,
+		"apiVersion: apps/v1\nkind: Deployment\n":                           false,
+		"jobs:\n  build:\n    runs-on: ubuntu-latest\n":                     false,
+	} {
+		if got := IsTektonResource(input); got != want {
+			t.Errorf("IsTektonResource(%q) = %v, want %v", input, got, want)
+		}
+	}
+	for filePath, want := range map[string]bool{
+		".tekton/pull-request.yaml": true,
+		"deploy/tekton/task.yml":    true,
+		"tekton.yaml":               false,
+		".tekton/README.md":         false,
+	} {
+		if got := IsTektonPath(filePath); got != want {
+			t.Errorf("IsTektonPath(%s) = %v, want %v", filePath, got, want)
+		}
+	}
+}
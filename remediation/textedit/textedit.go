@@ -0,0 +1,256 @@
+// Package textedit edits large files without copying them for each change. A Buffer keeps the text once, with the
+// offsets of its lines and the changes to it, and writes the changed text in a single pass, instead of splitting the
+// text into a slice of lines, changing the slice and joining it into another copy of the text.
+package textedit
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Buffer is a text and the changes to it. The offsets and lines are of the original text, so the changes do not move
+// the offsets of the other changes, and can be made in any order.
+type Buffer struct {
+	text string
+	// starts are the offsets of the starts of the lines
+	starts []int
+	edits  []edit
+}
+
+// edit replaces the text from start to end, or inserts it at start if end is start
+type edit struct {
+	start, end int
+	// rank orders the edits at the same offset, and the edits of the same rank are written in the order they were made
+	rank int
+	text string
+}
+
+const (
+	// rankInsert is the rank of the texts inserted at an offset, and of the lines inserted after the line before it
+	rankInsert = iota
+	// rankInsertBefore is the rank of the lines inserted before the line at the offset
+	rankInsertBefore
+	// rankReplace is the rank of the replacements, including of empty lines
+	rankReplace
+)
+
+// NewBuffer returns a buffer of the text, which is not copied
+func NewBuffer(text string) *Buffer {
+	starts := make([]int, 1, strings.Count(text, "\n")+1)
+	for offset := 0; ; {
+		index := strings.IndexByte(text[offset:], '\n')
+		if index == -1 {
+			break
+		}
+		offset += index + 1
+		starts = append(starts, offset)
+	}
+	return &Buffer{text: text, starts: starts}
+}
+
+// Lines returns the number of lines, which is the number of newlines plus one, as with strings.Split
+func (b *Buffer) Lines() int {
+	return len(b.starts)
+}
+
+// Line returns the nth line of the original text, from 1, without its newline, and the offset of its start. It
+// returns an empty line at the end of the text if there is no such line.
+func (b *Buffer) Line(n int) (string, int) {
+	if n < 1 || n > len(b.starts) {
+		return "", len(b.text)
+	}
+	start, end := b.starts[n-1], len(b.text)
+	if n < len(b.starts) {
+		end = b.starts[n] - 1
+	}
+	return b.text[start:end], start
+}
+
+// LineAt returns the line of the offset of the original text, from 1
+func (b *Buffer) LineAt(offset int) int {
+	return sort.Search(len(b.starts), func(i int) bool { return b.starts[i] > offset })
+}
+
+// ColumnOffset returns the offset in the line of the character at the column, from 1, or the length of the line if it
+// is shorter. The columns of the YAML parser are in characters, so the column of a node is not the offset of its
+// character in a line with characters of several bytes before it.
+func ColumnOffset(line string, column int) int {
+	offset := 0
+	for i := 1; i < column && offset < len(line); i++ {
+		_, size := utf8.DecodeRuneInString(line[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// Replace replaces the original text from start to end with the text. A change which starts in the text replaced by
+// a change before it in the text is dropped when the text is written.
+func (b *Buffer) Replace(start, end int, text string) {
+	rank := rankReplace
+	if end == start {
+		rank = rankInsert
+	}
+	b.edits = append(b.edits, edit{start: start, end: end, rank: rank, text: text})
+}
+
+// Insert inserts the text at the offset. The texts inserted at the same offset are written in the order they were
+// inserted, and before a replacement of the text at the offset.
+func (b *Buffer) Insert(offset int, text string) {
+	b.Replace(offset, offset, text)
+}
+
+// InsertLine inserts a line after the nth line, from 1
+func (b *Buffer) InsertLine(n int, line string) {
+	b.InsertLinesAfter(n, line)
+}
+
+// InsertLinesAfter inserts the lines after the nth line, from 1. The lines are inserted at the start of the next line,
+// so they are kept when the nth line is replaced or deleted, and written before the lines inserted before the next line.
+func (b *Buffer) InsertLinesAfter(n int, lines ...string) {
+	if len(lines) == 0 {
+		return
+	}
+	if n >= len(b.starts) {
+		// the last line has no newline after it
+		b.Insert(len(b.text), "\n"+strings.Join(lines, "\n"))
+		return
+	}
+	b.Insert(b.starts[n], strings.Join(lines, "\n")+"\n")
+}
+
+// InsertLinesBefore inserts the lines before the nth line, from 1, after the lines inserted after the line before it
+func (b *Buffer) InsertLinesBefore(n int, lines ...string) {
+	if len(lines) == 0 {
+		return
+	}
+	_, start := b.Line(n)
+	b.edits = append(b.edits, edit{start: start, end: start, rank: rankInsertBefore, text: strings.Join(lines, "\n") + "\n"})
+}
+
+// ReplaceLine replaces the nth line, from 1, without its newline
+func (b *Buffer) ReplaceLine(n int, line string) {
+	text, start := b.Line(n)
+	b.edits = append(b.edits, edit{start: start, end: start + len(text), rank: rankReplace, text: line})
+}
+
+// DeleteLine deletes the nth line, from 1, with its newline. The lines inserted before and after it are kept. The
+// newline before the last line is kept, since the last line has no newline.
+func (b *Buffer) DeleteLine(n int) {
+	text, start := b.Line(n)
+	end := start + len(text)
+	if n < len(b.starts) {
+		end++
+	}
+	b.edits = append(b.edits, edit{start: start, end: end, rank: rankReplace})
+}
+
+// Changed returns true if the text was changed
+func (b *Buffer) Changed() bool {
+	return len(b.edits) > 0
+}
+
+// WriteTo writes the changed text to w
+func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	write := func(s string) error {
+		n, err := io.WriteString(w, s)
+		written += int64(n)
+		return err
+	}
+	offset := 0
+	for _, e := range b.sortedEdits() {
+		if err := write(b.text[offset:e.start]); err != nil {
+			return written, err
+		}
+		if err := write(e.text); err != nil {
+			return written, err
+		}
+		offset = e.end
+	}
+	err := write(b.text[offset:])
+	return written, err
+}
+
+// String returns the changed text, which is the original text if it was not changed
+func (b *Buffer) String() string {
+	if len(b.edits) == 0 {
+		return b.text
+	}
+	var builder strings.Builder
+	size := len(b.text)
+	for _, e := range b.edits {
+		size += len(e.text) - (e.end - e.start)
+	}
+	if size > 0 {
+		builder.Grow(size)
+	}
+	b.WriteTo(&builder)
+	return builder.String()
+}
+
+// Replacement replaces the old text of a value that starts at a line and a column of the text, e.g. of a node of a YAML
+// file. The old text is looked up from the column, since the value may be quoted.
+type Replacement struct {
+	Line, Column int
+	Old, New     string
+	// Comment is added at the end of the line, if nothing but quotes or the colon of a key follows the old text
+	Comment string
+}
+
+// ApplyReplacements applies the replacements to a buffer of the text, so their lines and columns do not move with the
+// other replacements. The replacements whose old text is not found are not applied.
+func ApplyReplacements(text string, replacements []Replacement) string {
+	buffer := NewBuffer(text)
+	for _, r := range replacements {
+		line, lineStart := buffer.Line(r.Line)
+		start := ColumnOffset(line, r.Column)
+		offset := strings.Index(line[start:], r.Old)
+		if offset == -1 {
+			continue
+		}
+		start += offset
+		rest := line[start+len(r.Old):]
+		if trimmed := strings.TrimSpace(strings.Trim(rest, `"'`)); r.Comment != "" && (trimmed == "" || trimmed == ":") {
+			buffer.Replace(lineStart+start, lineStart+len(line), r.New+rest+" # "+r.Comment)
+			continue
+		}
+		buffer.Replace(lineStart+start, lineStart+start+len(r.Old), r.New)
+	}
+	return buffer.String()
+}
+
+// sortedEdits returns the edits in the order of their offsets, without the edits which start in the text replaced by
+// the edit before them or are outside the text. An edit made again is written once, e.g. when the node of an anchor is
+// changed for each of its aliases.
+func (b *Buffer) sortedEdits() []edit {
+	edits := make([]edit, len(b.edits))
+	copy(edits, b.edits)
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		// the texts inserted at an offset are written before the text which replaces the text at the offset
+		return edits[i].rank < edits[j].rank
+	})
+	valid, end := edits[:0], 0
+	for _, e := range edits {
+		if e.start < end || e.end < e.start || e.end > len(b.text) || made(valid, e) {
+			continue
+		}
+		valid = append(valid, e)
+		end = e.end
+	}
+	return valid
+}
+
+// made returns true if the sorted edits have an edit at the offset of the edit which is the same
+func made(edits []edit, e edit) bool {
+	for i := len(edits) - 1; i >= 0 && edits[i].start == e.start; i-- {
+		if edits[i] == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package textedit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLine(t *testing.T) {
+	buffer := NewBuffer("on: push\njobs:\n  build:\n")
+	if buffer.Lines() != 4 {
+		t.Errorf("Lines() = %d, want 4", buffer.Lines())
+	}
+	tests := []struct {
+		n     int
+		line  string
+		start int
+	}{
+		{n: 1, line: "on: push", start: 0},
+		{n: 2, line: "jobs:", start: 9},
+		{n: 3, line: "  build:", start: 15},
+		{n: 4, line: "", start: 24},
+		{n: 5, line: "", start: 24},
+	}
+	for _, test := range tests {
+		line, start := buffer.Line(test.n)
+		if line != test.line || start != test.start {
+			t.Errorf("Line(%d) = %q, %d, want %q, %d", test.n, line, start, test.line, test.start)
+		}
+		if n := buffer.LineAt(start + len(line)); test.n <= buffer.Lines() && n != test.n {
+			t.Errorf("LineAt(%d) = %d, want %d", start+len(line), n, test.n)
+		}
+	}
+}
+
+func TestColumnOffset(t *testing.T) {
+	tests := []struct {
+		line   string
+		column int
+		offset int
+	}{
+		{line: "image: node:20", column: 8, offset: 7},
+		{line: "{name: é, image: node:20}", column: 18, offset: 18},
+		{line: "{name: 日本, image: node:20}", column: 19, offset: 22},
+		{line: "é", column: 5, offset: 2},
+		{line: "image", column: 0, offset: 0},
+	}
+	for _, test := range tests {
+		if offset := ColumnOffset(test.line, test.column); offset != test.offset {
+			t.Errorf("ColumnOffset(%q, %d) = %d, want %d", test.line, test.column, offset, test.offset)
+		}
+	}
+}
+
+func TestEdits(t *testing.T) {
+	input := "steps:\n- uses: actions/checkout@v4\n- uses: actions/setup-go@v5 # go\n"
+	buffer := NewBuffer(input)
+	if buffer.String() != input {
+		t.Errorf("String() = %q, want the input", buffer.String())
+	}
+
+	// the edits are made with the offsets of the original text, in any order
+	line, start := buffer.Line(3)
+	column := strings.Index(line, "@") + 1
+	buffer.Replace(start+column, start+column+len("v5"), "0a44ba7841725637a19e28fa30b79a866c81b0a6")
+	buffer.InsertLine(1, "- uses: step-security/harden-runner@v2")
+	line, start = buffer.Line(2)
+	column = strings.Index(line, "@") + 1
+	buffer.Replace(start+column, start+len(line), "11bd71901bbe5b1630ceea73d27597364c9af683 # v4")
+	// an edit which starts in the text replaced by another edit is dropped
+	buffer.Replace(start+column+1, start+column+3, "#")
+	buffer.InsertLine(1, "- uses: actions/cache@v4")
+
+	want := "steps:\n" +
+		"- uses: step-security/harden-runner@v2\n" +
+		"- uses: actions/cache@v4\n" +
+		"- uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4\n" +
+		"- uses: actions/setup-go@0a44ba7841725637a19e28fa30b79a866c81b0a6 # go\n"
+	if got := buffer.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	var builder strings.Builder
+	if n, err := buffer.WriteTo(&builder); err != nil || builder.String() != want || n != int64(len(want)) {
+		t.Errorf("WriteTo() = %d, %v, %q, want %d, %q", n, err, builder.String(), len(want), want)
+	}
+}
+
+func TestInsertLineWithoutNewline(t *testing.T) {
+	buffer := NewBuffer("include:\n  - project: group/templates")
+	buffer.InsertLine(2, "    ref: main")
+	if got, want := buffer.String(), "include:\n  - project: group/templates\n    ref: main"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestLineEdits(t *testing.T) {
+	input := "jobs:\n  build:\n    env:\n      TOKEN: ${{ secrets.TOKEN }}\n    steps:\n"
+	buffer := NewBuffer(input)
+	buffer.InsertLinesBefore(5, "    if: github.repository == 'owner/repo'")
+	buffer.DeleteLine(4)
+	buffer.DeleteLine(3)
+	buffer.InsertLinesAfter(4, "    permissions:", "      contents: read")
+	buffer.ReplaceLine(2, "  test:")
+	buffer.InsertLinesAfter(2, "    runs-on: ubuntu-latest")
+	// the lines inserted at the end of a text without a newline start with one
+	buffer.InsertLinesAfter(6, "      - uses: actions/checkout@v4")
+
+	want := "jobs:\n" +
+		"  test:\n" +
+		"    runs-on: ubuntu-latest\n" +
+		"    permissions:\n" +
+		"      contents: read\n" +
+		"    if: github.repository == 'owner/repo'\n" +
+		"    steps:\n" +
+		"\n      - uses: actions/checkout@v4"
+	if got := buffer.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestEditsMadeAgain(t *testing.T) {
+	buffer := NewBuffer("steps: &steps\n  - run: make\ntest:\n  steps: *steps\n")
+	// the step is inserted into the anchor once, though it is inserted for each job
+	buffer.InsertLinesAfter(1, "  - uses: step-security/harden-runner@v2")
+	buffer.InsertLinesAfter(1, "  - uses: actions/checkout@v4")
+	buffer.InsertLinesAfter(1, "  - uses: step-security/harden-runner@v2")
+	buffer.ReplaceLine(2, "  - run: make test")
+	buffer.ReplaceLine(2, "  - run: make test")
+
+	want := "steps: &steps\n  - uses: step-security/harden-runner@v2\n  - uses: actions/checkout@v4\n  - run: make test\ntest:\n  steps: *steps\n"
+	if got := buffer.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+// BenchmarkBuffer replaces a value on each line of a workflow of 1 MB, like pinning the actions of a generated workflow
+func BenchmarkBuffer(b *testing.B) {
+	line := "      - uses: actions/checkout@v4\n"
+	input := strings.Repeat(line, (1<<20)/len(line))
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buffer := NewBuffer(input)
+		for n := 1; n < buffer.Lines(); n++ {
+			text, start := buffer.Line(n)
+			column := strings.IndexByte(text, '@') + 1
+			buffer.Replace(start+column, start+len(text), "11bd71901bbe5b1630ceea73d27597364c9af683 # v4")
+		}
+		_ = buffer.String()
+	}
+}
+
+func TestApplyReplacements(t *testing.T) {
+	input := "steps:\n  - image: \"node:20\"\n  - plugins:\n      - docker#v5.9.0:\n  - image: node:20 # comment\n"
+	got := ApplyReplacements(input, []Replacement{
+		{Line: 2, Column: 12, Old: "node:20", New: "node:20@sha256:abc"},
+		{Line: 4, Column: 9, Old: "docker#v5.9.0", New: "docker#0123", Comment: "v5.9.0"},
+		// the comment is not added when the line has one
+		{Line: 5, Column: 12, Old: "node:20", New: "node:20@sha256:abc", Comment: "20"},
+		// the replacements whose old text is not found are not applied
+		{Line: 1, Column: 1, Old: "node:18", New: "node:18@sha256:abc"},
+	})
+	want := "steps:\n  - image: \"node:20@sha256:abc\"\n  - plugins:\n      - docker#0123: # v5.9.0\n  - image: node:20@sha256:abc # comment\n"
+	if got != want {
+		t.Errorf("ApplyReplacements() = %q, want %q", got, want)
+	}
+}
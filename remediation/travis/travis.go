@@ -0,0 +1,317 @@
+package travis
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConfigPath is the path of the configuration of Travis CI
+	ConfigPath = ".travis.yml"
+	// WorkflowPath is the path of the workflow the configuration is converted to
+	WorkflowPath = ".github/workflows/ci.yml"
+
+	RuleUnconvertedKey = "travis-unconverted-key"
+)
+
+// language is how a language of Travis CI is set up in a workflow: the key with its versions, the action that installs
+// it with its input, and the commands Travis CI runs when install and script are not set
+type language struct {
+	versionKey     string
+	action         string
+	input          string
+	with           map[string]string
+	defaultInstall string
+	defaultScript  string
+}
+
+var languages = map[string]language{
+	"node_js": {versionKey: "node_js", action: "actions/setup-node@v4", input: "node-version", defaultInstall: "npm ci", defaultScript: "npm test"},
+	"python":  {versionKey: "python", action: "actions/setup-python@v5", input: "python-version"},
+	"go":      {versionKey: "go", action: "actions/setup-go@v5", input: "go-version", defaultScript: "go test -v ./..."},
+	"ruby":    {versionKey: "rvm", action: "ruby/setup-ruby@v1", input: "ruby-version", defaultInstall: "bundle install", defaultScript: "bundle exec rake"},
+	"java":    {versionKey: "jdk", action: "actions/setup-java@v4", input: "java-version", with: map[string]string{"distribution": "temurin"}},
+	"php":     {versionKey: "php", action: "shivammathur/setup-php@v2", input: "php-version", defaultInstall: "composer install"},
+}
+
+// runners are the GitHub-hosted runners of the operating systems of Travis CI
+var runners = map[string]string{
+	"linux":   "ubuntu-latest",
+	"osx":     "macos-latest",
+	"windows": "windows-latest",
+}
+
+// phase is a phase of the build of Travis CI, which is converted to a run step with the condition it runs on
+type phase struct {
+	key       string
+	name      string
+	condition string
+}
+
+var phases = []phase{
+	{key: "before_install", name: "Before install"},
+	{key: "install", name: "Install"},
+	{key: "before_script", name: "Before script"},
+	{key: "script", name: "Script"},
+	{key: "after_success", name: "After success"},
+	{key: "after_failure", name: "After failure", condition: "failure()"},
+	{key: "after_script", name: "After script", condition: "always()"},
+}
+
+// handledKeys are the keys that are converted along with the phases and the versions of the languages, and those that
+// need no equivalent in a workflow, e.g. the distribution of Linux
+var handledKeys = map[string]bool{"language": true, "os": true, "dist": true, "sudo": true, "group": true, "arch": true,
+	"branches": true, "env": true, "version": true}
+
+var majorVersionRegex = regexp.MustCompile(`\d+`)
+
+// ConvertConfigResponse is the workflow a Travis CI configuration is converted to, and the findings of the keys that
+// are not converted
+type ConvertConfigResponse struct {
+	Workflow string
+	Findings []findings.Finding
+}
+
+type step struct {
+	Name string            `yaml:"name,omitempty"`
+	If   string            `yaml:"if,omitempty"`
+	Uses string            `yaml:"uses,omitempty"`
+	With map[string]string `yaml:"with,omitempty"`
+	Run  string            `yaml:"run,omitempty"`
+}
+
+type strategy struct {
+	Matrix map[string][]string `yaml:"matrix"`
+}
+
+type job struct {
+	RunsOn   string    `yaml:"runs-on"`
+	Strategy *strategy `yaml:"strategy,omitempty"`
+	Steps    []step    `yaml:"steps"`
+}
+
+// workflowBody is the part of the workflow after its triggers and permissions, which are written as text
+type workflowBody struct {
+	Env  map[string]string `yaml:"env,omitempty"`
+	Jobs map[string]job    `yaml:"jobs"`
+}
+
+// getValues returns the values of a node that is a scalar or a sequence of scalars
+func getValues(node *yaml.Node) []string {
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml.ScalarNode {
+		return []string{node.Value}
+	}
+	var values []string
+	for _, item := range node.Content {
+		if item.Kind == yaml.ScalarNode {
+			values = append(values, item.Value)
+		}
+	}
+	return values
+}
+
+// quote returns the value as a YAML scalar, which is quoted if needed
+func quote(value string) string {
+	out, _ := yaml.Marshal(value)
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// getTriggers returns the triggers of the workflow. Travis CI builds pushes and pull requests, of the branches in
+// branches.only or of those not in branches.except.
+func getTriggers(topNode *yaml.Node) string {
+	branches := document.MappingValue(topNode, "branches")
+	filter, names := "", []string(nil)
+	if only := getValues(document.MappingValue(branches, "only")); len(only) > 0 {
+		filter, names = "branches", only
+	} else if except := getValues(document.MappingValue(branches, "except")); len(except) > 0 {
+		filter, names = "branches-ignore", except
+	}
+	var triggers strings.Builder
+	triggers.WriteString("on:\n")
+	for _, event := range []string{"push", "pull_request"} {
+		triggers.WriteString("  " + event + ":\n")
+		if filter == "" {
+			continue
+		}
+		triggers.WriteString("    " + filter + ":\n")
+		for _, name := range names {
+			triggers.WriteString("      - " + quote(name) + "\n")
+		}
+	}
+	return triggers.String()
+}
+
+// getEnv returns the variables of env, or of env.global, which are set as NAME=value. Encrypted variables and the
+// variables of the jobs of a build matrix are reported as findings, since they are not converted.
+func getEnv(topNode *yaml.Node, addFinding func(node *yaml.Node, key, message string)) map[string]string {
+	envNode := document.MappingValue(topNode, "env")
+	if envNode == nil {
+		return nil
+	}
+	if envNode.Kind == yaml.MappingNode {
+		for _, key := range []string{"jobs", "matrix"} {
+			if node := document.MappingValue(envNode, key); node != nil {
+				addFinding(node, "env."+key, "Variables of the jobs of the build matrix are not converted, so the jobs need to be added to the matrix of the workflow")
+			}
+		}
+		envNode = document.MappingValue(envNode, "global")
+	} else if envNode.Kind == yaml.SequenceNode && len(envNode.Content) > 1 {
+		addFinding(envNode, "env", "Each entry of env is a job of the build matrix, which is not converted, so the jobs need to be added to the matrix of the workflow")
+		return nil
+	}
+	if envNode == nil {
+		return nil
+	}
+	var entries []*yaml.Node
+	if envNode.Kind == yaml.SequenceNode {
+		entries = envNode.Content
+	} else {
+		entries = []*yaml.Node{envNode}
+	}
+	env := map[string]string{}
+	for _, entry := range entries {
+		if entry.Kind != yaml.ScalarNode {
+			addFinding(entry, "env", "Encrypted variables are not converted, so they need to be added as secrets of the repository")
+			continue
+		}
+		for _, variable := range strings.Fields(entry.Value) {
+			if name, value, found := strings.Cut(variable, "="); found {
+				env[name] = strings.Trim(value, `"'`)
+			}
+		}
+	}
+	if len(env) == 0 {
+		return nil
+	}
+	return env
+}
+
+// ConvertConfig converts a Travis CI configuration to a workflow with a job that sets up the language and runs the
+// commands of the phases of the build. The workflow only has read access to the contents of the repository. Keys that
+// have no equivalent in the workflow, e.g. deploy and services, are reported as findings so they can be ported by hand.
+func ConvertConfig(inputYaml string) (*ConvertConfigResponse, error) {
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(inputYaml), &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("unable to convert %s: not a mapping", ConfigPath)
+	}
+	topNode := doc.Content[0]
+	response := &ConvertConfigResponse{}
+	addFinding := func(node *yaml.Node, key, message string) {
+		response.Findings = append(response.Findings, findings.Finding{
+			RuleID:     RuleUnconvertedKey,
+			Message:    message,
+			Action:     key,
+			Line:       node.Line,
+			Column:     node.Column,
+			Suggestion: "Port the key to the workflow by hand, and remove .travis.yml once the workflow passes",
+		})
+	}
+
+	convertedKeys := map[string]bool{}
+	for key := range handledKeys {
+		convertedKeys[key] = true
+	}
+	for _, p := range phases {
+		convertedKeys[p.key] = true
+	}
+	for _, lang := range languages {
+		convertedKeys[lang.versionKey] = true
+	}
+	for i := 0; i+1 < len(topNode.Content); i += 2 {
+		key := topNode.Content[i]
+		if !convertedKeys[key.Value] {
+			addFinding(key, key.Value, fmt.Sprintf("Key %s of %s is not converted to the workflow", key.Value, ConfigPath))
+		}
+	}
+
+	buildJob := job{RunsOn: runners["linux"], Steps: []step{{Uses: "actions/checkout@v4"}}}
+	matrix := map[string][]string{}
+	if osValues := getValues(document.MappingValue(topNode, "os")); len(osValues) > 0 {
+		var osRunners []string
+		for _, os := range osValues {
+			if runner, found := runners[os]; found {
+				osRunners = append(osRunners, runner)
+			}
+		}
+		if len(osRunners) == 1 {
+			buildJob.RunsOn = osRunners[0]
+		} else if len(osRunners) > 1 {
+			matrix["os"] = osRunners
+			buildJob.RunsOn = "${{ matrix.os }}"
+		}
+	}
+
+	// ruby is the default language of Travis CI
+	languageName, languageNode := "ruby", document.MappingValue(topNode, "language")
+	if languageNode != nil {
+		languageName = languageNode.Value
+	}
+	lang, knownLanguage := languages[languageName]
+	if !knownLanguage {
+		addFinding(languageNode, "language", fmt.Sprintf("Language %s is not set up by the workflow, so the step that installs it needs to be added", languageName))
+	} else {
+		versions := getValues(document.MappingValue(topNode, lang.versionKey))
+		if languageName == "java" {
+			// e.g. openjdk11 is version 11 of the Temurin distribution of OpenJDK
+			for i, version := range versions {
+				if major := majorVersionRegex.FindString(version); major != "" {
+					versions[i] = major
+				}
+			}
+		}
+		setup := step{Uses: lang.action}
+		if len(versions) > 0 || len(lang.with) > 0 {
+			setup.With = map[string]string{}
+			for key, value := range lang.with {
+				setup.With[key] = value
+			}
+		}
+		if len(versions) == 1 {
+			setup.With[lang.input] = versions[0]
+		} else if len(versions) > 1 {
+			matrix[lang.input] = versions
+			setup.With[lang.input] = "${{ matrix." + lang.input + " }}"
+		}
+		buildJob.Steps = append(buildJob.Steps, setup)
+	}
+	if len(matrix) > 0 {
+		buildJob.Strategy = &strategy{Matrix: matrix}
+	}
+
+	for _, p := range phases {
+		commands := getValues(document.MappingValue(topNode, p.key))
+		if document.MappingValue(topNode, p.key) == nil {
+			switch p.key {
+			case "install":
+				commands = []string{lang.defaultInstall}
+			case "script":
+				commands = []string{lang.defaultScript}
+			}
+		}
+		if len(commands) == 0 || commands[0] == "" || commands[0] == "skip" || commands[0] == "true" {
+			continue
+		}
+		buildJob.Steps = append(buildJob.Steps, step{Name: p.name, If: p.condition, Run: strings.Join(commands, "\n")})
+	}
+
+	var body bytes.Buffer
+	encoder := yaml.NewEncoder(&body)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(workflowBody{Env: getEnv(topNode, addFinding), Jobs: map[string]job{"build": buildJob}}); err != nil {
+		return nil, fmt.Errorf("unable to write workflow: %v", err)
+	}
+	response.Workflow = fmt.Sprintf("# Converted from %s\nname: CI\n\n%s\npermissions:\n  contents: read\n\n%s", ConfigPath, getTriggers(topNode), body.String())
+	return response, nil
+}
@@ -0,0 +1,134 @@
+package travis
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestConvertConfig(t *testing.T) {
+	input := `language: node_js
+node_js:
+  - "18"
+  - "20"
+os: linux
+dist: focal
+branches:
+  only:
+    - main
+    - /^release-.*$/
+env:
+  global:
+    - NODE_ENV=test CI=true
+    - secure: "abc123"
+before_install:
+  - npm install -g npm@10
+script:
+  - npm run lint
+  - npm test
+after_failure: cat npm-debug.log
+deploy:
+  provider: npm
+  api_key: $NPM_TOKEN
+services:
+  - redis
+`
+	want := `# Converted from .travis.yml
+name: CI
+
+on:
+  push:
+    branches:
+      - main
+      - /^release-.*$/
+  pull_request:
+    branches:
+      - main
+      - /^release-.*$/
+
+permissions:
+  contents: read
+
+env:
+  CI: "true"
+  NODE_ENV: test
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        node-version:
+          - "18"
+          - "20"
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-node@v4
+        with:
+          node-version: ${{ matrix.node-version }}
+      - name: Before install
+        run: npm install -g npm@10
+      - name: Install
+        run: npm ci
+      - name: Script
+        run: |-
+          npm run lint
+          npm test
+      - name: After failure
+        if: failure()
+        run: cat npm-debug.log
+`
+	response, err := ConvertConfig(input)
+	if err != nil {
+		t.Fatalf("ConvertConfig() returned error: %v", err)
+	}
+	if response.Workflow != want {
+		t.Errorf("ConvertConfig() =\n%s\nwant\n%s", response.Workflow, want)
+	}
+	var got []string
+	for _, finding := range response.Findings {
+		got = append(got, fmt.Sprintf("%s:%d:%s", finding.RuleID, finding.Line, finding.Action))
+	}
+	if fmt.Sprint(got) != "[travis-unconverted-key:21:deploy travis-unconverted-key:24:services travis-unconverted-key:14:env]" {
+		t.Errorf("findings = %v, want deploy, services and the encrypted variable", got)
+	}
+}
+
+func TestConvertConfigDefaults(t *testing.T) {
+	input := `language: java
+jdk: openjdk17
+os:
+  - linux
+  - osx
+script: ./gradlew check
+`
+	want := `# Converted from .travis.yml
+name: CI
+
+on:
+  push:
+  pull_request:
+
+permissions:
+  contents: read
+
+jobs:
+  build:
+    runs-on: ${{ matrix.os }}
+    strategy:
+      matrix:
+        os:
+          - ubuntu-latest
+          - macos-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-java@v4
+        with:
+          distribution: temurin
+          java-version: "17"
+      - name: Script
+        run: ./gradlew check
+`
+	response, err := ConvertConfig(input)
+	if err != nil || response.Workflow != want || len(response.Findings) != 0 {
+		t.Errorf("ConvertConfig() = %+v, %v\n%s\nwant\n%s", response, err, response.Workflow, want)
+	}
+}
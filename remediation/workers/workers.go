@@ -0,0 +1,63 @@
+package workers
+
+import (
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/step-security/secure-repo/remediation/logging"
+)
+
+const (
+	// CountEnv is the number of files of a request that are remediated at the same time, which is DefaultCount if it
+	// is not set. Remediations mostly wait on the GitHub API and registries, so more files than CPUs can be remediated.
+	CountEnv = "REMEDIATION_WORKERS"
+
+	DefaultCount = 8
+)
+
+// Count returns the number of workers in REMEDIATION_WORKERS, or DefaultCount if it is not set or invalid
+func Count() int {
+	value := os.Getenv(CountEnv)
+	if value == "" {
+		return DefaultCount
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 1 {
+		logging.Logger().Warn("invalid number of workers, using the default", "env", CountEnv, "value", value, "default", DefaultCount)
+		return DefaultCount
+	}
+	return count
+}
+
+// Map runs f on each item with at most count goroutines, and returns the results in the order of the items, so the
+// results do not depend on which item is done first. The items are run one after the other if count is 1.
+func Map[T, R any](items []T, count int, f func(T) R) []R {
+	results := make([]R, len(items))
+	if count > len(items) {
+		count = len(items)
+	}
+	if count <= 1 {
+		for i, item := range items {
+			results[i] = f(item)
+		}
+		return results
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < count; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = f(items[i])
+			}
+		}()
+	}
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
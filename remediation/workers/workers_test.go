@@ -0,0 +1,46 @@
+package workers
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	items := []int{5, 1, 4, 2, 3, 0, 6, 7}
+	var running, maxRunning int32
+	results := Map(items, 3, func(item int) int {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			seen := atomic.LoadInt32(&maxRunning)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, current) {
+				break
+			}
+		}
+		// later items finish first, so the order of the results is not the order they are done in
+		time.Sleep(time.Duration(item) * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return item * 10
+	})
+	for i, item := range items {
+		if results[i] != item*10 {
+			t.Errorf("results[%d] = %d, want %d", i, results[i], item*10)
+		}
+	}
+	if maxRunning > 3 {
+		t.Errorf("expected at most 3 items to run at the same time, got %d", maxRunning)
+	}
+
+	if results := Map([]int{}, 3, func(item int) int { return item }); len(results) != 0 {
+		t.Errorf("expected no results, got %v", results)
+	}
+}
+
+func TestCount(t *testing.T) {
+	for value, want := range map[string]int{"": DefaultCount, "16": 16, "1": 1, "0": DefaultCount, "many": DefaultCount} {
+		t.Setenv(CountEnv, value)
+		if got := Count(); got != want {
+			t.Errorf("Count() with %s=%q = %d, want %d", CountEnv, value, got, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package actionpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/workflow/maintainedactions"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+)
+
+const (
+	RuleDeniedAction     = "denied-action"
+	RuleDisallowedAction = "disallowed-action"
+	// RuleUnverifiableAction is reported for the docker images of steps when the policy has allow rules, which match
+	// actions and cannot allow an image
+	RuleUnverifiableAction = "unverifiable-action"
+)
+
+// VerifiedCreators are the organizations that are verified creators in the GitHub Marketplace.
+// There is no API to get the verified creators, so the list is maintained here.
+var VerifiedCreators = []string{
+	"actions",
+	"github",
+	"aws-actions",
+	"azure",
+	"docker",
+	"google-github-actions",
+	"hashicorp",
+	"microsoft",
+	"step-security",
+	"sigstore",
+	"slsa-framework",
+}
+
+// ActionPolicy is an organization policy for the actions that can be used in workflows.
+// Denied actions are never allowed. If any of the allow rules are set, an action must match one of them.
+type ActionPolicy struct {
+	// AllowedOrgs are owners whose actions are allowed, e.g. actions
+	AllowedOrgs []string `json:",omitempty"`
+	// AllowedActions and DeniedActions are action patterns, e.g. docker/login-action or aws-actions/*
+	AllowedActions []string `json:",omitempty"`
+	DeniedActions  []string `json:",omitempty"`
+	// VerifiedCreatorsOnly allows actions from the VerifiedCreators
+	VerifiedCreatorsOnly bool `json:",omitempty"`
+	// Replacements maps disallowed actions to the allowed action that replaces them
+	Replacements map[string]string `json:",omitempty"`
+}
+
+// ParseActionPolicy parses a JSON policy, e.g. from a query parameter
+func ParseActionPolicy(policyJSON string) (*ActionPolicy, error) {
+	policy := ActionPolicy{}
+	err := json.Unmarshal([]byte(policyJSON), &policy)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse action policy %v", err)
+	}
+	return &policy, nil
+}
+
+func toLower(values []string) []string {
+	var lower []string
+	for _, value := range values {
+		lower = append(lower, strings.ToLower(value))
+	}
+	return lower
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ActionPolicy) hasAllowRules() bool {
+	return len(p.AllowedOrgs) > 0 || len(p.AllowedActions) > 0 || p.VerifiedCreatorsOnly
+}
+
+// Evaluate returns the rule the action violates, or "" if the action is allowed
+func (p *ActionPolicy) Evaluate(action string) string {
+	action = strings.ToLower(action)
+	owner := strings.Split(action, "/")[0]
+
+	if pin.ActionExists(action, toLower(p.DeniedActions)) {
+		return RuleDeniedAction
+	}
+	if !p.hasAllowRules() {
+		return ""
+	}
+	if contains(p.AllowedOrgs, owner) || pin.ActionExists(action, toLower(p.AllowedActions)) {
+		return ""
+	}
+	if p.VerifiedCreatorsOnly && contains(VerifiedCreators, owner) {
+		return ""
+	}
+	return RuleDisallowedAction
+}
+
+// FindPolicyViolations returns findings for the actions and reusable workflows used in the workflow that the policy does not allow.
+// If the policy has a replacement for the action, it is added as the suggestion. The docker images of steps are reported
+// as unverifiable if the policy has allow rules, since they cannot be allowed by them.
+func FindPolicyViolations(inputYaml string, policy *ActionPolicy) ([]findings.Finding, error) {
+	index, err := document.Parse(inputYaml).Index()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+
+	var policyFindings []findings.Finding
+	// reusable workflows are also subject to the policy
+	for _, reference := range index.ActionReferences(true) {
+		uses := reference.Uses.Value
+		finding := findings.Finding{
+			JobName: reference.JobName,
+			Line:    reference.Uses.Line,
+			Column:  reference.Uses.Column,
+		}
+
+		if strings.HasPrefix(uses, "docker://") {
+			if !policy.hasAllowRules() {
+				continue
+			}
+			finding.RuleID = RuleUnverifiableAction
+			finding.Action = uses
+			finding.Message = fmt.Sprintf("Docker image %s cannot be verified by the action policy", strings.TrimPrefix(uses, "docker://"))
+			policyFindings = append(policyFindings, finding)
+			continue
+		}
+		// local actions are not subject to the policy
+		if !strings.Contains(uses, "@") || strings.HasPrefix(uses, "./") {
+			continue
+		}
+		action := strings.Split(uses, "@")[0]
+
+		rule := policy.Evaluate(action)
+		if rule == "" {
+			continue
+		}
+
+		finding.RuleID, finding.Action = rule, action
+		if rule == RuleDeniedAction {
+			finding.Message = fmt.Sprintf("Action %s is denied by the action policy", action)
+		} else {
+			finding.Message = fmt.Sprintf("Action %s is not allowed by the action policy", action)
+		}
+		finding.Suggestion = getReplacement(policy.Replacements, action)
+		policyFindings = append(policyFindings, finding)
+	}
+
+	return policyFindings, nil
+}
+
+// getReplacement returns the replacement of the action in the policy, or an empty string if it has none. The actions of
+// the replacements are matched case-insensitively, and if several match, the one written as the action is used, or
+// else the first one in sorted order.
+func getReplacement(replacements map[string]string, action string) string {
+	if replacement, found := replacements[action]; found {
+		return replacement
+	}
+	deniedActions := make([]string, 0, len(replacements))
+	for deniedAction := range replacements {
+		deniedActions = append(deniedActions, deniedAction)
+	}
+	sort.Strings(deniedActions)
+	for _, deniedAction := range deniedActions {
+		if strings.EqualFold(deniedAction, action) {
+			return replacements[deniedAction]
+		}
+	}
+	return ""
+}
+
+// ReplaceDisallowedActions replaces the actions in the findings that have a replacement in the policy,
+// and marks the findings that were fixed.
+func ReplaceDisallowedActions(ctx context.Context, inputYaml string, policyFindings []findings.Finding, replaceByMajorTag bool) (string, bool, error) {
+	actionMap := make(map[string]string)
+	for _, finding := range policyFindings {
+		if finding.Suggestion != "" {
+			actionMap[finding.Action] = finding.Suggestion
+		}
+	}
+
+	if len(actionMap) == 0 {
+		return inputYaml, false, nil
+	}
+
+	out, replacedLines, err := maintainedactions.ReplaceActionLines(ctx, inputYaml, actionMap, replaceByMajorTag)
+	if err != nil {
+		return inputYaml, false, err
+	}
+
+	// the findings are fixed if the uses of their line was replaced, which is not the case for the reusable workflows,
+	// or for the actions whose replacement could not be resolved
+	replaced := make(map[int]bool)
+	for _, line := range replacedLines {
+		replaced[line] = true
+	}
+	for i, finding := range policyFindings {
+		if finding.Suggestion != "" && replaced[finding.Line] {
+			policyFindings[i].Fixed = true
+		}
+	}
+
+	return out, len(replacedLines) > 0, nil
+}
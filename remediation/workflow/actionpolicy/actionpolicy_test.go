@@ -0,0 +1,181 @@
+package actionpolicy
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestActionPolicy_Evaluate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ActionPolicy
+		action string
+		want   string
+	}{
+		{name: "empty policy", policy: ActionPolicy{}, action: "someone/action", want: ""},
+		{name: "denied action", policy: ActionPolicy{DeniedActions: []string{"tj-actions/*"}}, action: "tj-actions/changed-files", want: RuleDeniedAction},
+		{name: "denied overrides allowed", policy: ActionPolicy{AllowedOrgs: []string{"tj-actions"}, DeniedActions: []string{"tj-actions/changed-files"}}, action: "tj-actions/changed-files", want: RuleDeniedAction},
+		{name: "allowed org", policy: ActionPolicy{AllowedOrgs: []string{"Actions"}}, action: "actions/checkout", want: ""},
+		{name: "allowed action", policy: ActionPolicy{AllowedActions: []string{"docker/login-action"}}, action: "docker/login-action", want: ""},
+		{name: "not allowed", policy: ActionPolicy{AllowedActions: []string{"docker/login-action"}}, action: "docker/build-push-action", want: RuleDisallowedAction},
+		{name: "verified creator", policy: ActionPolicy{VerifiedCreatorsOnly: true}, action: "aws-actions/configure-aws-credentials", want: ""},
+		{name: "not verified creator", policy: ActionPolicy{VerifiedCreatorsOnly: true}, action: "someone/action", want: RuleDisallowedAction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Evaluate(tt.action); got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getReplacement(t *testing.T) {
+	replacements := map[string]string{
+		"Someone/Action": "first/action",
+		"someone/ACTION": "second/action",
+		"someone/action": "exact/action",
+		"other/Action":   "third/action",
+		"OTHER/action":   "fourth/action",
+	}
+	tests := []struct {
+		action string
+		want   string
+	}{
+		{action: "someone/action", want: "exact/action"},
+		{action: "other/action", want: "fourth/action"},
+		{action: "unknown/action", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			// the replacements are a map, so the same one must be returned in every run
+			for i := 0; i < 20; i++ {
+				if got := getReplacement(replacements, tt.action); got != tt.want {
+					t.Fatalf("getReplacement() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPolicyViolations(t *testing.T) {
+	const inputDirectory = "../../../testfiles/actionpolicy/input"
+	const outputDirectory = "../../../testfiles/actionpolicy/output"
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/changed-files/releases/latest",
+		httpmock.NewStringResponder(200, `{"tag_name": "v46.0.1"}`))
+
+	policy, err := ParseActionPolicy(`{"AllowedOrgs": ["actions", "docker"], "VerifiedCreatorsOnly": true, "DeniedActions": ["tj-actions/*"],
+		"Replacements": {"tj-actions/changed-files": "step-security/changed-files"}}`)
+	if err != nil {
+		t.Fatalf("ParseActionPolicy() unexpected error = %v", err)
+	}
+
+	input, err := ioutil.ReadFile(path.Join(inputDirectory, "policy.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+
+	got, err := FindPolicyViolations(string(input), policy)
+	if err != nil {
+		t.Fatalf("FindPolicyViolations() unexpected error = %v", err)
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("FindPolicyViolations() returned %d findings, want 4: %v", len(got), got)
+	}
+	if got[0].RuleID != RuleDisallowedAction || got[0].Action != "someone/deploy-action" || got[0].Line != 11 || got[0].Suggestion != "" {
+		t.Errorf("unexpected finding for disallowed action: %+v", got[0])
+	}
+	if got[1].RuleID != RuleDeniedAction || got[1].Action != "tj-actions/changed-files" || got[1].Suggestion != "step-security/changed-files" {
+		t.Errorf("unexpected finding for denied action: %+v", got[1])
+	}
+	if got[2].RuleID != RuleUnverifiableAction || got[2].Action != "docker://alpine:3.19" || got[2].Line != 14 {
+		t.Errorf("unexpected finding for docker image: %+v", got[2])
+	}
+	if got[3].RuleID != RuleDisallowedAction || got[3].Action != "other-org/workflows/.github/workflows/build.yml" || got[3].JobName != "reusable" {
+		t.Errorf("unexpected finding for reusable workflow: %+v", got[3])
+	}
+
+	out, updated, err := ReplaceDisallowedActions(context.Background(), string(input), got, false)
+	if err != nil {
+		t.Fatalf("ReplaceDisallowedActions() unexpected error = %v", err)
+	}
+	if !updated {
+		t.Errorf("ReplaceDisallowedActions() updated = false, want true")
+	}
+
+	output, err := ioutil.ReadFile(path.Join(outputDirectory, "policy.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+	if out != string(output) {
+		t.Errorf("ReplaceDisallowedActions() = %v, want %v", out, string(output))
+	}
+
+	// the denied actions of the output are replaced, so replacing them again does not change it
+	again, err := FindPolicyViolations(out, policy)
+	if err != nil {
+		t.Fatalf("FindPolicyViolations() of the output unexpected error = %v", err)
+	}
+	if replaced, updated, err := ReplaceDisallowedActions(context.Background(), out, again, false); err != nil || updated || replaced != out {
+		t.Errorf("ReplaceDisallowedActions() of the output = %v, %v, want it unchanged", updated, err)
+	}
+
+	if got[0].Fixed || !got[1].Fixed || got[2].Fixed || got[3].Fixed {
+		t.Errorf("unexpected fixed status for findings: %+v", got)
+	}
+}
+
+func TestPolicyViolationsOfImages(t *testing.T) {
+	input := "jobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: docker://alpine:3.19\n"
+	// the images cannot match the denied actions, so they are only reported for the policies with allow rules
+	got, err := FindPolicyViolations(input, &ActionPolicy{DeniedActions: []string{"tj-actions/*"}})
+	if err != nil || len(got) != 0 {
+		t.Errorf("FindPolicyViolations() = %v, %v, want no findings for a denylist", got, err)
+	}
+	got, err = FindPolicyViolations(input, &ActionPolicy{AllowedOrgs: []string{"actions"}})
+	if err != nil || len(got) != 1 || got[0].RuleID != RuleUnverifiableAction || got[0].Line != 5 {
+		t.Errorf("FindPolicyViolations() = %v, %v, want the image to be unverifiable", got, err)
+	}
+}
+
+func TestReplaceDisallowedActionsFixed(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/changed-files/releases/latest",
+		httpmock.NewStringResponder(200, `{"tag_name": "v46.0.1"}`))
+
+	// the action is written in another case on the second step and in the script of the last step, and the
+	// replacement of the third step cannot be resolved
+	input := `jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: tj-actions/changed-files@v41
+      - uses: TJ-Actions/changed-files@v41
+      - uses: tj-actions/verify-changed-files@v17
+      - run: echo "replaced tj-actions/changed-files@v41"
+`
+	policy := &ActionPolicy{DeniedActions: []string{"tj-actions/*"}, Replacements: map[string]string{
+		"tj-actions/changed-files": "step-security/changed-files", "tj-actions/verify-changed-files": "step-security/verify-changed-files"}}
+	got, err := FindPolicyViolations(input, policy)
+	if err != nil || len(got) != 3 {
+		t.Fatalf("FindPolicyViolations() = %v, %v, want 3 findings", got, err)
+	}
+	out, updated, err := ReplaceDisallowedActions(context.Background(), input, got, false)
+	if err != nil || !updated {
+		t.Fatalf("ReplaceDisallowedActions() = %v, %v", updated, err)
+	}
+	if !got[0].Fixed || !got[1].Fixed || got[2].Fixed {
+		t.Errorf("expected the findings of the replaced steps only to be fixed, got %+v\n%s", got, out)
+	}
+}
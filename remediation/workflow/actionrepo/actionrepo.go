@@ -0,0 +1,153 @@
+// Package actionrepo fetches the metadata of the repositories of actions once per repository and ref, and serves all
+// the modules from it, e.g. the tags that pin looks up the semantic version of a commit in, and that maintainedactions
+// looks up the major version of a commit in, instead of listing the tags in each module.
+package actionrepo
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v40/github"
+)
+
+// Tag is a tag of a repository. SHA is the commit of a lightweight tag, or the tag object of an annotated tag, whose
+// commit is looked up when it is needed.
+type Tag struct {
+	Name      string
+	SHA       string
+	Annotated bool
+}
+
+// Tags are the tags of a repository, sorted by name
+type Tags struct {
+	Tags []Tag
+}
+
+// Cache returns the metadata of the key, or fetches it with fetch and keeps it. It is set to the response cache by the
+// workflow package, so the instances fetch the metadata of a repository and ref once until it expires, since the
+// packages compiled to WebAssembly cannot depend on the cache. The metadata is fetched for each call if it is nil.
+var Cache func(key string, fetch func() (*json.RawMessage, error)) (*json.RawMessage, error)
+
+// Fetch returns the metadata of the kind for the ref of the repository, e.g. the commit of a tag, or fetches it and
+// keeps it in Cache. The ref is empty for the metadata of the repository itself, e.g. its tags.
+func Fetch[T any](kind, owner, repo, ref string, fetch func() (*T, error)) (*T, error) {
+	if Cache == nil {
+		return fetch()
+	}
+	raw, err := Cache(kind+" "+strings.ToLower(owner+"/"+repo)+"@"+ref, func() (*json.RawMessage, error) {
+		value, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := json.Marshal(value)
+		return (*json.RawMessage)(&raw), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	value := new(T)
+	return value, json.Unmarshal(*raw, value)
+}
+
+// ListTags returns the tags of the repository, with one request for each 100 tags
+func ListTags(ctx context.Context, client *github.Client, owner, repo string) (*Tags, error) {
+	list := func() (*Tags, error) {
+		tags := &Tags{}
+		opts := &github.ReferenceListOptions{Ref: "tags", ListOptions: github.ListOptions{PerPage: 100}}
+		for {
+			refs, resp, err := client.Git.ListMatchingRefs(ctx, owner, repo, opts)
+			if err != nil {
+				// the error is returned as is, so the callers can check its status
+				return nil, err
+			}
+			for _, ref := range refs {
+				tags.Tags = append(tags.Tags, Tag{
+					Name:      strings.TrimPrefix(ref.GetRef(), "refs/tags/"),
+					SHA:       ref.GetObject().GetSHA(),
+					Annotated: ref.GetObject().GetType() == "tag",
+				})
+			}
+			if resp == nil || resp.NextPage == 0 {
+				return tags, nil
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+	return Fetch("tags", owner, repo, "", list)
+}
+
+// WithPrefix returns the tags whose name starts with the prefix, e.g. v4. for the versions of v4, in the order of
+// their names
+func (t *Tags) WithPrefix(prefix string) []Tag {
+	var tags []Tag
+	for _, tag := range t.Tags {
+		if strings.HasPrefix(tag.Name, prefix) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Has returns true if the repository has a tag of the name
+func (t *Tags) Has(name string) bool {
+	for _, tag := range t.Tags {
+		if tag.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CommitSHA returns the commit of the tag, which is looked up for an annotated tag
+func (tag Tag) CommitSHA(ctx context.Context, client *github.Client, owner, repo string) (string, error) {
+	if !tag.Annotated {
+		return tag.SHA, nil
+	}
+	return CommitSHA(ctx, client, owner, repo, tag.Name)
+}
+
+// CommitSHA returns the commit of the tag or branch
+func CommitSHA(ctx context.Context, client *github.Client, owner, repo, ref string) (string, error) {
+	sha, err := Fetch("commit", owner, repo, ref, func() (*string, error) {
+		sha, _, err := client.Repositories.GetCommitSHA1(ctx, owner, repo, ref, "")
+		return &sha, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return *sha, nil
+}
+
+// LatestRelease returns the tag of the latest release of the repository
+func LatestRelease(ctx context.Context, client *github.Client, owner, repo string) (string, error) {
+	tagName, err := Fetch("latest-release", owner, repo, "", func() (*string, error) {
+		release, _, err := client.Repositories.GetLatestRelease(ctx, owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		return github.String(release.GetTagName()), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return *tagName, nil
+}
+
+// Repository is the state of a repository, which unmaintained checks
+type Repository struct {
+	Archived bool
+	PushedAt time.Time
+}
+
+// GetRepository returns the state of the repository
+func GetRepository(ctx context.Context, client *github.Client, owner, repo string) (*Repository, error) {
+	return Fetch("repository", owner, repo, "", func() (*Repository, error) {
+		repository, _, err := client.Repositories.Get(ctx, owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		return &Repository{Archived: repository.GetArchived(), PushedAt: repository.GetPushedAt().Time}, nil
+	})
+}
@@ -0,0 +1,113 @@
+package actionrepo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v40/github"
+	"github.com/jarcoal/httpmock"
+)
+
+func TestListTags(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// the tags are listed once for all the lookups, over several pages
+	calls := 0
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/git/matching-refs/tags",
+		func(req *http.Request) (*http.Response, error) {
+			calls++
+			if req.URL.Query().Get("page") == "2" {
+				return httpmock.NewStringResponse(200, `[{"ref":"refs/tags/v4.1.0","object":{"sha":"annotated","type":"tag"}}]`), nil
+			}
+			resp := httpmock.NewStringResponse(200, `[
+				{"ref":"refs/tags/v3","object":{"sha":"v3sha","type":"commit"}},
+				{"ref":"refs/tags/v4","object":{"sha":"v4sha","type":"commit"}},
+				{"ref":"refs/tags/v4.0.0","object":{"sha":"v4sha","type":"commit"}}
+			]`)
+			resp.Header.Set("Link", `<https://api.github.com/repos/actions/checkout/git/matching-refs/tags?page=2>; rel="next"`)
+			return resp, nil
+		})
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/commits/v4.1.0",
+		httpmock.NewStringResponder(200, `v41sha`))
+	useCache(t)
+
+	client := github.NewClient(nil)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		tags, err := ListTags(ctx, client, "actions", "checkout")
+		if err != nil {
+			t.Fatalf("ListTags() unexpected error = %v", err)
+		}
+		if len(tags.Tags) != 4 || !tags.Has("v4") || tags.Has("v5") {
+			t.Errorf("unexpected tags %+v", tags.Tags)
+		}
+		versions := tags.WithPrefix("v4.")
+		if len(versions) != 2 || versions[1].Name != "v4.1.0" {
+			t.Fatalf("WithPrefix(v4.) = %+v", versions)
+		}
+		if sha, err := versions[1].CommitSHA(ctx, client, "actions", "checkout"); err != nil || sha != "v41sha" {
+			t.Errorf("CommitSHA() = %s, %v, want the commit of the annotated tag", sha, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected the two pages of tags to be listed once, got %d requests", calls)
+	}
+	if info := httpmock.GetCallCountInfo(); info["GET https://api.github.com/repos/actions/checkout/commits/v4.1.0"] != 1 {
+		t.Errorf("expected the commit of the annotated tag to be looked up once, got %v", info)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/releases/latest",
+		httpmock.NewStringResponder(200, `{"tag_name":"v4.2.2"}`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout",
+		httpmock.NewStringResponder(200, `{"archived":true,"pushed_at":"2024-01-02T03:04:05Z"}`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/commits/main",
+		httpmock.NewStringResponder(200, `mainsha`))
+	useCache(t)
+
+	client := github.NewClient(nil)
+	ctx := context.Background()
+	for _, owner := range []string{"actions", "Actions"} {
+		if tagName, err := LatestRelease(ctx, client, "actions", "checkout"); err != nil || tagName != "v4.2.2" {
+			t.Errorf("LatestRelease() = %s, %v", tagName, err)
+		}
+		repository, err := GetRepository(ctx, client, owner, "checkout")
+		if err != nil || !repository.Archived || repository.PushedAt.Year() != 2024 {
+			t.Errorf("GetRepository() = %+v, %v", repository, err)
+		}
+		if sha, err := CommitSHA(ctx, client, "actions", "checkout", "main"); err != nil || sha != "mainsha" {
+			t.Errorf("CommitSHA() = %s, %v", sha, err)
+		}
+	}
+	// each metadata is fetched once for the repository and ref, whatever the case of the repository
+	if calls := httpmock.GetTotalCallCount(); calls != 3 {
+		t.Errorf("expected the metadata to be fetched once, got %d requests", calls)
+	}
+
+	// the errors are not kept
+	if _, err := LatestRelease(ctx, client, "actions", "missing"); err == nil {
+		t.Errorf("LatestRelease() expected an error for a missing repository")
+	}
+}
+
+// useCache sets Cache to a map for the test, like the response cache
+func useCache(t *testing.T) {
+	cached := map[string]*json.RawMessage{}
+	Cache = func(key string, fetch func() (*json.RawMessage, error)) (*json.RawMessage, error) {
+		if value, found := cached[key]; found {
+			return value, nil
+		}
+		value, err := fetch()
+		if err == nil {
+			cached[key] = value
+		}
+		return value, err
+	}
+	t.Cleanup(func() { Cache = nil })
+}
@@ -1,26 +1,38 @@
 package workflow
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"sort"
 	"strings"
+
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
 )
 
 const (
 	CodeQLWorkflowFileName   = "codeql.yml"
 	DependencyReviewFileName = "dependency-review.yml"
 	ScorecardFileName        = "scorecards.yml"
+	GitleaksFileName         = "gitleaks.yml"
+	TrufflehogFileName       = "trufflehog.yml"
 	CodeQL                   = "CodeQL"
 	DependencyReview         = "Dependency-review"
 	Scorecard                = "Scorecard"
+	SecretScanning           = "Secret-scanning"
+	Gitleaks                 = "gitleaks"
+	Trufflehog               = "trufflehog"
 )
 
 type WorkflowParameters struct {
 	LanguagesToAdd []string
 	DefaultBranch  string
+	// SecretScanningTool is gitleaks (default) or trufflehog
+	SecretScanningTool string
+	// PinActions pins the actions in the generated workflow to a commit SHA
+	PinActions bool
 }
 
 func getTemplate(file string) (string, error) {
@@ -38,7 +50,23 @@ func getTemplate(file string) (string, error) {
 	return string(template), nil
 }
 
-func AddWorkflow(name string, workflowParameters WorkflowParameters) (string, error) {
+func AddWorkflow(ctx context.Context, name string, workflowParameters WorkflowParameters) (string, error) {
+	workflow, err := addWorkflow(name, workflowParameters)
+	if err != nil {
+		return "", err
+	}
+
+	if workflowParameters.PinActions {
+		workflow, _, err = pin.PinActions(ctx, workflow, nil, false, nil)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return workflow, nil
+}
+
+func addWorkflow(name string, workflowParameters WorkflowParameters) (string, error) {
 	if name == CodeQL {
 		codeqlWorkflow, err := getTemplate(CodeQLWorkflowFileName)
 		if err != nil {
@@ -67,6 +95,23 @@ func AddWorkflow(name string, workflowParameters WorkflowParameters) (string, er
 		scorecardsWorkflow = strings.ReplaceAll(scorecardsWorkflow, "$default-branch", fmt.Sprintf(`"%s"`, workflowParameters.DefaultBranch))
 		return scorecardsWorkflow, nil
 
+	} else if name == SecretScanning {
+		templateFileName := GitleaksFileName
+		switch workflowParameters.SecretScanningTool {
+		case "", Gitleaks:
+		case Trufflehog:
+			templateFileName = TrufflehogFileName
+		default:
+			return "", fmt.Errorf("secret scanning tool %s not supported", workflowParameters.SecretScanningTool)
+		}
+		secretScanningWorkflow, err := getTemplate(templateFileName)
+		if err != nil {
+			return "", err
+		}
+		secretScanningWorkflow = strings.ReplaceAll(secretScanningWorkflow, "$default-branch", fmt.Sprintf(`"%s"`, workflowParameters.DefaultBranch))
+		secretScanningWorkflow = strings.ReplaceAll(secretScanningWorkflow, "$cron-weekly", fmt.Sprintf(`"%s"`, "0 0 * * 1")) // Note: Runs every monday at 12:00 AM
+		return secretScanningWorkflow, nil
+
 	} else {
 		return "", fmt.Errorf("match for %s Workflow name not found", name)
 	}
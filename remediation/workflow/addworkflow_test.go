@@ -1,9 +1,12 @@
 package workflow
 
 import (
+	"context"
 	"io/ioutil"
 	"reflect"
 	"testing"
+
+	"github.com/jarcoal/httpmock"
 )
 
 func Test_AddWorkflow(t *testing.T) {
@@ -45,10 +48,27 @@ func Test_AddWorkflow(t *testing.T) {
 			expectedError:      false,
 			expectedOutputFile: "../../testfiles/addworkflow/expected-scorecards.yml",
 		},
+		{
+			workflowName: "Secret-scanning",
+			workflowParameters: WorkflowParameters{
+				DefaultBranch: "main",
+			},
+			expectedError:      false,
+			expectedOutputFile: "../../testfiles/addworkflow/expected-gitleaks.yml",
+		},
+		{
+			workflowName: "Secret-scanning",
+			workflowParameters: WorkflowParameters{
+				DefaultBranch:      "main",
+				SecretScanningTool: "unknown",
+			},
+			expectedError:      true,
+			expectedOutputFile: "",
+		},
 	}
 
 	for _, test := range tests {
-		output, err := AddWorkflow(test.workflowName, test.workflowParameters)
+		output, err := AddWorkflow(context.Background(), test.workflowName, test.workflowParameters)
 		if err != nil {
 			if !test.expectedError {
 				t.Errorf("Error adding Workflow: %v", err)
@@ -66,3 +86,31 @@ func Test_AddWorkflow(t *testing.T) {
 	}
 
 }
+
+func Test_AddWorkflowPinned(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/commits/v4",
+		httpmock.NewStringResponder(200, `b4ffde65f46336ab88eb53be808477a3936bae11`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/git/matching-refs/tags",
+		httpmock.NewStringResponder(200, `[]`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/trufflesecurity/trufflehog/commits/main",
+		httpmock.NewStringResponder(200, `a05cf0859455b5b16317ee22d809887a4043cdf0`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/trufflesecurity/trufflehog/git/matching-refs/tags",
+		httpmock.NewStringResponder(200, `[]`))
+
+	output, err := AddWorkflow(context.Background(), SecretScanning, WorkflowParameters{DefaultBranch: "main", SecretScanningTool: Trufflehog, PinActions: true})
+	if err != nil {
+		t.Fatalf("Error adding Workflow: %v", err)
+	}
+
+	expectedOutput, err := ioutil.ReadFile("../../testfiles/addworkflow/expected-trufflehog.yml")
+	if err != nil {
+		t.Errorf("Error in reading file: %v", err)
+	}
+
+	if output != string(expectedOutput) {
+		t.Errorf("test failed trufflehog did not match expected output\n%s", output)
+	}
+}
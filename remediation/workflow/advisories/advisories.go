@@ -0,0 +1,270 @@
+package advisories
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/outbound"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleVulnerableAction = "vulnerable-action"
+
+	// OSVEcosystem is the OSV ecosystem of GitHub Actions advisories
+	OSVEcosystem = "GitHub Actions"
+)
+
+// OSVQueryURL is a variable so tests can use a different endpoint
+var OSVQueryURL = "https://api.osv.dev/v1/query"
+
+// versionRegex matches versions with at least a minor version, since major tags such as v2 move to the latest release
+var versionRegex = regexp.MustCompile(`^v?\d+\.\d+(\.\d+)?$`)
+
+var shaRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+type osvPackage struct {
+	Name      string `json:"name"`
+	Ecosystem string `json:"ecosystem"`
+}
+
+type osvQuery struct {
+	Package osvPackage `json:"package"`
+	Version string     `json:"version"`
+}
+
+type osvEvent struct {
+	Introduced string `json:"introduced,omitempty"`
+	Fixed      string `json:"fixed,omitempty"`
+}
+
+type osvRange struct {
+	Type   string     `json:"type"`
+	Events []osvEvent `json:"events"`
+}
+
+type osvAffected struct {
+	Package osvPackage `json:"package"`
+	Ranges  []osvRange `json:"ranges"`
+}
+
+type osvVulnerability struct {
+	ID       string        `json:"id"`
+	Aliases  []string      `json:"aliases"`
+	Affected []osvAffected `json:"affected"`
+}
+
+type osvResponse struct {
+	Vulns []osvVulnerability `json:"vulns"`
+}
+
+// getVersion returns the version of the action reference. For actions pinned to a commit SHA,
+// the version is taken from the comment, e.g. # v2.1.0
+func getVersion(usesNode *yaml.Node) string {
+	ref := strings.Split(usesNode.Value, "@")[1]
+	if shaRegex.MatchString(ref) {
+		ref = strings.TrimSpace(strings.TrimPrefix(usesNode.LineComment, "#"))
+	}
+	if !versionRegex.MatchString(ref) {
+		return ""
+	}
+	return ref
+}
+
+// compareVersions compares dotted numeric versions, returning -1, 0 or 1
+func compareVersions(a, b string) int {
+	aParts := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bParts := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aPart, bPart int
+		if i < len(aParts) {
+			aPart, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bPart, _ = strconv.Atoi(bParts[i])
+		}
+		if aPart != bPart {
+			if aPart < bPart {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func queryOSV(ctx context.Context, action, version string) ([]osvVulnerability, error) {
+	body, err := json.Marshal(osvQuery{Package: osvPackage{Name: action, Ecosystem: OSVEcosystem}, Version: version})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, OSVQueryURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := outbound.Client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OSV query returned status %d", resp.StatusCode)
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response osvResponse
+	err = json.Unmarshal(respBody, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.Vulns, nil
+}
+
+// getFixedVersion returns the lowest version that fixes all the vulnerabilities, or "" if any of them is not fixed
+func getFixedVersion(action string, vulns []osvVulnerability) string {
+	fixedVersion := ""
+	for _, vuln := range vulns {
+		vulnFixedVersion := ""
+		for _, affected := range vuln.Affected {
+			if !strings.EqualFold(affected.Package.Name, action) {
+				continue
+			}
+			for _, r := range affected.Ranges {
+				for _, event := range r.Events {
+					if event.Fixed != "" && (vulnFixedVersion == "" || compareVersions(event.Fixed, vulnFixedVersion) > 0) {
+						vulnFixedVersion = event.Fixed
+					}
+				}
+			}
+		}
+		if vulnFixedVersion == "" {
+			return ""
+		}
+		if fixedVersion == "" || compareVersions(vulnFixedVersion, fixedVersion) > 0 {
+			fixedVersion = vulnFixedVersion
+		}
+	}
+	return fixedVersion
+}
+
+// FindVulnerableActions queries OSV for each action used in the workflow with a specific version,
+// and returns findings for actions whose version is affected by a known vulnerability.
+// If all the vulnerabilities are fixed in a later release, the fixed release is added as the suggestion.
+func FindVulnerableActions(ctx context.Context, inputYaml string) ([]findings.Finding, error) {
+	index, err := document.Parse(inputYaml).Index()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+
+	results := make(map[string][]osvVulnerability)
+
+	var vulnerableFindings []findings.Finding
+	for _, reference := range index.ActionReferences(false) {
+		uses := reference.Uses.Value
+		if !strings.Contains(uses, "@") || strings.HasPrefix(uses, "docker://") || strings.HasPrefix(uses, "./") {
+			continue
+		}
+		action := strings.Split(uses, "@")[0]
+		version := getVersion(reference.Uses)
+		if version == "" {
+			continue
+		}
+
+		key := strings.ToLower(action) + "@" + version
+		vulns, found := results[key]
+		if !found {
+			vulns, err = queryOSV(ctx, action, strings.TrimPrefix(version, "v"))
+			if err != nil {
+				return nil, fmt.Errorf("unable to query vulnerabilities for %s: %v", key, err)
+			}
+			results[key] = vulns
+		}
+		if len(vulns) == 0 {
+			continue
+		}
+
+		var ids []string
+		for _, vuln := range vulns {
+			ids = append(ids, vuln.ID)
+		}
+
+		finding := findings.Finding{
+			RuleID:  RuleVulnerableAction,
+			Message: fmt.Sprintf("Action %s@%s is affected by %s", action, version, strings.Join(ids, ", ")),
+			JobName: reference.JobName,
+			Action:  action,
+			Line:    reference.Uses.Line,
+			Column:  reference.Uses.Column,
+		}
+		if fixedVersion := getFixedVersion(action, vulns); fixedVersion != "" {
+			// keep the v prefix used by the reference
+			if strings.HasPrefix(version, "v") && !strings.HasPrefix(fixedVersion, "v") {
+				fixedVersion = "v" + fixedVersion
+			}
+			finding.Suggestion = action + "@" + fixedVersion
+		}
+		vulnerableFindings = append(vulnerableFindings, finding)
+	}
+
+	return vulnerableFindings, nil
+}
+
+// FixVulnerableActions bumps the actions in the findings to the suggested fixed release and pins them,
+// and marks the findings that were fixed.
+func FixVulnerableActions(ctx context.Context, inputYaml string, vulnerableFindings []findings.Finding, exemptedActions []string, pinToImmutable bool) (string, bool, error) {
+	inputLines := strings.Split(inputYaml, "\n")
+	var bumped []string
+	// the steps shared by jobs with an alias have a finding for each job, on the line of the anchor
+	fixedLines := make(map[int]bool)
+	for i, finding := range vulnerableFindings {
+		if finding.Suggestion == "" || finding.Line < 1 || finding.Line > len(inputLines) {
+			continue
+		}
+		if fixedLines[finding.Line] {
+			vulnerableFindings[i].Fixed = true
+			continue
+		}
+		// the previous version comment of pinned actions is removed
+		refRegex := regexp.MustCompile(regexp.QuoteMeta(finding.Action) + `@[^\s'"#]+(\s+#.*)?`)
+		line := inputLines[finding.Line-1]
+		if !refRegex.MatchString(line) {
+			continue
+		}
+		inputLines[finding.Line-1] = refRegex.ReplaceAllLiteralString(line, finding.Suggestion)
+		bumped = append(bumped, finding.Suggestion)
+		vulnerableFindings[i].Fixed = true
+		fixedLines[finding.Line] = true
+	}
+
+	if len(bumped) == 0 {
+		return inputYaml, false, nil
+	}
+
+	out := strings.Join(inputLines, "\n")
+	for _, action := range bumped {
+		var err error
+		out, _, err = pin.PinActionWithPatFallback(ctx, action, out, exemptedActions, pinToImmutable, nil)
+		if err != nil {
+			return out, true, err
+		}
+	}
+
+	return out, true, nil
+}
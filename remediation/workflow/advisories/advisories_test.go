@@ -0,0 +1,124 @@
+package advisories
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// registerOSVResponder returns vulnerabilities from vulns, keyed by package name and version
+func registerOSVResponder(vulns map[string]string) {
+	httpmock.RegisterResponder("POST", OSVQueryURL,
+		func(req *http.Request) (*http.Response, error) {
+			var query osvQuery
+			if err := json.NewDecoder(req.Body).Decode(&query); err != nil {
+				return httpmock.NewStringResponse(400, `{}`), nil
+			}
+			if response, found := vulns[query.Package.Name+"@"+query.Version]; found {
+				return httpmock.NewStringResponse(200, response), nil
+			}
+			return httpmock.NewStringResponse(200, `{}`), nil
+		})
+}
+
+func TestVulnerableActions(t *testing.T) {
+	const inputDirectory = "../../../testfiles/advisories/input"
+	const outputDirectory = "../../../testfiles/advisories/output"
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerOSVResponder(map[string]string{
+		"tj-actions/changed-files@41.0.0": `{"vulns": [{"id": "GHSA-mcph-m25j-8j63", "affected": [{"package": {"name": "tj-actions/changed-files", "ecosystem": "GitHub Actions"}, "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "41.0.0"}]}]}]},
+			{"id": "GHSA-mrrh-fwg8-r2c3", "affected": [{"package": {"name": "tj-actions/changed-files", "ecosystem": "GitHub Actions"}, "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "41.0.1"}]}]}]}]}`,
+		"example/no-fix@1.0.0":        `{"vulns": [{"id": "GHSA-xxxx-xxxx-xxxx", "affected": [{"package": {"name": "example/no-fix", "ecosystem": "GitHub Actions"}, "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}]}]}]}`,
+		"example/pinned-action@2.0.1": `{"vulns": [{"id": "GHSA-yyyy-yyyy-yyyy", "affected": [{"package": {"name": "example/pinned-action", "ecosystem": "GitHub Actions"}, "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "2.0.0"}, {"fixed": "2.1.0"}]}]}]}]}`,
+	})
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/tj-actions/changed-files/commits/v41.0.1",
+		httpmock.NewStringResponder(200, `a284dc1814e3fd07f2e34267fc8f81227ed29fb8`))
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/tj-actions/changed-files/git/matching-refs/tags",
+		httpmock.NewStringResponder(200, `[]`))
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/example/pinned-action/commits/v2.1.0",
+		httpmock.NewStringResponder(200, `89abcdef0123456789abcdef0123456789abcdef`))
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/example/pinned-action/git/matching-refs/tags",
+		httpmock.NewStringResponder(200, `[]`))
+
+	input, err := ioutil.ReadFile(path.Join(inputDirectory, "vulnerable-actions.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+
+	got, err := FindVulnerableActions(context.Background(), string(input))
+	if err != nil {
+		t.Fatalf("FindVulnerableActions() unexpected error = %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("FindVulnerableActions() returned %d findings, want 3: %v", len(got), got)
+	}
+
+	if got[0].Action != "tj-actions/changed-files" || got[0].Line != 9 || got[0].Suggestion != "tj-actions/changed-files@v41.0.1" {
+		t.Errorf("unexpected finding for vulnerable action: %+v", got[0])
+	}
+	if got[0].Message != "Action tj-actions/changed-files@v41.0.0 is affected by GHSA-mcph-m25j-8j63, GHSA-mrrh-fwg8-r2c3" {
+		t.Errorf("unexpected message for vulnerable action: %s", got[0].Message)
+	}
+	if got[1].Action != "example/no-fix" || got[1].Suggestion != "" {
+		t.Errorf("unexpected finding for action without a fix: %+v", got[1])
+	}
+	if got[2].Action != "example/pinned-action" || got[2].Suggestion != "example/pinned-action@v2.1.0" {
+		t.Errorf("unexpected finding for pinned action: %+v", got[2])
+	}
+
+	out, updated, err := FixVulnerableActions(context.Background(), string(input), got, nil, false)
+	if err != nil {
+		t.Fatalf("FixVulnerableActions() unexpected error = %v", err)
+	}
+	if !updated {
+		t.Errorf("FixVulnerableActions() updated = false, want true")
+	}
+
+	output, err := ioutil.ReadFile(path.Join(outputDirectory, "vulnerable-actions.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+	if out != string(output) {
+		t.Errorf("FixVulnerableActions() = %v, want %v", out, string(output))
+	}
+
+	// the vulnerable actions of the output are fixed, so fixing them again does not change it
+	again, err := FindVulnerableActions(context.Background(), out)
+	if err != nil {
+		t.Fatalf("FindVulnerableActions() of the output unexpected error = %v", err)
+	}
+	if fixed, updated, err := FixVulnerableActions(context.Background(), out, again, nil, false); err != nil || updated || fixed != out {
+		t.Errorf("FixVulnerableActions() of the output = %v, %v, want it unchanged", updated, err)
+	}
+
+	if !got[0].Fixed || got[1].Fixed || !got[2].Fixed {
+		t.Errorf("unexpected fixed status for findings: %+v", got)
+	}
+}
+
+func TestFindVulnerableActionsAPIError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", OSVQueryURL, httpmock.NewStringResponder(500, `{}`))
+
+	input := "jobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v3.1.0\n"
+
+	_, err := FindVulnerableActions(context.Background(), input)
+	if err == nil {
+		t.Errorf("FindVulnerableActions() expected an error but got none")
+	}
+}
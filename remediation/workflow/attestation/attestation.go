@@ -0,0 +1,224 @@
+package attestation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	AttestBuildProvenanceAction = "actions/attest-build-provenance"
+	AttestBuildProvenanceTag    = "v1"
+	AttestBuildProvenanceName   = "Attest build provenance"
+)
+
+// artifactInputs maps actions that upload artifacts to the input with the path of the artifacts
+var artifactInputs = map[string]string{
+	"actions/upload-artifact":      "path",
+	"softprops/action-gh-release":  "files",
+	"actions/upload-release-asset": "asset_path",
+}
+
+// releaseActions need contents: write to upload to a release
+var releaseActions = map[string]bool{
+	"softprops/action-gh-release":  true,
+	"actions/upload-release-asset": true,
+}
+
+func getAction(stepNode *yaml.Node) string {
+	usesNode := document.MappingValue(stepNode, "uses")
+	if usesNode == nil {
+		return ""
+	}
+	return strings.ToLower(strings.Split(usesNode.Value, "@")[0])
+}
+
+// quote returns the value as a single quoted YAML scalar
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// getSubjectPaths returns the paths of the artifacts uploaded by a job, the index of the first upload step,
+// and whether the job uploads to a release
+func getSubjectPaths(stepsNode *yaml.Node) ([]string, int, bool) {
+	var subjectPaths []string
+	seen := make(map[string]bool)
+	firstUpload := -1
+	uploadsToRelease := false
+
+	for i, stepNode := range stepsNode.Content {
+		action := getAction(stepNode)
+		input, found := artifactInputs[action]
+		if !found {
+			continue
+		}
+		pathNode := document.MappingValue(document.MappingValue(stepNode, "with"), input)
+		if pathNode == nil || pathNode.Kind != yaml.ScalarNode {
+			continue
+		}
+		for _, subjectPath := range strings.Split(pathNode.Value, "\n") {
+			subjectPath = strings.TrimSpace(subjectPath)
+			// exclusions in upload-artifact paths are not supported as subjects
+			if subjectPath == "" || strings.HasPrefix(subjectPath, "!") || seen[subjectPath] {
+				continue
+			}
+			seen[subjectPath] = true
+			subjectPaths = append(subjectPaths, subjectPath)
+		}
+		if firstUpload == -1 {
+			firstUpload = i
+		}
+		uploadsToRelease = uploadsToRelease || releaseActions[action]
+	}
+
+	return subjectPaths, firstUpload, uploadsToRelease
+}
+
+func isAttested(stepsNode *yaml.Node) bool {
+	for _, stepNode := range stepsNode.Content {
+		action := getAction(stepNode)
+		if action == AttestBuildProvenanceAction || action == "actions/attest" {
+			return true
+		}
+	}
+	return false
+}
+
+// getAttestationStep returns the lines of the attestation step, indented as the item of the step it is inserted before
+func getAttestationStep(stepNode *yaml.Node, indentUnit string, subjectPaths []string) []string {
+	propertyIndent := strings.Repeat(" ", stepNode.Column-1)
+	dashIndent := strings.Repeat(" ", stepNode.Column-3)
+
+	lines := []string{
+		fmt.Sprintf("%s- name: %s", dashIndent, AttestBuildProvenanceName),
+		fmt.Sprintf("%suses: %s@%s", propertyIndent, AttestBuildProvenanceAction, AttestBuildProvenanceTag),
+		fmt.Sprintf("%swith:", propertyIndent),
+	}
+	if len(subjectPaths) == 1 {
+		lines = append(lines, fmt.Sprintf("%s%ssubject-path: %s", propertyIndent, indentUnit, quote(subjectPaths[0])))
+	} else {
+		lines = append(lines, fmt.Sprintf("%s%ssubject-path: |", propertyIndent, indentUnit))
+		for _, subjectPath := range subjectPaths {
+			lines = append(lines, fmt.Sprintf("%s%s%s%s", propertyIndent, indentUnit, indentUnit, subjectPath))
+		}
+	}
+	return lines
+}
+
+// addPermissions adds the permissions needed for the attestation to the job.
+// If the job does not have permissions, they are added based on the workflow level permissions.
+func addPermissions(buffer *textedit.Buffer, topNode, jobKeyNode, jobNode *yaml.Node, indentUnit string, uploadsToRelease bool) {
+	required := []string{"id-token", "attestations"}
+
+	permissionsNode := document.MappingValue(jobNode, "permissions")
+	if permissionsNode != nil {
+		// write-all already has the permissions, and other forms are not changed
+		if permissionsNode.Kind != yaml.MappingNode || permissionsNode.Style&yaml.FlowStyle != 0 || len(permissionsNode.Content) == 0 {
+			return
+		}
+		indent := strings.Repeat(" ", permissionsNode.Content[0].Column-1)
+		lastLine := permissionsNode.Content[len(permissionsNode.Content)-1].Line
+		for _, scope := range required {
+			scopeKeyNode, scopeNode := document.MappingEntry(permissionsNode, scope)
+			if scopeNode == nil {
+				buffer.InsertLinesAfter(lastLine, fmt.Sprintf("%s%s: write", indent, scope))
+			} else if scopeNode.Value != "write" && scopeNode.Anchor == "" {
+				buffer.ReplaceLine(scopeKeyNode.Line, fmt.Sprintf("%s%s: write", indent, scope))
+			}
+		}
+		return
+	}
+
+	// the job level permissions replace the workflow level permissions, so they are copied
+	var scopes []string
+	values := make(map[string]string)
+	workflowPermissionsNode := document.MappingValue(topNode, "permissions")
+	if workflowPermissionsNode != nil && workflowPermissionsNode.Kind == yaml.ScalarNode && workflowPermissionsNode.Value == "write-all" {
+		return
+	}
+	if workflowPermissionsNode != nil && workflowPermissionsNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(workflowPermissionsNode.Content); i += 2 {
+			scopes = append(scopes, workflowPermissionsNode.Content[i].Value)
+			values[workflowPermissionsNode.Content[i].Value] = workflowPermissionsNode.Content[i+1].Value
+		}
+	}
+	if _, found := values["contents"]; !found {
+		scopes = append(scopes, "contents")
+		values["contents"] = "read"
+	}
+	if uploadsToRelease {
+		values["contents"] = "write"
+	}
+	for _, scope := range required {
+		if _, found := values[scope]; !found {
+			scopes = append(scopes, scope)
+		}
+		values[scope] = "write"
+	}
+
+	indent := strings.Repeat(" ", jobNode.Content[0].Column-1)
+	lines := []string{fmt.Sprintf("%spermissions:", indent)}
+	for _, scope := range scopes {
+		lines = append(lines, fmt.Sprintf("%s%s%s: %s", indent, indentUnit, scope, values[scope]))
+	}
+	buffer.InsertLinesAfter(document.OpeningLine(jobKeyNode, jobNode), lines...)
+}
+
+// AddBuildProvenance adds the actions/attest-build-provenance step to jobs that upload artifacts or release assets,
+// with the uploaded paths as the subject, so consumers can verify where and how the artifacts were built.
+// The step is added before the first upload, and the job is given the id-token and attestations permissions.
+func AddBuildProvenance(inputYaml string) (string, bool, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return inputYaml, false, nil
+	}
+	topNode := t.Content[0]
+
+	jobsKeyNode, jobsNode := document.MappingEntry(topNode, "jobs")
+	if jobsNode == nil || jobsNode.Kind != yaml.MappingNode || len(jobsNode.Content) == 0 {
+		return inputYaml, false, nil
+	}
+	indentUnit := strings.Repeat(" ", jobsNode.Content[0].Column-jobsKeyNode.Column)
+
+	buffer := textedit.NewBuffer(inputYaml)
+	updated := false
+
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobKeyNode, jobNode := jobsNode.Content[i], document.Resolve(jobsNode.Content[i+1])
+		if jobNode.Kind != yaml.MappingNode || jobNode.Style&yaml.FlowStyle != 0 || len(jobNode.Content) == 0 {
+			continue
+		}
+		stepsNode := document.MappingValue(jobNode, "steps")
+		if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode || stepsNode.Style&yaml.FlowStyle != 0 || isAttested(stepsNode) {
+			continue
+		}
+
+		subjectPaths, firstUpload, uploadsToRelease := getSubjectPaths(stepsNode)
+		if len(subjectPaths) == 0 {
+			continue
+		}
+
+		// the item of the step starts on the line with the dash, and is an alias if the step is written at its anchor
+		uploadItemNode := stepsNode.Content[firstUpload]
+		uploadStepNode := document.Resolve(uploadItemNode)
+		if uploadStepNode.Kind != yaml.MappingNode || uploadStepNode.Style&yaml.FlowStyle != 0 || uploadItemNode.Column < 3 {
+			continue
+		}
+		buffer.InsertLinesBefore(uploadItemNode.Line, getAttestationStep(uploadItemNode, indentUnit, subjectPaths)...)
+
+		addPermissions(buffer, topNode, jobKeyNode, jobNode, indentUnit, uploadsToRelease)
+		updated = true
+	}
+
+	if !updated {
+		return inputYaml, false, nil
+	}
+
+	return buffer.String(), true, nil
+}
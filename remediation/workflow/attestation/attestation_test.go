@@ -0,0 +1,53 @@
+package attestation
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestAddBuildProvenance(t *testing.T) {
+	const inputDirectory = "../../../testfiles/attestation/input"
+	const outputDirectory = "../../../testfiles/attestation/output"
+
+	tests := []struct {
+		fileName    string
+		wantUpdated bool
+	}{
+		{fileName: "upload-artifacts.yml", wantUpdated: true},
+		{fileName: "no-artifacts.yml", wantUpdated: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			input, err := ioutil.ReadFile(path.Join(inputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			got, gotUpdated, err := AddBuildProvenance(string(input))
+			if err != nil {
+				t.Errorf("AddBuildProvenance() unexpected error = %v", err)
+			}
+
+			if gotUpdated != tt.wantUpdated {
+				t.Errorf("AddBuildProvenance() updated = %v, wantUpdated %v", gotUpdated, tt.wantUpdated)
+			}
+
+			output, err := ioutil.ReadFile(path.Join(outputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			if got != string(output) {
+				t.Errorf("AddBuildProvenance() = %v, want %v", got, string(output))
+			}
+
+			// the output attests the artifacts, so adding the attestation again does not change it
+			again, againUpdated, err := AddBuildProvenance(got)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("AddBuildProvenance() of the output = %v, %v, want it unchanged", againUpdated, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/step-security/secure-repo/remediation/workflow/metadata"
+)
+
+// benchmarkDirectory has the corpus of the benchmarks, which are workflows like those of the repositories remediated
+// by the hosted instance
+const benchmarkDirectory = "../../testfiles/benchmarks"
+
+// generatedSize is the size of the generated workflow of the corpus, like the workflows with many jobs generated by
+// tools, so the costs that grow faster than the size of the workflow show in the benchmarks. Larger workflows take
+// seconds for each iteration of the modules whose costs grow with the square of the size.
+const generatedSize = 1 << 15
+
+// jobNameRegex matches the names of the jobs, which are the keys indented by two spaces
+var jobNameRegex = regexp.MustCompile(`(?m)^  ([a-z][a-z0-9-]*):$`)
+
+// benchmarkCorpus returns the workflows of the corpus by name, and a workflow generated from the jobs of monorepo.yml
+// that is larger than generatedSize
+func benchmarkCorpus(b *testing.B) map[string]string {
+	files, err := os.ReadDir(benchmarkDirectory)
+	if err != nil {
+		b.Fatalf("unable to read corpus: %v", err)
+	}
+	corpus := make(map[string]string)
+	for _, file := range files {
+		content, err := os.ReadFile(path.Join(benchmarkDirectory, file.Name()))
+		if err != nil {
+			b.Fatalf("unable to read %s: %v", file.Name(), err)
+		}
+		corpus[strings.TrimSuffix(file.Name(), ".yml")] = string(content)
+	}
+
+	// the jobs are the last key of monorepo.yml, so they are repeated with a suffix until the workflow is large enough
+	monorepo := corpus["monorepo"]
+	jobsStart := strings.Index(monorepo, "\njobs:\n") + len("\njobs:\n")
+	var generated strings.Builder
+	generated.WriteString(monorepo)
+	for i := 1; generated.Len() < generatedSize; i++ {
+		generated.WriteString("\n")
+		generated.WriteString(jobNameRegex.ReplaceAllString(monorepo[jobsStart:], fmt.Sprintf("  ${1}-%d:", i)))
+	}
+	corpus["generated"] = generated.String()
+	return corpus
+}
+
+// activateBenchmarkResponders answers the requests to the GitHub API of pinning actions and harden-runner, so the
+// benchmarks measure the remediations rather than the network
+func activateBenchmarkResponders() {
+	httpmock.Activate()
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`^https://api\.github\.com/repos/[^/]+/[^/]+/commits/`),
+		httpmock.NewStringResponder(200, `11bd71901bbe5b1630ceea73d27597364c9af683`))
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`^https://api\.github\.com/repos/[^/]+/[^/]+/git/matching-refs/tags`),
+		httpmock.NewStringResponder(200, `[]`))
+}
+
+// BenchmarkSecureWorkflow runs each remediation module of the YAML editing hot paths on its own, and all the default
+// modules together, on each workflow of the corpus. The response cache is not used, so each iteration remediates the
+// workflow.
+func BenchmarkSecureWorkflow(b *testing.B) {
+	b.Setenv("KBFolder", "")
+	if err := metadata.IndexKnowledgeBase(); err != nil {
+		b.Fatalf("unable to index the knowledge base: %v", err)
+	}
+	defer metadata.SetIndex(nil)
+	activateBenchmarkResponders()
+	defer httpmock.DeactivateAndReset()
+
+	// the default modules are turned off for the benchmarks of the other modules
+	only := func(params ...string) map[string]string {
+		queryParams := map[string]string{"addPermissions": "false", "pinActions": "false", "addHardenRunner": "false"}
+		for _, param := range params {
+			queryParams[param] = "true"
+		}
+		return queryParams
+	}
+	modules := []struct {
+		name   string
+		params map[string]string
+	}{
+		{name: "default", params: map[string]string{}},
+		{name: "permissions", params: only("addPermissions")},
+		{name: "pin", params: only("pinActions")},
+		{name: "hardenrunner", params: only("addHardenRunner")},
+		{name: "githubtoken", params: only("removeUnnecessaryTokens")},
+		{name: "dispatchinputs", params: only("fixDispatchInputs")},
+		{name: "privileged", params: only("checkPrivilegedContainers")},
+		{name: "buildargs", params: only("fixSecretBuildArgs")},
+		{name: "githubenv", params: only("sanitizeUntrustedEnvWrites")},
+		{name: "forkguard", params: only("addForkPullRequestGuards")},
+		{name: "deprecatedcommands", params: only("rewriteDeprecatedCommands")},
+		{name: "shelldefaults", params: only("addShellDefaults")},
+		{name: "sbom", params: only("addSBOM")},
+		{name: "attestation", params: only("addBuildProvenance")},
+		{name: "signing", params: only("addCosignSigning")},
+		{name: "typosquat", params: only("fixTyposquattedActions")},
+		{name: "pintools", params: only("pinRunTools")},
+	}
+	corpus := benchmarkCorpus(b)
+	for _, module := range modules {
+		for _, name := range []string{"ci", "release", "monorepo", "generated"} {
+			input := corpus[name]
+			b.Run(module.name+"/"+name, func(b *testing.B) {
+				b.SetBytes(int64(len(input)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := SecureWorkflow(context.Background(), module.params, input, &mockDynamoDBClient{}, Options{}); err != nil {
+						b.Fatalf("Error not expected: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
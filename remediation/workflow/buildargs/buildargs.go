@@ -0,0 +1,317 @@
+package buildargs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/yamledit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleSecretBuildArg = "secret-build-arg"
+
+	BuildPushAction = "docker/build-push-action"
+)
+
+var secretRegex = regexp.MustCompile(`\$\{\{\s*secrets\.`)
+
+// buildArgRegex matches --build-arg NAME=${{ secrets.X }} in docker build commands
+var buildArgRegex = regexp.MustCompile(`--build-arg(?:=|\s+)([A-Za-z_][A-Za-z0-9_]*)=(["']?)(\$\{\{\s*secrets\.[A-Za-z0-9_]+\s*\}\})(["']?)`)
+
+var dockerBuildRegex = regexp.MustCompile(`docker\s+(buildx\s+)?build\b`)
+
+func getAction(stepNode *yaml.Node) string {
+	usesNode := document.MappingValue(stepNode, "uses")
+	if usesNode == nil {
+		return ""
+	}
+	return strings.ToLower(strings.Split(usesNode.Value, "@")[0])
+}
+
+// getSteps returns the steps of all jobs, along with the job name, and the steps in the runs section for composite actions
+func getSteps(topNode *yaml.Node) ([]string, []*yaml.Node) {
+	var jobNames []string
+	var steps []*yaml.Node
+	addSteps := func(jobName string, stepsNode *yaml.Node) {
+		if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, stepNode := range stepsNode.Content {
+			jobNames = append(jobNames, jobName)
+			steps = append(steps, document.Resolve(stepNode))
+		}
+	}
+	if jobsNode := document.MappingValue(topNode, "jobs"); jobsNode != nil && jobsNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+			addSteps(jobsNode.Content[i].Value, document.MappingValue(jobsNode.Content[i+1], "steps"))
+		}
+	}
+	addSteps("", document.MappingValue(document.MappingValue(topNode, "runs"), "steps"))
+	return jobNames, steps
+}
+
+// getSecretBuildArgs returns the names of the build-args of a build-push-action step that are set from secrets
+func getSecretBuildArgs(buildArgsNode *yaml.Node) []string {
+	var names []string
+	for _, line := range strings.Split(buildArgsNode.Value, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) == 2 && secretRegex.MatchString(parts[1]) {
+			names = append(names, parts[0])
+		}
+	}
+	return names
+}
+
+// FindSecretBuildArgs returns findings for secrets passed to docker build as build args, with the build-args input of
+// docker/build-push-action or --build-arg in run steps. Build args are stored in the image history, so the secrets leak with the image.
+func FindSecretBuildArgs(inputYaml string) ([]findings.Finding, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return nil, nil
+	}
+
+	var buildArgFindings []findings.Finding
+	addFinding := func(jobName, name string, node *yaml.Node) {
+		buildArgFindings = append(buildArgFindings, findings.Finding{
+			RuleID:     RuleSecretBuildArg,
+			Message:    fmt.Sprintf("Secret is passed to docker build as the build arg %s, and is stored in the image history", name),
+			JobName:    jobName,
+			Line:       node.Line,
+			Column:     node.Column,
+			Suggestion: fmt.Sprintf("Pass the secret as a BuildKit secret, and read it with RUN --mount=type=secret,id=%s in the Dockerfile", name),
+		})
+	}
+
+	jobNames, steps := getSteps(t.Content[0])
+	for i, stepNode := range steps {
+		if getAction(stepNode) == BuildPushAction {
+			buildArgsKeyNode, buildArgsNode := document.MappingEntry(document.MappingValue(stepNode, "with"), "build-args")
+			if buildArgsNode != nil && buildArgsNode.Kind == yaml.ScalarNode {
+				for _, name := range getSecretBuildArgs(buildArgsNode) {
+					addFinding(jobNames[i], name, buildArgsKeyNode)
+				}
+			}
+			continue
+		}
+		runKeyNode, runNode := document.MappingEntry(stepNode, "run")
+		if runNode == nil || runNode.Kind != yaml.ScalarNode || !dockerBuildRegex.MatchString(runNode.Value) {
+			continue
+		}
+		for _, match := range buildArgRegex.FindAllStringSubmatch(runNode.Value, -1) {
+			addFinding(jobNames[i], match[1], runKeyNode)
+		}
+	}
+
+	return buildArgFindings, nil
+}
+
+// fixBuildPushStep moves the secret build-args of a build-push-action step to the secrets input
+func fixBuildPushStep(buffer *textedit.Buffer, inputLines []string, stepNode *yaml.Node, indentUnit string) bool {
+	withNode := document.MappingValue(stepNode, "with")
+	buildArgsKeyNode, buildArgsNode := document.MappingEntry(withNode, "build-args")
+	// the build args with an anchor are also the build args of its aliases, and deleting them would delete the anchor
+	if buildArgsNode.Style&yaml.FoldedStyle != 0 || buildArgsNode.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 || buildArgsNode.Anchor != "" {
+		return false
+	}
+	inputIndent := strings.Repeat(" ", withNode.Content[0].Column-1)
+
+	var secretLines []string
+	remaining := 0
+	if buildArgsNode.Style&yaml.LiteralStyle != 0 {
+		lastLine := yamledit.LastLine(inputLines, buildArgsNode)
+		for i := buildArgsNode.Line; i < lastLine && i < len(inputLines); i++ {
+			parts := strings.SplitN(strings.TrimSpace(inputLines[i]), "=", 2)
+			if len(parts) == 2 && secretRegex.MatchString(parts[1]) {
+				secretLines = append(secretLines, strings.TrimSpace(inputLines[i]))
+				buffer.DeleteLine(i + 1)
+			} else if strings.TrimSpace(inputLines[i]) != "" {
+				remaining++
+			}
+		}
+	} else if buildArgsNode.Line == buildArgsKeyNode.Line && !strings.Contains(buildArgsNode.Value, "\n") {
+		secretLines = append(secretLines, strings.TrimSpace(buildArgsNode.Value))
+	} else {
+		return false
+	}
+
+	if remaining == 0 {
+		buffer.DeleteLine(buildArgsKeyNode.Line)
+		for i := buildArgsKeyNode.Line; i < yamledit.LastLine(inputLines, buildArgsNode); i++ {
+			buffer.DeleteLine(i + 1)
+		}
+	}
+
+	secretsKeyNode, secretsNode := document.MappingEntry(withNode, "secrets")
+	switch {
+	case secretsNode != nil && secretsNode.Anchor != "":
+		return false
+	case secretsNode == nil:
+		lines := []string{fmt.Sprintf("%ssecrets: |", inputIndent)}
+		for _, secretLine := range secretLines {
+			lines = append(lines, fmt.Sprintf("%s%s%s", inputIndent, indentUnit, secretLine))
+		}
+		buffer.InsertLinesAfter(yamledit.LastLine(inputLines, buildArgsNode), lines...)
+	case secretsNode.Style&yaml.LiteralStyle != 0:
+		firstLine := inputLines[secretsNode.Line]
+		secretIndent := firstLine[:len(firstLine)-len(strings.TrimLeft(firstLine, " "))]
+		lastLine := yamledit.LastLine(inputLines, secretsNode)
+		for _, secretLine := range secretLines {
+			buffer.InsertLinesAfter(lastLine, secretIndent+secretLine)
+		}
+	case secretsNode.Kind == yaml.ScalarNode && secretsNode.Style == 0 && secretsNode.Line == secretsKeyNode.Line:
+		buffer.ReplaceLine(secretsKeyNode.Line, fmt.Sprintf("%ssecrets: |", inputIndent))
+		lines := []string{fmt.Sprintf("%s%s%s", inputIndent, indentUnit, secretsNode.Value)}
+		for _, secretLine := range secretLines {
+			lines = append(lines, fmt.Sprintf("%s%s%s", inputIndent, indentUnit, secretLine))
+		}
+		buffer.InsertLinesAfter(secretsKeyNode.Line, lines...)
+	default:
+		return false
+	}
+	return true
+}
+
+// fixRunStep replaces --build-arg NAME=${{ secrets.X }} with --secret id=NAME,env=NAME, and passes the secret in the env of the step
+func fixRunStep(buffer *textedit.Buffer, inputLines []string, stepNode *yaml.Node, indentUnit string) bool {
+	runKeyNode, runNode := document.MappingEntry(stepNode, "run")
+	firstLine, lastLine, column := runNode.Line-1, runNode.Line-1, textedit.ColumnOffset(inputLines[runNode.Line-1], runNode.Column)
+	if runNode.Style&yaml.LiteralStyle != 0 {
+		firstLine, lastLine, column = runNode.Line, yamledit.LastLine(inputLines, runNode)-1, 0
+	} else if runNode.Style != 0 || runNode.Line != runKeyNode.Line || strings.Contains(runNode.Value, "\n") {
+		return false
+	}
+	// a script with an anchor is also the script of its aliases, which do not have the environment variables
+	if runNode.Anchor != "" {
+		return false
+	}
+
+	envValues := make(map[string]string)
+	var envNames []string
+	rewritten := make(map[int]string)
+	for i := firstLine; i <= lastLine && i < len(inputLines) && column <= len(inputLines[i]); i++ {
+		line := inputLines[i][:column] + buildArgRegex.ReplaceAllStringFunc(inputLines[i][column:], func(match string) string {
+			submatch := buildArgRegex.FindStringSubmatch(match)
+			if submatch[2] != submatch[4] {
+				return match
+			}
+			name := submatch[1]
+			if _, found := envValues[name]; !found {
+				envNames = append(envNames, name)
+			}
+			envValues[name] = submatch[3]
+			return fmt.Sprintf("--secret id=%s,env=%s", name, name)
+		})
+		if line != inputLines[i] {
+			rewritten[i] = line
+		}
+	}
+	if len(rewritten) == 0 {
+		return false
+	}
+
+	propertyIndent := strings.Repeat(" ", stepNode.Content[0].Column-1)
+	envNode := document.MappingValue(stepNode, "env")
+	var envLines []string
+	for _, name := range envNames {
+		if existing := document.MappingValue(envNode, name); existing != nil {
+			if existing.Value != envValues[name] {
+				return false
+			}
+			continue
+		}
+		envLines = append(envLines, fmt.Sprintf("%s%s%s: %s", propertyIndent, indentUnit, name, envValues[name]))
+	}
+
+	switch {
+	case envNode == nil:
+		envLines = append([]string{fmt.Sprintf("%senv:", propertyIndent)}, envLines...)
+		if runKeyNode != stepNode.Content[0] {
+			buffer.InsertLinesBefore(runKeyNode.Line, envLines...)
+		} else {
+			stepLastLine := yamledit.LastLine(inputLines, stepNode)
+			buffer.InsertLinesAfter(stepLastLine, envLines...)
+		}
+	// an env with an anchor is not changed, since it is also the env of the steps of its aliases
+	case envNode.Kind == yaml.MappingNode && envNode.Style&yaml.FlowStyle == 0 && len(envNode.Content) > 0 && envNode.Anchor == "":
+		envIndent := strings.Repeat(" ", envNode.Content[0].Column-1)
+		for i := range envLines {
+			envLines[i] = envIndent + strings.TrimLeft(envLines[i], " ")
+		}
+		envLastLine := yamledit.LastLine(inputLines, envNode)
+		buffer.InsertLinesAfter(envLastLine, envLines...)
+	default:
+		return false
+	}
+
+	for i, line := range rewritten {
+		buffer.ReplaceLine(i+1, line)
+	}
+	return true
+}
+
+// FixSecretBuildArgs passes the secrets in the findings as BuildKit secrets instead of build args, and marks the findings that were fixed.
+// For docker/build-push-action, the build args are moved to the secrets input, and in run steps, --build-arg is replaced
+// with --secret, reading the secret from an environment variable. The Dockerfile needs to read the secrets with RUN --mount=type=secret.
+func FixSecretBuildArgs(inputYaml string, buildArgFindings []findings.Finding) (string, bool, error) {
+	if len(buildArgFindings) == 0 {
+		return inputYaml, false, nil
+	}
+
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	topNode := t.Content[0]
+
+	indentUnit := "  "
+	if jobsKeyNode, jobsNode := document.MappingEntry(topNode, "jobs"); jobsNode != nil && len(jobsNode.Content) > 0 {
+		indentUnit = strings.Repeat(" ", jobsNode.Content[0].Column-jobsKeyNode.Column)
+	}
+
+	findingLines := make(map[int]bool)
+	for _, finding := range buildArgFindings {
+		findingLines[finding.Line] = true
+	}
+
+	inputLines := strings.Split(inputYaml, "\n")
+	buffer := textedit.NewBuffer(inputYaml)
+	fixedLines := make(map[int]bool)
+
+	_, steps := getSteps(topNode)
+	for _, stepNode := range steps {
+		if stepNode.Kind != yaml.MappingNode || stepNode.Style&yaml.FlowStyle != 0 || len(stepNode.Content) == 0 {
+			continue
+		}
+		if getAction(stepNode) == BuildPushAction {
+			buildArgsKeyNode, _ := document.MappingEntry(document.MappingValue(stepNode, "with"), "build-args")
+			if buildArgsKeyNode != nil && findingLines[buildArgsKeyNode.Line] && fixBuildPushStep(buffer, inputLines, stepNode, indentUnit) {
+				fixedLines[buildArgsKeyNode.Line] = true
+			}
+			continue
+		}
+		runKeyNode, _ := document.MappingEntry(stepNode, "run")
+		if runKeyNode != nil && findingLines[runKeyNode.Line] && fixRunStep(buffer, inputLines, stepNode, indentUnit) {
+			fixedLines[runKeyNode.Line] = true
+		}
+	}
+
+	if len(fixedLines) == 0 {
+		return inputYaml, false, nil
+	}
+
+	for i, finding := range buildArgFindings {
+		if fixedLines[finding.Line] {
+			buildArgFindings[i].Fixed = true
+		}
+	}
+
+	return buffer.String(), true, nil
+}
@@ -0,0 +1,64 @@
+package buildargs
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestSecretBuildArgs(t *testing.T) {
+	const inputDirectory = "../../../testfiles/buildargs/input"
+	const outputDirectory = "../../../testfiles/buildargs/output"
+
+	input, err := ioutil.ReadFile(path.Join(inputDirectory, "build-args.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+
+	got, err := FindSecretBuildArgs(string(input))
+	if err != nil {
+		t.Fatalf("FindSecretBuildArgs() unexpected error = %v", err)
+	}
+
+	// the echo command is not a docker build, so it is not reported
+	if len(got) != 4 {
+		t.Fatalf("FindSecretBuildArgs() returned %d findings, want 4: %v", len(got), got)
+	}
+	if got[0].RuleID != RuleSecretBuildArg || got[0].JobName != "image" || got[0].Line != 12 {
+		t.Errorf("unexpected finding: %+v", got[0])
+	}
+	if got[2].Message != "Secret is passed to docker build as the build arg API_KEY, and is stored in the image history" {
+		t.Errorf("unexpected message: %s", got[2].Message)
+	}
+
+	out, updated, err := FixSecretBuildArgs(string(input), got)
+	if err != nil {
+		t.Fatalf("FixSecretBuildArgs() unexpected error = %v", err)
+	}
+	if !updated {
+		t.Errorf("FixSecretBuildArgs() updated = false, want true")
+	}
+
+	output, err := ioutil.ReadFile(path.Join(outputDirectory, "build-args.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+	if out != string(output) {
+		t.Errorf("FixSecretBuildArgs() = %v, want %v", out, string(output))
+	}
+
+	// the output is remediated, so fixing it again does not change it
+	again, err := FindSecretBuildArgs(out)
+	if err != nil {
+		t.Fatalf("FindSecretBuildArgs() of the output unexpected error = %v", err)
+	}
+	if fixed, updated, err := FixSecretBuildArgs(out, again); err != nil || updated || fixed != out {
+		t.Errorf("FixSecretBuildArgs() of the output = %v, %v, want it unchanged", updated, err)
+	}
+
+	for _, finding := range got {
+		if !finding.Fixed {
+			t.Errorf("finding was not fixed: %+v", finding)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/cache"
+	"github.com/step-security/secure-repo/remediation/httpcache"
+	"github.com/step-security/secure-repo/remediation/storage"
+	"github.com/step-security/secure-repo/remediation/workflow/actionrepo"
+	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+	"github.com/step-security/secure-repo/remediation/workflow/policy"
+)
+
+// RepositoryParams are the query parameters with the repository and the path of a file
+var RepositoryParams = []string{"owner", "repo", "path", "branch"}
+
+func init() {
+	// the refs of actions and of the other dependencies pinned to commits are kept in the response cache
+	pin.RefCache = func(key string, resolve func() (*pin.ResolvedRef, error)) (*pin.ResolvedRef, error) {
+		return cache.Do(cache.Default(), cache.Key("resolve-ref", key), resolve)
+	}
+	// the tags, commits and releases of the repositories of actions are fetched once, and shared by the modules
+	actionrepo.Cache = func(key string, fetch func() (*json.RawMessage, error)) (*json.RawMessage, error) {
+		return cache.Do(cache.Default(), cache.Key("action-repo", key), fetch)
+	}
+	// the responses of the GitHub API are kept in the storage, so they are revalidated by the other instances
+	httpcache.PersistentStore = func() (httpcache.Store, error) {
+		store, err := storage.Default()
+		if err != nil || store == nil {
+			return nil, err
+		}
+		return store, nil
+	}
+}
+
+// usesRepository returns true if the response of SecureWorkflow depends on the repository of the workflow, which is when
+// repository guards are added with it, or a policy decides the remediations of the repository
+func usesRepository(queryStringParams map[string]string, params Options) bool {
+	if queryStringParams["addRepositoryGuards"] == "true" {
+		return true
+	}
+	if params.Evaluator != nil {
+		return true
+	}
+	// a policy that could not be compiled fails the request, which is not cached either
+	evaluator, err := policy.DefaultEvaluator()
+	return evaluator != nil || err != nil
+}
+
+// SecureWorkflowCached runs SecureWorkflow, or returns its response cached for the same workflow and parameters. Unless the
+// response depends on the repository, the repository and the path of the workflow are left out of the key, so the same
+// workflow is remediated once for the repositories of an organization, and the path is set in the report afterwards.
+// The actions missing from the knowledge base are only stored when the response is computed.
+func SecureWorkflowCached(ctx context.Context, c *cache.Cache, queryStringParams map[string]string, inputYaml string, svc dynamodbiface.DynamoDBAPI, params Options) (*permissions.SecureWorkflowReponse, error) {
+	if c == nil {
+		return SecureWorkflow(ctx, queryStringParams, inputYaml, svc, params)
+	}
+	workflowParams := queryStringParams
+	if !usesRepository(queryStringParams, params) {
+		workflowParams = cache.WithoutParams(queryStringParams, RepositoryParams...)
+	}
+	// the logger and the evaluator of the policy are not part of the key, and the registered remediators are, since they
+	// change the response
+	keyParams := params
+	keyParams.Logger, keyParams.Evaluator = nil, nil
+	var remediatorNames []string
+	for _, remediator := range getRemediators() {
+		remediatorNames = append(remediatorNames, remediator.Name())
+	}
+	key := cache.Key("secure-workflow", workflowParams, keyParams, remediatorNames, inputYaml)
+
+	response, err := cache.Do(c, key, func() (*permissions.SecureWorkflowReponse, error) {
+		return SecureWorkflow(ctx, workflowParams, inputYaml, svc, params)
+	})
+	if err == nil && response.Report != nil {
+		response.Report.SetFile(queryStringParams["path"])
+	}
+	return response, err
+}
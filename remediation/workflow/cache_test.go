@@ -0,0 +1,87 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/step-security/secure-repo/remediation/cache"
+)
+
+// countingStore counts the responses found in the memory store it wraps
+type countingStore struct {
+	*cache.MemoryStore
+	hits int
+}
+
+func (s *countingStore) Get(key string) (*cache.Entry, error) {
+	entry, err := s.MemoryStore.Get(key)
+	if entry != nil {
+		s.hits++
+	}
+	return entry, err
+}
+
+func TestSecureWorkflowCached(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	store := &countingStore{MemoryStore: cache.NewMemoryStore(10)}
+	c := cache.New(time.Hour, store)
+
+	input := "on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make\n"
+	params := map[string]string{"pinActions": "false", "addHardenRunner": "false", "addProjectComment": "false",
+		"owner": "acme", "repo": "api", "path": ".github/workflows/ci.yml"}
+	first, err := SecureWorkflowCached(context.Background(), c, params, input, nil, Options{})
+	if err != nil {
+		t.Fatalf("SecureWorkflowCached() returned error: %v", err)
+	}
+
+	// the same workflow in another repository shares the response, with its own path in the report
+	params["repo"], params["path"] = "web", ".github/workflows/build.yml"
+	second, err := SecureWorkflowCached(context.Background(), c, params, input, nil, Options{})
+	if err != nil {
+		t.Fatalf("SecureWorkflowCached() returned error: %v", err)
+	}
+	if store.hits != 1 || second.FinalOutput != first.FinalOutput {
+		t.Errorf("expected the response of the same workflow to be cached, got %d hits", store.hits)
+	}
+	if change := second.Report.Modules[0].Changes[0]; change.File != ".github/workflows/build.yml" {
+		t.Errorf("expected the path of the workflow in the changes, got %q", change.File)
+	}
+
+	// the repository guards use the repository, so it is part of the key
+	params["addRepositoryGuards"] = "true"
+	SecureWorkflowCached(context.Background(), c, params, input, nil, Options{})
+	params["repo"] = "api"
+	SecureWorkflowCached(context.Background(), c, params, input, nil, Options{})
+	if store.hits != 1 {
+		t.Errorf("expected the responses of other repositories not to be shared with repository guards, got %d hits", store.hits)
+	}
+}
+
+func TestSecureWorkflowCanceled(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	store := &countingStore{MemoryStore: cache.NewMemoryStore(10)}
+	c := cache.New(time.Hour, store)
+
+	input := "on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make\n"
+	params := map[string]string{"pinActions": "false", "addHardenRunner": "false"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := SecureWorkflowCached(ctx, c, params, input, nil, Options{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SecureWorkflowCached() error = %v, want %v", err, context.Canceled)
+	}
+
+	// the request that was canceled is not cached, so the workflow is remediated again with the same key
+	if _, err := SecureWorkflowCached(context.Background(), c, params, input, nil, Options{}); err != nil {
+		t.Fatalf("SecureWorkflowCached() returned error: %v", err)
+	}
+	if store.hits != 0 {
+		t.Errorf("expected the canceled request not to be cached, got %d hits", store.hits)
+	}
+	SecureWorkflowCached(context.Background(), c, params, input, nil, Options{})
+	if store.hits != 1 {
+		t.Errorf("expected the context not to be part of the key, got %d hits", store.hits)
+	}
+}
@@ -0,0 +1,144 @@
+package deprecatedcommands
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+// commandRegex matches echo of a workflow command, e.g. echo "::set-output name=version::1.0.0"
+var commandRegex = regexp.MustCompile(`^(\s*)echo\s+(["']?)::(set-output|save-state|set-env) name=([A-Za-z0-9_.-]+)::(.*?)(["']?)(\s*)$`)
+
+var addPathRegex = regexp.MustCompile(`^(\s*)echo\s+(["']?)::add-path::(.*?)(["']?)(\s*)$`)
+
+// commandFiles are the environment files that replace the deprecated commands
+var commandFiles = map[string]string{
+	"set-output": "GITHUB_OUTPUT",
+	"save-state": "GITHUB_STATE",
+	"set-env":    "GITHUB_ENV",
+	"add-path":   "GITHUB_PATH",
+}
+
+// getRunNodes returns the run nodes of steps that use a POSIX shell, in jobs and in the runs section for composite actions
+func getRunNodes(topNode *yaml.Node) []*yaml.Node {
+	var runNodes []*yaml.Node
+	addSteps := func(defaultShell string, stepsNode *yaml.Node) {
+		if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, stepNode := range stepsNode.Content {
+			runNode := document.MappingValue(stepNode, "run")
+			if runNode == nil || runNode.Kind != yaml.ScalarNode {
+				continue
+			}
+			shell := defaultShell
+			if shellNode := document.MappingValue(stepNode, "shell"); shellNode != nil {
+				shell = shellNode.Value
+			}
+			if shell == "" || strings.HasPrefix(shell, "bash") || strings.HasPrefix(shell, "sh") {
+				runNodes = append(runNodes, runNode)
+			}
+		}
+	}
+
+	getDefaultShell := func(node *yaml.Node) string {
+		if shellNode := document.MappingValue(document.MappingValue(document.MappingValue(node, "defaults"), "run"), "shell"); shellNode != nil {
+			return shellNode.Value
+		}
+		return ""
+	}
+
+	workflowShell := getDefaultShell(topNode)
+	if jobsNode := document.MappingValue(topNode, "jobs"); jobsNode != nil && jobsNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+			jobNode := jobsNode.Content[i+1]
+			shell := workflowShell
+			if jobShell := getDefaultShell(jobNode); jobShell != "" {
+				shell = jobShell
+			}
+			runsOnNode := document.MappingValue(jobNode, "runs-on")
+			// the default shell on windows runners is pwsh
+			if shell == "" && runsOnNode != nil && strings.Contains(strings.ToLower(runsOnNode.Value), "windows") {
+				shell = "pwsh"
+			}
+			addSteps(shell, document.MappingValue(jobNode, "steps"))
+		}
+	}
+	addSteps("", document.MappingValue(document.MappingValue(topNode, "runs"), "steps"))
+
+	return runNodes
+}
+
+// rewriteLine returns the line with the deprecated command replaced by a write to the environment file
+func rewriteLine(line string) string {
+	if match := commandRegex.FindStringSubmatch(line); match != nil && match[2] == match[6] {
+		indent, quote, command, name, value, trailing := match[1], match[2], match[3], match[4], match[5], match[7]
+		if quote == "" {
+			quote = `"`
+		}
+		return fmt.Sprintf(`%secho %s%s=%s%s >> "$%s"%s`, indent, quote, name, value, quote, commandFiles[command], trailing)
+	}
+	if match := addPathRegex.FindStringSubmatch(line); match != nil && match[2] == match[4] {
+		indent, quote, value, trailing := match[1], match[2], match[3], match[5]
+		if quote == "" {
+			quote = `"`
+		}
+		return fmt.Sprintf(`%secho %s%s%s >> "$%s"%s`, indent, quote, value, quote, commandFiles["add-path"], trailing)
+	}
+	return line
+}
+
+// RewriteDeprecatedCommands replaces the deprecated set-output, save-state, set-env and add-path workflow commands
+// in run steps with writes to the GITHUB_OUTPUT, GITHUB_STATE, GITHUB_ENV and GITHUB_PATH environment files.
+// Only commands that are echoed on their own line in a bash or sh step are replaced.
+func RewriteDeprecatedCommands(inputYaml string) (string, bool, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return inputYaml, false, nil
+	}
+
+	inputLines := strings.Split(inputYaml, "\n")
+	buffer := textedit.NewBuffer(inputYaml)
+	updated := false
+	for _, runNode := range getRunNodes(t.Content[0]) {
+		if runNode.Style&yaml.LiteralStyle != 0 {
+			lineCount := strings.Count(strings.TrimRight(runNode.Value, "\n"), "\n") + 1
+			for i := runNode.Line; i < runNode.Line+lineCount && i < len(inputLines); i++ {
+				if line := rewriteLine(inputLines[i]); line != inputLines[i] {
+					buffer.ReplaceLine(i+1, line)
+					updated = true
+				}
+			}
+			continue
+		}
+		// single line scripts, e.g. run: echo "::set-output name=version::1.0.0"
+		if runNode.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.FoldedStyle) != 0 || strings.Contains(runNode.Value, "\n") {
+			continue
+		}
+		lineIndex := runNode.Line - 1
+		if lineIndex >= len(inputLines) {
+			continue
+		}
+		line := inputLines[lineIndex]
+		column := document.ContentColumn(line, runNode)
+		if column > len(line) {
+			continue
+		}
+		if rewritten := rewriteLine(line[column:]); rewritten != line[column:] {
+			buffer.ReplaceLine(runNode.Line, line[:column]+rewritten)
+			updated = true
+		}
+	}
+
+	if !updated {
+		return inputYaml, false, nil
+	}
+	return buffer.String(), true, nil
+}
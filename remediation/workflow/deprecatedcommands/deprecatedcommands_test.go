@@ -0,0 +1,38 @@
+package deprecatedcommands
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestRewriteDeprecatedCommands(t *testing.T) {
+	const inputDirectory = "../../../testfiles/deprecatedcommands/input"
+	const outputDirectory = "../../../testfiles/deprecatedcommands/output"
+
+	input, err := ioutil.ReadFile(path.Join(inputDirectory, "deprecated-commands.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+
+	got, updated, err := RewriteDeprecatedCommands(string(input))
+	if err != nil {
+		t.Fatalf("RewriteDeprecatedCommands() unexpected error = %v", err)
+	}
+	if !updated {
+		t.Errorf("RewriteDeprecatedCommands() updated = false, want true")
+	}
+
+	output, err := ioutil.ReadFile(path.Join(outputDirectory, "deprecated-commands.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+	if got != string(output) {
+		t.Errorf("RewriteDeprecatedCommands() = %v, want %v", got, string(output))
+	}
+
+	// the output has no deprecated commands, so rewriting them again does not change it
+	if again, updated, err := RewriteDeprecatedCommands(got); err != nil || updated || again != got {
+		t.Errorf("RewriteDeprecatedCommands() of the output = %v, %v, want it unchanged", updated, err)
+	}
+}
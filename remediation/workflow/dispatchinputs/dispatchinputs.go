@@ -0,0 +1,371 @@
+package dispatchinputs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/yamledit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleDispatchInputInjection = "dispatch-input-injection"
+
+	GithubScriptAction = "actions/github-script"
+)
+
+// expressionRegex matches expressions with workflow_dispatch inputs and repository_dispatch payloads
+var expressionRegex = regexp.MustCompile(`\$\{\{\s*((?:inputs|github\.event\.inputs|github\.event\.client_payload)\.([A-Za-z0-9_.-]+))\s*\}\}`)
+
+var nonAlphanumericRegex = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// safeInputTypes are workflow_dispatch input types whose values are validated, so they cannot contain code
+var safeInputTypes = map[string]bool{
+	"boolean":     true,
+	"number":      true,
+	"choice":      true,
+	"environment": true,
+}
+
+// script is a run step, or a github-script step, that uses dispatch inputs
+type script struct {
+	jobName     string
+	stepNode    *yaml.Node
+	keyNode     *yaml.Node
+	node        *yaml.Node
+	shell       string
+	expressions []string
+}
+
+// getUnsafeInputs returns the workflow_dispatch inputs that are free-form strings, and whether the workflow is run on dispatch events.
+// The payload of repository_dispatch events is always free-form.
+func getUnsafeInputs(topNode *yaml.Node) (map[string]bool, bool) {
+	onNode := document.MappingValue(topNode, "on")
+	if onNode == nil {
+		return nil, false
+	}
+
+	var triggers []string
+	switch onNode.Kind {
+	case yaml.ScalarNode:
+		triggers = append(triggers, onNode.Value)
+	case yaml.SequenceNode:
+		for _, n := range onNode.Content {
+			triggers = append(triggers, n.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(onNode.Content); i += 2 {
+			triggers = append(triggers, onNode.Content[i].Value)
+		}
+	}
+
+	isDispatch := false
+	for _, trigger := range triggers {
+		if trigger == "workflow_dispatch" || trigger == "repository_dispatch" {
+			isDispatch = true
+		}
+	}
+
+	unsafeInputs := make(map[string]bool)
+	inputsNode := document.MappingValue(document.MappingValue(onNode, "workflow_dispatch"), "inputs")
+	if inputsNode != nil && inputsNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(inputsNode.Content); i += 2 {
+			typeNode := document.MappingValue(inputsNode.Content[i+1], "type")
+			unsafeInputs[inputsNode.Content[i].Value] = typeNode == nil || !safeInputTypes[typeNode.Value]
+		}
+	}
+	return unsafeInputs, isDispatch
+}
+
+// isUnsafeExpression returns true if the expression, e.g. inputs.name, can be set to arbitrary values by whoever dispatches the workflow
+func isUnsafeExpression(expression string, unsafeInputs map[string]bool) bool {
+	if strings.HasPrefix(expression, "github.event.client_payload.") {
+		return true
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(expression, "github.event.inputs."), "inputs.")
+	unsafe, found := unsafeInputs[name]
+	// inputs that are not declared are treated as free-form strings
+	return !found || unsafe
+}
+
+// getEnvName returns the name of the environment variable for the expression, e.g. INPUTS_NAME for inputs.name
+func getEnvName(expression string) string {
+	prefix, name := "INPUTS_", strings.TrimPrefix(strings.TrimPrefix(expression, "github.event.inputs."), "inputs.")
+	if strings.HasPrefix(expression, "github.event.client_payload.") {
+		prefix, name = "PAYLOAD_", strings.TrimPrefix(expression, "github.event.client_payload.")
+	}
+	return prefix + strings.Trim(strings.ToUpper(nonAlphanumericRegex.ReplaceAllString(name, "_")), "_")
+}
+
+// getShell returns the shell used by a run step, based on the step, job and workflow defaults, and the runner
+func getShell(topNode, jobNode, stepNode *yaml.Node) string {
+	if shellNode := document.MappingValue(stepNode, "shell"); shellNode != nil {
+		return shellNode.Value
+	}
+	for _, node := range []*yaml.Node{jobNode, topNode} {
+		if shellNode := document.MappingValue(document.MappingValue(document.MappingValue(node, "defaults"), "run"), "shell"); shellNode != nil {
+			return shellNode.Value
+		}
+	}
+	if runsOnNode := document.MappingValue(jobNode, "runs-on"); runsOnNode != nil {
+		var labels []string
+		if runsOnNode.Kind == yaml.ScalarNode {
+			labels = append(labels, runsOnNode.Value)
+		}
+		for _, n := range runsOnNode.Content {
+			labels = append(labels, n.Value)
+		}
+		for _, label := range labels {
+			if strings.Contains(strings.ToLower(label), "windows") {
+				return "pwsh"
+			}
+		}
+	}
+	return "bash"
+}
+
+// getScripts returns the run steps and github-script steps that use unsafe dispatch inputs
+func getScripts(topNode *yaml.Node) []script {
+	unsafeInputs, isDispatch := getUnsafeInputs(topNode)
+	if !isDispatch {
+		return nil
+	}
+
+	jobsNode := document.MappingValue(topNode, "jobs")
+	if jobsNode == nil || jobsNode.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var scripts []script
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobName, jobNode := jobsNode.Content[i].Value, document.Resolve(jobsNode.Content[i+1])
+		stepsNode := document.MappingValue(jobNode, "steps")
+		if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, stepNode := range stepsNode.Content {
+			stepNode = document.Resolve(stepNode)
+			s := script{jobName: jobName, stepNode: stepNode}
+			s.keyNode, s.node = document.MappingEntry(stepNode, "run")
+			if s.node != nil {
+				s.shell = getShell(topNode, jobNode, stepNode)
+			} else if usesNode := document.MappingValue(stepNode, "uses"); usesNode != nil && strings.HasPrefix(strings.ToLower(usesNode.Value), GithubScriptAction+"@") {
+				s.keyNode, s.node = document.MappingEntry(document.MappingValue(stepNode, "with"), "script")
+				s.shell = "github-script"
+			}
+			if s.node == nil || s.node.Kind != yaml.ScalarNode {
+				continue
+			}
+
+			seen := make(map[string]bool)
+			for _, match := range expressionRegex.FindAllStringSubmatch(s.node.Value, -1) {
+				if isUnsafeExpression(match[1], unsafeInputs) && !seen[match[1]] {
+					seen[match[1]] = true
+					s.expressions = append(s.expressions, match[1])
+				}
+			}
+			if len(s.expressions) > 0 {
+				scripts = append(scripts, s)
+			}
+		}
+	}
+	return scripts
+}
+
+// FindUnsafeDispatchInputs returns findings for run steps and github-script steps that use workflow_dispatch inputs
+// or repository_dispatch payloads in the script. Whoever can dispatch the workflow controls these values,
+// so they can inject code into the script.
+func FindUnsafeDispatchInputs(inputYaml string) ([]findings.Finding, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return nil, nil
+	}
+
+	var inputFindings []findings.Finding
+	for _, s := range getScripts(t.Content[0]) {
+		for _, expression := range s.expressions {
+			inputFindings = append(inputFindings, findings.Finding{
+				RuleID:     RuleDispatchInputInjection,
+				Message:    fmt.Sprintf("%s is used in a script in job %s, and can be used to inject code", expression, s.jobName),
+				JobName:    s.jobName,
+				Line:       s.keyNode.Line,
+				Column:     s.keyNode.Column,
+				Suggestion: fmt.Sprintf("Pass %s to the step as the environment variable %s", expression, getEnvName(expression)),
+			})
+		}
+	}
+	return inputFindings, nil
+}
+
+// getScriptLines returns the 0-based lines of the script, and the offset the script starts at in the first line
+func getScriptLines(inputLines []string, s script) (int, int, int, bool) {
+	switch {
+	case s.node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		return s.node.Line, yamledit.LastLine(inputLines, s.node) - 1, 0, true
+	case s.node.Line == s.keyNode.Line && !strings.Contains(s.node.Value, "\n"):
+		return s.node.Line - 1, s.node.Line - 1, textedit.ColumnOffset(inputLines[s.node.Line-1], s.node.Column), true
+	}
+	// multi-line plain and quoted scalars are only reported
+	return 0, 0, 0, false
+}
+
+// rewrite replaces the unsafe expressions in a line of the script with references to environment variables
+func rewrite(line, shell string, unsafe map[string]bool) string {
+	if shell == "github-script" {
+		// only expressions used as a complete string are replaced, since process.env is not expanded inside strings
+		for _, quote := range []string{`'`, `"`, "`"} {
+			quotedRegex := regexp.MustCompile(regexp.QuoteMeta(quote) + expressionRegex.String() + regexp.QuoteMeta(quote))
+			line = quotedRegex.ReplaceAllStringFunc(line, func(match string) string {
+				expression := quotedRegex.FindStringSubmatch(match)[1]
+				if !unsafe[expression] {
+					return match
+				}
+				return "process.env." + getEnvName(expression)
+			})
+		}
+		return line
+	}
+	return expressionRegex.ReplaceAllStringFunc(line, func(match string) string {
+		expression := expressionRegex.FindStringSubmatch(match)[1]
+		if !unsafe[expression] {
+			return match
+		}
+		envName := getEnvName(expression)
+		switch shell {
+		case "pwsh", "powershell":
+			return "$env:" + envName
+		case "cmd":
+			return "%" + envName + "%"
+		}
+		return "${" + envName + "}"
+	})
+}
+
+// hasUnsafeExpression returns true if the line still has an unsafe expression
+func hasUnsafeExpression(line string, unsafe map[string]bool) bool {
+	for _, match := range expressionRegex.FindAllStringSubmatch(line, -1) {
+		if unsafe[match[1]] {
+			return true
+		}
+	}
+	return false
+}
+
+// FixUnsafeDispatchInputs passes the dispatch inputs used in the scripts of the findings to the steps as environment variables,
+// and replaces the expressions in the scripts with the environment variables. The findings that were fixed are marked.
+func FixUnsafeDispatchInputs(inputYaml string, inputFindings []findings.Finding) (string, bool, error) {
+	if len(inputFindings) == 0 {
+		return inputYaml, false, nil
+	}
+
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	topNode := t.Content[0]
+
+	jobsKeyNode, jobsNode := document.MappingEntry(topNode, "jobs")
+	indentUnit := strings.Repeat(" ", jobsNode.Content[0].Column-jobsKeyNode.Column)
+
+	inputLines := strings.Split(inputYaml, "\n")
+	buffer := textedit.NewBuffer(inputYaml)
+	fixedLines := make(map[int]bool)
+
+	for _, s := range getScripts(topNode) {
+		found := false
+		for _, finding := range inputFindings {
+			found = found || (finding.JobName == s.jobName && finding.Line == s.keyNode.Line)
+		}
+		// a script with an anchor is also the script of its aliases, which do not have the environment variables
+		if !found || s.shell == "python" || s.stepNode.Style&yaml.FlowStyle != 0 || s.node.Anchor != "" {
+			continue
+		}
+		firstLine, lastLine, column, ok := getScriptLines(inputLines, s)
+		if !ok {
+			continue
+		}
+
+		unsafe := make(map[string]bool)
+		for _, expression := range s.expressions {
+			unsafe[expression] = true
+		}
+		rewritten := make(map[int]string)
+		for i := firstLine; i <= lastLine && i < len(inputLines) && column <= len(inputLines[i]); i++ {
+			line := inputLines[i][:column] + rewrite(inputLines[i][column:], s.shell, unsafe)
+			if hasUnsafeExpression(line[column:], unsafe) {
+				// expressions that could not be replaced are only reported
+				rewritten = nil
+				break
+			}
+			if line != inputLines[i] {
+				rewritten[i] = line
+			}
+		}
+		if len(rewritten) == 0 {
+			continue
+		}
+
+		// the environment variables are added to the env of the step
+		propertyIndent := strings.Repeat(" ", s.stepNode.Content[0].Column-1)
+		var envLines []string
+		envNode := document.MappingValue(s.stepNode, "env")
+		seenNames := make(map[string]bool)
+		for _, expression := range s.expressions {
+			// inputs.name and github.event.inputs.name have the same value, so they share the environment variable
+			envName := getEnvName(expression)
+			if document.MappingValue(envNode, envName) == nil && !seenNames[envName] {
+				seenNames[envName] = true
+				envLines = append(envLines, fmt.Sprintf("%s%s%s: ${{ %s }}", propertyIndent, indentUnit, envName, expression))
+			}
+		}
+
+		switch {
+		case envNode == nil:
+			envLines = append([]string{fmt.Sprintf("%senv:", propertyIndent)}, envLines...)
+			stepKeyNode := s.keyNode
+			if s.shell == "github-script" {
+				stepKeyNode, _ = document.MappingEntry(s.stepNode, "with")
+			}
+			if stepKeyNode != s.stepNode.Content[0] {
+				buffer.InsertLinesBefore(stepKeyNode.Line, envLines...)
+			} else {
+				stepLastLine := yamledit.LastLine(inputLines, s.stepNode)
+				buffer.InsertLinesAfter(stepLastLine, envLines...)
+			}
+		// an env with an anchor is not changed, since it is also the env of the steps of its aliases
+		case envNode.Kind == yaml.MappingNode && envNode.Style&yaml.FlowStyle == 0 && len(envNode.Content) > 0 && envNode.Anchor == "":
+			envIndent := strings.Repeat(" ", envNode.Content[0].Column-1)
+			for i := range envLines {
+				envLines[i] = envIndent + strings.TrimLeft(envLines[i], " ")
+			}
+			envLastLine := yamledit.LastLine(inputLines, envNode)
+			buffer.InsertLinesAfter(envLastLine, envLines...)
+		default:
+			continue
+		}
+
+		for i, line := range rewritten {
+			buffer.ReplaceLine(i+1, line)
+		}
+		fixedLines[s.keyNode.Line] = true
+	}
+
+	if len(fixedLines) == 0 {
+		return inputYaml, false, nil
+	}
+
+	for i, finding := range inputFindings {
+		if fixedLines[finding.Line] {
+			inputFindings[i].Fixed = true
+		}
+	}
+
+	return buffer.String(), true, nil
+}
@@ -0,0 +1,76 @@
+package dispatchinputs
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestUnsafeDispatchInputs(t *testing.T) {
+	const inputDirectory = "../../../testfiles/dispatchinputs/input"
+	const outputDirectory = "../../../testfiles/dispatchinputs/output"
+
+	input, err := ioutil.ReadFile(path.Join(inputDirectory, "dispatch-inputs.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+
+	got, err := FindUnsafeDispatchInputs(string(input))
+	if err != nil {
+		t.Fatalf("FindUnsafeDispatchInputs() unexpected error = %v", err)
+	}
+
+	// the boolean input is not reported
+	if len(got) != 5 {
+		t.Fatalf("FindUnsafeDispatchInputs() returned %d findings, want 5: %v", len(got), got)
+	}
+	if got[0].RuleID != RuleDispatchInputInjection || got[0].JobName != "deploy" || got[0].Line != 19 {
+		t.Errorf("unexpected finding: %+v", got[0])
+	}
+	if got[2].Message != "github.event.client_payload.tag is used in a script in job deploy, and can be used to inject code" {
+		t.Errorf("unexpected message: %s", got[2].Message)
+	}
+
+	out, updated, err := FixUnsafeDispatchInputs(string(input), got)
+	if err != nil {
+		t.Fatalf("FixUnsafeDispatchInputs() unexpected error = %v", err)
+	}
+	if !updated {
+		t.Errorf("FixUnsafeDispatchInputs() updated = false, want true")
+	}
+
+	output, err := ioutil.ReadFile(path.Join(outputDirectory, "dispatch-inputs.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+	if out != string(output) {
+		t.Errorf("FixUnsafeDispatchInputs() = %v, want %v", out, string(output))
+	}
+
+	// the output is remediated, so fixing it again does not change it
+	again, err := FindUnsafeDispatchInputs(out)
+	if err != nil {
+		t.Fatalf("FindUnsafeDispatchInputs() of the output unexpected error = %v", err)
+	}
+	if fixed, updated, err := FixUnsafeDispatchInputs(out, again); err != nil || updated || fixed != out {
+		t.Errorf("FixUnsafeDispatchInputs() of the output = %v, %v, want it unchanged", updated, err)
+	}
+
+	for _, finding := range got {
+		if !finding.Fixed {
+			t.Errorf("finding was not fixed: %+v", finding)
+		}
+	}
+}
+
+func TestFindUnsafeDispatchInputsNotDispatched(t *testing.T) {
+	input := "on: workflow_call\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: echo ${{ inputs.name }}\n"
+
+	got, err := FindUnsafeDispatchInputs(input)
+	if err != nil {
+		t.Fatalf("FindUnsafeDispatchInputs() unexpected error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("FindUnsafeDispatchInputs() returned findings for a workflow that is not dispatched: %v", got)
+	}
+}
@@ -0,0 +1,40 @@
+package document
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// ActionReference is the uses of a step, or of a job that calls a reusable workflow
+type ActionReference struct {
+	// JobName is the name of the job, which is empty for the steps of a composite action
+	JobName string
+	// Uses is the scalar of the uses key
+	Uses *yaml.Node
+	// Job is true for the uses of a job, which is a reusable workflow instead of an action
+	Job bool
+}
+
+// ActionReferences returns the uses of the steps of the jobs, and of the steps of runs for a composite action, in the
+// order of the workflow. The uses of the jobs that call reusable workflows are returned too if jobUses is true, for the
+// modules that check the reusable workflows like the actions.
+func (index *Index) ActionReferences(jobUses bool) []ActionReference {
+	var references []ActionReference
+	addSteps := func(jobName string, steps *yaml.Node) {
+		if steps == nil {
+			return
+		}
+		for _, step := range steps.Content {
+			if uses := MappingValue(step, "uses"); uses != nil {
+				references = append(references, ActionReference{JobName: jobName, Uses: uses})
+			}
+		}
+	}
+	for _, job := range index.Jobs {
+		if uses := MappingValue(job.Node, "uses"); uses != nil && jobUses {
+			references = append(references, ActionReference{JobName: job.Name, Uses: uses, Job: true})
+		}
+		addSteps(job.Name, job.Steps)
+	}
+	addSteps("", index.Steps)
+	return references
+}
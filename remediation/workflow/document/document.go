@@ -0,0 +1,111 @@
+// Package document parses a workflow once for all the remediation modules. The modules of a request run one after the
+// other on the output of the previous module, and most of them leave the workflow unchanged, so they are given the tree
+// of the same text instead of parsing it again. The modules also parse the same text the same way, so they cannot
+// disagree on whether a workflow is valid.
+package document
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/step-security/secure-repo/remediation/workflow/metadata"
+	"gopkg.in/yaml.v3"
+)
+
+// cacheSize is the number of documents kept, which is enough for the workflows of a request remediated by a pool of
+// workers, and for the input and output of each
+const cacheSize = 16
+
+// Document is a parsed workflow. Its tree is shared by the modules, which read it to find the lines to change, and
+// must not change it. The entries of a mapping whose key is repeated later in the mapping are not in the tree, since
+// the later entry is the one that takes effect; they are returned by Duplicates. The key of the triggers is on in the
+// tree, however it is written, e.g. true by a YAML 1.1 library.
+type Document struct {
+	text       string
+	root       yaml.Node
+	err        error
+	duplicates []Duplicate
+
+	indexOnce sync.Once
+	index     *Index
+}
+
+// Text returns the text of the workflow
+func (d *Document) Text() string {
+	return d.text
+}
+
+// Node returns the tree of the workflow, which is shared and must not be changed
+func (d *Document) Node() (*yaml.Node, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	return &d.root, nil
+}
+
+// Workflow returns the workflow decoded from its tree, which is not shared, so it can be changed
+func (d *Document) Workflow() (*metadata.Workflow, error) {
+	workflow := &metadata.Workflow{}
+	if d.err != nil {
+		return workflow, d.err
+	}
+	// an empty text has no document, which is decoded as an empty workflow like yaml.Unmarshal does
+	if d.root.Kind == 0 {
+		return workflow, nil
+	}
+	err := d.root.Decode(workflow)
+	return workflow, err
+}
+
+// cache is the documents parsed last, by their text
+var cache = struct {
+	sync.Mutex
+	documents map[string]*list.Element
+	order     *list.List
+}{documents: map[string]*list.Element{}, order: list.New()}
+
+// Parse returns the document of the text, which is parsed unless it was parsed recently. The error of parsing the
+// text is returned by Node and Workflow.
+func Parse(text string) *Document {
+	cache.Lock()
+	if element, found := cache.documents[text]; found {
+		cache.order.MoveToFront(element)
+		cache.Unlock()
+		return element.Value.(*Document)
+	}
+	cache.Unlock()
+
+	// the text is parsed without the lock, so the workflows remediated by other workers are not blocked
+	document := &Document{text: text}
+	document.err = unmarshal(text, &document.root)
+	if document.err == nil {
+		normalizeOnKey(&document.root)
+		document.duplicates = removeDuplicates(&document.root)
+	}
+
+	cache.Lock()
+	defer cache.Unlock()
+	if element, found := cache.documents[text]; found {
+		return element.Value.(*Document)
+	}
+	cache.documents[text] = cache.order.PushFront(document)
+	if cache.order.Len() > cacheSize {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.documents, oldest.Value.(*Document).text)
+	}
+	return document
+}
+
+// unmarshal parses the text into the node. The parser of yaml.v3 panics on some invalid texts, e.g. with bytes that are
+// not UTF-8 after a key, and the workflows of users can be any text, so its panics are returned as errors.
+func unmarshal(text string, node *yaml.Node) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			*node = yaml.Node{}
+			err = fmt.Errorf("yaml: unable to parse: %v", r)
+		}
+	}()
+	return yaml.Unmarshal([]byte(text), node)
+}
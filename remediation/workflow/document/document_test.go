@@ -0,0 +1,206 @@
+package document
+
+import (
+	"testing"
+)
+
+const workflow = `name: CI
+permissions:
+  contents: read
+jobs:
+  build:
+    runs-on: [self-hosted, linux]
+    permissions:
+      packages: write
+    steps:
+      - uses: actions/checkout@v4
+      - run: make
+  release:
+    uses: octo-org/workflows/.github/workflows/release.yml@main
+`
+
+func TestParse(t *testing.T) {
+	doc := Parse(workflow)
+	if Parse(workflow) != doc {
+		t.Errorf("Parse() parsed the same text again")
+	}
+	if doc.Text() != workflow {
+		t.Errorf("Text() = %q, want the workflow", doc.Text())
+	}
+	node, err := doc.Node()
+	if err != nil || len(node.Content) != 1 {
+		t.Fatalf("Node() = %v, %v, want the document", node, err)
+	}
+	decoded, err := doc.Workflow()
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if decoded.Name != "CI" || len(decoded.Jobs) != 2 {
+		t.Errorf("Workflow() = %+v, want the jobs of the workflow", decoded)
+	}
+	// the workflow is decoded for each call, so it can be changed
+	decoded.Name = "changed"
+	if again, _ := doc.Workflow(); again.Name != "CI" {
+		t.Errorf("Workflow() returned the changed workflow")
+	}
+
+	empty, err := Parse("").Workflow()
+	if err != nil || empty.Name != "" {
+		t.Errorf("Workflow() of an empty text = %+v, %v, want an empty workflow", empty, err)
+	}
+	invalid := Parse("jobs: [")
+	if _, err := invalid.Node(); err == nil {
+		t.Errorf("Node() of invalid yaml returned no error")
+	}
+	if _, err := invalid.Index(); err == nil {
+		t.Errorf("Index() of invalid yaml returned no error")
+	}
+}
+
+func TestParseEvicts(t *testing.T) {
+	first := Parse("name: first")
+	for i := 0; i < cacheSize; i++ {
+		Parse("name: " + string(rune('a'+i)))
+	}
+	if Parse("name: first") == first {
+		t.Errorf("Parse() kept more than %d documents", cacheSize)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	index, err := Parse(workflow).Index()
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if index.JobsKey == nil || index.JobsKey.Line != 4 {
+		t.Errorf("JobsKey = %v, want the key on line 4", index.JobsKey)
+	}
+	if index.Permissions == nil || index.Permissions.Line != 3 {
+		t.Errorf("Permissions = %v, want the mapping on line 3", index.Permissions)
+	}
+	if len(index.Jobs) != 2 || index.Jobs[0].Name != "build" || index.Jobs[1].Name != "release" {
+		t.Fatalf("Jobs = %v, want build and release", index.Jobs)
+	}
+
+	build := index.Job("build")
+	if build.RunsOn == nil || len(build.RunsOn.Content) != 2 {
+		t.Errorf("RunsOn = %v, want the labels", build.RunsOn)
+	}
+	if build.Permissions == nil || build.Permissions.Line != 8 {
+		t.Errorf("Permissions = %v, want the mapping on line 8", build.Permissions)
+	}
+	if build.Steps == nil || len(build.Steps.Content) != 2 || build.Steps.Line != 10 {
+		t.Errorf("Steps = %v, want the 2 steps on line 10", build.Steps)
+	}
+	if uses := MappingValue(build.Steps.Content[0], "uses"); uses == nil || uses.Value != "actions/checkout@v4" {
+		t.Errorf("MappingValue() = %v, want the action", uses)
+	}
+	if steps := Sequence(build.Steps); len(steps) != 2 || Sequence(build.RunsOn.Content[0]) != nil || Sequence(nil) != nil {
+		t.Errorf("Sequence() = %v, want the steps", steps)
+	}
+	if release := index.Job("release"); release.Steps != nil || release.RunsOn != nil {
+		t.Errorf("release = %+v, want no steps for a reusable workflow", release)
+	}
+	if index.Job("deploy") != nil {
+		t.Errorf("Job() returned a job that is not in the workflow")
+	}
+
+	composite, err := Parse("runs:\n  using: composite\n  steps:\n    - run: make\n").Index()
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if composite.Steps == nil || len(composite.Steps.Content) != 1 || len(composite.Jobs) != 0 {
+		t.Errorf("Index() of a composite action = %+v, want its steps", composite)
+	}
+}
+
+func TestActionReferences(t *testing.T) {
+	index, err := Parse(workflow).Index()
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	references := index.ActionReferences(false)
+	if len(references) != 1 || references[0].JobName != "build" || references[0].Uses.Value != "actions/checkout@v4" || references[0].Job {
+		t.Errorf("ActionReferences(false) = %+v, want the action of the step", references)
+	}
+	references = index.ActionReferences(true)
+	if len(references) != 2 || references[1].JobName != "release" || references[1].Uses.Line != 13 || !references[1].Job {
+		t.Errorf("ActionReferences(true) = %+v, want the reusable workflow of the job too", references)
+	}
+
+	composite, err := Parse("runs:\n  using: composite\n  steps:\n    - uses: actions/setup-go@v5\n    - run: make\n").Index()
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if references := composite.ActionReferences(true); len(references) != 1 || references[0].JobName != "" || references[0].Uses.Line != 4 {
+		t.Errorf("ActionReferences() of a composite action = %+v, want the action of its step", references)
+	}
+}
+
+func TestIndexAliases(t *testing.T) {
+	text := "jobs:\n  build: &build\n    runs-on: &runner ubuntu-latest\n    steps:\n      - run: make\n  test: *build\n"
+	index, err := Parse(text).Index()
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	test := index.Job("test")
+	if test == nil || test.Steps == nil || len(test.Steps.Content) != 1 || test.RunsOn == nil {
+		t.Fatalf("test = %+v, want the steps of the anchor", test)
+	}
+	if line := OpeningLine(index.Jobs[1].Key, index.Jobs[1].Node); line != 2 {
+		t.Errorf("OpeningLine() = %d, want the line of the anchor", line)
+	}
+	if line := OpeningLine(index.JobsKey, test.RunsOn); line != 3 {
+		t.Errorf("OpeningLine() = %d, want the line of the anchor of the label", line)
+	}
+	if line := OpeningLine(index.JobsKey, test.Steps); line != 1 {
+		t.Errorf("OpeningLine() = %d, want the line of the key", line)
+	}
+
+	line := "    runs-on: &runner ubuntu-latest"
+	if column := ContentColumn(line, test.RunsOn); line[column:] != "ubuntu-latest" {
+		t.Errorf("ContentColumn() = %d, want the column of the label", column)
+	}
+	if column := ContentColumn("      - run: make", MappingValue(test.Steps.Content[0], "run")); column != 13 {
+		t.Errorf("ContentColumn() = %d, want 13", column)
+	}
+}
+
+func TestIsOnKey(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"on: push", true},
+		{"\"on\": push", true},
+		{"'on': push", true},
+		{"true: push", true},
+		{"True: push", true},
+		{"On: push", true},
+		{"yes: push", true},
+		{"\"true\": push", false},
+		{"'On': push", false},
+		{"false: push", false},
+		{"name: push", false},
+	}
+	for _, test := range tests {
+		node, err := Parse(test.text).Node()
+		if err != nil {
+			t.Fatalf("Error not expected: %v", err)
+		}
+		key := node.Content[0].Content[0]
+		if got := key.Value == "on"; got != test.want {
+			t.Errorf("the key of %q is %q, want on: %v", test.text, key.Value, test.want)
+		}
+	}
+
+	// the key that takes effect of the ones that are read as on is the last one
+	doc := Parse("on: push\ntrue: pull_request\njobs: {}\n")
+	node, _ := doc.Node()
+	if top := node.Content[0]; len(top.Content) != 4 || top.Content[1].Value != "pull_request" {
+		t.Errorf("the triggers are %v, want pull_request", top.Content[1].Value)
+	}
+	if duplicates := doc.Duplicates(); len(duplicates) != 1 || duplicates[0].Key.Line != 1 {
+		t.Errorf("Duplicates() = %v, want the on at line 1", duplicates)
+	}
+}
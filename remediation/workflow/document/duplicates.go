@@ -0,0 +1,88 @@
+package document
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duplicate is a key of a mapping which a later key of the mapping repeats, such as a second env of a job or two jobs
+// with the same id. The later key is the one that takes effect, as in the parsers that accept duplicate keys, so the
+// modules read and change its entry, and leave the entry of the earlier key as it is.
+type Duplicate struct {
+	// Key is the key that is overridden, and Effective the later key that overrides it
+	Key       *yaml.Node
+	Effective *yaml.Node
+	// Path is the path of the mapping, e.g. jobs.build or jobs.build.steps[0], which is empty for the top level mapping
+	Path string
+}
+
+// Duplicates returns the keys of the mappings of the document that a later key of their mapping overrides, in the
+// order of their lines. Their entries are not in the tree of the document.
+func (d *Document) Duplicates() []Duplicate {
+	return d.duplicates
+}
+
+// removeDuplicates removes the entries of the mappings of the tree whose key is repeated by a later entry of the
+// mapping, so the modules only find the entries that take effect, and yaml.v3 decodes the tree instead of returning an
+// error for the repeated keys. It returns the keys of the removed entries.
+func removeDuplicates(root *yaml.Node) []Duplicate {
+	var duplicates []Duplicate
+	var walk func(node *yaml.Node, path string)
+	walk = func(node *yaml.Node, path string) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				walk(child, path)
+			}
+		case yaml.SequenceNode:
+			for i, child := range node.Content {
+				walk(child, path+"["+strconv.Itoa(i)+"]")
+			}
+		case yaml.MappingNode:
+			last := map[string]*yaml.Node{}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if key := node.Content[i]; isDuplicable(key) {
+					last[key.Value] = key
+				}
+			}
+			content := node.Content[:0:0]
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				if effective := last[key.Value]; isDuplicable(key) && effective != key {
+					duplicates = append(duplicates, Duplicate{Key: key, Effective: effective, Path: path})
+					continue
+				}
+				content = append(content, key, value)
+				walk(value, joinPath(path, key.Value))
+			}
+			if len(content) < len(node.Content) {
+				node.Content = content
+			}
+		}
+	}
+	walk(root, "")
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i].Key.Line < duplicates[j].Key.Line
+	})
+	return duplicates
+}
+
+// isDuplicable returns true if the key is a scalar other than the merge key, which may be repeated to merge several
+// mappings
+func isDuplicable(key *yaml.Node) bool {
+	return key.Kind == yaml.ScalarNode && key.Tag != "!!merge"
+}
+
+// joinPath returns the path of the value of the key of the mapping of the path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	if strings.ContainsAny(key, ".[]") {
+		key = strconv.Quote(key)
+	}
+	return path + "." + key
+}
@@ -0,0 +1,66 @@
+package document
+
+import (
+	"testing"
+)
+
+func TestDuplicates(t *testing.T) {
+	const text = `on: push
+env:
+  A: 1
+env:
+  B: 2
+defaults: &defaults
+  shell: bash
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+  build:
+    runs-on: windows-latest
+    <<: *defaults
+    steps:
+      - run: make test
+        env:
+          C: 3
+          C: 4
+  test:
+    runs-on: ubuntu-latest
+`
+	doc := Parse(text)
+	decoded, err := doc.Workflow()
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(decoded.Jobs) != 2 || decoded.Jobs["build"].Steps[0].Run != "make test" {
+		t.Errorf("Workflow() = %+v, want the later build job", decoded.Jobs)
+	}
+	if len(decoded.Env) != 1 || decoded.Env["B"] != "2" {
+		t.Errorf("Workflow() env = %v, want the later env", decoded.Env)
+	}
+
+	want := []struct {
+		key, path        string
+		line, overridden int
+	}{
+		{"env", "", 2, 4},
+		{"build", "jobs", 9, 13},
+		{"C", "jobs.build.steps[0].env", 19, 20},
+	}
+	duplicates := doc.Duplicates()
+	if len(duplicates) != len(want) {
+		t.Fatalf("Duplicates() = %v, want %d duplicates", duplicates, len(want))
+	}
+	for i, w := range want {
+		d := duplicates[i]
+		if d.Key.Value != w.key || d.Path != w.path || d.Key.Line != w.line || d.Effective.Line != w.overridden {
+			t.Errorf("Duplicates()[%d] = %s %q at %d, overridden at %d, want %s %q at %d, overridden at %d", i,
+				d.Key.Value, d.Path, d.Key.Line, d.Effective.Line, w.key, w.path, w.line, w.overridden)
+		}
+	}
+
+	if len(Parse(workflow).Duplicates()) != 0 {
+		t.Errorf("Duplicates() of a workflow without duplicates = %v", Parse(workflow).Duplicates())
+	}
+}
@@ -0,0 +1,160 @@
+package document
+
+import (
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"gopkg.in/yaml.v3"
+)
+
+// Index has the nodes of the jobs and steps of a workflow, or of the steps of a composite action, which the modules
+// look up for each job. It is built once for each document, instead of each module searching the tree for each key.
+type Index struct {
+	// Jobs are the jobs of the workflow, in the order of the workflow
+	Jobs []*Job
+	// JobsKey is the jobs key of the workflow, which is nil for a composite action
+	JobsKey *yaml.Node
+	// Permissions are the permissions of the workflow, if they are set
+	Permissions *yaml.Node
+	// Steps are the steps of a composite action, if it is one
+	Steps *yaml.Node
+
+	jobs map[string]*Job
+}
+
+// Job has the nodes of a job. The nodes of the keys that are not set are nil. The nodes of aliases are the nodes of
+// their anchors, so a job or steps shared by several jobs with an alias have the same nodes, which are changed once.
+type Job struct {
+	Name string
+	// Key is the name of the job, and Node its mapping
+	Key  *yaml.Node
+	Node *yaml.Node
+	// RunsOn is the label, the labels or the group of runs-on
+	RunsOn      *yaml.Node
+	Permissions *yaml.Node
+	// Steps is the sequence of the steps, which is nil for a job that calls a reusable workflow
+	Steps *yaml.Node
+}
+
+// Index returns the index of the document, which is built the first time it is needed
+func (d *Document) Index() (*Index, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	d.indexOnce.Do(func() {
+		d.index = newIndex(&d.root)
+	})
+	return d.index, nil
+}
+
+// Job returns the job of the name, or nil if the workflow has no such job
+func (index *Index) Job(name string) *Job {
+	return index.jobs[name]
+}
+
+// newIndex indexes the jobs of the mapping of the document, and the steps of runs for a composite action
+func newIndex(root *yaml.Node) *Index {
+	index := &Index{jobs: map[string]*Job{}}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return index
+	}
+	top := Resolve(root.Content[0])
+	index.Permissions = MappingValue(top, "permissions")
+	if runs := MappingValue(top, "runs"); runs != nil && runs.Kind == yaml.MappingNode {
+		if steps := MappingValue(runs, "steps"); steps != nil && steps.Kind == yaml.SequenceNode {
+			index.Steps = steps
+		}
+	}
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		if top.Content[i].Value != "jobs" || top.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		index.JobsKey = top.Content[i]
+		jobs := top.Content[i+1]
+		for j := 0; j+1 < len(jobs.Content); j += 2 {
+			node := Resolve(jobs.Content[j+1])
+			if node.Kind != yaml.MappingNode {
+				continue
+			}
+			job := &Job{Name: jobs.Content[j].Value, Key: jobs.Content[j], Node: node}
+			job.RunsOn = MappingValue(job.Node, "runs-on")
+			job.Permissions = MappingValue(job.Node, "permissions")
+			if steps := MappingValue(job.Node, "steps"); steps != nil && steps.Kind == yaml.SequenceNode {
+				job.Steps = steps
+			}
+			index.Jobs = append(index.Jobs, job)
+			index.jobs[job.Name] = job
+		}
+		break
+	}
+	return index
+}
+
+// MappingValue returns the value of the key of the mapping, or nil if the node is not a mapping or has no such key. The
+// mapping and the value are resolved if they are aliases.
+func MappingValue(node *yaml.Node, key string) *yaml.Node {
+	_, value := MappingEntry(node, key)
+	return value
+}
+
+// MappingEntry returns the key and the value of the key of the mapping, like MappingValue, for the modules that edit or
+// report the key as well
+func MappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	node = Resolve(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], Resolve(node.Content[i+1])
+		}
+	}
+	return nil, nil
+}
+
+// Sequence returns the items of the sequence, or nil if the node is not a sequence. The sequence is resolved if it is
+// an alias.
+func Sequence(node *yaml.Node) []*yaml.Node {
+	node = Resolve(node)
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return node.Content
+}
+
+// Resolve returns the node of the anchor of an alias, or the node if it is not an alias
+func Resolve(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// OpeningLine returns the line after which entries are inserted at the start of the block mapping of the value of the
+// key: the line of the anchor or the tag of the mapping if it has one, or else the line of the key. The mapping of an
+// alias is written at its anchor, so nothing can be inserted after the key of the alias.
+func OpeningLine(key, value *yaml.Node) int {
+	value = Resolve(value)
+	if value != nil && (value.Anchor != "" || value.Style&yaml.TaggedStyle != 0) {
+		return value.Line
+	}
+	return key.Line
+}
+
+// ContentColumn returns the offset of the content of a scalar in the line of its position, which is after its anchor
+// and tag if it has them. The scalar of an anchor is changed once for the anchor and its aliases.
+func ContentColumn(line string, node *yaml.Node) int {
+	column := textedit.ColumnOffset(line, node.Column)
+	if node.Anchor == "" && node.Style&yaml.TaggedStyle == 0 {
+		return column
+	}
+	for column < len(line) && (line[column] == '&' || line[column] == '!') {
+		end := strings.IndexByte(line[column:], ' ')
+		if end < 0 {
+			return len(line)
+		}
+		rest := line[column+end:]
+		column += end + len(rest) - len(strings.TrimLeft(rest, " "))
+	}
+	return column
+}
@@ -0,0 +1,40 @@
+package document
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// yaml11True are the plain scalars that YAML 1.1 reads as the boolean true. The parsers of YAML 1.1 read the key on of
+// a workflow as true, and a workflow they wrote back has one of these as the key of its triggers, most often true.
+var yaml11True = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true,
+	"true": true, "True": true, "TRUE": true,
+	"on": true, "On": true, "ON": true,
+}
+
+// IsOnKey returns true if a key of the top level mapping of a workflow is the key of its triggers: on, quoted or not,
+// or a plain key that YAML 1.1 reads as the boolean true, such as true or On
+func IsOnKey(key *yaml.Node) bool {
+	if key == nil || key.Kind != yaml.ScalarNode {
+		return false
+	}
+	if key.Value == "on" {
+		return true
+	}
+	return key.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 && yaml11True[key.Value]
+}
+
+// normalizeOnKey sets the value of the key of the triggers of the workflow to on, so the modules find the triggers by
+// the key on however it is written. The line and column of the key are kept, so the edits of the modules are made in
+// the text as it is.
+func normalizeOnKey(root *yaml.Node) {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return
+	}
+	top := root.Content[0]
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		if key := top.Content[i]; IsOnKey(key) {
+			key.Value, key.Tag = "on", "!!str"
+		}
+	}
+}
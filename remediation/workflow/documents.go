@@ -0,0 +1,188 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/lineending"
+	"github.com/step-security/secure-repo/remediation/multidoc"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/score"
+	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+)
+
+// secureDocuments runs the remediations on each document of a file with several documents, and returns the documents
+// joined with their separators. The response has what was done to any of the documents, and the lines of the findings
+// and of the report are lines of the file.
+func secureDocuments(ctx context.Context, queryStringParams map[string]string, inputYaml string, svc dynamodbiface.DynamoDBAPI, params Options) (*permissions.SecureWorkflowReponse, error) {
+	documents := multidoc.Split(lineending.Normalize(inputYaml))
+	responses := make([]*permissions.SecureWorkflowReponse, len(documents))
+	var orders [][]string
+	for i, document := range documents {
+		if document.IsEmpty() {
+			continue
+		}
+		response, ran, err := secureDocument(ctx, queryStringParams, document.Content, svc, params)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+		orders = append(orders, ran, getModuleNames(response.Report), getStatusNames(response.Report))
+	}
+
+	secureWorkflowReponse := &permissions.SecureWorkflowReponse{OriginalInput: inputYaml, Report: &report.Report{}}
+	var scoreChanges []*score.ScoreChange
+	outputs := make([]multidoc.Document, len(documents))
+	for i, document := range documents {
+		outputs[i] = document
+		response := responses[i]
+		if response == nil {
+			continue
+		}
+		outputs[i].Content = response.FinalOutput
+		mergeResponse(secureWorkflowReponse, response)
+		for _, finding := range response.Findings {
+			if finding.Line > 0 {
+				finding.Line += document.Line - 1
+			}
+			secureWorkflowReponse.Findings = append(secureWorkflowReponse.Findings, finding)
+		}
+		scoreChanges = append(scoreChanges, response.Score)
+	}
+	secureWorkflowReponse.FinalOutput = lineending.Restore(inputYaml, multidoc.Join(outputs))
+	secureWorkflowReponse.Score = score.Average(scoreChanges)
+
+	// the lines of each document in the input and the output of a module are offset by the lines of the documents before
+	// it, which are changed by the modules that ran before
+	lines := make([]int, len(documents))
+	for i, document := range documents {
+		lines[i] = countLines(document.Content)
+	}
+	names := mergeOrders(orders)
+	for _, name := range names {
+		linesBefore, linesAfter := 0, 0
+		for i, document := range documents {
+			separatorLines := countLines(document.Separator)
+			linesBefore, linesAfter = linesBefore+separatorLines, linesAfter+separatorLines
+			documentLines := lines[i]
+			if module := getModule(responses[i], name); module != nil {
+				secureWorkflowReponse.Report.AddModule(*module, linesBefore, linesAfter)
+				lines[i] += module.LineDelta()
+			}
+			linesBefore, linesAfter = linesBefore+documentLines, linesAfter+lines[i]
+		}
+	}
+	// a module failed on the file if it failed on any of its documents, and changed it if it changed any of them
+	secureWorkflowReponse.Report.SetStatuses(names)
+	return secureWorkflowReponse, nil
+}
+
+// mergeResponse adds what was done to a document to the response of the file
+func mergeResponse(merged, response *permissions.SecureWorkflowReponse) {
+	merged.HasErrors = merged.HasErrors || response.HasErrors
+	merged.AlreadyHasPermissions = merged.AlreadyHasPermissions || response.AlreadyHasPermissions
+	merged.AddedMaintainedActions = merged.AddedMaintainedActions || response.AddedMaintainedActions
+	merged.PinnedActions = merged.PinnedActions || response.PinnedActions
+	merged.AddedHardenRunner = merged.AddedHardenRunner || response.AddedHardenRunner
+	merged.AddedPermissions = merged.AddedPermissions || response.AddedPermissions
+	merged.ReplacedRunnerLabels = merged.ReplacedRunnerLabels || response.ReplacedRunnerLabels
+	merged.RemovedUnnecessaryTokens = merged.RemovedUnnecessaryTokens || response.RemovedUnnecessaryTokens
+	merged.AddedForkPullRequestGuards = merged.AddedForkPullRequestGuards || response.AddedForkPullRequestGuards
+	merged.FixedDispatchInputs = merged.FixedDispatchInputs || response.FixedDispatchInputs
+	merged.AddedShellDefaults = merged.AddedShellDefaults || response.AddedShellDefaults
+	merged.RewroteDeprecatedCommands = merged.RewroteDeprecatedCommands || response.RewroteDeprecatedCommands
+	merged.AddedRepositoryGuards = merged.AddedRepositoryGuards || response.AddedRepositoryGuards
+	merged.PinnedRunTools = merged.PinnedRunTools || response.PinnedRunTools
+	merged.AddedBuildProvenance = merged.AddedBuildProvenance || response.AddedBuildProvenance
+	merged.AddedCosignSigning = merged.AddedCosignSigning || response.AddedCosignSigning
+	merged.AddedSBOM = merged.AddedSBOM || response.AddedSBOM
+	merged.FixedVulnerableActions = merged.FixedVulnerableActions || response.FixedVulnerableActions
+	merged.FixedTyposquattedActions = merged.FixedTyposquattedActions || response.FixedTyposquattedActions
+	merged.FixedSecretBuildArgs = merged.FixedSecretBuildArgs || response.FixedSecretBuildArgs
+	merged.SanitizedUntrustedEnvWrites = merged.SanitizedUntrustedEnvWrites || response.SanitizedUntrustedEnvWrites
+	merged.HasPolicyViolations = merged.HasPolicyViolations || response.HasPolicyViolations
+	merged.FailedStrictChecks = merged.FailedStrictChecks || response.FailedStrictChecks
+	merged.IncorrectYaml = merged.IncorrectYaml || response.IncorrectYaml
+	merged.UsingSecureRepoPAT = merged.UsingSecureRepoPAT || response.UsingSecureRepoPAT
+	merged.JobErrors = append(merged.JobErrors, response.JobErrors...)
+	// an action missing from the knowledge base is missing once, though it is used by several documents
+	for _, action := range response.MissingActions {
+		found := false
+		for _, missing := range merged.MissingActions {
+			found = found || missing == action
+		}
+		if !found {
+			merged.MissingActions = append(merged.MissingActions, action)
+		}
+	}
+}
+
+// getModuleNames returns the names of the modules of the report, in the order they ran
+func getModuleNames(workflowReport *report.Report) []string {
+	if workflowReport == nil {
+		return nil
+	}
+	names := make([]string, 0, len(workflowReport.Modules))
+	for _, module := range workflowReport.Modules {
+		names = append(names, module.Name)
+	}
+	return names
+}
+
+// getStatusNames returns the names of the modules that ran, from the statuses of the report, in the order they ran
+func getStatusNames(workflowReport *report.Report) []string {
+	if workflowReport == nil {
+		return nil
+	}
+	names := make([]string, 0, len(workflowReport.Statuses))
+	for _, status := range workflowReport.Statuses {
+		names = append(names, status.Module)
+	}
+	return names
+}
+
+// getModule returns the module of the report of the document, or nil if the module did not change, skip or fail anything
+func getModule(response *permissions.SecureWorkflowReponse, name string) *report.Module {
+	if response == nil || response.Report == nil {
+		return nil
+	}
+	for i := range response.Report.Modules {
+		if response.Report.Modules[i].Name == name {
+			return &response.Report.Modules[i]
+		}
+	}
+	return nil
+}
+
+// mergeOrders returns the names of the orders, in which each name is after the names that are before it in an order
+func mergeOrders(orders [][]string) []string {
+	var merged []string
+	for _, order := range orders {
+		previous := -1
+		for _, name := range order {
+			index := -1
+			for i := range merged {
+				if merged[i] == name {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				index = previous + 1
+				merged = append(merged[:index], append([]string{name}, merged[index:]...)...)
+			}
+			previous = index
+		}
+	}
+	return merged
+}
+
+// countLines returns the number of lines of the text, including a last line without a newline
+func countLines(text string) int {
+	lines := strings.Count(text, "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		lines++
+	}
+	return lines
+}
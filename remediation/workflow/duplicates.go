@@ -0,0 +1,51 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+)
+
+// RuleDuplicateKey is the rule of the keys of a mapping that a later key of the mapping overrides, such as a second env
+// of a job or two jobs with the same id
+const RuleDuplicateKey = "duplicate-key"
+
+// findDuplicateKeys returns a finding for each key of the workflow that a later key of its mapping overrides. The
+// modules read and change the entry of the later key, which is the one that takes effect, and leave the earlier entry
+// as it is, so the finding is not fixed by the remediations.
+func findDuplicateKeys(inputYaml string) []findings.Finding {
+	var duplicateFindings []findings.Finding
+	for _, duplicate := range document.Parse(inputYaml).Duplicates() {
+		where := "the workflow"
+		if duplicate.Path != "" {
+			where = duplicate.Path
+		}
+		duplicateFindings = append(duplicateFindings, findings.Finding{
+			RuleID:     RuleDuplicateKey,
+			Message:    fmt.Sprintf("%s is defined more than once in %s, and only its definition at line %d takes effect", duplicate.Key.Value, where, duplicate.Effective.Line),
+			JobName:    getDuplicateJobName(duplicate),
+			Line:       duplicate.Key.Line,
+			Column:     duplicate.Key.Column,
+			Suggestion: fmt.Sprintf("Merge the %s at line %d into the one at line %d, or remove it", duplicate.Key.Value, duplicate.Key.Line, duplicate.Effective.Line),
+		})
+	}
+	return duplicateFindings
+}
+
+// getDuplicateJobName returns the id of the job of a duplicate key, which is the key itself for the jobs with the same
+// id, or an empty string for a key outside the jobs
+func getDuplicateJobName(duplicate document.Duplicate) string {
+	if duplicate.Path == "jobs" {
+		return duplicate.Key.Value
+	}
+	job, found := strings.CutPrefix(duplicate.Path, "jobs.")
+	if !found {
+		return ""
+	}
+	if end := strings.IndexAny(job, ".["); end >= 0 {
+		job = job[:end]
+	}
+	return job
+}
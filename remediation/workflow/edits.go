@@ -0,0 +1,52 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/workflow/equivalence"
+	"github.com/step-security/secure-repo/remediation/workflow/remediators"
+)
+
+// EditsReporter is implemented by remediators that report the edits they make to a workflow, see
+// remediators.EditsReporter
+type EditsReporter = remediators.EditsReporter
+
+// UnintendedEditError is returned by SecureWorkflow when the query parameter verifyEdits is true and a module changed
+// the workflow other than by the edits it reports, which is an internal error of the module, instead of returning the
+// workflow it changed
+type UnintendedEditError struct {
+	Module      string
+	Differences []equivalence.Difference
+}
+
+func (e *UnintendedEditError) Error() string {
+	differences := make([]string, 0, len(e.Differences))
+	for _, difference := range e.Differences {
+		differences = append(differences, difference.String())
+	}
+	return fmt.Sprintf("module %s made edits it is not meant to make: %s", e.Module, strings.Join(differences, "; "))
+}
+
+// verifyEdits returns an *UnintendedEditError for the first module whose output has differences from its input other
+// than its edits. The outputs of an input that does not parse are not verified.
+func verifyEdits(inputYaml string, outputs []moduleOutput) error {
+	if _, err := document.Parse(inputYaml).Node(); err != nil {
+		return nil
+	}
+	before := inputYaml
+	for _, output := range outputs {
+		if output.edits != nil {
+			differences, err := equivalence.Verify(before, output.output, *output.edits)
+			if err != nil {
+				return fmt.Errorf("module %s produced a workflow that cannot be parsed: %v", output.module, err)
+			}
+			if len(differences) > 0 {
+				return &UnintendedEditError{Module: output.module, Differences: differences}
+			}
+		}
+		before = output.output
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/workflow/actionpolicy"
+	"github.com/step-security/secure-repo/remediation/workflow/equivalence"
+	"github.com/step-security/secure-repo/remediation/workflow/remediators"
+)
+
+// greedyRemediator sets the runs-on of the jobs, but reports that it only sets their timeouts
+type greedyRemediator struct{}
+
+func (greedyRemediator) Name() string {
+	return "greedy"
+}
+
+func (greedyRemediator) Edits() *equivalence.Edits {
+	return &equivalence.Edits{Keys: []string{"jobs.*.timeout-minutes"}}
+}
+
+func (greedyRemediator) Detect(inputYaml string) ([]findings.Finding, error) {
+	return nil, nil
+}
+
+func (greedyRemediator) Apply(inputYaml string, detected []findings.Finding) (string, bool, error) {
+	output := strings.ReplaceAll(inputYaml, "runs-on: ubuntu-latest", "runs-on: ubuntu-22.04\n    timeout-minutes: 10")
+	return output, output != inputYaml, nil
+}
+
+func (greedyRemediator) Report(workflowReport *report.Report, path string, detected []findings.Finding) {
+}
+
+func TestSecureWorkflowUnintendedEdits(t *testing.T) {
+	defer func() { registered = nil }()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/commits/v2",
+		httpmock.NewStringResponder(200, `ebacdc22ef6c2cfb85ee5ded8f2e640f4c776dd5`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/git/matching-refs/tags",
+		httpmock.NewStringResponder(200, `[{"ref": "refs/tags/v2.0.0", "object": {"sha": "ebacdc22ef6c2cfb85ee5ded8f2e640f4c776dd5", "type": "commit"}}]`))
+
+	if err := RegisterRemediator(greedyRemediator{}); err != nil {
+		t.Fatalf("RegisterRemediator() unexpected error = %v", err)
+	}
+
+	input := `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - run: make build
+`
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	queryParams := map[string]string{"pinActions": "false", "verifyEdits": "true"}
+
+	_, err := SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{})
+	var unintended *UnintendedEditError
+	if !errors.As(err, &unintended) {
+		t.Fatalf("expected an *UnintendedEditError, got %v", err)
+	}
+	if unintended.Module != "greedy" || len(unintended.Differences) != 1 || unintended.Differences[0].Path != "jobs.build.runs-on" {
+		t.Errorf("unexpected error %v", unintended)
+	}
+
+	// the edits are only verified with verifyEdits
+	delete(queryParams, "verifyEdits")
+	if _, err := SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{}); err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+
+	// the edits of the built-in modules are their intended edits
+	queryParams = map[string]string{"pinActions": "false", "verifyEdits": "true", "greedy": "false", "addSBOM": "true",
+		"addShellDefaults": "true", "addCosignSigning": "true"}
+	output, err := SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if !output.AddedHardenRunner || !output.AddedPermissions || !output.AddedShellDefaults {
+		t.Errorf("expected the workflow to be remediated, got\n%s", output.FinalOutput)
+	}
+}
+
+// TestModuleEdits verifies the outputs of the tests of the modules against the edits the modules report
+func TestModuleEdits(t *testing.T) {
+	opts, err := newOptions(context.Background(), map[string]string{"owner": "octo-org", "repo": "octo-repo"}, nil,
+		Options{MaintainedActions: map[string]string{"actions/checkout": "actions/checkout"}, RunnerLabels: map[string]string{"ubuntu-latest": "ubuntu-22.04"},
+			ActionPolicy: &actionpolicy.ActionPolicy{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, param := range append(CheckParams, "removeUnnecessaryTokens", "rewriteDeprecatedCommands", "addSBOM", "addBuildProvenance",
+		"addCosignSigning", "addShellDefaults", "pinRunTools", "fixDispatchInputs", "fixSecretBuildArgs", "sanitizeUntrustedEnvWrites",
+		"addForkPullRequestGuards", "addRepositoryGuards", "fixTyposquattedActions", "replaceUnmaintainedActions", "fixVulnerableActions") {
+		opts.queryStringParams[param] = "true"
+	}
+	before, after := builtinRemediations(opts)
+	edits := make(map[string]*equivalence.Edits)
+	for _, remediation := range append(before, after...) {
+		edits[remediation.Remediator.Name()] = remediators.Edits(remediation.Remediator)
+	}
+
+	modules := map[string]string{
+		"actionpolicy":       "actionpolicy",
+		"addaction":          "hardenrunner",
+		"advisories":         "advisories",
+		"attestation":        "attestation",
+		"buildargs":          "buildargs",
+		"cosign":             "signing",
+		"deprecatedcommands": "deprecatedcommands",
+		"dispatchinputs":     "dispatchinputs",
+		"forkguard":          "forkguard",
+		"githubenv":          "githubenv",
+		"githubtoken":        "githubtoken",
+		"joblevelpermskb":    "permissions",
+		"maintainedActions":  "maintainedactions",
+		"pinactions":         "pin",
+		"pindockers":         "pin",
+		"pintools":           "pintools",
+		"repoguard":          "repoguard",
+		"runnerLabel":        "runnerlabel",
+		"sbom":               "sbom",
+		"shelldefaults":      "shelldefaults",
+		"toplevelperms":      "permissions",
+		"typosquat":          "typosquat",
+		"unmaintained":       "unmaintained",
+	}
+	for directory, module := range modules {
+		moduleEdits := edits[module]
+		if moduleEdits == nil {
+			t.Errorf("module %s does not report its edits", module)
+			continue
+		}
+		inputDirectory := path.Join("../../testfiles", directory, "input")
+		files, err := os.ReadDir(inputDirectory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			input, err := os.ReadFile(path.Join(inputDirectory, file.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			output, err := os.ReadFile(path.Join("../../testfiles", directory, "output", file.Name()))
+			if err != nil {
+				continue
+			}
+			// the outputs of some tests are the errors of the module rather than a workflow
+			if strings.HasPrefix(string(output), "KnownIssue") {
+				continue
+			}
+			differences, err := equivalence.Verify(string(input), string(output), *moduleEdits)
+			if err != nil {
+				t.Errorf("%s/%s: %v", directory, file.Name(), err)
+			}
+			for _, difference := range differences {
+				t.Errorf("%s/%s: module %s made an edit it does not report: %s", directory, file.Name(), module, difference)
+			}
+		}
+	}
+}
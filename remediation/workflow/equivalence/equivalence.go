@@ -0,0 +1,351 @@
+// Package equivalence verifies that a remediation only made the edits it is meant to make to a workflow. It compares
+// the workflow before and after the remediation as they are parsed rather than their text, so a change of formatting,
+// such as the indentation or the quotes of a value, is not a difference, and returns the jobs, steps and values that
+// were changed other than by the edits, e.g. a step that was lost or a key that was moved to another mapping.
+package equivalence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+// Edits are the changes a remediation is meant to make to a workflow
+type Edits struct {
+	// InsertSteps allows steps to be inserted in the steps of the jobs, and of a composite action
+	InsertSteps bool
+	// Keys are the paths of the values that may be changed, added or removed, in which * matches any key or index, e.g.
+	// jobs.*.permissions or jobs.*.steps.*.uses. The values within the value of a path may be changed as well.
+	Keys []string
+}
+
+// Difference is a change of the workflow that is not one of the edits
+type Difference struct {
+	// Path is the path of the value, e.g. jobs.build.steps[1].with, which is empty for the workflow itself
+	Path string
+	// Line is the line of the value in the workflow before the remediation, or after it for a value that was added
+	Line    int
+	Message string
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("line %d: %s", d.Line, d.Message)
+}
+
+// Verify returns the differences of the workflow after a remediation from the workflow before it, other than the edits.
+// The workflows are compared as document.Parse parses them, so a key that is repeated is compared by its definition
+// that takes effect. The error is returned if either workflow cannot be parsed.
+func Verify(before, after string, edits Edits) ([]Difference, error) {
+	beforeRoot, err := document.Parse(before).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the workflow before the edits: %v", err)
+	}
+	afterRoot, err := document.Parse(after).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the workflow after the edits: %v", err)
+	}
+	c := &comparer{edits: edits}
+	for _, key := range edits.Keys {
+		c.patterns = append(c.patterns, strings.Split(key, "."))
+	}
+	c.compare(getTop(beforeRoot), getTop(afterRoot), nil)
+	return c.differences, nil
+}
+
+// getTop returns the top level value of the document, or nil if it is empty
+func getTop(root *yaml.Node) *yaml.Node {
+	if len(root.Content) == 0 {
+		return nil
+	}
+	return root.Content[0]
+}
+
+// comparer compares the values of two workflows, and collects their differences
+type comparer struct {
+	edits       Edits
+	patterns    [][]string
+	differences []Difference
+}
+
+func (c *comparer) add(path []string, line int, format string, args ...interface{}) {
+	c.differences = append(c.differences, Difference{Path: formatPath(path), Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// compare adds the differences of the values of the path, unless the path is one of the edits
+func (c *comparer) compare(before, after *yaml.Node, path []string) {
+	if c.isEdited(path) {
+		return
+	}
+	before, after = document.Resolve(before), document.Resolve(after)
+	switch {
+	case before == nil && after == nil:
+		return
+	case before == nil:
+		c.add(path, after.Line, "the workflow was empty and now has a value")
+		return
+	case after == nil:
+		c.add(path, before.Line, "the workflow was emptied")
+		return
+	case before.Kind != after.Kind:
+		c.add(path, before.Line, "%s was changed from a %s to a %s", describe(path), kindName(before), kindName(after))
+		return
+	}
+	switch before.Kind {
+	case yaml.ScalarNode:
+		switch {
+		case before.ShortTag() != after.ShortTag():
+			c.add(path, before.Line, "%s was changed from the %s %q to the %s %q", describe(path), strings.TrimPrefix(before.ShortTag(), "!!"), before.Value,
+				strings.TrimPrefix(after.ShortTag(), "!!"), after.Value)
+		case before.Value != after.Value:
+			c.add(path, before.Line, "%s was changed from %q to %q", describe(path), before.Value, after.Value)
+		}
+	case yaml.MappingNode:
+		c.compareMappings(before, after, path)
+	case yaml.SequenceNode:
+		if c.edits.InsertSteps && isSteps(path) {
+			c.compareSteps(before.Content, after.Content, path)
+			return
+		}
+		for i := 0; i < len(before.Content) || i < len(after.Content); i++ {
+			itemPath := appendPath(path, indexSegment(i))
+			switch {
+			case i >= len(after.Content):
+				if !c.isEdited(itemPath) {
+					c.add(itemPath, before.Content[i].Line, "%s was removed", describe(itemPath))
+				}
+			case i >= len(before.Content):
+				if !c.isEdited(itemPath) {
+					c.add(itemPath, after.Content[i].Line, "%s was added", describe(itemPath))
+				}
+			default:
+				c.compare(before.Content[i], after.Content[i], itemPath)
+			}
+		}
+	}
+}
+
+// compareMappings adds the differences of the entries of two mappings, whose order does not matter
+func (c *comparer) compareMappings(before, after *yaml.Node, path []string) {
+	beforeKeys, beforeEntries := getEntries(before)
+	afterKeys, afterEntries := getEntries(after)
+	for _, key := range beforeKeys {
+		entryPath := appendPath(path, key)
+		beforeEntry := beforeEntries[key]
+		afterEntry, found := afterEntries[key]
+		if !found {
+			if !c.isEdited(entryPath) {
+				c.add(entryPath, beforeEntry[0].Line, "%s was removed", describe(entryPath))
+			}
+			continue
+		}
+		c.compare(beforeEntry[1], afterEntry[1], entryPath)
+	}
+	for _, key := range afterKeys {
+		entryPath := appendPath(path, key)
+		if _, found := beforeEntries[key]; !found && !c.isEdited(entryPath) {
+			c.add(entryPath, afterEntries[key][0].Line, "%s was added", describe(entryPath))
+		}
+	}
+}
+
+// compareSteps adds the differences of the steps of a job, in which steps may be inserted. The steps before are
+// matched in order with the steps after them that are the same apart from the edits, preferring the steps that are the
+// same without the edits, e.g. a step that was not changed rather than a step inserted before it that only differs in its
+// action. A step that is not matched was changed, so it is compared with the step that is closest to it of the steps
+// that were not matched at its place.
+func (c *comparer) compareSteps(before, after []*yaml.Node, path []string) {
+	// weights[i][j] is the weight of matching the step i before with the step j after, which is 0 if they are not the
+	// same, and more than the weight of the exact matches of all the steps if they are
+	exact := &comparer{}
+	weights := make([][]int, len(before))
+	for i := range before {
+		weights[i] = make([]int, len(after))
+		stepPath := appendPath(path, indexSegment(i))
+		for j := range after {
+			if len(c.diff(before[i], after[j], stepPath)) > 0 {
+				continue
+			}
+			weights[i][j] = len(before) + 1
+			if len(exact.diff(before[i], after[j], stepPath)) == 0 {
+				weights[i][j]++
+			}
+		}
+	}
+	// the common subsequence of the steps of the most weight, from the end
+	totals := make([][]int, len(before)+1)
+	for i := range totals {
+		totals[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			totals[i][j] = max(totals[i+1][j], totals[i][j+1])
+			if weights[i][j] > 0 {
+				totals[i][j] = max(totals[i][j], totals[i+1][j+1]+weights[i][j])
+			}
+		}
+	}
+	// the steps that are not matched are compared in the gaps between the matched steps
+	var unmatchedBefore, unmatchedAfter []int
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && weights[i][j] > 0 && totals[i][j] == totals[i+1][j+1]+weights[i][j]:
+			c.compareUnmatchedSteps(before, after, unmatchedBefore, unmatchedAfter, path)
+			unmatchedBefore, unmatchedAfter = nil, nil
+			i, j = i+1, j+1
+		case j >= len(after) || i < len(before) && totals[i+1][j] >= totals[i][j+1]:
+			unmatchedBefore = append(unmatchedBefore, i)
+			i++
+		default:
+			unmatchedAfter = append(unmatchedAfter, j)
+			j++
+		}
+	}
+	c.compareUnmatchedSteps(before, after, unmatchedBefore, unmatchedAfter, path)
+}
+
+// compareUnmatchedSteps adds the differences of the steps before that were not matched with the steps after them that
+// are closest to them at the same place. The steps after that are left were inserted.
+func (c *comparer) compareUnmatchedSteps(before, after []*yaml.Node, unmatchedBefore, unmatchedAfter []int, path []string) {
+	used := make(map[int]bool)
+	for _, i := range unmatchedBefore {
+		stepPath := appendPath(path, indexSegment(i))
+		var closest []Difference
+		closestIndex := -1
+		for _, j := range unmatchedAfter {
+			if used[j] {
+				continue
+			}
+			if differences := c.diff(before[i], after[j], stepPath); closestIndex < 0 || len(differences) < len(closest) {
+				closest, closestIndex = differences, j
+			}
+		}
+		if closestIndex < 0 {
+			c.add(stepPath, before[i].Line, "%s was removed", describe(stepPath))
+			continue
+		}
+		used[closestIndex] = true
+		c.differences = append(c.differences, closest...)
+	}
+}
+
+// diff returns the differences of two values, without adding them
+func (c *comparer) diff(before, after *yaml.Node, path []string) []Difference {
+	other := &comparer{edits: c.edits, patterns: c.patterns}
+	other.compare(before, after, path)
+	return other.differences
+}
+
+// isEdited returns true if the path is the path of one of the edits, or within one
+func (c *comparer) isEdited(path []string) bool {
+	for _, pattern := range c.patterns {
+		if len(pattern) > len(path) {
+			continue
+		}
+		matches := true
+		for i, segment := range pattern {
+			if segment != "*" && segment != path[i] && "["+segment+"]" != path[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// getEntries returns the keys of the mapping, in order, and the key and value of each. The entries merged with << are
+// entries of the mapping, unless the mapping has their keys.
+func getEntries(node *yaml.Node) ([]string, map[string][2]*yaml.Node) {
+	var keys []string
+	entries := make(map[string][2]*yaml.Node)
+	var merged []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag == "!!merge" {
+			merged = append(merged, value)
+			continue
+		}
+		if _, found := entries[key.Value]; !found {
+			keys = append(keys, key.Value)
+		}
+		entries[key.Value] = [2]*yaml.Node{key, value}
+	}
+	for _, value := range merged {
+		sources := []*yaml.Node{document.Resolve(value)}
+		if sources[0].Kind == yaml.SequenceNode {
+			sources = sources[0].Content
+		}
+		for _, source := range sources {
+			source = document.Resolve(source)
+			if source.Kind != yaml.MappingNode {
+				continue
+			}
+			sourceKeys, sourceEntries := getEntries(source)
+			for _, key := range sourceKeys {
+				if _, found := entries[key]; !found {
+					keys = append(keys, key)
+					entries[key] = sourceEntries[key]
+				}
+			}
+		}
+	}
+	return keys, entries
+}
+
+// isSteps returns true if the path is the path of the steps of a job, or of a composite action
+func isSteps(path []string) bool {
+	return len(path) == 3 && path[0] == "jobs" && path[2] == "steps" || len(path) == 2 && path[0] == "runs" && path[1] == "steps"
+}
+
+// appendPath returns a copy of the path with the segment, so the paths of the entries of a mapping do not share an array
+func appendPath(path []string, segment string) []string {
+	return append(append(make([]string, 0, len(path)+1), path...), segment)
+}
+
+func indexSegment(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// formatPath returns the path as it is written in the differences, e.g. jobs.build.steps[0].uses
+func formatPath(path []string) string {
+	var builder strings.Builder
+	for i, segment := range path {
+		if strings.HasPrefix(segment, "[") {
+			builder.WriteString(segment)
+			continue
+		}
+		if i > 0 {
+			builder.WriteByte('.')
+		}
+		if strings.ContainsAny(segment, ".[]") {
+			segment = strconv.Quote(segment)
+		}
+		builder.WriteString(segment)
+	}
+	return builder.String()
+}
+
+// describe returns the name of the value of the path in the message of a difference
+func describe(path []string) string {
+	if len(path) == 0 {
+		return "the workflow"
+	}
+	return formatPath(path)
+}
+
+func kindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.SequenceNode:
+		return "sequence"
+	default:
+		return "scalar"
+	}
+}
@@ -0,0 +1,195 @@
+package equivalence
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	const workflow = `name: build
+on: push
+env: &env
+  GO: "1.21"
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    env: *env
+    steps:
+      - uses: actions/checkout@v4
+      - run: |
+          make
+          make test
+`
+	stepEdits := Edits{InsertSteps: true, Keys: []string{"jobs.*.steps.*.uses", "jobs.*.permissions"}}
+	tests := []struct {
+		name  string
+		after string
+		edits Edits
+		want  []string
+	}{
+		{
+			name: "formatting",
+			after: `name: "build"
+on: push
+jobs:
+    build:
+        env:
+            GO: '1.21'
+        runs-on: ubuntu-latest
+        steps:
+        -   uses: actions/checkout@v4
+        -   run: "make\nmake test\n"
+env:
+  GO: "1.21"
+`,
+		},
+		{
+			name: "edits",
+			after: `name: build
+on: push
+env: &env
+  GO: "1.21"
+jobs:
+  build:
+    permissions:
+      contents: read
+    runs-on: ubuntu-latest
+    env: *env
+    steps:
+      - uses: step-security/harden-runner@v2
+      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4
+      - run: |
+          make
+          make test
+`,
+			edits: stepEdits,
+		},
+		{
+			name: "edits not allowed",
+			after: `name: build
+on: push
+env: &env
+  GO: "1.21"
+jobs:
+  build:
+    permissions:
+      contents: read
+    runs-on: ubuntu-latest
+    env: *env
+    steps:
+      - uses: step-security/harden-runner@v2
+      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4
+      - run: |
+          make
+          make test
+`,
+			want: []string{
+				"line 10: jobs.build.steps[0].uses was changed from \"actions/checkout@v4\" to \"step-security/harden-runner@v2\"",
+				"line 11: jobs.build.steps[1].run was removed",
+				"line 13: jobs.build.steps[1].uses was added",
+				"line 14: jobs.build.steps[2] was added",
+				"line 7: jobs.build.permissions was added",
+			},
+		},
+		{
+			name: "lost step",
+			after: `name: build
+on: push
+env: &env
+  GO: "1.21"
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    env: *env
+    steps:
+      - uses: step-security/harden-runner@v2
+      - uses: actions/checkout@v4
+`,
+			edits: stepEdits,
+			want:  []string{"line 11: jobs.build.steps[1] was removed"},
+		},
+		{
+			name: "changed step",
+			after: `name: build
+on: push
+env: &env
+  GO: "1.21"
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    env: *env
+    steps:
+      - uses: step-security/harden-runner@v2
+      - uses: actions/checkout@v4
+      - run: |
+          make
+          make check
+`,
+			edits: stepEdits,
+			want:  []string{"line 11: jobs.build.steps[1].run was changed from \"make\\nmake test\\n\" to \"make\\nmake check\\n\""},
+		},
+		{
+			name: "moved key",
+			after: `name: build
+on: push
+env: &env
+  GO: "1.21"
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - run: |
+          make
+          make test
+    env: *env
+  env: *env
+`,
+			edits: stepEdits,
+			want:  []string{"line 14: jobs.env was added"},
+		},
+		{
+			name: "type of value",
+			after: `name: build
+on: push
+env: &env
+  GO: 1.21
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    env: *env
+    steps:
+      - uses: actions/checkout@v4
+      - run: |
+          make
+          make test
+`,
+			// the value of the anchor is changed at each of its aliases
+			want: []string{
+				"line 4: env.GO was changed from the str \"1.21\" to the float \"1.21\"",
+				"line 4: jobs.build.env.GO was changed from the str \"1.21\" to the float \"1.21\"",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			differences, err := Verify(workflow, tt.after, tt.edits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, difference := range differences {
+				got = append(got, difference.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Verify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	if _, err := Verify("on: push\n", "on: [push\n", Edits{}); err == nil {
+		t.Errorf("Verify() returned no error for an invalid workflow")
+	}
+}
@@ -0,0 +1,53 @@
+package expressions
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ExpressionRegex matches ${{ }} expressions, with the expression in the first group
+var ExpressionRegex = regexp.MustCompile(`\$\{\{\s*(.*?)\s*\}\}`)
+
+// untrustedContexts are contexts that can be set by whoever opens an issue, pull request or comment,
+// or pushes a branch, so they must not be used in scripts
+var untrustedContexts = []*regexp.Regexp{
+	regexp.MustCompile(`^github\.event\.issue\.(title|body)$`),
+	regexp.MustCompile(`^github\.event\.pull_request\.(title|body)$`),
+	regexp.MustCompile(`^github\.event\.comment\.body$`),
+	regexp.MustCompile(`^github\.event\.review\.body$`),
+	regexp.MustCompile(`^github\.event\.review_comment\.body$`),
+	regexp.MustCompile(`^github\.event\.discussion\.(title|body)$`),
+	regexp.MustCompile(`^github\.event\.pages\.[^.]+\.page_name$`),
+	regexp.MustCompile(`^github\.event\.commits\.[^.]+\.(message|author\.email|author\.name)$`),
+	regexp.MustCompile(`^github\.event\.head_commit\.(message|author\.email|author\.name)$`),
+	regexp.MustCompile(`^github\.event\.pull_request\.head\.(ref|label|repo\.default_branch)$`),
+	regexp.MustCompile(`^github\.event\.workflow_run\.(head_branch|head_commit\.message|head_commit\.author\.email|head_commit\.author\.name)$`),
+	regexp.MustCompile(`^github\.head_ref$`),
+}
+
+// IsUntrusted returns true if the expression is a context that can be set by an attacker
+func IsUntrusted(expression string) bool {
+	expression = strings.TrimSpace(expression)
+	// github.event['issue'].title is treated as github.event.issue.title
+	expression = regexp.MustCompile(`\[['"]([^'"]+)['"]\]`).ReplaceAllString(expression, ".$1")
+	expression = regexp.MustCompile(`\[\*\]|\[\d+\]`).ReplaceAllString(expression, ".*")
+	for _, untrustedContext := range untrustedContexts {
+		if untrustedContext.MatchString(expression) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUntrustedExpressions returns the untrusted expressions used in a script, in the order they are used
+func GetUntrustedExpressions(script string) []string {
+	var untrusted []string
+	seen := make(map[string]bool)
+	for _, match := range ExpressionRegex.FindAllStringSubmatch(script, -1) {
+		if IsUntrusted(match[1]) && !seen[match[1]] {
+			seen[match[1]] = true
+			untrusted = append(untrusted, match[1])
+		}
+	}
+	return untrusted
+}
@@ -0,0 +1,24 @@
+package expressions
+
+import "testing"
+
+func TestIsUntrusted(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: "github.event.issue.title", want: true},
+		{expression: "github.event['pull_request'].body", want: true},
+		{expression: "github.event.commits[0].message", want: true},
+		{expression: "github.head_ref", want: true},
+		{expression: "github.event.pull_request.number", want: false},
+		{expression: "github.sha", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			if got := IsUntrusted(tt.expression); got != tt.want {
+				t.Errorf("IsUntrusted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
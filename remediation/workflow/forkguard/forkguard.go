@@ -0,0 +1,197 @@
+package forkguard
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleForkPullRequestSecrets = "fork-pull-request-secrets"
+
+	// ForkGuard skips the job for pull requests from forks, where secrets are not available
+	ForkGuard = "github.event.pull_request.head.repo.full_name == github.repository"
+)
+
+var secretsRegex = regexp.MustCompile(`secrets\s*(\.\s*([A-Za-z_][A-Za-z0-9_-]*)|\[)`)
+
+// getTriggers returns the events in the on section of the workflow
+func getTriggers(topNode *yaml.Node) []string {
+	_, onNode := document.MappingEntry(topNode, "on")
+	if onNode == nil {
+		return nil
+	}
+
+	var triggers []string
+	switch onNode.Kind {
+	case yaml.ScalarNode:
+		triggers = append(triggers, onNode.Value)
+	case yaml.SequenceNode:
+		for _, n := range onNode.Content {
+			triggers = append(triggers, n.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(onNode.Content); i += 2 {
+			triggers = append(triggers, onNode.Content[i].Value)
+		}
+	}
+	return triggers
+}
+
+// usesSecrets returns true if the node references a secret other than the GITHUB_TOKEN
+func usesSecrets(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		for _, match := range secretsRegex.FindAllStringSubmatch(node.Value, -1) {
+			if !strings.EqualFold(match[2], "GITHUB_TOKEN") {
+				return true
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if usesSecrets(node.Content[i+1]) {
+				return true
+			}
+		}
+	case yaml.SequenceNode:
+		for _, n := range node.Content {
+			if usesSecrets(n) {
+				return true
+			}
+		}
+	case yaml.AliasNode:
+		return usesSecrets(node.Alias)
+	}
+	return false
+}
+
+// isGuarded returns true if the job condition already checks whether the pull request is from a fork
+func isGuarded(jobNode *yaml.Node) bool {
+	_, ifNode := document.MappingEntry(jobNode, "if")
+	if ifNode == nil {
+		return false
+	}
+	return strings.Contains(ifNode.Value, "head.repo.full_name") || strings.Contains(ifNode.Value, "head.repo.fork")
+}
+
+// getGuard returns the condition to add to jobs. If the workflow has other triggers, the job must still run for them.
+func getGuard(triggers []string) string {
+	for _, trigger := range triggers {
+		if trigger != "pull_request" {
+			return fmt.Sprintf("github.event_name != 'pull_request' || %s", ForkGuard)
+		}
+	}
+	return ForkGuard
+}
+
+func isPullRequestWorkflow(triggers []string) bool {
+	for _, trigger := range triggers {
+		if trigger == "pull_request" {
+			return true
+		}
+	}
+	return false
+}
+
+// FindUnguardedJobs returns findings for jobs that use secrets in a workflow triggered by pull_request,
+// without a condition that skips pull requests from forks. Secrets are not passed to workflows run from forks,
+// so these jobs fail for outside contributors.
+func FindUnguardedJobs(inputYaml string) ([]findings.Finding, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return nil, nil
+	}
+	topNode := t.Content[0]
+
+	if !isPullRequestWorkflow(getTriggers(topNode)) {
+		return nil, nil
+	}
+
+	_, jobsNode := document.MappingEntry(topNode, "jobs")
+	if jobsNode == nil || jobsNode.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var guardFindings []findings.Finding
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobKeyNode, jobNode := jobsNode.Content[i], document.Resolve(jobsNode.Content[i+1])
+		// secrets passed to a reusable workflow, e.g. secrets: inherit
+		_, secretsNode := document.MappingEntry(jobNode, "secrets")
+		if (secretsNode == nil && !usesSecrets(jobNode)) || isGuarded(jobNode) {
+			continue
+		}
+		guardFindings = append(guardFindings, findings.Finding{
+			RuleID:     RuleForkPullRequestSecrets,
+			Message:    fmt.Sprintf("Job %s uses secrets on pull_request, but does not skip pull requests from forks", jobKeyNode.Value),
+			JobName:    jobKeyNode.Value,
+			Line:       jobKeyNode.Line,
+			Column:     jobKeyNode.Column,
+			Suggestion: fmt.Sprintf("if: %s", ForkGuard),
+		})
+	}
+
+	return guardFindings, nil
+}
+
+// AddForkGuards adds a condition to the jobs in the findings to skip pull requests from forks, and marks the findings that were fixed.
+// If a job already has a single line condition, the guard is combined with it. Other conditions are only reported.
+func AddForkGuards(inputYaml string, guardFindings []findings.Finding) (string, bool, error) {
+	if len(guardFindings) == 0 {
+		return inputYaml, false, nil
+	}
+
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	topNode := t.Content[0]
+	guard := getGuard(getTriggers(topNode))
+	_, jobsNode := document.MappingEntry(topNode, "jobs")
+
+	buffer := textedit.NewBuffer(inputYaml)
+	updated := false
+
+	for i, finding := range guardFindings {
+		jobKeyNode, jobNode := document.MappingEntry(jobsNode, finding.JobName)
+		if jobNode == nil || jobNode.Kind != yaml.MappingNode || jobNode.Style&yaml.FlowStyle != 0 || len(jobNode.Content) == 0 {
+			continue
+		}
+		indent := strings.Repeat(" ", jobNode.Content[0].Column-1)
+
+		ifKeyNode, ifNode := document.MappingEntry(jobNode, "if")
+		if ifNode == nil {
+			buffer.InsertLine(document.OpeningLine(jobKeyNode, jobNode), fmt.Sprintf("%sif: %s", indent, guard))
+			guardFindings[i].Fixed = true
+			updated = true
+			continue
+		}
+
+		// a condition with an anchor is not changed, since it is also the condition of its aliases
+		line, _ := buffer.Line(ifKeyNode.Line)
+		if ifNode.Kind != yaml.ScalarNode || ifNode.Anchor != "" || ifNode.Line != ifKeyNode.Line || ifNode.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 ||
+			strings.Contains(line, "#") || strings.Contains(ifNode.Value, ": ") {
+			continue
+		}
+		condition := strings.TrimSpace(ifNode.Value)
+		if strings.HasPrefix(condition, "${{") && strings.HasSuffix(condition, "}}") {
+			condition = strings.TrimSpace(condition[3 : len(condition)-2])
+		}
+		buffer.ReplaceLine(ifKeyNode.Line, fmt.Sprintf("%sif: ${{ (%s) && (%s) }}", indent, condition, guard))
+		guardFindings[i].Fixed = true
+		updated = true
+	}
+
+	if !updated {
+		return inputYaml, false, nil
+	}
+
+	return buffer.String(), true, nil
+}
@@ -0,0 +1,73 @@
+package forkguard
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestAddForkGuards(t *testing.T) {
+	const inputDirectory = "../../../testfiles/forkguard/input"
+	const outputDirectory = "../../../testfiles/forkguard/output"
+
+	tests := []struct {
+		fileName     string
+		wantFindings int
+		wantUpdated  bool
+	}{
+		{fileName: "pull-request-secrets.yml", wantFindings: 3, wantUpdated: true},
+		{fileName: "push-and-pull-request.yml", wantFindings: 1, wantUpdated: true},
+		{fileName: "push-only.yml", wantFindings: 0, wantUpdated: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			input, err := ioutil.ReadFile(path.Join(inputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			guardFindings, err := FindUnguardedJobs(string(input))
+			if err != nil {
+				t.Errorf("FindUnguardedJobs() unexpected error = %v", err)
+			}
+
+			if len(guardFindings) != tt.wantFindings {
+				t.Errorf("FindUnguardedJobs() findings = %v, want %d", guardFindings, tt.wantFindings)
+			}
+
+			got, gotUpdated, err := AddForkGuards(string(input), guardFindings)
+			if err != nil {
+				t.Errorf("AddForkGuards() unexpected error = %v", err)
+			}
+
+			if gotUpdated != tt.wantUpdated {
+				t.Errorf("AddForkGuards() updated = %v, wantUpdated %v", gotUpdated, tt.wantUpdated)
+			}
+
+			for _, finding := range guardFindings {
+				if !finding.Fixed {
+					t.Errorf("AddForkGuards() finding for job %s not fixed", finding.JobName)
+				}
+			}
+
+			output, err := ioutil.ReadFile(path.Join(outputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			if got != string(output) {
+				t.Errorf("AddForkGuards() = %v, want %v", got, string(output))
+			}
+
+			// the jobs of the output are guarded, so adding the guards again does not change it
+			again, err := FindUnguardedJobs(got)
+			if err != nil || len(again) != 0 {
+				t.Errorf("FindUnguardedJobs() of the output = %v, %v, want no findings", again, err)
+			}
+			if guarded, updated, err := AddForkGuards(got, again); err != nil || updated || guarded != got {
+				t.Errorf("AddForkGuards() of the output = %v, %v, want it unchanged", updated, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Package fuzzing has the checks of the fuzz targets of the workflow remediations, which get the workflows of the
+// repositories of users, so any YAML can reach them. A remediation must not panic, its output must parse if its input
+// did, and it must leave the values and the lines it does not remediate as they were.
+package fuzzing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/diff"
+	"gopkg.in/yaml.v3"
+)
+
+// MaxLines is the number of lines of the inputs that are checked, since the lines of the input and the output are
+// compared with the longest common subsequence, which takes the square of the lines
+const MaxLines = 1000
+
+// AddSeeds adds the YAML files of the folders to the corpus of the fuzz target. The folders that are not found, e.g. by
+// the binaries built by OSS-Fuzz, which get their corpus from the seed corpus of the build, are skipped.
+func AddSeeds(f *testing.F, folders ...string) {
+	for _, folder := range folders {
+		files, err := os.ReadDir(folder)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, file := range files {
+			if file.IsDir() || (filepath.Ext(file.Name()) != ".yml" && filepath.Ext(file.Name()) != ".yaml") {
+				continue
+			}
+			content, err := os.ReadFile(filepath.Join(folder, file.Name()))
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(string(content))
+		}
+	}
+}
+
+// CheckOutput checks the output of a remediation of the input. The output must parse if the input did, and have the
+// values of the input once strip removed the values the remediation changes from both. The lines of the input that the
+// output changed or removed must be lines the remediation changes, or lines of flow collections, which get the lines
+// inserted in them.
+func CheckOutput(t *testing.T, input, output string, strip func(value interface{}), changesLine func(line string) bool) {
+	t.Helper()
+	if output == input {
+		return
+	}
+	var inputValue, outputValue interface{}
+	if err := yaml.Unmarshal([]byte(input), &inputValue); err != nil {
+		return
+	}
+	if err := yaml.Unmarshal([]byte(output), &outputValue); err != nil {
+		t.Fatalf("the output does not parse: %v\ninput:\n%s\noutput:\n%s", err, input, output)
+	}
+	inputValue, outputValue = normalize(inputValue), normalize(outputValue)
+	strip(inputValue)
+	strip(outputValue)
+	if !reflect.DeepEqual(inputValue, outputValue) {
+		t.Fatalf("the output changed values it does not remediate\ninput:\n%s\noutput:\n%s", input, output)
+	}
+
+	if strings.Count(input, "\n") > MaxLines || strings.Count(output, "\n") > MaxLines {
+		return
+	}
+	for _, change := range diff.LineChanges(input, output) {
+		if change.Kind == diff.Added || change.Before == change.After {
+			continue
+		}
+		if !changesLine(change.Before) && !strings.ContainsAny(change.Before, "[{") {
+			t.Fatalf("the output changed line %q\ninput:\n%s\noutput:\n%s", change.Before, input, output)
+		}
+	}
+}
+
+// normalize returns the value with the mappings that have keys other than strings, e.g. 0, as mappings of strings, so
+// the functions that strip values only handle the mappings of strings
+func normalize(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		mapping := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			mapping[fmt.Sprint(key)] = normalize(child)
+		}
+		return mapping
+	case map[string]interface{}:
+		for key, child := range value {
+			value[key] = normalize(child)
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = normalize(child)
+		}
+	}
+	return value
+}
+
+// DeleteKeys returns a function that deletes the keys from the mappings of a value, at any depth
+func DeleteKeys(keys ...string) func(value interface{}) {
+	var strip func(value interface{})
+	strip = func(value interface{}) {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			for _, key := range keys {
+				delete(value, key)
+			}
+			for _, child := range value {
+				strip(child)
+			}
+		case []interface{}:
+			for _, child := range value {
+				strip(child)
+			}
+		}
+	}
+	return strip
+}
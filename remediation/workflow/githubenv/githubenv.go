@@ -0,0 +1,383 @@
+package githubenv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/workflow/expressions"
+	"github.com/step-security/secure-repo/remediation/yamledit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleUntrustedEnvWrite  = "untrusted-github-env-write"
+	RuleUntrustedPathWrite = "untrusted-github-path-write"
+
+	// sanitizeSuffix removes carriage returns and newlines from a bash variable, so the value cannot start a new variable
+	sanitizeSuffix = `//[$'\r\n']/}`
+)
+
+// writeRegex matches writes to the GITHUB_ENV and GITHUB_PATH files in bash and PowerShell
+var writeRegex = regexp.MustCompile(`\$(?:env:|\{)?GITHUB_(ENV|PATH)\b`)
+
+// variableRegex matches $NAME and ${NAME}, but not ${NAME//pattern/}
+var variableRegex = regexp.MustCompile(`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)\}|([A-Za-z_][A-Za-z0-9_]*))`)
+
+var nonAlphanumericRegex = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// write is a line of a run step that writes an untrusted value to GITHUB_ENV or GITHUB_PATH
+type write struct {
+	jobName  string
+	stepNode *yaml.Node
+	shell    string
+	// line is the 0-based line of the write, and column is the offset the script starts at in the line
+	line   int
+	column int
+	file   string
+	// multi-line plain and quoted scripts are only reported
+	fixable bool
+	// values are untrusted expressions, and environment variables set from untrusted expressions
+	values []string
+}
+
+// getShell returns the shell used by a run step, based on the step, job and workflow defaults, and the runner
+func getShell(topNode, jobNode, stepNode *yaml.Node) string {
+	if shellNode := document.MappingValue(stepNode, "shell"); shellNode != nil {
+		return shellNode.Value
+	}
+	for _, node := range []*yaml.Node{jobNode, topNode} {
+		if shellNode := document.MappingValue(document.MappingValue(document.MappingValue(node, "defaults"), "run"), "shell"); shellNode != nil {
+			return shellNode.Value
+		}
+	}
+	if runsOnNode := document.MappingValue(jobNode, "runs-on"); runsOnNode != nil {
+		var labels []string
+		if runsOnNode.Kind == yaml.ScalarNode {
+			labels = append(labels, runsOnNode.Value)
+		}
+		for _, n := range runsOnNode.Content {
+			labels = append(labels, n.Value)
+		}
+		for _, label := range labels {
+			if strings.Contains(strings.ToLower(label), "windows") {
+				return "pwsh"
+			}
+		}
+	}
+	return "bash"
+}
+
+// getUntrustedEnv returns the environment variables of the step that are set from untrusted expressions,
+// including the variables set for the job and the workflow
+func getUntrustedEnv(topNode, jobNode, stepNode *yaml.Node) map[string]bool {
+	untrustedEnv := make(map[string]bool)
+	for _, node := range []*yaml.Node{topNode, jobNode, stepNode} {
+		envNode := document.MappingValue(node, "env")
+		if envNode == nil || envNode.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(envNode.Content); i += 2 {
+			untrustedEnv[envNode.Content[i].Value] = len(expressions.GetUntrustedExpressions(envNode.Content[i+1].Value)) > 0
+		}
+	}
+	return untrustedEnv
+}
+
+// getScriptLines returns the 0-based lines of a run step, and the offset the script starts at in the first line
+func getScriptLines(inputLines []string, keyNode, node *yaml.Node) (int, int, int, bool) {
+	switch {
+	case node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		return node.Line, yamledit.LastLine(inputLines, node) - 1, 0, true
+	case node.Line == keyNode.Line && !strings.Contains(node.Value, "\n"):
+		return node.Line - 1, node.Line - 1, textedit.ColumnOffset(inputLines[node.Line-1], node.Column), true
+	}
+	return 0, 0, 0, false
+}
+
+// getValues returns the untrusted expressions and environment variables used in a line
+func getValues(line string, untrustedEnv map[string]bool) []string {
+	values := expressions.GetUntrustedExpressions(line)
+	seen := make(map[string]bool)
+	for _, match := range variableRegex.FindAllStringSubmatch(line, -1) {
+		name := match[1] + match[2]
+		if untrustedEnv[name] && !seen[name] {
+			seen[name] = true
+			values = append(values, "$"+name)
+		}
+	}
+	return values
+}
+
+// getWrites returns the lines of run steps that write untrusted values to GITHUB_ENV or GITHUB_PATH
+func getWrites(topNode *yaml.Node, inputLines []string) []write {
+	jobsNode := document.MappingValue(topNode, "jobs")
+	if jobsNode == nil || jobsNode.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var writes []write
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobName, jobNode := jobsNode.Content[i].Value, document.Resolve(jobsNode.Content[i+1])
+		stepsNode := document.MappingValue(jobNode, "steps")
+		if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, stepNode := range stepsNode.Content {
+			stepNode = document.Resolve(stepNode)
+			runKeyNode, runNode := document.MappingEntry(stepNode, "run")
+			if runNode == nil || runNode.Kind != yaml.ScalarNode || !writeRegex.MatchString(runNode.Value) {
+				continue
+			}
+			untrustedEnv := getUntrustedEnv(topNode, jobNode, stepNode)
+			shell := getShell(topNode, jobNode, stepNode)
+			addWrite := func(line, column int, script string, fixable bool) {
+				match := writeRegex.FindStringSubmatch(script)
+				if match == nil {
+					return
+				}
+				if values := getValues(script, untrustedEnv); len(values) > 0 {
+					writes = append(writes, write{jobName: jobName, stepNode: stepNode, shell: shell, line: line, column: column, file: "GITHUB_" + match[1], values: values, fixable: fixable})
+				}
+			}
+			firstLine, lastLine, column, ok := getScriptLines(inputLines, runKeyNode, runNode)
+			if !ok || runNode.Anchor != "" {
+				// multi-line plain and quoted scripts are reported on the run key, and so are the scripts with an anchor,
+				// which are also the scripts of their aliases
+				addWrite(runKeyNode.Line-1, textedit.ColumnOffset(inputLines[runKeyNode.Line-1], runKeyNode.Column), runNode.Value, false)
+				continue
+			}
+			for l := firstLine; l <= lastLine && l < len(inputLines) && column <= len(inputLines[l]); l++ {
+				addWrite(l, column, inputLines[l][column:], true)
+			}
+		}
+	}
+	return writes
+}
+
+// FindUntrustedWrites returns findings for run steps that write values an attacker can set, like pull request titles
+// and branch names, to GITHUB_ENV or GITHUB_PATH. A value with a newline can set any environment variable for
+// the later steps of the job, e.g. NODE_OPTIONS or LD_PRELOAD, and a path can replace the tools used by later steps.
+func FindUntrustedWrites(inputYaml string) ([]findings.Finding, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return nil, nil
+	}
+
+	inputLines := strings.Split(inputYaml, "\n")
+	var writeFindings []findings.Finding
+	for _, w := range getWrites(t.Content[0], inputLines) {
+		for _, value := range w.values {
+			finding := findings.Finding{
+				RuleID:     RuleUntrustedEnvWrite,
+				Message:    fmt.Sprintf("%s is written to %s in job %s, and can be used to set environment variables of later steps", value, w.file, w.jobName),
+				JobName:    w.jobName,
+				Line:       w.line + 1,
+				Column:     utf8.RuneCountInString(inputLines[w.line][:w.column]) + 1,
+				Suggestion: fmt.Sprintf("Remove newlines from %s before writing it to %s", value, w.file),
+			}
+			if w.file == "GITHUB_PATH" {
+				finding.RuleID = RuleUntrustedPathWrite
+				finding.Message = fmt.Sprintf("%s is written to %s in job %s, and can be used to replace the tools used by later steps", value, w.file, w.jobName)
+				finding.Suggestion = fmt.Sprintf("Do not add paths that are set from %s to %s", value, w.file)
+			}
+			writeFindings = append(writeFindings, finding)
+		}
+	}
+	return writeFindings, nil
+}
+
+// getEnvName returns the name of the environment variable for the expression, e.g. PULL_REQUEST_TITLE for github.event.pull_request.title
+func getEnvName(expression string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(expression, "github.event."), "github.")
+	return strings.Trim(strings.ToUpper(nonAlphanumericRegex.ReplaceAllString(name, "_")), "_")
+}
+
+// sanitize replaces the untrusted expressions and environment variables in the value written to GITHUB_ENV with
+// environment variables without newlines. Values in the name of the variable are not replaced, and false is returned.
+func sanitize(script string, untrustedEnv map[string]bool) (string, map[string]string, bool) {
+	separator := strings.Index(script, "=")
+	envValues := make(map[string]string)
+
+	sanitizeMatches := func(r *regexp.Regexp, script string, getName func([]string) (string, bool)) (string, bool) {
+		ok := true
+		var result strings.Builder
+		last := 0
+		for _, loc := range r.FindAllStringSubmatchIndex(script, -1) {
+			var submatches []string
+			for i := 0; i < len(loc); i += 2 {
+				if loc[i] < 0 {
+					submatches = append(submatches, "")
+				} else {
+					submatches = append(submatches, script[loc[i]:loc[i+1]])
+				}
+			}
+			name, untrusted := getName(submatches)
+			if !untrusted {
+				continue
+			}
+			if separator < 0 || loc[0] < separator {
+				ok = false
+				continue
+			}
+			result.WriteString(script[last:loc[0]])
+			result.WriteString("${" + name + sanitizeSuffix)
+			last = loc[1]
+		}
+		result.WriteString(script[last:])
+		return result.String(), ok
+	}
+
+	script, ok := sanitizeMatches(expressions.ExpressionRegex, script, func(match []string) (string, bool) {
+		if !expressions.IsUntrusted(match[1]) {
+			return "", false
+		}
+		name := getEnvName(match[1])
+		envValues[name] = match[0]
+		return name, true
+	})
+	if !ok {
+		return script, nil, false
+	}
+	separator = strings.Index(script, "=")
+	script, ok = sanitizeMatches(variableRegex, script, func(match []string) (string, bool) {
+		name := match[1] + match[2]
+		return name, untrustedEnv[name]
+	})
+	return script, envValues, ok
+}
+
+// SanitizeUntrustedWrites removes newlines from the untrusted values in the findings that are written to GITHUB_ENV,
+// and marks the findings that were fixed. Untrusted expressions are passed to the step as environment variables,
+// since bash can only remove the newlines from variables. Writes to GITHUB_PATH, and writes in other shells, are only reported.
+func SanitizeUntrustedWrites(inputYaml string, writeFindings []findings.Finding) (string, bool, error) {
+	if len(writeFindings) == 0 {
+		return inputYaml, false, nil
+	}
+
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	topNode := t.Content[0]
+
+	jobsKeyNode, jobsNode := document.MappingEntry(topNode, "jobs")
+	indentUnit := strings.Repeat(" ", jobsNode.Content[0].Column-jobsKeyNode.Column)
+
+	findingLines := make(map[int]bool)
+	for _, finding := range writeFindings {
+		if finding.RuleID == RuleUntrustedEnvWrite {
+			findingLines[finding.Line] = true
+		}
+	}
+
+	inputLines := strings.Split(inputYaml, "\n")
+	buffer := textedit.NewBuffer(inputYaml)
+	fixedLines := make(map[int]bool)
+
+	// the writes are grouped by step, since the environment variables are added once for each step
+	var steps []*yaml.Node
+	stepWrites := make(map[*yaml.Node][]write)
+	for _, w := range getWrites(topNode, inputLines) {
+		if w.file != "GITHUB_ENV" || !w.fixable || !findingLines[w.line+1] || w.shell != "bash" || w.stepNode.Style&yaml.FlowStyle != 0 {
+			continue
+		}
+		if _, found := stepWrites[w.stepNode]; !found {
+			steps = append(steps, w.stepNode)
+		}
+		stepWrites[w.stepNode] = append(stepWrites[w.stepNode], w)
+	}
+
+	for _, stepNode := range steps {
+		var jobNode *yaml.Node
+		for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+			if jobsNode.Content[i].Value == stepWrites[stepNode][0].jobName {
+				jobNode = document.Resolve(jobsNode.Content[i+1])
+			}
+		}
+		untrustedEnv := getUntrustedEnv(topNode, jobNode, stepNode)
+		envNode := document.MappingValue(stepNode, "env")
+
+		rewritten := make(map[int]string)
+		var envNames []string
+		envValues := make(map[string]string)
+		for _, w := range stepWrites[stepNode] {
+			script, values, ok := sanitize(inputLines[w.line][w.column:], untrustedEnv)
+			if !ok {
+				continue
+			}
+			conflict := false
+			for name, value := range values {
+				existing := document.MappingValue(envNode, name)
+				conflict = conflict || (existing != nil && existing.Value != value) || (envValues[name] != "" && envValues[name] != value)
+			}
+			if conflict {
+				continue
+			}
+			for _, match := range expressions.ExpressionRegex.FindAllStringSubmatch(inputLines[w.line][w.column:], -1) {
+				name := getEnvName(match[1])
+				if _, found := values[name]; found && envValues[name] == "" && document.MappingValue(envNode, name) == nil {
+					envNames = append(envNames, name)
+					envValues[name] = values[name]
+				}
+			}
+			rewritten[w.line] = inputLines[w.line][:w.column] + script
+		}
+		if len(rewritten) == 0 {
+			continue
+		}
+
+		propertyIndent := strings.Repeat(" ", stepNode.Content[0].Column-1)
+		var envLines []string
+		for _, name := range envNames {
+			envLines = append(envLines, fmt.Sprintf("%s%s%s: %s", propertyIndent, indentUnit, name, envValues[name]))
+		}
+
+		switch {
+		case len(envLines) == 0:
+		case envNode == nil:
+			envLines = append([]string{fmt.Sprintf("%senv:", propertyIndent)}, envLines...)
+			runKeyNode, _ := document.MappingEntry(stepNode, "run")
+			if runKeyNode != stepNode.Content[0] {
+				buffer.InsertLinesBefore(runKeyNode.Line, envLines...)
+			} else {
+				stepLastLine := yamledit.LastLine(inputLines, stepNode)
+				buffer.InsertLinesAfter(stepLastLine, envLines...)
+			}
+		// an env with an anchor is not changed, since it is also the env of the steps of its aliases
+		case envNode.Kind == yaml.MappingNode && envNode.Style&yaml.FlowStyle == 0 && len(envNode.Content) > 0 && envNode.Anchor == "":
+			envIndent := strings.Repeat(" ", envNode.Content[0].Column-1)
+			for i := range envLines {
+				envLines[i] = envIndent + strings.TrimLeft(envLines[i], " ")
+			}
+			envLastLine := yamledit.LastLine(inputLines, envNode)
+			buffer.InsertLinesAfter(envLastLine, envLines...)
+		default:
+			continue
+		}
+
+		for i, line := range rewritten {
+			buffer.ReplaceLine(i+1, line)
+			fixedLines[i+1] = true
+		}
+	}
+
+	if len(fixedLines) == 0 {
+		return inputYaml, false, nil
+	}
+
+	for i, finding := range writeFindings {
+		if finding.RuleID == RuleUntrustedEnvWrite && fixedLines[finding.Line] {
+			writeFindings[i].Fixed = true
+		}
+	}
+
+	return buffer.String(), true, nil
+}
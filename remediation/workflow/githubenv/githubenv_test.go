@@ -0,0 +1,74 @@
+package githubenv
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestUntrustedWrites(t *testing.T) {
+	const inputDirectory = "../../../testfiles/githubenv/input"
+	const outputDirectory = "../../../testfiles/githubenv/output"
+
+	input, err := ioutil.ReadFile(path.Join(inputDirectory, "untrusted-writes.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+
+	got, err := FindUntrustedWrites(string(input))
+	if err != nil {
+		t.Fatalf("FindUntrustedWrites() unexpected error = %v", err)
+	}
+
+	// github.sha is not reported
+	if len(got) != 5 {
+		t.Fatalf("FindUntrustedWrites() returned %d findings, want 5: %v", len(got), got)
+	}
+	if got[1].RuleID != RuleUntrustedEnvWrite || got[1].JobName != "build" || got[1].Line != 13 {
+		t.Errorf("unexpected finding: %+v", got[1])
+	}
+	if got[2].RuleID != RuleUntrustedPathWrite || got[2].Message != "github.head_ref is written to GITHUB_PATH in job build, and can be used to replace the tools used by later steps" {
+		t.Errorf("unexpected finding: %+v", got[2])
+	}
+
+	out, updated, err := SanitizeUntrustedWrites(string(input), got)
+	if err != nil {
+		t.Fatalf("SanitizeUntrustedWrites() unexpected error = %v", err)
+	}
+	if !updated {
+		t.Errorf("SanitizeUntrustedWrites() updated = false, want true")
+	}
+
+	output, err := ioutil.ReadFile(path.Join(outputDirectory, "untrusted-writes.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+	if out != string(output) {
+		t.Errorf("SanitizeUntrustedWrites() = %v, want %v", out, string(output))
+	}
+
+	// the output is remediated, so fixing it again does not change it
+	again, err := FindUntrustedWrites(out)
+	if err != nil {
+		t.Fatalf("FindUntrustedWrites() of the output unexpected error = %v", err)
+	}
+	if fixed, updated, err := SanitizeUntrustedWrites(out, again); err != nil || updated || fixed != out {
+		t.Errorf("SanitizeUntrustedWrites() of the output = %v, %v, want it unchanged", updated, err)
+	}
+
+	// writes to GITHUB_PATH, values in the variable name and PowerShell writes are only reported
+	for i, finding := range got {
+		if finding.Fixed != (i < 2) {
+			t.Errorf("unexpected Fixed for finding: %+v", finding)
+		}
+	}
+
+	// sanitized writes are not reported again
+	got, err = FindUntrustedWrites(out)
+	if err != nil {
+		t.Fatalf("FindUntrustedWrites() unexpected error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Errorf("FindUntrustedWrites() returned %d findings after sanitizing, want 3: %v", len(got), got)
+	}
+}
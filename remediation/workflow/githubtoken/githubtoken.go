@@ -0,0 +1,123 @@
+package githubtoken
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	metadata "github.com/step-security/secure-repo/remediation/workflow/metadata"
+	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+	"gopkg.in/yaml.v3"
+)
+
+// getStepNodes returns the step nodes of all jobs, and of the runs section for composite actions
+func getStepNodes(root *yaml.Node) []*yaml.Node {
+	if len(root.Content) == 0 {
+		return nil
+	}
+	topNode := root.Content[0]
+
+	var steps []*yaml.Node
+	if jobsNode := document.MappingValue(topNode, "jobs"); jobsNode != nil && jobsNode.Kind == yaml.MappingNode {
+		for i := 1; i < len(jobsNode.Content); i += 2 {
+			stepsNode := document.MappingValue(jobsNode.Content[i], "steps")
+			if stepsNode != nil && stepsNode.Kind == yaml.SequenceNode {
+				steps = append(steps, stepsNode.Content...)
+			}
+		}
+	}
+
+	stepsNode := document.MappingValue(document.MappingValue(topNode, "runs"), "steps")
+	if stepsNode != nil && stepsNode.Kind == yaml.SequenceNode {
+		steps = append(steps, stepsNode.Content...)
+	}
+
+	return steps
+}
+
+// isTokenUnused returns true if the knowledge base entry does not declare any use of the GITHUB_TOKEN
+func isTokenUnused(actionMetadata *metadata.ActionMetadata) bool {
+	return actionMetadata.GitHubToken.ActionInput.Input == "" &&
+		actionMetadata.GitHubToken.EnvironmentVariableName == "" &&
+		len(actionMetadata.GitHubToken.Permissions.Scopes) == 0
+}
+
+// getUnnecessaryTokenLines returns the (0-based) lines of a step's token inputs that can be removed.
+// An input is unnecessary if the action does not use the token at all,
+// or if the input already defaults to the GITHUB_TOKEN and is set to it explicitly.
+func getUnnecessaryTokenLines(stepNode *yaml.Node) []int {
+	usesNode := document.MappingValue(stepNode, "uses")
+	withKeyNode, withNode := document.MappingEntry(stepNode, "with")
+	// inputs with an anchor are not changed, since they may also be the inputs of other actions
+	if usesNode == nil || withNode == nil || withNode.Kind != yaml.MappingNode || withNode.Style&yaml.FlowStyle != 0 || withNode.Anchor != "" {
+		return nil
+	}
+
+	// Docker and local actions are not in the knowledge base
+	atIndex := strings.Index(usesNode.Value, "@")
+	if atIndex == -1 || strings.HasPrefix(usesNode.Value, "docker://") {
+		return nil
+	}
+	actionKey := usesNode.Value[0:atIndex]
+
+	actionMetadata, err := metadata.GetActionKnowledgeBase(actionKey)
+	if err != nil {
+		return nil
+	}
+
+	tokenUnused := isTokenUnused(actionMetadata)
+	defaultTokenInput := ""
+	if actionMetadata.GitHubToken.ActionInput.IsDefault {
+		defaultTokenInput = actionMetadata.GitHubToken.ActionInput.Input
+	}
+
+	var lines []int
+	for i := 0; i+1 < len(withNode.Content); i += 2 {
+		keyNode, valueNode := withNode.Content[i], withNode.Content[i+1]
+		// a token with an anchor is not removed, since its aliases would be left without it
+		if valueNode.Kind != yaml.ScalarNode || valueNode.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 || valueNode.Anchor != "" {
+			continue
+		}
+		if !permissions.IsGitHubToken(valueNode.Value) {
+			continue
+		}
+		if tokenUnused || keyNode.Value == defaultTokenInput {
+			lines = append(lines, keyNode.Line-1)
+		}
+	}
+
+	// remove the with key as well, if all the inputs are being removed
+	if len(lines) > 0 && len(lines) == len(withNode.Content)/2 {
+		lines = append(lines, withKeyNode.Line-1)
+	}
+
+	return lines
+}
+
+// RemoveUnnecessaryTokenInputs removes GITHUB_TOKEN inputs passed to actions which, as per the knowledge base,
+// either do not use the token, or already use it by default. This reduces exposure of the token to third-party code.
+func RemoveUnnecessaryTokenInputs(inputYaml string) (string, bool, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+
+	linesToRemove := make(map[int]bool)
+	for _, stepNode := range getStepNodes(t) {
+		for _, line := range getUnnecessaryTokenLines(stepNode) {
+			linesToRemove[line] = true
+		}
+	}
+
+	if len(linesToRemove) == 0 {
+		return inputYaml, false, nil
+	}
+
+	buffer := textedit.NewBuffer(inputYaml)
+	for line := range linesToRemove {
+		buffer.DeleteLine(line + 1)
+	}
+
+	return buffer.String(), true, nil
+}
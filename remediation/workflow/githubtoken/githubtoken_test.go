@@ -0,0 +1,57 @@
+package githubtoken
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestRemoveUnnecessaryTokenInputs(t *testing.T) {
+	const inputDirectory = "../../../testfiles/githubtoken/input"
+	const outputDirectory = "../../../testfiles/githubtoken/output"
+
+	os.Setenv("KBFolder", "../../../knowledge-base/actions")
+
+	tests := []struct {
+		fileName    string
+		wantUpdated bool
+	}{
+		{fileName: "unnecessary-token-inputs.yml", wantUpdated: true},
+		{fileName: "no-token-inputs.yml", wantUpdated: false},
+		{fileName: "composite-action.yml", wantUpdated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			input, err := ioutil.ReadFile(path.Join(inputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			got, gotUpdated, err := RemoveUnnecessaryTokenInputs(string(input))
+			if err != nil {
+				t.Errorf("RemoveUnnecessaryTokenInputs() unexpected error = %v", err)
+			}
+
+			if gotUpdated != tt.wantUpdated {
+				t.Errorf("RemoveUnnecessaryTokenInputs() updated = %v, wantUpdated %v", gotUpdated, tt.wantUpdated)
+			}
+
+			output, err := ioutil.ReadFile(path.Join(outputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			if got != string(output) {
+				t.Errorf("RemoveUnnecessaryTokenInputs() = %v, want %v", got, string(output))
+			}
+
+			// the tokens of the output are needed, so removing them again does not change it
+			again, againUpdated, err := RemoveUnnecessaryTokenInputs(got)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("RemoveUnnecessaryTokenInputs() of the output = %v, %v, want it unchanged", againUpdated, err)
+			}
+		})
+	}
+}
@@ -1,12 +1,13 @@
 package hardenrunner
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
+	"github.com/step-security/secure-repo/remediation/workflow/document"
 	metadata "github.com/step-security/secure-repo/remediation/workflow/metadata"
-	"github.com/step-security/secure-repo/remediation/workflow/permissions"
-	"github.com/step-security/secure-repo/remediation/workflow/pin"
+	"github.com/step-security/secure-repo/remediation/yamledit"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,6 +17,12 @@ const (
 	DefaultHardenRunnerConfig = "- name: Harden the runner (Audit all outbound calls)\n  uses: step-security/harden-runner@v2\n  with:\n    egress-policy: audit"
 )
 
+// Pinner pins the action of harden-runner added to a workflow, e.g. to the commit of its tag, which is looked up with the
+// GitHub API. The builds without the network, e.g. the preview in the browser, add harden-runner with its tag instead.
+type Pinner interface {
+	PinAction(ctx context.Context, action, inputYaml string, pinToImmutable bool) (string, error)
+}
+
 type HardenRunnerConfig struct {
 	Config           string   `json:"config"`
 	Subtractive      bool     `json:"subtractive"`
@@ -23,17 +30,14 @@ type HardenRunnerConfig struct {
 	RunnerLabels     []string `json:"runnerLabels"`
 }
 
-// getJobRunsOnLabels extracts the runs-on labels from a job's yaml.Node.
+// getJobRunsOnLabels extracts the runs-on labels of an indexed job.
 // Handles scalar (runs-on: ubuntu-latest), sequence (runs-on: [self-hosted, linux]),
 // and mapping with labels key (runs-on: {labels: [self-hosted, linux]}) formats.
-func getJobRunsOnLabels(jobNode *yaml.Node) []string {
-	for i := 0; i < len(jobNode.Content); i += 2 {
-		keyNode := jobNode.Content[i]
-		if keyNode.Value == "runs-on" && i+1 < len(jobNode.Content) {
-			return extractLabels(jobNode.Content[i+1])
-		}
+func getJobRunsOnLabels(job *document.Job) []string {
+	if job.RunsOn == nil {
+		return nil
 	}
-	return nil
+	return extractLabels(job.RunsOn)
 }
 
 // extractLabels extracts labels from a yaml.Node that can be a scalar, sequence, or mapping with a "labels" key.
@@ -82,38 +86,29 @@ func getActionFromConfig(config HardenRunnerConfig) string {
 	return HardenRunnerActionPath
 }
 
-func AddAction(inputYaml string, hardenRunnerConfig HardenRunnerConfig, pinActions, pinToImmutable bool, skipContainerJobs bool) (string, bool, error) {
+// AddAction adds harden-runner to the jobs of the workflow, and pins it with the pinner if the workflow changed and the
+// pinner is not nil
+func AddAction(ctx context.Context, inputYaml string, hardenRunnerConfig HardenRunnerConfig, pinner Pinner, pinToImmutable bool, skipContainerJobs bool) (string, bool, error) {
 	if hardenRunnerConfig.Config == "" {
 		hardenRunnerConfig.Config = DefaultHardenRunnerConfig
 	}
-	workflow := metadata.Workflow{}
 	updated := false
-	err := yaml.Unmarshal([]byte(inputYaml), &workflow)
+	// the steps are added with the lines of the tree of the input, which is parsed once
+	doc := document.Parse(inputYaml)
+	workflow, err := doc.Workflow()
 	if err != nil {
 		return "", updated, fmt.Errorf("unable to parse yaml %v", err)
 	}
+	index, _ := doc.Index()
 
 	// Extract the action path from the config to detect custom actions already present.
 	configAction := getActionFromConfig(hardenRunnerConfig)
 	configActionPath := strings.Split(configAction, "@")[0]
 
-	// Build a map of jobName → yaml.Node for runs-on label lookup
-	jobNodeMap := map[string]*yaml.Node{}
-	if hardenRunnerConfig.SkipHardenRunner && len(hardenRunnerConfig.RunnerLabels) > 0 {
-		t := yaml.Node{}
-		if err := yaml.Unmarshal([]byte(inputYaml), &t); err == nil {
-			jobsNode := permissions.IterateNode(&t, "jobs", "!!map", 0)
-			if jobsNode != nil {
-				for i := 0; i < len(jobsNode.Content); i += 2 {
-					jobNodeMap[jobsNode.Content[i].Value] = jobsNode.Content[i+1]
-				}
-			}
-		}
-	}
-
-	out := inputYaml
+	editor := yamledit.New(inputYaml)
 
-	for jobName, job := range workflow.Jobs {
+	for _, jobName := range workflow.Jobs.Names() {
+		job := workflow.Jobs[jobName]
 		// Skip adding action for reusable jobs
 		if metadata.IsCallingReusableWorkflow(job) {
 			continue
@@ -124,8 +119,8 @@ func AddAction(inputYaml string, hardenRunnerConfig HardenRunnerConfig, pinActio
 		}
 		// Skip jobs whose runs-on label doesn't match the allowed labels
 		if hardenRunnerConfig.SkipHardenRunner && len(hardenRunnerConfig.RunnerLabels) > 0 {
-			if jn, ok := jobNodeMap[jobName]; ok {
-				if shouldSkipJob(getJobRunsOnLabels(jn), hardenRunnerConfig.RunnerLabels) {
+			if indexedJob := index.Job(jobName); indexedJob != nil {
+				if shouldSkipJob(getJobRunsOnLabels(indexedJob), hardenRunnerConfig.RunnerLabels) {
 					continue
 				}
 			}
@@ -139,23 +134,25 @@ func AddAction(inputYaml string, hardenRunnerConfig HardenRunnerConfig, pinActio
 		}
 
 		if !alreadyPresent {
-			out, err = addAction(out, jobName, hardenRunnerConfig)
+			err = addAction(editor, index, jobName, hardenRunnerConfig)
 			if err != nil {
-				return out, updated, err
+				return editor.String(), updated, err
 			}
 			updated = true
 		} else if hardenRunnerConfig.Subtractive {
-			out, err = updateHardenRunnerConfig(out, jobName, hardenRunnerConfig)
+			err = updateHardenRunnerConfig(editor, index, jobName, hardenRunnerConfig)
 			if err != nil {
-				return out, updated, err
+				return editor.String(), updated, err
 			}
-			updated = true
 		}
 	}
 
-	if updated && pinActions {
+	// the steps that already have the config are replaced with the same lines, which does not change the workflow
+	out := editor.String()
+	updated = updated || out != inputYaml
+	if updated && pinner != nil {
 		action := getActionFromConfig(hardenRunnerConfig)
-		out, _, err = pin.PinActionWithPatFallback(action, out, nil, pinToImmutable, nil)
+		out, err = pinner.PinAction(ctx, action, out, pinToImmutable)
 		if err != nil {
 			return out, updated, err
 		}
@@ -164,112 +161,60 @@ func AddAction(inputYaml string, hardenRunnerConfig HardenRunnerConfig, pinActio
 	return out, updated, nil
 }
 
-func updateHardenRunnerConfig(inputYaml, jobName string, hardenRunnerConfig HardenRunnerConfig) (string, error) {
-	t := yaml.Node{}
-	err := yaml.Unmarshal([]byte(inputYaml), &t)
-	if err != nil {
-		return "", fmt.Errorf("unable to parse yaml %v", err)
-	}
-
-	jobNode := permissions.IterateNode(&t, "jobs", "!!map", 0)
-	jobNode = permissions.IterateNode(&t, jobName, "!!map", jobNode.Line)
-	stepsNode := permissions.IterateNode(&t, "steps", "!!seq", jobNode.Line)
-	if stepsNode == nil {
-		return "", fmt.Errorf("steps not found for job %s", jobName)
+// updateHardenRunnerConfig replaces the harden-runner step of the job with the config, whose node is looked up in the
+// index of the input of the editor
+func updateHardenRunnerConfig(editor *yamledit.Editor, index *document.Index, jobName string, hardenRunnerConfig HardenRunnerConfig) error {
+	job := index.Job(jobName)
+	if job == nil || job.Steps == nil {
+		return fmt.Errorf("steps not found for job %s", jobName)
 	}
 
-	spaces := strings.Repeat(" ", stepsNode.Column-1)
-	inputLines := strings.Split(inputYaml, "\n")
-
-	hrStartLine := -1
-	hrEndLine := len(inputLines)
-
-	for i, stepNode := range stepsNode.Content {
-		isHR := false
+	for i, stepNode := range job.Steps.Content {
 		for j := 0; j+1 < len(stepNode.Content); j += 2 {
 			if stepNode.Content[j].Value == "uses" && strings.HasPrefix(stepNode.Content[j+1].Value, HardenRunnerActionPath) {
-				isHR = true
-				break
+				return editor.ReplaceItem(job.Steps, i, withoutHeadComment(configLines(hardenRunnerConfig), stepNode.HeadComment))
 			}
 		}
-		if !isHR {
-			continue
-		}
-		hrStartLine = stepNode.Line - 1 // convert to 0-indexed
-		if i+1 < len(stepsNode.Content) {
-			hrEndLine = stepsNode.Content[i+1].Line - 1
-		} else {
-			// last step — scan forward until line is no longer part of this step
-			stepContentPrefix := spaces + " "
-			for j := hrStartLine + 1; j < len(inputLines); j++ {
-				line := inputLines[j]
-				if strings.TrimSpace(line) == "" {
-					continue
-				}
-				if !strings.HasPrefix(line, stepContentPrefix) {
-					hrEndLine = j
-					break
-				}
-			}
-		}
-		break
 	}
+	return nil
+}
 
-	if hrStartLine < 0 {
-		return inputYaml, nil
+// withoutHeadComment returns the lines of the config without the comments they start with, if they are the comments
+// before the step that is replaced, which are kept by replacing the step, so they are not written again
+func withoutHeadComment(lines, headComment string) string {
+	rest := lines
+	var comments []string
+	for rest != "" && strings.HasPrefix(strings.TrimSpace(rest), "#") {
+		line, next, _ := strings.Cut(rest, "\n")
+		comments, rest = append(comments, strings.TrimSpace(line)), next
 	}
-
-	var output []string
-	output = append(output, inputLines[:hrStartLine]...)
-	for _, line := range strings.Split(hardenRunnerConfig.Config, "\n") {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		output = append(output, spaces+line)
+	if len(comments) == 0 || !strings.HasSuffix(headComment, strings.Join(comments, "\n")) {
+		return lines
 	}
-	output = append(output, "")
-	output = append(output, inputLines[hrEndLine:]...)
-
-	return strings.Join(output, "\n"), nil
+	return rest
 }
 
-func addAction(inputYaml, jobName string, hardenRunnerConfig HardenRunnerConfig) (string, error) {
-	t := yaml.Node{}
+// addAction inserts the config before the first step of the job, whose node is looked up in the index of the input of
+// the editor
+func addAction(editor *yamledit.Editor, index *document.Index, jobName string, hardenRunnerConfig HardenRunnerConfig) error {
+	job := index.Job(jobName)
 
-	err := yaml.Unmarshal([]byte(inputYaml), &t)
-	if err != nil {
-		return "", fmt.Errorf("unable to parse yaml %v", err)
+	if job == nil || job.Steps == nil {
+		return fmt.Errorf("jobName %s not found in the input yaml", jobName)
 	}
 
-	jobNode := permissions.IterateNode(&t, "jobs", "!!map", 0)
-
-	jobNode = permissions.IterateNode(&t, jobName, "!!map", jobNode.Line)
-
-	jobNode = permissions.IterateNode(&t, "steps", "!!seq", jobNode.Line)
-
-	if jobNode == nil {
-		return "", fmt.Errorf("jobName %s not found in the input yaml", jobName)
-	}
-
-	inputLines := strings.Split(inputYaml, "\n")
-	var output []string
-	for i := 0; i < jobNode.Line-1; i++ {
-		output = append(output, inputLines[i])
-	}
-
-	spaces := strings.Repeat(" ", jobNode.Column-1)
+	// the step is followed by an empty line
+	return editor.InsertItems(job.Steps, 0, configLines(hardenRunnerConfig)+"\n")
+}
 
+// configLines returns the non-empty lines of the config, each followed by a newline
+func configLines(hardenRunnerConfig HardenRunnerConfig) string {
+	var lines strings.Builder
 	for _, line := range strings.Split(hardenRunnerConfig.Config, "\n") {
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
-		output = append(output, spaces+line)
+		lines.WriteString(line + "\n")
 	}
-	output = append(output, "")
-
-	for i := jobNode.Line - 1; i < len(inputLines); i++ {
-		output = append(output, inputLines[i])
-	}
-
-	return strings.Join(output, "\n"), nil
+	return lines.String()
 }
@@ -1,6 +1,7 @@
 package hardenrunner
 
 import (
+	"context"
 	"io/ioutil"
 	"path"
 	"testing"
@@ -27,6 +28,8 @@ func TestAddAction(t *testing.T) {
 		{name: "already present 2", args: args{inputYaml: "alreadypresent_2.yml"}, want: "alreadypresent_2.yml", wantErr: false, wantUpdated: false},
 		{name: "reusable job", args: args{inputYaml: "reusablejob.yml"}, want: "reusablejob.yml", wantErr: false, wantUpdated: false},
 		{name: "job name in input", args: args{inputYaml: "jobNameInInput.yml"}, want: "jobNameInInput.yml", wantErr: false, wantUpdated: true},
+		{name: "flow steps", args: args{inputYaml: "flowSteps.yml"}, want: "flowSteps.yml", wantErr: false, wantUpdated: true},
+		{name: "four spaces", args: args{inputYaml: "fourSpaces.yml"}, want: "fourSpaces.yml", wantErr: false, wantUpdated: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -34,7 +37,7 @@ func TestAddAction(t *testing.T) {
 			if err != nil {
 				t.Fatalf("error reading test file")
 			}
-			got, gotUpdated, err := AddAction(string(input), HardenRunnerConfig{Config: defaultTestConfig}, false, false, false)
+			got, gotUpdated, err := AddAction(context.Background(), string(input), HardenRunnerConfig{Config: defaultTestConfig}, nil, false, false)
 
 			if gotUpdated != tt.wantUpdated {
 				t.Errorf("AddAction() updated = %v, wantUpdated %v", gotUpdated, tt.wantUpdated)
@@ -51,6 +54,12 @@ func TestAddAction(t *testing.T) {
 			if got != string(output) {
 				t.Errorf("AddAction() = %v, want %v", got, string(output))
 			}
+
+			// the jobs of the output have harden-runner, so adding it again does not change it
+			again, againUpdated, err := AddAction(context.Background(), got, HardenRunnerConfig{Config: defaultTestConfig}, nil, false, false)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("AddAction() of the output = %v, %v, want it unchanged", againUpdated, err)
+			}
 		})
 	}
 }
@@ -113,7 +122,7 @@ func TestCustomActionConfig(t *testing.T) {
 			if err != nil {
 				t.Fatalf("error reading input file: %v", err)
 			}
-			got, gotUpdated, err := AddAction(string(input), tt.config, false, false, false)
+			got, gotUpdated, err := AddAction(context.Background(), string(input), tt.config, nil, false, false)
 			if err != nil {
 				t.Errorf("AddAction() error = %v", err)
 			}
@@ -127,6 +136,12 @@ func TestCustomActionConfig(t *testing.T) {
 			if got != string(expected) {
 				t.Errorf("AddAction() output mismatch\nGot:\n%s\nWant:\n%s", got, string(expected))
 			}
+
+			// the jobs of the output have the custom action, so adding it again does not change it
+			again, againUpdated, err := AddAction(context.Background(), got, tt.config, nil, false, false)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("AddAction() of the output = %v, %v, want it unchanged\n%s", againUpdated, err, again)
+			}
 		})
 	}
 }
@@ -203,7 +218,7 @@ func TestUpdateHardenRunnerConfig(t *testing.T) {
 			if err != nil {
 				t.Fatalf("error reading input file: %v", err)
 			}
-			got, gotUpdated, err := AddAction(string(input), tt.config, false, false, false)
+			got, gotUpdated, err := AddAction(context.Background(), string(input), tt.config, nil, false, false)
 			if err != nil {
 				t.Errorf("AddAction() error = %v", err)
 			}
@@ -217,6 +232,12 @@ func TestUpdateHardenRunnerConfig(t *testing.T) {
 			if got != string(expected) {
 				t.Errorf("AddAction() = %v, want %v", got, string(expected))
 			}
+
+			// the output has the config, so updating it again does not change it
+			again, againUpdated, err := AddAction(context.Background(), got, tt.config, nil, false, false)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("AddAction() of the output = %v, %v, want it unchanged\n%s", againUpdated, err, again)
+			}
 		})
 	}
 }
@@ -371,7 +392,7 @@ func TestRunnerLabelFiltering(t *testing.T) {
 			if err != nil {
 				t.Fatalf("error reading input file: %v", err)
 			}
-			got, gotUpdated, err := AddAction(string(input), tt.config, false, false, false)
+			got, gotUpdated, err := AddAction(context.Background(), string(input), tt.config, nil, false, false)
 			if err != nil {
 				t.Errorf("AddAction() error = %v", err)
 			}
@@ -391,6 +412,12 @@ func TestRunnerLabelFiltering(t *testing.T) {
 					t.Errorf("AddAction() output mismatch\nGot:\n%s\nWant:\n%s", got, string(expected))
 				}
 			}
+
+			// the jobs of the output with the labels have harden-runner, so adding it again does not change it
+			again, againUpdated, err := AddAction(context.Background(), got, tt.config, nil, false, false)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("AddAction() of the output = %v, %v, want it unchanged\n%s", againUpdated, err, again)
+			}
 		})
 	}
 }
@@ -406,7 +433,7 @@ func TestAddActionWithContainer(t *testing.T) {
 	}
 
 	// Test: Skip container jobs when skipContainerJobs = true
-	got, gotUpdated, err := AddAction(string(input), HardenRunnerConfig{Config: defaultTestConfig}, false, false, true)
+	got, gotUpdated, err := AddAction(context.Background(), string(input), HardenRunnerConfig{Config: defaultTestConfig}, nil, false, true)
 	if err != nil {
 		t.Errorf("AddAction() with skipContainerJobs=true error = %v", err)
 	}
@@ -464,7 +491,7 @@ func TestAddActionWithEmptyConfig(t *testing.T) {
 		t.Fatalf("error reading test file: %v", err)
 	}
 	// Empty Config should use DefaultHardenRunnerConfig
-	got, gotUpdated, err := AddAction(string(input), HardenRunnerConfig{}, false, false, false)
+	got, gotUpdated, err := AddAction(context.Background(), string(input), HardenRunnerConfig{}, nil, false, false)
 	if err != nil {
 		t.Fatalf("AddAction() error = %v", err)
 	}
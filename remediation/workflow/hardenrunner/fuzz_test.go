@@ -0,0 +1,46 @@
+package hardenrunner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/workflow/fuzzing"
+)
+
+// deleteHardenRunner deletes the Harden-Runner steps of the steps of the jobs of a workflow
+func deleteHardenRunner(value interface{}) {
+	workflow, _ := value.(map[string]interface{})
+	jobs, _ := workflow["jobs"].(map[string]interface{})
+	for _, job := range jobs {
+		job, _ := job.(map[string]interface{})
+		steps, ok := job["steps"].([]interface{})
+		if !ok {
+			continue
+		}
+		var kept []interface{}
+		for _, step := range steps {
+			if step, ok := step.(map[string]interface{}); ok {
+				if uses, _ := step["uses"].(string); strings.HasPrefix(uses, HardenRunnerActionPath) {
+					continue
+				}
+			}
+			kept = append(kept, step)
+		}
+		job["steps"] = kept
+	}
+}
+
+func FuzzAddAction(f *testing.F) {
+	fuzzing.AddSeeds(f, "../../../testfiles/addaction/input")
+	f.Fuzz(func(t *testing.T, input string) {
+		output, _, err := AddAction(context.Background(), input, HardenRunnerConfig{Config: defaultTestConfig}, nil, false, false)
+		if err != nil {
+			return
+		}
+		// only the steps of Harden-Runner are added
+		fuzzing.CheckOutput(t, input, output, deleteHardenRunner, func(line string) bool {
+			return false
+		})
+	})
+}
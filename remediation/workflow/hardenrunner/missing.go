@@ -0,0 +1,61 @@
+package hardenrunner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const RuleMissingHardenRunner = "missing-harden-runner"
+
+// FindJobsWithoutHardenRunner returns findings for the jobs that AddAction adds harden-runner to with the default
+// configuration, which are the jobs that run steps without it. The findings are at the name of the job.
+func FindJobsWithoutHardenRunner(inputYaml string) ([]findings.Finding, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 || t.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	jobsNode := document.MappingValue(t.Content[0], "jobs")
+	if jobsNode == nil || jobsNode.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var missing []findings.Finding
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobKeyNode, jobNode := jobsNode.Content[i], document.Resolve(jobsNode.Content[i+1])
+		// reusable workflows add harden-runner to their own jobs
+		if jobNode.Kind != yaml.MappingNode || document.MappingValue(jobNode, "uses") != nil || hasHardenRunner(document.MappingValue(jobNode, "steps")) {
+			continue
+		}
+		missing = append(missing, findings.Finding{
+			RuleID:     RuleMissingHardenRunner,
+			Message:    fmt.Sprintf("Job %s does not run Harden-Runner to monitor its outbound traffic", jobKeyNode.Value),
+			JobName:    jobKeyNode.Value,
+			Line:       jobKeyNode.Line,
+			Column:     jobKeyNode.Column,
+			Suggestion: "Add step-security/harden-runner as the first step of the job",
+		})
+	}
+	return missing, nil
+}
+
+func hasHardenRunner(stepsNode *yaml.Node) bool {
+	if stepsNode == nil {
+		return false
+	}
+	for _, stepNode := range stepsNode.Content {
+		if stepNode = document.Resolve(stepNode); stepNode.Kind != yaml.MappingNode {
+			continue
+		}
+		if uses := document.MappingValue(stepNode, "uses"); uses != nil && strings.HasPrefix(uses.Value, HardenRunnerActionPath) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package hardenrunner
+
+import "testing"
+
+func TestFindJobsWithoutHardenRunner(t *testing.T) {
+	input := `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: step-security/harden-runner@v2
+        with:
+          egress-policy: audit
+      - run: go test ./...
+  release:
+    uses: octo-org/workflows/.github/workflows/release.yml@main
+`
+	got, err := FindJobsWithoutHardenRunner(input)
+	if err != nil {
+		t.Fatalf("FindJobsWithoutHardenRunner() unexpected error = %v", err)
+	}
+	if len(got) != 1 || got[0].RuleID != RuleMissingHardenRunner || got[0].JobName != "build" || got[0].Line != 4 || got[0].Column != 3 {
+		t.Errorf("FindJobsWithoutHardenRunner() = %+v, want the build job", got)
+	}
+
+	if _, err := FindJobsWithoutHardenRunner("jobs: [build"); err == nil {
+		t.Errorf("expected an error for invalid YAML")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/metrics"
 	metadata "github.com/step-security/secure-repo/remediation/workflow/metadata"
 	"golang.org/x/oauth2"
 )
@@ -80,7 +81,7 @@ func getClient(PAT string) *github.Client {
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: PAT},
 	)
-	tc := oauth2.NewClient(ctx, ts)
+	tc := oauth2.NewClient(metrics.WithGitHubClient(ctx), ts)
 
 	client := github.NewClient(tc)
 	return client
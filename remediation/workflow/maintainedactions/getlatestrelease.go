@@ -2,11 +2,14 @@ package maintainedactions
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/metrics"
+	"github.com/step-security/secure-repo/remediation/workflow/actionrepo"
 	"golang.org/x/oauth2"
 )
 
@@ -30,7 +33,7 @@ func getMajorVersion(version string) string {
 	return version
 }
 
-func GetLatestRelease(ownerRepo string) (string, error) {
+func GetLatestRelease(ctx context.Context, ownerRepo string) (string, error) {
 	splitOnSlash := strings.Split(ownerRepo, "/")
 	if len(splitOnSlash) < 2 {
 		return "", fmt.Errorf("invalid owner/repo format: %s", ownerRepo)
@@ -38,11 +41,9 @@ func GetLatestRelease(ownerRepo string) (string, error) {
 	owner := splitOnSlash[0]
 	repo := splitOnSlash[1]
 
-	ctx := context.Background()
-
 	// First try without token
-	client := github.NewClient(nil)
-	release, _, err := client.Repositories.GetLatestRelease(ctx, owner, repo)
+	client := github.NewClient(metrics.GitHubHTTPClient())
+	tagName, err := actionrepo.LatestRelease(ctx, client, owner, repo)
 	if err != nil {
 		// If failed, try with token
 		token := os.Getenv("PAT")
@@ -53,22 +54,22 @@ func GetLatestRelease(ownerRepo string) (string, error) {
 		ts := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: token},
 		)
-		tc := oauth2.NewClient(ctx, ts)
+		tc := oauth2.NewClient(metrics.WithGitHubClient(ctx), ts)
 		client = github.NewClient(tc)
 
-		release, _, err = client.Repositories.GetLatestRelease(ctx, owner, repo)
+		tagName, err = actionrepo.LatestRelease(ctx, client, owner, repo)
 		if err != nil {
 			return "", fmt.Errorf("failed to get latest release with token: %w", err)
 		}
 	}
 
-	return getMajorVersion(release.GetTagName()), nil
+	return getMajorVersion(tagName), nil
 }
 
 // GetMajorTagFromSHA finds the major version tag (e.g., "v5") on ownerRepo
 // whose commit matches the given SHA, by listing all tags with prefix "tags/v".
 // Returns ("", nil) if no matching tag is found.
-func GetMajorTagFromSHA(ownerRepo, sha string) (string, error) {
+func GetMajorTagFromSHA(ctx context.Context, ownerRepo, sha string) (string, error) {
 	splitOnSlash := strings.Split(ownerRepo, "/")
 	if len(splitOnSlash) < 2 {
 		return "", fmt.Errorf("invalid owner/repo format: %s", ownerRepo)
@@ -76,36 +77,26 @@ func GetMajorTagFromSHA(ownerRepo, sha string) (string, error) {
 	owner := splitOnSlash[0]
 	repo := splitOnSlash[1]
 
-	ctx := context.Background()
-	client := github.NewClient(nil)
+	client := github.NewClient(metrics.GitHubHTTPClient())
 
 	token := os.Getenv("PAT")
 	if token != "" {
 		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-		client = github.NewClient(oauth2.NewClient(ctx, ts))
+		client = github.NewClient(oauth2.NewClient(metrics.WithGitHubClient(ctx), ts))
 	}
 
-	refs, _, err := client.Git.ListMatchingRefs(ctx, owner, repo, &github.ReferenceListOptions{
-		Ref: "tags/v",
-	})
+	tags, err := actionrepo.ListTags(ctx, client, owner, repo)
 	if err != nil {
 		return "", err
 	}
 
-	for _, ref := range refs {
-		var refSHA string
-		if ref.GetObject().GetType() == "commit" {
-			refSHA = ref.GetObject().GetSHA()
-		} else {
-			// annotated tag — dereference to get the commit SHA
-			refSHA, _, err = client.Repositories.GetCommitSHA1(ctx, owner, repo, ref.GetRef(), "")
-			if err != nil {
-				continue
-			}
+	for _, tag := range tags.WithPrefix("v") {
+		tagSHA, err := tag.CommitSHA(ctx, client, owner, repo)
+		if err != nil {
+			continue
 		}
-		if refSHA == sha {
-			tag := strings.TrimPrefix(ref.GetRef(), "refs/tags/")
-			return getMajorVersion(tag), nil
+		if tagSHA == sha {
+			return getMajorVersion(tag.Name), nil
 		}
 	}
 	return "", nil
@@ -113,9 +104,10 @@ func GetMajorTagFromSHA(ownerRepo, sha string) (string, error) {
 
 // GetMajorTagIfExists checks whether ownerRepo has a tag exactly matching
 // majorVersion (e.g., "v5"). Returns (majorVersion, true, nil) when the tag
-// exists, ("", false, nil) when it is absent (404), and ("", false, err) for
-// unexpected API failures.
-func GetMajorTagIfExists(ownerRepo, majorVersion string) (string, bool, error) {
+// exists, ("", false, nil) when it or the repository is absent (404), and
+// ("", false, err) for unexpected API failures. The tags are listed once for
+// the repository, and shared with the other lookups of its tags.
+func GetMajorTagIfExists(ctx context.Context, ownerRepo, majorVersion string) (string, bool, error) {
 	splitOnSlash := strings.Split(ownerRepo, "/")
 	if len(splitOnSlash) < 2 {
 		return "", false, fmt.Errorf("invalid owner/repo format: %s", ownerRepo)
@@ -123,14 +115,13 @@ func GetMajorTagIfExists(ownerRepo, majorVersion string) (string, bool, error) {
 	owner := splitOnSlash[0]
 	repo := splitOnSlash[1]
 
-	ctx := context.Background()
-	client := github.NewClient(nil)
+	client := github.NewClient(metrics.GitHubHTTPClient())
 
-	_, resp, err := client.Git.GetRef(ctx, owner, repo, "refs/tags/"+majorVersion)
+	tags, err := actionrepo.ListTags(ctx, client, owner, repo)
 	if err == nil {
-		return majorVersion, true, nil
+		return hasTag(tags, majorVersion)
 	}
-	if resp != nil && resp.StatusCode == 404 {
+	if isNotFound(err) {
 		return "", false, nil
 	}
 
@@ -140,15 +131,28 @@ func GetMajorTagIfExists(ownerRepo, majorVersion string) (string, bool, error) {
 		return "", false, nil
 	}
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(ctx, ts)
+	tc := oauth2.NewClient(metrics.WithGitHubClient(ctx), ts)
 	client = github.NewClient(tc)
 
-	_, resp, err = client.Git.GetRef(ctx, owner, repo, "refs/tags/"+majorVersion)
+	tags, err = actionrepo.ListTags(ctx, client, owner, repo)
 	if err == nil {
-		return majorVersion, true, nil
+		return hasTag(tags, majorVersion)
 	}
-	if resp != nil && resp.StatusCode == 404 {
+	if isNotFound(err) {
 		return "", false, nil
 	}
 	return "", false, fmt.Errorf("failed to check tag %s on %s: %w", majorVersion, ownerRepo, err)
 }
+
+func hasTag(tags *actionrepo.Tags, majorVersion string) (string, bool, error) {
+	if tags.Has(majorVersion) {
+		return majorVersion, true, nil
+	}
+	return "", false, nil
+}
+
+// isNotFound returns true if the error is a 404 of the GitHub API, e.g. for a repository that does not exist
+func isNotFound(err error) bool {
+	var errorResponse *github.ErrorResponse
+	return errors.As(err, &errorResponse) && errorResponse.Response != nil && errorResponse.Response.StatusCode == 404
+}
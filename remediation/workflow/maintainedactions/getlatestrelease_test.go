@@ -1,6 +1,7 @@
 package maintainedactions
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"path"
@@ -26,7 +27,7 @@ func TestGetMajorVersion(t *testing.T) {
 }
 
 func TestGetLatestRelease_InvalidRepo(t *testing.T) {
-	if _, err := GetLatestRelease("no-slash"); err == nil {
+	if _, err := GetLatestRelease(context.Background(), "no-slash"); err == nil {
 		t.Fatal("expected error for invalid owner/repo")
 	}
 }
@@ -37,7 +38,7 @@ func TestGetLatestRelease_NoPATFails(t *testing.T) {
 	t.Setenv("PAT", "")
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/releases/latest",
 		httpmock.NewStringResponder(500, `{"message":"boom"}`))
-	if _, err := GetLatestRelease("owner/repo"); err == nil {
+	if _, err := GetLatestRelease(context.Background(), "owner/repo"); err == nil {
 		t.Fatal("expected error when first call fails and no PAT is set")
 	}
 }
@@ -55,7 +56,7 @@ func TestGetLatestRelease_PATRetrySucceeds(t *testing.T) {
 			}
 			return httpmock.NewStringResponse(200, `{"tag_name":"v3.2.1"}`), nil
 		})
-	v, err := GetLatestRelease("owner/repo")
+	v, err := GetLatestRelease(context.Background(), "owner/repo")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -70,7 +71,7 @@ func TestGetLatestRelease_PATRetryFails(t *testing.T) {
 	t.Setenv("PAT", "fake-token")
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/releases/latest",
 		httpmock.NewStringResponder(500, `{"message":"boom"}`))
-	if _, err := GetLatestRelease("owner/repo"); err == nil {
+	if _, err := GetLatestRelease(context.Background(), "owner/repo"); err == nil {
 		t.Fatal("expected error when both attempts fail")
 	}
 }
@@ -78,7 +79,7 @@ func TestGetLatestRelease_PATRetryFails(t *testing.T) {
 // GetMajorTagFromSHA
 
 func TestGetMajorTagFromSHA_InvalidRepo(t *testing.T) {
-	if _, err := GetMajorTagFromSHA("no-slash", "abc"); err == nil {
+	if _, err := GetMajorTagFromSHA(context.Background(), "no-slash", "abc"); err == nil {
 		t.Fatal("expected error for invalid owner/repo")
 	}
 }
@@ -86,9 +87,9 @@ func TestGetMajorTagFromSHA_InvalidRepo(t *testing.T) {
 func TestGetMajorTagFromSHA_ListError(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags/v",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags",
 		httpmock.NewStringResponder(500, `{"message":"boom"}`))
-	if _, err := GetMajorTagFromSHA("owner/repo", "anything"); err == nil {
+	if _, err := GetMajorTagFromSHA(context.Background(), "owner/repo", "anything"); err == nil {
 		t.Fatal("expected error from ListMatchingRefs failure")
 	}
 }
@@ -96,12 +97,12 @@ func TestGetMajorTagFromSHA_ListError(t *testing.T) {
 func TestGetMajorTagFromSHA_CommitMatch(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags/v",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[
 			{"ref":"refs/tags/v2.0.0","object":{"sha":"aaaa","type":"commit"}},
 			{"ref":"refs/tags/v5.1.0","object":{"sha":"bbbb","type":"commit"}}
 		]`))
-	v, err := GetMajorTagFromSHA("owner/repo", "bbbb")
+	v, err := GetMajorTagFromSHA(context.Background(), "owner/repo", "bbbb")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -113,13 +114,13 @@ func TestGetMajorTagFromSHA_CommitMatch(t *testing.T) {
 func TestGetMajorTagFromSHA_AnnotatedTagMatch(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags/v",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[
 			{"ref":"refs/tags/v3.0.0","object":{"sha":"tagsha","type":"tag"}}
 		]`))
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/commits/refs/tags/v3.0.0",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/commits/v3.0.0",
 		httpmock.NewStringResponder(200, `commitsha`))
-	v, err := GetMajorTagFromSHA("owner/repo", "commitsha")
+	v, err := GetMajorTagFromSHA(context.Background(), "owner/repo", "commitsha")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -131,14 +132,14 @@ func TestGetMajorTagFromSHA_AnnotatedTagMatch(t *testing.T) {
 func TestGetMajorTagFromSHA_AnnotatedDerefError(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags/v",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[
 			{"ref":"refs/tags/v3.0.0","object":{"sha":"tagsha","type":"tag"}},
 			{"ref":"refs/tags/v4.0.0","object":{"sha":"match","type":"commit"}}
 		]`))
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/commits/refs/tags/v3.0.0",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/commits/v3.0.0",
 		httpmock.NewStringResponder(500, `{"message":"boom"}`))
-	v, err := GetMajorTagFromSHA("owner/repo", "match")
+	v, err := GetMajorTagFromSHA(context.Background(), "owner/repo", "match")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -150,11 +151,11 @@ func TestGetMajorTagFromSHA_AnnotatedDerefError(t *testing.T) {
 func TestGetMajorTagFromSHA_NoMatch(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags/v",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[
 			{"ref":"refs/tags/v2.0.0","object":{"sha":"aaaa","type":"commit"}}
 		]`))
-	v, err := GetMajorTagFromSHA("owner/repo", "nomatch")
+	v, err := GetMajorTagFromSHA(context.Background(), "owner/repo", "nomatch")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -167,11 +168,11 @@ func TestGetMajorTagFromSHA_WithPAT(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
 	t.Setenv("PAT", "fake-token")
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags/v",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[
 			{"ref":"refs/tags/v1.0.0","object":{"sha":"match","type":"commit"}}
 		]`))
-	v, err := GetMajorTagFromSHA("owner/repo", "match")
+	v, err := GetMajorTagFromSHA(context.Background(), "owner/repo", "match")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -183,7 +184,7 @@ func TestGetMajorTagFromSHA_WithPAT(t *testing.T) {
 // GetMajorTagIfExists
 
 func TestGetMajorTagIfExists_InvalidRepo(t *testing.T) {
-	if _, _, err := GetMajorTagIfExists("no-slash", "v1"); err == nil {
+	if _, _, err := GetMajorTagIfExists(context.Background(), "no-slash", "v1"); err == nil {
 		t.Fatal("expected error for invalid owner/repo")
 	}
 }
@@ -192,9 +193,9 @@ func TestGetMajorTagIfExists_NonErrorNoPAT(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
 	t.Setenv("PAT", "")
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/ref/tags/v5",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags",
 		httpmock.NewStringResponder(500, `{"message":"boom"}`))
-	tag, exists, err := GetMajorTagIfExists("owner/repo", "v5")
+	tag, exists, err := GetMajorTagIfExists(context.Background(), "owner/repo", "v5")
 	if err != nil || exists || tag != "" {
 		t.Errorf("got tag=%q exists=%v err=%v, want empty/false/nil", tag, exists, err)
 	}
@@ -205,16 +206,16 @@ func TestGetMajorTagIfExists_PATRetrySucceeds(t *testing.T) {
 	defer httpmock.DeactivateAndReset()
 	t.Setenv("PAT", "fake-token")
 	calls := 0
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/ref/tags/v5",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags",
 		func(req *http.Request) (*http.Response, error) {
 			calls++
 			if calls == 1 {
 				return httpmock.NewStringResponse(500, `{"message":"boom"}`), nil
 			}
 			return httpmock.NewStringResponse(200,
-				`{"ref":"refs/tags/v5","object":{"sha":"x","type":"commit"}}`), nil
+				`[{"ref":"refs/tags/v5","object":{"sha":"x","type":"commit"}}]`), nil
 		})
-	tag, exists, err := GetMajorTagIfExists("owner/repo", "v5")
+	tag, exists, err := GetMajorTagIfExists(context.Background(), "owner/repo", "v5")
 	if err != nil || !exists || tag != "v5" {
 		t.Errorf("got tag=%q exists=%v err=%v, want v5/true/nil", tag, exists, err)
 	}
@@ -225,7 +226,7 @@ func TestGetMajorTagIfExists_PATRetry404(t *testing.T) {
 	defer httpmock.DeactivateAndReset()
 	t.Setenv("PAT", "fake-token")
 	calls := 0
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/ref/tags/v5",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags",
 		func(req *http.Request) (*http.Response, error) {
 			calls++
 			if calls == 1 {
@@ -233,7 +234,7 @@ func TestGetMajorTagIfExists_PATRetry404(t *testing.T) {
 			}
 			return httpmock.NewStringResponse(404, `{"message":"Not Found"}`), nil
 		})
-	tag, exists, err := GetMajorTagIfExists("owner/repo", "v5")
+	tag, exists, err := GetMajorTagIfExists(context.Background(), "owner/repo", "v5")
 	if err != nil || exists || tag != "" {
 		t.Errorf("got tag=%q exists=%v err=%v, want empty/false/nil", tag, exists, err)
 	}
@@ -243,9 +244,9 @@ func TestGetMajorTagIfExists_PATRetryFails(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
 	t.Setenv("PAT", "fake-token")
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/ref/tags/v5",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/owner/repo/git/matching-refs/tags",
 		httpmock.NewStringResponder(500, `{"message":"boom"}`))
-	_, exists, err := GetMajorTagIfExists("owner/repo", "v5")
+	_, exists, err := GetMajorTagIfExists(context.Background(), "owner/repo", "v5")
 	if err == nil {
 		t.Fatal("expected wrapped error when both attempts fail with non-404")
 	}
@@ -257,7 +258,7 @@ func TestGetMajorTagIfExists_PATRetryFails(t *testing.T) {
 // resolveVersion
 
 func TestResolveVersion_NoRef(t *testing.T) {
-	if _, err := resolveVersion("actions/checkout", "actions/checkout", "new/action", true); err == nil {
+	if _, err := resolveVersion(context.Background(), "actions/checkout", "actions/checkout", "new/action", true); err == nil {
 		t.Fatal("expected error when originalUses has no @ref")
 	}
 }
@@ -265,15 +266,15 @@ func TestResolveVersion_NoRef(t *testing.T) {
 func TestResolveVersion_SHAResolved(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/orig/repo/git/matching-refs/tags/v",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/orig/repo/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[
 			{"ref":"refs/tags/v5.2.0","object":{"sha":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","type":"commit"}}
 		]`))
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/new/repo/git/ref/tags/v5",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/new/repo/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
-			`{"ref":"refs/tags/v5","object":{"sha":"x","type":"commit"}}`))
+			`[{"ref":"refs/tags/v5","object":{"sha":"x","type":"commit"}}]`))
 	uses := "orig/repo@aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
-	v, err := resolveVersion(uses, "orig/repo", "new/repo", true)
+	v, err := resolveVersion(context.Background(), uses, "orig/repo", "new/repo", true)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -285,10 +286,10 @@ func TestResolveVersion_SHAResolved(t *testing.T) {
 func TestResolveVersion_SHALookupFails(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/orig/repo/git/matching-refs/tags/v",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/orig/repo/git/matching-refs/tags",
 		httpmock.NewStringResponder(500, `{"message":"boom"}`))
 	uses := "orig/repo@aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
-	if _, err := resolveVersion(uses, "orig/repo", "new/repo", true); err == nil {
+	if _, err := resolveVersion(context.Background(), uses, "orig/repo", "new/repo", true); err == nil {
 		t.Fatal("expected error when SHA lookup fails")
 	}
 }
@@ -296,10 +297,10 @@ func TestResolveVersion_SHALookupFails(t *testing.T) {
 func TestResolveVersion_SHANoMatch(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/orig/repo/git/matching-refs/tags/v",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/orig/repo/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[]`))
 	uses := "orig/repo@aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
-	if _, err := resolveVersion(uses, "orig/repo", "new/repo", true); err == nil {
+	if _, err := resolveVersion(context.Background(), uses, "orig/repo", "new/repo", true); err == nil {
 		t.Fatal("expected error when SHA has no matching tag")
 	}
 }
@@ -328,7 +329,7 @@ func TestLoadMaintainedActions_InvalidJSON(t *testing.T) {
 func TestReplaceActions_InvalidYAML(t *testing.T) {
 	// A mapping key cannot also be a sequence at the same indent — yaml.Unmarshal errors.
 	bad := "foo: bar\n- item"
-	if _, _, err := ReplaceActions(bad, map[string]string{}, false); err == nil {
+	if _, _, err := ReplaceActions(context.Background(), bad, map[string]string{}, false); err == nil {
 		t.Fatal("expected error for invalid YAML")
 	}
 }
@@ -345,7 +346,7 @@ jobs:
     uses: ./.github/workflows/other.yml
 `
 	actionMap := map[string]string{"amannn/action-semantic-pull-request": "step-security/action-semantic-pull-request"}
-	got, updated, err := ReplaceActions(input, actionMap, false)
+	got, updated, err := ReplaceActions(context.Background(), input, actionMap, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -364,7 +365,7 @@ func TestReplaceActions_CompositeResolverFailureSkipped(t *testing.T) {
 	defer httpmock.DeactivateAndReset()
 	// Fork has no matching major version.
 	httpmock.RegisterResponder("GET",
-		"https://api.github.com/repos/step-security/action-semantic-pull-request/git/ref/tags/v3",
+		"https://api.github.com/repos/step-security/action-semantic-pull-request/git/matching-refs/tags",
 		httpmock.NewStringResponder(404, `{"message":"Not Found"}`))
 
 	input := `name: composite
@@ -376,7 +377,7 @@ runs:
 	actionMap := map[string]string{
 		"amannn/action-semantic-pull-request": "step-security/action-semantic-pull-request",
 	}
-	got, updated, err := ReplaceActions(input, actionMap, true)
+	got, updated, err := ReplaceActions(context.Background(), input, actionMap, true)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1,14 +1,17 @@
 package maintainedactions
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"os"
 	"strings"
 
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
 	"github.com/step-security/secure-repo/remediation/workflow/metadata"
-	"github.com/step-security/secure-repo/remediation/workflow/permissions"
 	"github.com/step-security/secure-repo/remediation/workflow/pin"
 	"gopkg.in/yaml.v3"
 )
@@ -32,6 +35,16 @@ type replacement struct {
 	latestVersion  string
 }
 
+// GetMaintainedActionsFile returns the path of the maintained actions JSON file,
+// which can be overridden using the MAINTAINED_ACTIONS environment variable
+func GetMaintainedActionsFile() string {
+	jsonPath := os.Getenv("MAINTAINED_ACTIONS")
+	if jsonPath == "" {
+		jsonPath = "maintainedactions/maintainedActions.json"
+	}
+	return jsonPath
+}
+
 // LoadMaintainedActions loads the maintained actions from the JSON file
 func LoadMaintainedActions(jsonPath string) (map[string]string, error) {
 	// Read the JSON file
@@ -60,9 +73,9 @@ func LoadMaintainedActions(jsonPath string) (map[string]string, error) {
 // resolveVersion determines the version to use for the replacement action.
 // When replaceByMajorTag is true, it matches the major version from the original action.
 // When false (default), it uses the latest release of the new action.
-func resolveVersion(originalUses, actionName, newAction string, replaceByMajorTag bool) (string, error) {
+func resolveVersion(ctx context.Context, originalUses, actionName, newAction string, replaceByMajorTag bool) (string, error) {
 	if !replaceByMajorTag {
-		return GetLatestRelease(newAction)
+		return GetLatestRelease(ctx, newAction)
 	}
 
 	parts := strings.SplitN(originalUses, "@", 2)
@@ -73,7 +86,7 @@ func resolveVersion(originalUses, actionName, newAction string, replaceByMajorTa
 	var version string
 	var err error
 	if len(ref) == 40 && pin.IsAllHex(ref) {
-		version, err = GetMajorTagFromSHA(actionName, ref)
+		version, err = GetMajorTagFromSHA(ctx, actionName, ref)
 		if err != nil {
 			return "", fmt.Errorf("unable to resolve SHA %s to major tag: %w", ref, err)
 		}
@@ -84,7 +97,7 @@ func resolveVersion(originalUses, actionName, newAction string, replaceByMajorTa
 		version = ref
 	}
 	majorVersion := getMajorVersion(version)
-	tag, exists, err := GetMajorTagIfExists(newAction, majorVersion)
+	tag, exists, err := GetMajorTagIfExists(ctx, newAction, majorVersion)
 	if err != nil || !exists {
 		return "", fmt.Errorf("major tag %s not found on %s", majorVersion, newAction)
 	}
@@ -94,31 +107,38 @@ func resolveVersion(originalUses, actionName, newAction string, replaceByMajorTa
 // ReplaceActions replaces original actions with Step Security actions in a workflow.
 // When replaceByMajorTag is true, the replacement action uses the same major version as the original.
 // When false (default), it uses the latest release of the replacement action.
-func ReplaceActions(inputYaml string, customerMaintainedActions map[string]string, replaceByMajorTag bool) (string, bool, error) {
-	workflow := metadata.Workflow{}
-	updated := false
+func ReplaceActions(ctx context.Context, inputYaml string, customerMaintainedActions map[string]string, replaceByMajorTag bool) (string, bool, error) {
+	out, replacedLines, err := ReplaceActionLines(ctx, inputYaml, customerMaintainedActions, replaceByMajorTag)
+	return out, len(replacedLines) > 0, err
+}
 
+// ReplaceActionLines replaces the actions like ReplaceActions, and returns the lines of the uses that were replaced, so
+// the findings of the actions can be marked as fixed from the edits that were applied. The actions whose replacement
+// version cannot be resolved are left as they are.
+func ReplaceActionLines(ctx context.Context, inputYaml string, customerMaintainedActions map[string]string, replaceByMajorTag bool) (string, []int, error) {
 	actionMap := customerMaintainedActions
 
-	err := yaml.Unmarshal([]byte(inputYaml), &workflow)
+	doc := document.Parse(inputYaml)
+	workflow, err := doc.Workflow()
 	if err != nil {
-		return "", updated, fmt.Errorf("unable to parse yaml: %v", err)
+		return "", nil, fmt.Errorf("unable to parse yaml: %v", err)
 	}
 
 	// Step 1: Check if anything needs to be replaced
 
 	var replacements []replacement
 
-	for jobName, job := range workflow.Jobs {
+	for _, jobName := range workflow.Jobs.Names() {
+		job := workflow.Jobs[jobName]
 		if metadata.IsCallingReusableWorkflow(job) {
 			continue
 		}
 		for stepIdx, step := range job.Steps {
 			actionName := strings.Split(step.Uses, "@")[0]
 			if newAction, ok := actionMap[actionName]; ok {
-				version, err := resolveVersion(step.Uses, actionName, newAction, replaceByMajorTag)
+				version, err := resolveVersion(ctx, step.Uses, actionName, newAction, replaceByMajorTag)
 				if err != nil {
-					log.Printf("skipping replacement of %s: %v", step.Uses, err)
+					logging.Logger().Warn("skipping replacement", "module", "maintainedactions", "action", step.Uses, "error", err)
 					continue
 				}
 				replacements = append(replacements, replacement{
@@ -138,9 +158,9 @@ func ReplaceActions(inputYaml string, customerMaintainedActions map[string]strin
 			if len(step.Uses) > 0 {
 				actionName := strings.Split(step.Uses, "@")[0]
 				if newAction, ok := actionMap[actionName]; ok {
-					version, err := resolveVersion(step.Uses, actionName, newAction, replaceByMajorTag)
+					version, err := resolveVersion(ctx, step.Uses, actionName, newAction, replaceByMajorTag)
 					if err != nil {
-						log.Printf("skipping replacement of %s: %v", step.Uses, err)
+						logging.Logger().Warn("skipping replacement", "module", "maintainedactions", "action", step.Uses, "error", err)
 						continue
 					}
 					replacements = append(replacements, replacement{
@@ -157,59 +177,55 @@ func ReplaceActions(inputYaml string, customerMaintainedActions map[string]strin
 
 	if len(replacements) == 0 {
 		// No changes needed
-		return inputYaml, false, nil
+		return inputYaml, nil, nil
 	}
 
-	// Step 2: Now modify the YAML lines manually
-	t := yaml.Node{}
-	err = yaml.Unmarshal([]byte(inputYaml), &t)
+	// Step 2: Now modify the YAML lines manually, at the steps of the index of the workflow
+	index, err := doc.Index()
 	if err != nil {
-		return "", updated, fmt.Errorf("unable to parse yaml: %v", err)
+		return "", nil, fmt.Errorf("unable to parse yaml: %v", err)
 	}
 
-	inputLines := strings.Split(inputYaml, "\n")
-	inputLines, updated = replaceAction(&t, inputLines, replacements, updated)
+	buffer := textedit.NewBuffer(inputYaml)
+	replacedLines := replaceAction(index, buffer, replacements)
 
-	output := strings.Join(inputLines, "\n")
-
-	return output, updated, nil
+	return buffer.String(), replacedLines, nil
 }
 
-func replaceAction(t *yaml.Node, inputLines []string, replacements []replacement, updated bool) ([]string, bool) {
+// replaceAction replaces the uses of the steps of the replacements, and returns their lines
+func replaceAction(index *document.Index, buffer *textedit.Buffer, replacements []replacement) []int {
+	var replacedLines []int
 	for _, r := range replacements {
 		var stepsNode *yaml.Node
 
 		if r.jobName == "composite" {
 			// Handle composite actions
-			runsNode := permissions.IterateNode(t, "runs", "!!map", 0)
-			stepsNode = permissions.IterateNode(runsNode, "steps", "!!seq", 0)
-		} else {
+			stepsNode = index.Steps
+		} else if job := index.Job(r.jobName); job != nil {
 			// Handle regular workflow jobs
-			jobsNode := permissions.IterateNode(t, "jobs", "!!map", 0)
-			jobNode := permissions.IterateNode(jobsNode, r.jobName, "!!map", 0)
-			stepsNode = permissions.IterateNode(jobNode, "steps", "!!seq", 0)
+			stepsNode = job.Steps
 		}
 
-		if stepsNode == nil {
+		if stepsNode == nil || r.stepIdx >= len(stepsNode.Content) {
 			continue
 		}
 
 		// Now get the specific step
 		stepNode := stepsNode.Content[r.stepIdx]
-		usesNode := permissions.IterateNode(stepNode, "uses", "!!str", 0)
-		if usesNode == nil {
+		usesNode := document.MappingValue(stepNode, "uses")
+		if usesNode == nil || usesNode.Tag != "!!str" {
 			continue
 		}
 
-		lineNum := usesNode.Line - 1 // 0-based indexing
-		columnNum := usesNode.Column - 1
-
-		// Replace the line
-		oldLine := inputLines[lineNum]
-		prefix := oldLine[:columnNum]
-		inputLines[lineNum] = prefix + r.newAction + "@" + r.latestVersion
-		updated = true
+		// Replace the line from the column of the action, after its anchor
+		oldLine, lineStart := buffer.Line(usesNode.Line)
+		columnNum := document.ContentColumn(oldLine, usesNode)
+		if columnNum >= len(oldLine) {
+			continue
+		}
+		buffer.Replace(lineStart+columnNum, lineStart+len(oldLine), r.newAction+"@"+r.latestVersion)
+		replacedLines = append(replacedLines, usesNode.Line)
 
 	}
-	return inputLines, updated
+	return replacedLines
 }
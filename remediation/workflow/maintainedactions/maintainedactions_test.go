@@ -1,6 +1,7 @@
 package maintainedactions
 
 import (
+	"context"
 	"io/ioutil"
 	"path"
 	"testing"
@@ -16,21 +17,18 @@ func TestReplaceActions(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
 
-	// Mock GitHub API responses for checking major version tags on forks
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/action-semantic-pull-request/git/ref/tags/v5",
-		httpmock.NewStringResponder(200, `{"ref":"refs/tags/v5","object":{"sha":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","type":"commit"}}`))
+	// Mock GitHub API responses for listing the tags of forks, which have no v3 tag of action-semantic-pull-request
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/action-semantic-pull-request/git/matching-refs/tags",
+		httpmock.NewStringResponder(200, `[{"ref":"refs/tags/v5","object":{"sha":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","type":"commit"}}]`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/action-semantic-pull-request/git/ref/tags/v3",
-		httpmock.NewStringResponder(404, `{"message":"Not Found"}`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/skip-duplicate-actions/git/matching-refs/tags",
+		httpmock.NewStringResponder(200, `[{"ref":"refs/tags/v5","object":{"sha":"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb","type":"commit"}}]`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/skip-duplicate-actions/git/ref/tags/v5",
-		httpmock.NewStringResponder(200, `{"ref":"refs/tags/v5","object":{"sha":"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb","type":"commit"}}`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/git-restore-mtime-action/git/matching-refs/tags",
+		httpmock.NewStringResponder(200, `[{"ref":"refs/tags/v1","object":{"sha":"cccccccccccccccccccccccccccccccccccccccc","type":"commit"}}]`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/git-restore-mtime-action/git/ref/tags/v1",
-		httpmock.NewStringResponder(200, `{"ref":"refs/tags/v1","object":{"sha":"cccccccccccccccccccccccccccccccccccccccc","type":"commit"}}`))
-
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/actions-cache/git/ref/tags/v1",
-		httpmock.NewStringResponder(200, `{"ref":"refs/tags/v1","object":{"sha":"dddddddddddddddddddddddddddddddddddddddd","type":"commit"}}`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/actions-cache/git/matching-refs/tags",
+		httpmock.NewStringResponder(200, `[{"ref":"refs/tags/v1","object":{"sha":"dddddddddddddddddddddddddddddddddddddddd","type":"commit"}}]`))
 
 	tests := []struct {
 		name        string
@@ -88,7 +86,7 @@ func TestReplaceActions(t *testing.T) {
 				t.Errorf("ReplaceActions() unable to json file %v", err)
 				return
 			}
-			got, updated, replaceErr := ReplaceActions(string(input), actionMap, true)
+			got, updated, replaceErr := ReplaceActions(context.Background(), string(input), actionMap, true)
 
 			// Check error
 			if (replaceErr != nil) != tt.wantErr {
@@ -112,6 +110,12 @@ func TestReplaceActions(t *testing.T) {
 				// WriteYAML(tt.outputFile+"second", got)
 				t.Errorf("ReplaceActions() = %v, want %v", got, string(expectedOutput))
 			}
+
+			// the actions of the output are replaced, so replacing them again does not change it
+			again, againUpdated, err := ReplaceActions(context.Background(), got, actionMap, true)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("ReplaceActions() of the output = %v, %v, want it unchanged\n%s", againUpdated, err, again)
+			}
 		})
 	}
 }
@@ -193,7 +197,7 @@ func TestReplaceActionsLatestRelease(t *testing.T) {
 				t.Errorf("ReplaceActions() unable to json file %v", err)
 				return
 			}
-			got, updated, replaceErr := ReplaceActions(string(input), actionMap, false)
+			got, updated, replaceErr := ReplaceActions(context.Background(), string(input), actionMap, false)
 
 			// Check error
 			if (replaceErr != nil) != tt.wantErr {
@@ -216,6 +220,12 @@ func TestReplaceActionsLatestRelease(t *testing.T) {
 			if got != string(expectedOutput) {
 				t.Errorf("ReplaceActions() = %v, want %v", got, string(expectedOutput))
 			}
+
+			// the actions of the output are replaced, so replacing them again does not change it
+			again, againUpdated, err := ReplaceActions(context.Background(), got, actionMap, false)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("ReplaceActions() of the output = %v, %v, want it unchanged\n%s", againUpdated, err, again)
+			}
 		})
 	}
-}
\ No newline at end of file
+}
@@ -2,10 +2,13 @@ package metadata
 
 import (
 	"errors"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path"
+	"sort"
 	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 )
@@ -49,6 +52,17 @@ type Container struct {
 }
 
 type Jobs map[string]Job
+
+// Names returns the names of the jobs, sorted, so the jobs are changed and reported in the same order in every run
+func (jobs Jobs) Names() []string {
+	names := make([]string, 0, len(jobs))
+	for name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 type With map[string]string
 type Env map[string]string
 
@@ -168,15 +182,45 @@ func (p *Permissions) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return ErrInvalidValue
 }
 
-func GetActionKnowledgeBase(action string) (*ActionMetadata, error) {
+var (
+	knowledgeBaseMutex sync.RWMutex
+	knowledgeBase      *Index
+	// loadKnowledgeBase indexes the knowledge base on first use, if it is set by IndexKnowledgeBaseLazily
+	loadKnowledgeBase func() (*Index, error)
+)
+
+// SetKnowledgeBase reads the knowledge base of actions from fsys, which has a folder for each action, instead of the
+// KBFolder folder, e.g. in the WebAssembly build, which has no file system. A nil fsys reads from KBFolder again.
+func SetKnowledgeBase(fsys fs.FS) error {
+	if fsys == nil {
+		SetIndex(nil)
+		return nil
+	}
+	index, err := NewIndex(fsys)
+	if err != nil {
+		return err
+	}
+	SetIndex(index)
+	return nil
+}
+
+func readKnowledgeBase(action string) ([]byte, error) {
+	if index := CurrentIndex(); index != nil {
+		return index.read(action)
+	}
+
 	kbFolder := os.Getenv("KBFolder")
-	// converting actionKey to lowercase to fix ISSUE#286
-	action = strings.ToLower(action)
 	if kbFolder == "" {
 		kbFolder = "../../knowledge-base/actions"
 	}
+	return ioutil.ReadFile(path.Join(kbFolder, action, kbFile))
+}
+
+func GetActionKnowledgeBase(action string) (*ActionMetadata, error) {
+	// converting actionKey to lowercase to fix ISSUE#286
+	action = strings.ToLower(action)
 
-	input, err := ioutil.ReadFile(path.Join(kbFolder, action, "action-security.yml"))
+	input, err := readKnowledgeBase(action)
 
 	if err != nil {
 		return nil, err
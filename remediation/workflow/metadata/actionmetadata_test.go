@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/google/go-github/v40/github"
 	"golang.org/x/oauth2"
@@ -128,7 +129,7 @@ func TestKnowledgeBase(t *testing.T) {
 				}
 			}
 
-			validScopes := []string{"actions", "checks", "contents", "deployments", "id-token", "issues", "packages",
+			validScopes := []string{"actions", "attestations", "checks", "contents", "deployments", "id-token", "issues", "packages",
 				"pull-requests", "repository-projects", "security-events", "statuses"}
 			mapScopes := make(map[string]bool)
 
@@ -179,6 +180,24 @@ func TestKnowledgeBase(t *testing.T) {
 	}
 }
 
+func TestSetKnowledgeBase(t *testing.T) {
+	SetKnowledgeBase(fstest.MapFS{
+		"octo-org/deploy/action-security.yml": {Data: []byte("name: Deploy\ngithub-token:\n  action-input:\n    input: token\n    is-default: true\n  permissions:\n    contents: read\n")},
+	})
+	defer SetKnowledgeBase(nil)
+
+	actionMetadata, err := GetActionKnowledgeBase("Octo-Org/Deploy")
+	if err != nil {
+		t.Fatalf("GetActionKnowledgeBase() unexpected error = %v", err)
+	}
+	if actionMetadata.Name != "Deploy" || actionMetadata.GitHubToken.Permissions.Scopes["contents"].Permission != "read" {
+		t.Errorf("GetActionKnowledgeBase() = %+v, want the metadata of the knowledge base", actionMetadata)
+	}
+	if _, err := GetActionKnowledgeBase("actions/checkout"); err == nil {
+		t.Errorf("expected an error for an action that is not in the knowledge base")
+	}
+}
+
 func doesActionRepoExist(filePath string) bool {
 	splitOnSlash := strings.Split(filePath, "/")
 
@@ -0,0 +1,154 @@
+package metadata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	knowledgebase "github.com/step-security/secure-repo/knowledge-base"
+	"github.com/step-security/secure-repo/remediation/logging"
+)
+
+// kbFile is the name of the file of each action in the knowledge base
+const kbFile = "action-security.yml"
+
+// Index is the knowledge base of actions read into memory, so its files are not read for each request
+type Index struct {
+	// files are the contents of the files by the lowercase path of their action, e.g. actions/checkout
+	files   map[string][]byte
+	version string
+}
+
+// NewIndex reads the knowledge base of fsys, which has a folder for each action
+func NewIndex(fsys fs.FS) (*Index, error) {
+	files := map[string][]byte{}
+	err := fs.WalkDir(fsys, ".", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || entry.Name() != kbFile {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+		files[filePath] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the knowledge base: %v", err)
+	}
+	return NewIndexFromFiles(files), nil
+}
+
+// NewIndexFromFiles returns the index of the contents of the files by their path, e.g. actions/checkout/action-security.yml.
+// The files of other names are left out.
+func NewIndexFromFiles(files map[string][]byte) *Index {
+	index := &Index{files: map[string][]byte{}}
+	var paths []string
+	for filePath, content := range files {
+		if path.Base(filePath) != kbFile || path.Dir(filePath) == "." {
+			continue
+		}
+		paths = append(paths, filePath)
+		index.files[strings.ToLower(path.Dir(filePath))] = content
+	}
+	// the paths are hashed in order, so the version only changes with the paths and contents of the files
+	sort.Strings(paths)
+	hash := sha256.New()
+	for _, filePath := range paths {
+		fmt.Fprintf(hash, "%s\x00%s\x00", filePath, files[filePath])
+	}
+	index.version = hex.EncodeToString(hash.Sum(nil))
+	return index
+}
+
+// Version returns the hash of the files of the index
+func (index *Index) Version() string {
+	return index.version
+}
+
+// Actions returns the lowercase paths of the actions, sorted
+func (index *Index) Actions() []string {
+	actions := make([]string, 0, len(index.files))
+	for action := range index.files {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+func (index *Index) read(action string) ([]byte, error) {
+	content, found := index.files[action]
+	if !found {
+		return nil, &fs.PathError{Op: "open", Path: path.Join(action, kbFile), Err: fs.ErrNotExist}
+	}
+	return content, nil
+}
+
+// SetIndex reads the knowledge base from the index instead of the KBFolder folder, e.g. when it is refreshed. A nil
+// index reads from KBFolder again.
+func SetIndex(index *Index) {
+	knowledgeBaseMutex.Lock()
+	defer knowledgeBaseMutex.Unlock()
+	knowledgeBase = index
+	loadKnowledgeBase = nil
+}
+
+// CurrentIndex returns the index the knowledge base is read from, or nil if it is read from the KBFolder folder. The
+// knowledge base of IndexKnowledgeBaseLazily is indexed by the first call.
+func CurrentIndex() *Index {
+	knowledgeBaseMutex.RLock()
+	index, load := knowledgeBase, loadKnowledgeBase
+	knowledgeBaseMutex.RUnlock()
+	if index != nil || load == nil {
+		return index
+	}
+	index, err := load()
+	if err != nil {
+		logging.Logger().Error("unable to index the knowledge base", "error", err)
+		return nil
+	}
+	knowledgeBaseMutex.Lock()
+	defer knowledgeBaseMutex.Unlock()
+	// the index set while the knowledge base was indexed, e.g. by a refresh, is kept
+	if knowledgeBase == nil && loadKnowledgeBase != nil {
+		knowledgeBase = index
+		loadKnowledgeBase = nil
+	}
+	return knowledgeBase
+}
+
+// newKnowledgeBaseIndex reads the knowledge base from the KBFolder folder if it is set, or from the knowledge base
+// embedded in the binary
+func newKnowledgeBaseIndex() (*Index, error) {
+	fsys := knowledgebase.Actions()
+	if kbFolder := os.Getenv("KBFolder"); kbFolder != "" {
+		fsys = os.DirFS(kbFolder)
+	}
+	return NewIndex(fsys)
+}
+
+// IndexKnowledgeBase reads the knowledge base into memory at startup, from the KBFolder folder if it is set, or from
+// the knowledge base embedded in the binary, so the binary can be deployed on its own
+func IndexKnowledgeBase() error {
+	index, err := newKnowledgeBaseIndex()
+	if err != nil {
+		return err
+	}
+	SetIndex(index)
+	return nil
+}
+
+// IndexKnowledgeBaseLazily reads the knowledge base like IndexKnowledgeBase when it is first used instead of at
+// startup, so the requests that do not read it, and the start of the Lambda function, do not wait for it to be indexed
+func IndexKnowledgeBaseLazily() {
+	knowledgeBaseMutex.Lock()
+	defer knowledgeBaseMutex.Unlock()
+	knowledgeBase = nil
+	loadKnowledgeBase = sync.OnceValues(newKnowledgeBaseIndex)
+}
@@ -0,0 +1,74 @@
+package metadata
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIndex(t *testing.T) {
+	fsys := fstest.MapFS{
+		"actions/checkout/action-security.yml":          {Data: []byte("name: Checkout\n")},
+		"github/codeql-action/init/action-security.yml": {Data: []byte("name: CodeQL Init\n")},
+		"actions/checkout/README.md":                    {Data: []byte("not indexed")},
+	}
+	index, err := NewIndex(fsys)
+	if err != nil {
+		t.Fatalf("NewIndex() unexpected error = %v", err)
+	}
+	if actions := strings.Join(index.Actions(), ","); actions != "actions/checkout,github/codeql-action/init" {
+		t.Errorf("Actions() = %s", actions)
+	}
+
+	// the version changes with the contents of the files
+	fsys["actions/checkout/action-security.yml"] = &fstest.MapFile{Data: []byte("name: Checkout v2\n")}
+	changed, _ := NewIndex(fsys)
+	if changed.Version() == index.Version() {
+		t.Errorf("expected the version to change with the contents of the files")
+	}
+	if same, _ := NewIndex(fsys); same.Version() != changed.Version() {
+		t.Errorf("expected the same files to have the same version")
+	}
+
+	SetIndex(changed)
+	defer SetIndex(nil)
+	if actionMetadata, err := GetActionKnowledgeBase("GitHub/CodeQL-Action/Init"); err != nil || actionMetadata.Name != "CodeQL Init" {
+		t.Errorf("GetActionKnowledgeBase() = %+v, %v", actionMetadata, err)
+	}
+}
+
+func TestIndexKnowledgeBase(t *testing.T) {
+	t.Setenv("KBFolder", "")
+	if err := IndexKnowledgeBase(); err != nil {
+		t.Fatalf("IndexKnowledgeBase() unexpected error = %v", err)
+	}
+	defer SetIndex(nil)
+	// without KBFolder, the knowledge base embedded in the binary is indexed
+	if actionMetadata, err := GetActionKnowledgeBase("actions/checkout"); err != nil || actionMetadata.GitHubToken.Permissions.Scopes["contents"].Permission != "read" {
+		t.Errorf("GetActionKnowledgeBase() = %+v, %v, want the embedded knowledge base", actionMetadata, err)
+	}
+}
+
+func TestIndexKnowledgeBaseLazily(t *testing.T) {
+	t.Setenv("KBFolder", "")
+	IndexKnowledgeBaseLazily()
+	defer SetIndex(nil)
+	knowledgeBaseMutex.RLock()
+	indexed := knowledgeBase != nil
+	knowledgeBaseMutex.RUnlock()
+	if indexed {
+		t.Fatalf("expected the knowledge base to be indexed on first use")
+	}
+	index := CurrentIndex()
+	if index == nil || index != CurrentIndex() {
+		t.Fatalf("CurrentIndex() = %v, want the same index of the embedded knowledge base", index)
+	}
+
+	// an index set before the first use replaces the knowledge base indexed lazily
+	IndexKnowledgeBaseLazily()
+	refreshed := NewIndexFromFiles(map[string][]byte{"actions/checkout/action-security.yml": []byte("name: Checkout\n")})
+	SetIndex(refreshed)
+	if CurrentIndex() != refreshed {
+		t.Errorf("CurrentIndex() is not the index that was set")
+	}
+}
@@ -0,0 +1,31 @@
+package permissions
+
+import (
+	"os"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/workflow/fuzzing"
+	"github.com/step-security/secure-repo/remediation/workflow/metadata"
+)
+
+func FuzzAddJobLevelPermissions(f *testing.F) {
+	fuzzing.AddSeeds(f, "../../../testfiles/joblevelperms/input")
+	// the knowledge base of the repository is read if it is found, or else the one embedded in the binary, e.g. by the
+	// binaries built by OSS-Fuzz
+	const kbFolder = "../../../knowledge-base/actions"
+	if _, err := os.Stat(kbFolder); err == nil {
+		os.Setenv("KBFolder", kbFolder)
+	} else if err := metadata.IndexKnowledgeBase(); err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		response, err := AddJobLevelPermissions(input, false)
+		if err != nil || response.HasErrors {
+			return
+		}
+		// only permissions are added
+		fuzzing.CheckOutput(t, input, response.FinalOutput, fuzzing.DeleteKeys("permissions"), func(line string) bool {
+			return false
+		})
+	})
+}
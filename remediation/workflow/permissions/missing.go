@@ -0,0 +1,73 @@
+package permissions
+
+import (
+	"fmt"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const RuleMissingPermissions = "missing-permissions"
+
+// FindMissingPermissions returns findings for a workflow without top level permissions, and for each job that
+// does not set permissions, since the GITHUB_TOKEN then has the default permissions of the repository
+func FindMissingPermissions(inputYaml string) ([]findings.Finding, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 || t.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	topNode := t.Content[0]
+
+	var jobsNode *yaml.Node
+	hasWorkflowPermissions := false
+	for i := 0; i+1 < len(topNode.Content); i += 2 {
+		switch topNode.Content[i].Value {
+		case "permissions":
+			hasWorkflowPermissions = true
+		case "jobs":
+			jobsNode = document.Resolve(topNode.Content[i+1])
+		}
+	}
+	if hasWorkflowPermissions || jobsNode == nil || jobsNode.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	permissionFindings := []findings.Finding{{
+		RuleID:     RuleMissingPermissions,
+		Message:    "Workflow does not set top level permissions for the GITHUB_TOKEN",
+		Line:       topNode.Content[0].Line,
+		Column:     topNode.Content[0].Column,
+		Suggestion: "Set top level permissions, e.g. contents: read",
+	}}
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobKeyNode, jobNode := jobsNode.Content[i], document.Resolve(jobsNode.Content[i+1])
+		if hasKey(jobNode, "permissions") {
+			continue
+		}
+		permissionFindings = append(permissionFindings, findings.Finding{
+			RuleID:     RuleMissingPermissions,
+			Message:    fmt.Sprintf("Job %s does not set permissions for the GITHUB_TOKEN", jobKeyNode.Value),
+			JobName:    jobKeyNode.Value,
+			Line:       jobKeyNode.Line,
+			Column:     jobKeyNode.Column,
+			Suggestion: "Set the minimum permissions needed by the job",
+		})
+	}
+	return permissionFindings, nil
+}
+
+func hasKey(node *yaml.Node, key string) bool {
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package permissions
+
+import "testing"
+
+func TestFindMissingPermissions(t *testing.T) {
+	input := `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+  release:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: write
+    steps:
+      - run: make release
+`
+	got, err := FindMissingPermissions(input)
+	if err != nil {
+		t.Fatalf("FindMissingPermissions() unexpected error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("FindMissingPermissions() returned %d findings, want 2: %v", len(got), got)
+	}
+	if got[0].JobName != "" || got[0].Line != 1 || got[1].JobName != "build" || got[1].Line != 4 {
+		t.Errorf("unexpected findings %+v", got)
+	}
+
+	// jobs use the top level permissions
+	got, err = FindMissingPermissions("on: push\npermissions: read-all\njobs:\n  build:\n    runs-on: ubuntu-latest\n")
+	if err != nil || len(got) != 0 {
+		t.Errorf("FindMissingPermissions() = %v, %v, want no findings", got, err)
+	}
+}
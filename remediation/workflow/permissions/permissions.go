@@ -7,26 +7,51 @@ import (
 
 	"github.com/PaesslerAG/gval"
 	"github.com/generikvault/gvalstrings"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/score"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
 	metadata "github.com/step-security/secure-repo/remediation/workflow/metadata"
+	"github.com/step-security/secure-repo/remediation/yamledit"
 	"gopkg.in/yaml.v3"
 )
 
 type SecureWorkflowReponse struct {
-	OriginalInput          string
-	FinalOutput            string
-	IsChanged              bool
-	HasErrors              bool
-	AlreadyHasPermissions  bool
-	AddedMaintainedActions bool
-	PinnedActions          bool
-	AddedHardenRunner      bool
-	AddedPermissions       bool
-	ReplacedRunnerLabels   bool
-	IncorrectYaml          bool
-	WorkflowFetchError     bool
-	JobErrors              []JobError
-	MissingActions         []string
-	UsingSecureRepoPAT     bool
+	OriginalInput               string
+	FinalOutput                 string
+	Diff                        string `json:",omitempty"`
+	IsChanged                   bool
+	HasErrors                   bool
+	AlreadyHasPermissions       bool
+	AddedMaintainedActions      bool
+	PinnedActions               bool
+	AddedHardenRunner           bool
+	AddedPermissions            bool
+	ReplacedRunnerLabels        bool
+	RemovedUnnecessaryTokens    bool
+	AddedForkPullRequestGuards  bool
+	FixedDispatchInputs         bool
+	AddedShellDefaults          bool
+	RewroteDeprecatedCommands   bool
+	AddedRepositoryGuards       bool
+	PinnedRunTools              bool
+	AddedBuildProvenance        bool
+	AddedCosignSigning          bool
+	AddedSBOM                   bool
+	FixedVulnerableActions      bool
+	FixedTyposquattedActions    bool
+	FixedSecretBuildArgs        bool
+	SanitizedUntrustedEnvWrites bool
+	HasPolicyViolations         bool
+	FailedStrictChecks          bool
+	IncorrectYaml               bool
+	WorkflowFetchError          bool
+	JobErrors                   []JobError
+	MissingActions              []string
+	UsingSecureRepoPAT          bool
+	Findings                    []findings.Finding
+	Report                      *report.Report
+	Score                       *score.ScoreChange `json:",omitempty"`
 }
 
 type JobError struct {
@@ -34,6 +59,17 @@ type JobError struct {
 	Errors  []string
 }
 
+// IsSkipped returns true if permissions were not added to the job for another reason than that it already had them,
+// e.g. an action that is not in the knowledge base
+func (e JobError) IsSkipped() bool {
+	for _, err := range e.Errors {
+		if err != errorAlreadyHasPermissions {
+			return true
+		}
+	}
+	return false
+}
+
 const errorSecretInRunStep = "KnownIssue-1: Jobs with run steps that use token are not supported"
 const errorSecretInRunStepEnvVariable = "KnownIssue-2: Jobs with run steps that use token in environment variable are not supported"
 const errorLocalAction = "KnownIssue-3: Action %s is a local action. Local actions are not supported"
@@ -92,85 +128,71 @@ func githubTokenInJobLevelEnv(job metadata.Job) bool {
 }
 
 func AddWorkflowLevelPermissions(inputYaml string, addProjectComment bool, addEmptyTopLevelPermissions bool) (string, error) {
-	workflow := metadata.Workflow{}
-
-	err := yaml.Unmarshal([]byte(inputYaml), &workflow)
+	doc := document.Parse(inputYaml)
+	workflow, err := doc.Workflow()
 	if err != nil {
 		return "", err
 	}
 
-	if alreadyHasWorkflowPermissions(workflow) {
+	if alreadyHasWorkflowPermissions(*workflow) {
 		// We are not modifying permissions if already defined
 		return inputYaml, fmt.Errorf("Workflow already has permissions")
 	}
 
-	t := yaml.Node{}
-
-	err = yaml.Unmarshal([]byte(inputYaml), &t)
+	t, err := doc.Node()
 	if err != nil {
 		return inputYaml, fmt.Errorf("unable to parse yaml %v", err)
 	}
 
-	line := 0
-	column := 0
 	topNode := t.Content
 	if len(topNode) == 0 {
 		return inputYaml, fmt.Errorf("Workflow file provided is Empty")
 	}
-	for _, n := range topNode[0].Content {
-		if n.Value == "jobs" && n.Tag == "!!str" {
-			line = n.Line
-			column = n.Column
+	jobsIndex := -1
+	for i := 0; i+1 < len(topNode[0].Content); i += 2 {
+		if n := topNode[0].Content[i]; n.Value == "jobs" && n.Tag == "!!str" {
+			jobsIndex = i / 2
 			break
 		}
 	}
 
-	if line == 0 {
+	if jobsIndex < 0 {
 		return inputYaml, fmt.Errorf("jobs not found in workflow")
 	}
 
-	inputLines := strings.Split(inputYaml, "\n")
-	var output []string
-	for i := 0; i < line-1; i++ {
-		output = append(output, inputLines[i])
-	}
-
-	spaces := ""
-	for i := 0; i < column-1; i++ {
-		spaces += " "
-	}
-
+	var permissions string
 	if addEmptyTopLevelPermissions {
 		if addProjectComment {
-			output = append(output, spaces+"permissions: {}  # added using https://github.com/step-security/secure-repo")
+			permissions = "permissions: {}  # added using https://github.com/step-security/secure-repo\n"
 		} else {
-			output = append(output, spaces+"permissions: {}")
+			permissions = "permissions: {}\n"
 		}
 	} else {
 		if addProjectComment {
-			output = append(output, spaces+"permissions:  # added using https://github.com/step-security/secure-repo")
+			permissions = "permissions:  # added using https://github.com/step-security/secure-repo\n"
 		} else {
-			output = append(output, spaces+"permissions:")
+			permissions = "permissions:\n"
 		}
-		output = append(output, spaces+"  contents: read")
+		permissions += "  contents: read\n"
 	}
-	output = append(output, "")
 
-	for i := line - 1; i < len(inputLines); i++ {
-		output = append(output, inputLines[i])
+	// the permissions are followed by an empty line
+	editor := yamledit.New(inputYaml)
+	if err := editor.InsertEntries(topNode[0], jobsIndex, permissions+"\n"); err != nil {
+		return inputYaml, err
 	}
-
-	return strings.Join(output, "\n"), nil
+	return editor.String(), nil
 }
 
 func AddJobLevelPermissions(inputYaml string, addEmptyTopLevelPermissions bool) (*SecureWorkflowReponse, error) {
 
-	workflow := metadata.Workflow{}
 	errors := make(map[string][]string)
 	//fixes := make(map[string]string)
 	fixWorkflowPermsReponse := &SecureWorkflowReponse{}
 
-	err := yaml.Unmarshal([]byte(inputYaml), &workflow)
+	// the permissions of the jobs are inserted with the lines of the tree of the input, which is parsed once
+	doc := document.Parse(inputYaml)
+	workflow, err := doc.Workflow()
 	if err != nil {
 		fixWorkflowPermsReponse.HasErrors = true
 		fixWorkflowPermsReponse.IncorrectYaml = true
@@ -178,7 +200,7 @@ func AddJobLevelPermissions(inputYaml string, addEmptyTopLevelPermissions bool)
 		return fixWorkflowPermsReponse, nil
 	}
 
-	if alreadyHasWorkflowPermissions(workflow) {
+	if alreadyHasWorkflowPermissions(*workflow) {
 		// We are not modifying permissions if already defined
 		fixWorkflowPermsReponse.HasErrors = true
 		fixWorkflowPermsReponse.AlreadyHasPermissions = true
@@ -186,10 +208,11 @@ func AddJobLevelPermissions(inputYaml string, addEmptyTopLevelPermissions bool)
 		return fixWorkflowPermsReponse, nil
 	}
 
-	out := inputYaml
-
-	for jobName, job := range workflow.Jobs {
+	index, _ := doc.Index()
+	out := yamledit.New(inputYaml)
 
+	for _, jobName := range workflow.Jobs.Names() {
+		job := workflow.Jobs[jobName]
 		if alreadyHasJobPermissions(job) {
 			// We are not modifying permissions if already defined
 			fixWorkflowPermsReponse.HasErrors = true
@@ -230,7 +253,7 @@ func AddJobLevelPermissions(inputYaml string, addEmptyTopLevelPermissions bool)
 					continue
 				} else {
 					// This is to add on the fixes for jobs
-					out, err = addPermissions(out, jobName, perms)
+					err = addPermissions(out, index, jobName, perms)
 
 					if err != nil {
 						// This should not happen
@@ -242,10 +265,14 @@ func AddJobLevelPermissions(inputYaml string, addEmptyTopLevelPermissions bool)
 		}
 
 	}
-	fixWorkflowPermsReponse.FinalOutput = out
+	fixWorkflowPermsReponse.FinalOutput = out.String()
 
-	// Convert to array of JobError from map
-	for job, jobErrors := range errors {
+	// Convert to array of JobError from map, in the order of the jobs
+	for _, job := range workflow.Jobs.Names() {
+		jobErrors, found := errors[job]
+		if !found {
+			continue
+		}
 		jobError := JobError{JobName: job}
 		jobError.Errors = append(jobError.Errors, jobErrors...)
 
@@ -255,7 +282,9 @@ func AddJobLevelPermissions(inputYaml string, addEmptyTopLevelPermissions bool)
 	return fixWorkflowPermsReponse, nil
 }
 
-func isGitHubToken(literal string) bool {
+// IsGitHubToken returns true if the literal is a reference to the GITHUB_TOKEN,
+// e.g. ${{ secrets.GITHUB_TOKEN }} or ${{ github.token }}
+func IsGitHubToken(literal string) bool {
 	literal = strings.ToLower(literal)
 	literal = strings.ReplaceAll(literal, "${{", "")
 	literal = strings.ReplaceAll(literal, "}}", "")
@@ -276,13 +305,13 @@ func (jobState *JobState) getPermissionsForAction(action metadata.Step) ([]strin
 	if strings.HasPrefix(action.Uses, "docker://") {
 		//Return error if it uses token in environment variable
 		for _, envValue := range action.Env {
-			if isGitHubToken(envValue) {
+			if IsGitHubToken(envValue) {
 				return nil, fmt.Errorf(errorDockerAction, action.Uses)
 			}
 		}
 		//Return error if it uses token in action input
 		for _, actionValue := range action.With {
-			if isGitHubToken(actionValue) {
+			if IsGitHubToken(actionValue) {
 				return nil, fmt.Errorf(errorDockerAction, action.Uses)
 			}
 		}
@@ -305,27 +334,32 @@ func (jobState *JobState) getPermissionsForAction(action metadata.Step) ([]strin
 
 	// If action has a default token, and the token was set explicitly, but not to the Github token, no permissions are needed
 	if actionMetadata.GitHubToken.ActionInput.IsDefault {
-		if action.With[actionMetadata.GitHubToken.ActionInput.Input] != "" && !isGitHubToken(action.With[actionMetadata.GitHubToken.ActionInput.Input]) {
+		if action.With[actionMetadata.GitHubToken.ActionInput.Input] != "" && !IsGitHubToken(action.With[actionMetadata.GitHubToken.ActionInput.Input]) {
 			return permissions, nil
 		}
 	}
 
 	// If action has does not have a default token, and the token was not set explicitly, no permissions are needed
 	if actionMetadata.GitHubToken.ActionInput.Input != "" && !actionMetadata.GitHubToken.ActionInput.IsDefault {
-		if action.With[actionMetadata.GitHubToken.ActionInput.Input] == "" || !isGitHubToken(action.With[actionMetadata.GitHubToken.ActionInput.Input]) {
+		if action.With[actionMetadata.GitHubToken.ActionInput.Input] == "" || !IsGitHubToken(action.With[actionMetadata.GitHubToken.ActionInput.Input]) {
 			return permissions, nil
 		}
 	}
 
 	// If action expects token in env variable, and the token was not set, or not to the Github token, no permissions are needed
 	if actionMetadata.GitHubToken.EnvironmentVariableName != "" {
-		if action.Env[actionMetadata.GitHubToken.EnvironmentVariableName] == "" || !isGitHubToken(action.Env[actionMetadata.GitHubToken.EnvironmentVariableName]) {
+		if action.Env[actionMetadata.GitHubToken.EnvironmentVariableName] == "" || !IsGitHubToken(action.Env[actionMetadata.GitHubToken.EnvironmentVariableName]) {
 			return permissions, nil
 		}
 	}
 
-	// TODO: Fix the order
-	for scope, value := range actionMetadata.GitHubToken.Permissions.Scopes {
+	scopes := make([]string, 0, len(actionMetadata.GitHubToken.Permissions.Scopes))
+	for scope := range actionMetadata.GitHubToken.Permissions.Scopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	for _, scope := range scopes {
+		value := actionMetadata.GitHubToken.Permissions.Scopes[scope]
 		if len(value.Expression) == 0 || evaluateExpression(value.Expression, action) {
 			permissions = append(permissions, fmt.Sprintf("%s: %s  # for %s %s", scope, value.Permission, actionKey, value.Reason))
 		}
@@ -376,8 +410,14 @@ type JobState struct {
 func evaluateEnvironmentVariables(step metadata.Step) string {
 	keyToEvaluate := ""
 	run := step.Run
-	for key, value := range step.Env {
-		if strings.Contains(value, "secrets.GITHUB_TOKEN") || strings.Contains(value, "github.token") {
+	// the keys are sorted, so the same variable is evaluated in every run if several have the token
+	keys := make([]string, 0, len(step.Env))
+	for key := range step.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value := step.Env[key]; strings.Contains(value, "secrets.GITHUB_TOKEN") || strings.Contains(value, "github.token") {
 			keyToEvaluate = key
 			break
 		}
@@ -405,7 +445,7 @@ func (jobState *JobState) getPermissionsForRunStep(step metadata.Step) ([]Permis
 	//permissions = append(permissions, Permission{permission: "", action: "", reason: ""})
 
 	// reviewdog
-	if step.Env["REVIEWDOG_GITHUB_API_TOKEN"] != "" && isGitHubToken(step.Env["REVIEWDOG_GITHUB_API_TOKEN"]) {
+	if step.Env["REVIEWDOG_GITHUB_API_TOKEN"] != "" && IsGitHubToken(step.Env["REVIEWDOG_GITHUB_API_TOKEN"]) {
 		if strings.Contains(runStep, "reviewdog") {
 			permissions = append(permissions, Permission{permission: checks_write, action: "reviewdog", reason: "to create check"})
 			permissions = append(permissions, Permission{permission: pull_requests_write, action: "reviewdog", reason: "to add comment to the PR"})
@@ -479,7 +519,7 @@ func (jobState *JobState) getPermissionsForRunStep(step metadata.Step) ([]Permis
 
 	// Dependabot run steps reference: https://github.com/dependabot/fetch-metadata#usage-instructions
 	// Dependabot auto approve
-	if step.Env["GITHUB_TOKEN"] != "" && isGitHubToken(step.Env["GITHUB_TOKEN"]) {
+	if step.Env["GITHUB_TOKEN"] != "" && IsGitHubToken(step.Env["GITHUB_TOKEN"]) {
 		if strings.Contains(runStep, "gh pr review --approve") {
 			permissions = append(permissions, Permission{permission: pull_requests_write, action: "dependabot", reason: "to enable auto-approve"})
 			return permissions, nil
@@ -487,7 +527,7 @@ func (jobState *JobState) getPermissionsForRunStep(step metadata.Step) ([]Permis
 	}
 
 	// Dependabot auto merge
-	if step.Env["GITHUB_TOKEN"] != "" && isGitHubToken(step.Env["GITHUB_TOKEN"]) {
+	if step.Env["GITHUB_TOKEN"] != "" && IsGitHubToken(step.Env["GITHUB_TOKEN"]) {
 		if strings.Contains(runStep, "gh pr merge --auto --merge") {
 			permissions = append(permissions, Permission{permission: contents_write, action: "dependabot", reason: "to enable auto-merge"})
 			return permissions, nil
@@ -495,7 +535,7 @@ func (jobState *JobState) getPermissionsForRunStep(step metadata.Step) ([]Permis
 	}
 
 	// Dependabot auto label
-	if step.Env["GITHUB_TOKEN"] != "" && isGitHubToken(step.Env["GITHUB_TOKEN"]) {
+	if step.Env["GITHUB_TOKEN"] != "" && IsGitHubToken(step.Env["GITHUB_TOKEN"]) {
 		if strings.Contains(runStep, "gh pr edit") && strings.Contains(runStep, "--add-label") {
 			permissions = append(permissions, Permission{permission: repository_projects_write, action: "dependabot", reason: "to enable auto-label"})
 			permissions = append(permissions, Permission{permission: issues_write, action: "dependabot", reason: "to enable auto-label"})
@@ -616,42 +656,23 @@ func removeRedundantPermisions(permissions []string) []string {
 	return newPermissions
 }
 
-func addPermissions(inputYaml string, jobName string, permissions []string) (string, error) {
-	t := yaml.Node{}
-
-	err := yaml.Unmarshal([]byte(inputYaml), &t)
-	if err != nil {
-		return "", fmt.Errorf("unable to parse yaml %v", err)
-	}
-
-	jobNode := IterateNode(&t, jobName, "!!map", 0)
+// addPermissions inserts the permissions before the first entry of the job, whose node is looked up in the index of
+// the input of the editor
+func addPermissions(editor *yamledit.Editor, index *document.Index, jobName string, permissions []string) error {
+	job := index.Job(jobName)
 
-	if jobNode == nil {
-		return "", fmt.Errorf("jobName %s not found in the input yaml", jobName)
+	if job == nil {
+		return fmt.Errorf("jobName %s not found in the input yaml", jobName)
 	}
 
-	inputLines := strings.Split(inputYaml, "\n")
-	var output []string
-	for i := 0; i < jobNode.Line-1; i++ {
-		output = append(output, inputLines[i])
-	}
-
-	spaces := ""
-	for i := 0; i < jobNode.Column-1; i++ {
-		spaces += " "
-	}
-
-	output = append(output, spaces+"permissions:")
+	var output strings.Builder
+	output.WriteString("permissions:\n")
 
 	for _, perm := range permissions {
-		output = append(output, spaces+"  "+perm)
+		output.WriteString("  " + perm + "\n")
 	}
 
-	for i := jobNode.Line - 1; i < len(inputLines); i++ {
-		output = append(output, inputLines[i])
-	}
-
-	return strings.Join(output, "\n"), nil
+	return editor.InsertEntries(job.Node, 0, output.String())
 }
 
 func IterateNode(node *yaml.Node, identifier, tag string, minLine int) *yaml.Node {
@@ -686,12 +707,10 @@ func ShouldAddWorkflowLevelPermissions(jobErrors []JobError) bool {
 		return false
 	}
 	for _, jobError := range jobErrors {
-		for _, eachJobError := range jobError.Errors {
-			if eachJobError != errorAlreadyHasPermissions {
-				// if any of the errors is not errorAlreadyHasPermissions
-				// we do not add workflow level permissions
-				return false
-			}
+		if jobError.IsSkipped() {
+			// if any of the errors is not errorAlreadyHasPermissions
+			// we do not add workflow level permissions
+			return false
 		}
 	}
 
@@ -5,8 +5,13 @@ import (
 	"log"
 	"os"
 	"path"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/workflow/metadata"
+	"github.com/step-security/secure-repo/remediation/yamledit"
 )
 
 func TestAddJobLevelPermissions(t *testing.T) {
@@ -70,6 +75,14 @@ func TestAddJobLevelPermissions(t *testing.T) {
 		if output != string(expectedOutput) {
 			t.Errorf("test failed %s did not match expected output\n%s", f.Name(), output)
 		}
+
+		// the jobs of the output have their permissions, so adding them again does not change it
+		if output == fixWorkflowPermsResponse.FinalOutput {
+			again, err := AddJobLevelPermissions(output, false)
+			if err != nil || again.FinalOutput != output {
+				t.Errorf("test failed %s changed when its permissions were added again: %v\n%s", f.Name(), err, again.FinalOutput)
+			}
+		}
 	}
 }
 
@@ -129,16 +142,39 @@ func Test_addPermissions(t *testing.T) {
 		{name: "bad yaml",
 			args: args{
 				inputYaml: "123",
-			}, want: "", wantErr: true},
+			}, want: "123", wantErr: true},
+		{name: "job",
+			args: args{
+				inputYaml:   "jobs:\n  build:\n    runs-on: ubuntu-latest\n",
+				jobName:     "build",
+				permissions: []string{"contents: read", "issues: write"},
+			}, want: "jobs:\n  build:\n    permissions:\n      contents: read\n      issues: write\n    runs-on: ubuntu-latest\n"},
+		{name: "four spaces",
+			args: args{
+				inputYaml:   "jobs:\n    build:\n        runs-on: ubuntu-latest\n",
+				jobName:     "build",
+				permissions: []string{"contents: read"},
+			}, want: "jobs:\n    build:\n        permissions:\n            contents: read\n        runs-on: ubuntu-latest\n"},
+		{name: "flow job",
+			args: args{
+				inputYaml:   "jobs:\n  build: {runs-on: ubuntu-latest, steps: [{run: echo}]}\n",
+				jobName:     "build",
+				permissions: []string{"contents: read", "issues: write"},
+			}, want: "jobs:\n  build: {permissions: {contents: read, issues: write}, runs-on: ubuntu-latest, steps: [{run: echo}]}\n"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := addPermissions(tt.args.inputYaml, tt.args.jobName, tt.args.permissions)
+			index, err := document.Parse(tt.args.inputYaml).Index()
+			if err != nil {
+				t.Fatalf("Error not expected: %v", err)
+			}
+			editor := yamledit.New(tt.args.inputYaml)
+			err = addPermissions(editor, index, tt.args.jobName, tt.args.permissions)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("addPermissions() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if got != tt.want {
+			if got := editor.String(); got != tt.want {
 				t.Errorf("addPermissions() = %v, want %v", got, tt.want)
 			}
 		})
@@ -190,6 +226,11 @@ func TestAddWorkflowLevelPermissions(t *testing.T) {
 		if output != string(expectedOutput) {
 			t.Errorf("test failed %s did not match expected output\n%s", f.Name(), output)
 		}
+
+		// the output has the permissions of the workflow, so they are not added again
+		if again, err := AddWorkflowLevelPermissions(output, addProjectComment, false); err == nil || again != output {
+			t.Errorf("test failed %s changed when its permissions were added again: %v\n%s", f.Name(), err, again)
+		}
 	}
 
 }
@@ -231,3 +272,71 @@ func TestAddWorkflowLevelPermissionsWithEmpty(t *testing.T) {
 		t.Errorf("test failed with addEmptyTopLevelPermissions=false for empty-permissions.yml - should contain 'contents: read' but not 'permissions: {}'\nGot:\n%s", output2)
 	}
 }
+
+func TestAddJobLevelPermissionsOrder(t *testing.T) {
+	os.Setenv("KBFolder", "../../../knowledge-base/actions")
+
+	input := `name: order
+on: push
+jobs:
+  zeta:
+    permissions:
+      contents: read
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo zeta
+  alpha:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: step-security/missing-alpha@v1
+  middle:
+    uses: octo-org/octo-repo/.github/workflows/reusable.yml@main
+  beta:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: step-security/missing-beta@v1
+`
+	first, err := AddJobLevelPermissions(input, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jobNames []string
+	for _, jobError := range first.JobErrors {
+		jobNames = append(jobNames, jobError.JobName)
+	}
+	if strings.Join(jobNames, ",") != "alpha,beta,middle,zeta" {
+		t.Errorf("job errors are not in the order of the jobs: %v", jobNames)
+	}
+	if strings.Join(first.MissingActions, ",") != "step-security/missing-alpha@v1,step-security/missing-beta@v1" {
+		t.Errorf("missing actions are not in the order of the jobs: %v", first.MissingActions)
+	}
+
+	// the map of the jobs is iterated in a different order in each run, which must not change the response
+	for i := 0; i < 20; i++ {
+		response, err := AddJobLevelPermissions(input, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(response, first) {
+			t.Fatalf("response of run %d is different from the first run\n%+v\n%+v", i, response, first)
+		}
+	}
+}
+
+func TestGetPermissionsForActionOrder(t *testing.T) {
+	os.Setenv("KBFolder", "../../../knowledge-base/actions")
+
+	step := metadata.Step{Uses: "EnricoMi/publish-unit-test-result-action/composite@v2", With: metadata.With{"comment_mode": "always"}}
+	jobState := &JobState{}
+	permissions, err := jobState.getPermissionsForAction(step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scopes []string
+	for _, permission := range permissions {
+		scopes = append(scopes, strings.Split(permission, ":")[0])
+	}
+	if strings.Join(scopes, ",") != "checks,contents,issues,pull-requests" {
+		t.Errorf("scopes are not sorted: %v", scopes)
+	}
+}
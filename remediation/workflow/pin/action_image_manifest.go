@@ -1,16 +1,17 @@
 package pin
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
 
-	"net/http"
-
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
-	"github.com/sirupsen/logrus"
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/outbound"
+	"github.com/step-security/secure-repo/remediation/workflow/actionrepo"
 )
 
 var (
@@ -34,12 +35,11 @@ type ociManifest struct {
 //	isImmutableAction("actions/checkout@v4.2.3")
 //
 // REF - https://github.com/actions/publish-immutable-action/issues/216#issuecomment-2549914784
-func IsImmutableAction(action string) bool {
+func IsImmutableAction(ctx context.Context, action string) bool {
 
-	artifactType, err := getOCIImageArtifactTypeForGhAction(action)
+	artifactType, err := getOCIImageArtifactTypeForGhAction(ctx, action)
 	if err != nil {
-		// log the error
-		logrus.WithFields(logrus.Fields{"action": action}).WithError(err).Error("error in getting OCI manifest for image")
+		logging.Logger().Error("error in getting OCI manifest for image", "module", "pin", "action", action, "error", err)
 		return false
 	}
 
@@ -61,7 +61,7 @@ func IsImmutableAction(action string) bool {
 // Returns:
 //   - artifactType: The artifact type string from the OCI manifest
 //   - error: An error if the action format is invalid or if there's a problem retrieving the manifest
-func getOCIImageArtifactTypeForGhAction(action string) (string, error) {
+func getOCIImageArtifactTypeForGhAction(ctx context.Context, action string) (string, error) {
 
 	// Split the action into parts (e.g., "actions/checkout@v2" -> ["actions/checkout", "v2"])
 	parts := strings.Split(action, "@")
@@ -86,23 +86,31 @@ func getOCIImageArtifactTypeForGhAction(action string) (string, error) {
 		parts[1] = strings.TrimPrefix(parts[1], "v")
 	}
 
-	// Convert GitHub action to GHCR image reference using proper OCI reference format
-	image := fmt.Sprintf("ghcr.io/%s:%s", actionPath, parts[1])
-	imageManifest, err := getOCIManifestForImage(image)
-	if err != nil {
-		return "", err
-	}
-
-	var ociManifest ociManifest
-	err = json.Unmarshal([]byte(imageManifest), &ociManifest)
+	// the manifest is fetched once for the version of the repository, and shared by the other lookups of it
+	owner, repo, _ := strings.Cut(actionPath, "/")
+	artifactType, err := actionrepo.Fetch("artifact-type", owner, repo, parts[1], func() (*string, error) {
+		// Convert GitHub action to GHCR image reference using proper OCI reference format
+		image := fmt.Sprintf("ghcr.io/%s:%s", actionPath, parts[1])
+		imageManifest, err := getOCIManifestForImage(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+
+		var ociManifest ociManifest
+		err = json.Unmarshal([]byte(imageManifest), &ociManifest)
+		if err != nil {
+			return nil, err
+		}
+		return &ociManifest.ArtifactType, nil
+	})
 	if err != nil {
 		return "", err
 	}
-	return ociManifest.ArtifactType, nil
+	return *artifactType, nil
 }
 
 // getOCIManifestForImage retrieves the artifact type from the OCI image manifest
-func getOCIManifestForImage(imageRef string) (string, error) {
+func getOCIManifestForImage(ctx context.Context, imageRef string) (string, error) {
 
 	// Parse the image reference
 	ref, err := name.ParseReference(imageRef)
@@ -111,7 +119,7 @@ func getOCIManifestForImage(imageRef string) (string, error) {
 	}
 
 	// Get the image manifest
-	desc, err := remote.Get(ref, remote.WithTransport(http.DefaultTransport))
+	desc, err := remote.Get(ref, remote.WithContext(ctx), remote.WithTransport(outbound.Default()))
 	if err != nil {
 		return "", fmt.Errorf("error getting manifest: %v", err)
 	}
@@ -1,6 +1,7 @@
 package pin
 
 import (
+	"context"
 	"crypto/tls"
 	"io/ioutil"
 	"net/http"
@@ -138,7 +139,7 @@ func Test_isImmutableAction(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			got := IsImmutableAction(tt.action)
+			got := IsImmutableAction(context.Background(), tt.action)
 			if got != tt.want {
 				t.Errorf("isImmutableAction() = %v, want %v", got, tt.want)
 			}
@@ -0,0 +1,33 @@
+package pin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/step-security/secure-repo/remediation/workflow/fuzzing"
+)
+
+func FuzzPinActions(f *testing.F) {
+	fuzzing.AddSeeds(f, "../../../testfiles/pinactions/input")
+
+	// every ref of every action resolves to the same commit, without tags of its version
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", `=~^https://api\.github\.com/repos/[^/]+/[^/]+/commits/`,
+		httpmock.NewStringResponder(200, `ebacdc22ef6c2cfb85ee5ded8f2e640f4c776dd5`))
+	httpmock.RegisterResponder("GET", `=~^https://api\.github\.com/repos/[^/]+/[^/]+/git/matching-refs/tags`,
+		httpmock.NewStringResponder(200, `[]`))
+
+	f.Fuzz(func(t *testing.T, input string) {
+		output, _, err := PinActions(context.Background(), input, nil, false, nil)
+		if err != nil {
+			return
+		}
+		// only the uses of the steps are pinned, and their comments set
+		fuzzing.CheckOutput(t, input, output, fuzzing.DeleteKeys("uses"), func(line string) bool {
+			return strings.Contains(line, "@")
+		})
+	})
+}
@@ -2,85 +2,100 @@ package pin
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/google/go-github/v40/github"
-	metadata "github.com/step-security/secure-repo/remediation/workflow/metadata"
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/metrics"
+	"github.com/step-security/secure-repo/remediation/workflow/actionrepo"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/workflow/metadata"
+	"github.com/step-security/secure-repo/remediation/yamledit"
 	"golang.org/x/oauth2"
 	"gopkg.in/yaml.v3"
 )
 
-func PinActions(inputYaml string, exemptedActions []string, pinToImmutable bool, actionCommitMap map[string]string) (string, bool, error) {
-	workflow := metadata.Workflow{}
+// PinActions pins the actions of the jobs, or of a composite action, to their commit SHA. An action that cannot be
+// pinned, e.g. because its ref is not found, does not stop the others from being pinned: the workflow with the actions
+// that were pinned is returned along with the errors of the others.
+func PinActions(ctx context.Context, inputYaml string, exemptedActions []string, pinToImmutable bool, actionCommitMap map[string]string) (string, bool, error) {
 	updated := false
-	err := yaml.Unmarshal([]byte(inputYaml), &workflow)
+	workflow, err := document.Parse(inputYaml).Workflow()
 	if err != nil {
 		return inputYaml, updated, fmt.Errorf("unable to parse yaml %v", err)
 	}
 
-	out := inputYaml
-
-	for _, job := range workflow.Jobs {
-
-		for _, step := range job.Steps {
-			if len(step.Uses) > 0 {
-				localUpdated := false
-				out, localUpdated, err = PinActionWithPatFallback(step.Uses, out, exemptedActions, pinToImmutable, actionCommitMap)
-				if err != nil {
-					return out, updated, err
-				}
-				updated = updated || localUpdated
-			}
-		}
+	var steps []metadata.Step
+	for _, jobName := range workflow.Jobs.Names() {
+		steps = append(steps, workflow.Jobs[jobName].Steps...)
 	}
-
 	// For composite actions
 	if workflow.Runs.Using == "composite" {
-		for _, run := range workflow.Runs.Steps {
-			if len(run.Uses) > 0 {
-				localUpdated := false
-				out, localUpdated, err = PinActionWithPatFallback(run.Uses, out, exemptedActions, pinToImmutable, actionCommitMap)
-				if err != nil {
-					return out, updated, err
-				}
-				updated = updated || localUpdated
-			}
+		steps = append(steps, workflow.Runs.Steps...)
+	}
+
+	out := inputYaml
+	var errs []error
+	for _, step := range steps {
+		if len(step.Uses) == 0 {
+			continue
+		}
+		// the actions after the context is done are not pinned, since their requests would fail
+		if ctx.Err() != nil {
+			return out, updated, errors.Join(append(errs, ctx.Err())...)
+		}
+		pinned, localUpdated, err := PinActionWithPatFallback(ctx, step.Uses, out, exemptedActions, pinToImmutable, actionCommitMap)
+		if err != nil {
+			errs = append(errs, err)
+			continue
 		}
+		out, updated = pinned, updated || localUpdated
 	}
 
-	return out, updated, nil
+	return out, updated, errors.Join(errs...)
+}
+
+// ActionPinner pins the actions added to a workflow by the other modules, e.g. harden-runner, with
+// PinActionWithPatFallback
+type ActionPinner struct{}
+
+// PinAction pins the action in the workflow to the commit of its tag
+func (ActionPinner) PinAction(ctx context.Context, action, inputYaml string, pinToImmutable bool) (string, error) {
+	out, _, err := PinActionWithPatFallback(ctx, action, inputYaml, nil, pinToImmutable, nil)
+	return out, err
 }
 
-func PinActionWithPatFallback(action, inputYaml string, exemptedActions []string, pinToImmutable bool, actionCommitMap map[string]string) (string, bool, error) {
+func PinActionWithPatFallback(ctx context.Context, action, inputYaml string, exemptedActions []string, pinToImmutable bool, actionCommitMap map[string]string) (string, bool, error) {
 	// use secure repo token
 	PAT := os.Getenv("SECURE_REPO_PAT")
 	if PAT == "" {
 		PAT = os.Getenv("PAT")
-		log.Println("SECURE_REPO_PAT is not set, using PAT")
+		logging.Logger().Debug("SECURE_REPO_PAT is not set, using PAT", "module", "pin")
 	} else {
-		log.Println("SECURE_REPO_PAT is set")
+		logging.Logger().Debug("SECURE_REPO_PAT is set", "module", "pin")
 	}
-	out, updated, err := PinAction(action, inputYaml, PAT, exemptedActions, pinToImmutable, actionCommitMap)
-	if err != nil && strings.Contains(err.Error(), "organization has an IP allow list enabled, and your IP address is not permitted to access this resource") {
+	out, updated, err := PinAction(ctx, action, inputYaml, PAT, exemptedActions, pinToImmutable, actionCommitMap)
+	if err != nil && strings.Contains(err.Error(), ipAllowListError) {
 		PAT = os.Getenv("PAT")
-		log.Println("[RETRY] SECURE_REPO_PAT is not set, using PAT")
-		return PinAction(action, inputYaml, PAT, exemptedActions, pinToImmutable, actionCommitMap)
+		logging.Logger().Info("retrying with PAT, since the IP allow list of the organization denied SECURE_REPO_PAT", "module", "pin", "action", action)
+		return PinAction(ctx, action, inputYaml, PAT, exemptedActions, pinToImmutable, actionCommitMap)
 	}
 	return out, updated, err
 }
 
-func PinAction(action, inputYaml, PAT string, exemptedActions []string, pinToImmutable bool, actionCommitMap map[string]string) (string, bool, error) {
+func PinAction(ctx context.Context, action, inputYaml, PAT string, exemptedActions []string, pinToImmutable bool, actionCommitMap map[string]string) (string, bool, error) {
 	updated := false
 
 	if !strings.Contains(action, "@") || strings.HasPrefix(action, "docker://") {
 		return inputYaml, updated, nil // Cannot pin local actions and docker actions
 	}
 
-	if isAbsolute(action) || (pinToImmutable && IsImmutableAction(action)) {
+	if isAbsolute(action) || (pinToImmutable && IsImmutableAction(ctx, action)) {
 		return inputYaml, updated, nil
 	}
 	leftOfAt := strings.Split(action, "@")
@@ -91,136 +106,97 @@ func PinAction(action, inputYaml, PAT string, exemptedActions []string, pinToImm
 		return inputYaml, updated, nil
 	}
 
+	// an action that is not of a repository, e.g. "action@v1", cannot be pinned
 	splitOnSlash := strings.Split(leftOfAt[0], "/")
+	if len(splitOnSlash) < 2 || splitOnSlash[0] == "" || splitOnSlash[1] == "" {
+		return inputYaml, updated, nil
+	}
 	owner := splitOnSlash[0]
 	repo := splitOnSlash[1]
 
-	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: PAT},
 	)
-	tc := oauth2.NewClient(ctx, ts)
+	tc := oauth2.NewClient(metrics.WithGitHubClient(ctx), ts)
 
 	client := github.NewClient(tc)
 	var commitSHA string
 	var err error
 
 	if actionCommitMap != nil {
-		// Check case-insensitively by iterating through the map
-		for mapAction, actionWithCommit := range actionCommitMap {
-			if strings.EqualFold(action, mapAction) && actionWithCommit != "" {
-				commitSHA = actionWithCommit
-
-				if !semanticTagRegex.MatchString(tagOrBranch) {
-					tagOrBranch, err = getSemanticVersion(client, owner, repo, tagOrBranch, commitSHA)
-					if err != nil {
-						return inputYaml, updated, err
-					}
-				}
-				break
+		commitSHA = getMappedCommit(actionCommitMap, action)
+		if commitSHA != "" && !semanticTagRegex.MatchString(tagOrBranch) {
+			tagOrBranch, err = getSemanticVersion(ctx, client, owner, repo, tagOrBranch, commitSHA)
+			if err != nil {
+				return inputYaml, updated, err
 			}
 		}
 	}
 
 	if commitSHA == "" {
-		commitSHA, _, err = client.Repositories.GetCommitSHA1(ctx, owner, repo, tagOrBranch, "")
+		resolved, err := resolveRef(ctx, client, owner, repo, tagOrBranch)
 		if err != nil {
 			return inputYaml, updated, err
 		}
-		tagOrBranch, err = getSemanticVersion(client, owner, repo, tagOrBranch, commitSHA)
-		if err != nil {
-			return inputYaml, updated, err
-		}
-
+		commitSHA, tagOrBranch = resolved.CommitSHA, resolved.Version
 	}
 
-	// pinnedAction := fmt.Sprintf("%s@%s # %s", leftOfAt[0], commitSHA, tagOrBranch)
-	// build separately so we can quote only the ref, not the comment
+	// the ref and the comment are written separately, so only the ref is quoted
 	pinnedRef := fmt.Sprintf("%s@%s", leftOfAt[0], commitSHA)
-	comment := fmt.Sprintf(" # %s", tagOrBranch)
-	fullPinned := pinnedRef + comment
+
+	// the action is replaced in the uses keys of the tree of the workflow, so it is not replaced in scripts and comments,
+	// and its quotes and flow collections are kept
+	root, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, updated, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	editor := yamledit.New(inputYaml)
 
 	// if the action with version is immutable, then pin the action with version instead of sha
 	pinnedActionWithVersion := fmt.Sprintf("%s@%s", leftOfAt[0], tagOrBranch)
-	if pinToImmutable && semanticTagRegex.MatchString(tagOrBranch) && IsImmutableAction(pinnedActionWithVersion) {
-		// strings.ReplaceAll is not suitable here because it would incorrectly replace substrings
-		// For example, if we want to replace "actions/checkout@v1" to "actions/checkout@v1.2.3", it would also incorrectly match and replace in "actions/checkout@v1.2.3"
-		// making new string to "actions/checkout@v1.2.3.2.3"
-		//
-		// Instead, we use a regex pattern that ensures we only replace complete action references:
-		// Pattern: (<action>@<version>)($|\s|"|')
-		// - Group 1 (<action>@<version>): Captures the exact action reference
-		// - Group 2 ($|\s|"|'): Captures the delimiter that follows (end of line, whitespace, or quotes)
-		//
-		// Examples:
-		// - "actions/checkout@v1.2.3" - No match (no delimiter after v1)
-		// - "actions/checkout@v1 "    - Matches (space delimiter)
-		// - "actions/checkout@v1""    - Matches (quote delimiter)
-		// - "actions/checkout@v1"     - Matches (quote delimiter)
-		// - "actions/checkout@v1\n"   - Matches (newline is considered whitespace \s)
-
-		actionRegex := regexp.MustCompile(`(` + regexp.QuoteMeta(action) + `)($|\s|"|')`)
-		inputYaml = actionRegex.ReplaceAllString(inputYaml, pinnedActionWithVersion+"$2")
-
-		inputYaml, _ = removePreviousActionComments(pinnedActionWithVersion, inputYaml)
-		return inputYaml, !strings.EqualFold(action, pinnedActionWithVersion), nil
+	if pinToImmutable && semanticTagRegex.MatchString(tagOrBranch) && IsImmutableAction(ctx, pinnedActionWithVersion) {
+		for _, node := range getUsesNodes(root, action) {
+			if err := editor.ReplaceScalar(node, pinnedActionWithVersion); err != nil {
+				return inputYaml, updated, err
+			}
+			// the comment of the previous version is removed
+			if _, err := editor.SetComment(node, ""); err != nil {
+				return inputYaml, updated, err
+			}
+		}
+		return editor.String(), !strings.EqualFold(action, pinnedActionWithVersion), nil
 	}
 
-	updated = !strings.EqualFold(action, fullPinned)
+	updated = !strings.EqualFold(action, fmt.Sprintf("%s # %s", pinnedRef, tagOrBranch))
 
-	// 1) Double-quoted form:  "owner/repo@oldRef"
-	doubleQuotedPattern := `"` + regexp.QuoteMeta(action) + `"` + `($|\s|"|')`
-	doubleQuotedRe := regexp.MustCompile(doubleQuotedPattern)
-	inputYaml = doubleQuotedRe.ReplaceAllString(
-		inputYaml,
-		fmt.Sprintf(`"%s"%s$1`, pinnedRef, comment),
-	)
-	inputYaml, _ = removePreviousActionComments(fmt.Sprintf(`"%s"%s`, pinnedRef, comment), inputYaml)
-
-	// 2) Single-quoted form:  'owner/repo@oldRef'
-	singleQuotedPattern := `'` + regexp.QuoteMeta(action) + `'` + `($|\s|"|')`
-	singleQuotedRe := regexp.MustCompile(singleQuotedPattern)
-	inputYaml = singleQuotedRe.ReplaceAllString(
-		inputYaml,
-		fmt.Sprintf(`'%s'%s$1`, pinnedRef, comment),
-	)
-	inputYaml, _ = removePreviousActionComments(fmt.Sprintf(`'%s'%s`, pinnedRef, comment), inputYaml)
-
-	// 3) Unquoted form:  owner/repo@oldRef
-	unqPattern := `\b` + regexp.QuoteMeta(action) + `\b` + `($|\s|"|')`
-	unqRe := regexp.MustCompile(unqPattern)
-	inputYaml = unqRe.ReplaceAllString(
-		inputYaml,
-		fullPinned+`$1`,
-	)
-	inputYaml, _ = removePreviousActionComments(fullPinned, inputYaml)
+	for _, node := range getUsesNodes(root, action) {
+		if err := editor.ReplaceScalar(node, pinnedRef); err != nil {
+			return inputYaml, updated, err
+		}
+		// the version is written in a comment, so Dependabot and Renovate can update it, unless the action is in a flow
+		// collection, where a comment would hide the rest of the line
+		if _, err := editor.SetComment(node, tagOrBranch); err != nil {
+			return inputYaml, updated, err
+		}
+	}
 
-	return inputYaml, updated, nil
+	return editor.String(), updated, nil
 }
 
-// It may be that there was already a comment next to the action
-// In this case we want to remove the earlier comment
-// we add a comment with the Action version so dependabot/ renovatebot can update it
-// if there was no comment next to any action, updated will be false
-func removePreviousActionComments(pinnedAction, inputYaml string) (string, bool) {
-	updated := false
-	stringParts := strings.Split(inputYaml, pinnedAction)
-	if len(stringParts) > 1 {
-		inputYaml = ""
-		inputYaml = stringParts[0]
-		for idx := 1; idx < len(stringParts); idx++ {
-			trimmedString := strings.SplitN(stringParts[idx], "\n", 2)
-			inputYaml = inputYaml + pinnedAction
-			if len(trimmedString) > 1 {
-				if strings.Contains(trimmedString[0], "#") {
-					updated = true
-				}
-				inputYaml = inputYaml + "\n" + trimmedString[1]
+// getUsesNodes returns the values of the uses keys of the tree that are the action
+func getUsesNodes(node *yaml.Node, action string) []*yaml.Node {
+	var nodes []*yaml.Node
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if value := node.Content[i+1]; node.Content[i].Value == "uses" && value.Kind == yaml.ScalarNode && value.Value == action {
+				nodes = append(nodes, value)
 			}
 		}
 	}
-
-	return inputYaml, updated
+	for _, child := range node.Content {
+		nodes = append(nodes, getUsesNodes(child, action)...)
+	}
+	return nodes
 }
 
 // https://github.com/sethvargo/ratchet/blob/3524c5cfde0439099b3a37274e683af4c779b0d1/parser/refs.go#L56
@@ -250,31 +226,41 @@ func IsAllHex(s string) bool {
 	return true
 }
 
-func getSemanticVersion(client *github.Client, owner, repo, tagOrBranch, commitSHA string) (string, error) {
-	tags, _, err := client.Git.ListMatchingRefs(context.Background(), owner, repo, &github.ReferenceListOptions{
-		Ref: fmt.Sprintf("tags/%s.", tagOrBranch),
-		ListOptions: github.ListOptions{
-			PerPage: 100,
-		},
-	})
+// getMappedCommit returns the commit of the action in the map, or an empty string if it has none. The actions of the map
+// are matched case-insensitively, and if several match, the one written as the action is used, or else the first one
+// in sorted order that has a commit.
+func getMappedCommit(actionCommitMap map[string]string, action string) string {
+	if commitSHA := actionCommitMap[action]; commitSHA != "" {
+		return commitSHA
+	}
+	mapActions := make([]string, 0, len(actionCommitMap))
+	for mapAction := range actionCommitMap {
+		mapActions = append(mapActions, mapAction)
+	}
+	sort.Strings(mapActions)
+	for _, mapAction := range mapActions {
+		if strings.EqualFold(action, mapAction) && actionCommitMap[mapAction] != "" {
+			return actionCommitMap[mapAction]
+		}
+	}
+	return ""
+}
+
+func getSemanticVersion(ctx context.Context, client *github.Client, owner, repo, tagOrBranch, commitSHA string) (string, error) {
+	tags, err := actionrepo.ListTags(ctx, client, owner, repo)
 	if err != nil {
 		return "", err
 	}
 
-	for i := len(tags) - 1; i >= 0; i-- {
-		tag := strings.TrimPrefix(*tags[i].Ref, "refs/tags/")
-		if *tags[i].Object.Type == "commit" {
-			if commitSHA == *tags[i].Object.SHA {
-				return tag, nil
-			}
-		} else {
-			commitsha, _, err := client.Repositories.GetCommitSHA1(context.Background(), owner, repo, tag, "")
-			if err != nil {
-				return "", err
-			}
-			if commitSHA == commitsha {
-				return tag, nil
-			}
+	// the latest version of the tag with the commit, e.g. v4.1.2 for v4
+	versions := tags.WithPrefix(tagOrBranch + ".")
+	for i := len(versions) - 1; i >= 0; i-- {
+		sha, err := versions[i].CommitSHA(ctx, client, owner, repo)
+		if err != nil {
+			return "", err
+		}
+		if commitSHA == sha {
+			return versions[i].Name, nil
 		}
 	}
 	return tagOrBranch, nil
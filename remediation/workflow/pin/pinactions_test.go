@@ -1,6 +1,7 @@
 package pin
 
 import (
+	"context"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -21,7 +22,7 @@ func TestPinActions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/peter-evans/close-issue/commits/v1",
 		httpmock.NewStringResponder(200, `a700eac5bf2a1c7a8cb6da0c13f93ed96fd53dbe`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/peter-evans/close-issue/git/matching-refs/tags/v1.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/peter-evans/close-issue/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -36,7 +37,7 @@ func TestPinActions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/evans/shield/commits/v1",
 		httpmock.NewStringResponder(200, `a700eac5bf2a1c7a8cb6da0c13f93ed96fd53dbd`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/evans/shield/git/matching-refs/tags/v1.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/evans/shield/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -51,25 +52,22 @@ func TestPinActions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/commits/master",
 		httpmock.NewStringResponder(200, `61b9e3751b92087fd0b06925ba6dd6314e06f089`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/git/matching-refs/tags/master.",
-		httpmock.NewStringResponder(200, `[]`))
-
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/elgohr/Publish-Docker-Github-Action/commits/master",
 		httpmock.NewStringResponder(200, `8217e91c0369a5342a4ef2d612de87492410a666`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/elgohr/Publish-Docker-Github-Action/git/matching-refs/tags/master.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/elgohr/Publish-Docker-Github-Action/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[]`))
 
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/borales/actions-yarn/commits/v2.3.0",
 		httpmock.NewStringResponder(200, `4965e1a0f0ae9c422a9a5748ebd1fb5e097d22b9`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/borales/actions-yarn/git/matching-refs/tags/v2.3.0.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/borales/actions-yarn/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[]`))
 
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/commits/v1",
 		httpmock.NewStringResponder(200, `544eadc6bf3d226fd7a7a9f0dc5b5bf7ca0675b9`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/git/matching-refs/tags/v1.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -101,6 +99,13 @@ func TestPinActions(t *testing.T) {
 					"type": "tag",
 					"url": "https://api.github.com/repos/actions/checkout/git/tags/a2ca40438991a1ab62db1b7cad0fd4e36a2ac254"
 				  }
+				},
+				{
+					"ref": "refs/tags/v4.5.6",
+					"object": {
+					  "sha": "c12b8546b67672ee38ac87bea491ac94a587f7sh",
+					  "type": "commit"
+					}
 				}
 			  ]`),
 	)
@@ -111,7 +116,7 @@ func TestPinActions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/setup-node/commits/v1",
 		httpmock.NewStringResponder(200, `f1f314fca9dfce2769ece7d933488f076716723e`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/setup-node/git/matching-refs/tags/v1.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/setup-node/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -126,7 +131,7 @@ func TestPinActions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/JS-DevTools/npm-publish/commits/v1",
 		httpmock.NewStringResponder(200, `0f451a94170d1699fd50710966d48fb26194d939`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/JS-DevTools/npm-publish/git/matching-refs/tags/v1.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/JS-DevTools/npm-publish/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -141,7 +146,7 @@ func TestPinActions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/brandedoutcast/publish-nuget/commits/v2",
 		httpmock.NewStringResponder(200, `c12b8546b67672ee38ac87bea491ac94a587f7cc`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/brandedoutcast/publish-nuget/git/matching-refs/tags/v2.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/brandedoutcast/publish-nuget/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -176,7 +181,7 @@ func TestPinActions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/setup-java/commits/v4",
 		httpmock.NewStringResponder(200, `c12b8546b67672ee38ac87bea491ac94a587f7cc`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/setup-java/git/matching-refs/tags/v4.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/setup-java/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -191,22 +196,10 @@ func TestPinActions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/commits/v4",
 		httpmock.NewStringResponder(200, `c12b8546b67672ee38ac87bea491ac94a587f7ch`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/git/matching-refs/tags/v4.",
-		httpmock.NewStringResponder(200,
-			`[
-				{
-					"ref": "refs/tags/v4.5.6",
-					"object": {
-					  "sha": "c12b8546b67672ee38ac87bea491ac94a587f7sh",
-					  "type": "commit"
-					}
-				  }
-			]`))
-
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/rohith/publish-nuget/commits/v2",
 		httpmock.NewStringResponder(200, `c12b8546b67672ee38ac87bea491ac94a587f7cc`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/rohith/publish-nuget/git/matching-refs/tags/v2.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/rohith/publish-nuget/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -221,7 +214,7 @@ func TestPinActions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/github/codeql-action/commits/v3",
 		httpmock.NewStringResponder(200, `d68b2d4edb4189fd2a5366ac14e72027bd4b37dd`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/github/codeql-action/git/matching-refs/tags/v3.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/github/codeql-action/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -236,18 +229,6 @@ func TestPinActions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/github/codeql-action/commits/v3.28.2",
 		httpmock.NewStringResponder(200, `d68b2d4edb4189fd2a5366ac14e72027bd4b37dd`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/github/codeql-action/git/matching-refs/tags/v3.28.2.",
-		httpmock.NewStringResponder(200,
-			`[
-					{
-						"ref": "refs/tags/v3.28.2",
-						"object": {
-						  "sha": "d68b2d4edb4189fd2a5366ac14e72027bd4b37dd",
-						  "type": "commit"
-						}
-					  }
-				]`))
-
 	// mock ping response
 	httpmock.RegisterResponder("GET", "https://ghcr.io/v2/",
 		httpmock.NewStringResponder(200, ``))
@@ -287,7 +268,7 @@ func TestPinActions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/peter-evans-test/close-issue/commits/v1",
 		httpmock.NewStringResponder(200, `a700eac5bf2a1c7a8cb6da0c13f93ed96fd53dbe`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/peter-evans-test/close-issue/git/matching-refs/tags/v1.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/peter-evans-test/close-issue/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -358,6 +339,7 @@ func TestPinActions(t *testing.T) {
 		{fileName: "invertedcommas.yml", wantUpdated: true, pinToImmutable: false},
 		{fileName: "pinusingmap.yml", wantUpdated: true, pinToImmutable: true},
 		{fileName: "action.yml", wantUpdated: true, pinToImmutable: false},
+		{fileName: "flowstyle.yml", wantUpdated: true, pinToImmutable: false},
 	}
 	for _, tt := range tests {
 
@@ -386,7 +368,7 @@ func TestPinActions(t *testing.T) {
 			}
 		}
 
-		output, gotUpdated, err = PinActions(string(input), tt.exemptedActions, tt.pinToImmutable, actionCommitMap)
+		output, gotUpdated, err = PinActions(context.Background(), string(input), tt.exemptedActions, tt.pinToImmutable, actionCommitMap)
 		if tt.wantUpdated != gotUpdated {
 			t.Errorf("test failed wantUpdated %v did not match gotUpdated %v", tt.wantUpdated, gotUpdated)
 		}
@@ -403,6 +385,16 @@ func TestPinActions(t *testing.T) {
 		if output != string(expectedOutput) {
 			t.Errorf("test failed %s did not match expected output\n%s", tt.fileName, output)
 		}
+
+		// the actions of the output are pinned, so pinning them again does not change it. The commits of the map are not
+		// SHAs, so the actions pinned with them are not.
+		if tt.fileName == "pinusingmap.yml" || tt.fileName == "action.yml" {
+			continue
+		}
+		again, againUpdated, err := PinActions(context.Background(), output, tt.exemptedActions, tt.pinToImmutable, actionCommitMap)
+		if err != nil || againUpdated || again != output {
+			t.Errorf("test failed %s changed when it was pinned again: %v, %v\n%s", tt.fileName, againUpdated, err, again)
+		}
 	}
 }
 
@@ -478,3 +470,68 @@ func TestActionExists(t *testing.T) {
 	}
 
 }
+
+func Test_getMappedCommit(t *testing.T) {
+	actionCommitMap := map[string]string{
+		"Actions/Checkout": "1111111111111111111111111111111111111111",
+		"actions/CHECKOUT": "2222222222222222222222222222222222222222",
+		"actions/checkout": "",
+		"Actions/Cache":    "3333333333333333333333333333333333333333",
+		"actions/cache":    "4444444444444444444444444444444444444444",
+	}
+	tests := []struct {
+		action string
+		want   string
+	}{
+		{action: "actions/cache", want: "4444444444444444444444444444444444444444"},
+		{action: "actions/checkout", want: "1111111111111111111111111111111111111111"},
+		{action: "actions/setup-go", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			// the map is iterated in a different order in each run, which must not change the commit
+			for i := 0; i < 20; i++ {
+				if got := getMappedCommit(actionCommitMap, tt.action); got != tt.want {
+					t.Fatalf("getMappedCommit() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPinActionsPartial(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/peter-evans/close-issue/commits/v1",
+		httpmock.NewStringResponder(200, `a700eac5bf2a1c7a8cb6da0c13f93ed96fd53dbe`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/peter-evans/close-issue/git/matching-refs/tags",
+		httpmock.NewStringResponder(200, `[{"ref": "refs/tags/v1.0.3", "object": {"sha": "a700eac5bf2a1c7a8cb6da0c13f93ed96fd53dbe", "type": "commit"}}]`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/missingaction/commits/v2",
+		httpmock.NewStringResponder(404, `{"message": "Not Found"}`))
+
+	// the action that cannot be pinned is before the one that can, which is still pinned
+	input := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/missingaction@v2
+      - uses: peter-evans/close-issue@v1
+`
+	want := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/missingaction@v2
+      - uses: peter-evans/close-issue@a700eac5bf2a1c7a8cb6da0c13f93ed96fd53dbe # v1.0.3
+`
+	output, updated, err := PinActions(context.Background(), input, nil, false, nil)
+	if err == nil || !strings.Contains(err.Error(), "missingaction") {
+		t.Errorf("PinActions() error = %v, want the error of actions/missingaction", err)
+	}
+	if !updated || output != want {
+		t.Errorf("PinActions() = %v\n%s\nwant the other action pinned\n%s", updated, output, want)
+	}
+}
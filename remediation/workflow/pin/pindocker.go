@@ -1,6 +1,7 @@
 package pin
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -8,29 +9,27 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
-	metadata "github.com/step-security/secure-repo/remediation/workflow/metadata"
-	"gopkg.in/yaml.v3"
+	"github.com/step-security/secure-repo/remediation/outbound"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
 )
 
-var Tr http.RoundTripper = remote.DefaultTransport
+var Tr http.RoundTripper = outbound.Default()
 
-func PinDocker(inputYaml string) (string, bool, error) {
+func PinDocker(ctx context.Context, inputYaml string) (string, bool, error) {
 	updated := false
-	workflow := metadata.Workflow{}
-
-	err := yaml.Unmarshal([]byte(inputYaml), &workflow)
+	workflow, err := document.Parse(inputYaml).Workflow()
 	if err != nil {
 		return inputYaml, updated, fmt.Errorf("unable to parse yaml %v", err)
 	}
 
 	out := inputYaml
 
-	for jobName, job := range workflow.Jobs {
-
+	for _, jobName := range workflow.Jobs.Names() {
+		job := workflow.Jobs[jobName]
 		for _, step := range job.Steps {
 			if len(step.Uses) > 0 && strings.HasPrefix(step.Uses, "docker://") && !strings.Contains(step.Uses, "@") {
 				localUpdated := false
-				out, localUpdated = pinDocker(step.Uses, jobName, out)
+				out, localUpdated = pinDocker(ctx, step.Uses, jobName, out)
 				updated = updated || localUpdated
 			}
 		}
@@ -39,7 +38,7 @@ func PinDocker(inputYaml string) (string, bool, error) {
 	return out, updated, nil
 }
 
-func pinDocker(action, jobName, inputYaml string) (string, bool) {
+func pinDocker(ctx context.Context, action, jobName, inputYaml string) (string, bool) {
 	updated := false
 	leftOfAt := strings.Split(action, ":")
 	tag := "latest"
@@ -54,7 +53,7 @@ func pinDocker(action, jobName, inputYaml string) (string, bool) {
 		return inputYaml, updated
 	}
 
-	img, err := remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(Tr))
+	img, err := remote.Image(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(Tr))
 	if err != nil {
 		//TODO: Log the error
 		return inputYaml, updated
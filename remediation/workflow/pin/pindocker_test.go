@@ -1,6 +1,7 @@
 package pin
 
 import (
+	"context"
 	"io/ioutil"
 	"log"
 	"os"
@@ -67,7 +68,7 @@ func TestDockerActions(t *testing.T) {
 			log.Fatal(err)
 		}
 
-		output, _, err := PinDocker(string(input))
+		output, _, err := PinDocker(context.Background(), string(input))
 
 		if err != nil {
 			t.Errorf("Error not expected")
@@ -82,5 +83,10 @@ func TestDockerActions(t *testing.T) {
 		if output != string(expectedOutput) {
 			t.Errorf("test failed %s did not match expected output\n%s", f.Name(), output)
 		}
+
+		// the images of the output are pinned, so pinning them again does not change it
+		if again, againUpdated, err := PinDocker(context.Background(), output); err != nil || againUpdated || again != output {
+			t.Errorf("test failed %s changed when it was pinned again: %v, %v\n%s", f.Name(), againUpdated, err, again)
+		}
 	}
 }
@@ -0,0 +1,66 @@
+package pin
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/metrics"
+	"github.com/step-security/secure-repo/remediation/workflow/actionrepo"
+	"golang.org/x/oauth2"
+)
+
+// ipAllowListError is the error of the GitHub API when the IP allow list of the organization denies the token
+const ipAllowListError = "organization has an IP allow list enabled, and your IP address is not permitted to access this resource"
+
+// ResolvedRef is the commit a tag or branch of a repository points to, and the most specific tag of the commit, e.g.
+// v1.2.3 for v1, which is kept in the comment of a pinned ref
+type ResolvedRef struct {
+	CommitSHA string
+	Version   string
+}
+
+// RefCache returns the ref cached for the key, or resolves and caches it. It is set to the response cache by the
+// workflow package, since the packages compiled to WebAssembly cannot depend on the cache, and refs are looked up every
+// time when it is nil.
+var RefCache func(key string, resolve func() (*ResolvedRef, error)) (*ResolvedRef, error)
+
+// resolveRef returns the commit of the tag or branch, which is cached in RefCache, so the refs shared by the files of a
+// repository, and by the repositories of an organization, are looked up once
+func resolveRef(ctx context.Context, client *github.Client, owner, repo, tagOrBranch string) (*ResolvedRef, error) {
+	resolve := func() (*ResolvedRef, error) {
+		commitSHA, err := actionrepo.CommitSHA(ctx, client, owner, repo, tagOrBranch)
+		if err != nil {
+			return nil, err
+		}
+		version, err := getSemanticVersion(ctx, client, owner, repo, tagOrBranch, commitSHA)
+		if err != nil {
+			return nil, err
+		}
+		return &ResolvedRef{CommitSHA: commitSHA, Version: version}, nil
+	}
+	if RefCache == nil {
+		return resolve()
+	}
+	return RefCache(strings.ToLower(owner+"/"+repo)+"@"+tagOrBranch, resolve)
+}
+
+func newClient(ctx context.Context, PAT string) *github.Client {
+	return github.NewClient(oauth2.NewClient(metrics.WithGitHubClient(ctx), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: PAT})))
+}
+
+// ResolveRef returns the commit of a tag or branch of a GitHub repository, resolved and cached the same way as the refs
+// of actions, with the token in SECURE_REPO_PAT, or PAT if it is not set or the IP allow list of the organization
+// denies it
+func ResolveRef(ctx context.Context, owner, repo, tagOrBranch string) (*ResolvedRef, error) {
+	PAT := os.Getenv("SECURE_REPO_PAT")
+	if PAT == "" {
+		PAT = os.Getenv("PAT")
+	}
+	resolved, err := resolveRef(ctx, newClient(ctx, PAT), owner, repo, tagOrBranch)
+	if err != nil && strings.Contains(err.Error(), ipAllowListError) {
+		return resolveRef(ctx, newClient(ctx, os.Getenv("PAT")), owner, repo, tagOrBranch)
+	}
+	return resolved, err
+}
@@ -0,0 +1,40 @@
+package pin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/step-security/secure-repo/remediation/cache"
+)
+
+func TestResolveRef(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/pre-commit/pre-commit-hooks/commits/v4",
+		httpmock.NewStringResponder(http.StatusOK, `2c9f875913ee60ca25ce70243dc24d5b6415598c`))
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/pre-commit/pre-commit-hooks/git/matching-refs/tags",
+		httpmock.NewStringResponder(http.StatusOK, `[{"ref": "refs/tags/v4.6.0", "object": {"sha": "2c9f875913ee60ca25ce70243dc24d5b6415598c", "type": "commit"}}]`))
+
+	c := cache.New(time.Hour, cache.NewMemoryStore(10))
+	RefCache = func(key string, resolve func() (*ResolvedRef, error)) (*ResolvedRef, error) {
+		return cache.Do(c, cache.Key("resolve-ref", key), resolve)
+	}
+	defer func() { RefCache = nil }()
+	for i := 0; i < 2; i++ {
+		resolved, err := resolveRef(context.Background(), newClient(context.Background(), ""), "pre-commit", "pre-commit-hooks", "v4")
+		if err != nil {
+			t.Fatalf("resolveRef() returned error: %v", err)
+		}
+		if resolved.CommitSHA != "2c9f875913ee60ca25ce70243dc24d5b6415598c" || resolved.Version != "v4.6.0" {
+			t.Errorf("resolveRef() = %+v", resolved)
+		}
+	}
+	// the second lookup is served from the cache
+	if calls := httpmock.GetTotalCallCount(); calls != 2 {
+		t.Errorf("expected the ref to be looked up once with 2 calls, got %d calls", calls)
+	}
+}
@@ -0,0 +1,80 @@
+package pin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const RuleUnpinnedAction = "unpinned-action"
+
+// isUnpinned returns true if the action or reusable workflow is referenced by a tag or branch.
+// Local actions cannot be pinned, and exempted actions are not reported.
+func isUnpinned(uses string, exemptedActions []string) bool {
+	if strings.HasPrefix(uses, "docker://") {
+		return !strings.Contains(uses, "@sha256:")
+	}
+	if strings.HasPrefix(uses, "./") || !strings.Contains(uses, "@") {
+		return false
+	}
+	return !isAbsolute(uses) && !ActionExists(strings.Split(uses, "@")[0], exemptedActions)
+}
+
+func suggestion(uses string) string {
+	if strings.HasPrefix(uses, "docker://") {
+		return "Pin the docker image to a digest"
+	}
+	return fmt.Sprintf("Pin %s to a full length commit SHA", strings.Split(uses, "@")[0])
+}
+
+// FindUnpinnedActions returns findings for actions, reusable workflows and docker images that are not pinned
+// to a full length commit SHA or digest, in workflows and composite actions
+func FindUnpinnedActions(inputYaml string, exemptedActions []string) ([]findings.Finding, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return nil, nil
+	}
+	topNode := t.Content[0]
+
+	var unpinnedFindings []findings.Finding
+	addFinding := func(jobName string, usesNode *yaml.Node) {
+		if usesNode == nil || !isUnpinned(usesNode.Value, exemptedActions) {
+			return
+		}
+		unpinnedFindings = append(unpinnedFindings, findings.Finding{
+			RuleID:     RuleUnpinnedAction,
+			Message:    fmt.Sprintf("%s is not pinned to a full length commit SHA", usesNode.Value),
+			JobName:    jobName,
+			Action:     usesNode.Value,
+			Line:       usesNode.Line,
+			Column:     usesNode.Column,
+			Suggestion: suggestion(usesNode.Value),
+		})
+	}
+	addSteps := func(jobName string, stepsNode *yaml.Node) {
+		if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, stepNode := range stepsNode.Content {
+			addFinding(jobName, document.MappingValue(stepNode, "uses"))
+		}
+	}
+
+	if jobsNode := document.MappingValue(topNode, "jobs"); jobsNode != nil && jobsNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+			jobName, jobNode := jobsNode.Content[i].Value, jobsNode.Content[i+1]
+			// reusable workflows
+			addFinding(jobName, document.MappingValue(jobNode, "uses"))
+			addSteps(jobName, document.MappingValue(jobNode, "steps"))
+		}
+	}
+	addSteps("", document.MappingValue(document.MappingValue(topNode, "runs"), "steps"))
+
+	return unpinnedFindings, nil
+}
@@ -0,0 +1,41 @@
+package pin
+
+import "testing"
+
+func TestFindUnpinnedActions(t *testing.T) {
+	input := `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@d35c59abb061a4a6fb18e82ac0862c26744d6ab5
+      - uses: ./.github/actions/local
+      - uses: docker://alpine:3.19
+      - uses: docker://alpine@sha256:c5b1261d6d3e43071626931fc004f70149baeba2c8ec672bd4f27761f8e1ad6b
+      - uses: myorg/internal@main
+  call:
+    uses: octo-org/workflows/.github/workflows/build.yml@v1
+`
+	got, err := FindUnpinnedActions(input, []string{"myorg/*"})
+	if err != nil {
+		t.Fatalf("FindUnpinnedActions() unexpected error = %v", err)
+	}
+
+	expected := []string{"actions/checkout@v4", "docker://alpine:3.19", "octo-org/workflows/.github/workflows/build.yml@v1"}
+	if len(got) != len(expected) {
+		t.Fatalf("FindUnpinnedActions() returned %d findings, want %d: %v", len(got), len(expected), got)
+	}
+	for i, action := range expected {
+		if got[i].Action != action || got[i].RuleID != RuleUnpinnedAction {
+			t.Errorf("unexpected finding %+v, want action %s", got[i], action)
+		}
+	}
+	if got[0].JobName != "build" || got[0].Line != 7 || got[0].Column != 15 {
+		t.Errorf("unexpected location of finding %+v", got[0])
+	}
+	if got[2].JobName != "call" {
+		t.Errorf("unexpected job of reusable workflow finding %+v", got[2])
+	}
+}
@@ -0,0 +1,302 @@
+package pintools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/outbound"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+// Registry URLs are variables so tests can point them to a mock server
+var (
+	NpmRegistryURL = "https://registry.npmjs.org"
+	PyPIURL        = "https://pypi.org/pypi"
+	GoProxyURL     = "https://proxy.golang.org"
+)
+
+const (
+	ecosystemNpm = "npm"
+	ecosystemPip = "pip"
+	ecosystemGo  = "go"
+
+	versionComment = "# pinned to latest version"
+)
+
+var (
+	npmInstallRegex = regexp.MustCompile(`^\s*npm\s+(install|i|add)\s`)
+	pipInstallRegex = regexp.MustCompile(`^\s*(pip3?|python3?\s+-m\s+pip|pipx)\s+install\s`)
+	goInstallRegex  = regexp.MustCompile(`^\s*go\s+install\s`)
+
+	npmPackageRegex = regexp.MustCompile(`^(@[a-z0-9][a-z0-9._~-]*/)?[a-z0-9][a-z0-9._~-]*$`)
+	pipPackageRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(\[[A-Za-z0-9,._-]+\])?$`)
+	goPackageRegex  = regexp.MustCompile(`^([a-zA-Z0-9.-]+\.[a-z]+/[^@\s]+)@latest$`)
+
+	// shell operators that separate commands on a line
+	separatorRegex = regexp.MustCompile(`\s*(&&|\|\||;|\|)\s*`)
+)
+
+// pip options that take a value, which is not a package
+var pipOptionsWithValue = map[string]bool{
+	"-r": true, "--requirement": true, "-c": true, "--constraint": true, "-e": true, "--editable": true,
+	"-i": true, "--index-url": true, "--extra-index-url": true, "-f": true, "--find-links": true,
+	"-t": true, "--target": true, "--python": true, "--prefix": true, "--root": true,
+}
+
+// resolver looks up the latest versions of the tools of a workflow, with the context of its remediation
+type resolver struct {
+	ctx      context.Context
+	versions map[string]string
+}
+
+func getJSON(ctx context.Context, requestURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := outbound.Client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d for %s", resp.StatusCode, requestURL)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// escapeModulePath encodes upper case letters as required by the Go module proxy, e.g. BurntSushi as !burnt!sushi
+func escapeModulePath(modulePath string) string {
+	var builder strings.Builder
+	for _, r := range modulePath {
+		if r >= 'A' && r <= 'Z' {
+			builder.WriteRune('!')
+			builder.WriteRune(r + ('a' - 'A'))
+		} else {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// getLatestVersion returns the latest version of a package, or an empty string if it could not be resolved
+func (r *resolver) getLatestVersion(ecosystem, name string) string {
+	key := ecosystem + ":" + name
+	if version, found := r.versions[key]; found {
+		return version
+	}
+
+	version := ""
+	switch ecosystem {
+	case ecosystemNpm:
+		latest := struct {
+			Version string `json:"version"`
+		}{}
+		if err := getJSON(r.ctx, fmt.Sprintf("%s/%s/latest", NpmRegistryURL, strings.Replace(name, "/", "%2f", 1)), &latest); err == nil {
+			version = latest.Version
+		}
+	case ecosystemPip:
+		project := struct {
+			Info struct {
+				Version string `json:"version"`
+			} `json:"info"`
+		}{}
+		if err := getJSON(r.ctx, fmt.Sprintf("%s/%s/json", PyPIURL, url.PathEscape(name)), &project); err == nil {
+			version = project.Info.Version
+		}
+	case ecosystemGo:
+		// the package may be in a sub directory of the module, so try each parent path until a module is found
+		parts := strings.Split(name, "/")
+		for i := len(parts); i >= 2 && version == ""; i-- {
+			latest := struct {
+				Version string `json:"Version"`
+			}{}
+			modulePath := strings.Join(parts[:i], "/")
+			if err := getJSON(r.ctx, fmt.Sprintf("%s/%s/@latest", GoProxyURL, escapeModulePath(modulePath)), &latest); err == nil {
+				version = latest.Version
+			}
+		}
+	}
+
+	r.versions[key] = version
+	return version
+}
+
+// pinCommand returns the command with unpinned packages pinned to their latest version
+func (r *resolver) pinCommand(command string) string {
+	var ecosystem string
+	switch {
+	case npmInstallRegex.MatchString(command):
+		ecosystem = ecosystemNpm
+	case pipInstallRegex.MatchString(command):
+		ecosystem = ecosystemPip
+	case goInstallRegex.MatchString(command):
+		ecosystem = ecosystemGo
+	default:
+		return command
+	}
+
+	fields := strings.Fields(command)
+	if ecosystem == ecosystemNpm {
+		// only global installs, local installs are pinned in package.json
+		global := false
+		for _, field := range fields {
+			if field == "-g" || field == "--global" {
+				global = true
+			}
+		}
+		if !global {
+			return command
+		}
+	}
+
+	// packages are after the install sub command
+	installIndex := 0
+	for i, field := range fields {
+		if field == "install" || field == "i" || field == "add" {
+			installIndex = i
+			break
+		}
+	}
+
+	skipNext := false
+	for i, field := range fields {
+		if strings.HasPrefix(field, "#") {
+			break
+		}
+		if skipNext {
+			skipNext = false
+			continue
+		}
+		if i <= installIndex {
+			continue
+		}
+		if strings.HasPrefix(field, "-") {
+			skipNext = ecosystem == ecosystemPip && pipOptionsWithValue[field]
+			continue
+		}
+
+		pinned := ""
+		switch ecosystem {
+		case ecosystemNpm:
+			if npmPackageRegex.MatchString(field) {
+				if version := r.getLatestVersion(ecosystem, field); version != "" {
+					pinned = fmt.Sprintf("%s@%s", field, version)
+				}
+			}
+		case ecosystemPip:
+			if match := pipPackageRegex.FindStringSubmatch(field); match != nil {
+				if version := r.getLatestVersion(ecosystem, match[1]); version != "" {
+					pinned = fmt.Sprintf("%s==%s", field, version)
+				}
+			}
+		case ecosystemGo:
+			if match := goPackageRegex.FindStringSubmatch(field); match != nil {
+				if version := r.getLatestVersion(ecosystem, match[1]); version != "" {
+					pinned = fmt.Sprintf("%s@%s", match[1], version)
+				}
+			}
+		}
+		if pinned != "" {
+			fields[i] = pinned
+		}
+	}
+
+	pinnedCommand := strings.Join(fields, " ")
+	if strings.Join(strings.Fields(command), " ") == pinnedCommand {
+		return command
+	}
+	// keep the leading whitespace of the command
+	return command[:len(command)-len(strings.TrimLeft(command, " \t"))] + pinnedCommand
+}
+
+// pinLine pins the packages of each command on a line of a script
+func (r *resolver) pinLine(line string) string {
+	separators := separatorRegex.FindAllStringIndex(line, -1)
+	var builder strings.Builder
+	start := 0
+	for _, separator := range separators {
+		builder.WriteString(r.pinCommand(line[start:separator[0]]))
+		builder.WriteString(line[separator[0]:separator[1]])
+		start = separator[1]
+	}
+	builder.WriteString(r.pinCommand(line[start:]))
+	return builder.String()
+}
+
+func getRunNodes(node *yaml.Node) []*yaml.Node {
+	var runNodes []*yaml.Node
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, n := range node.Content {
+			runNodes = append(runNodes, getRunNodes(n)...)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "run" && node.Content[i+1].Kind == yaml.ScalarNode {
+				runNodes = append(runNodes, node.Content[i+1])
+			} else {
+				runNodes = append(runNodes, getRunNodes(node.Content[i+1])...)
+			}
+		}
+	}
+	return runNodes
+}
+
+// PinRunTools pins tools installed in run steps with npm install -g, pip install and go install ...@latest
+// to the latest version at the time of remediation, so later runs install the same version.
+func PinRunTools(ctx context.Context, inputYaml string) (string, bool, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+
+	r := &resolver{ctx: ctx, versions: make(map[string]string)}
+	inputLines := strings.Split(inputYaml, "\n")
+	buffer := textedit.NewBuffer(inputYaml)
+	updated := false
+
+	for _, runNode := range getRunNodes(t) {
+		isBlock := runNode.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0
+		// the script starts on the line after the block indicator
+		fileLine := runNode.Line - 1
+		if isBlock {
+			fileLine = runNode.Line
+		}
+
+		for _, scriptLine := range strings.Split(runNode.Value, "\n") {
+			pinnedLine := r.pinLine(scriptLine)
+			if pinnedLine != scriptLine {
+				// find the line in the file, skipping blank lines that are not in the scalar value
+				for i := fileLine; i < len(inputLines); i++ {
+					if index := strings.Index(inputLines[i], scriptLine); index != -1 {
+						line := inputLines[i][:index] + pinnedLine + inputLines[i][index+len(scriptLine):]
+						if !strings.HasSuffix(strings.TrimSpace(line), "\\") && runNode.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) == 0 {
+							line = line + " " + versionComment
+						}
+						buffer.ReplaceLine(i+1, line)
+						fileLine = i + 1
+						updated = true
+						break
+					}
+				}
+			} else if scriptLine != "" && isBlock {
+				for i := fileLine; i < len(inputLines); i++ {
+					if strings.Contains(inputLines[i], scriptLine) {
+						fileLine = i + 1
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return buffer.String(), updated, nil
+}
@@ -0,0 +1,80 @@
+package pintools
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestPinRunTools(t *testing.T) {
+	const inputDirectory = "../../../testfiles/pintools/input"
+	const outputDirectory = "../../../testfiles/pintools/output"
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://registry.npmjs.org/eslint/latest",
+		httpmock.NewStringResponder(200, `{"name": "eslint", "version": "9.3.0"}`))
+	httpmock.RegisterResponder("GET", "https://registry.npmjs.org/@angular%2fcli/latest",
+		httpmock.NewStringResponder(200, `{"name": "@angular/cli", "version": "18.0.1"}`))
+	httpmock.RegisterResponder("GET", "https://pypi.org/pypi/pip/json",
+		httpmock.NewStringResponder(200, `{"info": {"version": "24.0"}}`))
+	httpmock.RegisterResponder("GET", "https://pypi.org/pypi/black/json",
+		httpmock.NewStringResponder(200, `{"info": {"version": "24.4.2"}}`))
+	httpmock.RegisterResponder("GET", "https://proxy.golang.org/golang.org/x/tools/cmd/goimports/@latest",
+		httpmock.NewStringResponder(404, `not found`))
+	httpmock.RegisterResponder("GET", "https://proxy.golang.org/golang.org/x/tools/cmd/@latest",
+		httpmock.NewStringResponder(404, `not found`))
+	httpmock.RegisterResponder("GET", "https://proxy.golang.org/golang.org/x/tools/@latest",
+		httpmock.NewStringResponder(200, `{"Version": "v0.21.0"}`))
+	httpmock.RegisterResponder("GET", "https://proxy.golang.org/github.com/!burnt!sushi/toml/cmd/tomlv/@latest",
+		httpmock.NewStringResponder(404, `not found`))
+	httpmock.RegisterResponder("GET", "https://proxy.golang.org/github.com/!burnt!sushi/toml/cmd/@latest",
+		httpmock.NewStringResponder(404, `not found`))
+	httpmock.RegisterResponder("GET", "https://proxy.golang.org/github.com/!burnt!sushi/toml/@latest",
+		httpmock.NewStringResponder(200, `{"Version": "v1.3.2"}`))
+
+	tests := []struct {
+		fileName    string
+		wantUpdated bool
+	}{
+		{fileName: "run-tools.yml", wantUpdated: true},
+		{fileName: "no-tools.yml", wantUpdated: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			input, err := ioutil.ReadFile(path.Join(inputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			got, gotUpdated, err := PinRunTools(context.Background(), string(input))
+			if err != nil {
+				t.Errorf("PinRunTools() unexpected error = %v", err)
+			}
+
+			if gotUpdated != tt.wantUpdated {
+				t.Errorf("PinRunTools() updated = %v, wantUpdated %v", gotUpdated, tt.wantUpdated)
+			}
+
+			output, err := ioutil.ReadFile(path.Join(outputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			if got != string(output) {
+				t.Errorf("PinRunTools() = %v, want %v", got, string(output))
+			}
+
+			// the tools of the output are pinned, so pinning them again does not change it
+			again, againUpdated, err := PinRunTools(context.Background(), got)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("PinRunTools() of the output = %v, %v, want it unchanged\n%s", againUpdated, err, again)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+// Package policy decides which remediations apply to a workflow, and with what parameters, with Rego policies evaluated
+// by the OPA engine embedded in the service against the metadata of the workflow.
+//
+// A policy is a Rego document that returns the query parameters of the remediations, e.g.
+//
+//	package stepsecurity.remediations
+//
+//	import future.keywords.in
+//
+//	params["addHardenRunner"] := false {
+//		some job in input.workflow.jobs
+//		"self-hosted" in job.runs_on
+//	}
+//
+// Parameters returned by the policy override the parameters of the request.
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// environment variables with the Rego files of the policy, a file or a folder, and the path of the policy
+	// decision, which defaults to DefaultPath
+	FilesEnv = "OPA_POLICY_FILES"
+	PathEnv  = "OPA_POLICY_PATH"
+
+	DefaultPath = "stepsecurity/remediations"
+)
+
+// Job is the metadata of a job of the workflow
+type Job struct {
+	ID string `json:"id"`
+	// RunsOn are the runner labels of the job, which are empty for reusable workflows
+	RunsOn    []string `json:"runs_on"`
+	Container string   `json:"container,omitempty"`
+	// Uses is the reusable workflow called by the job
+	Uses    string   `json:"uses,omitempty"`
+	Actions []string `json:"actions"`
+}
+
+// Workflow is the metadata of the workflow
+type Workflow struct {
+	Name     string   `json:"name,omitempty"`
+	Triggers []string `json:"triggers"`
+	Jobs     []Job    `json:"jobs"`
+}
+
+// Input is the input document of the policy
+type Input struct {
+	Repository string   `json:"repository,omitempty"`
+	Path       string   `json:"path,omitempty"`
+	Workflow   Workflow `json:"workflow"`
+	// Params are the query parameters of the request
+	Params map[string]string `json:"params"`
+}
+
+// Decision is the result of the policy. Params are the query parameters that override the parameters of the request,
+// whose values can be strings, booleans or numbers in the policy.
+type Decision struct {
+	Params map[string]string
+}
+
+// Evaluator evaluates the policy for the input. It is implemented by RegoEvaluator, and can be implemented by programs
+// that embed secure-repo and decide the remediations otherwise.
+type Evaluator interface {
+	Evaluate(ctx context.Context, input *Input) (*Decision, error)
+}
+
+// RegoEvaluator evaluates the policy with the embedded OPA engine. The policy is compiled once, when the evaluator is
+// created, and the evaluator can be used concurrently.
+type RegoEvaluator struct {
+	query rego.PreparedEvalQuery
+}
+
+// NewRegoEvaluator compiles the Rego modules, by file name, and prepares the query of the decision at path, e.g.
+// stepsecurity/remediations
+func NewRegoEvaluator(ctx context.Context, path string, modules map[string]string) (*RegoEvaluator, error) {
+	options := []func(*rego.Rego){}
+	for name, module := range modules {
+		options = append(options, rego.Module(name, module))
+	}
+	return newRegoEvaluator(ctx, path, options...)
+}
+
+// LoadRegoEvaluator reads the Rego files of the paths, which are files or folders, and prepares the query of the
+// decision at path. The tests of the policy, in files ending with _test.rego, are not loaded.
+func LoadRegoEvaluator(ctx context.Context, path string, paths []string) (*RegoEvaluator, error) {
+	return newRegoEvaluator(ctx, path, rego.Load(paths, func(abspath string, info os.FileInfo, depth int) bool {
+		return !info.IsDir() && strings.HasSuffix(info.Name(), "_test.rego")
+	}))
+}
+
+func newRegoEvaluator(ctx context.Context, path string, options ...func(*rego.Rego)) (*RegoEvaluator, error) {
+	decision := "data." + strings.ReplaceAll(strings.Trim(path, "/"), "/", ".")
+	query, err := rego.New(append(options, rego.Query(decision))...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile policy: %v", err)
+	}
+	return &RegoEvaluator{query: query}, nil
+}
+
+// NewEvaluatorFromEnv returns a RegoEvaluator for the policy in OPA_POLICY_FILES, or nil if it is not set
+func NewEvaluatorFromEnv() (Evaluator, error) {
+	files := os.Getenv(FilesEnv)
+	if files == "" {
+		return nil, nil
+	}
+	path := os.Getenv(PathEnv)
+	if path == "" {
+		path = DefaultPath
+	}
+	evaluator, err := LoadRegoEvaluator(context.Background(), path, []string{files})
+	if err != nil {
+		return nil, err
+	}
+	return evaluator, nil
+}
+
+var defaultEvaluator = sync.OnceValues(NewEvaluatorFromEnv)
+
+// DefaultEvaluator returns the evaluator configured by the environment variables, or nil if policies are not
+// configured. The policy is compiled the first time it is called, and the error is returned on each call.
+func DefaultEvaluator() (Evaluator, error) {
+	return defaultEvaluator()
+}
+
+// Evaluate evaluates the policy for the input, and returns its decision. An undefined decision, e.g. when the policy has
+// no params, is an empty decision.
+func (e *RegoEvaluator) Evaluate(ctx context.Context, input *Input) (*Decision, error) {
+	// the input is converted to the JSON document of the policy, with the names of the json tags
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal policy input: %v", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, fmt.Errorf("unable to marshal policy input: %v", err)
+	}
+	results, err := e.query.Eval(ctx, rego.EvalInput(value))
+	if err != nil {
+		return nil, fmt.Errorf("unable to evaluate policy: %v", err)
+	}
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		return newDecision(nil)
+	}
+	result, ok := results[0].Expressions[0].Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("policy returned a %T, want an object", results[0].Expressions[0].Value)
+	}
+	params, ok := result["params"].(map[string]interface{})
+	if !ok && result["params"] != nil {
+		return nil, fmt.Errorf("policy returned a %T for params, want an object", result["params"])
+	}
+	return newDecision(params)
+}
+
+// newDecision returns the decision of the parameters returned by the policy, whose values are converted to the strings
+// of query parameters
+func newDecision(params map[string]interface{}) (*Decision, error) {
+	decision := &Decision{Params: make(map[string]string, len(params))}
+	for key, value := range params {
+		switch v := value.(type) {
+		case string:
+			decision.Params[key] = v
+		case bool:
+			decision.Params[key] = strconv.FormatBool(v)
+		case json.Number:
+			decision.Params[key] = v.String()
+		case float64:
+			decision.Params[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return nil, fmt.Errorf("policy returned a %T for parameter %s, want a string, boolean or number", value, key)
+		}
+	}
+	return decision, nil
+}
+
+// scalars returns the value of a scalar node, the values of a sequence, or the keys of a mapping
+func scalars(node *yaml.Node) []string {
+	values := []string{}
+	if node == nil {
+		return values
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		values = append(values, node.Value)
+	case yaml.SequenceNode:
+		for _, n := range node.Content {
+			if n = document.Resolve(n); n.Kind == yaml.ScalarNode {
+				values = append(values, n.Value)
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			values = append(values, node.Content[i].Value)
+		}
+	}
+	return values
+}
+
+// NewInput returns the input of the policy for the workflow and the query parameters of the request
+func NewInput(inputYaml, repository, path string, queryStringParams map[string]string) (*Input, error) {
+	doc, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml: %v", err)
+	}
+	input := &Input{Repository: repository, Path: path, Workflow: Workflow{Triggers: []string{}, Jobs: []Job{}}, Params: queryStringParams}
+	if len(doc.Content) == 0 {
+		return input, nil
+	}
+	topNode := doc.Content[0]
+	if name := document.MappingValue(topNode, "name"); name != nil {
+		input.Workflow.Name = name.Value
+	}
+	input.Workflow.Triggers = scalars(document.MappingValue(topNode, "on"))
+
+	jobsNode := document.MappingValue(topNode, "jobs")
+	if jobsNode == nil || jobsNode.Kind != yaml.MappingNode {
+		return input, nil
+	}
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobNode := jobsNode.Content[i+1]
+		job := Job{ID: jobsNode.Content[i].Value, Actions: []string{}}
+		runsOn := document.MappingValue(jobNode, "runs-on")
+		// runs-on can be a group and labels
+		if labels := document.MappingValue(runsOn, "labels"); labels != nil {
+			runsOn = labels
+		}
+		job.RunsOn = scalars(runsOn)
+		if container := document.MappingValue(jobNode, "container"); container != nil {
+			if image := document.MappingValue(container, "image"); image != nil {
+				container = image
+			}
+			job.Container = container.Value
+		}
+		if uses := document.MappingValue(jobNode, "uses"); uses != nil {
+			job.Uses = uses.Value
+		}
+		if steps := document.MappingValue(jobNode, "steps"); steps != nil {
+			for _, step := range steps.Content {
+				if uses := document.MappingValue(step, "uses"); uses != nil {
+					job.Actions = append(job.Actions, uses.Value)
+				}
+			}
+		}
+		input.Workflow.Jobs = append(input.Workflow.Jobs, job)
+	}
+	return input, nil
+}
@@ -0,0 +1,116 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const workflowInput = `name: CI
+on:
+  push:
+  pull_request_target:
+jobs:
+  build:
+    runs-on:
+      group: large
+      labels: [self-hosted, linux]
+    container:
+      image: node:20
+    steps:
+      - uses: actions/checkout@v4
+      - run: make build
+  release:
+    uses: octo-org/workflows/.github/workflows/release.yml@main
+`
+
+func TestNewInput(t *testing.T) {
+	input, err := NewInput(workflowInput, "octo-org/app", ".github/workflows/ci.yml", map[string]string{"pinActions": "false"})
+	if err != nil {
+		t.Fatalf("NewInput() returned error: %v", err)
+	}
+	want := &Input{Repository: "octo-org/app", Path: ".github/workflows/ci.yml", Params: map[string]string{"pinActions": "false"},
+		Workflow: Workflow{Name: "CI", Triggers: []string{"push", "pull_request_target"}, Jobs: []Job{
+			{ID: "build", RunsOn: []string{"self-hosted", "linux"}, Container: "node:20", Actions: []string{"actions/checkout@v4"}},
+			{ID: "release", RunsOn: []string{}, Uses: "octo-org/workflows/.github/workflows/release.yml@main", Actions: []string{}},
+		}}}
+	if !reflect.DeepEqual(input, want) {
+		t.Errorf("NewInput() = %+v, want %+v", input, want)
+	}
+
+	if _, err := NewInput("jobs: [", "", "", nil); err == nil {
+		t.Errorf("expected an error for invalid yaml")
+	}
+}
+
+const remediationsPolicy = `package stepsecurity.remediations
+
+import future.keywords.in
+
+params["addHardenRunner"] := false {
+	some job in input.workflow.jobs
+	"self-hosted" in job.runs_on
+}
+
+params["sbomFormat"] := "spdx" {
+	input.repository == "octo-org/app"
+}
+
+params["maxJobs"] := count(input.workflow.jobs) * 2.5
+
+params["exemptedActions"] := ["myorg/*"] {
+	input.params.exempt == "true"
+}
+`
+
+func TestRegoEvaluator(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "remediations.rego"), []byte(remediationsPolicy), 0644)
+	// the tests of the policy are not loaded
+	os.WriteFile(filepath.Join(dir, "remediations_test.rego"), []byte("package stepsecurity.remediations\n\ntest_invalid { not_defined }\n"), 0644)
+
+	t.Setenv(FilesEnv, dir)
+	evaluator, err := NewEvaluatorFromEnv()
+	if err != nil {
+		t.Fatalf("NewEvaluatorFromEnv() returned error: %v", err)
+	}
+	input, _ := NewInput(workflowInput, "octo-org/app", "", map[string]string{})
+	decision, err := evaluator.Evaluate(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Evaluate() returned error: %v", err)
+	}
+	want := map[string]string{"addHardenRunner": "false", "sbomFormat": "spdx", "maxJobs": "5"}
+	if !reflect.DeepEqual(decision.Params, want) {
+		t.Errorf("Evaluate() params = %v, want %v", decision.Params, want)
+	}
+
+	// an undefined decision does not change the parameters
+	evaluator, err = NewRegoEvaluator(context.Background(), "/stepsecurity/remediations/", map[string]string{"other.rego": "package other\n\nallow := true\n"})
+	if err != nil {
+		t.Fatalf("NewRegoEvaluator() returned error: %v", err)
+	}
+	if decision, err = evaluator.Evaluate(context.Background(), input); err != nil || len(decision.Params) != 0 {
+		t.Errorf("Evaluate() = %v, %v, want an empty decision", decision, err)
+	}
+
+	evaluator, _ = NewRegoEvaluator(context.Background(), DefaultPath, map[string]string{"remediations.rego": remediationsPolicy})
+	input.Params["exempt"] = "true"
+	if _, err = evaluator.Evaluate(context.Background(), input); err == nil {
+		t.Errorf("expected an error for a parameter that is not a string, boolean or number")
+	}
+
+	if _, err = NewRegoEvaluator(context.Background(), DefaultPath, map[string]string{"invalid.rego": "package stepsecurity.remediations\n\nparams[\"pinActions\"] := {\n"}); err == nil {
+		t.Errorf("expected an error for a policy that does not compile")
+	}
+	t.Setenv(FilesEnv, filepath.Join(dir, "missing.rego"))
+	if _, err = NewEvaluatorFromEnv(); err == nil {
+		t.Errorf("expected an error for missing policy files")
+	}
+
+	t.Setenv(FilesEnv, "")
+	if evaluator, err := NewEvaluatorFromEnv(); evaluator != nil || err != nil {
+		t.Errorf("expected no evaluator without %s, got %v, %v", FilesEnv, evaluator, err)
+	}
+}
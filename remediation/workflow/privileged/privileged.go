@@ -0,0 +1,123 @@
+package privileged
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RulePrivilegedContainer = "privileged-container"
+	RuleSysAdminCapability  = "container-sys-admin"
+	RuleDockerSocketMount   = "docker-socket-mount"
+)
+
+// dockerCommandRegex matches docker commands that start containers, including the options up to the end of the command
+var dockerCommandRegex = regexp.MustCompile(`docker\s+(?:container\s+)?(?:run|create)\b[^;&|\n]*`)
+
+type flagRule struct {
+	ruleID     string
+	regex      *regexp.Regexp
+	message    string
+	suggestion string
+}
+
+// flagRules are the container options that undermine the isolation of the container from the runner
+var flagRules = []flagRule{
+	{
+		ruleID:     RulePrivilegedContainer,
+		regex:      regexp.MustCompile(`--privileged(=true)?(\s|$)`),
+		message:    "runs a privileged container, which has full access to the runner",
+		suggestion: "Remove --privileged, and add only the capabilities the container needs with --cap-add",
+	},
+	{
+		ruleID:     RuleSysAdminCapability,
+		regex:      regexp.MustCompile(`--cap-add(=|\s+)["']?(CAP_)?(SYS_ADMIN|ALL)\b`),
+		message:    "adds the SYS_ADMIN capability to a container, which allows it to escape to the runner",
+		suggestion: "Remove --cap-add=SYS_ADMIN, and add only the specific capabilities the container needs",
+	},
+	{
+		ruleID:     RuleDockerSocketMount,
+		regex:      regexp.MustCompile(`(-v|--volume|--mount)(=|\s+)["']?[^\s]*/var/run/docker\.sock`),
+		message:    "mounts the Docker socket in a container, which gives it root access to the runner",
+		suggestion: "Do not mount /var/run/docker.sock, use rootless Docker-in-Docker or build images with BuildKit instead",
+	},
+}
+
+// check returns findings for the container options, where is used in the message, e.g. Step in job build
+func check(options, where, jobName string, node *yaml.Node) []findings.Finding {
+	var optionFindings []findings.Finding
+	for _, rule := range flagRules {
+		if !rule.regex.MatchString(options) {
+			continue
+		}
+		optionFindings = append(optionFindings, findings.Finding{
+			RuleID:     rule.ruleID,
+			Message:    fmt.Sprintf("%s %s", where, rule.message),
+			JobName:    jobName,
+			Line:       node.Line,
+			Column:     node.Column,
+			Suggestion: rule.suggestion,
+		})
+	}
+	return optionFindings
+}
+
+// FindPrivilegedContainers returns findings for docker run commands in run steps, and options of job containers and services,
+// that run privileged containers, add the SYS_ADMIN capability or mount the Docker socket.
+// On shared runners, these containers can access the runner and other jobs.
+func FindPrivilegedContainers(inputYaml string) ([]findings.Finding, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return nil, nil
+	}
+	topNode := t.Content[0]
+
+	var privilegedFindings []findings.Finding
+	checkSteps := func(jobName string, stepsNode *yaml.Node) {
+		if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, stepNode := range stepsNode.Content {
+			runKeyNode, runNode := document.MappingEntry(stepNode, "run")
+			if runNode == nil || runNode.Kind != yaml.ScalarNode {
+				continue
+			}
+			// line continuations are joined, so options on the following lines are part of the command
+			script := strings.ReplaceAll(runNode.Value, "\\\n", " ")
+			for _, command := range dockerCommandRegex.FindAllString(script, -1) {
+				privilegedFindings = append(privilegedFindings, check(command, "Step", jobName, runKeyNode)...)
+			}
+		}
+	}
+
+	if jobsNode := document.MappingValue(topNode, "jobs"); jobsNode != nil && jobsNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+			jobName, jobNode := jobsNode.Content[i].Value, jobsNode.Content[i+1]
+
+			if optionsKeyNode, optionsNode := document.MappingEntry(document.MappingValue(jobNode, "container"), "options"); optionsNode != nil {
+				privilegedFindings = append(privilegedFindings, check(optionsNode.Value, "Job container", jobName, optionsKeyNode)...)
+			}
+			if servicesNode := document.MappingValue(jobNode, "services"); servicesNode != nil && servicesNode.Kind == yaml.MappingNode {
+				for j := 0; j+1 < len(servicesNode.Content); j += 2 {
+					if optionsKeyNode, optionsNode := document.MappingEntry(servicesNode.Content[j+1], "options"); optionsNode != nil {
+						where := fmt.Sprintf("Service %s", servicesNode.Content[j].Value)
+						privilegedFindings = append(privilegedFindings, check(optionsNode.Value, where, jobName, optionsKeyNode)...)
+					}
+				}
+			}
+
+			checkSteps(jobName, document.MappingValue(jobNode, "steps"))
+		}
+	}
+	checkSteps("", document.MappingValue(document.MappingValue(topNode, "runs"), "steps"))
+
+	return privilegedFindings, nil
+}
@@ -0,0 +1,43 @@
+package privileged
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestFindPrivilegedContainers(t *testing.T) {
+	const inputDirectory = "../../../testfiles/privileged/input"
+
+	input, err := ioutil.ReadFile(path.Join(inputDirectory, "privileged-containers.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+
+	got, err := FindPrivilegedContainers(string(input))
+	if err != nil {
+		t.Fatalf("FindPrivilegedContainers() unexpected error = %v", err)
+	}
+
+	want := []struct {
+		ruleID string
+		line   int
+	}{
+		{ruleID: RulePrivilegedContainer, line: 9},
+		{ruleID: RuleSysAdminCapability, line: 13},
+		{ruleID: RuleDockerSocketMount, line: 13},
+		{ruleID: RuleDockerSocketMount, line: 18},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("FindPrivilegedContainers() returned %d findings, want %d: %v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].RuleID != w.ruleID || got[i].Line != w.line || got[i].JobName != "test" {
+			t.Errorf("unexpected finding %d: %+v", i, got[i])
+		}
+	}
+	if got[1].Message != "Service dind adds the SYS_ADMIN capability to a container, which allows it to escape to the runner" {
+		t.Errorf("unexpected message: %s", got[1].Message)
+	}
+}
@@ -0,0 +1,334 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/logging"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/score"
+	"github.com/step-security/secure-repo/remediation/workflow/actionpolicy"
+	"github.com/step-security/secure-repo/remediation/workflow/advisories"
+	"github.com/step-security/secure-repo/remediation/workflow/equivalence"
+	"github.com/step-security/secure-repo/remediation/workflow/hardenrunner"
+	"github.com/step-security/secure-repo/remediation/workflow/maintainedactions"
+	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+	"github.com/step-security/secure-repo/remediation/workflow/pintools"
+	"github.com/step-security/secure-repo/remediation/workflow/policy"
+	"github.com/step-security/secure-repo/remediation/workflow/remediators"
+	"github.com/step-security/secure-repo/remediation/workflow/unmaintained"
+)
+
+// Options are the parameters of SecureWorkflow besides the query parameters of the request
+type Options struct {
+	// ExemptedActions are the actions that are not pinned, which can end with a wildcard
+	ExemptedActions []string
+	// PinToImmutable pins the actions that publish immutable releases to their semantic version
+	PinToImmutable bool
+	// MaintainedActions are the maintained actions suggested to replace unmaintained actions, which are loaded from
+	// the knowledge base if they are empty
+	MaintainedActions map[string]string
+	// ActionCommits are the commits the actions are pinned to, instead of the ones looked up with the GitHub API
+	ActionCommits map[string]string
+	// RunnerLabels replace the labels of runs-on
+	RunnerLabels       map[string]string
+	HardenRunnerConfig hardenrunner.HardenRunnerConfig
+	// ActionPolicy is the action policy, instead of the one in the query parameter actionPolicy
+	ActionPolicy *actionpolicy.ActionPolicy
+	// Logger writes the logs, e.g. with the id of the request, instead of the logger of the package logging
+	Logger *slog.Logger
+	// Evaluator decides the query parameters with a Rego policy, instead of the policy in OPA_POLICY_FILES
+	Evaluator policy.Evaluator
+}
+
+// options are the query parameters of a request, and the parameters passed to SecureWorkflow
+type options struct {
+	queryStringParams  map[string]string
+	exemptedActions    []string
+	pinToImmutable     bool
+	maintainedActions  map[string]string
+	actionCommits      map[string]string
+	runnerLabels       map[string]string
+	hardenRunnerConfig hardenrunner.HardenRunnerConfig
+	actionPolicy       *actionpolicy.ActionPolicy
+	// suggestedReplacements are the maintained actions suggested to replace unmaintained actions
+	suggestedReplacements map[string]string
+	svc                   dynamodbiface.DynamoDBAPI
+	logger                *slog.Logger
+	// ctx is the context of the request, which cancels the requests to GitHub and the other services when it is done
+	ctx context.Context
+}
+
+// newOptions returns the options of the query parameters and of the parameters passed to SecureWorkflow
+func newOptions(ctx context.Context, queryStringParams map[string]string, svc dynamodbiface.DynamoDBAPI, params Options) (*options, error) {
+	opts := &options{queryStringParams: queryStringParams, exemptedActions: params.ExemptedActions, pinToImmutable: params.PinToImmutable,
+		maintainedActions: params.MaintainedActions, actionCommits: params.ActionCommits, runnerLabels: params.RunnerLabels,
+		hardenRunnerConfig: params.HardenRunnerConfig, actionPolicy: params.ActionPolicy, svc: svc, logger: params.Logger, ctx: ctx}
+	if opts.exemptedActions == nil {
+		opts.exemptedActions = []string{}
+	}
+	if opts.maintainedActions == nil {
+		opts.maintainedActions = map[string]string{}
+	}
+	if opts.actionCommits == nil {
+		opts.actionCommits = map[string]string{}
+	}
+	if opts.runnerLabels == nil {
+		opts.runnerLabels = map[string]string{}
+	}
+	if opts.logger == nil {
+		opts.logger = logging.Logger()
+	}
+	// the policy is passed as JSON, if it is not passed as a parameter
+	if policyJSON, ok := queryStringParams["actionPolicy"]; ok && opts.actionPolicy == nil {
+		policy, err := actionpolicy.ParseActionPolicy(policyJSON)
+		if err != nil {
+			return nil, err
+		}
+		opts.actionPolicy = policy
+	}
+	opts.suggestedReplacements = opts.maintainedActions
+	return opts, nil
+}
+
+// applyPolicy returns a copy of the query parameters with the parameters decided by the policy, which is evaluated by the
+// evaluator of the options, or by the evaluator of the policy in OPA_POLICY_FILES, with the context of the request. The
+// query parameters are returned unchanged if there is no policy.
+func applyPolicy(ctx context.Context, queryStringParams map[string]string, inputYaml string, params Options) (map[string]string, error) {
+	evaluator := params.Evaluator
+	if evaluator == nil {
+		var err error
+		if evaluator, err = policy.DefaultEvaluator(); err != nil {
+			return nil, err
+		}
+	}
+	if evaluator == nil {
+		return queryStringParams, nil
+	}
+	input, err := policy.NewInput(inputYaml, remediators.Repository(queryStringParams), queryStringParams["path"], queryStringParams)
+	if err != nil {
+		return nil, err
+	}
+	decision, err := evaluator.Evaluate(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	policyParams := make(map[string]string, len(queryStringParams)+len(decision.Params))
+	for key, value := range queryStringParams {
+		policyParams[key] = value
+	}
+	for key, value := range decision.Params {
+		policyParams[key] = value
+	}
+	return policyParams, nil
+}
+
+func (o *options) isSet(param string) bool {
+	return o.queryStringParams[param] == "true"
+}
+
+// check returns whether the check enabled by the query parameter check runs, and whether its findings are fixed, which the
+// query parameter fix enables along with the check
+func (o *options) check(check, fix string) (bool, bool) {
+	fixed := o.isSet(fix)
+	return o.isSet(check) || fixed, fixed
+}
+
+// builtinRemediations returns the built-in remediations enabled by the options, the ones that run before the registered
+// remediators and the ones that run after them. The remediations that look up the actions and images are passed to
+// remediators.Builtin, which runs them along with the ones that do not.
+func builtinRemediations(opts *options) (before, after []remediators.Remediation) {
+	builtinOpts := remediators.Options{Params: opts.queryStringParams, RunnerLabels: opts.runnerLabels, HardenRunnerConfig: opts.hardenRunnerConfig,
+		PinToImmutable: opts.pinToImmutable, Ctx: opts.ctx}
+	add := func(list []remediators.Remediation, enabled, fix bool, remediator Remediator) []remediators.Remediation {
+		if !enabled {
+			return list
+		}
+		return append(list, remediators.Remediation{Remediator: remediator, Fix: fix})
+	}
+	replaceByMajorTag := opts.isSet("replaceActionByMajorTag")
+
+	builtinOpts.Permissions = &permissionsRemediator{
+		addEmptyTopLevelPermissions: opts.isSet("addEmptyTopLevelPermissions"), addProjectComment: opts.queryStringParams["addProjectComment"] != "false",
+		storeMissingActions: !opts.isSet("ignoreMissingKBs"), svc: opts.svc}
+	checked, fixed := opts.check("checkUnmaintainedActions", "replaceUnmaintainedActions")
+	builtinOpts.Actions = add(builtinOpts.Actions, checked, fixed, remediators.FindFix{Module: "unmaintained", Changes: remediators.UsesEdits,
+		Find: func(inputYaml string) ([]findings.Finding, error) {
+			return unmaintained.FindUnmaintainedActions(opts.ctx, inputYaml, opts.suggestedReplacements)
+		},
+		Fix: func(inputYaml string, detected []findings.Finding) (string, bool, error) {
+			return unmaintained.ReplaceUnmaintainedActions(opts.ctx, inputYaml, detected, replaceByMajorTag)
+		}})
+	checked, fixed = opts.check("checkVulnerableActions", "fixVulnerableActions")
+	builtinOpts.Actions = add(builtinOpts.Actions, checked, fixed, remediators.FindFix{Module: "advisories", Changes: remediators.UsesEdits,
+		Find: func(inputYaml string) ([]findings.Finding, error) {
+			return advisories.FindVulnerableActions(opts.ctx, inputYaml)
+		},
+		Fix: func(inputYaml string, detected []findings.Finding) (string, bool, error) {
+			return advisories.FixVulnerableActions(opts.ctx, inputYaml, detected, opts.exemptedActions, opts.pinToImmutable)
+		}})
+	builtinOpts.Actions = add(builtinOpts.Actions, opts.actionPolicy != nil, opts.isSet("replaceDisallowedActions"), remediators.FindFix{Module: "actionpolicy", Changes: remediators.UsesEdits,
+		Find: func(inputYaml string) ([]findings.Finding, error) {
+			return actionpolicy.FindPolicyViolations(inputYaml, opts.actionPolicy)
+		},
+		Fix: func(inputYaml string, detected []findings.Finding) (string, bool, error) {
+			return actionpolicy.ReplaceDisallowedActions(opts.ctx, inputYaml, detected, replaceByMajorTag)
+		}})
+	builtinOpts.Actions = add(builtinOpts.Actions, len(opts.maintainedActions) > 0, true, remediators.FindFix{Module: "maintainedactions", Changes: remediators.UsesEdits,
+		Fix: remediators.Changer(func(inputYaml string) (string, bool, error) {
+			return maintainedactions.ReplaceActions(opts.ctx, inputYaml, opts.maintainedActions, replaceByMajorTag)
+		})})
+
+	builtinOpts.Pins = add(builtinOpts.Pins, opts.queryStringParams["pinActions"] != "false", true,
+		&pinRemediator{ctx: opts.ctx, exemptedActions: opts.exemptedActions, pinToImmutable: opts.pinToImmutable, actionCommits: opts.actionCommits})
+	builtinOpts.Pins = add(builtinOpts.Pins, opts.isSet("pinRunTools"), true, remediators.FindFix{Module: "pintools", Safe: true, Changes: remediators.RunEdits,
+		Fix: remediators.Changer(func(inputYaml string) (string, bool, error) {
+			return pintools.PinRunTools(opts.ctx, inputYaml)
+		})})
+	// harden-runner is always pinned, unless it is exempted
+	if !pin.ActionExists(HardenRunnerActionPath, opts.exemptedActions) {
+		builtinOpts.HardenRunnerPinner = pin.ActionPinner{}
+	}
+	return remediators.Builtin(builtinOpts)
+}
+
+// permissionsRemediator adds the permissions of the jobs, and the permissions of the workflow. The response of the
+// permissions module has the errors of the jobs, and the actions missing from the knowledge base.
+type permissionsRemediator struct {
+	addEmptyTopLevelPermissions bool
+	addProjectComment           bool
+	storeMissingActions         bool
+	svc                         dynamodbiface.DynamoDBAPI
+	response                    *permissions.SecureWorkflowReponse
+}
+
+func (r *permissionsRemediator) Name() string {
+	return "permissions"
+}
+
+func (r *permissionsRemediator) Edits() *equivalence.Edits {
+	return &equivalence.Edits{Keys: []string{"permissions", "jobs.*.permissions"}}
+}
+
+func (r *permissionsRemediator) Detect(inputYaml string) ([]findings.Finding, error) {
+	return nil, nil
+}
+
+// Apply adds the permissions of the jobs, and the permissions of the workflow if all jobs have their permissions or the
+// errors of the jobs are that they already have permissions. The workflow is changed only if the permissions of the
+// workflow are added.
+func (r *permissionsRemediator) Apply(inputYaml string, detected []findings.Finding) (string, bool, error) {
+	response, err := permissions.AddJobLevelPermissions(inputYaml, r.addEmptyTopLevelPermissions)
+	if err != nil {
+		return "", false, err
+	}
+	r.response = response
+	if len(response.MissingActions) > 0 && r.storeMissingActions {
+		StoreMissingActions(response.MissingActions, r.svc)
+	}
+	if response.HasErrors && !permissions.ShouldAddWorkflowLevelPermissions(response.JobErrors) {
+		return response.FinalOutput, false, nil
+	}
+	output, err := permissions.AddWorkflowLevelPermissions(response.FinalOutput, r.addProjectComment, r.addEmptyTopLevelPermissions)
+	if err != nil {
+		return response.FinalOutput, false, err
+	}
+	// the errors of the jobs were that they already had permissions, and the permissions of the workflow were added
+	response.HasErrors = false
+	return output, true, nil
+}
+
+func (r *permissionsRemediator) Report(workflowReport *report.Report, path string, detected []findings.Finding) {
+	if r.response == nil {
+		return
+	}
+	for _, jobError := range r.response.JobErrors {
+		workflowReport.AddSkipped(r.Name(), report.Skipped{File: path, Item: jobError.JobName, Reason: strings.Join(jobError.Errors, ", ")})
+	}
+}
+
+// pinRemediator pins the actions and the docker images, and reports the ones that are still not pinned
+type pinRemediator struct {
+	ctx             context.Context
+	exemptedActions []string
+	pinToImmutable  bool
+	actionCommits   map[string]string
+	unpinned        []findings.Finding
+}
+
+func (r *pinRemediator) Name() string {
+	return "pin"
+}
+
+// Confidence is safe, since the actions and images are pinned to the versions they run
+func (r *pinRemediator) Confidence() string {
+	return report.ConfidenceSafe
+}
+
+func (r *pinRemediator) Edits() *equivalence.Edits {
+	return remediators.UsesEdits
+}
+
+func (r *pinRemediator) Detect(inputYaml string) ([]findings.Finding, error) {
+	return nil, nil
+}
+
+// Apply pins the actions and the docker images. The actions that cannot be pinned are returned as the error, along with
+// the workflow with the others pinned. The errors of pinning the docker images are ignored.
+func (r *pinRemediator) Apply(inputYaml string, detected []findings.Finding) (string, bool, error) {
+	output, pinnedActions, err := pin.PinActions(r.ctx, inputYaml, r.exemptedActions, r.pinToImmutable, r.actionCommits)
+	output, pinnedImages, _ := pin.PinDocker(r.ctx, output)
+	// actions that are still not pinned were exempted, or the commit or digest was not found
+	r.unpinned, _ = pin.FindUnpinnedActions(output, nil)
+	return output, pinnedActions || pinnedImages, err
+}
+
+func (r *pinRemediator) Report(workflowReport *report.Report, path string, detected []findings.Finding) {
+	for _, finding := range r.unpinned {
+		reason := "unable to find the commit for the tag"
+		if strings.HasPrefix(finding.Action, "docker://") {
+			reason = "unable to find the digest of the image"
+		} else if pin.ActionExists(strings.Split(finding.Action, "@")[0], r.exemptedActions) {
+			reason = "exempted"
+		}
+		workflowReport.AddSkipped(r.Name(), report.Skipped{File: path, Line: finding.Line, Item: finding.Action, Reason: reason})
+	}
+}
+
+// getAnalyzers returns the analyzers, which are enabled by the query parameters of AnalyzerParams
+func getAnalyzers(opts *options) []remediators.Analyzer {
+	return remediators.Analyzers(func(inputYaml string) ([]findings.Finding, error) {
+		return pin.FindUnpinnedActions(inputYaml, opts.exemptedActions)
+	})
+}
+
+// getScore returns the score of the workflow, with the findings of the analyzers
+func getScore(opts *options, inputYaml string) (score.Score, error) {
+	var detected []findings.Finding
+	for _, analyzer := range getAnalyzers(opts) {
+		analyzerFindings, err := analyzer.Find(inputYaml)
+		if err != nil {
+			return score.Score{}, err
+		}
+		detected = append(detected, analyzerFindings...)
+	}
+	return score.Compute(inputYaml, detected)
+}
+
+// getScoreChange returns the change of the score of the workflow made by the remediations. With dryRun=true, the output
+// has the proposed changes.
+func getScoreChange(opts *options, inputYaml, output string) (*score.ScoreChange, error) {
+	before, err := getScore(opts, inputYaml)
+	if err != nil {
+		return nil, err
+	}
+	after, err := getScore(opts, output)
+	if err != nil {
+		return nil, err
+	}
+	return score.NewScoreChange(before, after), nil
+}
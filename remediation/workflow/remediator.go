@@ -0,0 +1,81 @@
+package workflow
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/step-security/secure-repo/remediation/workflow/remediators"
+)
+
+// Remediator is a remediation of workflows, see remediators.Remediator
+type Remediator = remediators.Remediator
+
+// ConfidenceReporter is implemented by remediators that report the confidence of their changes, see
+// remediators.ConfidenceReporter
+type ConfidenceReporter = remediators.ConfidenceReporter
+
+// RemediationChecker is implemented by remediators that check whether a workflow is already remediated without fixing
+// it. A workflow is remediated by the remediators that do not implement it if fixing it does not change it.
+type RemediationChecker interface {
+	IsRemediated(inputYaml string) (bool, error)
+}
+
+// isRemediated returns whether the remediation leaves the workflow unchanged, which for a remediation that only detects
+// findings is whether the workflow has none
+func isRemediated(r remediators.Remediation, inputYaml string) (bool, error) {
+	if checker, ok := r.Remediator.(RemediationChecker); ok {
+		return checker.IsRemediated(inputYaml)
+	}
+	detected, err := r.Remediator.Detect(inputYaml)
+	if err != nil {
+		return false, err
+	}
+	if !r.Fix {
+		return len(detected) == 0, nil
+	}
+	output, _, err := r.Remediator.Apply(inputYaml, detected)
+	if err != nil {
+		return false, err
+	}
+	return output == "" || output == inputYaml, nil
+}
+
+var (
+	registeredMutex sync.RWMutex
+	registered      []Remediator
+)
+
+// RegisterRemediator adds a remediator that runs on every workflow, after the built-in remediations that change the workflow,
+// and before permissions are added and actions are pinned, so the actions it adds are secured as well. Remediators run in
+// the order they are registered, and a remediator is disabled by setting the query parameter with its name to false.
+func RegisterRemediator(remediator Remediator) error {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+	for _, other := range registered {
+		if other.Name() == remediator.Name() {
+			return fmt.Errorf("remediator %s is already registered", remediator.Name())
+		}
+	}
+	registered = append(registered, remediator)
+	return nil
+}
+
+func getRemediators() []Remediator {
+	registeredMutex.RLock()
+	defer registeredMutex.RUnlock()
+	return append([]Remediator{}, registered...)
+}
+
+// getRemediations returns the remediations enabled by the options, in the order they run: the built-in remediations that
+// change the workflow, the registered remediators, and the built-in remediations that secure the actions, e.g. add
+// permissions and pin actions, so the actions added by the others are secured as well
+func getRemediations(opts *options) []remediators.Remediation {
+	before, after := builtinRemediations(opts)
+	remediations := before
+	for _, remediator := range getRemediators() {
+		if opts.queryStringParams[remediator.Name()] != "false" {
+			remediations = append(remediations, remediators.Remediation{Remediator: remediator, Fix: true})
+		}
+	}
+	return append(remediations, after...)
+}
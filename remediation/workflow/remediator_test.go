@@ -0,0 +1,85 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/report"
+)
+
+// runnerRemediator replaces a deprecated runner label
+type runnerRemediator struct{}
+
+func (runnerRemediator) Name() string {
+	return "deprecatedrunner"
+}
+
+func (runnerRemediator) Detect(inputYaml string) ([]findings.Finding, error) {
+	var detected []findings.Finding
+	for i, line := range strings.Split(inputYaml, "\n") {
+		if column := strings.Index(line, "ubuntu-18.04"); column != -1 {
+			detected = append(detected, findings.Finding{RuleID: "deprecated-runner", Message: "ubuntu-18.04 is deprecated", Line: i + 1, Column: column + 1})
+		}
+	}
+	return detected, nil
+}
+
+func (runnerRemediator) Apply(inputYaml string, detected []findings.Finding) (string, bool, error) {
+	for i := range detected {
+		detected[i].Fixed = true
+	}
+	output := strings.ReplaceAll(inputYaml, "ubuntu-18.04", "ubuntu-latest")
+	return output, output != inputYaml, nil
+}
+
+func (r runnerRemediator) Report(workflowReport *report.Report, path string, detected []findings.Finding) {
+	workflowReport.AddUnfixed(r.Name(), path, detected)
+}
+
+func TestRegisterRemediator(t *testing.T) {
+	defer func() { registered = nil }()
+	if err := RegisterRemediator(runnerRemediator{}); err != nil {
+		t.Fatalf("RegisterRemediator() unexpected error = %v", err)
+	}
+	if err := RegisterRemediator(runnerRemediator{}); err == nil {
+		t.Errorf("RegisterRemediator() expected error for a duplicate name")
+	}
+
+	input := `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-18.04
+    steps:
+      - run: make build
+`
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	queryParams := map[string]string{"addHardenRunner": "false", "pinActions": "false", "addPermissions": "false"}
+
+	output, err := SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if output.FinalOutput != strings.Replace(input, "ubuntu-18.04", "ubuntu-latest", 1) {
+		t.Errorf("unexpected output\n%s", output.FinalOutput)
+	}
+	if len(output.Findings) != 1 || output.Findings[0].RuleID != "deprecated-runner" || !output.Findings[0].Fixed {
+		t.Errorf("unexpected findings %+v", output.Findings)
+	}
+	if len(output.Report.Modules) != 1 || output.Report.Modules[0].Name != "deprecatedrunner" || len(output.Report.Modules[0].Changes) != 1 {
+		t.Errorf("unexpected report %+v", output.Report)
+	}
+
+	// the remediator is disabled with its name
+	queryParams["deprecatedrunner"] = "false"
+	output, err = SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if output.FinalOutput != input || len(output.Findings) != 0 {
+		t.Errorf("expected the remediator not to run, got\n%s", output.FinalOutput)
+	}
+}
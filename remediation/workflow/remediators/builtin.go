@@ -0,0 +1,177 @@
+package remediators
+
+import (
+	"context"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/attestation"
+	"github.com/step-security/secure-repo/remediation/workflow/buildargs"
+	"github.com/step-security/secure-repo/remediation/workflow/deprecatedcommands"
+	"github.com/step-security/secure-repo/remediation/workflow/dispatchinputs"
+	"github.com/step-security/secure-repo/remediation/workflow/equivalence"
+	"github.com/step-security/secure-repo/remediation/workflow/forkguard"
+	"github.com/step-security/secure-repo/remediation/workflow/githubenv"
+	"github.com/step-security/secure-repo/remediation/workflow/githubtoken"
+	"github.com/step-security/secure-repo/remediation/workflow/hardenrunner"
+	"github.com/step-security/secure-repo/remediation/workflow/metadata"
+	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+	"github.com/step-security/secure-repo/remediation/workflow/privileged"
+	"github.com/step-security/secure-repo/remediation/workflow/repoguard"
+	"github.com/step-security/secure-repo/remediation/workflow/runnerlabel"
+	"github.com/step-security/secure-repo/remediation/workflow/sbom"
+	"github.com/step-security/secure-repo/remediation/workflow/scriptinjection"
+	"github.com/step-security/secure-repo/remediation/workflow/shelldefaults"
+	"github.com/step-security/secure-repo/remediation/workflow/signing"
+	"github.com/step-security/secure-repo/remediation/workflow/triggers"
+	"github.com/step-security/secure-repo/remediation/workflow/typosquat"
+)
+
+// Options are the query parameters of a request, and the remediations that need the network or the state of the handler,
+// which are nil for the preview
+type Options struct {
+	Params             map[string]string
+	RunnerLabels       map[string]string
+	HardenRunnerConfig hardenrunner.HardenRunnerConfig
+	// HardenRunnerPinner pins harden-runner when it is added, which is added with its tag if it is nil
+	HardenRunnerPinner hardenrunner.Pinner
+	PinToImmutable     bool
+	Ctx                context.Context
+
+	// Permissions adds the permissions of the jobs and of the workflow, unless the query parameter addPermissions is false
+	Permissions Remediator
+	// Actions are the remediations that check the actions with lookups, e.g. of the repositories of the actions, which run
+	// after the typosquatted actions are corrected
+	Actions []Remediation
+	// Pins are the remediations that pin the actions and the tools, which run before harden-runner is added
+	Pins []Remediation
+}
+
+// IsSet returns whether the query parameter is true
+func (o Options) IsSet(param string) bool {
+	return o.Params[param] == "true"
+}
+
+// Check returns whether the check enabled by the query parameter check runs, and whether its findings are fixed, which the
+// query parameter fix enables along with the check
+func (o Options) Check(check, fix string) (bool, bool) {
+	fixed := o.IsSet(fix)
+	return o.IsSet(check) || fixed, fixed
+}
+
+// Repository returns the owner and repo the workflow was fetched from, if any
+func Repository(params map[string]string) string {
+	if params["owner"] == "" || params["repo"] == "" {
+		return ""
+	}
+	return params["owner"] + "/" + params["repo"]
+}
+
+// Builtin returns the built-in remediations enabled by the options, the ones that run before the registered remediators
+// and the ones that run after them. The remediations of the options that need the network are run in their place, so the
+// preview runs the same remediations in the same order, without them.
+func Builtin(opts Options) (before, after []Remediation) {
+	add := func(list []Remediation, enabled, fix bool, remediator Remediator) []Remediation {
+		if !enabled {
+			return list
+		}
+		return append(list, Remediation{Remediator: remediator, Fix: fix})
+	}
+
+	before = add(before, opts.IsSet("removeUnnecessaryTokens"), true,
+		FindFix{Module: "githubtoken", Safe: true, Changes: &equivalence.Edits{Keys: StepKeys("with")},
+			Fix: Changer(githubtoken.RemoveUnnecessaryTokenInputs)})
+	checked, fixed := opts.Check("checkDispatchInputs", "fixDispatchInputs")
+	before = add(before, checked, fixed, FindFix{Module: "dispatchinputs", Find: dispatchinputs.FindUnsafeDispatchInputs,
+		Changes: &equivalence.Edits{Keys: StepKeys("run", "env", "with")}, Fix: dispatchinputs.FixUnsafeDispatchInputs})
+	before = add(before, opts.IsSet("checkPrivilegedContainers"), false,
+		FindFix{Module: "privileged", Find: privileged.FindPrivilegedContainers})
+	checked, fixed = opts.Check("checkSecretBuildArgs", "fixSecretBuildArgs")
+	before = add(before, checked, fixed, FindFix{Module: "buildargs", Find: buildargs.FindSecretBuildArgs,
+		Changes: &equivalence.Edits{Keys: StepKeys("run", "env", "with")}, Fix: buildargs.FixSecretBuildArgs})
+	checked, fixed = opts.Check("checkUntrustedEnvWrites", "sanitizeUntrustedEnvWrites")
+	before = add(before, checked, fixed, FindFix{Module: "githubenv", Find: githubenv.FindUntrustedWrites,
+		Changes: &equivalence.Edits{Keys: StepKeys("run", "env")}, Fix: githubenv.SanitizeUntrustedWrites})
+	checked, fixed = opts.Check("checkForkPullRequestSecrets", "addForkPullRequestGuards")
+	before = add(before, checked, fixed, FindFix{Module: "forkguard", Find: forkguard.FindUnguardedJobs,
+		Changes: &equivalence.Edits{Keys: []string{"jobs.*.if"}}, Fix: forkguard.AddForkGuards})
+	// the repository is taken from the owner and repo used to fetch the workflow
+	repository := Repository(opts.Params)
+	checked, fixed = opts.Check("checkPublishJobs", "addRepositoryGuards")
+	before = add(before, checked, fixed && repository != "", FindFix{Module: "repoguard", Find: repoguard.FindUnguardedPublishJobs,
+		Changes: &equivalence.Edits{Keys: []string{"jobs.*.if"}},
+		Fix: func(inputYaml string, detected []findings.Finding) (string, bool, error) {
+			return repoguard.AddRepositoryGuards(inputYaml, repository, detected)
+		}})
+	before = add(before, opts.IsSet("rewriteDeprecatedCommands"), true,
+		FindFix{Module: "deprecatedcommands", Safe: true, Changes: RunEdits, Fix: Changer(deprecatedcommands.RewriteDeprecatedCommands)})
+	// added before permissions, so the permissions needed by the SBOM action are computed from the knowledge base
+	before = add(before, opts.IsSet("addSBOM"), true, FindFix{Module: "sbom", Changes: &equivalence.Edits{InsertSteps: true, Keys: StepKeys("with")},
+		Fix: Changer(func(inputYaml string) (string, bool, error) {
+			return sbom.AddSBOMGeneration(inputYaml, opts.Params["sbomFormat"])
+		})})
+
+	after = add(after, opts.Params["addPermissions"] != "false" && opts.Permissions != nil, true, opts.Permissions)
+	// added after permissions, so the attestation permissions are added to the job level permissions
+	after = add(after, opts.IsSet("addBuildProvenance"), true, FindFix{Module: "attestation", Changes: &equivalence.Edits{InsertSteps: true, Keys: []string{"jobs.*.permissions"}},
+		Fix: Changer(attestation.AddBuildProvenance)})
+	after = add(after, opts.IsSet("addCosignSigning"), true, FindFix{Module: "signing", Changes: &equivalence.Edits{InsertSteps: true, Keys: append([]string{"jobs.*.permissions"}, StepKeys("id")...)},
+		Fix: Changer(signing.AddCosignSigning)})
+	// added after signing, so the scripts of the steps it adds run with the shell of the workflow as well
+	after = add(after, opts.IsSet("addShellDefaults"), true,
+		FindFix{Module: "shelldefaults", Changes: &equivalence.Edits{Keys: []string{"defaults", "jobs.*.defaults"}},
+			Fix: Changer(shelldefaults.AddShellDefaults)})
+	// checked before the other action checks, so they use the corrected actions
+	checked, fixed = opts.Check("checkTyposquattedActions", "fixTyposquattedActions")
+	after = add(after, checked, fixed, FindFix{Module: "typosquat", Changes: UsesEdits, Find: func(inputYaml string) ([]findings.Finding, error) {
+		// the indexed knowledge base is not walked for each workflow
+		if index := metadata.CurrentIndex(); index != nil {
+			return typosquat.FindTyposquattedActions(inputYaml, typosquat.PopularActions(index.Actions()))
+		}
+		popularActions, err := typosquat.LoadPopularActions(typosquat.GetKBFolder())
+		if err != nil {
+			return nil, err
+		}
+		return typosquat.FindTyposquattedActions(inputYaml, popularActions)
+	}, Fix: typosquat.FixTyposquattedActions})
+	after = append(after, opts.Actions...)
+	after = add(after, len(opts.RunnerLabels) > 0, true, FindFix{Module: "runnerlabel", Changes: &equivalence.Edits{Keys: []string{"jobs.*.runs-on"}}, Fix: Changer(func(inputYaml string) (string, bool, error) {
+		return runnerlabel.ReplaceRunnerLabels(inputYaml, opts.RunnerLabels)
+	})})
+	after = append(after, opts.Pins...)
+	// harden-runner in audit mode only monitors the egress traffic, while blocking it or updating the existing steps may
+	// break the jobs
+	safe := !opts.HardenRunnerConfig.Subtractive && !strings.Contains(opts.HardenRunnerConfig.Config, "egress-policy: block")
+	after = add(after, opts.Params["addHardenRunner"] != "false", true, FindFix{Module: "hardenrunner", Safe: safe,
+		// the existing harden-runner steps are updated with the configuration
+		Changes: &equivalence.Edits{InsertSteps: true, Keys: StepKeys("name", "uses", "with")},
+		Fix: Changer(func(inputYaml string) (string, bool, error) {
+			// the errors of adding harden-runner are ignored
+			output, added, _ := hardenrunner.AddAction(opts.Ctx, inputYaml, opts.HardenRunnerConfig, opts.HardenRunnerPinner, opts.PinToImmutable, opts.IsSet("skipHardenRunnerForContainers"))
+			return output, added, nil
+		})})
+	return before, after
+}
+
+// Analyzer is a check that only reports findings, which runs on the input before the remediations. The findings of an
+// analyzer with MarkFixed are marked as fixed when they are not found in the output.
+type Analyzer struct {
+	Name      string
+	Param     string
+	Find      func(inputYaml string) ([]findings.Finding, error)
+	MarkFixed bool
+}
+
+// Analyzers returns the analyzers, which are enabled by their query parameters. The unpinned actions are checked first
+// with findUnpinned, which is nil for the preview since the pin module looks up the actions.
+func Analyzers(findUnpinned func(inputYaml string) ([]findings.Finding, error)) []Analyzer {
+	var analyzers []Analyzer
+	if findUnpinned != nil {
+		analyzers = append(analyzers, Analyzer{Name: "pin", Param: "checkUnpinnedActions", MarkFixed: true, Find: findUnpinned})
+	}
+	return append(analyzers,
+		Analyzer{Name: "permissions", Param: "checkMissingPermissions", MarkFixed: true, Find: permissions.FindMissingPermissions},
+		Analyzer{Name: "scriptinjection", Param: "checkScriptInjection", Find: scriptinjection.FindScriptInjection},
+		Analyzer{Name: "triggers", Param: "checkDangerousTriggers", Find: triggers.FindDangerousTriggers},
+	)
+}
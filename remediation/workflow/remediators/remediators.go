@@ -0,0 +1,125 @@
+// Package remediators has the interface of the remediations of workflows, and the built-in remediations that need
+// neither the network nor the state of the handler. SecureWorkflow runs them along with the remediations that look up
+// actions and images, which it passes to Builtin, and the preview of the dashboard runs them on their own, so it compiles
+// to WebAssembly.
+package remediators
+
+import (
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/workflow/equivalence"
+)
+
+// Remediator is a remediation of workflows. Detect returns the findings in the workflow, Apply fixes the findings and returns
+// the workflow and whether it was changed, and Report adds what was not fixed to the report. The name is used for the
+// module in the report.
+type Remediator interface {
+	Name() string
+	Detect(inputYaml string) ([]findings.Finding, error)
+	Apply(inputYaml string, detected []findings.Finding) (string, bool, error)
+	Report(workflowReport *report.Report, path string, detected []findings.Finding)
+}
+
+// ConfidenceReporter is implemented by remediators that report the confidence of their changes, which is safe for changes
+// that keep the behavior of the workflow, so automated pull requests can merge them without review. The changes of
+// remediators that do not implement it need review.
+type ConfidenceReporter interface {
+	Confidence() string
+}
+
+// Confidence returns the confidence of the changes of a remediator
+func Confidence(remediator Remediator) string {
+	if reporter, ok := remediator.(ConfidenceReporter); ok && reporter.Confidence() != "" {
+		return reporter.Confidence()
+	}
+	return report.ConfidenceNeedsReview
+}
+
+// EditsReporter is implemented by remediators that report the edits they make to a workflow. When the query parameter
+// verifyEdits is true, the changes of a remediator that reports its edits are verified to be only those edits, and the
+// changes of the remediators that do not implement it, or report nil, are not verified.
+type EditsReporter interface {
+	Edits() *equivalence.Edits
+}
+
+// Edits returns the edits of a remediator, or nil if it does not report them
+func Edits(remediator Remediator) *equivalence.Edits {
+	if reporter, ok := remediator.(EditsReporter); ok {
+		return reporter.Edits()
+	}
+	return nil
+}
+
+// Remediation is a remediator enabled for a request, and whether it fixes its findings or only detects them
+type Remediation struct {
+	Remediator Remediator
+	Fix        bool
+}
+
+// FindFix is a built-in remediation with a function that finds the findings, and one that fixes them if the remediation
+// can fix them. A remediation with no function to find findings only changes the workflow.
+type FindFix struct {
+	// Module is the name of the module, and Safe whether its changes keep the behavior of the workflow
+	Module string
+	Safe   bool
+	// Changes are the edits the module makes, which are not verified if they are nil
+	Changes *equivalence.Edits
+	Find    func(inputYaml string) ([]findings.Finding, error)
+	Fix     func(inputYaml string, detected []findings.Finding) (string, bool, error)
+}
+
+func (r FindFix) Name() string {
+	return r.Module
+}
+
+func (r FindFix) Confidence() string {
+	if r.Safe {
+		return report.ConfidenceSafe
+	}
+	return report.ConfidenceNeedsReview
+}
+
+func (r FindFix) Edits() *equivalence.Edits {
+	return r.Changes
+}
+
+func (r FindFix) Detect(inputYaml string) ([]findings.Finding, error) {
+	if r.Find == nil {
+		return nil, nil
+	}
+	return r.Find(inputYaml)
+}
+
+func (r FindFix) Apply(inputYaml string, detected []findings.Finding) (string, bool, error) {
+	if r.Fix == nil {
+		return inputYaml, false, nil
+	}
+	return r.Fix(inputYaml, detected)
+}
+
+func (r FindFix) Report(workflowReport *report.Report, path string, detected []findings.Finding) {
+	workflowReport.AddUnfixed(r.Module, path, detected)
+}
+
+// Changer returns the function of a module that changes the workflow without findings
+func Changer(change func(inputYaml string) (string, bool, error)) func(inputYaml string, detected []findings.Finding) (string, bool, error) {
+	return func(inputYaml string, detected []findings.Finding) (string, bool, error) {
+		return change(inputYaml)
+	}
+}
+
+// StepKeys returns the paths of the keys of the steps of the jobs and of a composite action
+func StepKeys(keys ...string) []string {
+	paths := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		paths = append(paths, "jobs.*.steps.*."+key, "runs.steps.*."+key)
+	}
+	return paths
+}
+
+var (
+	// UsesEdits are the edits of the modules that replace or pin the actions of the steps
+	UsesEdits = &equivalence.Edits{Keys: StepKeys("uses")}
+	// RunEdits are the edits of the modules that rewrite the scripts of the steps
+	RunEdits = &equivalence.Edits{Keys: StepKeys("run")}
+)
@@ -0,0 +1,167 @@
+package repoguard
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const RuleUnguardedPublishJob = "unguarded-publish-job"
+
+// publishActions are actions that publish packages, images or releases
+var publishActions = []string{
+	"pypa/gh-action-pypi-publish",
+	"softprops/action-gh-release",
+	"actions/create-release",
+	"actions/upload-release-asset",
+	"ncipollo/release-action",
+	"goreleaser/goreleaser-action",
+	"js-devtools/npm-publish",
+	"brandedoutcast/publish-nuget",
+	"elgohr/publish-docker-github-action",
+	"changesets/action",
+}
+
+// publishCommandsRegex matches commands in run steps that publish packages, images or releases
+var publishCommandsRegex = regexp.MustCompile(`\b(npm publish|yarn publish|yarn npm publish|pnpm publish|docker push|docker buildx build[^\n]*--push|twine upload|cargo publish|gem push|nuget push|gh release create|mvn deploy|gradlew? publish|poetry publish|helm push)\b`)
+
+var repositoryRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// isPublishStep returns true if the step uses a publishing action, pushes with docker/build-push-action, or runs a publish command
+func isPublishStep(stepNode *yaml.Node) bool {
+	if usesNode := document.MappingValue(stepNode, "uses"); usesNode != nil {
+		action := strings.ToLower(strings.Split(usesNode.Value, "@")[0])
+		for _, publishAction := range publishActions {
+			if action == publishAction {
+				return true
+			}
+		}
+		if action == "docker/build-push-action" {
+			pushNode := document.MappingValue(document.MappingValue(stepNode, "with"), "push")
+			return pushNode != nil && pushNode.Value != "false"
+		}
+	}
+	if runNode := document.MappingValue(stepNode, "run"); runNode != nil {
+		return publishCommandsRegex.MatchString(runNode.Value)
+	}
+	return false
+}
+
+func isPublishJob(jobNode *yaml.Node) bool {
+	stepsNode := document.MappingValue(jobNode, "steps")
+	if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+		return false
+	}
+	for _, stepNode := range stepsNode.Content {
+		if isPublishStep(stepNode) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGuarded returns true if the job condition already checks the repository or its owner
+func isGuarded(jobNode *yaml.Node) bool {
+	ifNode := document.MappingValue(jobNode, "if")
+	if ifNode == nil {
+		return false
+	}
+	return strings.Contains(ifNode.Value, "github.repository ==") || strings.Contains(ifNode.Value, "github.repository_owner ==")
+}
+
+// FindUnguardedPublishJobs returns findings for jobs that publish packages, images or releases, without a condition on the repository.
+// When such a workflow runs in a fork, the job attempts to publish from the fork.
+func FindUnguardedPublishJobs(inputYaml string) ([]findings.Finding, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return nil, nil
+	}
+
+	jobsNode := document.MappingValue(t.Content[0], "jobs")
+	if jobsNode == nil || jobsNode.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var guardFindings []findings.Finding
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobKeyNode, jobNode := jobsNode.Content[i], document.Resolve(jobsNode.Content[i+1])
+		if !isPublishJob(jobNode) || isGuarded(jobNode) {
+			continue
+		}
+		guardFindings = append(guardFindings, findings.Finding{
+			RuleID:     RuleUnguardedPublishJob,
+			Message:    fmt.Sprintf("Job %s publishes packages or releases, but does not check that it is running in the original repository", jobKeyNode.Value),
+			JobName:    jobKeyNode.Value,
+			Line:       jobKeyNode.Line,
+			Column:     jobKeyNode.Column,
+			Suggestion: "if: github.repository == '<owner>/<repo>'",
+		})
+	}
+
+	return guardFindings, nil
+}
+
+// AddRepositoryGuards adds a condition to the jobs in the findings so they only run in the given repository, and marks the findings that were fixed.
+// If a job already has a single line condition, the guard is combined with it. Other conditions are only reported.
+func AddRepositoryGuards(inputYaml string, repository string, guardFindings []findings.Finding) (string, bool, error) {
+	if len(guardFindings) == 0 {
+		return inputYaml, false, nil
+	}
+	if !repositoryRegex.MatchString(repository) {
+		return inputYaml, false, fmt.Errorf("invalid repository %s, must be owner/repo", repository)
+	}
+	guard := fmt.Sprintf("github.repository == '%s'", repository)
+
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	jobsNode := document.MappingValue(t.Content[0], "jobs")
+
+	buffer := textedit.NewBuffer(inputYaml)
+	updated := false
+
+	for i, finding := range guardFindings {
+		jobKeyNode, jobNode := document.MappingEntry(jobsNode, finding.JobName)
+		if jobNode == nil || jobNode.Kind != yaml.MappingNode || jobNode.Style&yaml.FlowStyle != 0 || len(jobNode.Content) == 0 {
+			continue
+		}
+		indent := strings.Repeat(" ", jobNode.Content[0].Column-1)
+
+		ifKeyNode, ifNode := document.MappingEntry(jobNode, "if")
+		if ifNode == nil {
+			buffer.InsertLine(document.OpeningLine(jobKeyNode, jobNode), fmt.Sprintf("%sif: %s", indent, guard))
+			guardFindings[i].Fixed = true
+			updated = true
+			continue
+		}
+
+		// a condition with an anchor is not changed, since it is also the condition of its aliases
+		line, _ := buffer.Line(ifKeyNode.Line)
+		if ifNode.Kind != yaml.ScalarNode || ifNode.Anchor != "" || ifNode.Line != ifKeyNode.Line || ifNode.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 ||
+			strings.Contains(line, "#") || strings.Contains(ifNode.Value, ": ") {
+			continue
+		}
+		condition := strings.TrimSpace(ifNode.Value)
+		if strings.HasPrefix(condition, "${{") && strings.HasSuffix(condition, "}}") {
+			condition = strings.TrimSpace(condition[3 : len(condition)-2])
+		}
+		buffer.ReplaceLine(ifKeyNode.Line, fmt.Sprintf("%sif: ${{ (%s) && %s }}", indent, condition, guard))
+		guardFindings[i].Fixed = true
+		updated = true
+	}
+
+	if !updated {
+		return inputYaml, false, nil
+	}
+
+	return buffer.String(), true, nil
+}
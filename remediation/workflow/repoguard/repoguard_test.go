@@ -0,0 +1,82 @@
+package repoguard
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+)
+
+func TestAddRepositoryGuards(t *testing.T) {
+	const inputDirectory = "../../../testfiles/repoguard/input"
+	const outputDirectory = "../../../testfiles/repoguard/output"
+
+	tests := []struct {
+		fileName     string
+		wantFindings int
+		wantUpdated  bool
+	}{
+		{fileName: "publish-jobs.yml", wantFindings: 3, wantUpdated: true},
+		{fileName: "no-publish.yml", wantFindings: 0, wantUpdated: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			input, err := ioutil.ReadFile(path.Join(inputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			guardFindings, err := FindUnguardedPublishJobs(string(input))
+			if err != nil {
+				t.Errorf("FindUnguardedPublishJobs() unexpected error = %v", err)
+			}
+
+			if len(guardFindings) != tt.wantFindings {
+				t.Errorf("FindUnguardedPublishJobs() findings = %v, want %d", guardFindings, tt.wantFindings)
+			}
+
+			got, gotUpdated, err := AddRepositoryGuards(string(input), "org/app", guardFindings)
+			if err != nil {
+				t.Errorf("AddRepositoryGuards() unexpected error = %v", err)
+			}
+
+			if gotUpdated != tt.wantUpdated {
+				t.Errorf("AddRepositoryGuards() updated = %v, wantUpdated %v", gotUpdated, tt.wantUpdated)
+			}
+
+			for _, finding := range guardFindings {
+				if !finding.Fixed {
+					t.Errorf("AddRepositoryGuards() finding for job %s not fixed", finding.JobName)
+				}
+			}
+
+			output, err := ioutil.ReadFile(path.Join(outputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			if got != string(output) {
+				t.Errorf("AddRepositoryGuards() = %v, want %v", got, string(output))
+			}
+
+			// the publish jobs of the output are guarded, so adding the guards again does not change it
+			again, err := FindUnguardedPublishJobs(got)
+			if err != nil || len(again) != 0 {
+				t.Errorf("FindUnguardedPublishJobs() of the output = %v, %v, want no findings", again, err)
+			}
+			if guarded, updated, err := AddRepositoryGuards(got, "org/app", again); err != nil || updated || guarded != got {
+				t.Errorf("AddRepositoryGuards() of the output = %v, %v, want it unchanged", updated, err)
+			}
+		})
+	}
+}
+
+func TestAddRepositoryGuardsInvalidRepository(t *testing.T) {
+	guardFindings := []findings.Finding{{JobName: "publish"}}
+	_, _, err := AddRepositoryGuards("jobs:\n  publish:\n    runs-on: ubuntu-latest\n", "org/app' || true", guardFindings)
+	if err == nil {
+		t.Errorf("AddRepositoryGuards() expected error for invalid repository")
+	}
+}
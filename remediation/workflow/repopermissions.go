@@ -0,0 +1,115 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+)
+
+type RepoPermissionsRequest struct {
+	// Workflows maps the path of each workflow file in the repository to its content
+	Workflows map[string]string
+}
+
+type WorkflowPermissionsChange struct {
+	Path                  string
+	OriginalInput         string
+	FinalOutput           string
+	IsChanged             bool
+	AddedPermissions      bool
+	AlreadyHasPermissions bool
+	HasErrors             bool
+	IncorrectYaml         bool
+	JobErrors             []permissions.JobError
+}
+
+type RepoPermissionsSummary struct {
+	TotalWorkflows          int
+	ChangedWorkflows        int
+	AlreadyHavePermissions  int
+	WorkflowsWithErrors     int
+	WorkflowsIncorrectYaml  int
+	MissingActionsWorkflows int
+}
+
+type RepoPermissionsResponse struct {
+	Changes        []WorkflowPermissionsChange
+	Summary        RepoPermissionsSummary
+	MissingActions []string
+}
+
+// SecureRepoPermissions adds minimal permissions to all the workflows of a repository in a single call.
+// Only permissions are added; the other remediations of SecureWorkflow are turned off.
+// Changes are returned in the order of the workflow paths, along with a summary across all workflows.
+func SecureRepoPermissions(ctx context.Context, queryStringParams map[string]string, request RepoPermissionsRequest, svc dynamodbiface.DynamoDBAPI) (*RepoPermissionsResponse, error) {
+	params := map[string]string{}
+	for key, value := range queryStringParams {
+		params[key] = value
+	}
+	params["addPermissions"] = "true"
+	params["pinActions"] = "false"
+	params["addHardenRunner"] = "false"
+	// missing actions are stored once for the repository below
+	ignoreMissingKBs := params["ignoreMissingKBs"] == "true"
+	params["ignoreMissingKBs"] = "true"
+
+	paths := make([]string, 0, len(request.Workflows))
+	for path := range request.Workflows {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	response := new(RepoPermissionsResponse)
+	missingActions := make(map[string]bool)
+
+	for _, path := range paths {
+		inputYaml := request.Workflows[path]
+		change := WorkflowPermissionsChange{Path: path, OriginalInput: inputYaml, FinalOutput: inputYaml}
+
+		secureWorkflowReponse, err := SecureWorkflow(ctx, params, inputYaml, svc, Options{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to add permissions to %s: %v", path, err)
+		}
+
+		change.FinalOutput = secureWorkflowReponse.FinalOutput
+		change.IsChanged = secureWorkflowReponse.FinalOutput != inputYaml
+		change.AddedPermissions = secureWorkflowReponse.AddedPermissions
+		change.AlreadyHasPermissions = secureWorkflowReponse.AlreadyHasPermissions
+		change.HasErrors = secureWorkflowReponse.HasErrors
+		change.IncorrectYaml = secureWorkflowReponse.IncorrectYaml
+		change.JobErrors = secureWorkflowReponse.JobErrors
+
+		response.Summary.TotalWorkflows++
+		if change.IsChanged {
+			response.Summary.ChangedWorkflows++
+		}
+		if change.IncorrectYaml {
+			response.Summary.WorkflowsIncorrectYaml++
+		} else if change.AlreadyHasPermissions {
+			// already having permissions is reported as an error by SecureWorkflow, but needs no action
+			response.Summary.AlreadyHavePermissions++
+		} else if change.HasErrors {
+			response.Summary.WorkflowsWithErrors++
+		}
+		if len(secureWorkflowReponse.MissingActions) > 0 {
+			response.Summary.MissingActionsWorkflows++
+		}
+		for _, action := range secureWorkflowReponse.MissingActions {
+			if !missingActions[action] {
+				missingActions[action] = true
+				response.MissingActions = append(response.MissingActions, action)
+			}
+		}
+
+		response.Changes = append(response.Changes, change)
+	}
+
+	if len(response.MissingActions) > 0 && !ignoreMissingKBs && svc != nil {
+		StoreMissingActions(response.MissingActions, svc)
+	}
+
+	return response, nil
+}
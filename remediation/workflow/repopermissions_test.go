@@ -0,0 +1,55 @@
+package workflow
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSecureRepoPermissions(t *testing.T) {
+	const inputDirectory = "../../testfiles/joblevelperms/input"
+
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	workflows := make(map[string]string)
+	for _, fileName := range []string{"no-actions.yml", "workflow-already-has-permissions.yml", "incorrect-yaml.yml"} {
+		input, err := ioutil.ReadFile(path.Join(inputDirectory, fileName))
+		if err != nil {
+			log.Fatal(err)
+		}
+		workflows[".github/workflows/"+fileName] = string(input)
+	}
+
+	output, err := SecureRepoPermissions(context.Background(), map[string]string{"addProjectComment": "false"}, RepoPermissionsRequest{Workflows: workflows}, &mockDynamoDBClient{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+
+	if len(output.Changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %d", len(output.Changes))
+	}
+
+	// changes are sorted by path
+	expectedPaths := []string{".github/workflows/incorrect-yaml.yml", ".github/workflows/no-actions.yml", ".github/workflows/workflow-already-has-permissions.yml"}
+	for i, change := range output.Changes {
+		if change.Path != expectedPaths[i] {
+			t.Errorf("Expected path %s, got %s", expectedPaths[i], change.Path)
+		}
+	}
+
+	if !output.Changes[1].IsChanged || !output.Changes[1].AddedPermissions {
+		t.Errorf("Expected permissions to be added to no-actions.yml")
+	}
+
+	if output.Changes[2].IsChanged || !output.Changes[2].AlreadyHasPermissions {
+		t.Errorf("Expected workflow-already-has-permissions.yml to be unchanged")
+	}
+
+	expectedSummary := RepoPermissionsSummary{TotalWorkflows: 3, ChangedWorkflows: 1, AlreadyHavePermissions: 1, WorkflowsIncorrectYaml: 1}
+	if output.Summary != expectedSummary {
+		t.Errorf("Expected summary %+v, got %+v", expectedSummary, output.Summary)
+	}
+}
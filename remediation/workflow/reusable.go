@@ -0,0 +1,479 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/step-security/secure-repo/remediation/lineending"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultMaxInputs is the number of values that can differ between near-identical workflows, unless maxInputs is passed
+const defaultMaxInputs = 5
+
+// reusableKeys are the top-level keys of the workflows that are moved to the reusable workflow. Permissions are also
+// kept in the callers, since they limit the permissions of the called jobs.
+var reusableKeys = []string{"permissions", "env", "defaults", "jobs"}
+
+// verbatimKeys are the keys whose values cannot be set with inputs, so workflows that differ in them are not
+// near-identical. Their values are not evaluated as expressions, or for run, an input would be interpolated into the
+// script.
+var verbatimKeys = map[string]bool{"uses": true, "if": true, "needs": true, "permissions": true, "id": true, "shell": true, "run": true}
+
+var (
+	secretRegex    = regexp.MustCompile(`secrets\.([A-Za-z_][A-Za-z0-9_]*)`)
+	inputNameRegex = regexp.MustCompile(`[^a-z0-9_-]+`)
+)
+
+type ReusableWorkflowsRequest struct {
+	// Workflows maps the path of each workflow file in the repository to its content
+	Workflows map[string]string
+}
+
+// ReusableWorkflow is a hardened reusable workflow extracted from near-identical workflows, with an input for each
+// value that differs between them and the secrets its jobs use
+type ReusableWorkflow struct {
+	Path    string
+	Content string
+	Inputs  []string
+	Secrets []string
+	Callers []string
+}
+
+// ReusableWorkflowCaller is a workflow rewritten to call the reusable workflow its jobs were moved to
+type ReusableWorkflowCaller struct {
+	Path          string
+	OriginalInput string
+	FinalOutput   string
+	IsChanged     bool
+}
+
+type ReusableWorkflowsResponse struct {
+	ReusableWorkflows []ReusableWorkflow
+	Callers           []ReusableWorkflowCaller
+	MissingActions    []string
+}
+
+// leaf is a scalar of a workflow that can differ between near-identical workflows, with the key it is the value of
+type leaf struct {
+	node *yaml.Node
+	key  string
+}
+
+// reusableCandidate is a workflow whose jobs can be moved to a reusable workflow
+type reusableCandidate struct {
+	path    string
+	content string
+	topNode *yaml.Node
+	// shape is the workflow without the values that can be set with inputs, which is the same for near-identical workflows
+	shape  string
+	leaves []leaf
+}
+
+// collectLeaves writes the shape of the node to shape, and returns the scalars that are left out of it in the order
+// they are in the node. False is returned for nodes with aliases, since their values would be collected twice.
+func collectLeaves(node *yaml.Node, key string, verbatim bool, shape *strings.Builder, leaves []leaf) ([]leaf, bool) {
+	var ok bool
+	switch node.Kind {
+	case yaml.MappingNode:
+		shape.WriteString("{")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childKey := node.Content[i].Value
+			shape.WriteString(strconv.Quote(childKey) + ":")
+			if leaves, ok = collectLeaves(node.Content[i+1], childKey, verbatim || verbatimKeys[childKey], shape, leaves); !ok {
+				return nil, false
+			}
+			shape.WriteString(",")
+		}
+		shape.WriteString("}")
+	case yaml.SequenceNode:
+		shape.WriteString("[")
+		for _, item := range node.Content {
+			if leaves, ok = collectLeaves(item, key, verbatim, shape, leaves); !ok {
+				return nil, false
+			}
+			shape.WriteString(",")
+		}
+		shape.WriteString("]")
+	case yaml.ScalarNode:
+		if verbatim {
+			shape.WriteString("=" + strconv.Quote(node.Value))
+		} else {
+			shape.WriteString("_")
+			leaves = append(leaves, leaf{node: node, key: key})
+		}
+	default:
+		return nil, false
+	}
+	return leaves, true
+}
+
+// isCallable returns true if the jobs of the workflow can be moved to a reusable workflow: the workflow is not reusable
+// itself, and its jobs do not call reusable workflows
+func isCallable(topNode *yaml.Node) bool {
+	// the triggers may be read as the boolean true by YAML 1.1 libraries, which write them back with the key true
+	var on *yaml.Node
+	for i := 0; i+1 < len(topNode.Content); i += 2 {
+		if document.IsOnKey(topNode.Content[i]) {
+			on = topNode.Content[i+1]
+		}
+	}
+	if on == nil || on.Value == "workflow_call" || document.MappingValue(on, "workflow_call") != nil {
+		return false
+	}
+	for _, event := range on.Content {
+		if on.Kind == yaml.SequenceNode && event.Value == "workflow_call" {
+			return false
+		}
+	}
+	jobs := document.MappingValue(topNode, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode || len(jobs.Content) == 0 {
+		return false
+	}
+	for i := 1; i < len(jobs.Content); i += 2 {
+		if document.MappingValue(jobs.Content[i], "uses") != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func getReusableCandidate(filePath, content string) *reusableCandidate {
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	topNode := doc.Content[0]
+	if topNode.Kind != yaml.MappingNode || topNode.Style&yaml.FlowStyle != 0 || !isCallable(topNode) {
+		return nil
+	}
+	candidate := &reusableCandidate{path: filePath, content: content, topNode: topNode}
+	var shape strings.Builder
+	for _, key := range reusableKeys {
+		value := document.MappingValue(topNode, key)
+		if value == nil {
+			continue
+		}
+		shape.WriteString(key + ":")
+		var ok bool
+		if candidate.leaves, ok = collectLeaves(value, key, verbatimKeys[key], &shape, candidate.leaves); !ok {
+			return nil
+		}
+		shape.WriteString(";")
+	}
+	candidate.shape = shape.String()
+	return candidate
+}
+
+// copyNode returns a deep copy of the node
+func copyNode(node *yaml.Node) *yaml.Node {
+	copied := *node
+	copied.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		copied.Content[i] = copyNode(child)
+	}
+	return &copied
+}
+
+func newScalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func newMapping(keysAndValues ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: keysAndValues}
+}
+
+func encodeNode(node *yaml.Node) (string, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// getInputName returns a name for the input of the value of the key that is not one of the names already used
+func getInputName(key string, used map[string]bool) string {
+	name := strings.Trim(inputNameRegex.ReplaceAllString(strings.ReplaceAll(strings.ToLower(key), "_", "-"), "-"), "-")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "input-" + name
+	}
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// getInputType returns the type of an input for the tag of its values
+func getInputType(tag string) string {
+	switch tag {
+	case "!!int", "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	}
+	return "string"
+}
+
+// getReusablePath returns the path of the reusable workflow of the callers, which is named after the common prefix of
+// their names, e.g. .github/workflows/reusable-ci.yml for ci-frontend.yml and ci-backend.yml
+func getReusablePath(callers []*reusableCandidate, usedPaths map[string]bool) (string, string) {
+	prefix := ""
+	for i, caller := range callers {
+		name := strings.TrimSuffix(strings.TrimSuffix(path.Base(caller.path), ".yml"), ".yaml")
+		if i == 0 {
+			prefix = name
+			continue
+		}
+		for !strings.HasPrefix(name, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	prefix = strings.Trim(prefix, "-_.")
+	if prefix == "" {
+		prefix = "shared"
+	}
+	name := "reusable-" + prefix
+	for i := 2; usedPaths[".github/workflows/"+name+".yml"]; i++ {
+		name = fmt.Sprintf("reusable-%s-%d", prefix, i)
+	}
+	usedPaths[".github/workflows/"+name+".yml"] = true
+	return ".github/workflows/" + name + ".yml", prefix
+}
+
+// removeTopLevelKeys returns the lines of the workflow without the top-level keys, each of which spans the lines up to
+// the next top-level key
+func removeTopLevelKeys(content string, topNode *yaml.Node, keys map[string]bool) string {
+	lines := strings.Split(content, "\n")
+	removed := make([]bool, len(lines))
+	for i := 0; i+1 < len(topNode.Content); i += 2 {
+		if !keys[topNode.Content[i].Value] {
+			continue
+		}
+		end := len(lines)
+		if i+2 < len(topNode.Content) {
+			end = topNode.Content[i+2].Line - 1
+		}
+		for line := topNode.Content[i].Line - 1; line < end; line++ {
+			removed[line] = true
+		}
+	}
+	var kept []string
+	for i, line := range lines {
+		if !removed[i] {
+			kept = append(kept, line)
+		}
+	}
+	return strings.TrimRight(strings.Join(kept, "\n"), "\n") + "\n"
+}
+
+// extractReusableWorkflow returns the reusable workflow of the near-identical callers, and the callers rewritten to
+// call it, or false if more values differ between them than maxInputs, or if a value that differs is an expression,
+// which would be evaluated in the context of the callers
+func extractReusableWorkflow(callers []*reusableCandidate, maxInputs int, usedPaths map[string]bool) (*ReusableWorkflow, []ReusableWorkflowCaller, bool, error) {
+	first := callers[0]
+	var differing []int
+	for i, l := range first.leaves {
+		for _, caller := range callers[1:] {
+			other := caller.leaves[i].node
+			if other.Value == l.node.Value {
+				continue
+			}
+			if other.Tag != l.node.Tag || strings.Contains(other.Value, "${{") || strings.Contains(l.node.Value, "${{") {
+				return nil, nil, false, nil
+			}
+			differing = append(differing, i)
+			break
+		}
+	}
+	if len(differing) > maxInputs {
+		return nil, nil, false, nil
+	}
+
+	// the reusable workflow is the shared keys of the first caller, with the values that differ set by inputs
+	reusablePath, prefix := getReusablePath(callers, usedPaths)
+	reusable := &ReusableWorkflow{Path: reusablePath}
+	copied := copyNode(first.topNode)
+	var copiedLeaves []leaf
+	var shape strings.Builder
+	body := newMapping()
+	for _, key := range reusableKeys {
+		if value := document.MappingValue(copied, key); value != nil {
+			copiedLeaves, _ = collectLeaves(value, key, verbatimKeys[key], &shape, copiedLeaves)
+			body.Content = append(body.Content, newScalar(key), value)
+		}
+	}
+	inputs := newMapping()
+	usedNames := map[string]bool{}
+	for _, i := range differing {
+		name := getInputName(first.leaves[i].key, usedNames)
+		reusable.Inputs = append(reusable.Inputs, name)
+		inputs.Content = append(inputs.Content, newScalar(name), newMapping(
+			newScalar("type"), newScalar(getInputType(first.leaves[i].node.Tag)),
+			newScalar("required"), &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"}))
+		node := copiedLeaves[i].node
+		node.Value, node.Tag, node.Style = "${{ inputs."+name+" }}", "!!str", 0
+	}
+
+	// the secrets are passed by the callers explicitly, so the reusable workflow only gets the secrets its jobs use
+	usedSecrets := map[string]bool{}
+	for _, l := range copiedLeaves {
+		for _, match := range secretRegex.FindAllStringSubmatch(l.node.Value, -1) {
+			if match[1] != "GITHUB_TOKEN" && !usedSecrets[match[1]] {
+				usedSecrets[match[1]] = true
+				reusable.Secrets = append(reusable.Secrets, match[1])
+			}
+		}
+	}
+	sort.Strings(reusable.Secrets)
+	secrets := newMapping()
+	for _, secret := range reusable.Secrets {
+		secrets.Content = append(secrets.Content, newScalar(secret), newMapping(newScalar("required"), &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "false"}))
+	}
+	workflowCall := newMapping()
+	if len(inputs.Content) > 0 {
+		workflowCall.Content = append(workflowCall.Content, newScalar("inputs"), inputs)
+	}
+	if len(secrets.Content) > 0 {
+		workflowCall.Content = append(workflowCall.Content, newScalar("secrets"), secrets)
+	}
+	top := newMapping(newScalar("name"), newScalar(strings.TrimSuffix(path.Base(reusablePath), ".yml")),
+		newScalar("on"), newMapping(newScalar("workflow_call"), workflowCall))
+	top.Content = append(top.Content, body.Content...)
+	content, err := encodeNode(top)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("unable to write reusable workflow: %v", err)
+	}
+	reusable.Content = content
+
+	// the callers keep their name, triggers, permissions and concurrency, and have a single job that calls the
+	// reusable workflow with their values
+	jobs := document.MappingValue(first.topNode, "jobs")
+	jobName := prefix
+	if len(jobs.Content) == 2 {
+		jobName = jobs.Content[0].Value
+	}
+	var rewritten []ReusableWorkflowCaller
+	for _, caller := range callers {
+		job := newMapping(newScalar("uses"), newScalar("./"+reusablePath))
+		if len(differing) > 0 {
+			with := newMapping()
+			for j, i := range differing {
+				value := &yaml.Node{Kind: yaml.ScalarNode, Tag: caller.leaves[i].node.Tag, Value: caller.leaves[i].node.Value}
+				if strings.Contains(value.Value, "\n") {
+					value.Style = yaml.LiteralStyle
+				}
+				with.Content = append(with.Content, newScalar(reusable.Inputs[j]), value)
+			}
+			job.Content = append(job.Content, newScalar("with"), with)
+		}
+		if len(reusable.Secrets) > 0 {
+			passed := newMapping()
+			for _, secret := range reusable.Secrets {
+				passed.Content = append(passed.Content, newScalar(secret), newScalar("${{ secrets."+secret+" }}"))
+			}
+			job.Content = append(job.Content, newScalar("secrets"), passed)
+		}
+		jobsText, err := encodeNode(newMapping(newScalar("jobs"), newMapping(newScalar(jobName), job)))
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("unable to write caller %s: %v", caller.path, err)
+		}
+		output := removeTopLevelKeys(lineending.Normalize(caller.content), caller.topNode, map[string]bool{"env": true, "defaults": true, "jobs": true}) + jobsText
+		output = lineending.Restore(caller.content, output)
+		rewritten = append(rewritten, ReusableWorkflowCaller{Path: caller.path, OriginalInput: caller.content, FinalOutput: output, IsChanged: output != caller.content})
+		reusable.Callers = append(reusable.Callers, caller.path)
+	}
+	return reusable, rewritten, true, nil
+}
+
+// ExtractReusableWorkflows finds the near-identical workflows of a repository, which have the same jobs with at most
+// maxInputs values that differ, e.g. the version of a language, and moves their jobs to a reusable workflow. The
+// reusable workflow is hardened with SecureWorkflow and the query parameters, and has an input for each value that
+// differs, so the jobs of all the workflows are hardened in one place. The workflows are rewritten to call it with
+// their values and the secrets it uses, keeping their triggers. Workflows that are reusable already, or call reusable
+// workflows, are left as is.
+func ExtractReusableWorkflows(ctx context.Context, queryStringParams map[string]string, request ReusableWorkflowsRequest, svc dynamodbiface.DynamoDBAPI) (*ReusableWorkflowsResponse, error) {
+	maxInputs := defaultMaxInputs
+	if value, found := queryStringParams["maxInputs"]; found {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid maxInputs %q", value)
+		}
+		maxInputs = parsed
+	}
+	params := map[string]string{}
+	for key, value := range queryStringParams {
+		params[key] = value
+	}
+	delete(params, "maxInputs")
+	// missing actions are stored once for the repository below
+	ignoreMissingKBs := params["ignoreMissingKBs"] == "true"
+	params["ignoreMissingKBs"] = "true"
+
+	paths := make([]string, 0, len(request.Workflows))
+	usedPaths := map[string]bool{}
+	for filePath := range request.Workflows {
+		paths = append(paths, filePath)
+		usedPaths[filePath] = true
+	}
+	sort.Strings(paths)
+
+	// the workflows are grouped by their shape, in the order of the path of the first workflow of each group
+	var shapes []string
+	groups := map[string][]*reusableCandidate{}
+	for _, filePath := range paths {
+		candidate := getReusableCandidate(filePath, request.Workflows[filePath])
+		if candidate == nil {
+			continue
+		}
+		if _, found := groups[candidate.shape]; !found {
+			shapes = append(shapes, candidate.shape)
+		}
+		groups[candidate.shape] = append(groups[candidate.shape], candidate)
+	}
+
+	response := &ReusableWorkflowsResponse{}
+	missingActions := map[string]bool{}
+	for _, shape := range shapes {
+		callers := groups[shape]
+		if len(callers) < 2 {
+			continue
+		}
+		reusable, rewritten, extracted, err := extractReusableWorkflow(callers, maxInputs, usedPaths)
+		if err != nil {
+			return nil, err
+		}
+		if !extracted {
+			continue
+		}
+		secureWorkflowReponse, err := SecureWorkflow(ctx, params, reusable.Content, svc, Options{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to secure %s: %v", reusable.Path, err)
+		}
+		reusable.Content = secureWorkflowReponse.FinalOutput
+		for _, action := range secureWorkflowReponse.MissingActions {
+			if !missingActions[action] {
+				missingActions[action] = true
+				response.MissingActions = append(response.MissingActions, action)
+			}
+		}
+		response.ReusableWorkflows = append(response.ReusableWorkflows, *reusable)
+		response.Callers = append(response.Callers, rewritten...)
+	}
+
+	if len(response.MissingActions) > 0 && !ignoreMissingKBs && svc != nil {
+		StoreMissingActions(response.MissingActions, svc)
+	}
+	return response, nil
+}
@@ -0,0 +1,86 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExtractReusableWorkflows(t *testing.T) {
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	frontend := `name: Frontend
+on:
+  push:
+    paths: [frontend/**]
+env:
+  CI: true
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-node@v4
+        with:
+          node-version: 18
+      - run: npm ci && npm test
+        working-directory: frontend
+        env:
+          NPM_TOKEN: ${{ secrets.NPM_TOKEN }}
+`
+	backend := strings.NewReplacer("Frontend", "Backend", "frontend", "backend", "node-version: 18", "node-version: 20").Replace(frontend)
+	// the if condition cannot be set with an input, so the workflow is not near-identical
+	docs := strings.Replace(frontend, "      - run: npm ci", "      - if: github.ref == 'refs/heads/main'\n        run: npm ci", 1)
+	request := ReusableWorkflowsRequest{Workflows: map[string]string{
+		".github/workflows/ci-frontend.yml": frontend,
+		".github/workflows/ci-backend.yml":  backend,
+		".github/workflows/docs.yml":        docs,
+	}}
+
+	response, err := ExtractReusableWorkflows(context.Background(), map[string]string{"pinActions": "false", "addProjectComment": "false"}, request, &mockDynamoDBClient{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if len(response.ReusableWorkflows) != 1 || len(response.Callers) != 2 {
+		t.Fatalf("expected one reusable workflow with two callers, got %+v", response)
+	}
+
+	reusable := response.ReusableWorkflows[0]
+	if reusable.Path != ".github/workflows/reusable-ci.yml" || strings.Join(reusable.Callers, ",") != ".github/workflows/ci-backend.yml,.github/workflows/ci-frontend.yml" {
+		t.Errorf("unexpected reusable workflow %s called by %v", reusable.Path, reusable.Callers)
+	}
+	if strings.Join(reusable.Inputs, ",") != "node-version,working-directory" || strings.Join(reusable.Secrets, ",") != "NPM_TOKEN" {
+		t.Errorf("unexpected inputs %v and secrets %v", reusable.Inputs, reusable.Secrets)
+	}
+	for _, expected := range []string{"on:\n  workflow_call:\n    inputs:\n      node-version:\n        type: number\n        required: true\n",
+		"    secrets:\n      NPM_TOKEN:\n        required: false\n", "env:\n  CI: true\n", "node-version: ${{ inputs.node-version }}",
+		"working-directory: ${{ inputs.working-directory }}", "uses: step-security/harden-runner@", "permissions:\n  contents: read"} {
+		if !strings.Contains(reusable.Content, expected) {
+			t.Errorf("expected the reusable workflow to have %q\n%s", expected, reusable.Content)
+		}
+	}
+
+	want := `name: Backend
+on:
+  push:
+    paths: [backend/**]
+jobs:
+  test:
+    uses: ./.github/workflows/reusable-ci.yml
+    with:
+      node-version: 20
+      working-directory: backend
+    secrets:
+      NPM_TOKEN: ${{ secrets.NPM_TOKEN }}
+`
+	if caller := response.Callers[0]; caller.Path != ".github/workflows/ci-backend.yml" || !caller.IsChanged || caller.FinalOutput != want {
+		t.Errorf("unexpected caller %s\n%s\nwant\n%s", caller.Path, caller.FinalOutput, want)
+	}
+
+	// with fewer inputs than the values that differ, no workflow is extracted
+	response, err = ExtractReusableWorkflows(context.Background(), map[string]string{"pinActions": "false", "maxInputs": "1"}, request, &mockDynamoDBClient{})
+	if err != nil || len(response.ReusableWorkflows) != 0 || len(response.Callers) != 0 {
+		t.Errorf("expected no reusable workflow with maxInputs=1, got %+v, %v", response, err)
+	}
+}
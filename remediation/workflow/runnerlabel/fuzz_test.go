@@ -0,0 +1,28 @@
+package runnerlabel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/workflow/fuzzing"
+)
+
+func FuzzReplaceRunnerLabels(f *testing.F) {
+	fuzzing.AddSeeds(f, "../../../testfiles/runnerLabel/input")
+	labelMap := map[string]string{"ubuntu-latest": "step-ubuntu", "ubuntu-22.04": "step-ubuntu-22", "windows-latest": "step-windows"}
+	f.Fuzz(func(t *testing.T, input string) {
+		output, _, err := ReplaceRunnerLabels(input, labelMap)
+		if err != nil {
+			return
+		}
+		// only the labels of runs-on are replaced
+		fuzzing.CheckOutput(t, input, output, fuzzing.DeleteKeys("runs-on"), func(line string) bool {
+			for label := range labelMap {
+				if strings.Contains(line, label) {
+					return true
+				}
+			}
+			return false
+		})
+	})
+}
@@ -71,6 +71,16 @@ func TestReplaceRunnerLabels(t *testing.T) {
 			wantUpdated: true,
 			wantErr:     false,
 		},
+		{
+			name:       "flow mapping and quoted labels",
+			inputFile:  "flowJob.yml",
+			outputFile: "flowJob.yml",
+			labelMap: map[string]string{
+				"ubuntu-latest": "step-ubuntu-24",
+			},
+			wantUpdated: true,
+			wantErr:     false,
+		},
 		{
 			name:       "compact ubuntu version numbers",
 			inputFile:  "compactVersions.yml",
@@ -139,6 +149,12 @@ func TestReplaceRunnerLabels(t *testing.T) {
 			if got != string(expectedOutput) {
 				t.Errorf("ReplaceRunnerLabels() output mismatch\nGot:\n%s\n\nWant:\n%s", got, string(expectedOutput))
 			}
+
+			// the labels of the output are replaced, so replacing them again does not change it
+			again, againUpdated, err := ReplaceRunnerLabels(got, tt.labelMap)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("ReplaceRunnerLabels() of the output = %v, %v, want it unchanged\n%s", againUpdated, err, again)
+			}
 		})
 	}
 }
@@ -0,0 +1,176 @@
+package sbom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/yamledit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SBOMAction     = "anchore/sbom-action"
+	SBOMActionTag  = "v0"
+	SBOMActionName = "Generate SBOM"
+
+	FormatSPDX      = "spdx-json"
+	FormatCycloneDX = "cyclonedx-json"
+)
+
+// releaseAction is the action whose files input the SBOM is attached to
+const releaseAction = "softprops/action-gh-release"
+
+func getAction(stepNode *yaml.Node) string {
+	usesNode := document.MappingValue(stepNode, "uses")
+	if usesNode == nil {
+		return ""
+	}
+	return strings.ToLower(strings.Split(usesNode.Value, "@")[0])
+}
+
+// GetFormat returns the sbom-action format for the requested format, spdx or cyclonedx
+func GetFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", "spdx", FormatSPDX:
+		return FormatSPDX, nil
+	case "cyclonedx", FormatCycloneDX:
+		return FormatCycloneDX, nil
+	}
+	return "", fmt.Errorf("unsupported SBOM format %s", format)
+}
+
+// getOutputFile returns the name of the SBOM file, e.g. sbom.spdx.json
+func getOutputFile(format string) string {
+	return "sbom." + strings.TrimSuffix(format, "-json") + ".json"
+}
+
+func hasSBOM(stepsNode *yaml.Node) bool {
+	for _, stepNode := range stepsNode.Content {
+		if getAction(stepNode) == SBOMAction {
+			return true
+		}
+		if runNode := document.MappingValue(stepNode, "run"); runNode != nil && strings.Contains(runNode.Value, "syft ") {
+			return true
+		}
+	}
+	return false
+}
+
+// getSBOMStep returns the lines of the SBOM step, indented as the item of the step it is inserted before
+func getSBOMStep(stepNode *yaml.Node, indentUnit, format string) []string {
+	propertyIndent := strings.Repeat(" ", stepNode.Column-1)
+	dashIndent := strings.Repeat(" ", stepNode.Column-3)
+
+	return []string{
+		fmt.Sprintf("%s- name: %s", dashIndent, SBOMActionName),
+		fmt.Sprintf("%suses: %s@%s", propertyIndent, SBOMAction, SBOMActionTag),
+		fmt.Sprintf("%swith:", propertyIndent),
+		fmt.Sprintf("%s%sformat: %s", propertyIndent, indentUnit, format),
+		fmt.Sprintf("%s%soutput-file: %s", propertyIndent, indentUnit, getOutputFile(format)),
+	}
+}
+
+// attachToRelease adds the SBOM file to the files input of the release step
+func attachToRelease(buffer *textedit.Buffer, inputLines []string, stepNode *yaml.Node, indentUnit, outputFile string) {
+	propertyIndent := strings.Repeat(" ", stepNode.Content[0].Column-1)
+	withNode := document.MappingValue(stepNode, "with")
+
+	if withNode == nil {
+		usesKeyNode, _ := document.MappingEntry(stepNode, "uses")
+		buffer.InsertLinesAfter(usesKeyNode.Line, fmt.Sprintf("%swith:", propertyIndent),
+			fmt.Sprintf("%s%sfiles: %s", propertyIndent, indentUnit, outputFile))
+		return
+	}
+	// inputs with an anchor may also be the inputs of other actions, and the files with an anchor are also the files of
+	// its aliases
+	if withNode.Kind != yaml.MappingNode || withNode.Style&yaml.FlowStyle != 0 || len(withNode.Content) == 0 || withNode.Anchor != "" {
+		return
+	}
+
+	inputIndent := strings.Repeat(" ", withNode.Content[0].Column-1)
+	filesKeyNode, filesNode := document.MappingEntry(withNode, "files")
+	switch {
+	case filesNode != nil && filesNode.Anchor != "":
+	case filesNode == nil:
+		lastLine := yamledit.LastLine(inputLines, withNode)
+		buffer.InsertLinesAfter(lastLine, fmt.Sprintf("%sfiles: %s", inputIndent, outputFile))
+	case filesNode.Style&yaml.LiteralStyle != 0:
+		// files are newline separated, so the SBOM is added as another line
+		firstLine := inputLines[filesNode.Line]
+		fileIndent := firstLine[:len(firstLine)-len(strings.TrimLeft(firstLine, " "))]
+		lastLine := yamledit.LastLine(inputLines, filesNode)
+		buffer.InsertLinesAfter(lastLine, fmt.Sprintf("%s%s", fileIndent, outputFile))
+	case filesNode.Kind == yaml.ScalarNode && filesNode.Style&yaml.FoldedStyle == 0 && filesKeyNode.Line == filesNode.Line:
+		buffer.ReplaceLine(filesKeyNode.Line, fmt.Sprintf("%sfiles: |", inputIndent))
+		buffer.InsertLinesAfter(filesKeyNode.Line, fmt.Sprintf("%s%s%s", inputIndent, indentUnit, filesNode.Value),
+			fmt.Sprintf("%s%s%s", inputIndent, indentUnit, outputFile))
+	}
+}
+
+// AddSBOMGeneration adds an anchore/sbom-action step to jobs that publish a release or upload artifacts.
+// The SBOM is generated in the given format (spdx-json or cyclonedx-json), and is uploaded as a workflow artifact by the action.
+// For jobs that publish a release with softprops/action-gh-release, the SBOM is also attached to the release.
+func AddSBOMGeneration(inputYaml string, format string) (string, bool, error) {
+	format, err := GetFormat(format)
+	if err != nil {
+		return inputYaml, false, err
+	}
+
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return inputYaml, false, nil
+	}
+	topNode := t.Content[0]
+
+	jobsKeyNode, jobsNode := document.MappingEntry(topNode, "jobs")
+	if jobsNode == nil || jobsNode.Kind != yaml.MappingNode || len(jobsNode.Content) == 0 {
+		return inputYaml, false, nil
+	}
+	indentUnit := strings.Repeat(" ", jobsNode.Content[0].Column-jobsKeyNode.Column)
+
+	inputLines := strings.Split(inputYaml, "\n")
+	buffer := textedit.NewBuffer(inputYaml)
+	updated := false
+
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		stepsNode := document.MappingValue(jobsNode.Content[i+1], "steps")
+		if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode || stepsNode.Style&yaml.FlowStyle != 0 || hasSBOM(stepsNode) {
+			continue
+		}
+
+		// the SBOM is generated before the release, or the first artifact upload
+		var publishItem *yaml.Node
+		for _, itemNode := range stepsNode.Content {
+			action := getAction(itemNode)
+			if action == releaseAction {
+				publishItem = itemNode
+				break
+			}
+			if action == "actions/upload-artifact" && publishItem == nil {
+				publishItem = itemNode
+			}
+		}
+		// the step of an alias is changed at its anchor, and the SBOM step is added before the alias
+		publishStep := document.Resolve(publishItem)
+		if publishStep == nil || publishStep.Kind != yaml.MappingNode || publishStep.Style&yaml.FlowStyle != 0 || publishItem.Column < 3 {
+			continue
+		}
+
+		buffer.InsertLinesBefore(publishItem.Line, getSBOMStep(publishItem, indentUnit, format)...)
+		if getAction(publishStep) == releaseAction {
+			attachToRelease(buffer, inputLines, publishStep, indentUnit, getOutputFile(format))
+		}
+		updated = true
+	}
+
+	if !updated {
+		return inputYaml, false, nil
+	}
+
+	return buffer.String(), true, nil
+}
@@ -0,0 +1,62 @@
+package sbom
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestAddSBOMGeneration(t *testing.T) {
+	const inputDirectory = "../../../testfiles/sbom/input"
+	const outputDirectory = "../../../testfiles/sbom/output"
+
+	tests := []struct {
+		fileName    string
+		format      string
+		wantUpdated bool
+	}{
+		{fileName: "release.yml", format: "", wantUpdated: true},
+		{fileName: "cyclonedx.yml", format: "cyclonedx", wantUpdated: true},
+		{fileName: "existing-sbom.yml", format: "spdx", wantUpdated: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			input, err := ioutil.ReadFile(path.Join(inputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			got, gotUpdated, err := AddSBOMGeneration(string(input), tt.format)
+			if err != nil {
+				t.Errorf("AddSBOMGeneration() unexpected error = %v", err)
+			}
+
+			if gotUpdated != tt.wantUpdated {
+				t.Errorf("AddSBOMGeneration() updated = %v, wantUpdated %v", gotUpdated, tt.wantUpdated)
+			}
+
+			output, err := ioutil.ReadFile(path.Join(outputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			if got != string(output) {
+				t.Errorf("AddSBOMGeneration() = %v, want %v", got, string(output))
+			}
+
+			// the output generates the SBOM, so adding it again does not change it
+			again, againUpdated, err := AddSBOMGeneration(got, tt.format)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("AddSBOMGeneration() of the output = %v, %v, want it unchanged", againUpdated, err)
+			}
+		})
+	}
+}
+
+func TestAddSBOMGenerationInvalidFormat(t *testing.T) {
+	_, _, err := AddSBOMGeneration("on: push", "xml")
+	if err == nil {
+		t.Errorf("AddSBOMGeneration() expected error for unsupported format")
+	}
+}
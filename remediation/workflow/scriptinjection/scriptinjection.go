@@ -0,0 +1,73 @@
+package scriptinjection
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/workflow/expressions"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleScriptInjection = "script-injection"
+
+	GithubScriptAction = "actions/github-script"
+)
+
+// getScript returns the key node and value of the script of a run step or an actions/github-script step
+func getScript(stepNode *yaml.Node) (*yaml.Node, string) {
+	keyNode, valueNode := document.MappingEntry(stepNode, "run")
+	if usesNode := document.MappingValue(stepNode, "uses"); usesNode != nil && strings.HasPrefix(strings.ToLower(usesNode.Value), GithubScriptAction+"@") {
+		keyNode, valueNode = document.MappingEntry(document.MappingValue(stepNode, "with"), "script")
+	}
+	if keyNode == nil || valueNode.Kind != yaml.ScalarNode {
+		return nil, ""
+	}
+	return keyNode, valueNode.Value
+}
+
+// FindScriptInjection returns findings for run steps and github-script steps in workflows that use values an attacker
+// can set, like issue titles and branch names, in expressions. The expressions are replaced before the script runs,
+// so the values can inject code into the script.
+func FindScriptInjection(inputYaml string) ([]findings.Finding, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return nil, nil
+	}
+
+	jobsNode := document.MappingValue(t.Content[0], "jobs")
+	if jobsNode == nil || jobsNode.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var injectionFindings []findings.Finding
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobName := jobsNode.Content[i].Value
+		stepsNode := document.MappingValue(jobsNode.Content[i+1], "steps")
+		if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, stepNode := range stepsNode.Content {
+			keyNode, script := getScript(stepNode)
+			if keyNode == nil {
+				continue
+			}
+			for _, expression := range expressions.GetUntrustedExpressions(script) {
+				injectionFindings = append(injectionFindings, findings.Finding{
+					RuleID:     RuleScriptInjection,
+					Message:    fmt.Sprintf("%s is used in a script in job %s, and can be used to inject code", expression, jobName),
+					JobName:    jobName,
+					Line:       keyNode.Line,
+					Column:     keyNode.Column,
+					Suggestion: fmt.Sprintf("Pass %s to the step in an environment variable, and use the environment variable in the script", expression),
+				})
+			}
+		}
+	}
+	return injectionFindings, nil
+}
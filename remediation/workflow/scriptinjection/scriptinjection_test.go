@@ -0,0 +1,35 @@
+package scriptinjection
+
+import "testing"
+
+func TestFindScriptInjection(t *testing.T) {
+	input := `on: issues
+jobs:
+  triage:
+    runs-on: ubuntu-latest
+    steps:
+      - run: |
+          echo "${{ github.event.issue.title }}"
+          echo "${{ github.event.issue.number }}"
+      - uses: actions/github-script@v7
+        with:
+          script: |
+            console.log("${{ github.event.issue.body }}")
+      - uses: actions/labeler@v5
+        with:
+          script: ${{ github.event.issue.body }}
+`
+	got, err := FindScriptInjection(input)
+	if err != nil {
+		t.Fatalf("FindScriptInjection() unexpected error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("FindScriptInjection() returned %d findings, want 2: %v", len(got), got)
+	}
+	if got[0].Line != 6 || got[0].JobName != "triage" || got[0].Message != "github.event.issue.title is used in a script in job triage, and can be used to inject code" {
+		t.Errorf("unexpected finding %+v", got[0])
+	}
+	if got[1].Line != 11 || got[1].RuleID != RuleScriptInjection {
+		t.Errorf("unexpected finding %+v", got[1])
+	}
+}
@@ -1,237 +1,355 @@
 package workflow
 
 import (
-	"encoding/json"
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
-	"github.com/step-security/secure-repo/remediation/workflow/hardenrunner"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/lineending"
+	"github.com/step-security/secure-repo/remediation/metrics"
+	"github.com/step-security/secure-repo/remediation/multidoc"
+	"github.com/step-security/secure-repo/remediation/report"
 	"github.com/step-security/secure-repo/remediation/workflow/maintainedactions"
 	"github.com/step-security/secure-repo/remediation/workflow/permissions"
 	"github.com/step-security/secure-repo/remediation/workflow/pin"
-	"github.com/step-security/secure-repo/remediation/workflow/runnerlabel"
+	"github.com/step-security/secure-repo/remediation/workflow/remediators"
 )
 
+// AnalyzerParams are the query parameters that enable the analyzers which only report findings.
+// They are enabled when the findings are requested in SARIF format.
+var AnalyzerParams = []string{"checkUnpinnedActions", "checkMissingPermissions", "checkScriptInjection", "checkDangerousTriggers"}
+
+// CheckParams are the query parameters that enable all checks which report findings, including the analyzers
+var CheckParams = append([]string{"checkUnmaintainedActions", "checkVulnerableActions", "checkTyposquattedActions", "checkDispatchInputs",
+	"checkPrivilegedContainers", "checkSecretBuildArgs", "checkUntrustedEnvWrites", "checkForkPullRequestSecrets", "checkPublishJobs"}, AnalyzerParams...)
+
+// GetDryRunParams returns a copy of the query parameters with the checks enabled, unless they are set to false
+func GetDryRunParams(queryStringParams map[string]string) map[string]string {
+	dryRunParams := make(map[string]string, len(queryStringParams)+len(CheckParams))
+	for key, value := range queryStringParams {
+		dryRunParams[key] = value
+	}
+	for _, param := range CheckParams {
+		if _, found := dryRunParams[param]; !found {
+			dryRunParams[param] = "true"
+		}
+	}
+	return dryRunParams
+}
+
 const (
 	HardenRunnerActionPathWithTag = "step-security/harden-runner@v2"
 	HardenRunnerActionPath        = "step-security/harden-runner"
 	HardenRunnerActionName        = "Harden Runner"
 )
 
-func SecureWorkflow(queryStringParams map[string]string, inputYaml string, svc dynamodbiface.DynamoDBAPI, params ...interface{}) (*permissions.SecureWorkflowReponse, error) {
-	pinActions, addHardenRunner, addPermissions, addProjectComment, replaceMaintainedActions, replaceRunnerLabels := true, true, true, true, false, false
-	pinnedActions, addedHardenRunner, addedPermissions, replacedMaintainedActions, replacedRunnerLabels := false, false, false, false, false
-	ignoreMissingKBs := false
-	enableLogging := false
-	addEmptyTopLevelPermissions := false
-	skipHardenRunnerForContainers := false
-	replaceActionByMajorTag := false
-	exemptedActions, pinToImmutable, maintainedActionsMap, actionCommitMap, runnerLabelMap := []string{}, false, map[string]string{}, map[string]string{}, map[string]string{}
-	hardenRunnerConfig := hardenrunner.HardenRunnerConfig{}
-
-	if len(params) > 0 {
-		if v, ok := params[0].([]string); ok {
-			exemptedActions = v
-		}
+// SecureWorkflow runs the remediations enabled by the query parameters on the workflow. With dryRun=true, all checks are run,
+// and the findings and the report of the proposed changes are returned, but the output is the unchanged input.
+// The logs are written with the logger of the package logging, or with the Logger of the options, e.g. one with the id
+// of the request. The query parameters decided by the Rego policy of the Evaluator of the options, or of the Rego files
+// in OPA_POLICY_FILES, override the query parameters of the request. The requests to GitHub and the other services are
+// made with the context, and the error of the context is returned when it is done, e.g. when the client disconnected,
+// instead of a response with the errors of the modules that were canceled. The documents of a file with several
+// documents separated by "---" are remediated one by one, as if each was a workflow of its own. The output is validated
+// against the github-workflow schema, and an *InvalidWorkflowError with the module is returned if a module made a valid
+// workflow invalid. With strict=true, all checks are run, and FailedStrictChecks is set when a module failed or a
+// construct could not be resolved, such as an action that is not in the knowledge base, a uses with an expression, an
+// image that could not be pinned or a job whose permissions were not set, which are added to the findings.
+func SecureWorkflow(ctx context.Context, queryStringParams map[string]string, inputYaml string, svc dynamodbiface.DynamoDBAPI, params Options) (*permissions.SecureWorkflowReponse, error) {
+	if multidoc.Count(lineending.Normalize(inputYaml)) > 1 {
+		return secureDocuments(ctx, queryStringParams, inputYaml, svc, params)
 	}
-	if len(params) > 1 {
-		if v, ok := params[1].(bool); ok {
-			pinToImmutable = v
-		}
+	secureWorkflowReponse, _, err := secureDocument(ctx, queryStringParams, inputYaml, svc, params)
+	return secureWorkflowReponse, err
+}
+
+// secureDocument runs the remediations on a workflow with one document, and returns the names of the remediations in
+// the order they ran
+func secureDocument(ctx context.Context, queryStringParams map[string]string, inputYaml string, svc dynamodbiface.DynamoDBAPI, params Options) (*permissions.SecureWorkflowReponse, []string, error) {
+	// the modules edit the workflow with LF line endings, and the output has the line endings of the input
+	originalInput := inputYaml
+	inputYaml = lineending.Normalize(inputYaml)
+	queryStringParams, err := applyPolicy(ctx, queryStringParams, inputYaml, params)
+	if err != nil {
+		return nil, nil, err
 	}
-	if len(params) > 2 {
-		if v, ok := params[2].(map[string]string); ok {
-			maintainedActionsMap = v
-		}
+	// with strict=true, all checks are run, and the request fails when a construct could not be resolved
+	dryRun, strict := queryStringParams["dryRun"] == "true", queryStringParams["strict"] == "true"
+	if dryRun || strict {
+		queryStringParams = GetDryRunParams(queryStringParams)
 	}
-	if len(params) > 3 {
-		if v, ok := params[3].(map[string]string); ok {
-			actionCommitMap = v
-		}
+	opts, err := newOptions(ctx, queryStringParams, svc, params)
+	if err != nil {
+		return nil, nil, err
 	}
-	if len(params) > 4 {
-		if v, ok := params[4].(map[string]string); ok {
-			runnerLabelMap = v
-		}
+	// with enableLogging, the modules are logged at info instead of debug, along with the parameters and the input
+	logger, logLevel := opts.logger.With("workflow", queryStringParams["path"]), slog.LevelDebug
+	if repository := remediators.Repository(queryStringParams); repository != "" {
+		logger = logger.With("repository", repository)
 	}
-	if len(params) > 5 {
-		if v, ok := params[5].(hardenrunner.HardenRunnerConfig); ok {
-			hardenRunnerConfig = v
-		}
-	}
-	if queryStringParams["pinActions"] == "false" {
-		pinActions = false
+	if opts.isSet("enableLogging") {
+		logLevel = slog.LevelInfo
+		logger.Info("securing workflow", "params", queryStringParams, "input", inputYaml)
 	}
 
-	if queryStringParams["addHardenRunner"] == "false" {
-		addHardenRunner = false
-	}
+	secureWorkflowReponse := &permissions.SecureWorkflowReponse{FinalOutput: inputYaml, OriginalInput: originalInput}
 
-	if queryStringParams["addPermissions"] == "false" {
-		addPermissions = false
+	// the modules change the entries of the keys that take effect, which are the last of the keys that are repeated in a
+	// mapping, and the keys they override are reported
+	duplicateFindings := findDuplicateKeys(inputYaml)
+	for _, finding := range duplicateFindings {
+		logger.Warn("duplicate key", "message", finding.Message, "line", finding.Line)
 	}
 
-	if queryStringParams["ignoreMissingKBs"] == "true" {
-		ignoreMissingKBs = true
-	}
-
-	if queryStringParams["addProjectComment"] == "false" {
-		addProjectComment = false
+	// the changes of each module are the lines changed since the previous module ran
+	workflowReport, workflowPath, lastOutput := &report.Report{}, queryStringParams["path"], inputYaml
+	var outputs []moduleOutput
+	recordChanges := func(remediator Remediator) {
+		workflowReport.AddChanges(remediator.Name(), workflowPath, lastOutput, secureWorkflowReponse.FinalOutput, remediators.Confidence(remediator))
+		if secureWorkflowReponse.FinalOutput != lastOutput {
+			outputs = append(outputs, moduleOutput{module: remediator.Name(), output: secureWorkflowReponse.FinalOutput, edits: remediators.Edits(remediator)})
+		}
+		lastOutput = secureWorkflowReponse.FinalOutput
 	}
 
-	if len(maintainedActionsMap) > 0 {
-		replaceMaintainedActions = true
+	// runRemediator returns the findings of the remediator, and fixes them if apply is true. The errors of the remediator
+	// are added to the report, and the workflow keeps the changes it made before them, so the remediators after it still
+	// run on a workflow with the changes of all the others.
+	runRemediator := func(remediator Remediator, apply bool) ([]findings.Finding, bool) {
+		name := remediator.Name()
+		defer func(start time.Time) {
+			duration := time.Since(start)
+			metrics.ModuleDuration.Observe(duration.Seconds(), name)
+			logger.Log(opts.ctx, logLevel, "ran module", "module", name, "duration_ms", duration.Milliseconds())
+		}(time.Now())
+		detected, err := remediator.Detect(secureWorkflowReponse.FinalOutput)
+		if err != nil {
+			logger.Error("unable to run module", "module", name, "error", err)
+			secureWorkflowReponse.HasErrors = true
+			workflowReport.AddError(name, err)
+			recordChanges(remediator)
+			return detected, false
+		}
+		fixed := false
+		if apply {
+			output, changed, err := remediator.Apply(secureWorkflowReponse.FinalOutput, detected)
+			if err != nil {
+				logger.Error("unable to fix findings", "module", name, "error", err)
+				secureWorkflowReponse.HasErrors = true
+				workflowReport.AddError(name, err)
+			}
+			if output != "" {
+				secureWorkflowReponse.FinalOutput, fixed = output, changed
+			}
+			remediator.Report(workflowReport, workflowPath, detected)
+		}
+		recordChanges(remediator)
+		return detected, fixed
 	}
 
-	if len(runnerLabelMap) > 0 {
-		replaceRunnerLabels = true
+	// the analyzers report the findings of the input, and the findings of the ones that are remediated are marked as fixed
+	// at the end
+	analyzers := getAnalyzers(opts)
+	analyzerFindings := make([][]findings.Finding, len(analyzers))
+	var analyzed []string
+	for i, analyzer := range analyzers {
+		if !opts.isSet(analyzer.Param) {
+			continue
+		}
+		analyzed = append(analyzed, analyzer.Name)
+		start := time.Now()
+		analyzerFindings[i], err = analyzer.Find(inputYaml)
+		logger.Log(opts.ctx, logLevel, "ran analyzer", "module", analyzer.Name, "duration_ms", time.Since(start).Milliseconds())
+		if err != nil {
+			logger.Error("unable to run analyzer", "module", analyzer.Name, "error", err)
+			secureWorkflowReponse.HasErrors = true
+			workflowReport.AddError(analyzer.Name, err)
+		}
 	}
 
-	if queryStringParams["enableLogging"] == "true" {
-		enableLogging = true
+	// the maintained actions are suggested to replace unmaintained actions, if they are not passed
+	if checked, _ := opts.check("checkUnmaintainedActions", "replaceUnmaintainedActions"); checked && len(opts.suggestedReplacements) == 0 {
+		opts.suggestedReplacements, err = maintainedactions.LoadMaintainedActions(maintainedactions.GetMaintainedActionsFile())
+		if err != nil {
+			logger.Error("unable to load maintained actions", "error", err)
+			workflowReport.AddError("unmaintained", err)
+		}
 	}
-
-	if queryStringParams["addEmptyTopLevelPermissions"] == "true" {
-		addEmptyTopLevelPermissions = true
+	// the remediations run one after the other on the output of the previous one, and the changes of each are recorded
+	var remediationFindings []findings.Finding
+	changed, detectedBy := map[string]bool{}, map[string][]findings.Finding{}
+	var ran []string
+	for _, remediation := range getRemediations(opts) {
+		// the modules after the context is done are not run, since their requests would fail
+		if err := opts.ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		name := remediation.Remediator.Name()
+		ran = append(ran, name)
+		detected, fixed := runRemediator(remediation.Remediator, remediation.Fix)
+		remediationFindings = append(remediationFindings, detected...)
+		changed[name], detectedBy[name] = fixed, detected
+		// the response of the permissions has the errors of the jobs, and whether the workflow has permissions already
+		if remediator, ok := remediation.Remediator.(*permissionsRemediator); ok && remediator.response != nil {
+			permissionsResponse := remediator.response
+			secureWorkflowReponse.HasErrors = secureWorkflowReponse.HasErrors || permissionsResponse.HasErrors
+			secureWorkflowReponse.AlreadyHasPermissions = permissionsResponse.AlreadyHasPermissions
+			secureWorkflowReponse.IncorrectYaml = permissionsResponse.IncorrectYaml
+			secureWorkflowReponse.JobErrors = permissionsResponse.JobErrors
+			secureWorkflowReponse.MissingActions = permissionsResponse.MissingActions
+		}
 	}
 
-	if queryStringParams["skipHardenRunnerForContainers"] == "true" {
-		skipHardenRunnerForContainers = true
+	// a module that broke the workflow fails the request, so the broken workflow is not proposed in a pull request
+	if err := validateOutputs(inputYaml, outputs); err != nil {
+		logger.Error("module produced an invalid workflow", "error", err)
+		return nil, nil, err
 	}
-
-	if queryStringParams["replaceActionByMajorTag"] == "true" {
-		replaceActionByMajorTag = true
+	if opts.isSet("verifyEdits") {
+		if err := verifyEdits(inputYaml, outputs); err != nil {
+			logger.Error("module made unintended edits", "error", err)
+			return nil, nil, err
+		}
 	}
 
-	if enableLogging {
-		// Log query parameters
-		paramsJSON, _ := json.MarshalIndent(queryStringParams, "", "  ")
-		log.Printf("SecureWorkflow called with query parameters: %s", paramsJSON)
-
-		// Log input YAML (complete)
-		log.Printf("Input YAML: %s", inputYaml)
+	// Setting appropriate flags
+	secureWorkflowReponse.PinnedActions = changed["pin"]
+	secureWorkflowReponse.AddedHardenRunner = changed["hardenrunner"]
+	secureWorkflowReponse.AddedPermissions = changed["permissions"]
+	secureWorkflowReponse.AddedMaintainedActions = changed["maintainedactions"] || changed["unmaintained"] || changed["actionpolicy"]
+	secureWorkflowReponse.ReplacedRunnerLabels = changed["runnerlabel"]
+	secureWorkflowReponse.RemovedUnnecessaryTokens = changed["githubtoken"]
+	secureWorkflowReponse.AddedForkPullRequestGuards = changed["forkguard"]
+	secureWorkflowReponse.AddedShellDefaults = changed["shelldefaults"]
+	secureWorkflowReponse.AddedRepositoryGuards = changed["repoguard"]
+	secureWorkflowReponse.PinnedRunTools = changed["pintools"]
+	secureWorkflowReponse.AddedBuildProvenance = changed["attestation"]
+	secureWorkflowReponse.AddedCosignSigning = changed["signing"]
+	secureWorkflowReponse.AddedSBOM = changed["sbom"]
+	secureWorkflowReponse.FixedVulnerableActions = changed["advisories"]
+	secureWorkflowReponse.FixedTyposquattedActions = changed["typosquat"]
+	secureWorkflowReponse.FixedDispatchInputs = changed["dispatchinputs"]
+	secureWorkflowReponse.RewroteDeprecatedCommands = changed["deprecatedcommands"]
+	secureWorkflowReponse.FixedSecretBuildArgs = changed["buildargs"]
+	secureWorkflowReponse.SanitizedUntrustedEnvWrites = changed["githubenv"]
+	for _, finding := range detectedBy["actionpolicy"] {
+		secureWorkflowReponse.HasPolicyViolations = secureWorkflowReponse.HasPolicyViolations || !finding.Fixed
 	}
-
-	secureWorkflowReponse := &permissions.SecureWorkflowReponse{FinalOutput: inputYaml, OriginalInput: inputYaml}
-	var err error
-	if addPermissions {
-		if enableLogging {
-			log.Printf("Adding job level permissions")
-		}
-		secureWorkflowReponse, err = permissions.AddJobLevelPermissions(secureWorkflowReponse.FinalOutput, addEmptyTopLevelPermissions)
-		secureWorkflowReponse.OriginalInput = inputYaml
-		if err != nil {
-			if enableLogging {
-				log.Printf("Error adding job level permissions: %v", err)
-			}
-			return nil, err
-		} else {
-			if !secureWorkflowReponse.HasErrors || permissions.ShouldAddWorkflowLevelPermissions(secureWorkflowReponse.JobErrors) {
-				if enableLogging {
-					log.Printf("Adding workflow level permissions")
-				}
-				secureWorkflowReponse.FinalOutput, err = permissions.AddWorkflowLevelPermissions(secureWorkflowReponse.FinalOutput, addProjectComment, addEmptyTopLevelPermissions)
-				if err != nil {
-					if enableLogging {
-						log.Printf("Error adding workflow level permissions: %v", err)
-					}
-					secureWorkflowReponse.HasErrors = true
-				} else {
-					// reset the error
-					// this is done because workflow perms have been added
-					// only job errors were that perms already existed
-					secureWorkflowReponse.HasErrors = false
-				}
-			}
-			if len(secureWorkflowReponse.MissingActions) > 0 && !ignoreMissingKBs {
-				if enableLogging {
-					log.Printf("Storing missing actions: %v", secureWorkflowReponse.MissingActions)
-				}
-				StoreMissingActions(secureWorkflowReponse.MissingActions, svc)
-			}
+	var allFindings []findings.Finding
+	for i, analyzer := range analyzers {
+		if analyzer.MarkFixed && len(analyzerFindings[i]) > 0 {
+			remaining, _ := analyzer.Find(secureWorkflowReponse.FinalOutput)
+			findings.MarkFixed(analyzerFindings[i], remaining)
 		}
-		// if there are no errors, then we must have added perms
-		// if there are already perms at workflow level, that is treated as an error condition
-		addedPermissions = !secureWorkflowReponse.HasErrors
+		allFindings = append(allFindings, analyzerFindings[i]...)
 	}
-
-	if replaceMaintainedActions {
-		secureWorkflowReponse.FinalOutput, replacedMaintainedActions, err = maintainedactions.ReplaceActions(secureWorkflowReponse.FinalOutput, maintainedActionsMap, replaceActionByMajorTag)
+	secureWorkflowReponse.Findings = append(append(duplicateFindings, allFindings...), remediationFindings...)
+	if opts.isSet("computeScore") {
+		secureWorkflowReponse.Score, err = getScoreChange(opts, inputYaml, secureWorkflowReponse.FinalOutput)
 		if err != nil {
-			log.Printf("Error replacing maintained actions: %v", err)
+			logger.Error("unable to compute score", "error", err)
 			secureWorkflowReponse.HasErrors = true
+			workflowReport.AddError("score", err)
 		}
 	}
-
-	if replaceRunnerLabels {
-		if enableLogging {
-			log.Printf("Replacing runner labels")
-		}
-		secureWorkflowReponse.FinalOutput, replacedRunnerLabels, err = runnerlabel.ReplaceRunnerLabels(secureWorkflowReponse.FinalOutput, runnerLabelMap)
+	// the constructs that were not resolved, and the errors of the modules, fail the checks in strict mode
+	if strict {
+		unresolved, err := findUnresolved(secureWorkflowReponse.FinalOutput, ran, opts.exemptedActions, secureWorkflowReponse.JobErrors, secureWorkflowReponse.MissingActions)
 		if err != nil {
-			log.Printf("Error replacing runner labels: %v", err)
+			logger.Error("unable to find unresolved constructs", "error", err)
 			secureWorkflowReponse.HasErrors = true
+			workflowReport.AddError("strict", err)
 		}
-		if enableLogging {
-			log.Printf("Replaced runner labels: %v", replacedRunnerLabels)
+		for _, finding := range unresolved {
+			logger.Warn("unresolved construct", "rule", finding.RuleID, "message", finding.Message, "line", finding.Line)
 		}
+		secureWorkflowReponse.Findings = append(secureWorkflowReponse.Findings, unresolved...)
+		// the jobs that already have permissions are reported as errors, which are not errors of the modules
+		secureWorkflowReponse.FailedStrictChecks = len(unresolved) > 0 || hasModuleErrors(workflowReport)
+	}
+	// the errors of the modules whose requests were canceled are not returned, so they are not cached
+	if err := opts.ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	// the status of each module that ran is returned, since the modules after a module that failed still run
+	workflowReport.SetStatuses(append(analyzed, ran...))
+	secureWorkflowReponse.Report = workflowReport
+	if dryRun {
+		// the changes are only proposed in the report
+		secureWorkflowReponse.FinalOutput = inputYaml
+	} else {
+		metrics.ObserveReport(workflowReport)
 	}
+	secureWorkflowReponse.FinalOutput = lineending.Restore(originalInput, secureWorkflowReponse.FinalOutput)
+	secureWorkflowReponse.UsingSecureRepoPAT = pin.UsingSecureRepoPAT()
+
+	logger.Log(opts.ctx, logLevel, "secured workflow",
+		"pinned_actions", secureWorkflowReponse.PinnedActions,
+		"added_harden_runner", secureWorkflowReponse.AddedHardenRunner,
+		"added_permissions", secureWorkflowReponse.AddedPermissions,
+		"added_maintained_actions", secureWorkflowReponse.AddedMaintainedActions,
+		"replaced_runner_labels", secureWorkflowReponse.ReplacedRunnerLabels,
+		"has_errors", secureWorkflowReponse.HasErrors,
+		"failed_strict_checks", secureWorkflowReponse.FailedStrictChecks,
+		"using_secure_repo_pat", secureWorkflowReponse.UsingSecureRepoPAT)
+
+	return secureWorkflowReponse, ran, nil
+}
 
-	if pinActions {
-		if enableLogging {
-			log.Printf("Pinning GitHub Actions")
+// IsRemediated returns whether each remediation enabled by the query parameters would leave the workflow unchanged, by
+// the name of its module, so integrations that run on every change of a repository can skip the workflows that are
+// already remediated instead of opening pull requests without changes. The remediations run on the workflow one by one,
+// with the options of SecureWorkflow, and running SecureWorkflow on its own output leaves it unchanged. A file with
+// several documents is remediated by a remediation if each of its documents is.
+func IsRemediated(ctx context.Context, queryStringParams map[string]string, inputYaml string, svc dynamodbiface.DynamoDBAPI, params Options) (map[string]bool, error) {
+	inputYaml = lineending.Normalize(inputYaml)
+	if multidoc.Count(inputYaml) <= 1 {
+		return isDocumentRemediated(ctx, queryStringParams, inputYaml, svc, params)
+	}
+	remediated := map[string]bool{}
+	for _, document := range multidoc.Split(inputYaml) {
+		if document.IsEmpty() {
+			continue
 		}
-		pinnedAction, pinnedDocker := false, false
-		secureWorkflowReponse.FinalOutput, pinnedAction, err = pin.PinActions(secureWorkflowReponse.FinalOutput, exemptedActions, pinToImmutable, actionCommitMap)
+		documentRemediated, err := isDocumentRemediated(ctx, queryStringParams, document.Content, svc, params)
 		if err != nil {
-			if enableLogging {
-				log.Printf("Error pinning actions: %v", err)
-			}
-			return secureWorkflowReponse, err
+			return nil, err
 		}
-		secureWorkflowReponse.FinalOutput, pinnedDocker, _ = pin.PinDocker(secureWorkflowReponse.FinalOutput)
-		pinnedActions = pinnedAction || pinnedDocker
-		if enableLogging {
-			log.Printf("Pinned actions: %v, Pinned docker: %v", pinnedAction, pinnedDocker)
+		for name, documentIsRemediated := range documentRemediated {
+			if previous, found := remediated[name]; !found || previous {
+				remediated[name] = documentIsRemediated
+			}
 		}
 	}
+	return remediated, nil
+}
 
-	if addHardenRunner {
-		if enableLogging {
-			log.Printf("Adding harden runner action")
-		}
-		// Always pin harden-runner unless exempted
-		pinHardenRunner := true
-		if pin.ActionExists(HardenRunnerActionPath, exemptedActions) {
-			pinHardenRunner = false
-			if enableLogging {
-				log.Printf("Harden runner action is exempted from pinning")
-			}
-		}
-		secureWorkflowReponse.FinalOutput, addedHardenRunner, _ = hardenrunner.AddAction(secureWorkflowReponse.FinalOutput, hardenRunnerConfig, pinHardenRunner, pinToImmutable, skipHardenRunnerForContainers)
-		if enableLogging {
-			log.Printf("Added harden runner: %v", addedHardenRunner)
+// isDocumentRemediated returns whether each remediation would leave the workflow with one document unchanged
+func isDocumentRemediated(ctx context.Context, queryStringParams map[string]string, inputYaml string, svc dynamodbiface.DynamoDBAPI, params Options) (map[string]bool, error) {
+	queryStringParams, err := applyPolicy(ctx, queryStringParams, inputYaml, params)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := newOptions(ctx, queryStringParams, svc, params)
+	if err != nil {
+		return nil, err
+	}
+	if checked, _ := opts.check("checkUnmaintainedActions", "replaceUnmaintainedActions"); checked && len(opts.suggestedReplacements) == 0 {
+		opts.suggestedReplacements, err = maintainedactions.LoadMaintainedActions(maintainedactions.GetMaintainedActionsFile())
+		if err != nil {
+			return nil, fmt.Errorf("unable to load maintained actions: %v", err)
 		}
 	}
-
-	// Setting appropriate flags
-	secureWorkflowReponse.PinnedActions = pinnedActions
-	secureWorkflowReponse.AddedHardenRunner = addedHardenRunner
-	secureWorkflowReponse.AddedPermissions = addedPermissions
-	secureWorkflowReponse.AddedMaintainedActions = replacedMaintainedActions
-	secureWorkflowReponse.ReplacedRunnerLabels = replacedRunnerLabels
-	secureWorkflowReponse.UsingSecureRepoPAT = pin.UsingSecureRepoPAT()
-
-	if enableLogging {
-		log.Printf("SecureWorkflow complete - PinnedActions: %v, AddedHardenRunner: %v, AddedPermissions: %v, AddedMaintainedActions: %v, ReplacedRunnerLabels: %v, HasErrors: %v, UsingSecureRepoPAT: %v",
-			secureWorkflowReponse.PinnedActions,
-			secureWorkflowReponse.AddedHardenRunner,
-			secureWorkflowReponse.AddedPermissions,
-			secureWorkflowReponse.AddedMaintainedActions,
-			secureWorkflowReponse.ReplacedRunnerLabels,
-			secureWorkflowReponse.HasErrors,
-			secureWorkflowReponse.UsingSecureRepoPAT)
+	remediated := map[string]bool{}
+	for _, remediation := range getRemediations(opts) {
+		name := remediation.Remediator.Name()
+		remediated[name], err = isRemediated(remediation, inputYaml)
+		if err != nil {
+			return nil, fmt.Errorf("unable to check %s: %v", name, err)
+		}
 	}
-
-	return secureWorkflowReponse, nil
+	return remediated, nil
 }
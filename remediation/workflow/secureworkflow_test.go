@@ -1,15 +1,25 @@
 package workflow
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"io/ioutil"
 	"log"
+	"log/slog"
 	"os"
 	"path"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/jarcoal/httpmock"
+	"github.com/step-security/secure-repo/remediation/report"
 	"github.com/step-security/secure-repo/remediation/workflow/maintainedactions"
 	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+	"github.com/step-security/secure-repo/remediation/workflow/policy"
+	"github.com/step-security/secure-repo/remediation/workflow/triggers"
+	"gopkg.in/yaml.v3"
 )
 
 func TestSecureWorkflow(t *testing.T) {
@@ -22,7 +32,7 @@ func TestSecureWorkflow(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/commits/v1",
 		httpmock.NewStringResponder(200, `544eadc6bf3d226fd7a7a9f0dc5b5bf7ca0675b9`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/git/matching-refs/tags/v1.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -64,7 +74,7 @@ func TestSecureWorkflow(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/commits/v2",
 		httpmock.NewStringResponder(200, `ebacdc22ef6c2cfb85ee5ded8f2e640f4c776dd5`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/git/matching-refs/tags/v2.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -83,7 +93,7 @@ func TestSecureWorkflow(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/github/super-linter/commits/v3",
 		httpmock.NewStringResponder(200, `34b2f8032d759425f6b42ea2e52231b33ae05401`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/github/super-linter/git/matching-refs/tags/v3.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/github/super-linter/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -109,25 +119,14 @@ func TestSecureWorkflow(t *testing.T) {
 			  ]`),
 	)
 
-	// Mock GetMajorTagIfExists calls (GET /git/ref/tags/vN) for ReplaceActions
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/action-semantic-pull-request/git/ref/tags/v5",
-		httpmock.NewStringResponder(200, `{"ref":"refs/tags/v5","object":{"sha":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","type":"commit"}}`))
-
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/skip-duplicate-actions/git/ref/tags/v5",
-		httpmock.NewStringResponder(200, `{"ref":"refs/tags/v5","object":{"sha":"e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5","type":"commit"}}`))
-
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/git-restore-mtime-action/git/ref/tags/v1",
-		httpmock.NewStringResponder(200, `{"ref":"refs/tags/v1","object":{"sha":"f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1","type":"commit"}}`))
-
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/actions-cache/git/ref/tags/v1",
-		httpmock.NewStringResponder(200, `{"ref":"refs/tags/v1","object":{"sha":"dddddddddddddddddddddddddddddddddddddddd","type":"commit"}}`))
-
-	// Mock PinActions calls for step-security/action-semantic-pull-request@v5
+	// Mock PinActions calls for step-security/action-semantic-pull-request@v5, whose tags are also looked up by
+	// ReplaceActions for the major version
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/action-semantic-pull-request/commits/v5",
 		httpmock.NewStringResponder(200, `a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p6q7r8s9t0`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/action-semantic-pull-request/git/matching-refs/tags/v5.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/action-semantic-pull-request/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[
+			{"ref":"refs/tags/v5","object":{"sha":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","type":"commit"}},
 			{
 				"ref": "refs/tags/v5.5.5",
 				"object": {
@@ -141,8 +140,9 @@ func TestSecureWorkflow(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/skip-duplicate-actions/commits/v5",
 		httpmock.NewStringResponder(200, `e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/skip-duplicate-actions/git/matching-refs/tags/v5.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/skip-duplicate-actions/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[
+			{"ref":"refs/tags/v5","object":{"sha":"e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5e5","type":"commit"}},
 			{
 				"ref": "refs/tags/v5.3.0",
 				"object": {
@@ -156,8 +156,9 @@ func TestSecureWorkflow(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/git-restore-mtime-action/commits/v1",
 		httpmock.NewStringResponder(200, `f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/git-restore-mtime-action/git/matching-refs/tags/v1.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/git-restore-mtime-action/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[
+			{"ref":"refs/tags/v1","object":{"sha":"f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1f1","type":"commit"}},
 			{
 				"ref": "refs/tags/v1.1.0",
 				"object": {
@@ -171,8 +172,9 @@ func TestSecureWorkflow(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/actions-cache/commits/v1",
 		httpmock.NewStringResponder(200, `d4e5f6g7h8i9j0k1l2m3n4o5p6q7r8s9t0a1b2c3`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/actions-cache/git/matching-refs/tags/v1.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/actions-cache/git/matching-refs/tags",
 		httpmock.NewStringResponder(200, `[
+			{"ref":"refs/tags/v1","object":{"sha":"dddddddddddddddddddddddddddddddddddddddd","type":"commit"}},
 			{
 				"ref": "refs/tags/v1.0.0",
 				"object": {
@@ -189,6 +191,8 @@ func TestSecureWorkflow(t *testing.T) {
 		wantAddedPermissions       bool
 		wantAddedMaintainedActions bool
 		wantError                  bool
+		// wantFailedModules are the modules whose status is failed, while the others still remediate the workflow
+		wantFailedModules []string
 	}{
 		{fileName: "oneJob.yml", wantPinnedActions: true, wantAddedHardenRunner: true, wantAddedPermissions: false, wantAddedMaintainedActions: true, wantError: false},
 		{fileName: "allscenarios.yml", wantPinnedActions: true, wantAddedHardenRunner: true, wantAddedPermissions: true, wantError: false},
@@ -197,8 +201,8 @@ func TestSecureWorkflow(t *testing.T) {
 		{fileName: "nopin.yml", wantPinnedActions: false, wantAddedHardenRunner: true, wantAddedPermissions: true, wantError: false},
 		{fileName: "allperms.yml", wantPinnedActions: false, wantAddedHardenRunner: false, wantAddedPermissions: true, wantError: false},
 		{fileName: "multiplejobperms.yml", wantPinnedActions: false, wantAddedHardenRunner: false, wantAddedPermissions: true, wantError: false},
-		{fileName: "error.yml", wantPinnedActions: false, wantAddedHardenRunner: false, wantAddedPermissions: false, wantError: false},
-		{fileName: "missingaction.yml", wantPinnedActions: false, wantAddedHardenRunner: false, wantAddedPermissions: false, wantError: true},
+		{fileName: "error.yml", wantPinnedActions: false, wantAddedHardenRunner: false, wantAddedPermissions: false, wantError: false, wantFailedModules: []string{"permissions"}},
+		{fileName: "missingaction.yml", wantPinnedActions: true, wantAddedHardenRunner: true, wantAddedPermissions: false, wantError: false, wantFailedModules: []string{"pin"}},
 		{fileName: "compositeAction.yml", wantPinnedActions: true, wantAddedHardenRunner: false, wantAddedPermissions: false, wantAddedMaintainedActions: true, wantError: false},
 	}
 	for _, test := range tests {
@@ -240,6 +244,7 @@ func TestSecureWorkflow(t *testing.T) {
 			queryParams["replaceActionByMajorTag"] = "true"
 		}
 		queryParams["addProjectComment"] = "false"
+		queryParams["verifyEdits"] = "true"
 
 		var output *permissions.SecureWorkflowReponse
 		var actionMap map[string]string
@@ -248,9 +253,9 @@ func TestSecureWorkflow(t *testing.T) {
 			if err != nil {
 				t.Errorf("unable to load the file %s", err)
 			}
-			output, err = SecureWorkflow(queryParams, string(input), &mockDynamoDBClient{}, []string{"actions/*"}, false, actionMap)
+			output, err = SecureWorkflow(context.Background(), queryParams, string(input), &mockDynamoDBClient{}, Options{ExemptedActions: []string{"actions/*"}, MaintainedActions: actionMap})
 		} else {
-			output, err = SecureWorkflow(queryParams, string(input), &mockDynamoDBClient{})
+			output, err = SecureWorkflow(context.Background(), queryParams, string(input), &mockDynamoDBClient{}, Options{})
 		}
 
 		if test.wantError {
@@ -292,6 +297,21 @@ func TestSecureWorkflow(t *testing.T) {
 			t.Errorf("test failed %s did not match expected AddedMaintainedActions value. Expected:%v Actual:%v", test.fileName, test.wantAddedMaintainedActions, output.AddedMaintainedActions)
 		}
 
+		var failedModules []string
+		for _, status := range output.Report.Statuses {
+			if status.Status == report.StatusFailed {
+				failedModules = append(failedModules, status.Module)
+				if len(status.Errors) == 0 {
+					t.Errorf("test failed %s module %s failed without errors", test.fileName, status.Module)
+				}
+			}
+		}
+		if !reflect.DeepEqual(failedModules, test.wantFailedModules) {
+			t.Errorf("test failed %s did not match expected failed modules. Expected:%v Actual:%v", test.fileName, test.wantFailedModules, failedModules)
+		}
+		if output.HasErrors != (len(test.wantFailedModules) > 0) {
+			t.Errorf("test failed %s did not match expected HasErrors value. Actual:%v", test.fileName, output.HasErrors)
+		}
 	}
 }
 
@@ -306,7 +326,7 @@ func TestSecureWorkflowContainerJob(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/commits/v3",
 		httpmock.NewStringResponder(200, `c85c95e3d7251135ab7dc9ce3241c5835cc595a9`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/git/matching-refs/tags/v3.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -323,7 +343,7 @@ func TestSecureWorkflowContainerJob(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/commits/v2",
 		httpmock.NewStringResponder(200, `17d0e2bd7d51742c71671bd19fa12bdc9d40a3d6`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/git/matching-refs/tags/v2.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -351,7 +371,7 @@ func TestSecureWorkflowContainerJob(t *testing.T) {
 	queryParams["skipHardenRunnerForContainers"] = "true"
 	queryParams["addProjectComment"] = "false"
 
-	output, err := SecureWorkflow(queryParams, string(input), &mockDynamoDBClient{})
+	output, err := SecureWorkflow(context.Background(), queryParams, string(input), &mockDynamoDBClient{}, Options{})
 
 	if err != nil {
 		t.Errorf("Error not expected")
@@ -395,7 +415,7 @@ func TestSecureWorkflowEmptyPermissions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/commits/v2",
 		httpmock.NewStringResponder(200, `ee0669bd1cc54295c223e0bb666b733df41de1c5`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/git/matching-refs/tags/v2.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -412,7 +432,7 @@ func TestSecureWorkflowEmptyPermissions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/setup-node/commits/v1",
 		httpmock.NewStringResponder(200, `f1f314fca9dfce2769ece7d933488f076716723e`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/setup-node/git/matching-refs/tags/v1.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/setup-node/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -429,7 +449,7 @@ func TestSecureWorkflowEmptyPermissions(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/commits/v2",
 		httpmock.NewStringResponder(200, `17d0e2bd7d51742c71671bd19fa12bdc9d40a3d6`))
 
-	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/git/matching-refs/tags/v2.",
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/git/matching-refs/tags",
 		httpmock.NewStringResponder(200,
 			`[
 				{
@@ -456,7 +476,7 @@ func TestSecureWorkflowEmptyPermissions(t *testing.T) {
 	queryParams["addEmptyTopLevelPermissions"] = "true"
 	queryParams["addProjectComment"] = "false"
 
-	output, err := SecureWorkflow(queryParams, string(input), &mockDynamoDBClient{})
+	output, err := SecureWorkflow(context.Background(), queryParams, string(input), &mockDynamoDBClient{}, Options{})
 
 	if err != nil {
 		t.Errorf("Error not expected")
@@ -512,7 +532,7 @@ func TestSecureWorkflowRunnerLabels(t *testing.T) {
 		"windows-latest": "step-windows",
 	}
 
-	output, err := SecureWorkflow(queryParams, string(input), &mockDynamoDBClient{}, []string{}, false, map[string]string{}, map[string]string{}, runnerLabelMap)
+	output, err := SecureWorkflow(context.Background(), queryParams, string(input), &mockDynamoDBClient{}, Options{RunnerLabels: runnerLabelMap})
 
 	if err != nil {
 		t.Errorf("Error not expected: %v", err)
@@ -533,3 +553,758 @@ func TestSecureWorkflowRunnerLabels(t *testing.T) {
 		t.Errorf("Expected ReplacedRunnerLabels to be true, got false")
 	}
 }
+
+func TestSecureWorkflowRemoveUnnecessaryTokens(t *testing.T) {
+	const inputDirectory = "../../testfiles/githubtoken/input"
+	const outputDirectory = "../../testfiles/githubtoken/output"
+
+	input, err := ioutil.ReadFile(path.Join(inputDirectory, "unnecessary-token-inputs.yml"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	queryParams := make(map[string]string)
+	queryParams["removeUnnecessaryTokens"] = "true"
+	queryParams["addHardenRunner"] = "false"
+	queryParams["pinActions"] = "false"
+	queryParams["addPermissions"] = "false"
+	queryParams["addProjectComment"] = "false"
+
+	output, err := SecureWorkflow(context.Background(), queryParams, string(input), &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Errorf("Error not expected: %v", err)
+	}
+
+	expectedOutput, err := ioutil.ReadFile(path.Join(outputDirectory, "unnecessary-token-inputs.yml"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if output.FinalOutput != string(expectedOutput) {
+		t.Errorf("test failed unnecessary-token-inputs.yml did not match expected output\nExpected:\n%s\n\nGot:\n%s",
+			string(expectedOutput), output.FinalOutput)
+	}
+
+	if !output.RemovedUnnecessaryTokens {
+		t.Errorf("Expected RemovedUnnecessaryTokens to be true, got false")
+	}
+}
+
+func TestSecureWorkflowReport(t *testing.T) {
+	input := `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - run: make
+  release:
+    runs-on: ubuntu-latest
+    steps:
+      - run: gh release create
+        env:
+          GH_TOKEN: ${{ secrets.GITHUB_TOKEN }}
+`
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	queryParams := make(map[string]string)
+	queryParams["addHardenRunner"] = "false"
+	queryParams["pinActions"] = "false"
+	queryParams["addProjectComment"] = "false"
+	queryParams["addShellDefaults"] = "true"
+	queryParams["path"] = ".github/workflows/ci.yml"
+
+	output, err := SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+
+	// the default shell is added after the steps added by the other modules
+	modules := output.Report.Modules
+	if len(modules) != 2 || modules[0].Name != "permissions" || modules[1].Name != "shelldefaults" {
+		reportJSON, _ := json.Marshal(output.Report)
+		t.Fatalf("unexpected report %s", reportJSON)
+	}
+	if len(modules[1].Changes) != 3 {
+		t.Errorf("expected 3 lines added for the default shell, got %v", modules[1].Changes)
+	}
+	change := modules[1].Changes[0]
+	if change.File != ".github/workflows/ci.yml" || change.Line != 3 || change.Kind != "added" || change.After != "defaults:" {
+		t.Errorf("unexpected change %+v", change)
+	}
+	// the job using the token in a run step is skipped
+	if len(modules[0].Skipped) != 1 || modules[0].Skipped[0].Item != "release" || !strings.HasPrefix(modules[0].Skipped[0].Reason, "KnownIssue-2") {
+		t.Errorf("unexpected skipped items %+v", modules[0].Skipped)
+	}
+}
+
+func TestSecureWorkflowDryRun(t *testing.T) {
+	input := `name: CI
+on: pull_request_target
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ github.event.pull_request.title }}"
+`
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	queryParams := make(map[string]string)
+	queryParams["addHardenRunner"] = "false"
+	queryParams["pinActions"] = "false"
+	queryParams["addProjectComment"] = "false"
+	queryParams["checkDangerousTriggers"] = "false"
+	queryParams["dryRun"] = "true"
+
+	output, err := SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+
+	if output.FinalOutput != input {
+		t.Errorf("expected the input to be unchanged, got\n%s", output.FinalOutput)
+	}
+	if !output.AddedPermissions || len(output.Report.Modules) == 0 || output.Report.Modules[0].Name != "permissions" {
+		t.Errorf("expected permissions to be proposed, got %+v", output.Report)
+	}
+
+	// the checks are enabled, unless they are set to false
+	rules := map[string]bool{}
+	for _, finding := range output.Findings {
+		rules[finding.RuleID] = true
+	}
+	if !rules["missing-permissions"] || !rules["script-injection"] || rules["dangerous-trigger"] {
+		t.Errorf("unexpected findings %+v", output.Findings)
+	}
+	if _, found := queryParams["checkScriptInjection"]; found {
+		t.Errorf("expected the query parameters not to be changed")
+	}
+}
+
+// countAnchors returns the number of anchors and aliases in the node
+func countAnchors(node *yaml.Node) (anchors, aliases int) {
+	if node.Anchor != "" {
+		anchors++
+	}
+	if node.Kind == yaml.AliasNode {
+		aliases++
+	}
+	for _, child := range node.Content {
+		a, b := countAnchors(child)
+		anchors, aliases = anchors+a, aliases+b
+	}
+	return anchors, aliases
+}
+
+// anchorsQueryParams returns the query parameters of the workflows in testfiles/anchors, which enable the modules that
+// change the workflows without requests to GitHub
+func anchorsQueryParams() map[string]string {
+	return map[string]string{"owner": "owner", "repo": "repo", "pinActions": "false", "addProjectComment": "false",
+		"removeUnnecessaryTokens": "true", "fixDispatchInputs": "true", "fixSecretBuildArgs": "true",
+		"sanitizeUntrustedEnvWrites": "true", "addForkPullRequestGuards": "true", "addRepositoryGuards": "true",
+		"rewriteDeprecatedCommands": "true", "addShellDefaults": "true", "addSBOM": "true", "addBuildProvenance": "true",
+		"addCosignSigning": "true", "verifyEdits": "true"}
+}
+
+func TestSecureWorkflowAnchors(t *testing.T) {
+	const inputDirectory = "../../testfiles/anchors/input"
+	const outputDirectory = "../../testfiles/anchors/output"
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/commits/v2",
+		httpmock.NewStringResponder(200, `ebacdc22ef6c2cfb85ee5ded8f2e640f4c776dd5`))
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/git/matching-refs/tags",
+		httpmock.NewStringResponder(200,
+			`[
+				{
+				  "ref": "refs/tags/v2.0.0",
+				  "object": {
+					"sha": "ebacdc22ef6c2cfb85ee5ded8f2e640f4c776dd5",
+					"type": "commit"
+				  }
+				}
+			  ]`),
+	)
+
+	files, err := ioutil.ReadDir(inputDirectory)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	// the steps, jobs and values shared with anchors are changed once, at the anchor
+	queryParams, runnerLabels := anchorsQueryParams(), map[string]string{"ubuntu-latest": "step-ubuntu"}
+	for _, file := range files {
+		input, err := ioutil.ReadFile(path.Join(inputDirectory, file.Name()))
+		if err != nil {
+			log.Fatal(err)
+		}
+		output, err := SecureWorkflow(context.Background(), queryParams, string(input), &mockDynamoDBClient{}, Options{RunnerLabels: runnerLabels})
+		if err != nil {
+			t.Errorf("Error not expected for %s: %v", file.Name(), err)
+			continue
+		}
+		expectedOutput, err := ioutil.ReadFile(path.Join(outputDirectory, file.Name()))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if output.FinalOutput != string(expectedOutput) {
+			t.Errorf("test failed %s did not match expected output\nExpected:\n%s\n\nGot:\n%s",
+				file.Name(), string(expectedOutput), output.FinalOutput)
+		}
+
+		var inputNode, outputNode yaml.Node
+		if err := yaml.Unmarshal(input, &inputNode); err != nil {
+			log.Fatal(err)
+		}
+		if err := yaml.Unmarshal([]byte(output.FinalOutput), &outputNode); err != nil {
+			t.Errorf("unable to parse the output of %s: %v", file.Name(), err)
+			continue
+		}
+		inputAnchors, inputAliases := countAnchors(&inputNode)
+		outputAnchors, outputAliases := countAnchors(&outputNode)
+		if outputAnchors != inputAnchors || outputAliases != inputAliases {
+			t.Errorf("%s has %d anchors and %d aliases, want %d and %d", file.Name(), outputAnchors, outputAliases,
+				inputAnchors, inputAliases)
+		}
+	}
+}
+
+func TestSecureWorkflowLineEndings(t *testing.T) {
+	input := "name: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n"
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	queryParams := map[string]string{"addHardenRunner": "false", "pinActions": "false", "addProjectComment": "false"}
+	want, err := SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if !want.AddedPermissions {
+		t.Fatalf("expected permissions to be added, got\n%s", want.FinalOutput)
+	}
+
+	// the lines added to a workflow authored on Windows end with CRLF, like the other lines
+	crlfInput := strings.ReplaceAll(input, "\n", "\r\n")
+	output, err := SecureWorkflow(context.Background(), queryParams, crlfInput, &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if got := strings.ReplaceAll(want.FinalOutput, "\n", "\r\n"); output.FinalOutput != got {
+		t.Errorf("FinalOutput = %q, want %q", output.FinalOutput, got)
+	}
+	if output.OriginalInput != crlfInput {
+		t.Errorf("OriginalInput = %q, want the input", output.OriginalInput)
+	}
+
+	queryParams["dryRun"] = "true"
+	output, err = SecureWorkflow(context.Background(), queryParams, crlfInput, &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if output.FinalOutput != crlfInput {
+		t.Errorf("expected the input to be unchanged, got %q", output.FinalOutput)
+	}
+}
+
+func TestSecureWorkflowLogger(t *testing.T) {
+	input := `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make build
+`
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	queryParams := map[string]string{"addHardenRunner": "false", "pinActions": "false", "enableLogging": "true",
+		"owner": "octo-org", "repo": "app", "path": ".github/workflows/ci.yml"}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil)).With("request_id", "request-1")
+	_, err := SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{Logger: logger})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+
+	var moduleLogged bool
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("unable to parse log %s: %v", line, err)
+		}
+		if record["request_id"] != "request-1" || record["repository"] != "octo-org/app" || record["workflow"] != ".github/workflows/ci.yml" {
+			t.Errorf("log does not have the request id, repository and workflow: %s", line)
+		}
+		if record["msg"] == "ran module" && record["module"] == "permissions" {
+			moduleLogged = record["duration_ms"] != nil
+		}
+	}
+	if !moduleLogged {
+		t.Errorf("expected the permissions module to be logged with its duration, got\n%s", logs.String())
+	}
+}
+
+// selfHostedPolicy is a policy.Evaluator that disables Harden-Runner for workflows with jobs on self-hosted runners
+type selfHostedPolicy struct {
+	input *policy.Input
+}
+
+func (p *selfHostedPolicy) Evaluate(ctx context.Context, input *policy.Input) (*policy.Decision, error) {
+	p.input = input
+	decision := &policy.Decision{Params: map[string]string{"addShellDefaults": "true"}}
+	for _, job := range input.Workflow.Jobs {
+		for _, label := range job.RunsOn {
+			if label == "self-hosted" {
+				decision.Params["addHardenRunner"] = "false"
+			}
+		}
+	}
+	return decision, nil
+}
+
+func TestSecureWorkflowPolicy(t *testing.T) {
+	input := `name: CI
+on: push
+jobs:
+  build:
+    runs-on: [self-hosted, linux]
+    steps:
+      - run: make build
+`
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	queryParams := map[string]string{"pinActions": "false", "addPermissions": "false"}
+
+	evaluator := &selfHostedPolicy{}
+	response, err := SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{Evaluator: evaluator})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if evaluator.input == nil || evaluator.input.Params["pinActions"] != "false" {
+		t.Fatalf("expected the policy to be evaluated with the query parameters, got %+v", evaluator.input)
+	}
+	if strings.Contains(response.FinalOutput, HardenRunnerActionPath) || !strings.Contains(response.FinalOutput, "shell: bash") {
+		t.Errorf("expected the parameters of the policy to override the request, got\n%s", response.FinalOutput)
+	}
+	if _, found := queryParams["addShellDefaults"]; found {
+		t.Errorf("expected the query parameters of the request to be unchanged, got %v", queryParams)
+	}
+}
+
+func TestSecureWorkflowScore(t *testing.T) {
+	input := `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+`
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	queryParams := map[string]string{"addHardenRunner": "false", "pinActions": "false", "addProjectComment": "false", "computeScore": "true"}
+	output, err := SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if output.Score == nil {
+		t.Fatalf("expected the score to be computed")
+	}
+	// the permissions are added, and the action is left unpinned
+	if output.Score.Before.Score != 4.4 || output.Score.After.Score != 7.8 || output.Score.Delta != 3.4 {
+		t.Errorf("unexpected score %+v", output.Score)
+	}
+
+	delete(queryParams, "computeScore")
+	output, err = SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{})
+	if err != nil || output.Score != nil {
+		t.Errorf("expected no score without computeScore, got %+v, %v", output.Score, err)
+	}
+}
+
+func TestIsRemediated(t *testing.T) {
+	const inputDirectory = "../../testfiles/anchors/input"
+	const outputDirectory = "../../testfiles/anchors/output"
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/commits/v2",
+		httpmock.NewStringResponder(200, `ebacdc22ef6c2cfb85ee5ded8f2e640f4c776dd5`))
+
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	queryParams, runnerLabels := anchorsQueryParams(), map[string]string{"ubuntu-latest": "step-ubuntu"}
+	files, err := ioutil.ReadDir(outputDirectory)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, file := range files {
+		input, err := ioutil.ReadFile(path.Join(inputDirectory, file.Name()))
+		if err != nil {
+			log.Fatal(err)
+		}
+		remediated, err := IsRemediated(context.Background(), queryParams, string(input), &mockDynamoDBClient{}, Options{RunnerLabels: runnerLabels})
+		if err != nil {
+			t.Fatalf("Error not expected for %s: %v", file.Name(), err)
+		}
+		if remediated["hardenrunner"] || remediated["runnerlabel"] {
+			t.Errorf("IsRemediated() of the input %s = %v, want harden-runner and the runner labels not remediated", file.Name(), remediated)
+		}
+
+		// the output is remediated by each module, and securing it again does not change it
+		output, err := ioutil.ReadFile(path.Join(outputDirectory, file.Name()))
+		if err != nil {
+			log.Fatal(err)
+		}
+		remediated, err = IsRemediated(context.Background(), queryParams, string(output), &mockDynamoDBClient{}, Options{RunnerLabels: runnerLabels})
+		if err != nil {
+			t.Fatalf("Error not expected for %s: %v", file.Name(), err)
+		}
+		if len(remediated) == 0 {
+			t.Errorf("IsRemediated() of the output %s returned no modules", file.Name())
+		}
+		for module, ok := range remediated {
+			if !ok {
+				t.Errorf("IsRemediated() of the output %s: %s is not remediated", file.Name(), module)
+			}
+		}
+		again, err := SecureWorkflow(context.Background(), queryParams, string(output), &mockDynamoDBClient{}, Options{RunnerLabels: runnerLabels})
+		if err != nil {
+			t.Fatalf("Error not expected for %s: %v", file.Name(), err)
+		}
+		if again.FinalOutput != string(output) || again.IsChanged {
+			t.Errorf("SecureWorkflow() of the output %s changed it\n%s", file.Name(), again.FinalOutput)
+		}
+	}
+}
+
+func TestSecureWorkflowMultipleDocuments(t *testing.T) {
+	const inputDirectory = "../../testfiles/multidoc/input"
+	const outputDirectory = "../../testfiles/multidoc/output"
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/commits/v2",
+		httpmock.NewStringResponder(200, `ebacdc22ef6c2cfb85ee5ded8f2e640f4c776dd5`))
+
+	files, err := ioutil.ReadDir(inputDirectory)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	// each document is remediated, and the documents keep their separators
+	queryParams, runnerLabels := anchorsQueryParams(), map[string]string{"ubuntu-latest": "step-ubuntu"}
+	for _, file := range files {
+		input, err := ioutil.ReadFile(path.Join(inputDirectory, file.Name()))
+		if err != nil {
+			log.Fatal(err)
+		}
+		output, err := SecureWorkflow(context.Background(), queryParams, string(input), &mockDynamoDBClient{}, Options{RunnerLabels: runnerLabels})
+		if err != nil {
+			t.Errorf("Error not expected for %s: %v", file.Name(), err)
+			continue
+		}
+		expectedOutput, err := ioutil.ReadFile(path.Join(outputDirectory, file.Name()))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if output.FinalOutput != string(expectedOutput) {
+			t.Errorf("test failed %s did not match expected output\nExpected:\n%s\n\nGot:\n%s",
+				file.Name(), string(expectedOutput), output.FinalOutput)
+		}
+
+		// the changes of the report are lines of the file, and reverting them returns the input
+		reverted := output.FinalOutput
+		for i := len(output.Report.Modules) - 1; i >= 0; i-- {
+			reverted, err = report.Revert(reverted, output.Report.Modules[i].Changes)
+			if err != nil {
+				t.Fatalf("unable to revert %s of %s: %v", output.Report.Modules[i].Name, file.Name(), err)
+			}
+		}
+		if reverted != string(input) {
+			t.Errorf("reverting the report of %s = %q, want the input", file.Name(), reverted)
+		}
+		// the status of a module is the status of the file, so a module that changed a document changed the file
+		statuses := map[string]string{}
+		for _, status := range output.Report.Statuses {
+			statuses[status.Module] = status.Status
+		}
+		for _, module := range output.Report.Modules {
+			if len(module.Changes) > 0 && statuses[module.Name] != report.StatusChanged {
+				t.Errorf("the status of %s of %s is %q, want %q", module.Name, file.Name(), statuses[module.Name], report.StatusChanged)
+			}
+		}
+		inputLines := strings.Count(string(input), "\n")
+		for _, finding := range output.Findings {
+			if finding.Line > inputLines {
+				t.Errorf("finding %s of %s is at line %d, after the end of the file", finding.RuleID, file.Name(), finding.Line)
+			}
+		}
+
+		again, err := SecureWorkflow(context.Background(), queryParams, output.FinalOutput, &mockDynamoDBClient{}, Options{RunnerLabels: runnerLabels})
+		if err != nil {
+			t.Fatalf("Error not expected for %s: %v", file.Name(), err)
+		}
+		if again.FinalOutput != output.FinalOutput {
+			t.Errorf("securing the output of %s again changed it to\n%s", file.Name(), again.FinalOutput)
+		}
+		remediated, err := IsRemediated(context.Background(), queryParams, output.FinalOutput, &mockDynamoDBClient{}, Options{RunnerLabels: runnerLabels})
+		if err != nil {
+			t.Fatalf("Error not expected for %s: %v", file.Name(), err)
+		}
+		for module, ok := range remediated {
+			if !ok {
+				t.Errorf("IsRemediated() of the output %s: %s is not remediated", file.Name(), module)
+			}
+		}
+	}
+}
+
+// blockScalar is the text of a literal or folded scalar: its header, which is the indicator with its indentation and
+// chomping, and its lines, with the trailing blank lines that are part of it when it keeps them
+type blockScalar struct {
+	header string
+	lines  string
+}
+
+// getBlockScalars returns the literal and folded scalars of the workflow
+func getBlockScalars(t *testing.T, workflow string) []blockScalar {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(workflow), &root); err != nil {
+		t.Fatalf("unable to parse the workflow: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(workflow, "\n"), "\n")
+	var scalars []blockScalar
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		for i := 0; i+1 < len(node.Content) && node.Kind == yaml.MappingNode; i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Kind != yaml.ScalarNode || value.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				continue
+			}
+			// the lines of the scalar are the lines indented more than its key, and the blank lines
+			header := lines[value.Line-1][value.Column-1:]
+			end := value.Line
+			for end < len(lines) && (strings.TrimSpace(lines[end]) == "" || len(lines[end])-len(strings.TrimLeft(lines[end], " ")) > key.Column-1) {
+				end++
+			}
+			if !strings.Contains(header, "+") {
+				for end > value.Line && strings.TrimSpace(lines[end-1]) == "" {
+					end--
+				}
+			}
+			scalars = append(scalars, blockScalar{header: header, lines: strings.Join(lines[value.Line:end], "\n")})
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	walk(&root)
+	return scalars
+}
+
+func TestSecureWorkflowBlockScalars(t *testing.T) {
+	const inputDirectory = "../../testfiles/blockscalars/input"
+	const outputDirectory = "../../testfiles/blockscalars/output"
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/commits/v2",
+		httpmock.NewStringResponder(200, `ebacdc22ef6c2cfb85ee5ded8f2e640f4c776dd5`))
+
+	files, err := ioutil.ReadDir(inputDirectory)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	queryParams, runnerLabels := anchorsQueryParams(), map[string]string{"ubuntu-latest": "step-ubuntu"}
+	for _, file := range files {
+		input, err := ioutil.ReadFile(path.Join(inputDirectory, file.Name()))
+		if err != nil {
+			log.Fatal(err)
+		}
+		output, err := SecureWorkflow(context.Background(), queryParams, string(input), &mockDynamoDBClient{}, Options{RunnerLabels: runnerLabels})
+		if err != nil {
+			t.Errorf("Error not expected for %s: %v", file.Name(), err)
+			continue
+		}
+		expectedOutput, err := ioutil.ReadFile(path.Join(outputDirectory, file.Name()))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if output.FinalOutput != string(expectedOutput) {
+			t.Errorf("test failed %s did not match expected output\n%s", file.Name(), output.FinalOutput)
+		}
+
+		// the output of each module has the scalars of the input. The scripts with expressions or deprecated commands
+		// are rewritten line by line, and the files of a release get another line, but their headers are kept.
+		inputScalars := getBlockScalars(t, string(input))
+		moduleOutput := output.FinalOutput
+		for i := len(output.Report.Modules) - 1; i >= 0; i-- {
+			module := output.Report.Modules[i]
+			outputScalars := getBlockScalars(t, moduleOutput)
+			for _, scalar := range inputScalars {
+				found := false
+				for _, outputScalar := range outputScalars {
+					switch {
+					case outputScalar.header != scalar.header:
+					case strings.Contains(scalar.lines, "${{") || strings.Contains(scalar.lines, "::"):
+						found = found || strings.Count(outputScalar.lines, "\n") == strings.Count(scalar.lines, "\n")
+					default:
+						found = found || outputScalar.lines == scalar.lines || strings.HasPrefix(outputScalar.lines, scalar.lines+"\n")
+					}
+				}
+				if !found {
+					t.Errorf("%s changed the scalar %q of %s\n%s", module.Name, scalar.header, file.Name(), moduleOutput)
+				}
+			}
+			moduleOutput, err = report.Revert(moduleOutput, module.Changes)
+			if err != nil {
+				t.Fatalf("unable to revert %s of %s: %v", module.Name, file.Name(), err)
+			}
+		}
+	}
+}
+
+func TestSecureWorkflowUnicode(t *testing.T) {
+	const inputDirectory = "../../testfiles/unicode/input"
+	const outputDirectory = "../../testfiles/unicode/output"
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/commits/v2",
+		httpmock.NewStringResponder(200, `ebacdc22ef6c2cfb85ee5ded8f2e640f4c776dd5`))
+
+	files, err := ioutil.ReadDir(inputDirectory)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	queryParams, runnerLabels := anchorsQueryParams(), map[string]string{"ubuntu-latest": "step-ubuntu"}
+	for _, file := range files {
+		input, err := ioutil.ReadFile(path.Join(inputDirectory, file.Name()))
+		if err != nil {
+			log.Fatal(err)
+		}
+		output, err := SecureWorkflow(context.Background(), queryParams, string(input), &mockDynamoDBClient{}, Options{RunnerLabels: runnerLabels})
+		if err != nil {
+			t.Errorf("Error not expected for %s: %v", file.Name(), err)
+			continue
+		}
+		expectedOutput, err := ioutil.ReadFile(path.Join(outputDirectory, file.Name()))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if output.FinalOutput != string(expectedOutput) {
+			t.Errorf("test failed %s did not match expected output\n%s", file.Name(), output.FinalOutput)
+		}
+		// the byte order mark is kept at the start of the file, and is not written before the lines added before the
+		// first line
+		if strings.HasPrefix(string(input), "\ufeff") != strings.HasPrefix(output.FinalOutput, "\ufeff") ||
+			strings.Count(output.FinalOutput, "\ufeff") > 1 {
+			t.Errorf("the byte order mark of %s was not kept\n%q", file.Name(), output.FinalOutput)
+		}
+	}
+}
+
+func TestSecureWorkflowDuplicates(t *testing.T) {
+	const inputDirectory = "../../testfiles/duplicates/input"
+	const outputDirectory = "../../testfiles/duplicates/output"
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/commits/v2",
+		httpmock.NewStringResponder(200, `ebacdc22ef6c2cfb85ee5ded8f2e640f4c776dd5`))
+
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	// the lines of the keys that are overridden by a later key of their mapping, by file
+	duplicateLines := map[string][]int{
+		"env.yml":  {9, 18, 26},
+		"jobs.yml": {7},
+	}
+	for file, wantLines := range duplicateLines {
+		input, err := ioutil.ReadFile(path.Join(inputDirectory, file))
+		if err != nil {
+			log.Fatal(err)
+		}
+		output, err := SecureWorkflow(context.Background(), anchorsQueryParams(), string(input), &mockDynamoDBClient{}, Options{})
+		if err != nil {
+			t.Errorf("Error not expected for %s: %v", file, err)
+			continue
+		}
+		expectedOutput, err := ioutil.ReadFile(path.Join(outputDirectory, file))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if output.FinalOutput != string(expectedOutput) {
+			t.Errorf("test failed %s did not match expected output\n%s", file, output.FinalOutput)
+		}
+		var lines []int
+		for _, finding := range output.Findings {
+			if finding.RuleID == RuleDuplicateKey {
+				lines = append(lines, finding.Line)
+			}
+		}
+		if !reflect.DeepEqual(lines, wantLines) {
+			t.Errorf("duplicate keys of %s at lines %v, want %v", file, lines, wantLines)
+		}
+	}
+}
+
+func TestSecureWorkflowOnKey(t *testing.T) {
+	const inputDirectory = "../../testfiles/onkey/input"
+	const outputDirectory = "../../testfiles/onkey/output"
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/step-security/harden-runner/commits/v2",
+		httpmock.NewStringResponder(200, `ebacdc22ef6c2cfb85ee5ded8f2e640f4c776dd5`))
+
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+
+	queryParams := anchorsQueryParams()
+	queryParams["checkDangerousTriggers"] = "true"
+	// the triggers are found whether their key is on, "on" or a key YAML 1.1 reads as true, and the workflows triggered
+	// by pull_request_target that check out the pull request are dangerous
+	dangerous := map[string]bool{"true.yml": true, "quoted.yml": false, "capitalized.yml": true}
+	for file, wantDangerous := range dangerous {
+		input, err := ioutil.ReadFile(path.Join(inputDirectory, file))
+		if err != nil {
+			log.Fatal(err)
+		}
+		output, err := SecureWorkflow(context.Background(), queryParams, string(input), &mockDynamoDBClient{}, Options{})
+		if err != nil {
+			t.Errorf("Error not expected for %s: %v", file, err)
+			continue
+		}
+		expectedOutput, err := ioutil.ReadFile(path.Join(outputDirectory, file))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if output.FinalOutput != string(expectedOutput) {
+			t.Errorf("test failed %s did not match expected output\n%s", file, output.FinalOutput)
+		}
+		foundDangerous := false
+		for _, finding := range output.Findings {
+			foundDangerous = foundDangerous || finding.RuleID == triggers.RuleDangerousTrigger
+		}
+		if foundDangerous != wantDangerous {
+			t.Errorf("dangerous trigger of %s found = %v, want %v", file, foundDangerous, wantDangerous)
+		}
+	}
+}
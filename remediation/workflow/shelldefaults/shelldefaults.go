@@ -0,0 +1,136 @@
+package shelldefaults
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+// Shell is set as the default shell. When the shell is bash, GitHub runs scripts with bash --noprofile --norc -eo pipefail {0},
+// whereas scripts without a shell run with bash -e {0}, where failures in piped commands are ignored.
+const Shell = "bash"
+
+// isLinuxOrMacRunner returns true if runs-on is known to be a Linux or macOS runner, where bash is available and used by default.
+// Windows runners use pwsh by default, and expressions cannot be evaluated, so they are not changed.
+func isLinuxOrMacRunner(runsOnNode *yaml.Node) bool {
+	if runsOnNode == nil {
+		return false
+	}
+
+	var labels []string
+	switch runsOnNode.Kind {
+	case yaml.ScalarNode:
+		labels = append(labels, runsOnNode.Value)
+	case yaml.SequenceNode:
+		for _, n := range runsOnNode.Content {
+			labels = append(labels, n.Value)
+		}
+	default:
+		return false
+	}
+
+	for _, label := range labels {
+		label = strings.ToLower(label)
+		if strings.Contains(label, "${{") || strings.Contains(label, "windows") {
+			return false
+		}
+	}
+	return true
+}
+
+// needsShellDefault returns true if the job has run steps without a shell, and the default shell can be safely set to bash
+func needsShellDefault(jobNode *yaml.Node) bool {
+	if jobNode.Kind != yaml.MappingNode || jobNode.Style&yaml.FlowStyle != 0 {
+		return false
+	}
+
+	// jobs in containers may not have bash, and jobs with defaults already choose their own settings
+	if document.MappingValue(jobNode, "container") != nil || document.MappingValue(jobNode, "defaults") != nil {
+		return false
+	}
+
+	if !isLinuxOrMacRunner(document.MappingValue(jobNode, "runs-on")) {
+		return false
+	}
+
+	stepsNode := document.MappingValue(jobNode, "steps")
+	if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+		return false
+	}
+	for _, stepNode := range stepsNode.Content {
+		if document.MappingValue(stepNode, "run") != nil && document.MappingValue(stepNode, "shell") == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// getDefaultsLines returns the lines of a defaults block at the given indentation
+func getDefaultsLines(indent string, indentUnit string) []string {
+	return []string{
+		fmt.Sprintf("%sdefaults:", indent),
+		fmt.Sprintf("%s%srun:", indent, indentUnit),
+		fmt.Sprintf("%s%s%sshell: %s", indent, indentUnit, indentUnit, Shell),
+	}
+}
+
+// AddShellDefaults sets the default shell to bash, so that scripts run with -eo pipefail and a failed command in a pipe fails the step.
+// If every job with run steps is on a Linux or macOS runner, the default is set at the workflow level, otherwise it is set for each eligible job.
+func AddShellDefaults(inputYaml string) (string, bool, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return inputYaml, false, nil
+	}
+	topNode := t.Content[0]
+
+	jobsKeyNode, jobsNode := document.MappingEntry(topNode, "jobs")
+	if jobsNode == nil || jobsNode.Kind != yaml.MappingNode || jobsNode.Style&yaml.FlowStyle != 0 || len(jobsNode.Content) == 0 {
+		return inputYaml, false, nil
+	}
+
+	workflowDefaults := document.MappingValue(document.MappingValue(topNode, "defaults"), "run")
+	if document.MappingValue(workflowDefaults, "shell") != nil {
+		return inputYaml, false, nil
+	}
+
+	indentUnit := strings.Repeat(" ", jobsNode.Content[0].Column-jobsKeyNode.Column)
+
+	var eligibleJobs []*yaml.Node
+	allEligible := true
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobNode := document.Resolve(jobsNode.Content[i+1])
+		if needsShellDefault(jobNode) {
+			eligibleJobs = append(eligibleJobs, jobsNode.Content[i])
+		} else if document.MappingValue(jobNode, "steps") != nil {
+			allEligible = false
+		}
+	}
+
+	if len(eligibleJobs) == 0 {
+		return inputYaml, false, nil
+	}
+
+	buffer := textedit.NewBuffer(inputYaml)
+
+	if allEligible && document.MappingValue(topNode, "defaults") == nil {
+		// insert before the jobs key, at the top level
+		indent := strings.Repeat(" ", jobsKeyNode.Column-1)
+		buffer.InsertLinesBefore(jobsKeyNode.Line, getDefaultsLines(indent, indentUnit)...)
+		return buffer.String(), true, nil
+	}
+
+	// insert after each job key, or the anchor of the job, which is changed once for the jobs that are its aliases
+	for _, jobKeyNode := range eligibleJobs {
+		jobNode := document.MappingValue(jobsNode, jobKeyNode.Value)
+		indent := strings.Repeat(" ", jobNode.Content[0].Column-1)
+		buffer.InsertLinesAfter(document.OpeningLine(jobKeyNode, jobNode), getDefaultsLines(indent, indentUnit)...)
+	}
+
+	return buffer.String(), true, nil
+}
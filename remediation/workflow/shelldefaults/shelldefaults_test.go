@@ -0,0 +1,54 @@
+package shelldefaults
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestAddShellDefaults(t *testing.T) {
+	const inputDirectory = "../../../testfiles/shelldefaults/input"
+	const outputDirectory = "../../../testfiles/shelldefaults/output"
+
+	tests := []struct {
+		fileName    string
+		wantUpdated bool
+	}{
+		{fileName: "linux-jobs.yml", wantUpdated: true},
+		{fileName: "mixed-runners.yml", wantUpdated: true},
+		{fileName: "defaults-exist.yml", wantUpdated: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			input, err := ioutil.ReadFile(path.Join(inputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			got, gotUpdated, err := AddShellDefaults(string(input))
+			if err != nil {
+				t.Errorf("AddShellDefaults() unexpected error = %v", err)
+			}
+
+			if gotUpdated != tt.wantUpdated {
+				t.Errorf("AddShellDefaults() updated = %v, wantUpdated %v", gotUpdated, tt.wantUpdated)
+			}
+
+			output, err := ioutil.ReadFile(path.Join(outputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			if got != string(output) {
+				t.Errorf("AddShellDefaults() = %v, want %v", got, string(output))
+			}
+
+			// the output has the defaults, so adding them again does not change it
+			again, againUpdated, err := AddShellDefaults(got)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("AddShellDefaults() of the output = %v, %v, want it unchanged", againUpdated, err)
+			}
+		})
+	}
+}
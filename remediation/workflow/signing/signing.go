@@ -0,0 +1,226 @@
+package signing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/yamledit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	BuildPushAction       = "docker/build-push-action"
+	CosignInstallerAction = "sigstore/cosign-installer"
+	CosignInstallerTag    = "v3"
+	// BuildPushStepID is used to reference the digest output, if the build step does not have an id
+	BuildPushStepID = "build-and-push"
+)
+
+func getAction(stepNode *yaml.Node) string {
+	usesNode := document.MappingValue(stepNode, "uses")
+	if usesNode == nil {
+		return ""
+	}
+	return strings.ToLower(strings.Split(usesNode.Value, "@")[0])
+}
+
+// isPushed returns true if the build step pushes the image
+func isPushed(stepNode *yaml.Node) bool {
+	pushNode := document.MappingValue(document.MappingValue(stepNode, "with"), "push")
+	return pushNode != nil && pushNode.Value != "false"
+}
+
+func isSigned(stepsNode *yaml.Node) bool {
+	for _, stepNode := range stepsNode.Content {
+		if getAction(stepNode) == CosignInstallerAction {
+			return true
+		}
+		if runNode := document.MappingValue(stepNode, "run"); runNode != nil && strings.Contains(runNode.Value, "cosign sign") {
+			return true
+		}
+	}
+	return false
+}
+
+// pushesToGHCR returns true if the job logs in to, or pushes images to the GitHub container registry, which needs packages: write
+func pushesToGHCR(stepsNode *yaml.Node) bool {
+	for _, stepNode := range stepsNode.Content {
+		withNode := document.MappingValue(stepNode, "with")
+		for _, input := range []string{"registry", "tags"} {
+			if inputNode := document.MappingValue(withNode, input); inputNode != nil && strings.Contains(inputNode.Value, "ghcr.io") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getSigningSteps returns the lines of the steps that install cosign and sign the pushed images by digest, after the
+// item of the build step
+func getSigningSteps(stepNode *yaml.Node, indentUnit, stepID string, tagsNode *yaml.Node) []string {
+	propertyIndent := strings.Repeat(" ", stepNode.Column-1)
+	dashIndent := strings.Repeat(" ", stepNode.Column-3)
+	envIndent := propertyIndent + indentUnit
+
+	lines := []string{
+		fmt.Sprintf("%s- name: Install cosign", dashIndent),
+		fmt.Sprintf("%suses: %s@%s", propertyIndent, CosignInstallerAction, CosignInstallerTag),
+		fmt.Sprintf("%s- name: Sign the published Docker image", dashIndent),
+		fmt.Sprintf("%senv:", propertyIndent),
+	}
+
+	tags := strings.TrimRight(tagsNode.Value, "\n")
+	if strings.Contains(tags, "\n") {
+		lines = append(lines, fmt.Sprintf("%sTAGS: |", envIndent))
+		for _, tag := range strings.Split(tags, "\n") {
+			lines = append(lines, fmt.Sprintf("%s%s%s", envIndent, indentUnit, strings.TrimSpace(tag)))
+		}
+	} else if strings.HasPrefix(tags, "${{") {
+		lines = append(lines, fmt.Sprintf("%sTAGS: %s", envIndent, tags))
+	} else {
+		lines = append(lines, fmt.Sprintf("%sTAGS: '%s'", envIndent, strings.ReplaceAll(tags, "'", "''")))
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("%sDIGEST: ${{ steps.%s.outputs.digest }}", envIndent, stepID),
+		// tags may be comma or newline separated
+		fmt.Sprintf(`%srun: echo "${TAGS}" | tr ',' '\n' | xargs -I {} cosign sign --yes {}@${DIGEST}`, propertyIndent),
+	)
+	return lines
+}
+
+// addPermissions adds id-token: write to the job, to sign with the GitHub OIDC token.
+// If the job does not have permissions, they are added based on the workflow level permissions.
+func addPermissions(buffer *textedit.Buffer, topNode, jobKeyNode, jobNode *yaml.Node, indentUnit string, needsPackagesWrite bool) {
+	permissionsNode := document.MappingValue(jobNode, "permissions")
+	if permissionsNode != nil {
+		// write-all already has the permission, and other forms are not changed
+		if permissionsNode.Kind != yaml.MappingNode || permissionsNode.Style&yaml.FlowStyle != 0 || len(permissionsNode.Content) == 0 {
+			return
+		}
+		indent := strings.Repeat(" ", permissionsNode.Content[0].Column-1)
+		scopeKeyNode, scopeNode := document.MappingEntry(permissionsNode, "id-token")
+		if scopeNode == nil {
+			lastLine := permissionsNode.Content[len(permissionsNode.Content)-1].Line
+			buffer.InsertLinesAfter(lastLine, fmt.Sprintf("%sid-token: write", indent))
+		} else if scopeNode.Value != "write" && scopeNode.Anchor == "" {
+			buffer.ReplaceLine(scopeKeyNode.Line, fmt.Sprintf("%sid-token: write", indent))
+		}
+		return
+	}
+
+	// the job level permissions replace the workflow level permissions, so they are copied
+	var scopes []string
+	values := make(map[string]string)
+	workflowPermissionsNode := document.MappingValue(topNode, "permissions")
+	if workflowPermissionsNode != nil && workflowPermissionsNode.Kind == yaml.ScalarNode && workflowPermissionsNode.Value == "write-all" {
+		return
+	}
+	if workflowPermissionsNode != nil && workflowPermissionsNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(workflowPermissionsNode.Content); i += 2 {
+			scopes = append(scopes, workflowPermissionsNode.Content[i].Value)
+			values[workflowPermissionsNode.Content[i].Value] = workflowPermissionsNode.Content[i+1].Value
+		}
+	}
+	if _, found := values["contents"]; !found {
+		scopes = append(scopes, "contents")
+		values["contents"] = "read"
+	}
+	if needsPackagesWrite {
+		if _, found := values["packages"]; !found {
+			scopes = append(scopes, "packages")
+		}
+		values["packages"] = "write"
+	}
+	if _, found := values["id-token"]; !found {
+		scopes = append(scopes, "id-token")
+	}
+	values["id-token"] = "write"
+
+	indent := strings.Repeat(" ", jobNode.Content[0].Column-1)
+	lines := []string{fmt.Sprintf("%spermissions:", indent)}
+	for _, scope := range scopes {
+		lines = append(lines, fmt.Sprintf("%s%s%s: %s", indent, indentUnit, scope, values[scope]))
+	}
+	buffer.InsertLinesAfter(document.OpeningLine(jobKeyNode, jobNode), lines...)
+}
+
+// AddCosignSigning adds steps to install cosign and sign the images pushed by docker/build-push-action,
+// using keyless signing with the GitHub OIDC token. The images are signed by the digest output of the build step,
+// so an id is added to the build step if needed, and the job is given the id-token: write permission.
+func AddCosignSigning(inputYaml string) (string, bool, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return inputYaml, false, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return inputYaml, false, nil
+	}
+	topNode := t.Content[0]
+
+	jobsKeyNode, jobsNode := document.MappingEntry(topNode, "jobs")
+	if jobsNode == nil || jobsNode.Kind != yaml.MappingNode || len(jobsNode.Content) == 0 {
+		return inputYaml, false, nil
+	}
+	indentUnit := strings.Repeat(" ", jobsNode.Content[0].Column-jobsKeyNode.Column)
+
+	inputLines := strings.Split(inputYaml, "\n")
+	buffer := textedit.NewBuffer(inputYaml)
+	updated := false
+
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobKeyNode, jobNode := jobsNode.Content[i], document.Resolve(jobsNode.Content[i+1])
+		if jobNode.Kind != yaml.MappingNode || jobNode.Style&yaml.FlowStyle != 0 || len(jobNode.Content) == 0 {
+			continue
+		}
+		stepsNode := document.MappingValue(jobNode, "steps")
+		if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode || stepsNode.Style&yaml.FlowStyle != 0 || isSigned(stepsNode) {
+			continue
+		}
+
+		jobUpdated := false
+		for j, itemNode := range stepsNode.Content {
+			// the step of an alias is changed at its anchor, and the signing steps are added after the alias
+			stepNode := document.Resolve(itemNode)
+			if getAction(stepNode) != BuildPushAction || !isPushed(stepNode) || stepNode.Style&yaml.FlowStyle != 0 || itemNode.Column < 3 {
+				continue
+			}
+			tagsNode := document.MappingValue(document.MappingValue(stepNode, "with"), "tags")
+			if tagsNode == nil || tagsNode.Kind != yaml.ScalarNode {
+				continue
+			}
+
+			propertyIndent := strings.Repeat(" ", stepNode.Content[0].Column-1)
+			stepID := BuildPushStepID
+			if idNode := document.MappingValue(stepNode, "id"); idNode != nil {
+				stepID = idNode.Value
+			} else {
+				usesKeyNode, _ := document.MappingEntry(stepNode, "uses")
+				buffer.InsertLinesAfter(usesKeyNode.Line, fmt.Sprintf("%sid: %s", propertyIndent, stepID))
+			}
+
+			signingSteps := getSigningSteps(itemNode, indentUnit, stepID, tagsNode)
+			if j+1 < len(stepsNode.Content) {
+				nextStepLine := stepsNode.Content[j+1].Line
+				buffer.InsertLinesBefore(nextStepLine, signingSteps...)
+			} else {
+				lastLine := yamledit.LastLine(inputLines, itemNode)
+				buffer.InsertLinesAfter(lastLine, signingSteps...)
+			}
+			jobUpdated = true
+		}
+
+		if jobUpdated {
+			addPermissions(buffer, topNode, jobKeyNode, jobNode, indentUnit, pushesToGHCR(stepsNode))
+			updated = true
+		}
+	}
+
+	if !updated {
+		return inputYaml, false, nil
+	}
+
+	return buffer.String(), true, nil
+}
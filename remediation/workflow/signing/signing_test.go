@@ -0,0 +1,53 @@
+package signing
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestAddCosignSigning(t *testing.T) {
+	const inputDirectory = "../../../testfiles/cosign/input"
+	const outputDirectory = "../../../testfiles/cosign/output"
+
+	tests := []struct {
+		fileName    string
+		wantUpdated bool
+	}{
+		{fileName: "build-push.yml", wantUpdated: true},
+		{fileName: "no-push.yml", wantUpdated: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			input, err := ioutil.ReadFile(path.Join(inputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			got, gotUpdated, err := AddCosignSigning(string(input))
+			if err != nil {
+				t.Errorf("AddCosignSigning() unexpected error = %v", err)
+			}
+
+			if gotUpdated != tt.wantUpdated {
+				t.Errorf("AddCosignSigning() updated = %v, wantUpdated %v", gotUpdated, tt.wantUpdated)
+			}
+
+			output, err := ioutil.ReadFile(path.Join(outputDirectory, tt.fileName))
+			if err != nil {
+				t.Fatalf("error reading test file")
+			}
+
+			if got != string(output) {
+				t.Errorf("AddCosignSigning() = %v, want %v", got, string(output))
+			}
+
+			// the output signs the images, so adding the signing again does not change it
+			again, againUpdated, err := AddCosignSigning(got)
+			if err != nil || againUpdated || again != got {
+				t.Errorf("AddCosignSigning() of the output = %v, %v, want it unchanged", againUpdated, err)
+			}
+		})
+	}
+}
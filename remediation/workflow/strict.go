@@ -0,0 +1,124 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/report"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+	"github.com/step-security/secure-repo/remediation/workflow/pin"
+	"gopkg.in/yaml.v3"
+)
+
+// The rules of the constructs that the remediations could not resolve, which fail the request with strict=true
+const (
+	// RuleUnknownAction is the rule of an action that is not in the knowledge base, or whose tag was not found
+	RuleUnknownAction = "unknown-action"
+	// RuleDynamicUses is the rule of a uses with an expression, whose action is only known when the workflow runs
+	RuleDynamicUses = "dynamic-uses"
+	// RuleUnpinnableImage is the rule of a docker image whose digest was not found
+	RuleUnpinnableImage = "unpinnable-image"
+	// RuleSkippedJob is the rule of a job whose permissions were not set
+	RuleSkippedJob = "skipped-job"
+)
+
+// findUnresolved returns a finding for each construct of the remediated workflow that the modules that ran could not
+// resolve: the uses with an expression, the actions and images that were not pinned, the actions that are not in the
+// knowledge base, and the jobs whose permissions were not set. The lines are lines of the remediated workflow.
+func findUnresolved(outputYaml string, ran, exemptedActions []string, jobErrors []permissions.JobError, missingActions []string) ([]findings.Finding, error) {
+	index, err := document.Parse(outputYaml).Index()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	// the actions that are still not pinned after pin ran were not found, except the exempted ones
+	unpinned := map[[2]int]bool{}
+	for _, name := range ran {
+		if name != "pin" {
+			continue
+		}
+		unpinnedFindings, err := pin.FindUnpinnedActions(outputYaml, exemptedActions)
+		if err != nil {
+			return nil, err
+		}
+		for _, finding := range unpinnedFindings {
+			unpinned[[2]int{finding.Line, finding.Column}] = true
+		}
+	}
+	missing := map[string]bool{}
+	for _, action := range missingActions {
+		missing[strings.ToLower(strings.Split(action, "@")[0])] = true
+	}
+
+	var unresolved []findings.Finding
+	addUses := func(jobName string, usesNode *yaml.Node) {
+		if usesNode == nil || usesNode.Kind != yaml.ScalarNode {
+			return
+		}
+		uses := usesNode.Value
+		finding := findings.Finding{JobName: jobName, Action: uses, Line: usesNode.Line, Column: usesNode.Column}
+		switch {
+		case strings.Contains(uses, "${{"):
+			finding.RuleID = RuleDynamicUses
+			finding.Message = fmt.Sprintf("%s has an expression, so the action it runs is not known until the workflow runs", uses)
+			finding.Suggestion = "Replace the expression with the action and its version"
+		case unpinned[[2]int{usesNode.Line, usesNode.Column}] && strings.HasPrefix(uses, "docker://"):
+			finding.RuleID = RuleUnpinnableImage
+			finding.Message = fmt.Sprintf("the digest of %s was not found, so it could not be pinned", uses)
+			finding.Suggestion = "Pin the docker image to a digest"
+		case unpinned[[2]int{usesNode.Line, usesNode.Column}]:
+			finding.RuleID = RuleUnknownAction
+			finding.Message = fmt.Sprintf("the commit of %s was not found, so it could not be pinned", uses)
+			finding.Suggestion = fmt.Sprintf("Pin %s to a full length commit SHA", strings.Split(uses, "@")[0])
+		case missing[strings.ToLower(strings.Split(uses, "@")[0])]:
+			finding.RuleID = RuleUnknownAction
+			finding.Message = fmt.Sprintf("%s is not in the knowledge base, so the permissions it needs are not known", strings.Split(uses, "@")[0])
+			finding.Suggestion = "Add the action to the knowledge base, or set the permissions of the job"
+		default:
+			return
+		}
+		unresolved = append(unresolved, finding)
+	}
+	addSteps := func(jobName string, steps *yaml.Node) {
+		if steps == nil {
+			return
+		}
+		for _, step := range steps.Content {
+			addUses(jobName, document.MappingValue(step, "uses"))
+		}
+	}
+	for _, job := range index.Jobs {
+		// reusable workflows
+		addUses(job.Name, document.MappingValue(job.Node, "uses"))
+		addSteps(job.Name, job.Steps)
+	}
+	addSteps("", index.Steps)
+
+	for _, jobError := range jobErrors {
+		if !jobError.IsSkipped() {
+			continue
+		}
+		finding := findings.Finding{
+			RuleID:     RuleSkippedJob,
+			Message:    fmt.Sprintf("the permissions of job %s were not set: %s", jobError.JobName, strings.Join(jobError.Errors, "; ")),
+			JobName:    jobError.JobName,
+			Suggestion: "Set the permissions of the job",
+		}
+		if job := index.Job(jobError.JobName); job != nil {
+			finding.Line, finding.Column = job.Key.Line, job.Key.Column
+		}
+		unresolved = append(unresolved, finding)
+	}
+	return unresolved, nil
+}
+
+// hasModuleErrors returns true if a module of the report failed
+func hasModuleErrors(workflowReport *report.Report) bool {
+	for _, module := range workflowReport.Modules {
+		if len(module.Errors) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/permissions"
+)
+
+func TestSecureWorkflowStrict(t *testing.T) {
+	const input = `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: ${{ matrix.action }}
+  release:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: octo-org/unknown-action@v1
+  call:
+    uses: octo-org/workflows/.github/workflows/build.yml@main
+`
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	// the maintenance of the actions is checked with requests to GitHub
+	queryParams := map[string]string{"pinActions": "false", "addHardenRunner": "false", "ignoreMissingKBs": "true",
+		"checkUnmaintainedActions": "false", "strict": "true"}
+
+	output, err := SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if !output.FailedStrictChecks {
+		t.Errorf("expected the strict checks to fail")
+	}
+	type unresolved struct {
+		RuleID, JobName string
+		Line            int
+	}
+	var got []unresolved
+	for _, finding := range output.Findings {
+		switch finding.RuleID {
+		case RuleUnknownAction, RuleDynamicUses, RuleUnpinnableImage, RuleSkippedJob:
+			got = append(got, unresolved{finding.RuleID, finding.JobName, finding.Line})
+		}
+	}
+	want := []unresolved{
+		{RuleDynamicUses, "build", 8},
+		{RuleUnknownAction, "release", 12},
+		{RuleSkippedJob, "build", 4},
+		{RuleSkippedJob, "call", 13},
+		{RuleSkippedJob, "release", 9},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected unresolved findings %v, want %v\n%s", got, want, output.FinalOutput)
+	}
+	// the other checks are run as well
+	if !hasRule(output.Findings, "unpinned-action") {
+		t.Errorf("expected the findings of the checks, got %v", output.Findings)
+	}
+
+	// without strict, nothing fails
+	delete(queryParams, "strict")
+	output, err = SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if output.FailedStrictChecks || hasRule(output.Findings, RuleSkippedJob) {
+		t.Errorf("expected no strict checks without strict")
+	}
+
+	// a workflow that is remediated completely passes
+	queryParams["strict"] = "true"
+	output, err = SecureWorkflow(context.Background(), queryParams, "name: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make\n", &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if output.FailedStrictChecks {
+		t.Errorf("expected the strict checks to pass, got %v", output.Findings)
+	}
+}
+
+func Test_findUnresolved(t *testing.T) {
+	const output = `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4
+      - uses: octo-org/private-action@v1
+      - uses: octo-org/exempted-action@v1
+      - uses: docker://alpine:3.18
+      - uses: ./local-action
+`
+	jobErrors := []permissions.JobError{
+		{JobName: "build", Errors: []string{"KnownIssue-4: Action octo-org/private-action@v1 is not in the knowledge base"}},
+	}
+	got, err := findUnresolved(output, []string{"pin", "permissions"}, []string{"octo-org/exempted-action"}, jobErrors, []string{"octo-org/private-action@v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := map[string][]int{}
+	for _, finding := range got {
+		rules[finding.RuleID] = append(rules[finding.RuleID], finding.Line)
+	}
+	// an action that was not pinned is reported once, though it is also not in the knowledge base
+	want := map[string][]int{
+		RuleUnknownAction:   {8},
+		RuleUnpinnableImage: {10},
+		RuleSkippedJob:      {4},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("findUnresolved() = %v, want %v", got, want)
+	}
+
+	// the jobs that already have permissions are not skipped
+	jobErrors = []permissions.JobError{{JobName: "build", Errors: []string{"KnownIssue-5: Permissions were not added to the job since it already had permissions defined"}}}
+	got, err = findUnresolved(output, nil, nil, jobErrors, nil)
+	if err != nil || len(got) != 0 {
+		t.Errorf("findUnresolved() = %v, %v, want no findings", got, err)
+	}
+
+	if _, err := findUnresolved("on: [push\n", nil, nil, nil, nil); err == nil {
+		t.Errorf("findUnresolved() returned no error for an invalid workflow")
+	}
+}
+
+// hasRule returns true if one of the findings is of the rule
+func hasRule(workflowFindings []findings.Finding, ruleID string) bool {
+	for _, finding := range workflowFindings {
+		if finding.RuleID == ruleID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package triggers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RuleDangerousTrigger = "dangerous-trigger"
+
+	CheckoutAction = "actions/checkout"
+)
+
+// privilegedTriggers run with a write token and secrets, even when the workflow is run for a pull request from a fork
+var privilegedTriggers = []string{"pull_request_target", "workflow_run"}
+
+// untrustedRefRegex matches refs of the pull request that triggered the workflow, which can have any code
+var untrustedRefRegex = regexp.MustCompile(`github\.event\.pull_request\.head\.(sha|ref)|github\.head_ref|github\.event\.workflow_run\.head_(sha|branch)|refs/pull/`)
+
+// getPrivilegedTriggers returns the privileged events in the on section of the workflow
+func getPrivilegedTriggers(topNode *yaml.Node) []string {
+	onNode := document.MappingValue(topNode, "on")
+	if onNode == nil {
+		return nil
+	}
+
+	var triggers []string
+	switch onNode.Kind {
+	case yaml.ScalarNode:
+		triggers = append(triggers, onNode.Value)
+	case yaml.SequenceNode:
+		for _, n := range onNode.Content {
+			triggers = append(triggers, n.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(onNode.Content); i += 2 {
+			triggers = append(triggers, onNode.Content[i].Value)
+		}
+	}
+
+	var privileged []string
+	for _, trigger := range triggers {
+		for _, privilegedTrigger := range privilegedTriggers {
+			if trigger == privilegedTrigger {
+				privileged = append(privileged, trigger)
+			}
+		}
+	}
+	return privileged
+}
+
+// FindDangerousTriggers returns findings for workflows triggered by pull_request_target or workflow_run that check out
+// the code of the pull request. The code runs with a write token and access to secrets, so a pull request from a fork
+// can take over the repository.
+func FindDangerousTriggers(inputYaml string) ([]findings.Finding, error) {
+	t, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(t.Content) == 0 {
+		return nil, nil
+	}
+	topNode := t.Content[0]
+
+	privileged := getPrivilegedTriggers(topNode)
+	jobsNode := document.MappingValue(topNode, "jobs")
+	if len(privileged) == 0 || jobsNode == nil || jobsNode.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var triggerFindings []findings.Finding
+	for i := 0; i+1 < len(jobsNode.Content); i += 2 {
+		jobName := jobsNode.Content[i].Value
+		stepsNode := document.MappingValue(jobsNode.Content[i+1], "steps")
+		if stepsNode == nil || stepsNode.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, stepNode := range stepsNode.Content {
+			usesNode := document.MappingValue(stepNode, "uses")
+			if usesNode == nil || !strings.HasPrefix(strings.ToLower(usesNode.Value), CheckoutAction+"@") {
+				continue
+			}
+			refKeyNode, refNode := document.MappingEntry(document.MappingValue(stepNode, "with"), "ref")
+			if refNode == nil || !untrustedRefRegex.MatchString(refNode.Value) {
+				continue
+			}
+			triggerFindings = append(triggerFindings, findings.Finding{
+				RuleID:     RuleDangerousTrigger,
+				Message:    fmt.Sprintf("Job %s checks out the code of the pull request in a workflow triggered by %s, which has access to secrets and a write token", jobName, strings.Join(privileged, ", ")),
+				JobName:    jobName,
+				Action:     usesNode.Value,
+				Line:       refKeyNode.Line,
+				Column:     refKeyNode.Column,
+				Suggestion: "Use the pull_request trigger to build the code of pull requests, and pass the results to a separate workflow",
+			})
+		}
+	}
+	return triggerFindings, nil
+}
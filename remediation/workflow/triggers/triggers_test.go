@@ -0,0 +1,47 @@
+package triggers
+
+import "testing"
+
+func TestFindDangerousTriggers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{
+			name:     "pull_request_target with head checkout",
+			input:    "on: pull_request_target\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n        with:\n          ref: ${{ github.event.pull_request.head.sha }}\n",
+			expected: 1,
+		},
+		{
+			name:     "workflow_run with head checkout",
+			input:    "on:\n  workflow_run:\n    workflows: [CI]\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n        with:\n          ref: ${{ github.event.workflow_run.head_sha }}\n",
+			expected: 1,
+		},
+		{
+			name:     "pull_request_target with base checkout",
+			input:    "on: pull_request_target\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n",
+			expected: 0,
+		},
+		{
+			name:     "pull_request with head checkout",
+			input:    "on: pull_request\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n        with:\n          ref: ${{ github.event.pull_request.head.sha }}\n",
+			expected: 0,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := FindDangerousTriggers(test.input)
+		if err != nil {
+			t.Fatalf("%s: FindDangerousTriggers() unexpected error = %v", test.name, err)
+		}
+		if len(got) != test.expected {
+			t.Errorf("%s: FindDangerousTriggers() returned %d findings, want %d: %v", test.name, len(got), test.expected, got)
+		}
+		for _, finding := range got {
+			if finding.RuleID != RuleDangerousTrigger || finding.JobName != "build" || finding.Action != "actions/checkout@v4" {
+				t.Errorf("%s: unexpected finding %+v", test.name, finding)
+			}
+		}
+	}
+}
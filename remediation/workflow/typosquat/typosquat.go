@@ -0,0 +1,283 @@
+package typosquat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+)
+
+const RuleTyposquattedAction = "typosquatted-action"
+
+// homoglyphs maps characters and sequences that look alike to the character they imitate
+var homoglyphs = []struct {
+	from string
+	to   string
+}{
+	{"rn", "m"},
+	{"vv", "w"},
+	{"0", "o"},
+	{"1", "l"},
+	{"i", "l"},
+	{"а", "a"},
+	{"с", "c"},
+	{"е", "e"},
+	{"о", "o"},
+	{"р", "p"},
+	{"х", "x"},
+	{"у", "y"},
+	{"ј", "j"},
+	{"ѕ", "s"},
+	{"ı", "l"},
+	{"_", "-"},
+}
+
+var shaRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+type candidate struct {
+	action    string
+	distance  int
+	homoglyph bool
+}
+
+// GetKBFolder returns the knowledge base folder, which can be overridden using the KBFolder environment variable
+func GetKBFolder() string {
+	kbFolder := os.Getenv("KBFolder")
+	if kbFolder == "" {
+		kbFolder = "../../knowledge-base/actions"
+	}
+	return kbFolder
+}
+
+// LoadPopularActions returns the owner/repo of the actions in the knowledge base, which are used as the list of popular actions
+func LoadPopularActions(kbFolder string) ([]string, error) {
+	var actions []string
+	err := filepath.Walk(kbFolder, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || info.Name() != "action-security.yml" {
+			return nil
+		}
+		relativePath, err := filepath.Rel(kbFolder, filepath.Dir(filePath))
+		if err != nil {
+			return err
+		}
+		actions = append(actions, filepath.ToSlash(relativePath))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load popular actions: %v", err)
+	}
+	return PopularActions(actions), nil
+}
+
+// PopularActions returns the owner/repo of the paths of the actions in the knowledge base, e.g. github/codeql-action
+// for github/codeql-action/init, sorted and without duplicates
+func PopularActions(actions []string) []string {
+	seen := make(map[string]bool)
+	var popularActions []string
+	for _, action := range actions {
+		parts := strings.Split(action, "/")
+		if len(parts) < 2 {
+			continue
+		}
+		popularAction := strings.ToLower(parts[0] + "/" + parts[1])
+		if !seen[popularAction] {
+			seen[popularAction] = true
+			popularActions = append(popularActions, popularAction)
+		}
+	}
+	sort.Strings(popularActions)
+	return popularActions
+}
+
+// normalize replaces homoglyphs with the character they imitate
+func normalize(s string) string {
+	for _, h := range homoglyphs {
+		s = strings.ReplaceAll(s, h.from, h.to)
+	}
+	return s
+}
+
+// editDistance returns the optimal string alignment distance, which counts adjacent transpositions as a single edit
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := 0; j <= len(rb); j++ {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// maxDistance is the largest edit distance considered a near-miss, short names only allow a single edit
+func maxDistance(name string) int {
+	if len([]rune(name)) < 5 {
+		return 1
+	}
+	return 2
+}
+
+// getCandidates returns the popular actions the action is a near-miss of, closest first.
+// The owner or the repository must match, so unrelated actions with similar names are not reported.
+func getCandidates(action string, popularActions []string) []candidate {
+	parts := strings.SplitN(action, "/", 2)
+	owner, repo := parts[0], parts[1]
+
+	var candidates []candidate
+	for _, popularAction := range popularActions {
+		if normalize(action) == normalize(popularAction) {
+			candidates = append(candidates, candidate{action: popularAction, distance: editDistance(action, popularAction), homoglyph: true})
+			continue
+		}
+		popularParts := strings.SplitN(popularAction, "/", 2)
+		popularOwner, popularRepo := popularParts[0], popularParts[1]
+		if owner == popularOwner {
+			if distance := editDistance(repo, popularRepo); distance <= maxDistance(popularRepo) {
+				candidates = append(candidates, candidate{action: popularAction, distance: distance})
+			}
+		} else if repo == popularRepo {
+			if distance := editDistance(owner, popularOwner); distance <= maxDistance(popularOwner) {
+				candidates = append(candidates, candidate{action: popularAction, distance: distance})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].homoglyph != candidates[j].homoglyph {
+			return candidates[i].homoglyph
+		}
+		return candidates[i].distance < candidates[j].distance
+	})
+	return candidates
+}
+
+// isHighConfidence returns true if the action only imitates the closest candidate, so it can be corrected
+func isHighConfidence(candidates []candidate) bool {
+	if candidates[0].homoglyph {
+		return len(candidates) == 1 || !candidates[1].homoglyph
+	}
+	return candidates[0].distance == 1 && (len(candidates) == 1 || candidates[1].distance > 1)
+}
+
+// FindTyposquattedActions compares the actions used in the workflow with the popular actions,
+// and returns findings for actions that are not popular, but are a near-miss of a popular action,
+// e.g. actions/chekout. When the action clearly imitates one popular action, it is added as the suggestion.
+func FindTyposquattedActions(inputYaml string, popularActions []string) ([]findings.Finding, error) {
+	index, err := document.Parse(inputYaml).Index()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+
+	popular := make(map[string]bool)
+	for _, popularAction := range popularActions {
+		popular[strings.ToLower(popularAction)] = true
+	}
+
+	var typosquatFindings []findings.Finding
+	for _, reference := range index.ActionReferences(false) {
+		uses := reference.Uses.Value
+		if !strings.Contains(uses, "@") || strings.HasPrefix(uses, "docker://") || strings.HasPrefix(uses, "./") {
+			continue
+		}
+		splitOnSlash := strings.Split(strings.Split(uses, "@")[0], "/")
+		if len(splitOnSlash) < 2 {
+			continue
+		}
+		action := splitOnSlash[0] + "/" + splitOnSlash[1]
+		if popular[strings.ToLower(action)] {
+			continue
+		}
+
+		candidates := getCandidates(strings.ToLower(action), popularActions)
+		if len(candidates) == 0 {
+			continue
+		}
+
+		finding := findings.Finding{
+			RuleID:  RuleTyposquattedAction,
+			Message: fmt.Sprintf("Action %s is similar to the popular action %s and may be typosquatting it", action, candidates[0].action),
+			JobName: reference.JobName,
+			Action:  action,
+			Line:    reference.Uses.Line,
+			Column:  reference.Uses.Column,
+		}
+		if isHighConfidence(candidates) {
+			finding.Suggestion = candidates[0].action
+		}
+		typosquatFindings = append(typosquatFindings, finding)
+	}
+
+	return typosquatFindings, nil
+}
+
+// FixTyposquattedActions replaces the actions in the findings that have a suggested popular action,
+// and marks the findings that were fixed. Actions pinned to a commit SHA are only replaced if the
+// version comment can be used as the ref, since the SHA belongs to the typosquatted repository.
+func FixTyposquattedActions(inputYaml string, typosquatFindings []findings.Finding) (string, bool, error) {
+	inputLines := strings.Split(inputYaml, "\n")
+	updated := false
+	// the steps shared by jobs with an alias have a finding for each job, on the line of the anchor
+	fixedLines := make(map[int]bool)
+	for i, finding := range typosquatFindings {
+		if finding.Suggestion == "" || finding.Line < 1 || finding.Line > len(inputLines) {
+			continue
+		}
+		if fixedLines[finding.Line] {
+			typosquatFindings[i].Fixed = true
+			continue
+		}
+		refRegex := regexp.MustCompile(regexp.QuoteMeta(finding.Action) + `((?:/[^@\s'"]*)?)@([^\s'"#]+)(\s+#\s*(\S+))?`)
+		line := inputLines[finding.Line-1]
+		match := refRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		subPath, ref, comment := match[1], match[2], match[4]
+		if shaRegex.MatchString(ref) {
+			if comment == "" {
+				continue
+			}
+			ref = comment
+		}
+		inputLines[finding.Line-1] = strings.Replace(line, match[0], finding.Suggestion+subPath+"@"+ref, 1)
+		typosquatFindings[i].Fixed = true
+		fixedLines[finding.Line] = true
+		updated = true
+	}
+
+	if !updated {
+		return inputYaml, false, nil
+	}
+	return strings.Join(inputLines, "\n"), true, nil
+}
@@ -0,0 +1,111 @@
+package typosquat
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestTyposquattedActions(t *testing.T) {
+	const inputDirectory = "../../../testfiles/typosquat/input"
+	const outputDirectory = "../../../testfiles/typosquat/output"
+
+	popularActions := []string{"actions/cache", "actions/checkout", "actions/setup-go", "actions/setup-node", "docker/build-push-action"}
+
+	input, err := ioutil.ReadFile(path.Join(inputDirectory, "typosquatted-actions.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+
+	got, err := FindTyposquattedActions(string(input), popularActions)
+	if err != nil {
+		t.Fatalf("FindTyposquattedActions() unexpected error = %v", err)
+	}
+
+	want := []struct {
+		action     string
+		line       int
+		suggestion string
+	}{
+		{action: "actions/chekout", line: 8, suggestion: "actions/checkout"},
+		{action: "actlons/setup-node", line: 9, suggestion: "actions/setup-node"},
+		{action: "action/cache", line: 10, suggestion: "actions/cache"},
+		{action: "docker/build-push-actoin", line: 12, suggestion: "docker/build-push-action"},
+		{action: "actions/setup-gde", line: 13, suggestion: ""},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("FindTyposquattedActions() returned %d findings, want %d: %v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].RuleID != RuleTyposquattedAction || got[i].Action != w.action || got[i].Line != w.line || got[i].Suggestion != w.suggestion {
+			t.Errorf("unexpected finding %d: %+v", i, got[i])
+		}
+	}
+
+	out, updated, err := FixTyposquattedActions(string(input), got)
+	if err != nil {
+		t.Fatalf("FixTyposquattedActions() unexpected error = %v", err)
+	}
+	if !updated {
+		t.Errorf("FixTyposquattedActions() updated = false, want true")
+	}
+
+	output, err := ioutil.ReadFile(path.Join(outputDirectory, "typosquatted-actions.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+	if out != string(output) {
+		t.Errorf("FixTyposquattedActions() = %v, want %v", out, string(output))
+	}
+
+	// the output is remediated, so fixing it again does not change it
+	again, err := FindTyposquattedActions(out, popularActions)
+	if err != nil {
+		t.Fatalf("FindTyposquattedActions() of the output unexpected error = %v", err)
+	}
+	if fixed, updated, err := FixTyposquattedActions(out, again); err != nil || updated || fixed != out {
+		t.Errorf("FixTyposquattedActions() of the output = %v, %v, want it unchanged", updated, err)
+	}
+
+	if got[4].Fixed {
+		t.Errorf("FixTyposquattedActions() fixed a finding without a suggestion: %+v", got[4])
+	}
+}
+
+func TestLoadPopularActions(t *testing.T) {
+	popularActions, err := LoadPopularActions("../../../knowledge-base/actions")
+	if err != nil {
+		t.Fatalf("LoadPopularActions() unexpected error = %v", err)
+	}
+
+	found := false
+	for _, action := range popularActions {
+		if action == "actions/checkout" {
+			found = true
+		}
+		if action == "google/clusterfuzzlite/actions" {
+			t.Errorf("LoadPopularActions() returned a path within an action: %s", action)
+		}
+	}
+	if !found {
+		t.Errorf("LoadPopularActions() did not return actions/checkout")
+	}
+}
+
+func Test_editDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"checkout", "checkout", 0},
+		{"chekout", "checkout", 1},
+		{"chekcout", "checkout", 1},
+		{"cache", "cash", 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package unmaintained
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/metrics"
+	"github.com/step-security/secure-repo/remediation/workflow/actionrepo"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"github.com/step-security/secure-repo/remediation/workflow/maintainedactions"
+	"golang.org/x/oauth2"
+)
+
+const (
+	RuleArchivedAction     = "archived-action"
+	RuleUnmaintainedAction = "unmaintained-action"
+
+	// UnmaintainedAfter is the time since the last push after which an action is considered unmaintained
+	UnmaintainedAfter = 365 * 24 * time.Hour
+)
+
+// now is a variable so tests can control the current time
+var now = time.Now
+
+type repoStatus struct {
+	archived bool
+	pushedAt time.Time
+}
+
+func getClient(ctx context.Context) *github.Client {
+	PAT := os.Getenv("PAT")
+	if PAT == "" {
+		return github.NewClient(metrics.GitHubHTTPClient())
+	}
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: PAT},
+	)
+	return github.NewClient(oauth2.NewClient(metrics.WithGitHubClient(ctx), ts))
+}
+
+func getRepoStatus(ctx context.Context, client *github.Client, owner, repo string) (*repoStatus, error) {
+	repository, err := actionrepo.GetRepository(ctx, client, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	return &repoStatus{archived: repository.Archived, pushedAt: repository.PushedAt}, nil
+}
+
+// FindUnmaintainedActions queries the GitHub API for the repository of each action used in the workflow,
+// and returns findings for actions whose repository is archived or has not been pushed to in UnmaintainedAfter.
+// If a maintained replacement exists in maintainedActionsMap, it is added as the suggestion.
+func FindUnmaintainedActions(ctx context.Context, inputYaml string, maintainedActionsMap map[string]string) ([]findings.Finding, error) {
+	index, err := document.Parse(inputYaml).Index()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse yaml %v", err)
+	}
+
+	client := getClient(ctx)
+	statuses := make(map[string]*repoStatus)
+
+	var unmaintainedFindings []findings.Finding
+	for _, reference := range index.ActionReferences(false) {
+		uses := reference.Uses.Value
+		if !strings.Contains(uses, "@") || strings.HasPrefix(uses, "docker://") || strings.HasPrefix(uses, "./") {
+			continue
+		}
+		action := strings.Split(uses, "@")[0]
+		splitOnSlash := strings.Split(action, "/")
+		if len(splitOnSlash) < 2 {
+			continue
+		}
+		ownerRepo := strings.ToLower(splitOnSlash[0] + "/" + splitOnSlash[1])
+
+		status, found := statuses[ownerRepo]
+		if !found {
+			status, err = getRepoStatus(ctx, client, splitOnSlash[0], splitOnSlash[1])
+			if err != nil {
+				return nil, fmt.Errorf("unable to get repository status for %s: %v", ownerRepo, err)
+			}
+			statuses[ownerRepo] = status
+		}
+
+		finding := findings.Finding{
+			JobName:    reference.JobName,
+			Action:     action,
+			Line:       reference.Uses.Line,
+			Column:     reference.Uses.Column,
+			Suggestion: maintainedActionsMap[action],
+		}
+		if status.archived {
+			finding.RuleID = RuleArchivedAction
+			finding.Message = fmt.Sprintf("Action %s is archived and no longer maintained", action)
+		} else if !status.pushedAt.IsZero() && now().Sub(status.pushedAt) > UnmaintainedAfter {
+			finding.RuleID = RuleUnmaintainedAction
+			finding.Message = fmt.Sprintf("Action %s has not been updated since %s", action, status.pushedAt.Format("2006-01-02"))
+		} else {
+			continue
+		}
+		unmaintainedFindings = append(unmaintainedFindings, finding)
+	}
+
+	return unmaintainedFindings, nil
+}
+
+// ReplaceUnmaintainedActions replaces the actions in the findings that have a suggested maintained replacement,
+// and marks the findings that were fixed.
+func ReplaceUnmaintainedActions(ctx context.Context, inputYaml string, unmaintainedFindings []findings.Finding, replaceByMajorTag bool) (string, bool, error) {
+	actionMap := make(map[string]string)
+	for _, finding := range unmaintainedFindings {
+		if finding.Suggestion != "" {
+			actionMap[finding.Action] = finding.Suggestion
+		}
+	}
+
+	if len(actionMap) == 0 {
+		return inputYaml, false, nil
+	}
+
+	out, replacedLines, err := maintainedactions.ReplaceActionLines(ctx, inputYaml, actionMap, replaceByMajorTag)
+	if err != nil {
+		return inputYaml, false, err
+	}
+
+	// the findings are fixed if the uses of their line was replaced
+	replaced := make(map[int]bool)
+	for _, line := range replacedLines {
+		replaced[line] = true
+	}
+	for i, finding := range unmaintainedFindings {
+		if finding.Suggestion != "" && replaced[finding.Line] {
+			unmaintainedFindings[i].Fixed = true
+		}
+	}
+
+	return out, len(replacedLines) > 0, nil
+}
@@ -0,0 +1,78 @@
+package unmaintained
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestFindUnmaintainedActions(t *testing.T) {
+	const inputDirectory = "../../../testfiles/unmaintained/input"
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	saveNow := now
+	defer func() { now = saveNow }()
+	now = func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout",
+		httpmock.NewStringResponder(200, `{"archived": false, "pushed_at": "2024-05-20T10:00:00Z"}`))
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/amannn/action-semantic-pull-request",
+		httpmock.NewStringResponder(200, `{"archived": true, "pushed_at": "2024-01-10T10:00:00Z"}`))
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/old-owner/stale-action",
+		httpmock.NewStringResponder(200, `{"archived": false, "pushed_at": "2021-03-01T10:00:00Z"}`))
+
+	input, err := ioutil.ReadFile(path.Join(inputDirectory, "unmaintained-actions.yml"))
+	if err != nil {
+		t.Fatalf("error reading test file")
+	}
+
+	maintainedActionsMap := map[string]string{
+		"amannn/action-semantic-pull-request": "step-security/action-semantic-pull-request",
+	}
+
+	got, err := FindUnmaintainedActions(context.Background(), string(input), maintainedActionsMap)
+	if err != nil {
+		t.Fatalf("FindUnmaintainedActions() unexpected error = %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("FindUnmaintainedActions() returned %d findings, want 2: %v", len(got), got)
+	}
+
+	if got[0].RuleID != RuleArchivedAction || got[0].Action != "amannn/action-semantic-pull-request" || got[0].Line != 11 || got[0].JobName != "lint" {
+		t.Errorf("unexpected finding for archived action: %+v", got[0])
+	}
+	if got[0].Suggestion != "step-security/action-semantic-pull-request" {
+		t.Errorf("expected suggestion for archived action, got %q", got[0].Suggestion)
+	}
+
+	if got[1].RuleID != RuleUnmaintainedAction || got[1].Action != "old-owner/stale-action" || got[1].Line != 12 {
+		t.Errorf("unexpected finding for unmaintained action: %+v", got[1])
+	}
+	if got[1].Suggestion != "" {
+		t.Errorf("expected no suggestion for unmaintained action, got %q", got[1].Suggestion)
+	}
+}
+
+func TestFindUnmaintainedActionsAPIError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.github.com/repos/actions/checkout",
+		httpmock.NewStringResponder(500, `{"message": "error"}`))
+
+	input := "jobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v3\n"
+
+	_, err := FindUnmaintainedActions(context.Background(), input, nil)
+	if err == nil {
+		t.Errorf("FindUnmaintainedActions() expected an error but got none")
+	}
+}
@@ -0,0 +1,68 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/workflow/equivalence"
+	"github.com/step-security/secure-repo/remediation/workflow/validation"
+)
+
+// InvalidWorkflowError is returned by SecureWorkflow when a module turned a valid workflow into an invalid one, which is
+// an internal error of the module, instead of returning the workflow it broke
+type InvalidWorkflowError struct {
+	Module   string
+	Problems []validation.Problem
+}
+
+func (e *InvalidWorkflowError) Error() string {
+	problems := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		problems = append(problems, problem.String())
+	}
+	return fmt.Sprintf("module %s produced an invalid workflow: %s", e.Module, strings.Join(problems, "; "))
+}
+
+// moduleOutput is the workflow returned by a module that changed it, and the edits the module reports
+type moduleOutput struct {
+	module string
+	output string
+	edits  *equivalence.Edits
+}
+
+// validateOutputs returns an *InvalidWorkflowError for the first module whose output has problems that its input did not
+// have. The outputs of an input that is not a workflow, such as a composite action or a file that does not parse, are not
+// validated, since the problems of the input cannot be told apart from the problems of the modules.
+func validateOutputs(inputYaml string, outputs []moduleOutput) error {
+	if len(outputs) == 0 {
+		return nil
+	}
+	before, err := validation.Validate(inputYaml)
+	if err != nil {
+		return err
+	}
+	for _, problem := range before {
+		if problem.Path == "" {
+			return nil
+		}
+	}
+	// the outputs of the modules are only validated one by one when the final output is invalid
+	after, err := validation.Validate(outputs[len(outputs)-1].output)
+	if err != nil {
+		return err
+	}
+	if len(validation.New(before, after)) == 0 {
+		return nil
+	}
+	for _, output := range outputs {
+		after, err := validation.Validate(output.output)
+		if err != nil {
+			return err
+		}
+		if added := validation.New(before, after); len(added) > 0 {
+			return &InvalidWorkflowError{Module: output.module, Problems: added}
+		}
+		before = after
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/step-security/secure-repo/remediation/findings"
+	"github.com/step-security/secure-repo/remediation/report"
+)
+
+// brokenRemediator renames the runs-on keys of the jobs, which makes the workflow invalid
+type brokenRemediator struct{}
+
+func (brokenRemediator) Name() string {
+	return "broken"
+}
+
+func (brokenRemediator) Detect(inputYaml string) ([]findings.Finding, error) {
+	return nil, nil
+}
+
+func (brokenRemediator) Apply(inputYaml string, detected []findings.Finding) (string, bool, error) {
+	output := strings.ReplaceAll(inputYaml, "runs-on:", "runs_on:")
+	return output, output != inputYaml, nil
+}
+
+func (brokenRemediator) Report(workflowReport *report.Report, path string, detected []findings.Finding) {
+}
+
+func TestSecureWorkflowInvalidOutput(t *testing.T) {
+	defer func() { registered = nil }()
+	if err := RegisterRemediator(brokenRemediator{}); err != nil {
+		t.Fatalf("RegisterRemediator() unexpected error = %v", err)
+	}
+
+	input := `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make build
+`
+	os.Setenv("KBFolder", "../../knowledge-base/actions")
+	queryParams := map[string]string{"addHardenRunner": "false", "pinActions": "false"}
+
+	_, err := SecureWorkflow(context.Background(), queryParams, input, &mockDynamoDBClient{}, Options{})
+	var invalid *InvalidWorkflowError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected an *InvalidWorkflowError, got %v", err)
+	}
+	if invalid.Module != "broken" || len(invalid.Problems) == 0 || invalid.Problems[0].Path != "/jobs/build" {
+		t.Errorf("unexpected error %v", invalid)
+	}
+
+	// the problems of the input are not blamed on the module, and the modules that keep the workflow valid are not
+	queryParams["broken"] = "false"
+	invalidInput := strings.Replace(input, "make build", "echo ${{ github.sha", 1)
+	output, err := SecureWorkflow(context.Background(), queryParams, invalidInput, &mockDynamoDBClient{}, Options{})
+	if err != nil {
+		t.Fatalf("Error not expected: %v", err)
+	}
+	if !output.AddedPermissions {
+		t.Errorf("expected the permissions to be added, got\n%s", output.FinalOutput)
+	}
+}
@@ -0,0 +1,378 @@
+// Package validation checks that a workflow is valid, against the github-workflow JSON schema of SchemaStore and with
+// basic checks of actionlint that the schema cannot express, such as jobs that need jobs which do not exist. It is used
+// to check the output of the remediations, so a module that breaks a workflow fails the request instead of opening a
+// pull request with a workflow that does not run.
+package validation
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/step-security/secure-repo/remediation/workflow/document"
+	"gopkg.in/yaml.v3"
+)
+
+// workflowSchema is the github-workflow.json schema of https://github.com/SchemaStore/schemastore, which is updated by
+// copying src/schemas/json/github-workflow.json of the repository
+//
+//go:embed github-workflow.json
+var workflowSchema string
+
+const schemaURL = "https://json.schemastore.org/github-workflow.json"
+
+var (
+	compileOnce    sync.Once
+	compiledSchema *jsonschema.Schema
+	compileErr     error
+)
+
+// Problem is a reason why a workflow is not valid
+type Problem struct {
+	// Path is the JSON pointer of the value with the problem, e.g. /jobs/build/steps/0, which is empty for the workflow
+	Path string
+	// Line is the line of the value, or 0 if it is not known
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	message := p.Message
+	if p.Path != "" {
+		message = strings.TrimPrefix(p.Path, "/") + ": " + message
+	}
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d: %s", p.Line, message)
+	}
+	return message
+}
+
+// key is the problem without the indexes of its path, so the problems of a workflow are found in the workflow after
+// steps are inserted before them
+func (p Problem) key() string {
+	tokens := strings.Split(p.Path, "/")
+	for i, token := range tokens {
+		if _, err := strconv.Atoi(token); err == nil {
+			tokens[i] = "*"
+		}
+	}
+	return strings.Join(tokens, "/") + " " + p.Message
+}
+
+// Validate returns the problems of the workflow, which has no problems if it is valid. The error is returned if the
+// schema cannot be compiled.
+func Validate(inputYaml string) ([]Problem, error) {
+	compileOnce.Do(func() {
+		compiledSchema, compileErr = jsonschema.CompileString(schemaURL, workflowSchema)
+	})
+	if compileErr != nil {
+		return nil, compileErr
+	}
+
+	root, err := document.Parse(inputYaml).Node()
+	if err != nil {
+		return []Problem{{Message: fmt.Sprintf("unable to parse the workflow: %v", err)}}, nil
+	}
+	if len(root.Content) == 0 {
+		return []Problem{{Message: "the workflow is empty"}}, nil
+	}
+	top := root.Content[0]
+
+	var problems []Problem
+	if err := compiledSchema.Validate(toJSON(top)); err != nil {
+		validationErr, ok := err.(*jsonschema.ValidationError)
+		if !ok {
+			return nil, err
+		}
+		problems = append(problems, schemaProblems(top, validationErr)...)
+	}
+	problems = append(problems, lint(top)...)
+	return problems, nil
+}
+
+// New returns the problems of after that are not problems of before, e.g. the problems a module added to a workflow
+func New(before, after []Problem) []Problem {
+	counts := map[string]int{}
+	for _, problem := range before {
+		counts[problem.key()]++
+	}
+	var added []Problem
+	for _, problem := range after {
+		if counts[problem.key()] > 0 {
+			counts[problem.key()]--
+			continue
+		}
+		added = append(added, problem)
+	}
+	return added
+}
+
+// schemaProblems returns the problems of the errors of the keywords that failed, such as a missing property, rather
+// than of the schemas that contain them
+func schemaProblems(top *yaml.Node, validationErr *jsonschema.ValidationError) []Problem {
+	var problems []Problem
+	seen := map[string]bool{}
+	for _, err := range leaves(validationErr) {
+		problem := Problem{Path: err.InstanceLocation, Message: sortNames(err.Message)}
+		if seen[problem.key()] {
+			continue
+		}
+		seen[problem.key()] = true
+		if node := lookup(top, err.InstanceLocation); node != nil {
+			problem.Line = node.Line
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+// leaves returns the errors without causes of the error. Of the schemas of a oneOf or anyOf, only the errors of the one
+// that matched the deepest values are returned, since it is the schema the value was meant to match, e.g. the object
+// of the events rather than the string of an event for an "on" with an invalid event. Of the schemas that matched
+// values as deep, the one of the type of the value is the one it was meant to match.
+func leaves(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	causes := err.Causes
+	if strings.HasSuffix(err.KeywordLocation, "/oneOf") || strings.HasSuffix(err.KeywordLocation, "/anyOf") {
+		best, bestScore := causes[0], -1
+		for _, cause := range causes {
+			score := 2 * maxDepth(cause)
+			if !isTypeError(cause) {
+				score++
+			}
+			if score > bestScore {
+				best, bestScore = cause, score
+			}
+		}
+		causes = []*jsonschema.ValidationError{best}
+	}
+	var result []*jsonschema.ValidationError
+	for _, cause := range causes {
+		result = append(result, leaves(cause)...)
+	}
+	return result
+}
+
+// maxDepth returns the depth of the deepest value with an error of the error or its causes
+func maxDepth(err *jsonschema.ValidationError) int {
+	depth := strings.Count(err.InstanceLocation, "/")
+	for _, cause := range err.Causes {
+		if causeDepth := maxDepth(cause); causeDepth > depth {
+			depth = causeDepth
+		}
+	}
+	return depth
+}
+
+// isTypeError returns true if the error is that the value is not of the type of the schema
+func isTypeError(err *jsonschema.ValidationError) bool {
+	for len(err.Causes) == 1 {
+		err = err.Causes[0]
+	}
+	return len(err.Causes) == 0 && strings.HasSuffix(err.KeywordLocation, "/type")
+}
+
+// quotedNames matches the list of quoted names of a message, e.g. of the additional properties of a mapping
+var quotedNames = regexp.MustCompile(`'[^']*'(, '[^']*')+`)
+
+// sortNames sorts the names of the lists of a message, which the schema lists in the order of a map
+func sortNames(message string) string {
+	return quotedNames.ReplaceAllStringFunc(message, func(names string) string {
+		list := strings.Split(names, ", ")
+		sort.Strings(list)
+		return strings.Join(list, ", ")
+	})
+}
+
+// lookup returns the node of the JSON pointer, or the deepest node of the pointer that is found
+func lookup(node *yaml.Node, pointer string) *yaml.Node {
+	node = document.Resolve(node)
+	if pointer == "" {
+		return node
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			next = document.MappingValue(node, token)
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(token); err == nil && i >= 0 && i < len(node.Content) {
+				next = document.Resolve(node.Content[i])
+			}
+		}
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+// toJSON returns the value of the node as the schema validates it, which is the value of the workflow converted to
+// JSON. The keys merged with << are values of the mapping, unless the mapping has them.
+func toJSON(node *yaml.Node) interface{} {
+	node = document.Resolve(node)
+	switch node.Kind {
+	case yaml.MappingNode:
+		mapping := map[string]interface{}{}
+		var merged []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Tag == "!!merge" {
+				merged = append(merged, document.Resolve(node.Content[i+1]))
+				continue
+			}
+			mapping[node.Content[i].Value] = toJSON(node.Content[i+1])
+		}
+		for _, value := range merged {
+			sources := []*yaml.Node{value}
+			if value.Kind == yaml.SequenceNode {
+				sources = value.Content
+			}
+			for _, source := range sources {
+				if values, ok := toJSON(source).(map[string]interface{}); ok {
+					for key, value := range values {
+						if _, found := mapping[key]; !found {
+							mapping[key] = value
+						}
+					}
+				}
+			}
+		}
+		return mapping
+	case yaml.SequenceNode:
+		sequence := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			sequence = append(sequence, toJSON(item))
+		}
+		return sequence
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!null":
+			return nil
+		case "!!bool", "!!int", "!!float":
+			var value interface{}
+			if err := node.Decode(&value); err == nil {
+				switch value := value.(type) {
+				case bool, float64:
+					return value
+				case int:
+					return int64(value)
+				case int64, uint64:
+					return value
+				}
+			}
+		}
+		return node.Value
+	}
+	return nil
+}
+
+var (
+	// actionPattern matches the actions of steps, which are local actions, Docker images or actions of repositories
+	// at a ref
+	actionPattern = regexp.MustCompile(`^(\./.*|docker://.+|[^/@\s]+/[^@\s]+@[^@\s]+)$`)
+	// reusableWorkflowPattern matches the reusable workflows that jobs call, which are local or of a repository at a ref
+	reusableWorkflowPattern = regexp.MustCompile(`^(\./.+\.ya?ml|[^/@\s]+/[^/@\s]+/.+\.ya?ml@[^@\s]+)$`)
+)
+
+// lint returns the problems of the workflow that the schema does not find, which actionlint finds: jobs that need jobs
+// which do not exist, steps of a job with the same id, actions and reusable workflows that cannot be resolved, and
+// expressions that are not closed
+func lint(top *yaml.Node) []Problem {
+	var problems []Problem
+	add := func(node *yaml.Node, path, format string, args ...interface{}) {
+		problems = append(problems, Problem{Path: path, Line: node.Line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	jobs := document.MappingValue(top, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return checkExpressions(top, "", problems)
+	}
+	names := map[string]bool{}
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		names[jobs.Content[i].Value] = true
+	}
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		name, job := jobs.Content[i].Value, document.Resolve(jobs.Content[i+1])
+		path := "/jobs/" + escape(name)
+		if job.Kind != yaml.MappingNode {
+			continue
+		}
+		if needs := document.MappingValue(job, "needs"); needs != nil {
+			items := []*yaml.Node{needs}
+			if needs.Kind == yaml.SequenceNode {
+				items = needs.Content
+			}
+			for _, item := range items {
+				item = document.Resolve(item)
+				if item.Kind == yaml.ScalarNode && !names[item.Value] {
+					add(item, path+"/needs", "job %s does not exist", item.Value)
+				}
+			}
+		}
+		if uses := document.MappingValue(job, "uses"); uses != nil && uses.Kind == yaml.ScalarNode && !reusableWorkflowPattern.MatchString(uses.Value) {
+			add(uses, path+"/uses", "%q is not a reusable workflow", uses.Value)
+		}
+		steps := document.MappingValue(job, "steps")
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			continue
+		}
+		ids := map[string]bool{}
+		for j, step := range steps.Content {
+			step = document.Resolve(step)
+			stepPath := fmt.Sprintf("%s/steps/%d", path, j)
+			if id := document.MappingValue(step, "id"); id != nil && id.Kind == yaml.ScalarNode {
+				if ids[id.Value] {
+					add(id, stepPath+"/id", "another step of the job has the id %s", id.Value)
+				}
+				ids[id.Value] = true
+			}
+			if uses := document.MappingValue(step, "uses"); uses != nil && uses.Kind == yaml.ScalarNode && !strings.Contains(uses.Value, "${{") && !actionPattern.MatchString(uses.Value) {
+				add(uses, stepPath+"/uses", "%q is not an action", uses.Value)
+			}
+		}
+	}
+	return checkExpressions(top, "", problems)
+}
+
+// checkExpressions adds the problems of the strings of the node with a ${{ that is not closed by }}
+func checkExpressions(node *yaml.Node, path string, problems []Problem) []Problem {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			problems = checkExpressions(node.Content[i+1], path+"/"+escape(node.Content[i].Value), problems)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			problems = checkExpressions(item, fmt.Sprintf("%s/%d", path, i), problems)
+		}
+	case yaml.ScalarNode:
+		value := node.Value
+		for {
+			start := strings.Index(value, "${{")
+			if start < 0 {
+				break
+			}
+			end := strings.Index(value[start:], "}}")
+			if end < 0 {
+				problems = append(problems, Problem{Path: path, Line: node.Line, Message: "expression is not closed with }}"})
+				break
+			}
+			value = value[start+end+2:]
+		}
+	}
+	return problems
+}
+
+// escape returns the token of the JSON pointer of a key
+func escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,116 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		workflow string
+		want     []string
+	}{
+		{name: "valid", workflow: `on: push
+permissions:
+  contents: read
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - run: make
+  test:
+    needs: build
+    uses: octo-org/workflows/.github/workflows/test.yml@v1
+`},
+		{name: "anchors and merge keys", workflow: `on: push
+jobs:
+  build: &build
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+  test:
+    <<: *build
+    timeout-minutes: 10
+`},
+		{name: "invalid permission", workflow: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    permissions:
+      content: read
+    steps:
+      - run: make
+`, want: []string{"line 6: jobs/build/permissions: additionalProperties 'content' not allowed"}},
+		{name: "steps without runs-on", workflow: `on: push
+jobs:
+  build:
+    steps:
+      - run: make
+`, want: []string{"line 4: jobs/build: missing properties: 'runs-on'"}},
+		{name: "actionlint checks", workflow: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    needs: [lint]
+    steps:
+      - id: make
+        uses: actions/checkout
+      - id: make
+        run: echo "${{ github.sha"
+`, want: []string{
+			"line 5: jobs/build/needs: job lint does not exist",
+			"line 8: jobs/build/steps/0/uses: \"actions/checkout\" is not an action",
+			"line 9: jobs/build/steps/1/id: another step of the job has the id make",
+			"line 10: jobs/build/steps/1/run: expression is not closed with }}",
+		}},
+		{name: "not parsed", workflow: "on: push\njobs: [\n", want: []string{"unable to parse the workflow: yaml: line 2: did not find expected node content"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems, err := Validate(test.workflow)
+			if err != nil {
+				t.Fatalf("Validate() returned error: %v", err)
+			}
+			var got []string
+			for _, problem := range problems {
+				got = append(got, problem.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("Validate() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	before, err := Validate(`on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ github.sha"
+`)
+	if err != nil {
+		t.Fatalf("Validate() returned error: %v", err)
+	}
+	// the problem of the step is not new after a step is inserted before it
+	after, err := Validate(`on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    permissions:
+      content: read
+    steps:
+      - uses: step-security/harden-runner@v2
+      - run: echo "${{ github.sha"
+`)
+	if err != nil {
+		t.Fatalf("Validate() returned error: %v", err)
+	}
+	added := New(before, after)
+	if len(added) != 1 || added[0].Path != "/jobs/build/permissions" {
+		t.Errorf("New() = %v, want the problem of the permissions", added)
+	}
+}
@@ -6,18 +6,19 @@ import (
 	"os"
 
 	"github.com/google/go-github/v40/github"
+	"github.com/step-security/secure-repo/remediation/metrics"
 	"golang.org/x/oauth2"
 )
 
-func GetGitHubWorkflowContents(queryStringParams map[string]string) (string, error) {
+// GetGitHubWorkflowContents returns the contents of the file of the path, owner, repo and branch query parameters
+func GetGitHubWorkflowContents(ctx context.Context, queryStringParams map[string]string) (string, error) {
 
 	PAT := os.Getenv("PAT")
 
-	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: PAT},
 	)
-	tc := oauth2.NewClient(ctx, ts)
+	tc := oauth2.NewClient(metrics.WithGitHubClient(ctx), ts)
 
 	client := github.NewClient(tc)
 
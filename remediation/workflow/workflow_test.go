@@ -1,6 +1,7 @@
 package workflow
 
 import (
+	"context"
 	"io/ioutil"
 	"log"
 	"testing"
@@ -56,7 +57,7 @@ func Test_getGitHubWorkflowContents(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := GetGitHubWorkflowContents(tt.args.queryStringParams)
+			got, err := GetGitHubWorkflowContents(context.Background(), tt.args.queryStringParams)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getGitHubWorkflowContents() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -0,0 +1,83 @@
+package yamledit
+
+import (
+	"strings"
+
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"gopkg.in/yaml.v3"
+)
+
+// LastLine returns the last line, from 1, of a node in the lines of its text, as split by strings.Split: the line of
+// the node, or the last line of the last literal or folded scalar in it. The lines after a node are inserted after its
+// last line, so the lines of its block scalars must all be before it, or the inserted lines end the scalar.
+func LastLine(lines []string, node *yaml.Node) int {
+	last := node.Line
+	if node.Kind == yaml.ScalarNode && node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		last = blockScalarLastLine(lines, node)
+	}
+	for _, child := range node.Content {
+		if line := LastLine(lines, child); line > last {
+			last = line
+		}
+	}
+	return last
+}
+
+// blockScalarLastLine returns the last line of a literal or folded scalar. The number of lines of the value is not the
+// number of lines of the scalar, since the lines of a folded scalar are joined and the empty lines at the end of the
+// scalar are dropped unless it keeps them, so the lines of the scalar are found in the text: they are the lines that
+// are indented at least as much as its content, and the empty lines between them. The empty lines after them are part
+// of the scalar if it keeps them, with the + chomping indicator. A comment is part of the scalar if it is indented as
+// its content.
+func blockScalarLastLine(lines []string, node *yaml.Node) int {
+	last := node.Line
+	// the text ends with a newline, after which strings.Split returns an empty line which is not a line of the text
+	end := len(lines)
+	if end > 0 && lines[end-1] == "" {
+		end--
+	}
+	if node.Line < 1 || node.Line > end {
+		return last
+	}
+	keep := keepsEmptyLines(lines[node.Line-1], node)
+	// the content is indented by the indentation of its first line, less the spaces the value starts with, which are
+	// the spaces of a first line more indented than the rest
+	value := strings.TrimLeft(node.Value, "\n")
+	contentIndent := -1
+	for n := node.Line + 1; n <= end; n++ {
+		line := strings.TrimSuffix(lines[n-1], "\r")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" {
+			if keep {
+				last = n
+			}
+			continue
+		}
+		indent := len(line) - len(trimmed)
+		if contentIndent < 0 {
+			if value == "" {
+				break
+			}
+			contentIndent = indent - (len(value) - len(strings.TrimLeft(value, " ")))
+		}
+		if indent < contentIndent || contentIndent <= 0 {
+			break
+		}
+		last = n
+	}
+	return last
+}
+
+// keepsEmptyLines returns true if the header of a block scalar, in the line of the node, has the + chomping indicator
+func keepsEmptyLines(line string, node *yaml.Node) bool {
+	column := textedit.ColumnOffset(line, node.Column)
+	start := strings.IndexAny(line[column:], "|>")
+	if start < 0 {
+		return false
+	}
+	header := line[column+start+1:]
+	if end := strings.IndexAny(header, " \t#\r"); end >= 0 {
+		header = header[:end]
+	}
+	return strings.Contains(header, "+")
+}
@@ -0,0 +1,36 @@
+package yamledit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLastLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{name: "plain", input: "run: make\nname: build\n", want: 1},
+		{name: "literal", input: "run: |\n  make\n  make test\n\nname: build\n", want: 3},
+		{name: "literal stripped", input: "run: |-\n  make\n\nname: build\n", want: 2},
+		{name: "literal keeping its empty lines", input: "run: |+\n  make\n\n\nname: build\n", want: 4},
+		{name: "literal keeping its empty lines at the end", input: "run: |+\n  make\n\n", want: 3},
+		{name: "literal with empty lines", input: "run: |\n  make\n\n  make test\nname: build\n", want: 4},
+		{name: "literal with a comment", input: "run: |\n  make\n  # make test\n# the end\n", want: 3},
+		{name: "literal with an indentation indicator", input: "run: |4\n      make\n    make test\nname: build\n", want: 3},
+		{name: "literal without a newline at the end", input: "run: |-\n  make", want: 2},
+		{name: "empty literal", input: "run: |\nname: build\n", want: 1},
+		{name: "folded", input: "run: >\n  golangci-lint run\n  --timeout 5m\n\nname: build\n", want: 3},
+		{name: "folded with more indented lines", input: "run: >\n  make\n    all\n  test\nname: build\n", want: 4},
+		{name: "mapping", input: "steps:\n  - run: make\n  - run: >-\n      make\n      test\nname: build\n", want: 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := parse(t, test.input)
+			if got := LastLine(strings.Split(test.input, "\n"), root.Content[1]); got != test.want {
+				t.Errorf("LastLine() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package yamledit
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Indentation is the indentation of the nested block collections of a text
+type Indentation struct {
+	// Width is the number of spaces the entries of a nested block mapping are indented by
+	Width int
+	// IndentSequences is true if the items of a block sequence in a mapping are indented more than its key, e.g.
+	// "steps:\n  - run: echo", and false if the dashes are at the column of the key, e.g. "steps:\n- run: echo"
+	IndentSequences bool
+}
+
+// DefaultIndentation is the indentation the modules write the blocks they insert with
+var DefaultIndentation = Indentation{Width: 2, IndentSequences: true}
+
+// blockScalarPattern matches the end of a line which starts a literal or folded block scalar
+var blockScalarPattern = regexp.MustCompile(`(^|[:-] )[|>][-+0-9]*$`)
+
+// indentedLine is a line of block YAML with its indent
+type indentedLine struct {
+	indent int
+	// content is the line without its indent and the dashes of the items it starts
+	content string
+	// column is the column of the content, after the dashes
+	column int
+	dashes int
+}
+
+// parseLine returns the indent and the content of the line, and false if the line is empty or a comment
+func parseLine(line string) (indentedLine, bool) {
+	content := strings.TrimRight(strings.TrimLeft(line, " "), " \r")
+	if content == "" || content[0] == '#' {
+		return indentedLine{}, false
+	}
+	parsed := indentedLine{indent: len(line) - len(strings.TrimLeft(line, " "))}
+	parsed.column = parsed.indent
+	for content == "-" || strings.HasPrefix(content, "- ") {
+		trimmed := strings.TrimLeft(content[1:], " ")
+		parsed.column += len(content) - len(trimmed)
+		parsed.dashes++
+		content = trimmed
+	}
+	if i := strings.Index(content, " #"); i >= 0 {
+		content = strings.TrimRight(content[:i], " ")
+	}
+	parsed.content = content
+	return parsed, true
+}
+
+// startsBlockScalar returns true if the line ends with the indicator of a literal or folded block scalar
+func (l indentedLine) startsBlockScalar() bool {
+	return blockScalarPattern.MatchString(l.content)
+}
+
+// DetectIndentation returns the indentation of most of the nested block collections of the text, or DefaultIndentation
+// if the text has none. The lines of block scalars are not read, since they are not YAML.
+func DetectIndentation(text string) Indentation {
+	widths := map[int]int{}
+	indented, indentless := 0, 0
+	var previous indentedLine
+	hasPrevious, blockColumn := false, -1
+	for _, line := range strings.Split(text, "\n") {
+		parsed, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+		if blockColumn >= 0 && parsed.indent > blockColumn {
+			continue
+		}
+		blockColumn = -1
+		// a key without a value on its line is followed by its nested collection
+		if hasPrevious && strings.HasSuffix(previous.content, ":") {
+			switch {
+			case parsed.dashes > 0 && parsed.indent == previous.column:
+				indentless++
+			case parsed.dashes > 0 && parsed.indent > previous.column:
+				indented++
+			case parsed.dashes == 0 && parsed.indent > previous.column:
+				widths[parsed.indent-previous.column]++
+			}
+		}
+		if parsed.startsBlockScalar() {
+			blockColumn = parsed.column
+		}
+		previous, hasPrevious = parsed, true
+	}
+
+	indentation := DefaultIndentation
+	count := 0
+	for width, n := range widths {
+		if n > count || n == count && width < indentation.Width {
+			indentation.Width, count = width, n
+		}
+	}
+	indentation.IndentSequences = indented >= indentless
+	return indentation
+}
+
+// Reindent returns the block YAML of the text, which is written with DefaultIndentation, with the indentation. The
+// lines of block scalars keep their indent relative to the first line of the scalar.
+func (in Indentation) Reindent(text string) string {
+	if in == DefaultIndentation {
+		return text
+	}
+	// the levels map the columns of the text to the columns of the output, from the outermost
+	type level struct{ from, to int }
+	levels := []level{{from: 0, to: 0}}
+	// the column of the level of the block scalar, and the indent of its first line in the text and the output
+	blockColumn, blockFrom, blockTo := -1, -1, 0
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if blockColumn >= 0 && indent > blockColumn {
+			if blockFrom < 0 {
+				blockFrom = indent
+			}
+			lines[i] = strings.Repeat(" ", blockTo+max(indent-blockFrom, 0)) + line[indent:]
+			continue
+		}
+		blockColumn = -1
+
+		parsed, ok := parseLine(line)
+		if !ok {
+			// a comment is indented like the line it is in the level of
+			to := 0
+			for j := len(levels) - 1; j >= 0; j-- {
+				if levels[j].from <= indent {
+					to = levels[j].to
+					if indent > levels[j].from {
+						to += in.Width
+					}
+					break
+				}
+			}
+			lines[i] = strings.Repeat(" ", to) + line[indent:]
+			continue
+		}
+
+		for len(levels) > 1 && levels[len(levels)-1].from > indent {
+			levels = levels[:len(levels)-1]
+		}
+		top := levels[len(levels)-1]
+		to := top.to
+		if indent > top.from {
+			to = top.to + in.Width
+			if parsed.dashes > 0 && !in.IndentSequences {
+				to = top.to
+			}
+			levels = append(levels, level{from: indent, to: to})
+		}
+		// the content of an item is at the column after its dash, which is not changed
+		if parsed.dashes > 0 {
+			levels = append(levels, level{from: parsed.column, to: to + parsed.column - indent})
+		}
+		lines[i] = strings.Repeat(" ", to) + line[indent:]
+
+		if parsed.startsBlockScalar() {
+			blockColumn, blockFrom, blockTo = parsed.column, -1, to+parsed.column-indent+in.Width
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,494 @@
+// Package yamledit changes the nodes of a parsed YAML file in its text, so the comments, blank lines, quotes and flow
+// collections of the rest of the file are kept as they are. The modules find the nodes to change in the tree of the
+// file, and the Editor writes the changes at the offsets of the nodes in a textedit.Buffer, in the style of the
+// collection they are made in, instead of each module splicing lines and columns of its own.
+package yamledit
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/step-security/secure-repo/remediation/textedit"
+	"gopkg.in/yaml.v3"
+)
+
+// Editor changes the nodes of the tree of a text. The nodes must be of the tree of the text the Editor was created
+// with, and the changes do not move the nodes of the other changes. The blocks it inserts are written with the
+// indentation of the text. An alias is changed at its anchor, so the change is made once for the anchor and all its
+// aliases, and the aliases are left as they are.
+type Editor struct {
+	text        string
+	lines       []string
+	buffer      *textedit.Buffer
+	indentation Indentation
+}
+
+// New returns an editor of the text
+func New(text string) *Editor {
+	return &Editor{text: text, lines: strings.Split(text, "\n"), buffer: textedit.NewBuffer(text), indentation: DetectIndentation(text)}
+}
+
+// String returns the text with the changes
+func (e *Editor) String() string {
+	return e.buffer.String()
+}
+
+// Changed returns true if the text was changed
+func (e *Editor) Changed() bool {
+	return e.buffer.Changed()
+}
+
+// ReplaceScalar replaces the value of a scalar written on one line. The value is written in the style of the scalar,
+// e.g. in single quotes if the scalar is, and quoted if it would not be read as the same string in plain style.
+func (e *Editor) ReplaceScalar(node *yaml.Node, value string) error {
+	node = resolve(node)
+	start, end, err := e.scalarSpan(node)
+	if err != nil {
+		return err
+	}
+	scalar, err := formatScalar(value, node.Style)
+	if err != nil {
+		return err
+	}
+	e.buffer.Replace(start, end, scalar)
+	return nil
+}
+
+// SetComment replaces the comment after a scalar at the end of its line, or removes it if the comment is empty. It
+// returns false if the scalar is followed by other nodes on its line, e.g. in a flow mapping, since a comment would
+// hide them.
+func (e *Editor) SetComment(node *yaml.Node, comment string) (bool, error) {
+	node = resolve(node)
+	_, end, err := e.scalarSpan(node)
+	if err != nil {
+		return false, err
+	}
+	line, lineStart := e.buffer.Line(e.buffer.LineAt(end))
+	rest := line[end-lineStart:]
+	if trimmed := strings.TrimLeft(rest, " \t"); trimmed != "" && trimmed[0] != '#' {
+		return false, nil
+	}
+	rest = strings.TrimSuffix(rest, "\r")
+	text := ""
+	if comment != "" {
+		text = " # " + comment
+	}
+	if rest != text {
+		e.buffer.Replace(end, end+len(rest), text)
+	}
+	return true, nil
+}
+
+// InsertEntries inserts the entries of the block mapping of the text, e.g. "permissions:\n  contents: read", before
+// the entry at the index of the mapping, or after its last entry if the index is the number of entries. The text is
+// written with DefaultIndentation, and is reindented like the rest of the text. In a flow mapping, the entries are
+// written in flow style, without their comments.
+func (e *Editor) InsertEntries(mapping *yaml.Node, index int, text string) error {
+	mapping = resolve(mapping)
+	if mapping.Kind != yaml.MappingNode || index < 0 || 2*index > len(mapping.Content) {
+		return fmt.Errorf("unable to insert entries at %d of node at line %d", index, mapping.Line)
+	}
+	if mapping.Style&yaml.FlowStyle != 0 {
+		var nodes []*yaml.Node
+		for i := 0; i < len(mapping.Content); i += 2 {
+			nodes = append(nodes, mapping.Content[i])
+		}
+		var last *yaml.Node
+		if len(mapping.Content) > 0 {
+			last = mapping.Content[len(mapping.Content)-1]
+		}
+		return e.insertFlow(mapping, nodes, last, index, text, yaml.MappingNode)
+	}
+	if len(mapping.Content) == 0 {
+		return fmt.Errorf("unable to insert entries into empty block mapping at line %d", mapping.Line)
+	}
+	indent := strings.Repeat(" ", mapping.Content[0].Column-1)
+	lines := indentLines(e.indentation.Reindent(text), indent)
+	if 2*index == len(mapping.Content) {
+		e.buffer.InsertLinesAfter(e.lastLine(mapping.Content[len(mapping.Content)-1], mapping.Content[0].Column-1), lines...)
+		return nil
+	}
+	key := mapping.Content[2*index]
+	start, err := e.start(key)
+	if err != nil {
+		return err
+	}
+	line, lineStart := e.buffer.Line(e.buffer.LineAt(start))
+	if strings.TrimLeft(line[:start-lineStart], " ") == "" {
+		e.buffer.InsertLinesBefore(key.Line, lines...)
+		return nil
+	}
+	// the first entry of a mapping in a block sequence is on the line of its dash, so the entries are inserted after it
+	lines[0] = strings.TrimPrefix(lines[0], indent)
+	e.buffer.Insert(start, strings.Join(lines, "\n")+"\n"+indent)
+	return nil
+}
+
+// InsertItems inserts the items of the block sequence of the text, e.g. "- uses: actions/checkout@v4", before the item
+// at the index of the sequence, or after its last item if the index is the number of items. The text is written with
+// DefaultIndentation, and the empty lines of the text are kept in a block sequence. In a flow sequence, the items are
+// written in flow style, without their comments.
+func (e *Editor) InsertItems(sequence *yaml.Node, index int, text string) error {
+	sequence = resolve(sequence)
+	if sequence.Kind != yaml.SequenceNode || index < 0 || index > len(sequence.Content) {
+		return fmt.Errorf("unable to insert items at %d of node at line %d", index, sequence.Line)
+	}
+	if sequence.Style&yaml.FlowStyle != 0 {
+		var last *yaml.Node
+		if len(sequence.Content) > 0 {
+			last = sequence.Content[len(sequence.Content)-1]
+		}
+		return e.insertFlow(sequence, sequence.Content, last, index, text, yaml.SequenceNode)
+	}
+	if len(sequence.Content) == 0 {
+		return fmt.Errorf("unable to insert items into empty block sequence at line %d", sequence.Line)
+	}
+	column, err := e.dashColumn(sequence)
+	if err != nil {
+		return err
+	}
+	lines := indentLines(e.indentation.Reindent(text), strings.Repeat(" ", column-1))
+	if index == len(sequence.Content) {
+		e.buffer.InsertLinesAfter(e.lastLine(sequence.Content[index-1], column-1), lines...)
+		return nil
+	}
+	dashLine, err := e.dashLine(sequence, index)
+	if err != nil {
+		return err
+	}
+	e.buffer.InsertLinesBefore(dashLine, lines...)
+	return nil
+}
+
+// ReplaceItem replaces the item at the index of the sequence with the items of the block sequence of the text. The
+// comments and blank lines after the item are kept.
+func (e *Editor) ReplaceItem(sequence *yaml.Node, index int, text string) error {
+	sequence = resolve(sequence)
+	if sequence.Kind != yaml.SequenceNode || index < 0 || index >= len(sequence.Content) {
+		return fmt.Errorf("unable to replace item %d of node at line %d", index, sequence.Line)
+	}
+	item := sequence.Content[index]
+	if sequence.Style&yaml.FlowStyle != 0 {
+		start, err := e.start(item)
+		if err != nil {
+			return err
+		}
+		end, err := e.end(item)
+		if err != nil {
+			return err
+		}
+		items, err := flowItems(text, yaml.SequenceNode)
+		if err != nil {
+			return err
+		}
+		e.buffer.Replace(start, end, items)
+		return nil
+	}
+	dashLine, err := e.dashLine(sequence, index)
+	if err != nil {
+		return err
+	}
+	column, err := e.dashColumn(sequence)
+	if err != nil {
+		return err
+	}
+	_, start := e.buffer.Line(dashLine)
+	line, end := e.buffer.Line(e.lastLine(item, column-1))
+	end += len(line)
+	replacement := strings.Join(indentLines(e.indentation.Reindent(text), strings.Repeat(" ", column-1)), "\n")
+	e.buffer.Replace(start, end, replacement)
+	return nil
+}
+
+// insertFlow inserts the nodes of the text into a flow collection, before the node at the index of nodes, or after
+// the last node
+func (e *Editor) insertFlow(collection *yaml.Node, nodes []*yaml.Node, last *yaml.Node, index int, text string, kind yaml.Kind) error {
+	items, err := flowItems(text, kind)
+	if err != nil {
+		return err
+	}
+	if index < len(nodes) {
+		start, err := e.start(nodes[index])
+		if err != nil {
+			return err
+		}
+		e.buffer.Insert(start, items+", ")
+		return nil
+	}
+	if last == nil {
+		// the items of an empty collection are written after its bracket
+		start, err := e.start(collection)
+		if err != nil {
+			return err
+		}
+		e.buffer.Insert(start+1, items)
+		return nil
+	}
+	end, err := e.end(last)
+	if err != nil {
+		return err
+	}
+	e.buffer.Insert(end, ", "+items)
+	return nil
+}
+
+// flowItems returns the items of the block collection of the text in flow style, separated by commas and without the
+// brackets of the collection
+func flowItems(text string, kind yaml.Kind) (string, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &document); err != nil {
+		return "", fmt.Errorf("unable to parse yaml %v", err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != kind {
+		return "", fmt.Errorf("unable to insert %q, which is not a collection of the kind of the node", text)
+	}
+	collection := document.Content[0]
+	setFlowStyle(collection)
+	out, err := yaml.Marshal(collection)
+	if err != nil {
+		return "", fmt.Errorf("unable to write yaml %v", err)
+	}
+	flow := strings.TrimSpace(string(out))
+	return flow[1 : len(flow)-1], nil
+}
+
+// setFlowStyle sets the style of the collections of the tree to flow, and removes the comments, which cannot be
+// written in a flow collection on one line
+func setFlowStyle(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		node.Style = yaml.FlowStyle
+	}
+	if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	for _, child := range node.Content {
+		setFlowStyle(child)
+	}
+}
+
+// formatScalar returns the scalar of the value in the style, or in double quotes if the value is not read as the same
+// string in plain style
+func formatScalar(value string, style yaml.Style) (string, error) {
+	style &= yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle
+	out, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: style, Value: value})
+	if err != nil {
+		return "", fmt.Errorf("unable to write yaml %v", err)
+	}
+	scalar := strings.TrimSuffix(string(out), "\n")
+	if strings.Contains(scalar, "\n") {
+		return "", fmt.Errorf("unable to write %q on one line", value)
+	}
+	return scalar, nil
+}
+
+// indentLines returns the lines of the text with the indent, without the newline at its end. The empty lines are kept
+// without the indent.
+func indentLines(text, indent string) []string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = indent + line
+		} else {
+			lines[i] = ""
+		}
+	}
+	return lines
+}
+
+// offset returns the offset of the node in the text. The columns of the nodes are in characters, which are converted
+// to bytes.
+func (e *Editor) offset(node *yaml.Node) (int, error) {
+	if node.Line < 1 || node.Line > e.buffer.Lines() {
+		return 0, fmt.Errorf("node at line %d is not in the text", node.Line)
+	}
+	line, lineStart := e.buffer.Line(node.Line)
+	column := 0
+	for i := 1; i < node.Column; i++ {
+		if column >= len(line) {
+			return 0, fmt.Errorf("node at line %d column %d is not in the text", node.Line, node.Column)
+		}
+		_, size := utf8.DecodeRuneInString(line[column:])
+		column += size
+	}
+	return lineStart + column, nil
+}
+
+// scalarSpan returns the offsets of the start and the end of a scalar written on one line, with its quotes
+func (e *Editor) scalarSpan(node *yaml.Node) (int, int, error) {
+	if node.Kind != yaml.ScalarNode || node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return 0, 0, fmt.Errorf("node at line %d is not a scalar written on one line", node.Line)
+	}
+	start, err := e.start(node)
+	if err != nil {
+		return 0, 0, err
+	}
+	line, lineStart := e.buffer.Line(e.buffer.LineAt(start))
+	rest := line[start-lineStart:]
+	switch {
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		for i := 1; i < len(rest); i++ {
+			switch rest[i] {
+			case '\\':
+				i++
+			case '"':
+				return start, start + i + 1, nil
+			}
+		}
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		for i := 1; i < len(rest); i++ {
+			if rest[i] == '\'' {
+				if i+1 < len(rest) && rest[i+1] == '\'' {
+					i++
+					continue
+				}
+				return start, start + i + 1, nil
+			}
+		}
+	case strings.HasPrefix(rest, node.Value):
+		return start, start + len(node.Value), nil
+	}
+	return 0, 0, fmt.Errorf("scalar at line %d is not written on one line", node.Line)
+}
+
+// end returns the offset of the end of a node in a flow collection, after the bracket of a collection
+func (e *Editor) end(node *yaml.Node) (int, error) {
+	node = resolve(node)
+	if node.Kind == yaml.ScalarNode {
+		_, end, err := e.scalarSpan(node)
+		return end, err
+	}
+	start, err := e.start(node)
+	if err != nil {
+		return 0, err
+	}
+	if node.Style&yaml.FlowStyle == 0 {
+		return 0, fmt.Errorf("node at line %d is not in flow style", node.Line)
+	}
+	depth := 0
+	var quote byte
+	for i := start; i < len(e.text); i++ {
+		c := e.text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("collection at line %d is not closed", node.Line)
+}
+
+// dashLine returns the line of the dash of the item at the index of a block sequence, which is the line of the item
+// unless the item starts on the line after its dash
+func (e *Editor) dashLine(sequence *yaml.Node, index int) (int, error) {
+	column, err := e.dashColumn(sequence)
+	if err != nil {
+		return 0, err
+	}
+	indent := strings.Repeat(" ", column-1)
+	for n := sequence.Content[index].Line; n >= sequence.Line; n-- {
+		line, _ := e.buffer.Line(n)
+		if strings.HasPrefix(line, indent+"-") {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("item %d of the sequence at line %d has no dash", index, sequence.Line)
+}
+
+// lastLine returns the last line of a block node which is in a collection indented by indent spaces, which is the last
+// line that is more indented, or is an item of a block sequence at the same indent. The blank lines and comments after
+// the node are not part of it, unless they are lines of a block scalar it ends with.
+func (e *Editor) lastLine(node *yaml.Node, indent int) int {
+	last := LastLine(e.lines, node)
+	if node.Kind == yaml.ScalarNode {
+		return last
+	}
+	sequenceIndent := node.Kind == yaml.SequenceNode && node.Style&yaml.FlowStyle == 0 && node.Column-1 == indent
+	for n := node.Line + 1; n <= e.buffer.Lines(); n++ {
+		line, _ := e.buffer.Line(n)
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || trimmed == "\r" || trimmed[0] == '#' {
+			continue
+		}
+		lineIndent := len(line) - len(trimmed)
+		if lineIndent <= indent && !(sequenceIndent && lineIndent == indent && trimmed[0] == '-') {
+			break
+		}
+		if n > last {
+			last = n
+		}
+	}
+	return last
+}
+
+// resolve returns the node of the anchor of an alias, or the node if it is not an alias
+func resolve(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// hasProperties returns true if the node starts with an anchor or a tag, where its position is, instead of at its
+// content
+func hasProperties(node *yaml.Node) bool {
+	return node.Anchor != "" || node.Style&yaml.TaggedStyle != 0
+}
+
+// start returns the offset of the content of the node in the text, after its anchor and tag, which may be followed by
+// the content on the next line
+func (e *Editor) start(node *yaml.Node) (int, error) {
+	start, err := e.offset(node)
+	if err != nil || !hasProperties(node) {
+		return start, err
+	}
+	for start < len(e.text) && (e.text[start] == '&' || e.text[start] == '!') {
+		end := strings.IndexAny(e.text[start:], " \t\r\n")
+		if end < 0 {
+			return 0, fmt.Errorf("node at line %d has no content", node.Line)
+		}
+		start += end
+		for start < len(e.text) && strings.IndexByte(" \t\r\n", e.text[start]) >= 0 {
+			start++
+		}
+	}
+	return start, nil
+}
+
+// dashColumn returns the column of the dashes of a block sequence. The column of a sequence with an anchor or a tag is
+// the column of its properties, so the column of its dashes is the column of the last dash before its first item.
+func (e *Editor) dashColumn(sequence *yaml.Node) (int, error) {
+	if !hasProperties(sequence) {
+		return sequence.Column, nil
+	}
+	item := sequence.Content[0]
+	start, err := e.offset(item)
+	if err != nil {
+		return 0, err
+	}
+	line, lineStart := e.buffer.Line(item.Line)
+	if dash := strings.LastIndex(line[:start-lineStart], "-"); dash >= 0 {
+		return utf8.RuneCountInString(line[:dash]) + 1, nil
+	}
+	// the item starts on the line after its dash
+	for n := item.Line - 1; n > sequence.Line; n-- {
+		line, _ := e.buffer.Line(n)
+		if trimmed := strings.TrimSpace(line); trimmed == "-" || strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "-\t") {
+			return utf8.RuneCountInString(line[:strings.Index(line, "-")]) + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("sequence at line %d has no dash", sequence.Line)
+}
@@ -0,0 +1,306 @@
+package yamledit
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parse returns the root node of the document of the text
+func parse(t *testing.T, text string) *yaml.Node {
+	t.Helper()
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &document); err != nil {
+		t.Fatalf("unable to parse %q: %v", text, err)
+	}
+	return document.Content[0]
+}
+
+// get returns the node at the path of keys and indexes of sequences
+func get(node *yaml.Node, path ...interface{}) *yaml.Node {
+	for _, part := range path {
+		switch part := part.(type) {
+		case string:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == part {
+					node = node.Content[i+1]
+					break
+				}
+			}
+		case int:
+			node = node.Content[part]
+		}
+	}
+	return node
+}
+
+func TestReplaceScalar(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		value string
+		want  string
+	}{
+		{name: "plain", input: "runs-on: ubuntu-latest # runner\n", value: "step-ubuntu-24", want: "runs-on: step-ubuntu-24 # runner\n"},
+		{name: "single quoted", input: "runs-on: 'ubuntu-latest'\n", value: "step-ubuntu-24", want: "runs-on: 'step-ubuntu-24'\n"},
+		{name: "double quoted with escape", input: "runs-on: \"ubuntu\\u002Dlatest\" # runner\n", value: "step-ubuntu-24", want: "runs-on: \"step-ubuntu-24\" # runner\n"},
+		{name: "plain that must be quoted", input: "runs-on: ubuntu-latest\n", value: "true", want: "runs-on: \"true\"\n"},
+		{name: "after characters of several bytes", input: "{name: é, runs-on: ubuntu-latest}", value: "step-ubuntu-24", want: "{name: é, runs-on: step-ubuntu-24}"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := parse(t, test.input)
+			editor := New(test.input)
+			if err := editor.ReplaceScalar(get(root, "runs-on"), test.value); err != nil {
+				t.Fatalf("Error not expected: %v", err)
+			}
+			if got := editor.String(); got != test.want {
+				t.Errorf("String() = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	input := "run: |\n  echo\n"
+	if err := New(input).ReplaceScalar(get(parse(t, input), "run"), "echo"); err == nil {
+		t.Errorf("expected an error for a block scalar")
+	}
+}
+
+func TestSetComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		comment string
+		want    string
+		set     bool
+	}{
+		{name: "added", input: "uses: actions/checkout@v4\n", comment: "v4.2.2", want: "uses: actions/checkout@v4 # v4.2.2\n", set: true},
+		{name: "replaced", input: "uses: 'actions/checkout@v4'   # latest\n", comment: "v4.2.2", want: "uses: 'actions/checkout@v4' # v4.2.2\n", set: true},
+		{name: "removed", input: "uses: actions/checkout@v4 # v4\r\n", want: "uses: actions/checkout@v4\r\n", set: true},
+		{name: "flow mapping", input: "{uses: actions/checkout@v4, with: {fetch-depth: 0}}\n", comment: "v4.2.2", want: "{uses: actions/checkout@v4, with: {fetch-depth: 0}}\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			editor := New(test.input)
+			set, err := editor.SetComment(get(parse(t, test.input), "uses"), test.comment)
+			if err != nil {
+				t.Fatalf("Error not expected: %v", err)
+			}
+			if got := editor.String(); got != test.want || set != test.set {
+				t.Errorf("SetComment() = %v, %q, want %v, %q", set, got, test.set, test.want)
+			}
+		})
+	}
+}
+
+func TestInsertEntries(t *testing.T) {
+	permissions := "permissions:  # added\n  contents: read\n"
+	tests := []struct {
+		name  string
+		input string
+		path  []interface{}
+		index int
+		want  string
+	}{
+		{
+			name:  "block mapping",
+			input: "jobs:\n  build:\n    # the runner\n    runs-on: ubuntu-latest\n",
+			path:  []interface{}{"jobs", "build"},
+			want:  "jobs:\n  build:\n    # the runner\n    permissions:  # added\n      contents: read\n    runs-on: ubuntu-latest\n",
+		},
+		{
+			name:  "after the last entry",
+			input: "jobs:\n  build:\n    steps:\n    - run: echo\n\n# the end\n",
+			path:  []interface{}{"jobs", "build"},
+			index: 1,
+			want:  "jobs:\n  build:\n    steps:\n    - run: echo\n    permissions:  # added\n      contents: read\n\n# the end\n",
+		},
+		{
+			name:  "after a block scalar ending with a comment",
+			input: "jobs:\n  build:\n    run: |\n      make\n      # not a comment\n# the end\n",
+			path:  []interface{}{"jobs", "build"},
+			index: 1,
+			want:  "jobs:\n  build:\n    run: |\n      make\n      # not a comment\n    permissions:  # added\n      contents: read\n# the end\n",
+		},
+		{
+			name:  "after a block scalar keeping its empty lines",
+			input: "jobs:\n  build:\n    run: |+\n      make\n\n\n# the end\n",
+			path:  []interface{}{"jobs", "build"},
+			index: 1,
+			want:  "jobs:\n  build:\n    run: |+\n      make\n\n\n    permissions:  # added\n      contents: read\n# the end\n",
+		},
+		{
+			name:  "mapping in a block sequence",
+			input: "steps:\n  - uses: actions/checkout@v4\n",
+			path:  []interface{}{"steps", 0},
+			want:  "steps:\n  - permissions:  # added\n      contents: read\n    uses: actions/checkout@v4\n",
+		},
+		{
+			name:  "flow mapping",
+			input: "jobs:\n  build: {runs-on: ubuntu-latest, steps: [{run: echo}]}\n",
+			path:  []interface{}{"jobs", "build"},
+			want:  "jobs:\n  build: {permissions: {contents: read}, runs-on: ubuntu-latest, steps: [{run: echo}]}\n",
+		},
+		{
+			name:  "after the last entry of a flow mapping",
+			input: "jobs:\n  build: {runs-on: ubuntu-latest, steps: [{run: echo}] }\n",
+			path:  []interface{}{"jobs", "build"},
+			index: 2,
+			want:  "jobs:\n  build: {runs-on: ubuntu-latest, steps: [{run: echo}], permissions: {contents: read} }\n",
+		},
+		{
+			name:  "empty flow mapping",
+			input: "jobs:\n  build: {}\n",
+			path:  []interface{}{"jobs", "build"},
+			want:  "jobs:\n  build: {permissions: {contents: read}}\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			editor := New(test.input)
+			if err := editor.InsertEntries(get(parse(t, test.input), test.path...), test.index, permissions); err != nil {
+				t.Fatalf("Error not expected: %v", err)
+			}
+			got := editor.String()
+			if got != test.want {
+				t.Errorf("String() = %q, want %q", got, test.want)
+			}
+			var output yaml.Node
+			if err := yaml.Unmarshal([]byte(got), &output); err != nil {
+				t.Errorf("output is not valid YAML: %v", err)
+			}
+		})
+	}
+}
+
+func TestInsertItems(t *testing.T) {
+	step := "- name: Harden the runner\n  uses: step-security/harden-runner@v2\n  with:\n    egress-policy: audit\n\n"
+	tests := []struct {
+		name  string
+		input string
+		index int
+		want  string
+	}{
+		{
+			name:  "block sequence",
+			input: "steps:\n  # checkout\n  - uses: actions/checkout@v4\n",
+			want: "steps:\n  # checkout\n  - name: Harden the runner\n    uses: step-security/harden-runner@v2\n    with:\n      egress-policy: audit\n\n" +
+				"  - uses: actions/checkout@v4\n",
+		},
+		{
+			name:  "after the last item",
+			input: "steps:\n- uses: actions/checkout@v4\n  with:\n    fetch-depth: 0\nenv: {}",
+			index: 1,
+			want: "steps:\n- uses: actions/checkout@v4\n  with:\n    fetch-depth: 0\n- name: Harden the runner\n  uses: step-security/harden-runner@v2\n  with:\n    egress-policy: audit\n\n" +
+				"env: {}",
+		},
+		{
+			name:  "flow sequence",
+			input: "steps: [{uses: actions/checkout@v4}]\n",
+			want:  "steps: [{name: Harden the runner, uses: step-security/harden-runner@v2, with: {egress-policy: audit}}, {uses: actions/checkout@v4}]\n",
+		},
+		{
+			name:  "empty flow sequence",
+			input: "steps: []\n",
+			want:  "steps: [{name: Harden the runner, uses: step-security/harden-runner@v2, with: {egress-policy: audit}}]\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			editor := New(test.input)
+			if err := editor.InsertItems(get(parse(t, test.input), "steps"), test.index, step); err != nil {
+				t.Fatalf("Error not expected: %v", err)
+			}
+			if got := editor.String(); got != test.want {
+				t.Errorf("String() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReplaceItem(t *testing.T) {
+	step := "- uses: step-security/harden-runner@v2\n  with:\n    egress-policy: block\n"
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "block sequence",
+			input: "steps:\n  - uses: step-security/harden-runner@v2\n    with:\n      egress-policy: audit\n\n  # checkout\n  - uses: actions/checkout@v4\n",
+			want:  "steps:\n  - uses: step-security/harden-runner@v2\n    with:\n      egress-policy: block\n\n  # checkout\n  - uses: actions/checkout@v4\n",
+		},
+		{
+			name:  "last item",
+			input: "steps:\n- uses: step-security/harden-runner@v2\n  with:\n    egress-policy: audit",
+			want:  "steps:\n- uses: step-security/harden-runner@v2\n  with:\n    egress-policy: block",
+		},
+		{
+			name:  "flow sequence",
+			input: "steps: [{uses: step-security/harden-runner@v2}, {uses: actions/checkout@v4}]\n",
+			want:  "steps: [{uses: step-security/harden-runner@v2, with: {egress-policy: block}}, {uses: actions/checkout@v4}]\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			editor := New(test.input)
+			if err := editor.ReplaceItem(get(parse(t, test.input), "steps"), 0, step); err != nil {
+				t.Fatalf("Error not expected: %v", err)
+			}
+			if got := editor.String(); got != test.want {
+				t.Errorf("String() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDetectIndentation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Indentation
+	}{
+		{name: "two spaces", input: "jobs:\n  build:\n    steps:\n      - run: echo\n", want: Indentation{Width: 2, IndentSequences: true}},
+		{name: "four spaces", input: "jobs:\n    build:\n        steps:\n            - run: echo\n", want: Indentation{Width: 4, IndentSequences: true}},
+		{name: "sequences not indented", input: "jobs:\n    build:\n        steps:\n        - run: echo\n          with:\n              a: b\n", want: Indentation{Width: 4, IndentSequences: false}},
+		{name: "block scalar", input: "run: |\n      if:\n          echo\nenv:\n  A: b\n", want: Indentation{Width: 2, IndentSequences: true}},
+		{name: "flow", input: "jobs: {build: {steps: []}}\n", want: DefaultIndentation},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := DetectIndentation(test.input); got != test.want {
+				t.Errorf("DetectIndentation() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReindent(t *testing.T) {
+	step := "- name: Harden the runner\n  uses: step-security/harden-runner@v2\n  # the policy\n  with:\n    egress-policy: block\n    allowed-endpoints: >\n      api.github.com:443\n\n      github.com:443\n    files:\n      - a\n"
+	tests := []struct {
+		name        string
+		indentation Indentation
+		want        string
+	}{
+		{name: "default", indentation: DefaultIndentation, want: step},
+		{
+			name:        "four spaces",
+			indentation: Indentation{Width: 4, IndentSequences: true},
+			want: "- name: Harden the runner\n  uses: step-security/harden-runner@v2\n  # the policy\n  with:\n      egress-policy: block\n      allowed-endpoints: >\n          api.github.com:443\n\n" +
+				"          github.com:443\n      files:\n          - a\n",
+		},
+		{
+			name:        "sequences not indented",
+			indentation: Indentation{Width: 2, IndentSequences: false},
+			want: "- name: Harden the runner\n  uses: step-security/harden-runner@v2\n  # the policy\n  with:\n    egress-policy: block\n    allowed-endpoints: >\n      api.github.com:443\n\n" +
+				"      github.com:443\n    files:\n    - a\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.indentation.Reindent(step); got != test.want {
+				t.Errorf("Reindent() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}